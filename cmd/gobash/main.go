@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -10,61 +13,121 @@ import (
 	"time"
 	"gobash/internal/builtin"
 	"gobash/internal/executor"
+	"gobash/internal/i18n"
 	"gobash/internal/shell"
 )
 
+// version 版本号，构建时用 -ldflags "-X main.version=..." 设置（见 build.sh）
+var version = "dev"
+
 func main() {
-	var scriptPath = flag.String("c", "", "执行命令字符串")
-	var scriptFile = flag.String("f", "", "执行脚本文件")
-	flag.Parse()
+	var scriptPath = flag.String("c", "", i18n.T("执行命令字符串"))
+	var scriptFile = flag.String("f", "", i18n.T("执行脚本文件"))
+	var noExec = flag.Bool("n", false, i18n.T("只检查语法，不执行命令（发现错误时退出码为 2）"))
+	var format = flag.Bool("fmt", false, i18n.T("把脚本格式化为规范的源代码输出到标准输出，不执行命令（保留注释，不保留空行）"))
+	var dumpAST = flag.Bool("ast", false, i18n.T("把脚本的语法树以 JSON 格式输出到标准输出，不执行命令"))
+	var lintScripts = flag.Bool("lint", false, i18n.T("静态检查脚本中常见的问题（如没有引用的变量），不执行命令（发现问题时退出码为 1）"))
+	var timeout = flag.Duration("timeout", 0, i18n.T("每个脚本的最长执行时间（如 30s、5m），超时后终止脚本，退出码为 124；0 表示不限制"))
+	var commandTimeout = flag.Duration("command-timeout", 0, i18n.T("单个外部命令的最长执行时间，超时的命令退出码为 124；0 表示不限制"))
+	var substLimit = flag.Int("subst-limit", 0, i18n.T("命令替换输出的最大字节数，超过时截断并且退出状态为 1；0 表示不限制"))
+	var dryRun = flag.Bool("dry-run", false, i18n.T("试运行：不执行外部命令和修改文件的内置命令，只把展开后的命令输出到标准错误"))
+	var profile = flag.Bool("profile", false, i18n.T("统计每个命令的执行次数和耗时，脚本结束时把按总耗时排序的报告输出到标准错误"))
+	var cover = flag.Bool("cover", false, i18n.T("记录脚本中每一行的执行次数，脚本结束时把行覆盖率报告输出到标准错误"))
+	var coverProfile = flag.String("coverprofile", "", i18n.T("记录脚本中每一行的执行次数，脚本结束时以 LCOV 格式写到指定文件"))
+	var showVersion = flag.Bool("version", false, i18n.T("显示版本信息后退出"))
+	// 代替 bash、sh 执行时（如 bash -euo pipefail x.sh），bash 风格的 shell 选项在 flag 解析之前取出
+	shellOpts, args := splitShellOptions(os.Args[1:])
+	flag.CommandLine.Parse(args)
+
+	executor.Version = version
+	// 脚本的 #! 行指定的 bash、sh 不存在时（如 Windows 上）由 gobash 自己执行
+	if self, err := os.Executable(); err == nil {
+		builtin.ShellPath = self
+	}
+	if *showVersion {
+		fmt.Printf("gobash, version %s (%s)\n", version, executor.MachType())
+		os.Exit(0)
+	}
 
 	sh := shell.New()
+	if err := sh.SetOptions(shellOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "gobash: %v\n", err)
+		os.Exit(2)
+	}
+	sh.Executor().SetCommandTimeout(*commandTimeout)
+	sh.Executor().SetSubstitutionLimit(*substLimit)
+	sh.Executor().SetDryRun(*dryRun)
+	if *profile {
+		profiler = executor.NewProfiler()
+		sh.Executor().SetProfiler(profiler)
+	}
+	if *cover || *coverProfile != "" {
+		coverage = executor.NewCoverage()
+		coverageReport = *cover
+		coverageFile = *coverProfile
+		sh.Executor().SetCoverage(coverage)
+	}
+
+	// 语法检查模式：解析整个脚本并报告所有语法错误，不执行任何命令
+	if *noExec {
+		os.Exit(checkSyntax(sh, *scriptPath, *scriptFile, flag.Args()))
+	}
+
+	// 输出语法树：-ast 输出 JSON，-fmt 输出格式化后的源代码，都不执行命令
+	if *dumpAST || *format {
+		mode := shell.FormatSource
+		if *dumpAST {
+			mode = shell.FormatAST
+		}
+		os.Exit(formatScripts(sh, mode, *scriptPath, *scriptFile, flag.Args()))
+	}
+
+	// 静态检查：输出警告，不执行命令
+	if *lintScripts {
+		os.Exit(lint(sh, *scriptPath, *scriptFile, flag.Args()))
+	}
 
-	// 执行命令字符串
+	// 执行命令字符串，退出码是最后一个命令的退出状态
+	// 与 bash -c 相同按非交互式 shell 执行：错误消息带行号，set -u 展开未定义的变量时终止执行；
+	// 命令字符串之后的参数依次是 $0、$1、$2……
 	if *scriptPath != "" {
-		if err := sh.ExecuteReader(strings.NewReader(*scriptPath)); err != nil {
-			// 检查是否是 exit 命令
-			if exitErr, ok := err.(*builtin.ExitError); ok {
-				os.Exit(exitErr.Code)
-			}
-			fmt.Fprintf(os.Stderr, "错误: %v\n", err)
-			os.Exit(1)
+		sh.SetScriptPath("")
+		if args := flag.Args(); len(args) > 0 {
+			sh.SetPositionalArgs(args[0], args[1:])
 		}
-		return
+		ctx, cancel := scriptContext(*timeout)
+		err := sh.ExecuteReaderContext(ctx, strings.NewReader(*scriptPath))
+		cancel()
+		exit(exitCode(sh, err))
 	}
 
 	// 执行脚本文件
 	if *scriptFile != "" {
 		// 获取 -f 之后的参数作为脚本参数
 		scriptArgs := flag.Args()
-		if err := sh.ExecuteScript(*scriptFile, scriptArgs...); err != nil {
-			// 检查是否是 exit 命令
-			if exitErr, ok := err.(*builtin.ExitError); ok {
-				os.Exit(exitErr.Code)
-			}
-			fmt.Fprintf(os.Stderr, "错误: %v\n", err)
-			os.Exit(1)
-		}
-		return
+		ctx, cancel := scriptContext(*timeout)
+		err := sh.ExecuteScriptContext(ctx, *scriptFile, scriptArgs...)
+		cancel()
+		exit(exitCode(sh, err))
 	}
 
 	// 如果有命令行参数，作为脚本执行
-	if len(os.Args) > 1 && os.Args[1][0] != '-' {
+	if args := flag.Args(); len(args) > 0 {
 		// 收集所有脚本文件（支持通配符和多个文件）
 		var scriptFiles []string
 		var scriptArgs []string
 		argsStartIndex := -1
 		
 		// 遍历所有非选项参数，区分脚本文件和脚本参数
-		for i := 1; i < len(os.Args); i++ {
-			arg := os.Args[i]
+		for i := 0; i < len(args); i++ {
+			arg := args[i]
 			
 			// 检查是否包含通配符（如 *.sh）
 			if strings.Contains(arg, "*") || strings.Contains(arg, "?") {
 				// 使用通配符匹配文件
 				matches, err := filepath.Glob(arg)
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "错误: 通配符匹配失败 %s: %v\n", arg, err)
+					i18n.Fprintf(os.Stderr, "错误: 通配符匹配失败 %s: %v\n", arg, err)
 					os.Exit(1)
 				}
 				if len(matches) == 0 {
@@ -94,7 +157,7 @@ func main() {
 		
 		// 如果没有找到任何文件，退出
 		if len(scriptFiles) == 0 {
-			fmt.Fprintf(os.Stderr, "错误: 没有找到要执行的脚本文件\n")
+			i18n.Fprintf(os.Stderr, "错误: 没有找到要执行的脚本文件\n")
 			os.Exit(1)
 		}
 		
@@ -114,21 +177,21 @@ func main() {
 		
 		// 调试输出：显示匹配到的文件数
 		if len(scriptFiles) > 1 {
-			fmt.Fprintf(os.Stderr, "找到 %d 个脚本文件，开始执行...\n", len(scriptFiles))
+			i18n.Fprintf(os.Stderr, "找到 %d 个脚本文件，开始执行...\n", len(scriptFiles))
 		}
 		
 		// 依次执行所有脚本文件
-		hasError := false
+		status := 0
 		for i, scriptPath := range scriptFiles {
 			// 检查是否是文件
 			info, err := os.Stat(scriptPath)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "警告: 跳过 %s: %v\n", scriptPath, err)
-				hasError = true
+				i18n.Fprintf(os.Stderr, "警告: 跳过 %s: %v\n", scriptPath, err)
+				status = 1
 				continue
 			}
 			if info.IsDir() {
-				fmt.Fprintf(os.Stderr, "警告: 跳过目录 %s\n", scriptPath)
+				i18n.Fprintf(os.Stderr, "警告: 跳过目录 %s\n", scriptPath)
 				continue
 			}
 			
@@ -147,54 +210,219 @@ func main() {
 			}
 			
 			// 执行脚本，传递脚本参数（只有第一个脚本接收参数）
-			// 使用 goroutine 来执行脚本，避免卡死
-			scriptErr := make(chan error, 1)
-			go func() {
-				// 只有第一个脚本接收参数
-				if i == 0 {
-					scriptErr <- sh.ExecuteScript(scriptPath, scriptArgs...)
-				} else {
-					scriptErr <- sh.ExecuteScript(scriptPath)
-				}
-			}()
-			
-			// 设置超时（300秒），如果脚本卡死则跳过
-			select {
-			case err := <-scriptErr:
-				if err != nil {
-					// 检查是否是 exit 命令或脚本退出错误
-					if exitErr, ok := err.(*builtin.ExitError); ok {
-						// exit 命令是正常的脚本退出，记录退出码但继续执行下一个脚本
-						if exitErr.Code != 0 {
-							hasError = true
-						}
-						// 不输出错误信息，因为 exit 是正常的脚本退出
-					} else if scriptExitErr, ok := err.(*executor.ScriptExitError); ok {
-						// 脚本退出错误（由于 set -e），记录退出码但继续执行下一个脚本
-						if scriptExitErr.Code != 0 {
-							hasError = true
-						}
-						// 不输出错误信息，因为这是正常的脚本退出
-					} else {
-						fmt.Fprintf(os.Stderr, "错误: 执行脚本 %s 失败: %v\n", scriptPath, err)
-						hasError = true
-					}
-				}
-			case <-time.After(300 * time.Second):
-				fmt.Fprintf(os.Stderr, "警告: 脚本 %s 执行超时（300秒），跳过\n", scriptPath)
-				hasError = true
-				// 注意：goroutine 可能仍在运行，但我们已经继续执行下一个脚本了
+			// 设置了 -timeout 时，超时的脚本被终止（包括正在运行的外部命令），然后继续执行下一个脚本
+			ctx, cancel := scriptContext(*timeout)
+			if i == 0 {
+				err = sh.ExecuteScriptContext(ctx, scriptPath, scriptArgs...)
+			} else {
+				err = sh.ExecuteScriptContext(ctx, scriptPath)
+			}
+			cancel()
+			// 超时的脚本给出警告，其他错误已经报告过（或由 exitCode 输出），记录退出码后继续执行下一个脚本
+			if errors.Is(err, context.DeadlineExceeded) {
+				i18n.Fprintf(os.Stderr, "警告: 脚本 %s 执行超时（%s），跳过\n", scriptPath, *timeout)
+			}
+			if code := exitCode(sh, err); code != 0 {
+				status = code
 			}
 		}
 		
-		// 所有脚本执行完成后，如果有错误则退出
-		if hasError {
-			os.Exit(1)
-		}
-		return
+		// 所有脚本执行完成后，以最后一个失败的脚本的退出码退出
+		exit(status)
 	}
 
 	// 交互式模式
 	sh.Run()
 }
 
+// shellOptionLetters 可以在命令行中使用的 bash 单字母 shell 选项（与 set 的选项相同）
+const shellOptionLetters = "abeuvxBCEHT"
+
+// splitShellOptions 从命令行参数开头取出 bash 风格的 shell 选项：-e、+x、-o 选项名以及组合的 -euo pipefail，
+// 返回按 set 的参数格式排列的选项和剩下交给 flag 解析的参数；gobash 自己的选项（连同它的值）保留，
+// 组合选项中的 c（如 -ec 命令）作为单独的 -c 保留；遇到第一个不是选项的参数时停止
+func splitShellOptions(args []string) (options, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			return options, append(rest, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if f := flag.Lookup(name); f != nil && arg[0] == '-' {
+			rest = append(rest, arg)
+			if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && boolFlag.IsBoolFlag()) && i+1 < len(args) {
+				i++
+				rest = append(rest, args[i])
+			}
+			continue
+		}
+		group := arg[1:]
+		if strings.Trim(group, shellOptionLetters+"oc") != "" || strings.Count(group, "o") > 1 ||
+			(strings.Contains(group, "c") && arg[0] == '+') {
+			// 不认识的选项交给 flag 报告
+			return options, append(rest, args[i:]...)
+		}
+		command := false
+		for _, letter := range group {
+			switch letter {
+			case 'o':
+				if i+1 >= len(args) {
+					return options, append(rest, args[i:]...)
+				}
+				i++
+				options = append(options, arg[:1]+"o", args[i])
+			case 'c':
+				command = true
+			default:
+				options = append(options, arg[:1]+string(letter))
+			}
+		}
+		if command {
+			rest = append(rest, "-c")
+		}
+	}
+	return options, rest
+}
+
+// profiler 设置了 -profile 时记录命令耗时的性能分析器
+var profiler *executor.Profiler
+
+// profileReportLimit 性能分析报告最多输出的命令数
+const profileReportLimit = 30
+
+// coverage 设置了 -cover 或 -coverprofile 时记录行覆盖率
+var coverage *executor.Coverage
+
+// coverageReport 是否输出覆盖率报告（-cover），coverageFile 是写入 LCOV 的文件（-coverprofile）
+var (
+	coverageReport bool
+	coverageFile   string
+)
+
+// exit 输出性能分析报告（设置了 -profile 时）和覆盖率（设置了 -cover、-coverprofile 时）后以 code 退出
+func exit(code int) {
+	if profiler != nil {
+		profiler.WriteReport(os.Stderr, profileReportLimit)
+	}
+	if coverage != nil && coverageReport {
+		coverage.WriteReport(os.Stderr)
+	}
+	if coverage != nil && coverageFile != "" {
+		if err := writeCoverProfile(coverageFile); err != nil {
+			i18n.Fprintf(os.Stderr, "错误: 无法写入覆盖率文件 %s: %v\n", coverageFile, err)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	os.Exit(code)
+}
+
+// writeCoverProfile 把覆盖率以 LCOV 格式写到 path
+func writeCoverProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := coverage.WriteLCOV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+
+// exitCode 返回执行脚本或命令字符串后 gobash 的退出码
+// 成功时为最后一个命令的退出状态；超时为 124；exit、set -e 以及 shell 报告过的错误（ShellError）使用它们的退出码，
+// 其他错误（如无法打开脚本文件）先输出，退出码为 1（脚本文件不存在时为 127）
+func exitCode(sh *shell.Shell, err error) int {
+	if err == nil {
+		return sh.LastStatus()
+	}
+	// 超时：中断错误已经报告过
+	if errors.Is(err, context.DeadlineExceeded) {
+		return 124
+	}
+	var shellErr executor.ShellError
+	if errors.As(err, &shellErr) {
+		return shellErr.ExitCode()
+	}
+	i18n.Fprintf(os.Stderr, "错误: %v\n", err)
+	if errors.Is(err, os.ErrNotExist) {
+		// 与 bash 相同，脚本文件不存在时退出码为 127
+		return 127
+	}
+	return 1
+}
+
+// scriptContext 返回执行一个脚本使用的 context，timeout 为 0 时不限制执行时间
+func scriptContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// checkSyntax 执行 -n 语法检查，返回进程退出码
+// 依次检查 -c 命令字符串、-f 脚本文件和命令行中的脚本文件，
+// 没有指定脚本时从标准输入读取；存在语法错误时返回 2
+func checkSyntax(sh *shell.Shell, command, scriptFile string, files []string) int {
+	if parseScripts(command, scriptFile, files, sh.CheckReader, sh.CheckScript) > 0 {
+		return 2
+	}
+	return 0
+}
+
+// formatScripts 执行 -fmt 或 -ast，按 mode 把各个脚本的语法树输出到标准输出，返回进程退出码
+// 脚本的顺序和存在语法错误时的退出码与 -n 相同
+func formatScripts(sh *shell.Shell, mode shell.FormatMode, command, scriptFile string, files []string) int {
+	count := parseScripts(command, scriptFile, files,
+		func(r io.Reader) (int, error) { return sh.FormatReader(r, os.Stdout, mode) },
+		func(path string) (int, error) { return sh.FormatScript(path, os.Stdout, mode) })
+	if count > 0 {
+		return 2
+	}
+	return 0
+}
+
+// lint 执行 -lint，把各个脚本的警告输出到标准输出，返回进程退出码
+// 脚本的顺序与 -n 相同；发现警告、语法错误或无法读取脚本时返回 1
+func lint(sh *shell.Shell, command, scriptFile string, files []string) int {
+	count := parseScripts(command, scriptFile, files,
+		func(r io.Reader) (int, error) { return sh.LintReader(r, os.Stdout, "") },
+		func(path string) (int, error) { return sh.LintScript(path, os.Stdout) })
+	if count > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parseScripts 依次用 reader 处理 -c 命令字符串，用 script 处理 -f 脚本文件和命令行中的脚本文件，
+// 没有指定脚本时用 reader 处理标准输入；返回发现的问题总数（无法读取的脚本计为一个）
+func parseScripts(command, scriptFile string, files []string, reader func(io.Reader) (int, error), script func(string) (int, error)) int {
+	if scriptFile != "" {
+		files = append([]string{scriptFile}, files...)
+	}
+
+	errorCount := 0
+	check := func(count int, err error) {
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "错误: %v\n", err)
+			errorCount++
+			return
+		}
+		errorCount += count
+	}
+
+	if command != "" {
+		check(reader(strings.NewReader(command)))
+	}
+	for _, file := range files {
+		check(script(file))
+	}
+	if command == "" && len(files) == 0 {
+		check(reader(os.Stdin))
+	}
+
+	return errorCount
+}
@@ -0,0 +1,546 @@
+package builtin
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tarUsage tar 命令的用法说明
+const tarUsage = "用法: tar {c|x|t}[vz][f 归档文件] [-C 目录] [文件...]"
+
+// tarOptions tar 命令的选项
+type tarOptions struct {
+	mode    byte   // 'c' 创建、'x' 解压、't' 列出
+	gzip    bool   // -z 使用 gzip 压缩
+	verbose bool   // -v 输出处理的文件
+	archive string // -f 归档文件，- 或空表示标准输入/输出
+	dir     string // -C 切换到的目录
+	files   []string
+}
+
+// tar 创建、解压或列出 tar 归档
+// 用法：tar {c|x|t}[vz][f 归档文件] [-C 目录] [文件...]
+// 选项可以不带 -（如 tar czf backup.tgz dir/），f 和 C 的参数按顺序取后面的参数；
+// 解压和列出时自动识别 gzip 压缩；解压时去掉成员名开头的 /，拒绝包含 .. 的成员
+func tarCmd(args []string, env map[string]string, stdio *IO) error {
+	var opts tarOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		// 第一个参数可以是不带 - 的选项组合
+		bundle := i == 0 && !strings.HasPrefix(arg, "-")
+		if !bundle && (!strings.HasPrefix(arg, "-") || arg == "-") {
+			opts.files = append(opts.files, arg)
+			continue
+		}
+		if arg == "--" {
+			opts.files = append(opts.files, args[i+1:]...)
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--file="); ok {
+			opts.archive = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--directory="); ok {
+			opts.dir = value
+			continue
+		}
+		if !bundle {
+			arg = arg[1:]
+		}
+		// f 和 C 的参数：-f文件 使用剩余部分，否则使用下一个参数
+		next := i
+		for j := 0; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'c', 'x', 't':
+				if opts.mode != 0 && opts.mode != flag {
+					return i18n.Errorf("tar: c、x、t 只能指定一个\n%s", i18n.T(tarUsage))
+				}
+				opts.mode = flag
+			case 'z':
+				opts.gzip = true
+			case 'v':
+				opts.verbose = true
+			case 'f', 'C':
+				value := ""
+				if !bundle && j+1 < len(arg) {
+					value, j = arg[j+1:], len(arg)
+				} else {
+					if next+1 >= len(args) {
+						return i18n.Errorf("tar: -%c 缺少参数", flag)
+					}
+					next++
+					value = args[next]
+				}
+				if flag == 'f' {
+					opts.archive = value
+				} else {
+					opts.dir = value
+				}
+			default:
+				return i18n.Errorf("tar: -%c: 无效选项\n%s", flag, i18n.T(tarUsage))
+			}
+		}
+		i = next
+	}
+	if opts.mode == 0 {
+		return i18n.Errorf("tar: 必须指定 c、x 或 t 之一\n%s", i18n.T(tarUsage))
+	}
+
+	switch opts.mode {
+	case 'c':
+		return tarCreate(opts, env, stdio)
+	default:
+		return tarRead(opts, env, stdio)
+	}
+}
+
+// tarCreate 把文件和目录（递归）写入归档
+func tarCreate(opts tarOptions, env map[string]string, stdio *IO) error {
+	if len(opts.files) == 0 {
+		return i18n.Errorf("tar: 不能创建空归档")
+	}
+	baseDir := workDir(env)
+	if opts.dir != "" {
+		baseDir = resolvePath(env, opts.dir)
+	}
+
+	// 归档写到标准输出时，-v 的输出写到标准错误
+	var out io.Writer = stdio.Stdout
+	verbose := stdio.Stdout
+	var archiveInfo fs.FileInfo
+	if opts.archive != "" && opts.archive != "-" {
+		f, err := stdio.create(resolvePath(env, opts.archive), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+		if err != nil {
+			return fmt.Errorf("tar: %s: %v", opts.archive, pathErrorReason(err))
+		}
+		defer f.Close()
+		archiveInfo, _ = f.Stat()
+		out = f
+	} else {
+		verbose = stdio.Stderr
+	}
+	buffered := bufio.NewWriter(out)
+	out = buffered
+	var zw *gzip.Writer
+	if opts.gzip {
+		zw = gzip.NewWriter(out)
+		out = zw
+	}
+	tw := tar.NewWriter(out)
+
+	var errs []string
+	for _, file := range opts.files {
+		root := file
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(baseDir, root)
+		}
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			name := file
+			if rel, relErr := filepath.Rel(root, p); relErr == nil && rel != "." {
+				name = path.Join(filepath.ToSlash(file), filepath.ToSlash(rel))
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("tar: %s: %v", name, pathErrorReason(err)))
+				return nil
+			}
+			if err := stdio.checkPath("open", AccessRead, p); err != nil {
+				errs = append(errs, fmt.Sprintf("tar: %s: %v", name, pathErrorReason(err)))
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("tar: %s: %v", name, pathErrorReason(err)))
+				return nil
+			}
+			// 不把正在写的归档文件本身放进归档
+			if archiveInfo != nil && os.SameFile(info, archiveInfo) {
+				return nil
+			}
+			link := ""
+			if info.Mode()&fs.ModeSymlink != 0 {
+				if link, err = os.Readlink(p); err != nil {
+					errs = append(errs, fmt.Sprintf("tar: %s: %v", name, pathErrorReason(err)))
+					return nil
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("tar: %s: %v", name, err))
+				return nil
+			}
+			// 成员名使用 / 分隔的相对路径，去掉开头的 /
+			header.Name = strings.TrimLeft(filepath.ToSlash(name), "/")
+			if info.IsDir() {
+				header.Name = strings.TrimSuffix(header.Name, "/") + "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if opts.verbose {
+				fmt.Fprintln(verbose, header.Name)
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return i18n.Errorf("tar: 写入归档失败: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return i18n.Errorf("tar: 写入归档失败: %v", err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return i18n.Errorf("tar: 写入归档失败: %v", err)
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return i18n.Errorf("tar: 写入归档失败: %v", err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// tarRead 列出或解压归档中的成员，指定文件时只处理这些成员（以及目录下的成员）
+func tarRead(opts tarOptions, env map[string]string, stdio *IO) error {
+	var in io.Reader = stdio.Stdin
+	if opts.archive != "" && opts.archive != "-" {
+		f, err := stdio.open(resolvePath(env, opts.archive))
+		if err != nil {
+			return fmt.Errorf("tar: %s: %v", opts.archive, pathErrorReason(err))
+		}
+		defer f.Close()
+		in = f
+	}
+	// 根据文件头自动识别 gzip 压缩
+	buffered := bufio.NewReader(in)
+	in = buffered
+	if magic, _ := buffered.Peek(2); opts.gzip || len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(buffered)
+		if err != nil {
+			return i18n.Errorf("tar: 无效的 gzip 数据: %v", err)
+		}
+		defer zr.Close()
+		in = zr
+	}
+
+	destDir := workDir(env)
+	if opts.dir != "" {
+		destDir = resolvePath(env, opts.dir)
+	}
+	selected := func(name string) bool {
+		if len(opts.files) == 0 {
+			return true
+		}
+		name = strings.TrimSuffix(name, "/")
+		for _, file := range opts.files {
+			file = strings.TrimSuffix(filepath.ToSlash(file), "/")
+			if name == file || strings.HasPrefix(name, file+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	tr := tar.NewReader(in)
+	var errs []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return i18n.Errorf("tar: 读取归档失败: %v", err)
+		}
+		if !selected(header.Name) {
+			continue
+		}
+
+		if opts.mode == 't' {
+			if opts.verbose {
+				// 与 ls -l 的格式相同
+				kind, name := "-", header.Name
+				switch header.Typeflag {
+				case tar.TypeDir:
+					kind = "d"
+				case tar.TypeSymlink:
+					kind, name = "l", name+" -> "+header.Linkname
+				case tar.TypeLink:
+					name += " link to " + header.Linkname
+				}
+				fmt.Fprintf(stdio.Stdout, "%s%s %8d %s %s\n", kind, header.FileInfo().Mode().Perm().String()[1:], header.Size,
+					header.ModTime.Local().Format("Jan 02 15:04"), name)
+			} else {
+				fmt.Fprintln(stdio.Stdout, header.Name)
+			}
+			continue
+		}
+
+		if opts.verbose {
+			fmt.Fprintln(stdio.Stdout, header.Name)
+		}
+		if err := extractTarEntry(tr, header, destDir, stdio); err != nil {
+			errs = append(errs, fmt.Sprintf("tar: %s: %v", header.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// extractTarEntry 把归档成员解压到 destDir 下
+func extractTarEntry(tr *tar.Reader, header *tar.Header, destDir string, stdio *IO) error {
+	name := strings.TrimLeft(header.Name, "/")
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return i18n.Errorf("成员名包含 ..，已跳过")
+		}
+	}
+	if name == "" {
+		return nil
+	}
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	mode := header.FileInfo().Mode()
+	if err := stdio.Check(AccessWrite, target); err != nil {
+		return err
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, mode.Perm()|0700); err != nil {
+			return pathErrorReason(err)
+		}
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return pathErrorReason(err)
+		}
+		// 先删除已有的文件，避免写入符号链接指向的文件
+		os.Remove(target)
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+		if err != nil {
+			return pathErrorReason(err)
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return pathErrorReason(err)
+		}
+		os.Remove(target)
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return pathErrorReason(err)
+		}
+		return nil
+	case tar.TypeLink:
+		linkName := strings.TrimLeft(header.Linkname, "/")
+		if strings.Contains("/"+linkName+"/", "/../") {
+			return i18n.Errorf("链接目标包含 ..，已跳过")
+		}
+		os.Remove(target)
+		if err := os.Link(filepath.Join(destDir, filepath.FromSlash(linkName)), target); err != nil {
+			return pathErrorReason(err)
+		}
+		return nil
+	default:
+		return i18n.Errorf("不支持的成员类型 %q，已跳过", header.Typeflag)
+	}
+	return os.Chtimes(target, time.Now(), header.ModTime)
+}
+
+// gzipCmd 压缩或解压文件
+// 用法：gzip [-cdfk] [-1..-9] [文件...]
+// 没有文件时从标准输入压缩到标准输出；文件 a 压缩为 a.gz 并删除原文件（-k 保留，-c 输出到标准输出）；
+// -d 解压 .gz（或 .tgz，解压为 .tar）文件，-f 覆盖已存在的输出文件，-1 到 -9 指定压缩级别
+func gzipCmd(args []string, env map[string]string, stdio *IO) error {
+	return runGzip("gzip", false, args, env, stdio)
+}
+
+// gunzip 解压 gzip 文件，等同于 gzip -d
+// 用法：gunzip [-cfk] [文件...]
+func gunzip(args []string, env map[string]string, stdio *IO) error {
+	return runGzip("gunzip", true, args, env, stdio)
+}
+
+// runGzip 实现 gzip 和 gunzip
+func runGzip(cmdName string, decompress bool, args []string, env map[string]string, stdio *IO) error {
+	toStdout, force, keep := false, false, false
+	level := gzip.DefaultCompression
+	var files []string
+	parseOptions := true
+	for _, arg := range args {
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		switch arg {
+		case "--":
+			parseOptions = false
+			continue
+		case "--stdout":
+			toStdout = true
+			continue
+		case "--decompress":
+			decompress = true
+			continue
+		case "--keep":
+			keep = true
+			continue
+		case "--force":
+			force = true
+			continue
+		}
+		for _, flag := range arg[1:] {
+			switch {
+			case flag == 'c':
+				toStdout = true
+			case flag == 'd':
+				decompress = true
+			case flag == 'f':
+				force = true
+			case flag == 'k':
+				keep = true
+			case flag >= '1' && flag <= '9':
+				level = int(flag - '0')
+			default:
+				return i18n.Errorf("%s: -%c: 无效选项\n用法: %s [-cdfk] [-1..-9] [文件...]", cmdName, flag, cmdName)
+			}
+		}
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var errs []string
+	for _, file := range files {
+		if err := gzipFile(file, decompress, toStdout, force, keep, level, env, stdio); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s: %v", cmdName, file, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// gzipFile 压缩或解压一个文件，- 表示标准输入（输出到标准输出）
+func gzipFile(file string, decompress, toStdout, force, keep bool, level int, env map[string]string, stdio *IO) error {
+	// 输出文件名
+	outName := ""
+	if file != "-" && !toStdout {
+		switch {
+		case !decompress && strings.HasSuffix(file, ".gz"):
+			return i18n.Errorf("已经有 .gz 后缀")
+		case !decompress:
+			outName = file + ".gz"
+		case strings.HasSuffix(file, ".gz"):
+			outName = strings.TrimSuffix(file, ".gz")
+		case strings.HasSuffix(file, ".tgz"):
+			outName = strings.TrimSuffix(file, ".tgz") + ".tar"
+		default:
+			return i18n.Errorf("未知的后缀，已跳过")
+		}
+	}
+
+	in, err := openInput(env, file, stdio)
+	if err != nil {
+		return pathErrorReason(err)
+	}
+	defer in.Close()
+	if file != "-" {
+		if info, err := os.Stat(resolvePath(env, file)); err == nil && info.IsDir() {
+			return i18n.Errorf("是一个目录")
+		}
+	}
+
+	var out io.Writer = stdio.Stdout
+	var outFile *os.File
+	if outName != "" {
+		if !keep && file != "-" {
+			// 完成后删除输入文件
+			if err := stdio.Check(AccessWrite, resolvePath(env, file)); err != nil {
+				return err
+			}
+		}
+		if err := stdio.Check(AccessWrite, resolvePath(env, outName)); err != nil {
+			return err
+		}
+		flags := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+		if force {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		}
+		outFile, err = os.OpenFile(resolvePath(env, outName), flags, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			return i18n.Errorf("%s 已存在（使用 -f 覆盖）", outName)
+		}
+		if err != nil {
+			return pathErrorReason(err)
+		}
+		out = outFile
+	}
+
+	if decompress {
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(in); err == nil {
+			_, err = io.Copy(out, zr)
+		}
+		if err != nil {
+			err = i18n.Errorf("无效的 gzip 数据: %v", err)
+		}
+	} else {
+		var zw *gzip.Writer
+		zw, err = gzip.NewWriterLevel(out, level)
+		if err == nil {
+			if file != "-" {
+				zw.Name = filepath.Base(file)
+			}
+			if _, err = io.Copy(zw, in); err == nil {
+				err = zw.Close()
+			}
+		}
+	}
+	if outFile != nil {
+		if closeErr := outFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(outFile.Name())
+			return err
+		}
+		if !keep {
+			in.Close()
+			os.Remove(resolvePath(env, file))
+		}
+	}
+	return err
+}
@@ -2,17 +2,30 @@
 // 
 // 内置命令是shell的核心功能，包括：
 // - 目录操作：cd, pwd
+// - 输入输出：echo, printf, read
 // - 文件操作：ls, cat, mkdir, rmdir, rm, touch, clear
+// - 磁盘空间：du, df
+// - 文件信息：stat
+// - 路径处理：basename, dirname, realpath, mktemp
 // - 文本处理：head, tail, wc, grep, sort, uniq, cut
-// - 环境变量：export, unset, env, set
-// - 控制命令：exit, alias, unalias, history, which, type, true, false, test
-// - 作业控制：jobs, fg, bg
+// - 文件比较：diff, cmp
+// - 编码和校验：base64, md5sum, sha256sum
+// - 归档和压缩：tar, gzip, gunzip
+// - 时间：date, sleep, timeout
+// - 资源限制：umask, ulimit
+// - 环境变量：export, unset, env, set, declare
+// - 算术：let, expr
+// - 控制命令：exit, return, trap, alias, unalias, history, which, type, true, false, test
+// - 作业控制：jobs, fg, bg, kill, wait
+// - 进程：ps, pgrep, pkill
+// - 网络：http
 //
-// 所有内置命令都遵循 BuiltinFunc 函数签名，接收参数列表和环境变量映射。
+// 所有内置命令都遵循 BuiltinFunc 函数签名，接收参数列表、环境变量映射和标准输入输出。
 package builtin
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -22,6 +35,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"gobash/internal/i18n"
+	"gobash/internal/lexer"
 )
 
 // ExitError 表示 exit 命令，包含退出码
@@ -33,11 +50,101 @@ func (e *ExitError) Error() string {
 	return fmt.Sprintf("exit %d", e.Code)
 }
 
+// ExitCode 返回 exit 指定的退出码
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Position exit 的位置由执行器记录，这里未知
+func (e *ExitError) Position() lexer.Position {
+	return lexer.Position{}
+}
+
+// Unwrap exit 没有原始错误
+func (e *ExitError) Unwrap() error {
+	return nil
+}
+
+// ReturnError 表示 return 命令，包含函数的退出状态
+// 执行器在函数调用处把它转换为函数的退出状态
+type ReturnError struct {
+	Code int
+}
+
+func (e *ReturnError) Error() string {
+	return fmt.Sprintf("return %d", e.Code)
+}
+
+// ExitCode 返回 return 指定的退出状态
+func (e *ReturnError) ExitCode() int {
+	return e.Code
+}
+
+// StatusError 命令以指定的退出状态失败（如 ls 找不到文件时为 2）
+// Message 为空时不输出错误信息，执行器用它表示只有退出状态的失败（如 [[ ]] 和 (( )) 的条件为假），不需要启动进程
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// ExitCode 返回退出状态
+func (e *StatusError) ExitCode() int {
+	return e.Code
+}
+
+// Position 内置命令不知道自己在脚本中的位置
+func (e *StatusError) Position() lexer.Position {
+	return lexer.Position{}
+}
+
+// Unwrap 退出状态错误没有原始错误
+func (e *StatusError) Unwrap() error {
+	return nil
+}
+
+// errUnsupported 命令需要的系统功能在当前平台上不可用（如查询文件系统、列出进程）
+var errUnsupported = i18n.NewError("当前平台不支持")
+
+// IO 内置命令的标准输入、输出和错误输出
+// 内置命令只通过 IO 读写，不直接使用 os.Stdin/os.Stdout/os.Stderr，
+// 这样重定向、命令替换和嵌入方捕获输出时都不需要替换进程的全局标准流
+type IO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Context 命令执行的 context，被取消（执行被中断或超时）时 sleep 等需要等待的命令应该尽快返回，nil 表示不会被取消
+	Context context.Context
+	// Umask shell 的文件创建掩码，umask 命令修改它，创建文件的命令使用它；nil 表示使用默认掩码（进程的 umask）
+	Umask *Umask
+	// Policy 嵌入方设置的访问策略，执行外部命令、读写文件和访问网络之前检查（见 policy.go）；nil 表示允许所有操作
+	Policy Policy
+	// Exports shell 的导出表，env、xargs 等执行外部命令的命令只把导出的变量传给外部命令；nil 表示所有变量都导出
+	Exports *Exports
+}
+
+// ctx 返回命令执行的 context，没有设置时返回 context.Background()
+func (stdio *IO) ctx() context.Context {
+	if stdio.Context == nil {
+		return context.Background()
+	}
+	return stdio.Context
+}
+
+// StdIO 返回使用进程标准流的 IO
+func StdIO() *IO {
+	return &IO{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
 // BuiltinFunc 内置命令函数类型
 // 所有内置命令必须符合此函数签名
 // args: 命令参数列表
 // env: 环境变量映射，可以读取和修改环境变量
-type BuiltinFunc func(args []string, env map[string]string) error
+// stdio: 命令的标准输入输出（已经应用了命令上的重定向）
+type BuiltinFunc func(args []string, env map[string]string, stdio *IO) error
 
 var builtins map[string]BuiltinFunc
 
@@ -46,7 +153,10 @@ func init() {
 	builtins["cd"] = cd
 	builtins["pwd"] = pwd
 	builtins["echo"] = echo
+	builtins["printf"] = printfCommand
+	builtins["read"] = read
 	builtins["exit"] = exit
+	builtins["return"] = returnCmd
 	builtins["export"] = export
 	builtins["unset"] = unset
 	builtins["env"] = env
@@ -71,16 +181,52 @@ func init() {
 	builtins["tail"] = tail
 	builtins["wc"] = wc
 	builtins["grep"] = grep
+	builtins["sed"] = sed
 	builtins["sort"] = sortCmd
 	builtins["uniq"] = uniq
 	builtins["cut"] = cut
-	builtins["jobs"] = jobs
-	builtins["fg"] = fg
-	builtins["bg"] = bg
+	builtins["tr"] = tr
+	builtins["xargs"] = XargsBuiltin(nil)
+	builtins["date"] = date
+	builtins["sleep"] = sleep
+	builtins["timeout"] = TimeoutBuiltin(nil)
+	builtins["basename"] = basename
+	builtins["dirname"] = dirname
+	builtins["realpath"] = realpath
+	builtins["mktemp"] = mktemp
+	builtins["seq"] = seq
+	builtins["tee"] = tee
+	builtins["du"] = du
+	builtins["df"] = df
+	builtins["ps"] = ps
+	builtins["pgrep"] = pgrep
+	builtins["pkill"] = pkill
+	builtins["http"] = httpCmd
+	builtins["base64"] = base64Cmd
+	builtins["md5sum"] = md5sum
+	builtins["sha256sum"] = sha256sum
+	builtins["tar"] = tarCmd
+	builtins["gzip"] = gzipCmd
+	builtins["gunzip"] = gunzip
+	builtins["diff"] = diff
+	builtins["cmp"] = cmp
+	builtins["stat"] = stat
+	builtins["umask"] = umaskCmd
+	builtins["ulimit"] = ulimit
+	for name, fn := range JobBuiltins(nil) {
+		builtins[name] = fn
+	}
+	for name, fn := range DirStackBuiltins(NewDirStack()) {
+		builtins[name] = fn
+	}
 	builtins["declare"] = declare
+	builtins["readonly"] = readonly
 	builtins["shift"] = shift
 	builtins["local"] = local
+	builtins["let"] = let
+	builtins["expr"] = exprCmd
 	builtins["command"] = command
+	builtins["trap"] = TrapBuiltin(make(map[string]string))
 }
 
 // GetBuiltins 获取所有内置命令
@@ -90,34 +236,85 @@ func GetBuiltins() map[string]BuiltinFunc {
 	return builtins
 }
 
+// workDir 返回 shell 的当前工作目录（变量 PWD）
+// 每个 shell 有自己的工作目录，内置命令不使用也不修改进程的工作目录
+func workDir(env map[string]string) string {
+	if dir := env["PWD"]; dir != "" {
+		return dir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// resolvePath 将相对路径解析为相对于 shell 当前工作目录的路径
+func resolvePath(env map[string]string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workDir(env), path)
+}
+
+// homeDir 返回用户主目录（HOME，Windows 上为 USERPROFILE）
+func homeDir(env map[string]string) string {
+	if home := env["HOME"]; home != "" {
+		return home
+	}
+	return env["USERPROFILE"]
+}
+
 // cd 改变当前工作目录
-// 支持相对路径、绝对路径和~展开（用户主目录）
-// 如果没有参数，切换到用户主目录
-func cd(args []string, env map[string]string) error {
+// 用法：cd [-L|-P] [dir]
+// 没有参数时切换到用户主目录；cd - 切换到 OLDPWD 并输出新目录；
+// 相对路径（不以 . 或 .. 开头）会依次在 CDPATH 的各个目录中查找，找到时输出新目录；
+// -L（默认）按逻辑路径处理 ..，-P 解析符号链接得到物理路径
+// 工作目录记录在 PWD 中，不调用 os.Chdir，这样同一进程中的多个 shell 互不影响
+func cd(args []string, env map[string]string, stdio *IO) error {
+	physical := false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'L':
+				physical = false
+			case 'P':
+				physical = true
+			default:
+				return i18n.Errorf("cd: -%c: 无效选项\n用法: cd [-L|-P] [dir]", flag)
+			}
+		}
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return i18n.Errorf("cd: 参数太多")
+	}
+
 	var dir string
-	if len(args) == 0 {
+	printDir := false
+	switch {
+	case len(args) == 0:
 		// 没有参数，切换到home目录
-		home := os.Getenv("HOME")
-		if home == "" {
-			home = os.Getenv("USERPROFILE")
-		}
-		if home == "" {
+		dir = homeDir(env)
+		if dir == "" {
 			usr, err := user.Current()
 			if err != nil {
-				return err
+				return i18n.Errorf("cd: HOME 未设置")
 			}
 			dir = usr.HomeDir
-		} else {
-			dir = home
 		}
-	} else {
+	case args[0] == "-":
+		dir = env["OLDPWD"]
+		if dir == "" {
+			return i18n.Errorf("cd: OLDPWD 未设置")
+		}
+		printDir = true
+	default:
 		dir = args[0]
 		// 展开 ~
 		if strings.HasPrefix(dir, "~") {
-			home := os.Getenv("HOME")
-			if home == "" {
-				home = os.Getenv("USERPROFILE")
-			}
+			home := homeDir(env)
 			if home == "" {
 				usr, err := user.Current()
 				if err != nil {
@@ -129,52 +326,238 @@ func cd(args []string, env map[string]string) error {
 		}
 	}
 
-	if err := os.Chdir(dir); err != nil {
-		return fmt.Errorf("cd: %v", err)
+	target := ""
+	if found, ok := searchCDPath(env, dir); ok {
+		target = found
+		printDir = true
+	} else if physical && !filepath.IsAbs(dir) {
+		// 物理路径：先解析符号链接再处理 ..，不能先按字符串清理路径
+		target = workDir(env) + string(filepath.Separator) + dir
+	} else {
+		target = filepath.Clean(resolvePath(env, dir))
 	}
 
-	// 更新PWD环境变量
-	pwd, err := os.Getwd()
+	info, err := os.Stat(target)
 	if err != nil {
-		return err
+		return fmt.Errorf("cd: %s: %v", dir, pathErrorReason(err))
+	}
+	if !info.IsDir() {
+		return i18n.Errorf("cd: %s: 不是目录", dir)
 	}
-	env["PWD"] = pwd
-	os.Setenv("PWD", pwd)
+	if physical {
+		if resolved, err := filepath.EvalSymlinks(target); err == nil {
+			target = resolved
+		}
+	}
+
+	// 更新PWD和OLDPWD环境变量
+	env["OLDPWD"] = workDir(env)
+	env["PWD"] = target
 
+	if printDir {
+		fmt.Fprintln(stdio.Stdout, target)
+	}
 	return nil
 }
 
+// searchCDPath 在 CDPATH 的目录中查找 dir，返回找到的目录
+// 绝对路径以及以 . 或 .. 开头的路径不查找 CDPATH；CDPATH 中的空项表示当前目录，
+// 只有在非空项中找到时才返回 true（此时 cd 输出新目录）
+func searchCDPath(env map[string]string, dir string) (string, bool) {
+	cdpath := env["CDPATH"]
+	if cdpath == "" || filepath.IsAbs(dir) || dir == "." || dir == ".." ||
+		strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "../") {
+		return "", false
+	}
+	for _, base := range filepath.SplitList(cdpath) {
+		if base == "" {
+			// 空项表示当前目录，按普通的相对路径处理
+			if info, err := os.Stat(resolvePath(env, dir)); err == nil && info.IsDir() {
+				return "", false
+			}
+			continue
+		}
+		candidate := filepath.Clean(resolvePath(env, filepath.Join(base, dir)))
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
 // pwd 显示当前工作目录的绝对路径
-// 输出当前shell的工作目录
-func pwd(args []string, env map[string]string) error {
-	dir, err := os.Getwd()
-	if err != nil {
-		return err
+// 用法：pwd [-L|-P]，-P 输出解析了符号链接的物理路径
+func pwd(args []string, env map[string]string, stdio *IO) error {
+	dir := workDir(env)
+	for _, arg := range args {
+		switch arg {
+		case "-L":
+		case "-P":
+			if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+				dir = resolved
+			}
+		default:
+			return i18n.Errorf("pwd: %s: 无效选项\n用法: pwd [-L|-P]", arg)
+		}
 	}
-	fmt.Println(dir)
+	fmt.Fprintln(stdio.Stdout, dir)
 	return nil
 }
 
 // echo 输出文本到标准输出
-// 将所有参数用空格连接后输出，最后换行
-func echo(args []string, env map[string]string) error {
-	if len(args) == 0 {
-		fmt.Println()
-		return nil
+// 用法：echo [-neE] [参数...]
+// 将所有参数用空格连接后输出，最后换行；-n 不输出末尾的换行符，-e 解释转义序列，-E 不解释转义序列（默认）
+func echo(args []string, env map[string]string, stdio *IO) error {
+	// shopt -s xpg_echo 时默认解释转义序列
+	interpret := hasShellOption(env, "xpg_echo")
+	newline := true
+
+	// 开头的 -n、-e、-E 及其组合（如 -ne）是选项，遇到第一个不是选项的参数为止
+	for len(args) > 0 && isEchoOption(args[0]) {
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'n':
+				newline = false
+			case 'e':
+				interpret = true
+			case 'E':
+				interpret = false
+			}
+		}
+		args = args[1:]
 	}
+
 	output := strings.Join(args, " ")
-	// 如果输出为空字符串，至少打印一个换行
-	if output == "" {
-		fmt.Println()
-	} else {
-		fmt.Println(output)
+	if interpret {
+		var stop bool
+		output, stop = expandEchoEscapes(output)
+		if stop {
+			// \c 之后的内容（包括末尾的换行符）都不输出
+			newline = false
+		}
 	}
+	if newline {
+		output += "\n"
+	}
+	fmt.Fprint(stdio.Stdout, output)
 	return nil
 }
 
+// isEchoOption 检查参数是否是 echo 的选项（只由 n、e、E 组成）
+func isEchoOption(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	for _, flag := range arg[1:] {
+		if flag != 'n' && flag != 'e' && flag != 'E' {
+			return false
+		}
+	}
+	return true
+}
+
+// hasShellOption 检查 shopt 选项是否已启用（已启用的选项保存在 BASHOPTS 中，以冒号分隔）
+func hasShellOption(env map[string]string, name string) bool {
+	for _, opt := range strings.Split(env["BASHOPTS"], ":") {
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// expandEchoEscapes 解释 echo -e 的转义序列
+// 支持 \a \b \e \f \n \r \t \v \\、\0NNN（八进制）、\xHH（十六进制）、\uHHHH、\UHHHHHHHH；
+// 遇到 \c 时停止输出，第二个返回值为 true
+func expandEchoEscapes(s string) (string, bool) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'c':
+			return sb.String(), true
+		case 'e', 'E':
+			sb.WriteByte(0x1b)
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case '\\':
+			sb.WriteByte('\\')
+		case '0':
+			// \0 后最多 3 位八进制数字
+			value, n := parseEscapeDigits(s[i+1:], 8, 3)
+			sb.WriteByte(byte(value))
+			i += n
+		case 'x':
+			value, n := parseEscapeDigits(s[i+1:], 16, 2)
+			if n == 0 {
+				sb.WriteString("\\x")
+				break
+			}
+			sb.WriteByte(byte(value))
+			i += n
+		case 'u', 'U':
+			width := 4
+			if c == 'U' {
+				width = 8
+			}
+			value, n := parseEscapeDigits(s[i+1:], 16, width)
+			if n == 0 {
+				sb.WriteByte('\\')
+				sb.WriteByte(c)
+				break
+			}
+			sb.WriteRune(rune(value))
+			i += n
+		default:
+			// 不认识的转义序列原样输出
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), false
+}
+
+// parseEscapeDigits 从 s 开头解析最多 max 位 base 进制数字，返回数值和使用的字符数
+func parseEscapeDigits(s string, base, max int) (int, int) {
+	value, n := 0, 0
+	for n < max && n < len(s) {
+		digit := strings.IndexByte("0123456789abcdef", toLowerASCII(s[n]))
+		if digit < 0 || digit >= base {
+			break
+		}
+		value = value*base + digit
+		n++
+	}
+	return value, n
+}
+
+// toLowerASCII 把 ASCII 大写字母转换为小写
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
 // exit 退出shell
 // 返回 ExitError 而不是直接调用 os.Exit，以便调用者可以决定如何处理
-func exit(args []string, env map[string]string) error {
+func exit(args []string, env map[string]string, stdio *IO) error {
 	code := 0
 	if len(args) > 0 {
 		// 解析退出码
@@ -188,109 +571,103 @@ func exit(args []string, env map[string]string) error {
 	return &ExitError{Code: code}
 }
 
-// export 导出环境变量
-// 将变量设置到环境变量中，格式为 KEY=VALUE
-// 支持多个变量同时设置
-func export(args []string, env map[string]string) error {
-	if len(args) == 0 {
-		// 显示所有导出的环境变量
-		for k, v := range env {
-			fmt.Printf("export %s=%s\n", k, v)
+// ReturnBuiltin 返回 return 命令，inFunction 判断当前是否在函数中执行（为 nil 时总是允许）
+// return [n] 结束函数，退出状态为 n（取低 8 位），省略 n 时为最后一条命令的退出状态 $?；
+// 不在函数中时报错，退出状态为 2
+func ReturnBuiltin(inFunction func() bool) BuiltinFunc {
+	return func(args []string, env map[string]string, stdio *IO) error {
+		if inFunction != nil && !inFunction() {
+			return &StatusError{Code: 2, Message: i18n.T("只能在函数中使用 `return'")}
+		}
+		code, _ := strconv.Atoi(env["?"])
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				fmt.Fprintln(stdio.Stderr, i18n.Sprintf("return: %s: 需要数字参数", args[0]))
+				return &ReturnError{Code: 2}
+			}
+			code = n
 		}
-		return nil
+		return &ReturnError{Code: code & 0xff}
 	}
+}
 
-	// 处理参数，支持两种格式：
-	// 1. export VAR=value（一个参数）
-	// 2. export VAR value（两个参数，当 parser 将 VAR='value' 解析为两个参数时）
-	i := 0
-	for i < len(args) {
-		arg := args[i]
-		
-		// 检查是否包含 = 号
-		parts := strings.SplitN(arg, "=", 2)
-		if len(parts) == 2 {
-			// 格式：VAR=value
-			key := parts[0]
-			value := parts[1]
-			
-			// 移除引号（如果有）
-			if len(value) >= 2 {
-				if (value[0] == '"' && value[len(value)-1] == '"') ||
-					(value[0] == '\'' && value[len(value)-1] == '\'') {
-					value = value[1 : len(value)-1]
+// returnCmd 不检查调用位置的 return 命令（执行器会用 ReturnBuiltin 替换它）
+var returnCmd = ReturnBuiltin(nil)
+
+// UnsetBuiltin 返回 unset 命令：unsetVariable 删除变量、整个数组（名称 或 名称[@]）或数组元素（名称[下标]），
+// nameref 为 true 时删除名称引用本身，返回变量是否存在；unsetFunction 删除函数。二者为 nil 时只从 env 中删除变量
+// 用法：unset [-fnv] [名称 ...]；没有 -f、-v 时先删除变量，没有这个变量时删除同名函数；
+// 有名称不能删除（不是有效的标识符、只读变量）时退出状态为 1
+func UnsetBuiltin(unsetVariable func(name string, nameref bool) (bool, error), unsetFunction func(name string)) BuiltinFunc {
+	if unsetFunction == nil {
+		unsetFunction = func(name string) {}
+	}
+	return func(args []string, env map[string]string, stdio *IO) error {
+		functions, variables, nameref := false, false, false
+		for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+			if args[0] == "--" {
+				args = args[1:]
+				break
+			}
+			for _, flag := range args[0][1:] {
+				switch flag {
+				case 'f':
+					functions = true
+				case 'v':
+					variables = true
+				case 'n':
+					nameref = true
+				default:
+					return &StatusError{Code: 2, Message: i18n.Sprintf("unset: -%c: 无效选项\n%s", flag, i18n.T(unsetUsage))}
 				}
 			}
-			
-			env[key] = value
-			os.Setenv(key, value)
-			i++
-		} else {
-			// 格式：VAR 或 VAR value
-			key := arg
-			var value string
-			
-			// 检查是否有下一个参数作为值
-			if i+1 < len(args) {
-				// 下一个参数可能是值（当 parser 将 VAR='value' 解析为两个参数时）
-				nextArg := args[i+1]
-				// 检查下一个参数是否看起来像值（不是变量名格式）
-				// 如果下一个参数不包含 = 且不是纯变量名，则将其作为值
-				if !strings.Contains(nextArg, "=") {
-					value = nextArg
-					// 移除引号（如果有）
-					if len(value) >= 2 {
-						if (value[0] == '"' && value[len(value)-1] == '"') ||
-							(value[0] == '\'' && value[len(value)-1] == '\'') {
-							value = value[1 : len(value)-1]
-						}
-					}
-					env[key] = value
-					os.Setenv(key, value)
-					i += 2
+			args = args[1:]
+		}
+		var failed []string
+		for _, name := range args {
+			if functions {
+				unsetFunction(name)
+				continue
+			}
+			if base, _, _ := strings.Cut(name, "["); !isName(base) {
+				failed = append(failed, i18n.Sprintf("unset: `%s': 不是有效的标识符", name))
+				continue
+			}
+			_, found := env[name]
+			if unsetVariable != nil {
+				var err error
+				if found, err = unsetVariable(name, nameref); err != nil {
+					failed = append(failed, "unset: "+err.Error())
 					continue
 				}
+			} else {
+				delete(env, name)
 			}
-			
-			// 只设置变量名，使用现有值
-			if existingValue, ok := env[key]; ok {
-				os.Setenv(key, existingValue)
+			if !found && !variables {
+				unsetFunction(name)
 			}
-			i++
 		}
+		if len(failed) > 0 {
+			return &StatusError{Code: 1, Message: strings.Join(failed, "\n")}
+		}
+		return nil
 	}
-
-	return nil
 }
 
-// unset 取消设置环境变量
-// 从环境变量映射中删除指定的变量
-// 支持同时删除多个变量
-func unset(args []string, env map[string]string) error {
-	for _, arg := range args {
-		delete(env, arg)
-		os.Unsetenv(arg)
-	}
-	return nil
-}
+// unsetUsage unset 的用法说明
+const unsetUsage = "用法: unset [-f] [-v] [-n] [名称 ...]"
 
-// env 显示环境变量
-func env(args []string, env map[string]string) error {
-	for k, v := range env {
-		fmt.Printf("%s=%s\n", k, v)
-	}
-	return nil
-}
+// unset 只删除 env 中变量的 unset 命令（执行器会用 UnsetBuiltin 替换它）
+var unset = UnsetBuiltin(nil, nil)
 
 // set 设置shell选项
 // 注意：set命令的实际处理在shell.go中的handleSetCommand函数中完成
 // 这个函数作为占位符，主要用于非交互式执行场景
-func set(args []string, env map[string]string) error {
+func set(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
 		// 显示所有变量
-		for k, v := range env {
-			fmt.Printf("%s=%s\n", k, v)
-		}
+		WriteVariables(stdio.Stdout, env, Variables{})
 		return nil
 	}
 	// set命令的选项处理在shell层完成（shell.go中的handleSetCommand）
@@ -300,142 +677,37 @@ func set(args []string, env map[string]string) error {
 			parts := strings.SplitN(arg, "=", 2)
 			if len(parts) == 2 {
 				env[parts[0]] = parts[1]
-				os.Setenv(parts[0], parts[1])
-			}
-		}
-	}
-	return nil
-}
-
-// ls 列出目录内容
-func ls(args []string, env map[string]string) error {
-	var path string
-	longFormat := false
-	showAll := false
-
-	// 解析参数
-	for i, arg := range args {
-		if strings.HasPrefix(arg, "-") {
-			if strings.Contains(arg, "l") {
-				longFormat = true
 			}
-			if strings.Contains(arg, "a") {
-				showAll = true
-			}
-		} else if i == len(args)-1 {
-			path = arg
-		}
-	}
-
-	if path == "" {
-		path = "."
-	}
-
-	// 展开 ~
-	if strings.HasPrefix(path, "~") {
-		home := os.Getenv("HOME")
-		if home == "" {
-			home = os.Getenv("USERPROFILE")
-		}
-		if home != "" {
-			path = strings.Replace(path, "~", home, 1)
-		}
-	}
-
-	file, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("ls: %v", err)
-	}
-	defer file.Close()
-
-	info, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("ls: %v", err)
-	}
-
-	if !info.IsDir() {
-		// 单个文件
-		if longFormat {
-			printFileInfo(info, info.Name())
-		} else {
-			fmt.Println(info.Name())
 		}
-		return nil
 	}
-
-	// 目录
-	entries, err := file.Readdir(-1)
-	if err != nil {
-		return fmt.Errorf("ls: %v", err)
-	}
-
-	// 排序
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Name() < entries[j].Name()
-	})
-
-	for _, entry := range entries {
-		name := entry.Name()
-		if !showAll && strings.HasPrefix(name, ".") {
-			continue
-		}
-
-		if longFormat {
-			printFileInfo(entry, name)
-		} else {
-			fmt.Print(name + "  ")
-		}
-	}
-
-	if !longFormat {
-		fmt.Println()
-	}
-
 	return nil
 }
 
-// printFileInfo 打印文件详细信息
-func printFileInfo(info os.FileInfo, name string) {
-	mode := info.Mode().String()
-	size := info.Size()
-	modTime := info.ModTime().Format("Jan 02 15:04")
-	dir := ""
-	if info.IsDir() {
-		dir = "d"
-	} else {
-		dir = "-"
-	}
-	fmt.Printf("%s%s %8d %s %s\n", dir, mode[1:10], size, modTime, name)
-}
-
 // cat 显示文件内容
 // 将指定文件的内容输出到标准输出
 // 支持多个文件，会依次显示
-func cat(args []string, env map[string]string) error {
+func cat(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
 		// 从stdin读取
-		_, err := io.Copy(os.Stdout, os.Stdin)
+		_, err := io.Copy(stdio.Stdout, stdio.Stdin)
 		return err
 	}
 
 	for _, filename := range args {
 		// 展开 ~
 		if strings.HasPrefix(filename, "~") {
-			home := os.Getenv("HOME")
-			if home == "" {
-				home = os.Getenv("USERPROFILE")
-			}
+			home := homeDir(env)
 			if home != "" {
 				filename = strings.Replace(filename, "~", home, 1)
 			}
 		}
 
-		file, err := os.Open(filename)
+		file, err := stdio.open(resolvePath(env, filename))
 		if err != nil {
 			return fmt.Errorf("cat: %v", err)
 		}
 
-		_, err = io.Copy(os.Stdout, file)
+		_, err = io.Copy(stdio.Stdout, file)
 		file.Close()
 		if err != nil {
 			return fmt.Errorf("cat: %v", err)
@@ -448,9 +720,9 @@ func cat(args []string, env map[string]string) error {
 // mkdir 创建目录
 // 支持 -p 选项创建父目录（如果不存在）
 // 支持同时创建多个目录
-func mkdir(args []string, env map[string]string) error {
+func mkdir(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
-		return fmt.Errorf("mkdir: 缺少操作数")
+		return i18n.Errorf("mkdir: 缺少操作数")
 	}
 
 	parents := false
@@ -467,22 +739,22 @@ func mkdir(args []string, env map[string]string) error {
 	for _, path := range paths {
 		// 展开 ~
 		if strings.HasPrefix(path, "~") {
-			home := os.Getenv("HOME")
-			if home == "" {
-				home = os.Getenv("USERPROFILE")
-			}
+			home := homeDir(env)
 			if home != "" {
 				path = strings.Replace(path, "~", home, 1)
 			}
 		}
 
+		if err := stdio.checkPath("mkdir", AccessWrite, resolvePath(env, path)); err != nil {
+			return fmt.Errorf("mkdir: %v", err)
+		}
 		if parents {
-			err := os.MkdirAll(path, 0755)
+			err := makeDir(resolvePath(env, path), true, stdio.umask())
 			if err != nil {
 				return fmt.Errorf("mkdir: %v", err)
 			}
 		} else {
-			err := os.Mkdir(path, 0755)
+			err := makeDir(resolvePath(env, path), false, stdio.umask())
 			if err != nil {
 				return fmt.Errorf("mkdir: %v", err)
 			}
@@ -495,24 +767,24 @@ func mkdir(args []string, env map[string]string) error {
 // rmdir 删除空目录
 // 只能删除空目录，如果目录不为空会返回错误
 // 支持同时删除多个目录
-func rmdir(args []string, env map[string]string) error {
+func rmdir(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
-		return fmt.Errorf("rmdir: 缺少操作数")
+		return i18n.Errorf("rmdir: 缺少操作数")
 	}
 
 	for _, path := range args {
 		// 展开 ~
 		if strings.HasPrefix(path, "~") {
-			home := os.Getenv("HOME")
-			if home == "" {
-				home = os.Getenv("USERPROFILE")
-			}
+			home := homeDir(env)
 			if home != "" {
 				path = strings.Replace(path, "~", home, 1)
 			}
 		}
 
-		err := os.Remove(path)
+		err := stdio.checkPath("remove", AccessWrite, resolvePath(env, path))
+		if err == nil {
+			err = os.Remove(resolvePath(env, path))
+		}
 		if err != nil {
 			return fmt.Errorf("rmdir: %v", err)
 		}
@@ -522,9 +794,9 @@ func rmdir(args []string, env map[string]string) error {
 }
 
 // rm 删除文件或目录
-func rm(args []string, env map[string]string) error {
+func rm(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
-		return fmt.Errorf("rm: 缺少操作数")
+		return i18n.Errorf("rm: 缺少操作数")
 	}
 
 	recursive := false
@@ -547,16 +819,13 @@ func rm(args []string, env map[string]string) error {
 	for _, path := range paths {
 		// 展开 ~
 		if strings.HasPrefix(path, "~") {
-			home := os.Getenv("HOME")
-			if home == "" {
-				home = os.Getenv("USERPROFILE")
-			}
+			home := homeDir(env)
 			if home != "" {
 				path = strings.Replace(path, "~", home, 1)
 			}
 		}
 
-		info, err := os.Stat(path)
+		info, err := os.Stat(resolvePath(env, path))
 		if err != nil {
 			if !force {
 				return fmt.Errorf("rm: %v", err)
@@ -564,14 +833,17 @@ func rm(args []string, env map[string]string) error {
 			continue
 		}
 
+		if err = stdio.checkPath("remove", AccessWrite, resolvePath(env, path)); err != nil {
+			return fmt.Errorf("rm: %v", err)
+		}
 		if info.IsDir() {
 			if recursive {
-				err = os.RemoveAll(path)
+				err = os.RemoveAll(resolvePath(env, path))
 			} else {
-				err = fmt.Errorf("rm: %s: 是一个目录", path)
+				err = i18n.Errorf("rm: %s: 是一个目录", path)
 			}
 		} else {
-			err = os.Remove(path)
+			err = os.Remove(resolvePath(env, path))
 		}
 
 		if err != nil && !force {
@@ -583,24 +855,21 @@ func rm(args []string, env map[string]string) error {
 }
 
 // touch 创建文件或更新文件时间戳
-func touch(args []string, env map[string]string) error {
+func touch(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
-		return fmt.Errorf("touch: 缺少操作数")
+		return i18n.Errorf("touch: 缺少操作数")
 	}
 
 	for _, filename := range args {
 		// 展开 ~
 		if strings.HasPrefix(filename, "~") {
-			home := os.Getenv("HOME")
-			if home == "" {
-				home = os.Getenv("USERPROFILE")
-			}
+			home := homeDir(env)
 			if home != "" {
 				filename = strings.Replace(filename, "~", home, 1)
 			}
 		}
 
-		file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, 0644)
+		file, err := stdio.create(resolvePath(env, filename), os.O_CREATE|os.O_WRONLY, 0666)
 		if err != nil {
 			return fmt.Errorf("touch: %v", err)
 		}
@@ -608,22 +877,22 @@ func touch(args []string, env map[string]string) error {
 
 		// 更新时间戳
 		now := time.Now()
-		os.Chtimes(filename, now, now)
+		os.Chtimes(resolvePath(env, filename), now, now)
 	}
 
 	return nil
 }
 
 // clear 清屏
-func clear(args []string, env map[string]string) error {
+func clear(args []string, env map[string]string, stdio *IO) error {
 	// Windows使用cls，Unix使用clear
-	fmt.Print("\033[2J\033[H")
+	fmt.Fprint(stdio.Stdout, "\033[2J\033[H")
 	return nil
 }
 
 // alias 设置或显示别名
 // 注意：这个函数需要通过shell来访问别名map，使用环境变量作为通信机制
-func alias(args []string, env map[string]string) error {
+func alias(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
 		// 显示所有别名 - 通过环境变量获取
 		aliasesStr := env["__WBASH_ALIASES__"]
@@ -634,7 +903,7 @@ func alias(args []string, env map[string]string) error {
 		parts := strings.Split(aliasesStr, ";")
 		for _, part := range parts {
 			if part != "" {
-				fmt.Println("alias " + part)
+				fmt.Fprintln(stdio.Stdout, "alias " + part)
 			}
 		}
 		return nil
@@ -656,7 +925,7 @@ func alias(args []string, env map[string]string) error {
 				parts := strings.Split(aliasesStr, ";")
 				for _, part := range parts {
 					if strings.HasPrefix(part, name+"=") {
-						fmt.Println("alias " + part)
+						fmt.Fprintln(stdio.Stdout, "alias " + part)
 						return nil
 					}
 				}
@@ -668,9 +937,9 @@ func alias(args []string, env map[string]string) error {
 }
 
 // unalias 取消设置别名
-func unalias(args []string, env map[string]string) error {
+func unalias(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
-		return fmt.Errorf("unalias: 缺少操作数")
+		return i18n.Errorf("unalias: 缺少操作数")
 	}
 
 	for _, name := range args {
@@ -687,178 +956,77 @@ func unalias(args []string, env map[string]string) error {
 }
 
 // history 显示命令历史（简化版，实际由shell处理）
-func history(args []string, env map[string]string) error {
+func history(args []string, env map[string]string, stdio *IO) error {
 	// history命令由shell直接处理，这里只是占位
 	return nil
 }
 
-// which 查找命令路径
-func which(args []string, env map[string]string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("which: 缺少操作数")
-	}
-
-	for _, cmdName := range args {
-		// 检查是否为内置命令
-		if _, ok := builtins[cmdName]; ok {
-			fmt.Printf("%s: shell builtin\n", cmdName)
-			continue
-		}
-
-		// 检查PATH环境变量
-		pathEnv := os.Getenv("PATH")
-		if pathEnv == "" {
-			continue
-		}
-
-		paths := strings.Split(pathEnv, ":")
-		if len(paths) == 0 {
-			// Windows使用分号分隔
-			paths = strings.Split(pathEnv, ";")
-		}
-
-		found := false
-		for _, path := range paths {
-			if path == "" {
-				continue
-			}
-			fullPath := filepath.Join(path, cmdName)
-			// Windows需要添加.exe扩展名
-			if _, err := os.Stat(fullPath); err == nil {
-				fmt.Println(fullPath)
-				found = true
-				break
-			}
-			// 尝试添加.exe
-			if _, err := os.Stat(fullPath + ".exe"); err == nil {
-				fmt.Println(fullPath + ".exe")
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			// 命令未找到，但不报错（与bash行为一致）
-		}
-	}
-
-	return nil
-}
-
-// typeCmd 显示命令类型
-func typeCmd(args []string, env map[string]string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("type: 缺少操作数")
-	}
-
-	for _, cmdName := range args {
-		// 检查是否为内置命令
-		if _, ok := builtins[cmdName]; ok {
-			fmt.Printf("%s is a shell builtin\n", cmdName)
-			continue
-		}
-
-		// 检查是否为别名（通过环境变量，实际由shell处理）
-		// 这里简化处理，只检查内置命令和外部命令
-
-		// 检查PATH环境变量
-		pathEnv := os.Getenv("PATH")
-		if pathEnv != "" {
-			paths := strings.Split(pathEnv, ":")
-			if len(paths) == 0 {
-				paths = strings.Split(pathEnv, ";")
-			}
-
-			found := false
-			for _, path := range paths {
-				if path == "" {
-					continue
-				}
-				fullPath := filepath.Join(path, cmdName)
-				if _, err := os.Stat(fullPath); err == nil {
-					fmt.Printf("%s is %s\n", cmdName, fullPath)
-					found = true
-					break
-				}
-				if _, err := os.Stat(fullPath + ".exe"); err == nil {
-					fmt.Printf("%s is %s\n", cmdName, fullPath+".exe")
-					found = true
-					break
-				}
-			}
-
-			if found {
-				continue
-			}
-		}
-
-		// 命令未找到
-		fmt.Printf("type: %s: not found\n", cmdName)
-	}
-
-	return nil
-}
-
 // trueCmd 总是成功返回
-func trueCmd(args []string, env map[string]string) error {
+func trueCmd(args []string, env map[string]string, stdio *IO) error {
 	return nil
 }
 
-// falseCmd 总是失败返回
-func falseCmd(args []string, env map[string]string) error {
-	return fmt.Errorf("false")
+// falseCmd 总是失败返回（退出状态为 1，不输出消息）
+func falseCmd(args []string, env map[string]string, stdio *IO) error {
+	return &StatusError{Code: 1}
 }
 
-// declare 声明变量或数组
-// 支持 -A 选项声明关联数组
-// 例如：declare -A arr
-func declare(args []string, env map[string]string) error {
-	if len(args) == 0 {
-		// 显示所有变量（简化实现）
-		return nil
-	}
-	
-	assocArray := false
-	var varName string
-	
-	// 解析参数
-	for _, arg := range args {
-		if arg == "-A" {
-			assocArray = true
-		} else if !strings.HasPrefix(arg, "-") {
-			varName = arg
-		}
-	}
-	
-	if varName != "" {
-		// 通过环境变量传递关联数组声明信息
-		if assocArray {
-			env["__WBASH_DECLARE_ASSOC__"] = varName
-		} else {
-			env["__WBASH_DECLARE_VAR__"] = varName
-		}
+// LocalBuiltin 返回使用变量表 vars 的 local 命令（local -n 在 vars 中声明名称引用）
+func LocalBuiltin(vars Variables) BuiltinFunc {
+	vars = vars.withDefaults()
+	return func(args []string, env map[string]string, stdio *IO) error {
+		return localCmd(vars, args, env, stdio)
 	}
-	
-	return nil
 }
 
-// local 声明局部变量（只能在函数内使用）
-func local(args []string, env map[string]string) error {
+// local 使用空变量表的 local 命令（执行器会用 LocalBuiltin 替换它）
+var local = LocalBuiltin(Variables{})
+
+// localCmd 声明局部变量（只能在函数内使用）
+// -n 声明局部的名称引用，函数返回时和局部变量一起删除
+func localCmd(vars Variables, args []string, env map[string]string, stdio *IO) error {
+	nameref := false
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'n':
+				nameref = true
+			case 'a', 'A', 'i', 'l', 'r', 't', 'u', 'x':
+				// 其他属性目前只接受，不改变变量的行为
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("local: -%c: 无效选项", flag)}
+			}
+		}
+		args = args[1:]
+	}
 	if len(args) == 0 {
-		return fmt.Errorf("local: 缺少变量名")
+		return i18n.Errorf("local: 缺少变量名")
 	}
 
 	// 检查是否在函数中（通过检查是否有 __WBASH_IN_FUNCTION__ 标记）
 	// 这个标记由 executor 在执行函数时设置
 	if _, ok := env["__WBASH_IN_FUNCTION__"]; !ok {
-		return fmt.Errorf("local: 只能在函数内使用")
+		return i18n.Errorf("local: 只能在函数内使用")
 	}
 
 	// 处理每个变量声明
 	// 注意：需要收集所有局部变量名，因为 executor 需要一次性处理
 	localVarNames := []string{}
 	
+	namerefs := vars.Namerefs()
 	for _, arg := range args {
+		if nameref {
+			name, target, hasTarget := strings.Cut(arg, "=")
+			if !isName(name) || (hasTarget && !isAssignable(target)) {
+				return i18n.Errorf("local: `%s': 不是有效的标识符", arg)
+			}
+			if hasTarget && ResolveNameref(namerefs, target) == name {
+				return i18n.Errorf("local: %s: 名称引用不能引用自身", name)
+			}
+			namerefs[name] = target
+			localVarNames = append(localVarNames, name)
+			continue
+		}
 		// 解析 VAR=value 格式
 		if strings.Contains(arg, "=") {
 			parts := strings.SplitN(arg, "=", 2)
@@ -893,33 +1061,34 @@ func local(args []string, env map[string]string) error {
 }
 
 // testCmd 测试条件（test命令和[命令）
-func testCmd(args []string, env map[string]string) error {
+// 与 bash 相同，条件为假时退出状态为 1（没有参数时也为假），表达式有错误时报告错误，退出状态为 2
+func testCmd(args []string, env map[string]string, stdio *IO) error {
 	// 处理 [ 命令，需要移除结尾的 ]
 	if len(args) > 0 && args[len(args)-1] == "]" {
 		args = args[:len(args)-1]
 	}
 	
 	if len(args) == 0 {
-		return fmt.Errorf("test: 缺少参数")
+		return &StatusError{Code: 1}
 	}
 	
 	// 解析测试表达式
-	result, err := evaluateTestExpression(args)
+	result, err := evaluateTestExpression(args, env)
 	if err != nil {
-		return err
+		return &StatusError{Code: 2, Message: err.Error()}
 	}
 	
 	if !result {
-		return fmt.Errorf("test failed")
+		return &StatusError{Code: 1}
 	}
 	
 	return nil
 }
 
 // evaluateTestExpression 计算测试表达式
-func evaluateTestExpression(args []string) (bool, error) {
+func evaluateTestExpression(args []string, env map[string]string) (bool, error) {
 	if len(args) == 0 {
-		return false, fmt.Errorf("test: 缺少参数")
+		return false, i18n.Errorf("test: 缺少参数")
 	}
 	
 	// 单参数：检查字符串是否非空
@@ -943,29 +1112,29 @@ func evaluateTestExpression(args []string) (bool, error) {
 		// 文件测试
 		switch op {
 		case "-f":
-			return testFile(value, func(info os.FileInfo) bool {
+			return testFile(resolvePath(env, value), func(info os.FileInfo) bool {
 				return !info.IsDir()
 			})
 		case "-d":
-			return testFile(value, func(info os.FileInfo) bool {
+			return testFile(resolvePath(env, value), func(info os.FileInfo) bool {
 				return info.IsDir()
 			})
 		case "-e":
-			return testFile(value, func(info os.FileInfo) bool {
+			return testFile(resolvePath(env, value), func(info os.FileInfo) bool {
 				return true
 			})
 		case "-r":
-			return testFile(value, func(info os.FileInfo) bool {
+			return testFile(resolvePath(env, value), func(info os.FileInfo) bool {
 				// 简化：检查文件是否存在
 				return true
 			})
 		case "-w":
-			return testFile(value, func(info os.FileInfo) bool {
+			return testFile(resolvePath(env, value), func(info os.FileInfo) bool {
 				// 简化：检查文件是否存在
 				return true
 			})
 		case "-x":
-			return testFile(value, func(info os.FileInfo) bool {
+			return testFile(resolvePath(env, value), func(info os.FileInfo) bool {
 				// 简化：检查文件是否存在
 				return true
 			})
@@ -1001,7 +1170,7 @@ func evaluateTestExpression(args []string) (bool, error) {
 		}
 	}
 	
-	return false, fmt.Errorf("test: 不支持的表达式")
+	return false, i18n.Errorf("test: 不支持的表达式")
 }
 
 // testFile 测试文件
@@ -1056,7 +1225,7 @@ func compareNumbers(left, right, op string) (bool, error) {
 }
 
 // head 显示文件的前几行
-func head(args []string, env map[string]string) error {
+func head(args []string, env map[string]string, stdio *IO) error {
 	n := 10 // 默认显示10行
 	files := []string{}
 	
@@ -1091,19 +1260,19 @@ func head(args []string, env map[string]string) error {
 	
 	// 如果没有指定文件，从stdin读取
 	if len(files) == 0 {
-		return headFromStdin(n)
+		return headFromStdin(n, stdio)
 	}
 	
 	// 处理多个文件
 	for i, file := range files {
 		if len(files) > 1 {
 			if i > 0 {
-				fmt.Println()
+				fmt.Fprintln(stdio.Stdout)
 			}
-			fmt.Printf("==> %s <==\n", file)
+			fmt.Fprintf(stdio.Stdout, "==> %s <==\n", file)
 		}
 		
-		if err := headFromFile(file, n); err != nil {
+		if err := headFromFile(resolvePath(env, file), n, stdio); err != nil {
 			return err
 		}
 	}
@@ -1112,8 +1281,8 @@ func head(args []string, env map[string]string) error {
 }
 
 // headFromFile 从文件读取前n行
-func headFromFile(filename string, n int) error {
-	file, err := os.Open(filename)
+func headFromFile(filename string, n int, stdio *IO) error {
+	file, err := stdio.open(filename)
 	if err != nil {
 		return fmt.Errorf("head: %v", err)
 	}
@@ -1123,7 +1292,7 @@ func headFromFile(filename string, n int) error {
 	lineCount := 0
 	
 	for scanner.Scan() && lineCount < n {
-		fmt.Println(scanner.Text())
+		fmt.Fprintln(stdio.Stdout, scanner.Text())
 		lineCount++
 	}
 	
@@ -1131,12 +1300,12 @@ func headFromFile(filename string, n int) error {
 }
 
 // headFromStdin 从stdin读取前n行
-func headFromStdin(n int) error {
-	scanner := bufio.NewScanner(os.Stdin)
+func headFromStdin(n int, stdio *IO) error {
+	scanner := bufio.NewScanner(stdio.Stdin)
 	lineCount := 0
 	
 	for scanner.Scan() && lineCount < n {
-		fmt.Println(scanner.Text())
+		fmt.Fprintln(stdio.Stdout, scanner.Text())
 		lineCount++
 	}
 	
@@ -1144,8 +1313,10 @@ func headFromStdin(n int) error {
 }
 
 // tail 显示文件的后几行
-func tail(args []string, env map[string]string) error {
+// -n N（或 -N）显示最后 N 行，-n +N 从第 N 行开始显示到末尾
+func tail(args []string, env map[string]string, stdio *IO) error {
 	n := 10 // 默认显示10行
+	fromStart := false
 	files := []string{}
 	
 	// 解析参数
@@ -1156,14 +1327,14 @@ func tail(args []string, env map[string]string) error {
 			// 解析 -n 选项
 			if strings.HasPrefix(arg, "-n") {
 				if len(arg) > 2 {
-					// -n5 格式
-					if num, err := strconv.Atoi(arg[2:]); err == nil {
-						n = num
+					// -n5、-n+5 格式
+					if num, plus, ok := tailCount(arg[2:]); ok {
+						n, fromStart = num, plus
 					}
 				} else if i+1 < len(args) {
-					// -n 5 格式
-					if num, err := strconv.Atoi(args[i+1]); err == nil {
-						n = num
+					// -n 5、-n +5 格式
+					if num, plus, ok := tailCount(args[i+1]); ok {
+						n, fromStart = num, plus
 						i++ // 跳过下一个参数
 					}
 				}
@@ -1179,19 +1350,19 @@ func tail(args []string, env map[string]string) error {
 	
 	// 如果没有指定文件，从stdin读取
 	if len(files) == 0 {
-		return tailFromStdin(n)
+		return tailLines(stdio.Stdin, n, fromStart, stdio)
 	}
 	
 	// 处理多个文件
 	for i, file := range files {
 		if len(files) > 1 {
 			if i > 0 {
-				fmt.Println()
+				fmt.Fprintln(stdio.Stdout)
 			}
-			fmt.Printf("==> %s <==\n", file)
+			fmt.Fprintf(stdio.Stdout, "==> %s <==\n", file)
 		}
 		
-		if err := tailFromFile(file, n); err != nil {
+		if err := tailFromFile(resolvePath(env, file), n, fromStart, stdio); err != nil {
 			return err
 		}
 	}
@@ -1199,44 +1370,42 @@ func tail(args []string, env map[string]string) error {
 	return nil
 }
 
-// tailFromFile 从文件读取后n行
-func tailFromFile(filename string, n int) error {
-	file, err := os.Open(filename)
+// tailCount 解析 tail -n 的行数：N 表示最后 N 行，+N 表示从第 N 行开始（fromStart 为 true）
+func tailCount(s string) (n int, fromStart bool, ok bool) {
+	fromStart = strings.HasPrefix(s, "+")
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
 	if err != nil {
-		return fmt.Errorf("tail: %v", err)
+		return 0, false, false
 	}
-	defer file.Close()
-	
-	// 读取所有行
-	scanner := bufio.NewScanner(file)
-	lines := []string{}
-	
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	
-	if err := scanner.Err(); err != nil {
-		return err
-	}
-	
-	// 显示最后n行
-	start := len(lines) - n
-	if start < 0 {
-		start = 0
+	if n < 0 {
+		n = -n
 	}
-	
-	for i := start; i < len(lines); i++ {
-		fmt.Println(lines[i])
+	return n, fromStart, true
+}
+
+// tailFromFile 从文件读取后n行（fromStart 时从第 n 行开始）
+func tailFromFile(filename string, n int, fromStart bool, stdio *IO) error {
+	file, err := stdio.open(filename)
+	if err != nil {
+		return fmt.Errorf("tail: %v", err)
 	}
-	
-	return nil
+	defer file.Close()
+	return tailLines(file, n, fromStart, stdio)
 }
 
-// tailFromStdin 从stdin读取后n行（简化实现，使用缓冲区）
-func tailFromStdin(n int) error {
-	scanner := bufio.NewScanner(os.Stdin)
+// tailLines 输出 r 的最后 n 行，fromStart 时输出从第 n 行开始的所有行
+func tailLines(r io.Reader, n int, fromStart bool, stdio *IO) error {
+	scanner := bufio.NewScanner(r)
+	if fromStart {
+		for line := 1; scanner.Scan(); line++ {
+			if line >= n {
+				fmt.Fprintln(stdio.Stdout, scanner.Text())
+			}
+		}
+		return scanner.Err()
+	}
+
 	lines := []string{}
-	
 	for scanner.Scan() {
 		lines = append(lines, scanner.Text())
 		// 只保留最后n行
@@ -1247,14 +1416,14 @@ func tailFromStdin(n int) error {
 	
 	// 显示所有行
 	for _, line := range lines {
-		fmt.Println(line)
+		fmt.Fprintln(stdio.Stdout, line)
 	}
 	
 	return scanner.Err()
 }
 
 // wc 统计行数、字数、字符数
-func wc(args []string, env map[string]string) error {
+func wc(args []string, env map[string]string, stdio *IO) error {
 	showLines := true
 	showWords := true
 	showChars := true
@@ -1299,7 +1468,7 @@ func wc(args []string, env map[string]string) error {
 	
 	// 如果没有指定文件，从stdin读取
 	if len(files) == 0 {
-		return wcFromStdin(showLines, showWords, showChars, showBytes, "")
+		return wcFromStdin(showLines, showWords, showChars, showBytes, "", stdio)
 	}
 	
 	// 处理多个文件
@@ -1309,13 +1478,13 @@ func wc(args []string, env map[string]string) error {
 	totalBytes := int64(0)
 	
 	for _, file := range files {
-		lines, words, chars, bytes, err := wcFromFile(file, showLines, showWords, showChars, showBytes)
+		lines, words, chars, bytes, err := wcFromFile(resolvePath(env, file), showLines, showWords, showChars, showBytes, stdio)
 		if err != nil {
 			return err
 		}
 		
 		// 显示统计结果
-		wcPrint(showLines, showWords, showChars, showBytes, lines, words, chars, bytes, file)
+		wcPrint(showLines, showWords, showChars, showBytes, lines, words, chars, bytes, file, stdio)
 		
 		totalLines += lines
 		totalWords += words
@@ -1325,15 +1494,15 @@ func wc(args []string, env map[string]string) error {
 	
 	// 如果有多个文件，显示总计
 	if len(files) > 1 {
-		wcPrint(showLines, showWords, showChars, showBytes, totalLines, totalWords, totalChars, totalBytes, "total")
+		wcPrint(showLines, showWords, showChars, showBytes, totalLines, totalWords, totalChars, totalBytes, "total", stdio)
 	}
 	
 	return nil
 }
 
 // wcFromFile 统计文件
-func wcFromFile(filename string, showLines, showWords, showChars, showBytes bool) (int64, int64, int64, int64, error) {
-	file, err := os.Open(filename)
+func wcFromFile(filename string, showLines, showWords, showChars, showBytes bool, stdio *IO) (int64, int64, int64, int64, error) {
+	file, err := stdio.open(filename)
 	if err != nil {
 		return 0, 0, 0, 0, fmt.Errorf("wc: %v", err)
 	}
@@ -1349,7 +1518,7 @@ func wcFromFile(filename string, showLines, showWords, showChars, showBytes bool
 		line := scanner.Text()
 		lines++
 		words += int64(len(strings.Fields(line)))
-		chars += int64(len(line)) + 1 // +1 for newline
+		chars += int64(utf8.RuneCountInString(line)) + 1 // 按字符计数，+1 for newline
 		bytes += int64(len(line)) + 1
 	}
 	
@@ -1361,8 +1530,8 @@ func wcFromFile(filename string, showLines, showWords, showChars, showBytes bool
 }
 
 // wcFromStdin 从stdin统计
-func wcFromStdin(showLines, showWords, showChars, showBytes bool, filename string) error {
-	scanner := bufio.NewScanner(os.Stdin)
+func wcFromStdin(showLines, showWords, showChars, showBytes bool, filename string, stdio *IO) error {
+	scanner := bufio.NewScanner(stdio.Stdin)
 	lines := int64(0)
 	words := int64(0)
 	chars := int64(0)
@@ -1372,7 +1541,7 @@ func wcFromStdin(showLines, showWords, showChars, showBytes bool, filename strin
 		line := scanner.Text()
 		lines++
 		words += int64(len(strings.Fields(line)))
-		chars += int64(len(line)) + 1 // +1 for newline
+		chars += int64(utf8.RuneCountInString(line)) + 1 // 按字符计数，+1 for newline
 		bytes += int64(len(line)) + 1
 	}
 	
@@ -1380,12 +1549,12 @@ func wcFromStdin(showLines, showWords, showChars, showBytes bool, filename strin
 		return err
 	}
 	
-	wcPrint(showLines, showWords, showChars, showBytes, lines, words, chars, bytes, filename)
+	wcPrint(showLines, showWords, showChars, showBytes, lines, words, chars, bytes, filename, stdio)
 	return nil
 }
 
 // wcPrint 打印统计结果
-func wcPrint(showLines, showWords, showChars, showBytes bool, lines, words, chars, bytes int64, filename string) {
+func wcPrint(showLines, showWords, showChars, showBytes bool, lines, words, chars, bytes int64, filename string, stdio *IO) {
 	parts := []string{}
 	
 	if showLines {
@@ -1415,181 +1584,11 @@ func wcPrint(showLines, showWords, showChars, showBytes bool, lines, words, char
 		result += " " + filename
 	}
 	
-	fmt.Println(result)
-}
-
-// grep 文本搜索（简化版）
-func grep(args []string, env map[string]string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("grep: 缺少操作数")
-	}
-	
-	// 解析参数
-	pattern := ""
-	files := []string{}
-	caseInsensitive := false
-	showLineNumbers := false
-	showOnlyMatches := false
-	
-	i := 0
-	for i < len(args) {
-		arg := args[i]
-		if strings.HasPrefix(arg, "-") {
-			// 解析选项
-			for _, ch := range arg[1:] {
-				switch ch {
-				case 'i':
-					caseInsensitive = true
-				case 'n':
-					showLineNumbers = true
-				case 'o':
-					showOnlyMatches = true
-				}
-			}
-		} else if pattern == "" {
-			pattern = arg
-		} else {
-			files = append(files, arg)
-		}
-		i++
-	}
-	
-	if pattern == "" {
-		return fmt.Errorf("grep: 缺少模式")
-	}
-	
-	// 如果没有指定文件，从stdin读取
-	if len(files) == 0 {
-		return grepFromStdin(pattern, caseInsensitive, showLineNumbers, showOnlyMatches, "")
-	}
-	
-	// 处理多个文件
-	for i, file := range files {
-		if len(files) > 1 {
-			if i > 0 {
-				fmt.Println()
-			}
-		}
-		
-		if err := grepFromFile(file, pattern, caseInsensitive, showLineNumbers, showOnlyMatches, len(files) > 1); err != nil {
-			return err
-		}
-	}
-	
-	return nil
-}
-
-// grepFromFile 从文件搜索
-func grepFromFile(filename string, pattern string, caseInsensitive, showLineNumbers, showOnlyMatches, showFilename bool) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return fmt.Errorf("grep: %v", err)
-	}
-	defer file.Close()
-	
-	searchPattern := pattern
-	if caseInsensitive {
-		searchPattern = strings.ToLower(pattern)
-	}
-	
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		searchLine := line
-		if caseInsensitive {
-			searchLine = strings.ToLower(line)
-		}
-		
-		if strings.Contains(searchLine, searchPattern) {
-			prefix := ""
-			if showFilename {
-				prefix = filename + ":"
-			}
-			if showLineNumbers {
-				if prefix != "" {
-					prefix += fmt.Sprintf("%d:", lineNum)
-				} else {
-					prefix = fmt.Sprintf("%d:", lineNum)
-				}
-			}
-			
-			if showOnlyMatches {
-				// 只显示匹配的部分
-				start := strings.Index(searchLine, searchPattern)
-				if start >= 0 {
-					match := line[start : start+len(pattern)]
-					if prefix != "" {
-						fmt.Printf("%s%s\n", prefix, match)
-					} else {
-						fmt.Println(match)
-					}
-				}
-			} else {
-				if prefix != "" {
-					fmt.Printf("%s%s\n", prefix, line)
-				} else {
-					fmt.Println(line)
-				}
-			}
-		}
-	}
-	
-	return scanner.Err()
-}
-
-// grepFromStdin 从stdin搜索
-func grepFromStdin(pattern string, caseInsensitive, showLineNumbers, showOnlyMatches bool, filename string) error {
-	searchPattern := pattern
-	if caseInsensitive {
-		searchPattern = strings.ToLower(pattern)
-	}
-	
-	scanner := bufio.NewScanner(os.Stdin)
-	lineNum := 0
-	
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		searchLine := line
-		if caseInsensitive {
-			searchLine = strings.ToLower(line)
-		}
-		
-		if strings.Contains(searchLine, searchPattern) {
-			prefix := ""
-			if showLineNumbers {
-				prefix = fmt.Sprintf("%d:", lineNum)
-			}
-			
-			if showOnlyMatches {
-				// 只显示匹配的部分
-				start := strings.Index(searchLine, searchPattern)
-				if start >= 0 {
-					match := line[start : start+len(pattern)]
-					if prefix != "" {
-						fmt.Printf("%s%s\n", prefix, match)
-					} else {
-						fmt.Println(match)
-					}
-				}
-			} else {
-				if prefix != "" {
-					fmt.Printf("%s%s\n", prefix, line)
-				} else {
-					fmt.Println(line)
-				}
-			}
-		}
-	}
-	
-	return scanner.Err()
+	fmt.Fprintln(stdio.Stdout, result)
 }
 
 // sortCmd 排序（简化版）
-func sortCmd(args []string, env map[string]string) error {
+func sortCmd(args []string, env map[string]string, stdio *IO) error {
 	reverse := false
 	numeric := false
 	unique := false
@@ -1619,13 +1618,13 @@ func sortCmd(args []string, env map[string]string) error {
 	
 	// 如果没有指定文件，从stdin读取
 	if len(files) == 0 {
-		return sortFromStdin(reverse, numeric, unique)
+		return sortFromStdin(reverse, numeric, unique, stdio)
 	}
 	
 	// 处理多个文件
 	allLines := []string{}
 	for _, file := range files {
-		lines, err := readLinesFromFile(file)
+		lines, err := readLinesFromFile(resolvePath(env, file), stdio)
 		if err != nil {
 			return fmt.Errorf("sort: %v", err)
 		}
@@ -1637,15 +1636,15 @@ func sortCmd(args []string, env map[string]string) error {
 	
 	// 输出
 	for _, line := range sortedLines {
-		fmt.Println(line)
+		fmt.Fprintln(stdio.Stdout, line)
 	}
 	
 	return nil
 }
 
 // sortFromStdin 从stdin排序
-func sortFromStdin(reverse, numeric, unique bool) error {
-	scanner := bufio.NewScanner(os.Stdin)
+func sortFromStdin(reverse, numeric, unique bool, stdio *IO) error {
+	scanner := bufio.NewScanner(stdio.Stdin)
 	lines := []string{}
 	
 	for scanner.Scan() {
@@ -1661,15 +1660,15 @@ func sortFromStdin(reverse, numeric, unique bool) error {
 	
 	// 输出
 	for _, line := range sortedLines {
-		fmt.Println(line)
+		fmt.Fprintln(stdio.Stdout, line)
 	}
 	
 	return nil
 }
 
 // readLinesFromFile 从文件读取所有行
-func readLinesFromFile(filename string) ([]string, error) {
-	file, err := os.Open(filename)
+func readLinesFromFile(filename string, stdio *IO) ([]string, error) {
+	file, err := stdio.open(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -1741,7 +1740,7 @@ func sortLines(lines []string, reverse, numeric, unique bool) []string {
 }
 
 // uniq 去重（简化版）
-func uniq(args []string, env map[string]string) error {
+func uniq(args []string, env map[string]string, stdio *IO) error {
 	count := false
 	showOnlyDuplicates := false
 	ignoreCase := false
@@ -1771,12 +1770,12 @@ func uniq(args []string, env map[string]string) error {
 	
 	// 如果没有指定文件，从stdin读取
 	if len(files) == 0 {
-		return uniqFromStdin(count, showOnlyDuplicates, ignoreCase)
+		return uniqFromStdin(count, showOnlyDuplicates, ignoreCase, stdio)
 	}
 	
 	// 处理多个文件
 	for _, file := range files {
-		if err := uniqFromFile(file, count, showOnlyDuplicates, ignoreCase); err != nil {
+		if err := uniqFromFile(resolvePath(env, file), count, showOnlyDuplicates, ignoreCase, stdio); err != nil {
 			return err
 		}
 	}
@@ -1785,8 +1784,8 @@ func uniq(args []string, env map[string]string) error {
 }
 
 // uniqFromFile 从文件去重
-func uniqFromFile(filename string, count, showOnlyDuplicates, ignoreCase bool) error {
-	file, err := os.Open(filename)
+func uniqFromFile(filename string, count, showOnlyDuplicates, ignoreCase bool, stdio *IO) error {
+	file, err := stdio.open(filename)
 	if err != nil {
 		return fmt.Errorf("uniq: %v", err)
 	}
@@ -1820,7 +1819,7 @@ func uniqFromFile(filename string, count, showOnlyDuplicates, ignoreCase bool) e
 						output = fmt.Sprintf("%7d ", prevLineCount+1)
 					}
 					output += prevLine
-					fmt.Println(output)
+					fmt.Fprintln(stdio.Stdout, output)
 				}
 			}
 			prevLine = line
@@ -1836,7 +1835,7 @@ func uniqFromFile(filename string, count, showOnlyDuplicates, ignoreCase bool) e
 				output = fmt.Sprintf("%7d ", prevLineCount+1)
 			}
 			output += prevLine
-			fmt.Println(output)
+			fmt.Fprintln(stdio.Stdout, output)
 		}
 	}
 	
@@ -1844,8 +1843,8 @@ func uniqFromFile(filename string, count, showOnlyDuplicates, ignoreCase bool) e
 }
 
 // uniqFromStdin 从stdin去重
-func uniqFromStdin(count, showOnlyDuplicates, ignoreCase bool) error {
-	scanner := bufio.NewScanner(os.Stdin)
+func uniqFromStdin(count, showOnlyDuplicates, ignoreCase bool, stdio *IO) error {
+	scanner := bufio.NewScanner(stdio.Stdin)
 	prevLine := ""
 	prevLineCount := 0
 	
@@ -1871,7 +1870,7 @@ func uniqFromStdin(count, showOnlyDuplicates, ignoreCase bool) error {
 						output = fmt.Sprintf("%7d ", prevLineCount+1)
 					}
 					output += prevLine
-					fmt.Println(output)
+					fmt.Fprintln(stdio.Stdout, output)
 				}
 			}
 			prevLine = line
@@ -1887,7 +1886,7 @@ func uniqFromStdin(count, showOnlyDuplicates, ignoreCase bool) error {
 				output = fmt.Sprintf("%7d ", prevLineCount+1)
 			}
 			output += prevLine
-			fmt.Println(output)
+			fmt.Fprintln(stdio.Stdout, output)
 		}
 	}
 	
@@ -1,11 +1,33 @@
 package builtin
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
+// TestMain 使用中文消息运行测试（测试检查的是源代码中的中文错误消息）
+func TestMain(m *testing.M) {
+	i18n.SetLanguage(i18n.Chinese)
+	os.Exit(m.Run())
+}
+
 func TestEcho(t *testing.T) {
 	tests := []struct {
 		args     []string
@@ -17,20 +39,226 @@ func TestEcho(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		err := echo(tt.args, make(map[string]string))
+		var out bytes.Buffer
+		err := echo(tt.args, make(map[string]string), &IO{Stdout: &out})
 		if err != nil {
 			t.Errorf("echo命令执行失败: %v", err)
 		}
+		if got := out.String(); got != tt.expected+"\n" {
+			t.Errorf("echo %v 输出 %q，期望 %q", tt.args, got, tt.expected+"\n")
+		}
+	}
+}
+
+// TestEchoOptions 测试 echo 的 -n、-e、-E 选项和转义序列，以及 xpg_echo 选项
+func TestEchoOptions(t *testing.T) {
+	tests := []struct {
+		args     []string
+		bashopts string
+		expected string
+	}{
+		{[]string{"-n", "foo"}, "", "foo"},
+		{[]string{"-e", `a\tb\nc`}, "", "a\tb\nc\n"},
+		{[]string{"-E", `a\tb`}, "", "a\\tb\n"},
+		{[]string{`a\tb`}, "", "a\\tb\n"},
+		{[]string{"-ne", `x\x41\0101\u00e9`}, "", "xAAé"},
+		{[]string{"-e", `a\cb`, "c"}, "", "a"},
+		{[]string{"-e", `\q\\`}, "", "\\q\\\n"},
+		{[]string{"-eE", `a\n`}, "", "a\\n\n"},
+		{[]string{"--", "-n"}, "", "-- -n\n"},
+		{[]string{"-nx", "foo"}, "", "-nx foo\n"},
+		{[]string{"foo", "-n"}, "", "foo -n\n"},
+		{[]string{`a\tb`}, "xpg_echo", "a\tb\n"},
+		{[]string{"-E", `a\tb`}, "xpg_echo", "a\\tb\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		env := map[string]string{"BASHOPTS": tt.bashopts}
+		if err := echo(tt.args, env, &IO{Stdout: &out}); err != nil {
+			t.Errorf("echo %v 执行失败: %v", tt.args, err)
+		}
+		if got := out.String(); got != tt.expected {
+			t.Errorf("echo %v（BASHOPTS=%q）输出 %q，期望 %q", tt.args, tt.bashopts, got, tt.expected)
+		}
 	}
 }
 
 func TestPwd(t *testing.T) {
-	err := pwd([]string{}, make(map[string]string))
+	err := pwd([]string{}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("pwd命令执行失败: %v", err)
 	}
 }
 
+func TestCd(t *testing.T) {
+	root := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	real := filepath.Join(root, "real", "sub")
+	project := filepath.Join(root, "cdpath", "proj")
+	for _, dir := range []string{real, project} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+
+	env := map[string]string{"PWD": root}
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := cd(args, env, &IO{Stdout: &out}); err != nil {
+			t.Fatalf("cd %v 失败: %v", args, err)
+		}
+		return out.String()
+	}
+
+	// 相对路径，OLDPWD 记录之前的目录
+	run("real")
+	if env["PWD"] != filepath.Join(root, "real") || env["OLDPWD"] != root {
+		t.Errorf("cd real: PWD=%q OLDPWD=%q", env["PWD"], env["OLDPWD"])
+	}
+
+	// cd - 回到 OLDPWD 并输出新目录
+	if out := run("-"); out != root+"\n" {
+		t.Errorf("cd - 输出 %q，期望 %q", out, root+"\n")
+	}
+	if env["PWD"] != root || env["OLDPWD"] != filepath.Join(root, "real") {
+		t.Errorf("cd -: PWD=%q OLDPWD=%q", env["PWD"], env["OLDPWD"])
+	}
+
+	// 逻辑路径保留符号链接，.. 回到链接所在的目录
+	run("link")
+	if env["PWD"] != link {
+		t.Errorf("cd link: PWD=%q，期望 %q", env["PWD"], link)
+	}
+	run("..")
+	if env["PWD"] != root {
+		t.Errorf("cd ..: PWD=%q，期望 %q", env["PWD"], root)
+	}
+
+	// -P 解析符号链接
+	run("-P", "link")
+	if env["PWD"] != real {
+		t.Errorf("cd -P link: PWD=%q，期望 %q", env["PWD"], real)
+	}
+
+	// CDPATH 中找到的目录会输出
+	env["PWD"] = root
+	env["CDPATH"] = filepath.Join(root, "cdpath")
+	if out := run("proj"); out != project+"\n" {
+		t.Errorf("cd proj 输出 %q，期望 %q", out, project+"\n")
+	}
+	// 以 ./ 开头的路径不查找 CDPATH
+	env["PWD"] = root
+	if out := run("./real"); out != "" || env["PWD"] != filepath.Join(root, "real") {
+		t.Errorf("cd ./real 输出 %q，PWD=%q", out, env["PWD"])
+	}
+
+	// 错误情况
+	delete(env, "OLDPWD")
+	if err := cd([]string{"-"}, env, StdIO()); err == nil {
+		t.Error("OLDPWD 未设置时 cd - 应该失败")
+	}
+	if err := cd([]string{"nonexistent"}, env, StdIO()); err == nil {
+		t.Error("cd 到不存在的目录应该失败")
+	}
+}
+
+// TestDirStack 测试 pushd、popd、dirs：压栈、旋转、删除，并同步更新 PWD 和 OLDPWD
+func TestDirStack(t *testing.T) {
+	root := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	for _, dir := range []string{a, b} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+	}
+
+	env := map[string]string{"PWD": root, "HOME": root}
+	cmds := DirStackBuiltins(NewDirStack())
+	run := func(name string, args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := cmds[name](args, env, &IO{Stdout: &out}); err != nil {
+			t.Fatalf("%s %v 失败: %v", name, args, err)
+		}
+		return out.String()
+	}
+
+	if got := run("pushd", "a"); got != "~/a ~\n" {
+		t.Errorf("pushd a 输出 %q", got)
+	}
+	if env["PWD"] != a || env["OLDPWD"] != root {
+		t.Errorf("pushd a: PWD=%q OLDPWD=%q", env["PWD"], env["OLDPWD"])
+	}
+	if got := run("pushd", b); got != "~/b ~/a ~\n" {
+		t.Errorf("pushd b 输出 %q", got)
+	}
+
+	// 没有参数时交换栈顶的两个目录
+	if got := run("pushd"); got != "~/a ~/b ~\n" || env["PWD"] != a {
+		t.Errorf("pushd 输出 %q，PWD=%q", got, env["PWD"])
+	}
+	// +N 旋转目录栈
+	if got := run("pushd", "+2"); got != "~ ~/a ~/b\n" || env["PWD"] != root {
+		t.Errorf("pushd +2 输出 %q，PWD=%q", got, env["PWD"])
+	}
+	// -N 从栈底数
+	if got := run("dirs", "-0"); got != "~/b\n" {
+		t.Errorf("dirs -0 输出 %q", got)
+	}
+	if got := run("dirs", "-v", "-l"); got != " 0  "+root+"\n 1  "+a+"\n 2  "+b+"\n" {
+		t.Errorf("dirs -v -l 输出 %q", got)
+	}
+	if got := run("dirs", "-p"); got != "~\n~/a\n~/b\n" {
+		t.Errorf("dirs -p 输出 %q", got)
+	}
+
+	// popd +N 删除第 N 项，不切换目录
+	if got := run("popd", "+1"); got != "~ ~/b\n" || env["PWD"] != root {
+		t.Errorf("popd +1 输出 %q，PWD=%q", got, env["PWD"])
+	}
+	// popd 删除栈顶并切换到新的栈顶
+	if got := run("popd"); got != "~/b\n" || env["PWD"] != b || env["OLDPWD"] != root {
+		t.Errorf("popd 输出 %q，PWD=%q OLDPWD=%q", got, env["PWD"], env["OLDPWD"])
+	}
+
+	var out bytes.Buffer
+	if err := cmds["popd"](nil, env, &IO{Stdout: &out}); err == nil || !strings.Contains(err.Error(), "目录栈为空") {
+		t.Errorf("空目录栈 popd 应该报错，得到 %v", err)
+	}
+	if err := cmds["pushd"]([]string{"missing"}, env, &IO{Stdout: &out}); err == nil || env["PWD"] != b {
+		t.Errorf("pushd 不存在的目录应该报错且不改变 PWD，得到 %v，PWD=%q", err, env["PWD"])
+	}
+	if err := cmds["pushd"]([]string{"+5"}, env, &IO{Stdout: &out}); err == nil {
+		t.Error("pushd +5 超出范围应该报错")
+	}
+
+	// -n 只修改目录栈，dirs -c 清空目录栈
+	run("pushd", "-n", a)
+	if env["PWD"] != b {
+		t.Errorf("pushd -n 不应该切换目录，PWD=%q", env["PWD"])
+	}
+	if got := run("dirs"); got != "~/b ~/a\n" {
+		t.Errorf("pushd -n 后 dirs 输出 %q", got)
+	}
+	if got := run("dirs", "-c"); got != "" {
+		t.Errorf("dirs -c 不应该有输出，得到 %q", got)
+	}
+	if got := run("dirs", "-c", "-p"); got != "~/b\n" {
+		t.Errorf("dirs -c -p 输出 %q", got)
+	}
+}
+
 func TestExit(t *testing.T) {
 	// 测试exit命令（不会真正退出，因为测试环境）
 	// 这里只测试参数解析
@@ -54,7 +282,7 @@ func TestExport(t *testing.T) {
 	env := make(map[string]string)
 	
 	// 测试设置环境变量
-	err := export([]string{"TEST_VAR=test_value"}, env)
+	err := export([]string{"TEST_VAR=test_value"}, env, StdIO())
 	if err != nil {
 		t.Errorf("export命令执行失败: %v", err)
 	}
@@ -64,13 +292,65 @@ func TestExport(t *testing.T) {
 	}
 }
 
+func TestExportOptions(t *testing.T) {
+	exports := NewExports()
+	functions := map[string]string{"greet": "{ echo hi; }"}
+	run := ExportBuiltin(exports, func(name string) (string, bool) {
+		source, ok := functions[name]
+		return source, ok
+	})
+	env := map[string]string{"B": `say "$x"`, "A": "1", "?": "0", "__WBASH_IN_FUNCTION__": "1"}
+	call := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(args, env, &IO{Stdout: &out, Stderr: &out})
+		return out.String(), err
+	}
+
+	// 没有导出的变量不输出
+	if out, _ := call("-p"); out != "" {
+		t.Errorf("没有导出变量时 export -p 输出 %q", out)
+	}
+	call("A")
+	if _, err := call("B"); err != nil || !exports.IsExported("A") || !exports.IsExported("B") {
+		t.Errorf("export 失败: %v", err)
+	}
+	if out, _ := call("-p"); out != "declare -x A=\"1\"\ndeclare -x B=\"say \\\"\\$x\\\"\"\n" {
+		t.Errorf("export -p 输出 %q", out)
+	}
+	if _, err := call("-n", "A", "C=3"); err != nil || exports.IsExported("A") || exports.IsExported("C") || env["C"] != "3" {
+		t.Errorf("export -n 应该去掉导出属性并保留变量: %v %v", err, env)
+	}
+	if out, _ := call(); strings.Contains(out, "A=") {
+		t.Errorf("export 不应该输出 export -n 的变量: %q", out)
+	}
+	if _, err := call("A"); err != nil || !exports.IsExported("A") {
+		t.Errorf("export A 应该重新导出变量: %v", err)
+	}
+
+	if _, err := call("-f", "greet"); err != nil {
+		t.Errorf("export -f 失败: %v", err)
+	}
+	if out, _ := call("-pf"); out != "greet () { echo hi; }\ndeclare -fx greet\n" {
+		t.Errorf("export -pf 输出 %q", out)
+	}
+	if _, err := call("-f", "nosuch"); err == nil {
+		t.Error("导出不存在的函数应该失败")
+	}
+	if _, err := call("-fn", "greet"); err != nil || len(exports.Functions()) != 0 {
+		t.Errorf("export -fn 应该取消导出函数: %v %v", err, exports.Functions())
+	}
+	if _, err := call("1x=2"); err == nil {
+		t.Error("无效的变量名应该失败")
+	}
+}
+
 func TestUnset(t *testing.T) {
 	env := map[string]string{
 		"TEST_VAR": "test_value",
 	}
 	
 	// 测试取消设置环境变量
-	err := unset([]string{"TEST_VAR"}, env)
+	err := unset([]string{"TEST_VAR"}, env, StdIO())
 	if err != nil {
 		t.Errorf("unset命令执行失败: %v", err)
 	}
@@ -81,14 +361,14 @@ func TestUnset(t *testing.T) {
 }
 
 func TestTrue(t *testing.T) {
-	err := trueCmd([]string{}, make(map[string]string))
+	err := trueCmd([]string{}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("true命令执行失败: %v", err)
 	}
 }
 
 func TestFalse(t *testing.T) {
-	err := falseCmd([]string{}, make(map[string]string))
+	err := falseCmd([]string{}, make(map[string]string), StdIO())
 	if err == nil {
 		t.Error("false命令应该返回错误")
 	}
@@ -99,7 +379,7 @@ func TestMkdir(t *testing.T) {
 	testDir := filepath.Join(os.TempDir(), "gobash_test_mkdir")
 	defer os.RemoveAll(testDir)
 	
-	err := mkdir([]string{testDir}, make(map[string]string))
+	err := mkdir([]string{testDir}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("mkdir命令执行失败: %v", err)
 	}
@@ -115,7 +395,7 @@ func TestTouch(t *testing.T) {
 	testFile := filepath.Join(os.TempDir(), "gobash_test_touch.txt")
 	defer os.Remove(testFile)
 	
-	err := touch([]string{testFile}, make(map[string]string))
+	err := touch([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("touch命令执行失败: %v", err)
 	}
@@ -138,7 +418,7 @@ func TestCat(t *testing.T) {
 	}
 	
 	// 测试cat命令
-	err = cat([]string{testFile}, make(map[string]string))
+	err = cat([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("cat命令执行失败: %v", err)
 	}
@@ -146,19 +426,138 @@ func TestCat(t *testing.T) {
 
 func TestLs(t *testing.T) {
 	// 测试ls命令（列出当前目录）
-	err := ls([]string{}, make(map[string]string))
+	err := ls([]string{}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("ls命令执行失败: %v", err)
 	}
 	
 	// 测试ls特定目录
 	tempDir := os.TempDir()
-	err = ls([]string{tempDir}, make(map[string]string))
+	err = ls([]string{tempDir}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("ls命令执行失败: %v", err)
 	}
 }
 
+// TestLsOptions 测试 ls 的多个参数、-R、-S、-r、-h、按列输出和文件不存在时的退出状态
+func TestLsOptions(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "dir", "sub"), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	files := map[string]int{"big": 3000, "small": 2, ".hidden": 1, "dir/x": 0, "dir/sub/y": 0}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(root, name), make([]byte, size), 0644); err != nil {
+			t.Fatalf("创建文件失败: %v", err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := ls(args, map[string]string{"PWD": root, "COLUMNS": "20"}, &IO{Stdout: &out})
+		return out.String(), err
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{}, "big\ndir\nsmall\n"},
+		{[]string{"-A"}, ".hidden\nbig\ndir\nsmall\n"},
+		{[]string{"-r"}, "small\ndir\nbig\n"},
+		{[]string{"-C"}, "big  dir  small\n"},
+		{[]string{"-S", "small", "big"}, "big\nsmall\n"},
+		{[]string{"small", "dir", "big"}, "big\nsmall\n\ndir:\nsub\nx\n"},
+		{[]string{"-R", "dir"}, "dir:\nsub\nx\n\ndir/sub:\ny\n"},
+		{[]string{"-d", "dir"}, "dir\n"},
+	}
+	for _, tt := range tests {
+		got, err := run(tt.args...)
+		if err != nil {
+			t.Errorf("ls %v 执行失败: %v", tt.args, err)
+		}
+		if got != tt.expected {
+			t.Errorf("ls %v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+
+	// 超过宽度时分成多行，先从上到下排列
+	var out bytes.Buffer
+	printColumns(&out, []string{"aaaaaaaa", "bbbbbbbb", "cccccccc", "dd"}, 20)
+	if got := out.String(); got != "aaaaaaaa  cccccccc\nbbbbbbbb  dd\n" {
+		t.Errorf("printColumns 输出 %q", got)
+	}
+
+	if got, _ := run("-lh", "big"); !strings.Contains(got, "2.9K") {
+		t.Errorf("ls -lh big 输出 %q，期望包含 2.9K", got)
+	}
+
+	// 文件不存在时继续列出其他文件，退出状态为 2
+	got, err := run("missing", "small")
+	if got != "small\n" {
+		t.Errorf("ls missing small 输出 %q", got)
+	}
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.ExitCode() != 2 || !strings.Contains(statusErr.Error(), "missing") {
+		t.Errorf("ls missing small 应该返回退出状态 2 的错误，得到 %v", err)
+	}
+}
+
+// TestLsColor 测试 ls --color 按文件类型加颜色以及 LS_COLORS
+func TestLsColor(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	for name, perm := range map[string]os.FileMode{"a.tar": 0644, "plain": 0644, "run": 0755} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, perm); err != nil {
+			t.Fatalf("创建文件失败: %v", err)
+		}
+	}
+	if err := os.Symlink("missing", filepath.Join(root, "broken")); err != nil {
+		t.Skipf("不能创建符号链接: %v", err)
+	}
+
+	run := func(lsColors string, args ...string) string {
+		var out bytes.Buffer
+		env := map[string]string{"PWD": root, "LS_COLORS": lsColors}
+		if err := ls(args, env, &IO{Stdout: &out}); err != nil {
+			t.Errorf("ls %v 执行失败: %v", args, err)
+		}
+		return out.String()
+	}
+
+	expected := "a.tar\n\033[40;31;01mbroken\033[0m\n\033[01;34mdir\033[0m\nplain\n"
+	if runtime.GOOS != "windows" {
+		expected += "\033[01;32mrun\033[0m\n"
+	} else {
+		expected += "run\n"
+	}
+	if got := run("", "--color"); got != expected {
+		t.Errorf("ls --color 输出 %q，期望 %q", got, expected)
+	}
+	if got := run("*.tar=01;31", "--color=always", "a.tar"); got != "\033[01;31ma.tar\033[0m\n" {
+		t.Errorf("LS_COLORS 中的 *.tar 应该生效，输出 %q", got)
+	}
+	if got := run("di=04", "--color", "-d", "dir"); got != "\033[04mdir\033[0m\n" {
+		t.Errorf("LS_COLORS 应该覆盖目录的颜色，输出 %q", got)
+	}
+	// 输出不是终端时 --color=auto 不加颜色
+	if got := run("", "--color=auto"); strings.Contains(got, "\033[") {
+		t.Errorf("ls --color=auto 输出到管道时不应该有颜色，输出 %q", got)
+	}
+	var out bytes.Buffer
+	if err := ls([]string{"--color=sometimes"}, map[string]string{"PWD": root}, &IO{Stdout: &out}); err == nil {
+		t.Error("ls --color=sometimes 应该失败")
+	}
+
+	// 按列输出时颜色不占宽度
+	printColumns(&out, []string{"\033[01;34maaaaaaaa\033[0m", "bbbbbbbb"}, 18)
+	if got := out.String(); got != "\033[01;34maaaaaaaa\033[0m  bbbbbbbb\n" {
+		t.Errorf("printColumns 输出 %q", got)
+	}
+}
+
 func TestRm(t *testing.T) {
 	// 创建临时文件
 	testFile := filepath.Join(os.TempDir(), "gobash_test_rm.txt")
@@ -169,7 +568,7 @@ func TestRm(t *testing.T) {
 	}
 	
 	// 测试rm命令
-	err = rm([]string{testFile}, make(map[string]string))
+	err = rm([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("rm命令执行失败: %v", err)
 	}
@@ -189,7 +588,7 @@ func TestRmdir(t *testing.T) {
 	}
 	
 	// 测试rmdir命令
-	err = rmdir([]string{testDir}, make(map[string]string))
+	err = rmdir([]string{testDir}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("rmdir命令执行失败: %v", err)
 	}
@@ -219,7 +618,7 @@ func TestTestCmd(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := testCmd(tt.args, make(map[string]string))
+			err := testCmd(tt.args, make(map[string]string), StdIO())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("test命令 '%v' 错误，期望错误: %v，得到: %v", tt.args, tt.wantErr, err != nil)
 			}
@@ -229,38 +628,271 @@ func TestTestCmd(t *testing.T) {
 
 func TestTypeCmd(t *testing.T) {
 	// 测试type命令
-	err := typeCmd([]string{"echo"}, make(map[string]string))
+	err := typeCmd([]string{"echo"}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("type命令执行失败: %v", err)
 	}
 	
-	err = typeCmd([]string{"nonexistent_command"}, make(map[string]string))
+	err = typeCmd([]string{"nonexistent_command"}, make(map[string]string), StdIO())
 	// type命令对于不存在的命令应该输出"not found"，但不应该返回错误
 	if err != nil {
 		t.Logf("type命令对于不存在的命令返回: %v（这是可以接受的）", err)
 	}
 }
 
+func TestTypeOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 上需要 .exe 扩展名")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"tool", "echo"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := TypeBuiltin(Commands{
+		Alias: func(name string) (string, bool) {
+			return "ls -l", name == "ll"
+		},
+		Function: func(name string) (string, bool) {
+			return "{ echo hi; }", name == "greet"
+		},
+	})
+	env := map[string]string{"PATH": dir + string(filepath.ListSeparator) + filepath.Join(dir, "missing"), "PWD": dir}
+	call := func(args ...string) (string, error) {
+		var out, errOut bytes.Buffer
+		err := run(args, env, &IO{Stdout: &out, Stderr: &errOut})
+		return out.String(), err
+	}
+
+	tool := filepath.Join(dir, "tool")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ll"}, "ll is aliased to `ls -l'\n"},
+		{[]string{"greet"}, "greet is a function\ngreet () { echo hi; }\n"},
+		{[]string{"tool", "if"}, "tool is " + tool + "\nif is a shell keyword\n"},
+		{[]string{"-t", "ll", "greet", "cd", "tool", "while"}, "alias\nfunction\nbuiltin\nfile\nkeyword\n"},
+		{[]string{"-a", "echo"}, "echo is a shell builtin\necho is " + filepath.Join(dir, "echo") + "\n"},
+		{[]string{"-p", "tool", "echo"}, tool + "\n"},
+		{[]string{"-P", "echo"}, filepath.Join(dir, "echo") + "\n"},
+		{[]string{"./tool"}, "./tool is ./tool\n"},
+	}
+	for _, tt := range tests {
+		if out, err := call(tt.args...); err != nil || out != tt.want {
+			t.Errorf("type %v 输出 %q（%v），期望 %q", tt.args, out, err, tt.want)
+		}
+	}
+
+	out, err := call("-t", "nosuch", "cd")
+	var statusErr *StatusError
+	if out != "builtin\n" || !errors.As(err, &statusErr) || statusErr.Code != 1 {
+		t.Errorf("type -t nosuch cd 输出 %q（%v），期望退出状态 1", out, err)
+	}
+	if out, err := call("-f", "greet"); out != "" || err == nil {
+		t.Errorf("type -f 不应该查找函数: %q（%v）", out, err)
+	}
+}
+
 func TestEnv(t *testing.T) {
 	// 测试env命令
 	envMap := make(map[string]string)
 	envMap["TEST_VAR"] = "test_value"
 	
-	err := env([]string{}, envMap)
+	err := env([]string{}, envMap, StdIO())
 	if err != nil {
 		t.Errorf("env命令执行失败: %v", err)
 	}
 }
 
+func TestEnvCommand(t *testing.T) {
+	dir := t.TempDir()
+	base := map[string]string{"PWD": dir, "HOME": "/home/u", "FOO": "1"}
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := env(args, base, &IO{Stdin: strings.NewReader(""), Stdout: &out, Stderr: &out})
+		return out.String(), err
+	}
+
+	if out, err := run("-i", "B=2", "A=1"); err != nil || out != "A=1\nB=2\n" {
+		t.Errorf("env -i 输出 %q（%v）", out, err)
+	}
+	if out, _ := run("-u", "FOO", "-uHOME"); out != "PWD="+dir+"\n" {
+		t.Errorf("env -u 输出 %q", out)
+	}
+	// 内置命令使用构造的环境，-i 时保留工作目录
+	if out, err := run("-i", "X=y", "env"); err != nil || out != "PWD="+dir+"\nX=y\n" {
+		t.Errorf("env -i X=y env 输出 %q（%v）", out, err)
+	}
+	if base["X"] != "" || base["FOO"] != "1" {
+		t.Errorf("env 不应该修改当前环境: %v", base)
+	}
+
+	// 没有变量时外部命令得到空的环境，而不是继承当前进程的环境
+	if path, err := exec.LookPath("env"); err == nil {
+		if out, err := run("-i", path); err != nil || out != "" {
+			t.Errorf("env -i %s 输出 %q（%v），期望为空", path, out, err)
+		}
+	}
+
+	if _, err := exec.LookPath("sh"); err == nil {
+		out, err := run("-i", "A=hello", "sh", "-c", `echo "$A:$FOO"; pwd; exit 3`)
+		if out != "hello:\n"+dir+"\n" {
+			t.Errorf("env 外部命令输出 %q", out)
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Errorf("env 应该返回命令的退出状态: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"gobash-no-such-command"}, 127},
+		{[]string{"-z"}, 125},
+		{[]string{"-u"}, 125},
+	} {
+		_, err := run(tt.args...)
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.Code != tt.code {
+			t.Errorf("env %v 退出状态应该为 %d: %v", tt.args, tt.code, err)
+		}
+	}
+}
+
 func TestWhich(t *testing.T) {
 	// 测试which命令（查找echo命令）
-	err := which([]string{"echo"}, make(map[string]string))
+	err := which([]string{"echo"}, make(map[string]string), StdIO())
 	// which可能找不到echo（如果不在PATH中），这是可以接受的
 	if err != nil {
 		t.Logf("which命令返回: %v（可能是命令未找到，这是可以接受的）", err)
 	}
 }
 
+func TestWhichOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 上需要 PATHEXT 中的扩展名")
+	}
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	for _, path := range []string{filepath.Join(dir1, "tool"), filepath.Join(dir2, "tool"), filepath.Join(dir2, "other")} {
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 没有执行权限的文件不算匹配
+	if err := os.WriteFile(filepath.Join(dir1, "other"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"PATH": strings.Join([]string{dir1, dir2}, string(filepath.ListSeparator))}
+	call := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := which(args, env, &IO{Stdout: &out, Stderr: &out})
+		return out.String(), err
+	}
+
+	if out, err := call("tool", "other"); err != nil || out != filepath.Join(dir1, "tool")+"\n"+filepath.Join(dir2, "other")+"\n" {
+		t.Errorf("which 输出 %q（%v）", out, err)
+	}
+	if out, err := call("-a", "tool"); err != nil || out != filepath.Join(dir1, "tool")+"\n"+filepath.Join(dir2, "tool")+"\n" {
+		t.Errorf("which -a 输出 %q（%v）", out, err)
+	}
+	out, err := call("nosuch", "tool")
+	var statusErr *StatusError
+	if out != filepath.Join(dir1, "tool")+"\n" || !errors.As(err, &statusErr) || statusErr.Code != 1 {
+		t.Errorf("which 找不到命令时应该返回退出状态 1: %q（%v）", out, err)
+	}
+}
+
+func TestLookPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 上需要 PATHEXT 中的扩展名")
+	}
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "tool")
+	if err := os.WriteFile(tool, []byte("#!/usr/bin/env python3 -u\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"PATH": dir, "PWD": dir}
+
+	if path, err := LookPath("tool", env); err != nil || path != tool {
+		t.Errorf("LookPath(tool) = %q（%v）", path, err)
+	}
+	if path, err := LookPath("./tool", env); err != nil || path != tool {
+		t.Errorf("LookPath(./tool) 应该相对于 PWD: %q（%v）", path, err)
+	}
+	if _, err := LookPath("nosuch", env); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("LookPath 找不到命令时应该返回 exec.ErrNotFound: %v", err)
+	}
+	if name := TrimPathExt("tool.exe", env); name != "tool.exe" {
+		t.Errorf("非 Windows 系统上 TrimPathExt 不应该去掉扩展名: %q", name)
+	}
+
+	// #!/usr/bin/env prog 在 PATH 中查找 prog，#! 行中的参数放在脚本路径之前
+	python := filepath.Join(dir, "python3")
+	if err := os.WriteFile(python, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	argv, err := shebangCommand(tool, env)
+	if err != nil || strings.Join(argv, " ") != python+" -u "+tool {
+		t.Errorf("shebangCommand = %q（%v）", argv, err)
+	}
+	script := filepath.Join(dir, "script")
+	if err := os.WriteFile(script, []byte("#!"+python+"\necho hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if argv, err := shebangCommand(script, env); err != nil || strings.Join(argv, " ") != python+" "+script {
+		t.Errorf("shebangCommand = %q（%v）", argv, err)
+	}
+	if argv, err := shebangCommand(python, env); err != nil || argv != nil {
+		t.Errorf("没有 #! 行时 shebangCommand 应该返回 nil: %q（%v）", argv, err)
+	}
+
+	// 启用 selfexec 时找不到的 bash（#! 行指定的和作为命令的）用 ShellPath 代替，否则报告错误
+	helper := filepath.Join(dir, "helper.sh")
+	if err := os.WriteFile(helper, []byte("#!/usr/bin/env bash -e\necho hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path string) { ShellPath = path }(ShellPath)
+	ShellPath = python
+	if _, err := shebangCommand(helper, env); err == nil {
+		t.Errorf("没有启用 selfexec 时 shebangCommand 应该返回错误")
+	}
+	if _, err := Command(nil, "bash", []string{"helper.sh"}, env); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("没有启用 selfexec 时找不到 bash 应该返回 exec.ErrNotFound: %v", err)
+	}
+	env["GOBASH_SELF_EXEC"] = "1"
+	if argv, err := shebangCommand(helper, env); err != nil || strings.Join(argv, " ") != python+" "+helper {
+		t.Errorf("shebangCommand 应该使用 ShellPath: %q（%v）", argv, err)
+	}
+	cmd, err := Command(nil, "helper.sh", []string{"a"}, env)
+	if err != nil || cmd.Path != python || strings.Join(cmd.Args, " ") != python+" "+helper+" a" {
+		t.Errorf("Command 应该按 #! 行执行脚本: %v（%v）", cmd, err)
+	}
+	delete(env, "GOBASH_SELF_EXEC")
+	env["BASHOPTS"] = "selfexec"
+	cmd, err = Command(nil, "bash", []string{"helper.sh", "a"}, env)
+	if err != nil || cmd.Path != python || strings.Join(cmd.Args, " ") != "bash helper.sh a" {
+		t.Errorf("shopt -s selfexec 时 bash 命令应该使用 ShellPath: %v（%v）", cmd, err)
+	}
+}
+
+func TestBatchCommandLine(t *testing.T) {
+	line, err := batchCommandLine("cmd.exe", `C:\my tools\run.bat`, []string{"a b", `c"d`, "%PATH%", "x&y", "", "plain"})
+	want := `cmd.exe /e:ON /v:OFF /d /c ""C:\my tools\run.bat" "a b" "c""d" "%%cd:~,%PATH%%cd:~,%" "x&y" "" plain"`
+	if err != nil || line != want {
+		t.Errorf("batchCommandLine = %s（%v），期望 %s", line, err, want)
+	}
+	if _, err := batchCommandLine("cmd.exe", "run.bat", []string{"a\nb"}); err == nil {
+		t.Error("参数包含换行符时应该返回错误")
+	}
+	if !isBatchFile(`C:\x\RUN.CMD`) || isBatchFile("run.exe") {
+		t.Error("isBatchFile 应该按扩展名（不区分大小写）判断")
+	}
+}
+
 func TestHead(t *testing.T) {
 	// 创建临时文件
 	testFile := filepath.Join(os.TempDir(), "gobash_test_head.txt")
@@ -273,13 +905,13 @@ func TestHead(t *testing.T) {
 	}
 	
 	// 测试head命令（默认10行）
-	err = head([]string{testFile}, make(map[string]string))
+	err = head([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("head命令执行失败: %v", err)
 	}
 	
 	// 测试head -n 5
-	err = head([]string{"-n", "5", testFile}, make(map[string]string))
+	err = head([]string{"-n", "5", testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("head -n 5命令执行失败: %v", err)
 	}
@@ -297,16 +929,33 @@ func TestTail(t *testing.T) {
 	}
 	
 	// 测试tail命令（默认10行）
-	err = tail([]string{testFile}, make(map[string]string))
+	err = tail([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("tail命令执行失败: %v", err)
 	}
 	
 	// 测试tail -n 5
-	err = tail([]string{"-n", "5", testFile}, make(map[string]string))
+	err = tail([]string{"-n", "5", testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("tail -n 5命令执行失败: %v", err)
 	}
+
+	// 从标准输入读取；-n +N 从第 N 行开始
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-n", "2"}, "3\n4\n"},
+		{[]string{"-n", "+3"}, "3\n4\n"},
+		{[]string{"-n+2"}, "2\n3\n4\n"},
+		{[]string{"-n", "+0"}, "1\n2\n3\n4\n"},
+		{[]string{"-1"}, "4\n"},
+	} {
+		var out bytes.Buffer
+		if err := tail(tt.args, map[string]string{}, &IO{Stdin: strings.NewReader("1\n2\n3\n4\n"), Stdout: &out}); err != nil || out.String() != tt.want {
+			t.Errorf("tail %v 输出 %q（%v），期望 %q", tt.args, out.String(), err, tt.want)
+		}
+	}
 }
 
 func TestWc(t *testing.T) {
@@ -321,16 +970,28 @@ func TestWc(t *testing.T) {
 	}
 	
 	// 测试wc命令
-	err = wc([]string{testFile}, make(map[string]string))
+	err = wc([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("wc命令执行失败: %v", err)
 	}
 	
 	// 测试wc -l
-	err = wc([]string{"-l", testFile}, make(map[string]string))
+	err = wc([]string{"-l", testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("wc -l命令执行失败: %v", err)
 	}
+
+	// wc -m 按字符计数，wc -c 按字节计数
+	var out bytes.Buffer
+	err = wc([]string{"-m"}, make(map[string]string), &IO{Stdin: strings.NewReader("中文abc\n"), Stdout: &out})
+	if err != nil || strings.TrimSpace(out.String()) != "6" {
+		t.Errorf("wc -m 输出 %q（%v）", out.String(), err)
+	}
+	out.Reset()
+	wc([]string{"-c"}, make(map[string]string), &IO{Stdin: strings.NewReader("中文abc\n"), Stdout: &out})
+	if strings.TrimSpace(out.String()) != "10" {
+		t.Errorf("wc -c 输出 %q", out.String())
+	}
 }
 
 func TestGrep(t *testing.T) {
@@ -345,18 +1006,149 @@ func TestGrep(t *testing.T) {
 	}
 	
 	// 测试grep命令
-	err = grep([]string{"hello", testFile}, make(map[string]string))
+	err = grep([]string{"hello", testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("grep命令执行失败: %v", err)
 	}
 	
 	// 测试grep -i（忽略大小写）
-	err = grep([]string{"-i", "HELLO", testFile}, make(map[string]string))
+	err = grep([]string{"-i", "HELLO", testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("grep -i命令执行失败: %v", err)
 	}
 }
 
+// TestGrepOptions 测试 grep 的正则表达式、-v、-c、-l、-q、-r 以及退出状态
+func TestGrepOptions(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	files := map[string]string{
+		"a.txt":     "foo bar\nbaz\nfoo(1)\nFOO\n",
+		"sub/b.txt": "nothing\nfoo here\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("创建文件失败: %v", err)
+		}
+	}
+
+	var errOut bytes.Buffer
+	run := func(stdin string, args ...string) (string, int) {
+		var out bytes.Buffer
+		err := grep(args, map[string]string{"PWD": root}, &IO{Stdin: strings.NewReader(stdin), Stdout: &out, Stderr: &errOut})
+		status := 0
+		if err != nil {
+			status = 1
+			if statusErr, ok := err.(*StatusError); ok {
+				status = statusErr.ExitCode()
+			}
+		}
+		return out.String(), status
+	}
+
+	tests := []struct {
+		name     string
+		stdin    string
+		args     []string
+		expected string
+		status   int
+	}{
+		{"基本正则中的括号是普通字符", "", []string{"foo(1)", "a.txt"}, "foo(1)\n", 0},
+		{"基本正则的 \\{n\\}", "", []string{`o\{2\}`, "a.txt"}, "foo bar\nfoo(1)\n", 0},
+		{"扩展正则", "", []string{"-E", `^(baz|FOO)$`, "a.txt"}, "baz\nFOO\n", 0},
+		{"固定字符串", "", []string{"-F", "o(", "a.txt"}, "foo(1)\n", 0},
+		{"反向匹配", "", []string{"-v", "foo", "a.txt"}, "baz\nFOO\n", 0},
+		{"忽略大小写和行号", "", []string{"-in", "foo$", "a.txt"}, "4:FOO\n", 0},
+		{"计数", "", []string{"-c", "foo", "a.txt", "sub/b.txt"}, "a.txt:2\nsub/b.txt:1\n", 0},
+		{"列出文件名", "", []string{"-l", "foo", "a.txt", "sub/b.txt"}, "a.txt\nsub/b.txt\n", 0},
+		{"递归搜索", "", []string{"-r", "here", "."}, "./sub/b.txt:foo here\n", 0},
+		{"只输出匹配部分", "", []string{"-o", "ba.", "a.txt"}, "bar\nbaz\n", 0},
+		{"多个 -e", "", []string{"-e", "baz", "-e", "FOO", "a.txt"}, "baz\nFOO\n", 0},
+		{"整个单词", "", []string{"-w", "ba", "a.txt"}, "", 1},
+		{"整行", "x\ny\n", []string{"-x", "y"}, "y\n", 0},
+		{"quiet 有匹配", "", []string{"-q", "foo", "a.txt"}, "", 0},
+		{"没有匹配", "", []string{"zzz", "a.txt"}, "", 1},
+		{"文件不存在", "", []string{"foo", "missing"}, "", 2},
+		{"quiet 时有匹配就成功", "", []string{"-q", "foo", "missing", "a.txt"}, "", 0},
+		{"无效的正则表达式", "", []string{"-E", "(", "a.txt"}, "", 2},
+		{"-s 不输出错误消息", "", []string{"-s", "foo", "missing"}, "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, status := run(tt.stdin, tt.args...)
+			if got != tt.expected || status != tt.status {
+				t.Errorf("grep %v 输出 %q（退出状态 %d），期望 %q（退出状态 %d）", tt.args, got, status, tt.expected, tt.status)
+			}
+		})
+	}
+
+	// 文件不存在的错误消息输出到标准错误，没有匹配时不输出消息
+	errOut.Reset()
+	if _, status := run("", "zzz", "a.txt"); status != 1 || errOut.Len() != 0 {
+		t.Errorf("没有匹配时退出状态 %d，标准错误 %q", status, errOut.String())
+	}
+	if run("", "foo", "missing"); !strings.HasPrefix(errOut.String(), "grep: missing") {
+		t.Errorf("文件不存在时标准错误 %q", errOut.String())
+	}
+}
+
+// TestSed 测试 sed 的 s 命令及其标志、地址范围、-n 和 p、d、q 命令
+func TestSed(t *testing.T) {
+	input := "one\ntwo\nthree\nfour\nfive\n"
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"s/o/0/"}, "0ne\ntw0\nthree\nf0ur\nfive\n"},
+		{[]string{"s/e/E/g"}, "onE\ntwo\nthrEE\nfour\nfivE\n"},
+		{[]string{"s/e/E/2"}, "one\ntwo\nthreE\nfour\nfive\n"},
+		{[]string{"s/O/_/i"}, "_ne\ntw_\nthree\nf_ur\nfive\n"},
+		{[]string{"-n", "s/two/2/p"}, "2\n"},
+		{[]string{"-n", "2,4p"}, "two\nthree\nfour\n"},
+		{[]string{"-n", "$p"}, "five\n"},
+		{[]string{"/two/,/four/d"}, "one\nfive\n"},
+		{[]string{"3,$d"}, "one\ntwo\n"},
+		{[]string{"-n", "/^t/!p"}, "one\nfour\nfive\n"},
+		{[]string{"2q"}, "one\ntwo\n"},
+		{[]string{`s/\(t\)\(w\)/\2\1/;3,$d`}, "one\nwto\n"},
+		{[]string{"-E", `s/^(f)(.*)$/\2-\1 [&]/`}, "one\ntwo\nthree\nour-f [four]\nive-f [five]\n"},
+		{[]string{"-e", "1d", "-e", "s|e$|E|"}, "two\nthreE\nfour\nfivE\n"},
+		{[]string{"-n", "/three/="}, "3\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := sed(tt.args, map[string]string{}, &IO{Stdin: strings.NewReader(input), Stdout: &out})
+		if err != nil {
+			t.Errorf("sed %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.expected {
+			t.Errorf("sed %v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+
+	for _, script := range []string{"x", "s/a/b", "s/a/b/z", "/a"} {
+		if err := sed([]string{script}, map[string]string{}, &IO{Stdin: strings.NewReader(input), Stdout: &bytes.Buffer{}}); err == nil {
+			t.Errorf("sed %q 应该报错", script)
+		}
+	}
+
+	// -i 直接修改文件
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(file, []byte(input), 0644); err != nil {
+		t.Fatalf("创建文件失败: %v", err)
+	}
+	if err := sed([]string{"-i", "2,$d", "f.txt"}, map[string]string{"PWD": dir}, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("sed -i 执行失败: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "one\n" {
+		t.Errorf("sed -i 后文件内容 %q", data)
+	}
+}
+
 func TestSort(t *testing.T) {
 	// 创建临时文件
 	testFile := filepath.Join(os.TempDir(), "gobash_test_sort.txt")
@@ -369,13 +1161,13 @@ func TestSort(t *testing.T) {
 	}
 	
 	// 测试sort命令
-	err = sortCmd([]string{testFile}, make(map[string]string))
+	err = sortCmd([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("sort命令执行失败: %v", err)
 	}
 	
 	// 测试sort -r（反向排序）
-	err = sortCmd([]string{"-r", testFile}, make(map[string]string))
+	err = sortCmd([]string{"-r", testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("sort -r命令执行失败: %v", err)
 	}
@@ -393,7 +1185,7 @@ func TestUniq(t *testing.T) {
 	}
 	
 	// 测试uniq命令
-	err = uniq([]string{testFile}, make(map[string]string))
+	err = uniq([]string{testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("uniq命令执行失败: %v", err)
 	}
@@ -411,17 +1203,1559 @@ func TestCut(t *testing.T) {
 	}
 	
 	// 测试cut命令
-	err = cut([]string{"-d", ",", "-f", "1", testFile}, make(map[string]string))
+	err = cut([]string{"-d", ",", "-f", "1", testFile}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("cut命令执行失败: %v", err)
 	}
 }
 
+// TestCutOptions 测试 cut 的字段列表、字符范围、--output-delimiter、--complement 和 -s
+func TestCutOptions(t *testing.T) {
+	input := "a,b,c,d,e,f\nno delimiter\nx,y\n"
+	tests := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{[]string{"-d,", "-f1,3-5"}, input, "a,c,d,e\nno delimiter\nx\n"},
+		{[]string{"-d", ",", "-f", "5-"}, input, "e,f\nno delimiter\n\n"},
+		{[]string{"-d,", "-f-2", "-s"}, input, "a,b\nx,y\n"},
+		{[]string{"-d,", "-f2", "--complement"}, input, "a,c,d,e,f\nno delimiter\nx\n"},
+		{[]string{"-d,", "-f1,2", "--output-delimiter=:"}, input, "a:b\nno delimiter\nx:y\n"},
+		{[]string{"-f2"}, "1\t2\t3\n", "2\n"},
+		{[]string{"-c", "1-3,6"}, "abcdefgh\n", "abcf\n"},
+		{[]string{"-c2-"}, "héllo\n", "éllo\n"},
+		{[]string{"-c", "1,2,5-", "--output-delimiter", "|"}, "abcdefg\n", "ab|efg\n"},
+		{[]string{"-c", "2", "--complement"}, "abc\n", "ac\n"},
+		{[]string{"-b", "1-2"}, "abc\n", "ab\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := cut(tt.args, map[string]string{}, &IO{Stdin: strings.NewReader(tt.input), Stdout: &out})
+		if err != nil {
+			t.Errorf("cut %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.expected {
+			t.Errorf("cut %v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+
+	for _, args := range [][]string{{"-d,"}, {"-f", "0"}, {"-f", "3-1"}, {"-d", "ab", "-f1"}, {"-f1", "-c1"}, {"-c1", "-s"}} {
+		if err := cut(args, map[string]string{}, &IO{Stdin: strings.NewReader(input), Stdout: &bytes.Buffer{}}); err == nil {
+			t.Errorf("cut %v 应该报错", args)
+		}
+	}
+}
+
+// TestTr 测试 tr 的字符转换、范围、字符类、-d、-s 和 -c
+func TestTr(t *testing.T) {
+	tests := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{[]string{"a-z", "A-Z"}, "hello, world\n", "HELLO, WORLD\n"},
+		{[]string{"[:upper:]", "[:lower:]"}, "Hello WORLD\n", "hello world\n"},
+		{[]string{"abc", "x"}, "aabbcc\n", "xxxxxx\n"},
+		{[]string{"-t", "abc", "x"}, "abc\n", "xbc\n"},
+		{[]string{"-d", "[:digit:]"}, "a1b22c333\n", "abc\n"},
+		{[]string{"-s", " "}, "a   b  c\n", "a b c\n"},
+		{[]string{"-s", "a-z", "A-Z"}, "aabbcc dd\n", "ABC D\n"},
+		{[]string{"-cd", "[:alnum:]\\n"}, "a-b_c!\n", "abc\n"},
+		{[]string{"-c", "[:alpha:]", "_"}, "ab1c\n", "ab_c_"},
+		{[]string{"-ds", "0-9", " "}, "a1  2 b\n", "a b\n"},
+		{[]string{"\\n", " "}, "a\nb\n", "a b "},
+		{[]string{"a-f", "[x*]"}, "abcfg\n", "xxxxg\n"},
+		{[]string{"abcd", "[x*2]yz"}, "abcd\n", "xxyz\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := tr(tt.args, map[string]string{}, &IO{Stdin: strings.NewReader(tt.input), Stdout: &out})
+		if err != nil {
+			t.Errorf("tr %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.expected {
+			t.Errorf("tr %v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"a"}, {"-d", "a", "b"}, {"z-a", "b"}, {"[:bogus:]", "a"}} {
+		if err := tr(args, map[string]string{}, &IO{Stdin: strings.NewReader(""), Stdout: &bytes.Buffer{}}); err == nil {
+			t.Errorf("tr %v 应该报错", args)
+		}
+	}
+}
+
+func TestXargs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{[]string{}, "a b\nc\n", "a b c\n"},
+		{[]string{"-n", "2", "echo", "x"}, "1 2 3", "x 1 2\nx 3\n"},
+		{[]string{"-n1"}, "'a b' \"c d\" e\\ f", "a b\nc d\ne f\n"},
+		{[]string{"-I", "{}", "echo", "<{}>"}, "one\n  two\n\n", "<one>\n<two>\n"},
+		{[]string{"-0", "echo"}, "a b\x00c\x00", "a b c\n"},
+		{[]string{"-d", ",", "-n", "1"}, "x,y", "x\ny\n"},
+		{[]string{"-r", "echo", "empty"}, "", ""},
+		{[]string{"echo", "empty"}, "", "empty\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := XargsBuiltin(nil)(tt.args, map[string]string{}, &IO{Stdin: strings.NewReader(tt.input), Stdout: &out, Stderr: &bytes.Buffer{}})
+		if err != nil {
+			t.Errorf("xargs %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.expected {
+			t.Errorf("xargs %v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+
+	// -P 并行执行，每个命令的输出不会交错
+	var out bytes.Buffer
+	err := XargsBuiltin(nil)([]string{"-P", "4", "-n", "1"}, map[string]string{}, &IO{Stdin: strings.NewReader("1 2 3 4 5 6"), Stdout: &out, Stderr: &bytes.Buffer{}})
+	if err != nil {
+		t.Fatalf("xargs -P 执行失败: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	if strings.Join(lines, " ") != "1 2 3 4 5 6" {
+		t.Errorf("xargs -P 输出 %q", out.String())
+	}
+
+	// 退出状态：命令失败为 123（不输出消息），命令未找到为 127；只表示退出状态的内置命令错误不输出
+	lookup := func(name string) (BuiltinFunc, bool) {
+		if name != "fail" {
+			return nil, false
+		}
+		return func(args []string, env map[string]string, stdio *IO) error { return &StatusError{Code: 1} }, true
+	}
+	for args, code := range map[string]int{"false": 123, "fail": 123, "gobash-no-such-command": 127} {
+		var errOut bytes.Buffer
+		err := XargsBuiltin(lookup)([]string{args}, map[string]string{}, &IO{Stdin: strings.NewReader("a"), Stdout: &bytes.Buffer{}, Stderr: &errOut})
+		var status *StatusError
+		if !errors.As(err, &status) || status.Code != code {
+			t.Errorf("xargs %s 应该返回退出状态 %d，实际 %v", args, code, err)
+		}
+		if code == 123 && (status == nil || status.Message != "" || errOut.Len() != 0) {
+			t.Errorf("xargs %s 不应该输出消息: %v %q", args, err, errOut.String())
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 45, 0, time.UTC)
+	formats := map[string]string{
+		"%Y-%m-%d %H:%M:%S": "2024-03-15 10:30:45",
+		"%s":                "1710498645",
+		"%a %A %b %B":       "Fri Friday Mar March",
+		"%e|%-d|%_m|%j":     "15|15| 3|075",
+		"%I:%M %p %P":       "10:30 AM am",
+		"%F %T %D":          "2024-03-15 10:30:45 03/15/24",
+		"%u %w %V %%":       "5 5 11 %",
+		"%z %:z %Z":         "+0000 +00:00 UTC",
+		"%q":                "%q",
+	}
+	for format, expected := range formats {
+		if got := formatDate(now, format); got != expected {
+			t.Errorf("formatDate(%q) = %q，期望 %q", format, got, expected)
+		}
+	}
+
+	dates := map[string]string{
+		"yesterday":           "2024-03-14 10:30:45",
+		"tomorrow":            "2024-03-16 10:30:45",
+		"2 days ago":          "2024-03-13 10:30:45",
+		"3 hours":             "2024-03-15 13:30:45",
+		"-1 week":             "2024-03-08 10:30:45",
+		"next month":          "2024-04-15 10:30:45",
+		"last year":           "2023-03-15 10:30:45",
+		"1 day 2 hours ago":   "2024-03-14 08:30:45",
+		"2024-01-02":          "2024-01-02 00:00:00",
+		"2024-01-02 08:09":    "2024-01-02 08:09:00",
+		"2024-01-02T08:09:10": "2024-01-02 08:09:10",
+		"2024/01/31 +1 day":   "2024-02-01 00:00:00",
+		"12:00":               "2024-03-15 12:00:00",
+		"@0":                  "1970-01-01 00:00:00",
+		"":                    "2024-03-15 00:00:00",
+	}
+	for s, expected := range dates {
+		got, err := parseDateString(s, now)
+		if err != nil {
+			t.Errorf("parseDateString(%q) 失败: %v", s, err)
+			continue
+		}
+		if formatted := got.Format("2006-01-02 15:04:05"); formatted != expected {
+			t.Errorf("parseDateString(%q) = %s，期望 %s", s, formatted, expected)
+		}
+	}
+	for _, s := range []string{"bogus", "3 parsecs", "2024-13-01", "25:00", "next"} {
+		if _, err := parseDateString(s, now); err == nil {
+			t.Errorf("parseDateString(%q) 应该报错", s)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := date([]string{"-u", "-d", "@86400", "+%Y-%m-%d %H:%M"}, map[string]string{}, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("date 执行失败: %v", err)
+	}
+	if out.String() != "1970-01-02 00:00\n" {
+		t.Errorf("date 输出 %q", out.String())
+	}
+	out.Reset()
+	if err := date([]string{"-d@0"}, map[string]string{"TZ": "Asia/Shanghai"}, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("date 执行失败: %v", err)
+	}
+	if out.String() != "Thu Jan  1 08:00:00 CST 1970\n" {
+		t.Errorf("date 使用 TZ 输出 %q", out.String())
+	}
+}
+
+func TestSleep(t *testing.T) {
+	durations := map[string]time.Duration{
+		"1":        time.Second,
+		"0.5":      500 * time.Millisecond,
+		"2s":       2 * time.Second,
+		"1.5m":     90 * time.Second,
+		"1h":       time.Hour,
+		"1d":       24 * time.Hour,
+		"infinity": math.MaxInt64,
+	}
+	for arg, expected := range durations {
+		if d, err := parseDurationArg(arg); err != nil || d != expected {
+			t.Errorf("parseDurationArg(%q) = %v, %v，期望 %v", arg, d, err, expected)
+		}
+	}
+	for _, arg := range []string{"", "s", "-1", "1x", "abc", "0x10", "nan"} {
+		if _, err := parseDurationArg(arg); err == nil {
+			t.Errorf("parseDurationArg(%q) 应该报错", arg)
+		}
+	}
+
+	start := time.Now()
+	if err := sleep([]string{"0.05", "0.05s"}, map[string]string{}, &IO{}); err != nil {
+		t.Fatalf("sleep 执行失败: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("sleep 0.05 0.05s 只等待了 %s", elapsed)
+	}
+
+	// context 被取消时立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err := sleep([]string{"5"}, map[string]string{}, &IO{Context: ctx})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 context.DeadlineExceeded，得到 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("取消后应该立即返回，实际耗时 %s", elapsed)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var out bytes.Buffer
+	if err := TimeoutBuiltin(nil)([]string{"5", "echo", "hi"}, map[string]string{}, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("timeout 执行失败: %v", err)
+	}
+	if out.String() != "hi\n" {
+		t.Errorf("timeout 5 echo hi 输出 %q", out.String())
+	}
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"0.05", "sleep", "5"}, 124},
+		{[]string{"1", "false"}, 1},
+		{[]string{"1", "gobash-no-such-command"}, 127},
+		{[]string{"x", "true"}, 125},
+		{[]string{"1"}, 125},
+		{[]string{"-k", "1", "true"}, 125},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		err := TimeoutBuiltin(nil)(tt.args, map[string]string{}, &IO{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}})
+		var coded interface{ ExitCode() int }
+		code := 1
+		if errors.As(err, &coded) {
+			code = coded.ExitCode()
+		}
+		if err == nil || code != tt.code {
+			t.Errorf("timeout %v 退出状态 %d（%v），期望 %d", tt.args, code, err, tt.code)
+		}
+		if code == 124 && err.Error() != "" {
+			t.Errorf("超时不应该输出消息，得到 %q", err.Error())
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("timeout %v 耗时 %s", tt.args, elapsed)
+		}
+	}
+}
+
+func TestPathCommands(t *testing.T) {
+	run := func(fn BuiltinFunc, args []string, env map[string]string) (string, error) {
+		var out bytes.Buffer
+		err := fn(args, env, &IO{Stdout: &out})
+		return out.String(), err
+	}
+
+	tests := []struct {
+		fn       BuiltinFunc
+		args     []string
+		expected string
+	}{
+		{basename, []string{"/usr/lib/libc.so.6"}, "libc.so.6\n"},
+		{basename, []string{"/usr/lib/libc.so.6", ".6"}, "libc.so\n"},
+		{basename, []string{"dir/sub//"}, "sub\n"},
+		{basename, []string{"/"}, "/\n"},
+		{basename, []string{"a.go", "a.go"}, "a.go\n"},
+		{basename, []string{"-s", ".go", "a/x.go", "y.go"}, "x\ny\n"},
+		{basename, []string{"-a", "a/b", "c"}, "b\nc\n"},
+		{dirname, []string{"/usr/lib/"}, "/usr\n"},
+		{dirname, []string{"file", "a/b", "/x", "//", "a//b//"}, ".\na\n/\n/\na\n"},
+	}
+	for _, tt := range tests {
+		got, err := run(tt.fn, tt.args, map[string]string{})
+		if err != nil {
+			t.Errorf("%v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+
+	// realpath 解析符号链接和相对路径
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(dir, "real", "sub"), 0755)
+	if err := os.Symlink(filepath.Join(dir, "real"), filepath.Join(dir, "link")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	env := map[string]string{"PWD": dir}
+	realTests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"link/sub"}, filepath.Join(dir, "real", "sub") + "\n"},
+		{[]string{"link/../link/new"}, filepath.Join(dir, "real", "new") + "\n"},
+		{[]string{"-m", "link/a/b"}, filepath.Join(dir, "real", "a", "b") + "\n"},
+		{[]string{"-s", "link/sub"}, filepath.Join(dir, "link", "sub") + "\n"},
+	}
+	for _, tt := range realTests {
+		got, err := run(realpath, tt.args, env)
+		if err != nil {
+			t.Errorf("realpath %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("realpath %v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+	for _, args := range [][]string{{"link/a/b"}, {"-e", "link/new"}} {
+		if _, err := run(realpath, args, env); err == nil {
+			t.Errorf("realpath %v 应该报错", args)
+		}
+	}
+
+	// mktemp 创建文件和目录
+	env["TMPDIR"] = dir
+	out, err := run(mktemp, []string{}, env)
+	if err != nil {
+		t.Fatalf("mktemp 执行失败: %v", err)
+	}
+	if name := strings.TrimSpace(out); !strings.HasPrefix(name, filepath.Join(dir, "tmp.")) {
+		t.Errorf("mktemp 创建了 %q", name)
+	} else if info, err := os.Stat(name); err != nil || !info.Mode().IsRegular() || info.Mode().Perm() != 0600 {
+		t.Errorf("mktemp 创建的文件不正确: %v", err)
+	}
+	out, err = run(mktemp, []string{"-d", "work.XXXXXX"}, env)
+	if err != nil {
+		t.Fatalf("mktemp -d 执行失败: %v", err)
+	}
+	name := strings.TrimSpace(out)
+	if !strings.HasPrefix(name, "work.") || len(name) != len("work.XXXXXX") {
+		t.Errorf("mktemp -d work.XXXXXX 输出 %q", name)
+	} else if info, err := os.Stat(filepath.Join(dir, name)); err != nil || !info.IsDir() {
+		t.Errorf("mktemp -d 没有在当前目录创建目录: %v", err)
+	}
+	for _, args := range [][]string{{"foo.XX"}, {"-t", "a/b.XXX"}, {"a.XXX", "b.XXX"}} {
+		if _, err := run(mktemp, args, env); err == nil {
+			t.Errorf("mktemp %v 应该报错", args)
+		}
+	}
+}
+
+func TestSeq(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"3"}, "1\n2\n3\n"},
+		{[]string{"2", "4"}, "2\n3\n4\n"},
+		{[]string{"1", "2", "7"}, "1\n3\n5\n7\n"},
+		{[]string{"5", "-2", "1"}, "5\n3\n1\n"},
+		{[]string{"5", "1"}, ""},
+		{[]string{"-2", "0"}, "-2\n-1\n0\n"},
+		{[]string{"-w", "8", "10"}, "08\n09\n10\n"},
+		{[]string{"-w", "-3", "1", "-1"}, "-3\n-2\n-1\n"},
+		{[]string{"-s", ",", "4"}, "1,2,3,4\n"},
+		{[]string{"-s", " - ", "1", "3"}, "1 - 2 - 3\n"},
+		{[]string{"1", "0.5", "2"}, "1.0\n1.5\n2.0\n"},
+		{[]string{"0.1", "0.1", "0.3"}, "0.1\n0.2\n0.3\n"},
+		{[]string{"-f", "%.2f", "1", "2"}, "1.00\n2.00\n"},
+		{[]string{"-f", "n%gx", "2"}, "n1x\nn2x\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := seq(tt.args, map[string]string{}, &IO{Stdout: &out}); err != nil {
+			t.Errorf("seq %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.expected {
+			t.Errorf("seq %v 输出 %q，期望 %q", tt.args, got, tt.expected)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"a"}, {"1", "0", "3"}, {"1", "2", "3", "4"}, {"-f", "%d", "3"}, {"-x", "3"}} {
+		if err := seq(args, map[string]string{}, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+			t.Errorf("seq %v 应该报错", args)
+		}
+	}
+}
+
+func TestTee(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	var out bytes.Buffer
+	if err := tee([]string{"a.txt", "b.txt"}, env, &IO{Stdin: strings.NewReader("hello\n"), Stdout: &out}); err != nil {
+		t.Fatalf("tee 执行失败: %v", err)
+	}
+	if out.String() != "hello\n" {
+		t.Errorf("tee 输出 %q", out.String())
+	}
+	if err := tee([]string{"-a", "a.txt"}, env, &IO{Stdin: strings.NewReader("world\n"), Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("tee -a 执行失败: %v", err)
+	}
+	for name, expected := range map[string]string{"a.txt": "hello\nworld\n", "b.txt": "hello\n"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != expected {
+			t.Errorf("%s 的内容为 %q，期望 %q", name, data, expected)
+		}
+	}
+
+	// 无法打开的文件报错，但仍然写入其他输出
+	out.Reset()
+	err := tee([]string{filepath.Join(dir, "missing", "c.txt"), "b.txt"}, env, &IO{Stdin: strings.NewReader("x\n"), Stdout: &out})
+	if err == nil {
+		t.Error("tee 写入不存在的目录应该报错")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b.txt")); out.String() != "x\n" || string(data) != "x\n" {
+		t.Errorf("tee 出错后输出 %q，b.txt 为 %q", out.String(), data)
+	}
+}
+func TestDu(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	os.WriteFile(filepath.Join(dir, "a", "f"), []byte("12345"), 0644)
+	os.WriteFile(filepath.Join(dir, "a", "b", "g"), []byte("1234567890"), 0644)
+	env := map[string]string{"PWD": dir}
+	dirSize := func(name string) int64 {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	sizeB := dirSize("a/b") + 10
+	sizeA := dirSize("a") + 5 + sizeB
+	line := func(size int64, path string) string {
+		return strconv.FormatInt(size, 10) + "\t" + path + "\n"
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-b", "a"}, line(sizeB, "a/b") + line(sizeA, "a")},
+		{[]string{"-ab", "a"}, line(10, "a/b/g") + line(sizeB, "a/b") + line(5, "a/f") + line(sizeA, "a")},
+		{[]string{"-sb", "a"}, line(sizeA, "a")},
+		{[]string{"-b", "-d", "0", "a/"}, line(sizeA, "a/")},
+		{[]string{"-b", "--max-depth=1", "-a", "a"}, line(sizeB, "a/b") + line(5, "a/f") + line(sizeA, "a")},
+		{[]string{"-cb", "a/f", "a/b/g"}, line(5, "a/f") + line(10, "a/b/g") + line(15, "total")},
+		{[]string{"-sbk", "a/b/g"}, line(1, "a/b/g")},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := du(tt.args, env, &IO{Stdout: &out}); err != nil {
+			t.Errorf("du %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if out.String() != tt.expected {
+			t.Errorf("du %v 输出 %q，期望 %q", tt.args, out.String(), tt.expected)
+		}
+	}
+
+	if err := du([]string{"missing"}, env, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("du 不存在的文件应该报错")
+	}
+	if err := du([]string{"-s", "-d", "1"}, env, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("du -s 和 -d 1 同时使用应该报错")
+	}
+}
+
+func TestDf(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	if err := df([]string{"-T", dir}, map[string]string{}, &IO{Stdout: &out}); err != nil {
+		t.Skipf("当前环境无法获取文件系统信息: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("df 输出 %q，期望一行表头和一行数据", out.String())
+	}
+	if fields := strings.Fields(lines[0]); len(fields) != 8 || fields[0] != "Filesystem" || fields[1] != "Type" || fields[2] != "1K-blocks" {
+		t.Errorf("df 表头为 %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); len(fields) < 7 || !strings.HasSuffix(fields[5], "%") {
+		t.Errorf("df 数据行为 %q", lines[1])
+	}
+
+	if err := df([]string{filepath.Join(dir, "missing")}, map[string]string{}, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("df 不存在的文件应该报错")
+	}
+}
+func TestKill(t *testing.T) {
+	var out bytes.Buffer
+	if err := kill(nil, []string{"-l", "9", "TERM", "SIGINT", "137"}, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("kill -l 执行失败: %v", err)
+	}
+	if expected := "KILL\n15\n2\nKILL\n"; out.String() != expected {
+		t.Errorf("kill -l 输出 %q，期望 %q", out.String(), expected)
+	}
+
+	// 信号 0 只检查进程是否存在
+	self := strconv.Itoa(os.Getpid())
+	for _, args := range [][]string{{"-0", self}, {"-s", "0", self}, {"-n", "0", "--", self}} {
+		if err := kill(nil, args, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+			t.Errorf("kill %v 执行失败: %v", args, err)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"-FOO", self}, {"-0", "abc"}, {"-0", "%1"}} {
+		if err := kill(nil, args, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+			t.Errorf("kill %v 应该报错", args)
+		}
+	}
+}
+
+func TestProcessCommands(t *testing.T) {
+	if _, err := listProcesses(); err != nil {
+		t.Skipf("当前平台无法列出进程: %v", err)
+	}
+	sleepPath, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("没有 sleep 命令")
+	}
+	child := exec.Command(sleepPath, "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer child.Process.Kill()
+	self, pid := strconv.Itoa(os.Getpid()), strconv.Itoa(child.Process.Pid)
+
+	var out bytes.Buffer
+	if err := ps([]string{"-f", "-p", pid}, map[string]string{}, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("ps 执行失败: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], pid) || !strings.HasSuffix(lines[1], " 30") {
+		t.Errorf("ps -f -p %s 输出 %q", pid, out.String())
+	}
+
+	out.Reset()
+	if err := pgrep([]string{"-l", "-P", self, "-x", "sleep"}, map[string]string{}, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("pgrep 执行失败: %v", err)
+	}
+	if expected := pid + " sleep\n"; out.String() != expected {
+		t.Errorf("pgrep 输出 %q，期望 %q", out.String(), expected)
+	}
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"-P", self, "no-such-process"}, 1},
+		{[]string{"-y", "sleep"}, 2},
+		{[]string{"-P", self}, 0},
+		{[]string{}, 2},
+	} {
+		err := pgrep(tt.args, map[string]string{}, &IO{Stdout: &bytes.Buffer{}})
+		var statusErr *StatusError
+		if tt.code == 0 && err != nil || tt.code != 0 && (!errors.As(err, &statusErr) || statusErr.Code != tt.code) {
+			t.Errorf("pgrep %v 返回 %v，期望退出状态 %d", tt.args, err, tt.code)
+		}
+	}
+
+	out.Reset()
+	if err := pkill([]string{"-KILL", "-e", "-P", self, "^sle+p$"}, map[string]string{}, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("pkill 执行失败: %v", err)
+	}
+	if expected := "sleep killed (pid " + pid + ")\n"; out.String() != expected {
+		t.Errorf("pkill 输出 %q，期望 %q", out.String(), expected)
+	}
+	if err := child.Wait(); err == nil {
+		t.Error("pkill 之后 sleep 应该被信号终止")
+	}
+}
+func TestHTTP(t *testing.T) {
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s %s %s %s", r.Method, r.Header.Get("X-Test"), r.Header.Get("Content-Type"), body)
+		case "/missing":
+			http.Error(w, "not found", http.StatusNotFound)
+		case "/flaky":
+			// 前两次返回 503
+			if failures < 2 {
+				failures++
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		case "/redirect":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		default:
+			w.Header().Set("X-Name", "gobash")
+			fmt.Fprint(w, "hello")
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	tests := []struct {
+		args     []string
+		expected string
+		code     int
+	}{
+		{[]string{server.URL + "/file.txt"}, "hello", 0},
+		{[]string{"-s", "-H", "X-Test: 1", "-d", "a=1", "-d", "b=2", server.URL + "/echo"}, "POST 1 application/x-www-form-urlencoded a=1&b=2", 0},
+		{[]string{"PUT", server.URL + "/echo", "-H", "Content-Type: text/plain", "--data=x"}, "PUT  text/plain x", 0},
+		{[]string{"-X", "delete", server.URL + "/echo"}, "DELETE   ", 0},
+		{[]string{"-I", server.URL}, "HTTP/1.1 200 OK\r\n", 0},
+		{[]string{server.URL + "/missing"}, "not found\n", 0},
+		{[]string{"-f", server.URL + "/missing"}, "", 22},
+		{[]string{"-L", server.URL + "/redirect"}, "GET   ", 0},
+		{[]string{"--retry", "3", "--retry-delay", "0.01", "-s", server.URL + "/flaky"}, "ok", 0},
+		{[]string{"-s", "http://127.0.0.1:1/"}, "", 7},
+		{[]string{"http://"}, "", 3},
+		{[]string{"-y", server.URL}, "", 2},
+		{[]string{}, "", 2},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := httpCmd(tt.args, env, &IO{Stdout: &out, Stderr: &bytes.Buffer{}})
+		code := 0
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			code = statusErr.Code
+		} else if err != nil {
+			code = -1
+		}
+		got := out.String()
+		if len(tt.args) > 0 && tt.args[0] == "-I" {
+			got, _, _ = strings.Cut(got, "Content-Length")
+		}
+		if got != tt.expected || code != tt.code {
+			t.Errorf("http %v 输出 %q（退出状态 %d，%v），期望 %q（退出状态 %d）", tt.args, got, code, err, tt.expected, tt.code)
+		}
+	}
+
+	// -s 时错误没有消息
+	if err := httpCmd([]string{"-s", "http://127.0.0.1:1/"}, env, &IO{Stdout: &bytes.Buffer{}}); err == nil || err.Error() != "" {
+		t.Errorf("http -s 出错时返回 %v，期望没有消息的错误", err)
+	}
+	// -o 和 -O 写入文件
+	if err := httpCmd([]string{"-o", "out.txt", server.URL}, env, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("http -o 执行失败: %v", err)
+	}
+	if err := httpCmd([]string{"-sO", server.URL + "/page.html"}, env, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("http -O 执行失败: %v", err)
+	}
+	for _, name := range []string{"out.txt", "page.html"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != "hello" {
+			t.Errorf("%s 的内容为 %q", name, data)
+		}
+	}
+}
+func TestBase64(t *testing.T) {
+	tests := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{nil, "hello\n", "aGVsbG8K\n"},
+		{[]string{"-d"}, "aGVsbG8K\n", "hello\n"},
+		{[]string{"-w", "4"}, "hello", "aGVs\nbG8=\n"},
+		{[]string{"--wrap=0"}, strings.Repeat("a", 60), strings.Repeat("YWFh", 20) + "\n"},
+		{[]string{"-d"}, "aGVs\nbG8=\n", "hello"},
+		{[]string{"-di"}, "aGVs*bG8=", "hello"},
+		{nil, "", ""},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := base64Cmd(tt.args, map[string]string{}, &IO{Stdin: strings.NewReader(tt.input), Stdout: &out}); err != nil {
+			t.Errorf("base64 %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if out.String() != tt.expected {
+			t.Errorf("base64 %v 输出 %q，期望 %q", tt.args, out.String(), tt.expected)
+		}
+	}
+	if err := base64Cmd([]string{"-d"}, map[string]string{}, &IO{Stdin: strings.NewReader("aGVs*bG8="), Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("base64 -d 无效输入应该报错")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("abc"), 0644)
+	const abcMD5 = "900150983cd24fb0d6963f7d28e17f72"
+	const abcSHA256 = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+	var out bytes.Buffer
+	if err := md5sum([]string{"a.txt", "-"}, env, &IO{Stdin: strings.NewReader("abc"), Stdout: &out}); err != nil {
+		t.Fatalf("md5sum 执行失败: %v", err)
+	}
+	if expected := abcMD5 + "  a.txt\n" + abcMD5 + "  -\n"; out.String() != expected {
+		t.Errorf("md5sum 输出 %q，期望 %q", out.String(), expected)
+	}
+	out.Reset()
+	if err := sha256sum([]string{"-b", "a.txt"}, env, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("sha256sum 执行失败: %v", err)
+	}
+	if expected := abcSHA256 + " *a.txt\n"; out.String() != expected {
+		t.Errorf("sha256sum -b 输出 %q，期望 %q", out.String(), expected)
+	}
+
+	// -c 验证校验文件
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("xyz"), 0644)
+	sums := abcSHA256 + "  a.txt\n" + abcSHA256 + " *b.txt\n" + abcSHA256 + "  missing.txt\nbad line\n"
+	os.WriteFile(filepath.Join(dir, "sums"), []byte(sums), 0644)
+	out.Reset()
+	var stderr bytes.Buffer
+	err := sha256sum([]string{"-c", "sums"}, env, &IO{Stdout: &out, Stderr: &stderr})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != 1 {
+		t.Errorf("sha256sum -c 有失败时返回 %v，期望退出状态 1", err)
+	}
+	if expected := "a.txt: OK\nb.txt: FAILED\nmissing.txt: FAILED open or read\n"; out.String() != expected {
+		t.Errorf("sha256sum -c 输出 %q，期望 %q", out.String(), expected)
+	}
+	if !strings.Contains(stderr.String(), "1 个校验和不匹配") || !strings.Contains(stderr.String(), "1 行格式不正确") {
+		t.Errorf("sha256sum -c 的警告为 %q", stderr.String())
+	}
+
+	out.Reset()
+	err = md5sum([]string{"--quiet", "-c", "-"}, env, &IO{Stdin: strings.NewReader(abcMD5 + "  a.txt\n"), Stdout: &out, Stderr: &bytes.Buffer{}})
+	if err != nil || out.String() != "" {
+		t.Errorf("md5sum --quiet -c 输出 %q（%v），期望没有输出", out.String(), err)
+	}
+}
+func TestTar(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	os.MkdirAll(filepath.Join(dir, "src", "d"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "a.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "d", "b.txt"), []byte("world"), 0600)
+
+	for _, args := range [][]string{{"czf", "backup.tgz", "src/"}, {"-cf", "plain.tar", "src"}} {
+		if err := tarCmd(args, env, &IO{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}); err != nil {
+			t.Fatalf("tar %v 执行失败: %v", args, err)
+		}
+	}
+
+	// 列出时自动识别 gzip
+	expected := "src/\nsrc/a.txt\nsrc/d/\nsrc/d/b.txt\n"
+	for _, args := range [][]string{{"tf", "backup.tgz"}, {"-tzf", "backup.tgz"}, {"-t", "-f", "plain.tar"}} {
+		var out bytes.Buffer
+		if err := tarCmd(args, env, &IO{Stdout: &out}); err != nil {
+			t.Fatalf("tar %v 执行失败: %v", args, err)
+		}
+		if out.String() != expected {
+			t.Errorf("tar %v 输出 %q，期望 %q", args, out.String(), expected)
+		}
+	}
+
+	// 解压到 -C 指定的目录，可以只解压部分成员
+	os.Mkdir(filepath.Join(dir, "out"), 0755)
+	if err := tarCmd([]string{"xzf", "backup.tgz", "-C", "out"}, env, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("tar xzf 执行失败: %v", err)
+	}
+	for name, content := range map[string]string{"src/a.txt": "hello", "src/d/b.txt": "world"} {
+		if data, err := os.ReadFile(filepath.Join(dir, "out", name)); err != nil || string(data) != content {
+			t.Errorf("解压后 %s 的内容为 %q（%v），期望 %q", name, data, err, content)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "out", "src", "d", "b.txt")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("解压后 b.txt 的权限不正确: %v", err)
+	}
+	os.Mkdir(filepath.Join(dir, "part"), 0755)
+	if err := tarCmd([]string{"-x", "-f", "plain.tar", "--directory=part", "src/d"}, env, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("tar -x 部分成员执行失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "part", "src", "a.txt")); err == nil {
+		t.Error("只解压 src/d 时不应该解压 src/a.txt")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "part", "src", "d", "b.txt")); err != nil {
+		t.Errorf("没有解压 src/d/b.txt: %v", err)
+	}
+
+	// 包含 .. 的成员被拒绝
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	os.Mkdir(filepath.Join(dir, "safe"), 0755)
+	if err := tarCmd([]string{"x", "-C", "safe"}, env, &IO{Stdin: &archive, Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("解压包含 .. 的成员应该报错")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+		t.Error("包含 .. 的成员不应该被解压")
+	}
+
+	for _, args := range [][]string{{"zf", "x.tgz"}, {"cx"}, {"cf", "empty.tar"}, {"-q"}} {
+		if err := tarCmd(args, env, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+			t.Errorf("tar %v 应该报错", args)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte("hello gzip\n"), 0644)
+
+	if err := gzipCmd([]string{"-9", "a.txt"}, env, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("gzip 执行失败: %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("gzip 之后原文件应该被删除")
+	}
+	var out bytes.Buffer
+	if err := gunzip([]string{"-c", "a.txt.gz"}, env, &IO{Stdout: &out}); err != nil || out.String() != "hello gzip\n" {
+		t.Errorf("gunzip -c 输出 %q（%v）", out.String(), err)
+	}
+	if err := gzipCmd([]string{"-dk", "a.txt.gz"}, env, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("gzip -dk 执行失败: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hello gzip\n" {
+		t.Errorf("解压后的内容为 %q", data)
+	}
+	if _, err := os.Stat(path + ".gz"); err != nil {
+		t.Error("gzip -k 应该保留原文件")
+	}
+	// 输出文件已存在时需要 -f
+	if err := gzipCmd([]string{"a.txt"}, env, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("a.txt.gz 已存在时 gzip 应该报错")
+	}
+	if err := gzipCmd([]string{"-f", "a.txt"}, env, &IO{Stdout: &bytes.Buffer{}}); err != nil {
+		t.Errorf("gzip -f 执行失败: %v", err)
+	}
+
+	// 标准输入到标准输出
+	var compressed, plain bytes.Buffer
+	if err := gzipCmd(nil, env, &IO{Stdin: strings.NewReader("stream"), Stdout: &compressed}); err != nil {
+		t.Fatalf("gzip 标准输入执行失败: %v", err)
+	}
+	if err := gunzip(nil, env, &IO{Stdin: &compressed, Stdout: &plain}); err != nil || plain.String() != "stream" {
+		t.Errorf("gunzip 标准输入输出 %q（%v）", plain.String(), err)
+	}
+	if err := gunzip([]string{"-"}, env, &IO{Stdin: strings.NewReader("not gzip"), Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("gunzip 无效数据应该报错")
+	}
+}
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	write := func(name, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	write("a.txt", "one\ntwo\nthree\nfour\n")
+	write("b.txt", "one\n2\nthree\nfour\nfive")
+	write("c.txt", "one\ntwo\nthree\nfour\n")
+
+	status := func(err error) int {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			return statusErr.Code
+		}
+		if err != nil {
+			return -1
+		}
+		return 0
+	}
+	tests := []struct {
+		name   string
+		args   []string
+		want   string
+		status int
+	}{
+		{"相同", []string{"a.txt", "c.txt"}, "", 0},
+		{"普通格式", []string{"a.txt", "b.txt"}, "2c2\n< two\n---\n> 2\n4a5\n> five\n\\ No newline at end of file\n", 1},
+		{"删除", []string{"b.txt", "a.txt"}, "2c2\n< 2\n---\n> two\n5d4\n< five\n\\ No newline at end of file\n", 1},
+		{"上下文为 0", []string{"-U0", "a.txt", "b.txt"}, "@@ -2 +2 @@\n-two\n+2\n@@ -4,0 +5 @@\n+five\n\\ No newline at end of file\n", 1},
+		{"简要", []string{"-q", "a.txt", "b.txt"}, "Files a.txt and b.txt differ\n", 1},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := diff(tt.args, env, &IO{Stdout: &out})
+		if got := status(err); got != tt.status {
+			t.Errorf("%s: 退出状态为 %d，期望 %d（%v）", tt.name, got, tt.status, err)
+		}
+		got := out.String()
+		if strings.HasPrefix(tt.args[0], "-U") {
+			// 去掉带修改时间的文件头
+			got = got[strings.Index(got, "@@"):]
+		}
+		if got != tt.want {
+			t.Errorf("%s: 输出 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+
+	// 统一格式的文件头和合并的块
+	var out bytes.Buffer
+	diff([]string{"-u", "a.txt", "-"}, env, &IO{Stdin: strings.NewReader("one\ntwo\nthree\n4\n"), Stdout: &out})
+	if !strings.HasPrefix(out.String(), "--- a.txt\t") || !strings.Contains(out.String(), "\n+++ -\n@@ -1,4 +1,4 @@\n one\n two\n three\n-four\n+4\n") {
+		t.Errorf("diff -u 输出 %q", out.String())
+	}
+
+	// 目录：默认只比较第一层，-r 递归
+	write("d1/same", "x\n")
+	write("d2/same", "x\n")
+	write("d1/only", "x\n")
+	write("d1/sub/f", "old\n")
+	write("d2/sub/f", "new\n")
+	out.Reset()
+	if err := diff([]string{"d1", "d2"}, env, &IO{Stdout: &out}); status(err) != 1 ||
+		out.String() != "Only in d1: only\nCommon subdirectories: d1/sub and d2/sub\n" {
+		t.Errorf("diff 目录输出 %q（%v）", out.String(), err)
+	}
+	out.Reset()
+	if err := diff([]string{"-r", "d1", "d2"}, env, &IO{Stdout: &out}); status(err) != 1 ||
+		out.String() != "Only in d1: only\ndiff -r d1/sub/f d2/sub/f\n1c1\n< old\n---\n> new\n" {
+		t.Errorf("diff -r 输出 %q（%v）", out.String(), err)
+	}
+
+	// 错误时退出状态为 2
+	if err := diff([]string{"a.txt", "missing"}, env, &IO{Stdout: &bytes.Buffer{}}); status(err) != 2 {
+		t.Errorf("文件不存在时退出状态应该为 2: %v", err)
+	}
+	if err := diff([]string{"a.txt"}, env, &IO{Stdout: &bytes.Buffer{}}); status(err) != 2 {
+		t.Errorf("缺少参数时退出状态应该为 2: %v", err)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// 编辑序列应用到 a 上应该得到 b，且长度最短
+	tests := []struct {
+		a, b  string
+		edits int
+	}{
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abcabba", "cbabac", 5},
+		{"abcdef", "abxdef", 2},
+		{"aaaa", "aa", 2},
+	}
+	for _, tt := range tests {
+		a, b := strings.Split(tt.a, ""), strings.Split(tt.b, "")
+		edits := diffLines(a, b)
+		var result []string
+		changes := 0
+		for _, e := range edits {
+			switch e.kind {
+			case '=':
+				result = append(result, a[e.a])
+			case '+':
+				result = append(result, b[e.b])
+				changes++
+			case '-':
+				changes++
+			}
+		}
+		if strings.Join(result, "") != tt.b || changes != tt.edits {
+			t.Errorf("diffLines(%q, %q) 得到 %q，%d 处修改，期望 %d 处", tt.a, tt.b, strings.Join(result, ""), changes, tt.edits)
+		}
+	}
+}
+
+func TestCmp(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	os.WriteFile(filepath.Join(dir, "a"), []byte("abc\ndef\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "b"), []byte("abc\ndxf\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "c"), []byte("abc\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "d"), []byte("abc\nd"), 0644)
+
+	tests := []struct {
+		args    []string
+		want    string
+		status  int
+		message string
+	}{
+		{[]string{"a", "a"}, "", 0, ""},
+		{[]string{"a", "b"}, "a b differ: byte 6, line 2\n", 1, ""},
+		{[]string{"-s", "a", "b"}, "", 1, ""},
+		{[]string{"-l", "a", "b"}, "6 145 170\n", 1, ""},
+		{[]string{"a", "c"}, "", 1, "cmp: EOF on c after byte 4, line 1"},
+		{[]string{"a", "d"}, "", 1, "cmp: EOF on d after byte 5, in line 2"},
+		{[]string{"a", "missing"}, "", 2, ""},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := cmp(tt.args, env, &IO{Stdout: &out})
+		code := 0
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			code = statusErr.Code
+		}
+		if code != tt.status || out.String() != tt.want || tt.message != "" && err.Error() != tt.message {
+			t.Errorf("cmp %v: 输出 %q，退出状态 %d（%v），期望 %q，%d", tt.args, out.String(), code, err, tt.want, tt.status)
+		}
+	}
+
+	// 第二个文件默认为标准输入
+	if err := cmp([]string{"c"}, env, &IO{Stdin: strings.NewReader("abc\n"), Stdout: &bytes.Buffer{}}); err != nil {
+		t.Errorf("cmp 标准输入应该相同: %v", err)
+	}
+}
+func TestStat(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PWD": dir}
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte("hello"), 0644)
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(path, mtime, mtime)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "empty"), nil, 0600)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-c", "%n %s %a %A %Y", "a.txt"}, fmt.Sprintf("a.txt 5 644 -rw-r--r-- %d\n", mtime.Unix())},
+		{[]string{"-c", "%F", "a.txt", "sub", "empty"}, "regular file\ndirectory\nregular empty file\n"},
+		{[]string{"-c%04a|%-6n|%%", "empty"}, "0600|empty |%\n"},
+		{[]string{"--printf=%n\\t%s\\n", "a.txt"}, "a.txt\t5\n"},
+		{[]string{"--format=%s %q", "a.txt"}, "5 ?\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := stat(tt.args, env, &IO{Stdout: &out}); err != nil {
+			t.Errorf("stat %v 执行失败: %v", tt.args, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("stat %v 输出 %q，期望 %q", tt.args, out.String(), tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := stat([]string{"a.txt"}, env, &IO{Stdout: &out}); err != nil {
+		t.Fatalf("stat 执行失败: %v", err)
+	}
+	for _, want := range []string{"  File: a.txt\n", "  Size: 5 ", "regular file\n", "Access: (0644/-rw-r--r--)\n", "Modify: " + mtime.Local().Format("2006-01-02 15:04:05")} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("stat 默认输出 %q 中没有 %q", out.String(), want)
+		}
+	}
+
+	if err := stat([]string{"missing"}, env, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("stat 不存在的文件应该报错")
+	}
+	if err := stat(nil, env, &IO{Stdout: &bytes.Buffer{}}); err == nil {
+		t.Error("stat 缺少操作数应该报错")
+	}
+
+	// 所有者、inode 和硬链接数来自 syscall.Stat_t，Windows 上输出 ?
+	if runtime.GOOS != "windows" {
+		if err := os.Link(path, filepath.Join(dir, "b.txt")); err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if err := stat([]string{"-c", "%u %h %i", "a.txt", "b.txt"}, env, &IO{Stdout: &out}); err != nil {
+			t.Fatalf("stat 执行失败: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if len(lines) != 2 || lines[0] != lines[1] || !strings.HasPrefix(lines[0], strconv.Itoa(os.Getuid())+" 2 ") {
+			t.Errorf("stat -c '%%u %%h %%i' 输出 %q，期望两个文件的 uid、链接数 2 和相同的 inode", out.String())
+		}
+		out.Reset()
+		if err := stat([]string{"-c", "%U %G", "a.txt"}, env, &IO{Stdout: &out}); err != nil || strings.Contains(out.String(), "?") {
+			t.Errorf("stat -c '%%U %%G' 输出 %q（%v），期望所有者和组的名称", out.String(), err)
+		}
+	}
+}
+
 func TestClear(t *testing.T) {
 	// 测试clear命令
-	err := clear([]string{}, make(map[string]string))
+	err := clear([]string{}, make(map[string]string), StdIO())
 	if err != nil {
 		t.Errorf("clear命令执行失败: %v", err)
 	}
 }
 
+
+func TestUmask(t *testing.T) {
+	umask := NewUmask()
+	umask.Set(0022)
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := umaskCmd(args, map[string]string{}, &IO{Stdout: &out, Stderr: &out, Umask: umask})
+		return out.String(), err
+	}
+
+	tests := []struct {
+		args []string
+		want string
+		mask os.FileMode
+	}{
+		{nil, "0022\n", 0022},
+		{[]string{"-S"}, "u=rwx,g=rx,o=rx\n", 0022},
+		{[]string{"-p"}, "umask 0022\n", 0022},
+		{[]string{"077"}, "", 0077},
+		{[]string{"u=rwx,g=rx,o="}, "", 0027},
+		{[]string{"g-x,o+r"}, "", 0033},
+		{[]string{"a+w"}, "", 0011},
+		{[]string{"-S", "go="}, "u=rwx,g=,o=\n", 0077},
+	}
+	for _, tt := range tests {
+		if out, err := run(tt.args...); err != nil || out != tt.want || umask.Mask() != tt.mask {
+			t.Errorf("umask %v 输出 %q（%v），掩码 %04o，期望 %q 和 %04o", tt.args, out, err, umask.Mask(), tt.want, tt.mask)
+		}
+	}
+	for _, bad := range []string{"8", "1000", "u~r", "u+z", "-z"} {
+		if _, err := run(bad); err == nil {
+			t.Errorf("umask %s 应该失败", bad)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// 创建文件和目录时使用 shell 的掩码，不受进程 umask 影响
+	dir := t.TempDir()
+	umask.Set(0027)
+	stdio := &IO{Stdin: strings.NewReader("x"), Stdout: io.Discard, Stderr: io.Discard, Umask: umask}
+	env := map[string]string{"PWD": dir}
+	if err := touch([]string{"f"}, env, stdio); err != nil {
+		t.Fatal(err)
+	}
+	if err := mkdir([]string{"-p", "a/b"}, env, stdio); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]os.FileMode{"f": 0640, "a": 0750, "a/b": 0750} {
+		if info, err := os.Stat(filepath.Join(dir, path)); err != nil || info.Mode().Perm() != want {
+			t.Errorf("%s 的权限应该为 %04o: %v %v", path, want, info.Mode(), err)
+		}
+	}
+}
+
+func TestUlimit(t *testing.T) {
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := ulimit(args, map[string]string{}, &IO{Stdout: &out, Stderr: &out})
+		return out.String(), err
+	}
+
+	out, err := run("-a")
+	if err != nil || strings.Count(out, "\n") != len(ulimitResources) || !strings.Contains(out, "(-n) ") {
+		t.Errorf("ulimit -a 输出 %q（%v）", out, err)
+	}
+	if _, err := run("-z"); err == nil {
+		t.Error("ulimit -z 应该失败")
+	}
+	if _, err := run("-n", "abc"); err == nil {
+		t.Error("ulimit -n abc 应该失败")
+	}
+
+	soft, hard, err := getRlimit('n')
+	if err != nil || soft == rlimInfinity || soft < 2 || runtime.GOOS != "linux" {
+		return
+	}
+	// 降低再恢复打开文件数的软限制
+	defer setRlimit('n', soft, hard)
+	if _, err := run("-S", "-n", strconv.FormatUint(soft-1, 10)); err != nil {
+		t.Fatalf("ulimit -S -n 失败: %v", err)
+	}
+	if out, _ := run("-n"); out != strconv.FormatUint(soft-1, 10)+"\n" {
+		t.Errorf("ulimit -n 输出 %q，期望 %d", out, soft-1)
+	}
+	if out, _ := run("-Hn"); out != formatRlimit(hard, 1)+"\n" {
+		t.Errorf("ulimit -Hn 输出 %q", out)
+	}
+}
+
+func TestDeclareOptions(t *testing.T) {
+	exports := NewExports()
+	exports.Export("V")
+	declare := DeclareBuiltin(Variables{
+		Exports:     exports,
+		Arrays:      func() map[string][]string { return map[string][]string{"arr": {"x", "y z"}} },
+		ArrayIndices: func() map[string][]int { return map[string][]int{"arr": {1, 4}} },
+		AssocArrays: func() map[string]map[string]string { return map[string]map[string]string{"m": {"b": "2", "a": "1"}} },
+		Functions:   func() map[string]string { return map[string]string{"g": "{ echo g; }", "f": "{ echo f; }"} },
+		Arithmetic:  func(expr string) (string, error) { return "(" + expr + ")", nil },
+	})
+	env := map[string]string{"V": `say "hi" $x`, "arr": "x", "arr_LENGTH": "2", "?": "0", "__WBASH_X": "1"}
+	run := func(args ...string) (string, string, error) {
+		var out, errOut bytes.Buffer
+		err := declare(args, env, &IO{Stdout: &out, Stderr: &errOut})
+		return out.String(), errOut.String(), err
+	}
+	exitStatus := func(err error) int {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			return statusErr.Code
+		}
+		return -1
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-p", "V", "arr", "m"}, "declare -x V=\"say \\\"hi\\\" \\$x\"\ndeclare -a arr=([1]=\"x\" [4]=\"y z\")\ndeclare -A m=([a]=\"1\" [b]=\"2\" )\n"},
+		{[]string{"-p"}, "declare -x V=\"say \\\"hi\\\" \\$x\"\ndeclare -a arr=([1]=\"x\" [4]=\"y z\")\ndeclare -A m=([a]=\"1\" [b]=\"2\" )\n"},
+		{nil, "V='say \"hi\" $x'\narr=([1]=\"x\" [4]=\"y z\")\nm=([a]=\"1\" [b]=\"2\" )\nf () { echo f; }\ng () { echo g; }\n"},
+		{[]string{"-f", "g"}, "g () { echo g; }\n"},
+		{[]string{"-F"}, "declare -f f\ndeclare -f g\n"},
+	}
+	for _, tt := range tests {
+		if out, _, err := run(tt.args...); err != nil || out != tt.want {
+			t.Errorf("declare %v 输出 %q（%v），期望 %q", tt.args, out, err, tt.want)
+		}
+	}
+
+	// 找不到的名称输出错误，退出状态为 1
+	if out, errOut, err := run("-p", "V", "missing"); !strings.HasPrefix(out, "declare -x V=") || errOut != "declare: missing: not found\n" || exitStatus(err) != 1 {
+		t.Errorf("declare -p missing 输出 %q %q（%v）", out, errOut, err)
+	}
+	if _, _, err := run("-f", "nofunc"); exitStatus(err) != 1 {
+		t.Errorf("declare -f nofunc 退出状态应该为 1: %v", err)
+	}
+	if _, _, err := run("-Q"); exitStatus(err) != 2 {
+		t.Errorf("declare -Q 退出状态应该为 2: %v", err)
+	}
+
+	// 赋值并设置属性
+	if _, _, err := run("-x", "N=1"); err != nil || env["N"] != "1" || !exports.IsExported("N") {
+		t.Errorf("declare -x N=1 失败: %v %q", err, env["N"])
+	}
+	// 有整数属性的变量的值按算术表达式求值，+i 取消整数属性
+	if _, _, err := run("-ix", "I=5+1"); err != nil || env["I"] != "(5+1)" {
+		t.Errorf("declare -ix I=5+1 失败: %v %q", err, env["I"])
+	}
+	if out, _, _ := run("-p", "I"); out != "declare -ix I=\"(5+1)\"\n" {
+		t.Errorf("declare -p I 输出 %q", out)
+	}
+	if _, _, err := run("+i", "I=2"); err != nil || env["I"] != "2" {
+		t.Errorf("declare +i I=2 失败: %v %q", err, env["I"])
+	}
+}
+
+func TestPrintf(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{"%s-%d|%5.2f|%x|%o\\n", "a", "42", "3.14159", "255", "8"}, "a-42| 3.14|ff|10\n", 0},
+		{[]string{`%s\n`, "x", "y"}, "x\ny\n", 0},
+		{[]string{`\101\t%b|%-3s|`, `c\x41\101\n`, "z"}, "A\tcAA\n|z  |", 0},
+		{[]string{"%q %q %q", "a b", "", "it's"}, `a\ b '' it\'s`, 0},
+		{[]string{"%c%c %d %d", "hello", "x", "'A", "0x10"}, "hx 65 16", 0},
+		{[]string{"%*d|%.*s|%05d", "4", "3", "2", "abc", "-42"}, "   3|ab|-0042", 0},
+		{[]string{"%e %g %%", "1.5", "0.0001234567"}, "1.500000e+00 0.000123457 %", 0},
+		{[]string{"%b-%s", `stop\chere`, "never"}, "stop", 0},
+		{[]string{"%d|", "12", "abc"}, "12|0|", 1},
+		{[]string{}, "", 2},
+	}
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		err := printfCommand(tt.args, map[string]string{}, &IO{Stdout: &out, Stderr: &errOut})
+		code := 0
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			code = statusErr.Code
+		} else if err != nil {
+			code = -1
+		}
+		if out.String() != tt.want || code != tt.code {
+			t.Errorf("printf %q 输出 %q（退出状态 %d），期望 %q（%d）", tt.args, out.String(), code, tt.want, tt.code)
+		}
+	}
+
+	// -v 把结果赋给变量
+	var assigned []string
+	printf := PrintfBuiltin(func(name, value string) error {
+		assigned = append(assigned, name+"="+value)
+		return nil
+	})
+	var out bytes.Buffer
+	if err := printf([]string{"-v", "v", "%03d", "7"}, map[string]string{}, &IO{Stdout: &out}); err != nil || out.Len() != 0 || len(assigned) != 1 || assigned[0] != "v=007" {
+		t.Errorf("printf -v 赋值 %v，输出 %q（%v）", assigned, out.String(), err)
+	}
+	if err := printf([]string{"-v", "1x", "y"}, map[string]string{}, &IO{Stdout: &out}); err == nil {
+		t.Error("printf -v 1x 应该失败")
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		args  []string
+		ifs   *string
+		input string
+		want  string // 赋值后的变量，按名称的顺序
+		code  int
+	}{
+		{[]string{"a", "b"}, nil, "  x  y  z  \nnext", "a=x b=y  z", 0},
+		{[]string{"a", "b"}, nil, "x  \n", "a=x b=", 0},
+		{nil, nil, "  a b  \n", "REPLY=  a b  ", 0},
+		{[]string{"v"}, ptr(""), "  a b  \n", "v=  a b  ", 0},
+		{[]string{"x", "y"}, nil, "a\\ b\\\nc d\n", "x=a bc y=d", 0},
+		{[]string{"-r", "x"}, nil, "a\\ b\n", "x=a\\ b", 0},
+		{[]string{"x", "y"}, ptr(":"), "a\\:b:c\n", "x=a:b y=c", 0},
+		{[]string{"a", "b"}, ptr(":"), "x:y:\n", "a=x b=y", 0},
+		{[]string{"a", "b"}, ptr(":"), "x:y:z:\n", "a=x b=y:z:", 0},
+		{[]string{"a", "b"}, ptr(":"), "::x\n", "a= b=:x", 0},
+		{[]string{"a", "b"}, ptr(": "), "x: y : \n", "a=x b=y", 0},
+		{[]string{"-n", "3", "x"}, nil, "abcdef", "x=abc", 0},
+		{[]string{"-n2", "x"}, nil, "中文字", "x=中文", 0},
+		{[]string{"-N", "4", "x"}, nil, "a b\nc", "x=a b\n", 0},
+		{[]string{"-d", ";", "x"}, nil, "a,b;c", "x=a,b", 0},
+		{[]string{"-d", "", "x"}, nil, "a\nb", "x=a\nb", 1},
+		{[]string{"x"}, nil, "", "x=", 1},
+		{[]string{"x"}, nil, "last", "x=last", 1},
+		{[]string{"-z"}, nil, "", "", 2},
+		{[]string{"-n"}, nil, "", "", 2},
+		{[]string{"1x"}, nil, "", "", -1},
+	}
+	for _, tt := range tests {
+		env := map[string]string{}
+		if tt.ifs != nil {
+			env["IFS"] = *tt.ifs
+		}
+		var assigned []string
+		read := ReadBuiltin(Variables{}, func(name, value string) error {
+			assigned = append(assigned, name+"="+value)
+			return nil
+		}, nil)
+		err := read(tt.args, env, &IO{Stdin: strings.NewReader(tt.input), Stderr: io.Discard})
+		code := 0
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			code = statusErr.Code
+		} else if err != nil {
+			code = -1
+		}
+		if got := strings.Join(assigned, " "); got != tt.want || code != tt.code {
+			t.Errorf("read %q <<< %q 赋值 %q（退出状态 %d），期望 %q（%d）", tt.args, tt.input, got, code, tt.want, tt.code)
+		}
+	}
+
+	// -a 把字段存入数组
+	arrays := map[string][]string{}
+	read := ReadBuiltin(Variables{Arrays: func() map[string][]string { return arrays }}, nil, func(n int) (io.Reader, bool) {
+		if n == 5 {
+			return strings.NewReader("from5\n"), true
+		}
+		return nil, false
+	})
+	env := map[string]string{"IFS": ":", "arr": "old"}
+	if err := read([]string{"-a", "arr"}, env, &IO{Stdin: strings.NewReader("a:b::c:\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(arrays["arr"], ","); got != "a,b,,c" || env["arr"] != "" {
+		t.Errorf("read -a 得到 %q", got)
+	}
+
+	// -u 从 shell 打开的文件描述符读取，没有打开的文件描述符报告错误
+	if err := read([]string{"-u", "5", "x"}, env, &IO{Stdin: strings.NewReader("stdin\n")}); err != nil || env["x"] != "from5" {
+		t.Errorf("read -u 5 得到 %q（%v）", env["x"], err)
+	}
+	if err := read([]string{"-u", "7", "x"}, env, &IO{Stdin: strings.NewReader("")}); err == nil {
+		t.Error("read -u 7 没有报告错误")
+	}
+
+	// 只读取到行结束符，剩余的输入留给后面的命令
+	stdin := strings.NewReader("one\ntwo\n")
+	read([]string{"x"}, env, &IO{Stdin: stdin})
+	if rest, _ := io.ReadAll(stdin); string(rest) != "two\n" {
+		t.Errorf("read 之后剩余的输入为 %q", rest)
+	}
+}
+
+// ptr 返回 s 的指针
+func ptr(s string) *string {
+	return &s
+}
+
+func TestResolveNameref(t *testing.T) {
+	namerefs := map[string]string{"a": "b", "b": "c", "e": "", "el": "arr[1]", "loop1": "loop2", "loop2": "loop1"}
+	tests := map[string]string{
+		"a": "c", "b": "c", "x": "x", "e": "e", "a[2]": "c[2]", "el": "arr[1]", "el[0]": "arr[1]",
+	}
+	for name, want := range tests {
+		if got := ResolveNameref(namerefs, name); got != want {
+			t.Errorf("ResolveNameref(%q) = %q，期望 %q", name, got, want)
+		}
+	}
+	// 循环引用不会死循环
+	ResolveNameref(namerefs, "loop1")
+}
+
+func TestLet(t *testing.T) {
+	var evaluated []string
+	let := LetBuiltin(func(expr string) (int64, error) {
+		evaluated = append(evaluated, expr)
+		if expr == "bad" {
+			return 0, errors.New("语法错误")
+		}
+		return strconv.ParseInt(expr, 10, 64)
+	})
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"1", "2"}, 0},
+		{[]string{"1", "0"}, 1},
+		{[]string{"0", "1"}, 0},
+		{[]string{"bad"}, 1},
+		{[]string{}, 2},
+	}
+	for _, tt := range tests {
+		err := let(tt.args, map[string]string{}, &IO{})
+		code := 0
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			code = statusErr.Code
+		} else if err != nil {
+			code = -1
+		}
+		if code != tt.code {
+			t.Errorf("let %q 退出状态 %d，期望 %d", tt.args, code, tt.code)
+		}
+	}
+	if strings.Join(evaluated, " ") != "1 2 1 0 0 1 bad" {
+		t.Errorf("依次计算的表达式为 %q", evaluated)
+	}
+
+	// 没有求值函数时只计算整数和变量
+	if err := LetBuiltin(nil)([]string{"n"}, map[string]string{"n": "3"}, &IO{}); err != nil {
+		t.Errorf("let n 失败: %v", err)
+	}
+}
+
+func TestExpr(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{"1", "+", "2", "*", "3"}, "7\n", 0},
+		{[]string{"(", "1", "+", "2", ")", "*", "3"}, "9\n", 0},
+		{[]string{"5", "-", "5"}, "0\n", 1},
+		{[]string{"-7", "%", "3"}, "-1\n", 0},
+		{[]string{"length", "héllo"}, "5\n", 0},
+		{[]string{"substr", "hello", "2", "3"}, "ell\n", 0},
+		{[]string{"substr", "hello", "9", "1"}, "\n", 1},
+		{[]string{"index", "hello", "ol"}, "3\n", 0},
+		{[]string{"abc123", ":", `abc\([0-9]*\)`}, "123\n", 0},
+		{[]string{"abc123", ":", "[a-z]*"}, "3\n", 0},
+		{[]string{"match", "xyz", "y"}, "0\n", 1},
+		{[]string{"10", "<", "9"}, "0\n", 1},
+		{[]string{"a", "<", "b"}, "1\n", 0},
+		{[]string{"", "|", "7"}, "7\n", 0},
+		{[]string{"3", "&", "0"}, "0\n", 1},
+		{[]string{"+", "length"}, "length\n", 0},
+		{[]string{"1", "/", "0"}, "", 2},
+		{[]string{"a", "+", "1"}, "", 2},
+		{[]string{"(", "1"}, "", 2},
+		{[]string{"a", ":", `\(`}, "", 3},
+		{[]string{}, "", 2},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := exprCmd(tt.args, map[string]string{}, &IO{Stdout: &out})
+		code := 0
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			code = statusErr.Code
+		} else if err != nil {
+			code = -1
+		}
+		if out.String() != tt.want || code != tt.code {
+			t.Errorf("expr %q 输出 %q（退出状态 %d），期望 %q（%d）", tt.args, out.String(), code, tt.want, tt.code)
+		}
+	}
+}
+
+// TestTrap 测试 trap 设置、显示和删除陷阱
+func TestTrap(t *testing.T) {
+	traps := map[string]string{}
+	trap := TrapBuiltin(traps)
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := trap(args, map[string]string{}, &IO{Stdout: &out})
+		return out.String(), err
+	}
+
+	if _, err := run(`echo "it's $?"`, "err", "DEBUG"); err != nil {
+		t.Fatalf("trap 失败: %v", err)
+	}
+	if traps["ERR"] != `echo "it's $?"` || traps["DEBUG"] != traps["ERR"] {
+		t.Fatalf("陷阱表 = %v", traps)
+	}
+	if out, _ := run("-p", "ERR"); out != "trap -- 'echo \"it'\\''s $?\"' ERR\n" {
+		t.Errorf("trap -p ERR 输出 %q", out)
+	}
+	run("-", "DEBUG")
+	run("ERR")
+	if out, _ := run(); len(traps) != 0 || out != "" {
+		t.Errorf("删除后陷阱表 = %v，输出 %q", traps, out)
+	}
+	run("", "DEBUG")
+	if out, _ := run(); out != "trap -- '' DEBUG\n" {
+		t.Errorf("trap 输出 %q", out)
+	}
+
+	run("-", "DEBUG")
+
+	// 0 是 EXIT，信号可以带 SIG 前缀
+	if _, err := run("echo bye", "0", "sigint", "TERM"); err != nil {
+		t.Fatalf("trap 失败: %v", err)
+	}
+	if out, _ := run(); out != "trap -- 'echo bye' EXIT\ntrap -- 'echo bye' INT\ntrap -- 'echo bye' TERM\n" {
+		t.Errorf("trap 输出 %q", out)
+	}
+
+	for _, cond := range []string{"RETURN", "NOSUCH"} {
+		if _, err := run("echo x", cond); err == nil {
+			t.Errorf("trap 'echo x' %s 应该报错", cond)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"gobash/internal/i18n"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// openInput 打开输入文件，- 表示标准输入
+func openInput(env map[string]string, name string, stdio *IO) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(stdio.Stdin), nil
+	}
+	return stdio.open(resolvePath(env, name))
+}
+
+// base64Cmd 对文件或标准输入进行 base64 编码或解码
+// 用法：base64 [-di] [-w 列数] [文件]
+// 编码结果默认每 76 个字符换行，-w 0 不换行；-d 解码（忽略换行），-i 解码时忽略其他非 base64 字符
+func base64Cmd(args []string, env map[string]string, stdio *IO) error {
+	decode, ignoreGarbage := false, false
+	wrap := 76
+	var files []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		switch arg {
+		case "--":
+			parseOptions = false
+			continue
+		case "--decode":
+			decode = true
+			continue
+		case "--ignore-garbage":
+			ignoreGarbage = true
+			continue
+		}
+		value, isWrap := strings.CutPrefix(arg, "--wrap=")
+		for j := 1; !isWrap && j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'd', 'D':
+				decode = true
+			case 'i':
+				ignoreGarbage = true
+			case 'w':
+				value, isWrap = arg[j+1:], true
+				if value == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("base64: -w 缺少参数")
+					}
+					i++
+					value = args[i]
+				}
+			default:
+				return i18n.Errorf("base64: -%c: 无效选项\n用法: base64 [-di] [-w 列数] [文件]", flag)
+			}
+		}
+		if isWrap {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return i18n.Errorf("base64: 无效的列数: %q", value)
+			}
+			wrap = n
+		}
+	}
+	if len(files) > 1 {
+		return i18n.Errorf("base64: 多余的操作数: %s", files[1])
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	r, err := openInput(env, files[0], stdio)
+	if err != nil {
+		return fmt.Errorf("base64: %s: %v", files[0], pathErrorReason(err))
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("base64: %s: %v", files[0], err)
+	}
+
+	if decode {
+		// 去掉换行等空白（-i 时去掉所有非 base64 字符）
+		clean := bytes.Map(func(r rune) rune {
+			switch {
+			case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '+', r == '/', r == '=':
+				return r
+			case r == '\n' || r == '\r' || ignoreGarbage:
+				return -1
+			}
+			return r
+		}, data)
+		decoded, err := base64.StdEncoding.DecodeString(string(clean))
+		if err != nil {
+			return i18n.Errorf("base64: 无效的输入")
+		}
+		_, err = stdio.Stdout.Write(decoded)
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	writer := bufio.NewWriter(stdio.Stdout)
+	defer writer.Flush()
+	for wrap > 0 && len(encoded) > wrap {
+		writer.WriteString(encoded[:wrap] + "\n")
+		encoded = encoded[wrap:]
+	}
+	if encoded != "" {
+		writer.WriteString(encoded + "\n")
+	}
+	return nil
+}
+
+// md5sum 和 sha256sum 计算和验证 MD5、SHA-256 校验和
+var (
+	md5sum    = checksumBuiltin("md5sum", md5.New)
+	sha256sum = checksumBuiltin("sha256sum", sha256.New)
+)
+
+// checksumBuiltin 返回使用 newHash 计算校验和的 md5sum、sha256sum 等命令
+// 用法：名称 [-bt] [文件...] 或 名称 -c [--quiet] [--status] [校验文件...]
+// 输出 "校验和  文件名"（-b 时为 "校验和 *文件名"），没有文件时读取标准输入；
+// -c 从校验文件中读取这种格式的行并逐个验证，输出 "文件名: OK" 或 "文件名: FAILED"，有失败时退出状态为 1
+func checksumBuiltin(name string, newHash func() hash.Hash) BuiltinFunc {
+	return func(args []string, env map[string]string, stdio *IO) error {
+		usage := i18n.Sprintf("用法: %s [-bt] [文件...] 或 %s -c [--quiet] [--status] [校验文件...]", name, name)
+		check, binary, quiet, status := false, false, false, false
+		var files []string
+		parseOptions := true
+		for _, arg := range args {
+			if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+				files = append(files, arg)
+				continue
+			}
+			switch arg {
+			case "--":
+				parseOptions = false
+				continue
+			case "--check":
+				check = true
+				continue
+			case "--quiet":
+				quiet = true
+				continue
+			case "--status":
+				status = true
+				continue
+			}
+			for _, flag := range arg[1:] {
+				switch flag {
+				case 'c':
+					check = true
+				case 'b':
+					binary = true
+				case 't':
+					binary = false
+				default:
+					return i18n.Errorf("%s: -%c: 无效选项\n%s", name, flag, usage)
+				}
+			}
+		}
+		if len(files) == 0 {
+			files = []string{"-"}
+		}
+
+		sum := func(file string) (string, error) {
+			r, err := openInput(env, file, stdio)
+			if err != nil {
+				return "", pathErrorReason(err)
+			}
+			defer r.Close()
+			h := newHash()
+			if _, err := io.Copy(h, r); err != nil {
+				return "", err
+			}
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+
+		var errs []string
+		if !check {
+			mark := " "
+			if binary {
+				mark = "*"
+			}
+			for _, file := range files {
+				digest, err := sum(file)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %s: %v", name, file, err))
+					continue
+				}
+				fmt.Fprintf(stdio.Stdout, "%s %s%s\n", digest, mark, file)
+			}
+		} else {
+			failed, unreadable, malformed := 0, 0, 0
+			for _, checkFile := range files {
+				r, err := openInput(env, checkFile, stdio)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %s: %v", name, checkFile, pathErrorReason(err)))
+					continue
+				}
+				scanner := bufio.NewScanner(r)
+				for scanner.Scan() {
+					line := strings.TrimSuffix(scanner.Text(), "\r")
+					if line == "" || strings.HasPrefix(line, "#") {
+						continue
+					}
+					// 格式为 "校验和  文件名" 或 "校验和 *文件名"
+					expected, file, ok := strings.Cut(line, " ")
+					if !ok || len(expected) != newHash().Size()*2 || file == "" || file[0] != ' ' && file[0] != '*' {
+						malformed++
+						continue
+					}
+					file = file[1:]
+					result := "OK"
+					digest, err := sum(file)
+					switch {
+					case err != nil:
+						unreadable++
+						result = "FAILED open or read"
+						if !status {
+							fmt.Fprintf(stdio.Stderr, "%s: %s: %v\n", name, file, err)
+						}
+					case !strings.EqualFold(digest, expected):
+						failed++
+						result = "FAILED"
+					}
+					if !status && !(quiet && result == "OK") {
+						fmt.Fprintf(stdio.Stdout, "%s: %s\n", file, result)
+					}
+				}
+				r.Close()
+			}
+			// 格式不正确的行只给出警告，有文件无法读取或不匹配时退出状态为 1
+			if !status {
+				for _, warning := range []struct {
+					count int
+					text  string
+				}{
+					{malformed, "行格式不正确"},
+					{unreadable, "个文件无法读取"},
+					{failed, "个校验和不匹配"},
+				} {
+					if warning.count > 0 {
+						i18n.Fprintf(stdio.Stderr, "%s: 警告: %d %s\n", name, warning.count, i18n.T(warning.text))
+					}
+				}
+			}
+			if failed+unreadable > 0 && len(errs) == 0 {
+				return &StatusError{Code: 1}
+			}
+		}
+
+		if len(errs) > 0 {
+			return fmt.Errorf("%s", strings.Join(errs, "\n"))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,71 @@
+package builtin
+
+import (
+	"gobash/internal/i18n"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Windows 批处理文件的命令行
+//
+// CreateProcess 通过 cmd.exe 执行 .bat、.cmd 文件，cmd.exe 按自己的规则解析命令行：%VAR% 在引号中也会展开，
+// 不在引号中的 & | < > ^ 等是特殊字符，引号中的 \" 不是转义。Go 按可执行文件（CommandLineToArgvW）的规则转义参数，
+// 含有空格、引号或 %VAR% 的参数传给批处理文件时会被改变，甚至执行参数中的命令。
+// 这里自己构造 cmd.exe 的命令行（通过 SysProcAttr.CmdLine 传递）：有特殊字符的参数放在引号中，
+// 参数中的 " 写成 ""，% 写成 %%cd:~,%（展开为 %，不会与之后的文本组成变量名）
+
+// batchSpecial 批处理文件的参数中需要放在引号中的字符
+const batchSpecial = " \t\"&|<>^()%!,;=`'"
+
+// isBatchFile 检查 program 是否是批处理文件
+func isBatchFile(program string) bool {
+	ext := filepath.Ext(program)
+	return strings.EqualFold(ext, ".bat") || strings.EqualFold(ext, ".cmd")
+}
+
+// batchCommandLine 返回用 cmd.exe 执行批处理文件 script 的命令行，参数不能包含换行符
+func batchCommandLine(comspec, script string, args []string) (string, error) {
+	var b strings.Builder
+	b.WriteString(comspec)
+	// /d 不执行 AutoRun，/v:OFF 关闭 !VAR! 的延迟展开；/c 之后的第一个和最后一个引号由 cmd.exe 去掉
+	b.WriteString(` /e:ON /v:OFF /d /c "`)
+	appendBatchArg(&b, script, true)
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "\r\n\x00") {
+			return "", i18n.Errorf("%s: 批处理文件的参数不能包含换行符: %q", script, arg)
+		}
+		b.WriteByte(' ')
+		appendBatchArg(&b, arg, false)
+	}
+	b.WriteByte('"')
+	return b.String(), nil
+}
+
+// appendBatchArg 把批处理文件的参数 arg 写到 b，空参数和有特殊字符的参数（quote 为 true 时总是）放在引号中
+func appendBatchArg(b *strings.Builder, arg string, quote bool) {
+	if !quote && arg != "" && !strings.ContainsAny(arg, batchSpecial) {
+		b.WriteString(arg)
+		return
+	}
+	b.WriteByte('"')
+	for _, c := range arg {
+		switch c {
+		case '"':
+			b.WriteString(`""`)
+		case '%':
+			b.WriteString("%%cd:~,%")
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+}
+
+// commandProcessor 返回执行批处理文件的 cmd.exe 的路径（%ComSpec%）
+func commandProcessor() string {
+	if comspec := os.Getenv("ComSpec"); comspec != "" {
+		return comspec
+	}
+	return "cmd.exe"
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package builtin
+
+import "os/exec"
+
+// setCommandLine 其他系统直接把参数传给进程，没有命令行
+func setCommandLine(cmd *exec.Cmd, line string) {}
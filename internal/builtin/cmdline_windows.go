@@ -0,0 +1,14 @@
+package builtin
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCommandLine 用 line 作为创建进程的完整命令行，不再由 Go 转义 cmd.Args
+func setCommandLine(cmd *exec.Cmd, line string) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CmdLine = line
+}
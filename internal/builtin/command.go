@@ -1,10 +1,10 @@
 package builtin
 
 import (
+	"errors"
 	"fmt"
+	"gobash/internal/i18n"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 )
 
@@ -13,7 +13,7 @@ import (
 // -v: 显示命令路径或类型
 // -V: 显示详细描述
 // -p: 使用标准PATH
-func command(args []string, env map[string]string) error {
+func command(args []string, env map[string]string, stdio *IO) error {
 	if len(args) == 0 {
 		return nil
 	}
@@ -51,47 +51,20 @@ func command(args []string, env map[string]string) error {
 			// 检查是否为内置命令
 			if _, ok := builtins[cmdName]; ok {
 				if verbose {
-					fmt.Printf("%s\n", cmdName)
+					fmt.Fprintf(stdio.Stdout, "%s\n", cmdName)
 				}
 				continue
 			}
 
-			// 检查PATH环境变量
-			pathEnv := os.Getenv("PATH")
+			// 按 PATH 查找外部命令（Windows 上按 PATHEXT 补全扩展名）
+			searchEnv := env
 			if useStandardPath {
-				// 使用标准PATH（简化实现，使用当前PATH）
-				pathEnv = os.Getenv("PATH")
+				// 使用标准PATH（简化实现，使用进程启动时的PATH）
+				searchEnv = map[string]string{"PATH": os.Getenv("PATH"), "PATHEXT": env["PATHEXT"], "PWD": workDir(env)}
 			}
-
-			if pathEnv != "" {
-				paths := strings.Split(pathEnv, ":")
-				if len(paths) == 0 || (len(paths) == 1 && paths[0] == "") {
-					// Windows使用分号分隔
-					paths = strings.Split(pathEnv, ";")
-				}
-
-				found := false
-				for _, path := range paths {
-					if path == "" {
-						continue
-					}
-					fullPath := filepath.Join(path, cmdName)
-					if _, err := os.Stat(fullPath); err == nil {
-						fmt.Println(fullPath)
-						found = true
-						break
-					}
-					// 尝试添加.exe
-					if _, err := os.Stat(fullPath + ".exe"); err == nil {
-						fmt.Println(fullPath + ".exe")
-						found = true
-						break
-					}
-				}
-
-				if found {
-					continue
-				}
+			if fullPath, err := LookPath(cmdName, searchEnv); err == nil {
+				fmt.Fprintln(stdio.Stdout, fullPath)
+				continue
 			}
 
 			// 命令未找到，不输出（command -v 的行为）
@@ -109,23 +82,38 @@ func command(args []string, env map[string]string) error {
 
 	// 检查是否为内置命令
 	if builtinFunc, ok := builtins[cmdName]; ok {
-		return builtinFunc(cmdArgs, env)
+		return builtinFunc(cmdArgs, env, stdio)
 	}
 
 	// 执行外部命令
-	cmd := exec.Command(cmdName, cmdArgs...)
-	cmd.Env = getEnvArray(env)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd, err := stdio.command(nil, cmdName, cmdArgs, env)
+	if errors.Is(err, os.ErrPermission) {
+		return &StatusError{Code: 126, Message: fmt.Sprintf("command: %s: %v", cmdName, err)}
+	}
+	if err != nil {
+		return &StatusError{Code: 127, Message: i18n.Sprintf("command: %s: 命令未找到", cmdName)}
+	}
+	cmd.Env = stdio.environ(env)
+	cmd.Dir = workDir(env)
+	cmd.Stdin = stdio.Stdin
+	cmd.Stdout = stdio.Stdout
+	cmd.Stderr = stdio.Stderr
 	return cmd.Run()
 }
 
-// getEnvArray 将环境变量映射转换为数组
-func getEnvArray(env map[string]string) []string {
-	var result []string
+// exported 报告变量 name 是否传给外部命令（见 IO.Exports）
+func (stdio *IO) exported(name string) bool {
+	return stdio.Exports == nil || stdio.Exports.IsExported(name)
+}
+
+// environ 返回传给外部命令的环境变量数组，只包括 env 中导出的变量
+// 没有导出的变量时返回空数组而不是 nil（exec.Cmd 的 Env 为 nil 时会继承当前进程的环境）
+func (stdio *IO) environ(env map[string]string) []string {
+	result := []string{}
 	for k, v := range env {
-		result = append(result, fmt.Sprintf("%s=%s", k, v))
+		if stdio.exported(k) {
+			result = append(result, fmt.Sprintf("%s=%s", k, v))
+		}
 	}
 	return result
 }
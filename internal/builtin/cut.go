@@ -3,163 +3,268 @@ package builtin
 import (
 	"bufio"
 	"fmt"
-	"os"
-	"sort"
+	"gobash/internal/i18n"
+	"io"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
-// cut 剪切字段（简化版）
-func cut(args []string, env map[string]string) error {
-	delimiter := "\t" // 默认制表符
-	fields := ""
+// cutRange 字段或字符范围，end 为 0 表示到行尾
+type cutRange struct {
+	start, end int
+}
+
+// cutOptions cut 的选项
+type cutOptions struct {
+	mode          byte // 'f' 字段、'c' 字符、'b' 字节
+	ranges        []cutRange
+	delimiter     string
+	outDelimiter  string
+	hasOutDelim   bool // 是否指定了 --output-delimiter
+	onlyDelimited bool // -s 不输出没有分隔符的行
+	complement    bool // --complement 选择列表之外的部分
+}
+
+// cut 剪切字段
+// 用法：cut -f 列表 [-d 分隔符] [-s] | -c 列表 | -b 列表 [--complement] [--output-delimiter=字符串] [文件...]
+// 列表由逗号分隔的 N、N-M、N-、-M 组成；-f 时没有分隔符的行原样输出（-s 时不输出）
+func cut(args []string, env map[string]string, stdio *IO) error {
+	opts := cutOptions{delimiter: "\t"}
+	var list string
 	files := []string{}
-	
-	// 解析参数
-	i := 0
-	for i < len(args) {
+
+	// setList 设置列表类型和列表，只能指定一种列表类型
+	setList := func(mode byte, value string) error {
+		if opts.mode != 0 && opts.mode != mode {
+			return i18n.Errorf("cut: 只能指定一种列表类型")
+		}
+		opts.mode = mode
+		list = value
+		return nil
+	}
+
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
 		arg := args[i]
-		if strings.HasPrefix(arg, "-") {
-			// 解析选项
-			if strings.HasPrefix(arg, "-d") {
-				// -d 分隔符
-				if len(arg) > 2 {
-					delimiter = arg[2:]
-				} else if i+1 < len(args) {
-					delimiter = args[i+1]
-					i++
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+
+		// 长选项
+		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			needValue := func() (string, error) {
+				if hasValue {
+					return value, nil
+				}
+				if i+1 >= len(args) {
+					return "", i18n.Errorf("cut: --%s 缺少参数", name)
 				}
-			} else if strings.HasPrefix(arg, "-f") {
-				// -f 字段列表
-				if len(arg) > 2 {
-					fields = arg[2:]
-				} else if i+1 < len(args) {
-					fields = args[i+1]
+				i++
+				return args[i], nil
+			}
+			var err error
+			switch name {
+			case "complement":
+				opts.complement = true
+			case "only-delimited":
+				opts.onlyDelimited = true
+			case "output-delimiter":
+				opts.outDelimiter, err = needValue()
+				opts.hasOutDelim = true
+			case "delimiter":
+				opts.delimiter, err = needValue()
+			case "fields", "characters", "bytes":
+				if value, err = needValue(); err == nil {
+					err = setList(name[0], value)
+				}
+			default:
+				err = i18n.Errorf("cut: --%s: 无效选项", name)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			switch flag {
+			case 's':
+				opts.onlyDelimited = true
+			case 'd', 'f', 'c', 'b':
+				// 选项的值可以紧跟在选项后面，也可以是下一个参数
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("cut: -%c 缺少参数", flag)
+					}
 					i++
+					value = args[i]
 				}
+				if flag == 'd' {
+					opts.delimiter = value
+				} else if err := setList(flag, value); err != nil {
+					return err
+				}
+				j = len(arg)
+			default:
+				return i18n.Errorf("cut: -%c: 无效选项\n用法: cut -f 列表 [-d 分隔符] [-s] | -c 列表 | -b 列表 [--complement] [--output-delimiter=字符串] [文件...]", flag)
 			}
-		} else {
-			files = append(files, arg)
 		}
-		i++
 	}
-	
-	if fields == "" {
-		return fmt.Errorf("cut: 必须指定字段列表 (-f)")
+
+	if opts.mode == 0 {
+		return i18n.Errorf("cut: 必须指定字段列表 (-f)、字符列表 (-c) 或字节列表 (-b)")
 	}
-	
-	// 解析字段列表（支持 1,2,3 或 1-3 或 1-3,5 等格式）
-	fieldList, err := parseFieldList(fields)
+	if utf8.RuneCountInString(opts.delimiter) != 1 {
+		return i18n.Errorf("cut: 分隔符必须是单个字符")
+	}
+	if opts.mode != 'f' && opts.onlyDelimited {
+		return i18n.Errorf("cut: -s 只能和 -f 一起使用")
+	}
+	ranges, err := parseCutList(list)
 	if err != nil {
 		return fmt.Errorf("cut: %v", err)
 	}
-	
+	opts.ranges = ranges
+	if opts.mode == 'f' && !opts.hasOutDelim {
+		opts.outDelimiter = opts.delimiter
+	}
+
 	// 如果没有指定文件，从stdin读取
 	if len(files) == 0 {
-		return cutFromStdin(delimiter, fieldList)
+		files = []string{"-"}
 	}
-	
-	// 处理多个文件
+	var errs []string
 	for _, file := range files {
-		if err := cutFromFile(file, delimiter, fieldList); err != nil {
-			return err
+		if file == "-" {
+			cutReader(stdio.Stdin, opts, stdio)
+			continue
+		}
+		f, err := stdio.open(resolvePath(env, file))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cut: %s: %v", file, pathErrorReason(err)))
+			continue
 		}
+		cutReader(f, opts, stdio)
+		f.Close()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
 	}
-	
 	return nil
 }
 
-// parseFieldList 解析字段列表
-func parseFieldList(fields string) ([]int, error) {
-	var result []int
-	parts := strings.Split(fields, ",")
-	
-	for _, part := range parts {
+// parseCutList 解析列表（支持 1,3 或 1-3 或 3- 或 -3 等格式）
+func parseCutList(list string) ([]cutRange, error) {
+	var ranges []cutRange
+	for _, part := range strings.Split(list, ",") {
 		part = strings.TrimSpace(part)
-		if strings.Contains(part, "-") {
-			// 范围格式，如 1-3
-			rangeParts := strings.Split(part, "-")
-			if len(rangeParts) != 2 {
-				return nil, fmt.Errorf("无效的字段范围: %s", part)
-			}
-			start, err1 := strconv.Atoi(strings.TrimSpace(rangeParts[0]))
-			end, err2 := strconv.Atoi(strings.TrimSpace(rangeParts[1]))
-			if err1 != nil || err2 != nil {
-				return nil, fmt.Errorf("无效的字段范围: %s", part)
-			}
-			if start > end {
-				return nil, fmt.Errorf("字段范围起始值不能大于结束值: %s", part)
+		if part == "" || part == "-" {
+			return nil, i18n.Errorf("无效的范围: %q", part)
+		}
+
+		var r cutRange
+		var err error
+		if start, end, isRange := strings.Cut(part, "-"); isRange {
+			r.start, r.end = 1, 0
+			if start != "" {
+				if r.start, err = strconv.Atoi(start); err != nil {
+					return nil, i18n.Errorf("无效的范围: %s", part)
+				}
 			}
-			for i := start; i <= end; i++ {
-				result = append(result, i)
+			if end != "" {
+				if r.end, err = strconv.Atoi(end); err != nil {
+					return nil, i18n.Errorf("无效的范围: %s", part)
+				}
+				if r.end < r.start {
+					return nil, i18n.Errorf("范围起始值不能大于结束值: %s", part)
+				}
 			}
 		} else {
-			// 单个字段
-			field, err := strconv.Atoi(part)
-			if err != nil {
-				return nil, fmt.Errorf("无效的字段号: %s", part)
+			if r.start, err = strconv.Atoi(part); err != nil {
+				return nil, i18n.Errorf("无效的字段号: %s", part)
 			}
-			result = append(result, field)
+			r.end = r.start
 		}
-	}
-	
-	// 去重并排序
-	seen := make(map[int]bool)
-	uniqueFields := []int{}
-	for _, f := range result {
-		if !seen[f] {
-			seen[f] = true
-			uniqueFields = append(uniqueFields, f)
+		if r.start < 1 {
+			return nil, i18n.Errorf("字段和字符从 1 开始编号: %s", part)
 		}
+		ranges = append(ranges, r)
 	}
-	sort.Ints(uniqueFields)
-	
-	return uniqueFields, nil
+	return ranges, nil
 }
 
-// cutFromFile 从文件剪切
-func cutFromFile(filename string, delimiter string, fieldList []int) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return fmt.Errorf("cut: %v", err)
-	}
-	defer file.Close()
-	
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		output := cutLine(line, delimiter, fieldList)
-		fmt.Println(output)
+// selected 检查第 n 个字段（字符）是否被选中
+func (opts *cutOptions) selected(n int) bool {
+	for _, r := range opts.ranges {
+		if n >= r.start && (r.end == 0 || n <= r.end) {
+			return !opts.complement
+		}
 	}
-	
-	return scanner.Err()
+	return opts.complement
 }
 
-// cutFromStdin 从stdin剪切
-func cutFromStdin(delimiter string, fieldList []int) error {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Text()
-		output := cutLine(line, delimiter, fieldList)
-		fmt.Println(output)
+// cutReader 逐行剪切 r 的内容
+func cutReader(r io.Reader, opts cutOptions, stdio *IO) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if output, ok := cutLine(line, &opts); ok {
+			fmt.Fprintln(stdio.Stdout, output)
+		}
 	}
-	
-	return scanner.Err()
 }
 
-// cutLine 剪切一行
-func cutLine(line string, delimiter string, fieldList []int) string {
-	parts := strings.Split(line, delimiter)
-	var result []string
-	
-	for _, fieldNum := range fieldList {
-		// 字段编号从1开始
-		index := fieldNum - 1
-		if index >= 0 && index < len(parts) {
-			result = append(result, parts[index])
+// cutLine 剪切一行，第二个返回值为 false 时不输出这一行（-s 时没有分隔符的行）
+func cutLine(line string, opts *cutOptions) (string, bool) {
+	if opts.mode == 'f' {
+		if !strings.Contains(line, opts.delimiter) {
+			// 没有分隔符的行原样输出
+			return line, !opts.onlyDelimited
 		}
+		var result []string
+		for i, field := range strings.Split(line, opts.delimiter) {
+			if opts.selected(i + 1) {
+				result = append(result, field)
+			}
+		}
+		return strings.Join(result, opts.outDelimiter), true
 	}
-	
-	return strings.Join(result, delimiter)
-}
 
+	// -c 按字符，-b 按字节；指定了 --output-delimiter 时在不相邻的部分之间插入
+	var units []string
+	if opts.mode == 'c' {
+		for _, r := range line {
+			units = append(units, string(r))
+		}
+	} else {
+		for i := 0; i < len(line); i++ {
+			units = append(units, line[i:i+1])
+		}
+	}
+	var sb strings.Builder
+	prev := -1
+	for i, unit := range units {
+		if !opts.selected(i + 1) {
+			continue
+		}
+		if opts.hasOutDelim && prev >= 0 && prev != i-1 {
+			sb.WriteString(opts.outDelimiter)
+		}
+		sb.WriteString(unit)
+		prev = i
+	}
+	return sb.String(), true
+}
@@ -0,0 +1,432 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// date 的默认输出格式，与 C 语言环境下的 GNU date 相同
+const defaultDateFormat = "%a %b %e %H:%M:%S %Z %Y"
+
+// date 显示日期和时间
+// 用法：date [-uR] [-d 日期字符串] [-I[date|hours|minutes|seconds]] [+格式]
+// 格式支持 %Y、%m、%d、%H、%M、%S、%s 等转换（% 后可加 -、_、0 指定填充方式）；
+// -d 支持 "yesterday"、"2 days ago"、"next week"、"2024-01-02 10:00"、"@1700000000" 等写法；
+// -u 使用 UTC，否则使用 TZ 变量指定的时区
+func date(args []string, env map[string]string, stdio *IO) error {
+	utc := false
+	dateStr, hasDate := "", false
+	format := ""
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "+"):
+			if format != "" {
+				return i18n.Errorf("date: 多余的操作数: %s", arg)
+			}
+			format = arg[1:]
+			continue
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			return i18n.Errorf("date: 无效的日期: %s（不支持设置系统时间）", arg)
+		case arg == "--utc" || arg == "--universal":
+			utc = true
+			continue
+		case arg == "--rfc-email":
+			format = "%a, %d %b %Y %H:%M:%S %z"
+			continue
+		case arg == "--date" || strings.HasPrefix(arg, "--date="):
+			if value, ok := strings.CutPrefix(arg, "--date="); ok {
+				dateStr = value
+			} else if i+1 < len(args) {
+				i++
+				dateStr = args[i]
+			} else {
+				return i18n.Errorf("date: --date 缺少参数")
+			}
+			hasDate = true
+			continue
+		case arg == "--iso-8601" || strings.HasPrefix(arg, "--iso-8601="):
+			spec, _ := strings.CutPrefix(strings.TrimPrefix(arg, "--iso-8601"), "=")
+			isoFormat, err := isoDateFormat(spec)
+			if err != nil {
+				return err
+			}
+			format = isoFormat
+			continue
+		}
+
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'u':
+				utc = true
+			case 'R':
+				format = "%a, %d %b %Y %H:%M:%S %z"
+			case 'I':
+				isoFormat, err := isoDateFormat(arg[j+1:])
+				if err != nil {
+					return err
+				}
+				format = isoFormat
+				j = len(arg)
+			case 'd':
+				dateStr = arg[j+1:]
+				if dateStr == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("date: -d 缺少参数")
+					}
+					i++
+					dateStr = args[i]
+				}
+				hasDate = true
+				j = len(arg)
+			default:
+				return i18n.Errorf("date: -%c: 无效选项\n用法: date [-uR] [-d 日期字符串] [-I[date|hours|minutes|seconds]] [+格式]", flag)
+			}
+		}
+	}
+
+	loc := dateLocation(env)
+	if utc {
+		loc = time.UTC
+	}
+	t := time.Now().In(loc)
+	if hasDate {
+		parsed, err := parseDateString(dateStr, t)
+		if err != nil {
+			return i18n.Errorf("date: 无效的日期: %q", dateStr)
+		}
+		t = parsed
+	}
+
+	if format == "" {
+		format = defaultDateFormat
+	}
+	fmt.Fprintln(stdio.Stdout, formatDate(t, format))
+	return nil
+}
+
+// dateLocation 返回 TZ 变量指定的时区，没有设置时使用本地时区，无法识别时使用 UTC
+func dateLocation(env map[string]string) *time.Location {
+	tz, ok := env["TZ"]
+	if !ok {
+		return time.Local
+	}
+	if loc, err := time.LoadLocation(strings.TrimPrefix(tz, ":")); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// isoDateFormat 返回 -I 选项对应的格式
+func isoDateFormat(spec string) (string, error) {
+	switch spec {
+	case "", "date":
+		return "%Y-%m-%d", nil
+	case "hours":
+		return "%Y-%m-%dT%H%:z", nil
+	case "minutes":
+		return "%Y-%m-%dT%H:%M%:z", nil
+	case "seconds":
+		return "%Y-%m-%dT%H:%M:%S%:z", nil
+	case "ns":
+		return "%Y-%m-%dT%H:%M:%S,%N%:z", nil
+	}
+	return "", i18n.Errorf("date: 无效的 ISO 8601 精度: %s", spec)
+}
+
+// formatDate 按 strftime 风格的格式输出时间
+func formatDate(t time.Time, format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+
+		// 填充方式：- 不填充，_ 用空格填充，0 用 0 填充
+		var padFlag byte
+		if strings.IndexByte("-_0", format[i]) >= 0 && i+1 < len(format) {
+			padFlag = format[i]
+			i++
+		}
+		// %:z 输出带冒号的时区偏移
+		if format[i] == ':' && i+1 < len(format) && format[i+1] == 'z' {
+			i++
+			sb.WriteString(t.Format("-07:00"))
+			continue
+		}
+
+		// number 输出宽度为 width 的数字，pad 是默认的填充字符
+		number := func(n, width int, pad byte) string {
+			switch padFlag {
+			case '-':
+				return strconv.Itoa(n)
+			case '_':
+				pad = ' '
+			case '0':
+				pad = '0'
+			}
+			s := strconv.Itoa(n)
+			if len(s) < width {
+				s = strings.Repeat(string(pad), width-len(s)) + s
+			}
+			return s
+		}
+
+		hour12 := t.Hour() % 12
+		if hour12 == 0 {
+			hour12 = 12
+		}
+		isoYear, isoWeek := t.ISOWeek()
+		yday := t.YearDay() - 1
+		wday := int(t.Weekday())
+
+		switch format[i] {
+		case '%':
+			sb.WriteByte('%')
+		case 'a':
+			sb.WriteString(t.Weekday().String()[:3])
+		case 'A':
+			sb.WriteString(t.Weekday().String())
+		case 'b', 'h':
+			sb.WriteString(t.Month().String()[:3])
+		case 'B':
+			sb.WriteString(t.Month().String())
+		case 'c':
+			sb.WriteString(formatDate(t, "%a %b %e %H:%M:%S %Y"))
+		case 'C':
+			sb.WriteString(number(t.Year()/100, 2, '0'))
+		case 'd':
+			sb.WriteString(number(t.Day(), 2, '0'))
+		case 'D', 'x':
+			sb.WriteString(formatDate(t, "%m/%d/%y"))
+		case 'e':
+			sb.WriteString(number(t.Day(), 2, ' '))
+		case 'F':
+			sb.WriteString(formatDate(t, "%Y-%m-%d"))
+		case 'g':
+			sb.WriteString(number(isoYear%100, 2, '0'))
+		case 'G':
+			sb.WriteString(number(isoYear, 4, '0'))
+		case 'H':
+			sb.WriteString(number(t.Hour(), 2, '0'))
+		case 'I':
+			sb.WriteString(number(hour12, 2, '0'))
+		case 'j':
+			sb.WriteString(number(t.YearDay(), 3, '0'))
+		case 'k':
+			sb.WriteString(number(t.Hour(), 2, ' '))
+		case 'l':
+			sb.WriteString(number(hour12, 2, ' '))
+		case 'm':
+			sb.WriteString(number(int(t.Month()), 2, '0'))
+		case 'M':
+			sb.WriteString(number(t.Minute(), 2, '0'))
+		case 'n':
+			sb.WriteByte('\n')
+		case 'N':
+			sb.WriteString(fmt.Sprintf("%09d", t.Nanosecond()))
+		case 'p':
+			sb.WriteString(t.Format("PM"))
+		case 'P':
+			sb.WriteString(strings.ToLower(t.Format("PM")))
+		case 'r':
+			sb.WriteString(formatDate(t, "%I:%M:%S %p"))
+		case 'R':
+			sb.WriteString(formatDate(t, "%H:%M"))
+		case 's':
+			sb.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			sb.WriteString(number(t.Second(), 2, '0'))
+		case 't':
+			sb.WriteByte('\t')
+		case 'T', 'X':
+			sb.WriteString(formatDate(t, "%H:%M:%S"))
+		case 'u':
+			sb.WriteString(strconv.Itoa((wday+6)%7 + 1))
+		case 'U':
+			sb.WriteString(number((yday+7-wday)/7, 2, '0'))
+		case 'V':
+			sb.WriteString(number(isoWeek, 2, '0'))
+		case 'w':
+			sb.WriteString(strconv.Itoa(wday))
+		case 'W':
+			sb.WriteString(number((yday+7-(wday+6)%7)/7, 2, '0'))
+		case 'y':
+			sb.WriteString(number(t.Year()%100, 2, '0'))
+		case 'Y':
+			sb.WriteString(number(t.Year(), 4, '0'))
+		case 'z':
+			sb.WriteString(t.Format("-0700"))
+		case 'Z':
+			sb.WriteString(t.Format("MST"))
+		default:
+			// 无法识别的转换原样输出
+			sb.WriteString(format[start : i+1])
+		}
+	}
+	return sb.String()
+}
+
+var (
+	dateDayPattern  = regexp.MustCompile(`^(\d{4})[-/](\d{1,2})[-/](\d{1,2})(?:t(\d{1,2}):(\d{2})(?::(\d{2}))?)?$`)
+	dateTimePattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})(?::(\d{2}))?$`)
+	dateNumPattern  = regexp.MustCompile(`^([+-]?\d+)([a-z]*)$`)
+)
+
+// parseDateString 解析 -d 的日期字符串，相对时间相对于 now 计算
+// 支持 now、today、yesterday、tomorrow，YYYY-MM-DD 日期和 HH:MM[:SS] 时间，@秒数，
+// 以及 "N 单位 [ago]"、"next 单位"、"last 单位" 等相对时间（可以组合使用，如 "2024-01-02 +1 day"）
+func parseDateString(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	}
+	if epoch, ok := strings.CutPrefix(s, "@"); ok {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds, 0).In(now.Location()), nil
+	}
+
+	year, month, day := now.Date()
+	hour, minute, second, nsec := now.Hour(), now.Minute(), now.Second(), now.Nanosecond()
+	dateSet, timeSet := false, false
+	var years, months, days int
+	var duration time.Duration
+
+	// addRelative 累加 n 个单位的相对时间
+	addRelative := func(n int, unit string) bool {
+		switch strings.TrimSuffix(unit, "s") {
+		case "sec", "second":
+			duration += time.Duration(n) * time.Second
+		case "min", "minute":
+			duration += time.Duration(n) * time.Minute
+		case "hour":
+			duration += time.Duration(n) * time.Hour
+		case "day":
+			days += n
+		case "week":
+			days += 7 * n
+		case "fortnight":
+			days += 14 * n
+		case "month":
+			months += n
+		case "year":
+			years += n
+		default:
+			return false
+		}
+		return true
+	}
+
+	// setTime 设置时、分、秒（秒可以省略）
+	setTime := func(h, m, sec string) error {
+		if timeSet {
+			return i18n.Errorf("重复的时间")
+		}
+		hour, _ = strconv.Atoi(h)
+		minute, _ = strconv.Atoi(m)
+		second, _ = strconv.Atoi(sec)
+		nsec = 0
+		if hour > 23 || minute > 59 || second > 59 {
+			return i18n.Errorf("无效的时间: %s:%s", h, m)
+		}
+		timeSet = true
+		return nil
+	}
+
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch field {
+		case "now", "today":
+			continue
+		case "yesterday":
+			days--
+			continue
+		case "tomorrow":
+			days++
+			continue
+		case "ago":
+			// ago 把前面的相对时间取反
+			years, months, days, duration = -years, -months, -days, -duration
+			continue
+		case "next", "last", "this":
+			if i+1 >= len(fields) {
+				return time.Time{}, i18n.Errorf("缺少时间单位")
+			}
+			n := map[string]int{"next": 1, "last": -1, "this": 0}[field]
+			i++
+			if !addRelative(n, fields[i]) {
+				return time.Time{}, i18n.Errorf("无效的时间单位: %s", fields[i])
+			}
+			continue
+		}
+
+		if m := dateDayPattern.FindStringSubmatch(field); m != nil {
+			if dateSet {
+				return time.Time{}, i18n.Errorf("重复的日期")
+			}
+			year, _ = strconv.Atoi(m[1])
+			monthNum, _ := strconv.Atoi(m[2])
+			month = time.Month(monthNum)
+			day, _ = strconv.Atoi(m[3])
+			if month < 1 || month > 12 || day < 1 || day > 31 {
+				return time.Time{}, i18n.Errorf("无效的日期: %s", field)
+			}
+			dateSet = true
+			// 2024-01-02T10:00 形式同时包含时间
+			if m[4] != "" {
+				if err := setTime(m[4], m[5], m[6]); err != nil {
+					return time.Time{}, err
+				}
+			}
+			continue
+		}
+		if m := dateTimePattern.FindStringSubmatch(field); m != nil {
+			if err := setTime(m[1], m[2], m[3]); err != nil {
+				return time.Time{}, err
+			}
+			continue
+		}
+
+		// N 单位，单位可以紧跟在数字后面，也可以是下一个字段；只有单位时表示 1 个单位
+		if m := dateNumPattern.FindStringSubmatch(field); m != nil {
+			n, err := strconv.Atoi(m[1])
+			if err != nil {
+				return time.Time{}, err
+			}
+			unit := m[2]
+			if unit == "" {
+				if i+1 >= len(fields) {
+					return time.Time{}, i18n.Errorf("缺少时间单位")
+				}
+				i++
+				unit = fields[i]
+			}
+			if !addRelative(n, unit) {
+				return time.Time{}, i18n.Errorf("无效的时间单位: %s", unit)
+			}
+			continue
+		}
+		if !addRelative(1, field) {
+			return time.Time{}, i18n.Errorf("无法识别: %s", field)
+		}
+	}
+
+	// 只指定了日期时，时间为当天 00:00:00
+	if dateSet && !timeSet {
+		hour, minute, second, nsec = 0, 0, 0, 0
+	}
+	t := time.Date(year, month, day, hour, minute, second, nsec, now.Location())
+	return t.AddDate(years, months, days).Add(duration), nil
+}
@@ -0,0 +1,450 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// declareUsage declare 命令的用法说明
+const declareUsage = "用法: declare [-aAfFgilnrtux] [-p] [名称[=值] ...]"
+
+// Variables declare 和 set 查询的变量表
+// 普通变量保存在 env 中，数组、关联数组和函数由执行器保存，为 nil 的查询表示没有这类变量
+type Variables struct {
+	Exports     *Exports                            // 变量和函数的导出属性，nil 表示没有导出的变量和函数
+	Arrays      func() map[string][]string          // 返回所有数组
+	ArrayIndices func() map[string][]int            // 返回稀疏数组的下标：数组名 -> 每个元素的下标，没有记录的数组下标从 0 连续编号
+	AssocArrays func() map[string]map[string]string // 返回所有关联数组
+	Namerefs    func() map[string]string            // 返回所有名称引用：引用名 -> 被引用的变量名（declare -n 修改它）
+	Integers    func() map[string]bool              // 返回有整数属性的变量（declare -i 修改它）
+	Readonly    func() map[string]bool              // 返回只读变量（declare -r 和 readonly 修改它）
+	Arithmetic  func(expr string) (string, error)   // 求值算术表达式，给有整数属性的变量赋值时使用
+	Functions   func() map[string]string            // 返回所有函数：函数名 -> 函数体的源代码
+}
+
+// withDefaults 把为 nil 的查询替换为空的查询
+func (v Variables) withDefaults() Variables {
+	if v.Exports == nil {
+		v.Exports = NewExports()
+	}
+	if v.Arrays == nil {
+		v.Arrays = func() map[string][]string { return nil }
+	}
+	if v.ArrayIndices == nil {
+		v.ArrayIndices = func() map[string][]int { return nil }
+	}
+	if v.AssocArrays == nil {
+		v.AssocArrays = func() map[string]map[string]string { return nil }
+	}
+	if v.Namerefs == nil {
+		namerefs := make(map[string]string)
+		v.Namerefs = func() map[string]string { return namerefs }
+	}
+	if v.Integers == nil {
+		integers := make(map[string]bool)
+		v.Integers = func() map[string]bool { return integers }
+	}
+	if v.Readonly == nil {
+		readonly := make(map[string]bool)
+		v.Readonly = func() map[string]bool { return readonly }
+	}
+	if v.Arithmetic == nil {
+		// 没有执行器时值保持不变
+		v.Arithmetic = func(expr string) (string, error) { return expr, nil }
+	}
+	if v.Functions == nil {
+		v.Functions = func() map[string]string { return nil }
+	}
+	return v
+}
+
+// maxNamerefDepth 名称引用链的最大长度，超过时不再继续解析（避免循环引用）
+const maxNamerefDepth = 8
+
+// ResolveNameref 沿着名称引用 namerefs 找到 name 最终引用的变量名，name 不是名称引用时原样返回
+// name 可以带数组下标（如 ref[1]），下标保持不变；引用目标也可以是数组元素（declare -n ref='arr[1]'）
+func ResolveNameref(namerefs map[string]string, name string) string {
+	base, index, hasIndex := strings.Cut(name, "[")
+	for depth := 0; depth < maxNamerefDepth; depth++ {
+		target, ok := namerefs[base]
+		if !ok || target == "" {
+			break
+		}
+		base = target
+	}
+	if hasIndex && !strings.Contains(base, "[") {
+		return base + "[" + index
+	}
+	return base
+}
+
+// DeclareBuiltin 返回使用变量表 vars 的 declare 命令
+// 每个执行器用自己的数组、函数和导出表创建 declare
+func DeclareBuiltin(vars Variables) BuiltinFunc {
+	vars = vars.withDefaults()
+	return func(args []string, env map[string]string, stdio *IO) error {
+		return declareCmd(vars, args, env, stdio)
+	}
+}
+
+// declare 使用空变量表的 declare 命令（执行器会用 DeclareBuiltin 替换它）
+var declare = DeclareBuiltin(Variables{})
+
+// ReadonlyBuiltin 返回使用变量表 vars 的 readonly 命令
+// 用法：readonly [-aAp] [名称[=值] ...]，与 declare -r 相同；没有名称时以 declare -p 的格式输出所有只读变量
+func ReadonlyBuiltin(vars Variables) BuiltinFunc {
+	vars = vars.withDefaults()
+	return func(args []string, env map[string]string, stdio *IO) error {
+		options := []string{"-r"}
+		for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+			if args[0] == "--" {
+				args = args[1:]
+				break
+			}
+			if flags := strings.ReplaceAll(args[0][1:], "p", ""); flags != "" {
+				options = append(options, "-"+flags)
+			}
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			names := make([]string, 0, len(vars.Readonly()))
+			for name := range vars.Readonly() {
+				if isVariable(vars, env, name) {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintln(stdio.Stdout, declareLine(vars, env, name))
+			}
+			return nil
+		}
+		err := declareCmd(vars, append(append(options, "--"), args...), env, stdio)
+		if err != nil && err.Error() != "" {
+			return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), "declare: ", "readonly: "))
+		}
+		return err
+	}
+}
+
+// readonly 使用空变量表的 readonly 命令（执行器会用 ReadonlyBuiltin 替换它）
+var readonly = ReadonlyBuiltin(Variables{})
+
+// declareCmd 声明变量或显示变量和函数
+// 用法：declare [-aAfFgilnrtux] [-p] [名称[=值] ...]
+// 没有名称时以 set 的格式输出所有变量和函数；-p 以 declare 的格式输出变量（没有名称时输出所有变量），
+// -f 输出函数定义，-F 只输出函数名；-A 声明关联数组，-x 导出变量，-n 声明名称引用（+n 取消），
+// -i 给变量加上整数属性（+i 取消），之后给它赋的值按算术表达式求值；-r 把变量设为只读，之后不能再赋值；
+// -a、-A 声明的数组和 名称=(...) 形式的数组初始值由执行器处理；
+// 给名称引用赋值时赋给它引用的变量；有名称找不到时退出状态为 1
+func declareCmd(vars Variables, args []string, env map[string]string, stdio *IO) error {
+	list, functions, functionNames, export, readonly := false, false, false, false, false
+	nameref, unsetNameref := false, false
+	integer, unsetInteger := false, false
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			break
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'p':
+				list = true
+			case 'f':
+				functions = true
+			case 'F':
+				functionNames = true
+			case 'x':
+				export = arg[0] == '-'
+			case 'n':
+				nameref, unsetNameref = arg[0] == '-', arg[0] == '+'
+			case 'i':
+				integer, unsetInteger = arg[0] == '-', arg[0] == '+'
+			case 'r':
+				readonly = readonly || arg[0] == '-'
+			case 'a', 'A':
+				// 数组由执行器声明
+			case 'g', 'l', 't', 'u':
+				// 其他属性目前只接受，不改变变量的行为
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("declare: %c%c: 无效选项\n%s", arg[0], flag, i18n.T(declareUsage))}
+			}
+		}
+	}
+	names := args[i:]
+
+	if functions || functionNames {
+		return declareFunctions(vars, names, functionNames, stdio)
+	}
+	if list {
+		if len(names) == 0 {
+			for _, name := range variableNames(vars, env) {
+				fmt.Fprintln(stdio.Stdout, declareLine(vars, env, name))
+			}
+			return nil
+		}
+		notFound := false
+		for _, name := range names {
+			if !isVariable(vars, env, name) {
+				fmt.Fprintf(stdio.Stderr, "declare: %s: not found\n", name)
+				notFound = true
+				continue
+			}
+			fmt.Fprintln(stdio.Stdout, declareLine(vars, env, name))
+		}
+		if notFound {
+			return &StatusError{Code: 1}
+		}
+		return nil
+	}
+	if len(names) == 0 {
+		WriteVariables(stdio.Stdout, env, vars)
+		return nil
+	}
+
+	var failed []string
+	namerefs := vars.Namerefs()
+	for _, arg := range names {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isName(name) {
+			failed = append(failed, i18n.Sprintf("declare: `%s': 不是有效的标识符", arg))
+			continue
+		}
+		if nameref {
+			if hasValue && !isAssignable(value) {
+				failed = append(failed, i18n.Sprintf("declare: `%s': 名称引用的值不是有效的变量名", value))
+				continue
+			}
+			if hasValue && ResolveNameref(namerefs, value) == name {
+				failed = append(failed, i18n.Sprintf("declare: %s: 名称引用不能引用自身", name))
+				continue
+			}
+			if _, ok := namerefs[name]; !ok || hasValue {
+				namerefs[name] = value
+			}
+			delete(env, name)
+			continue
+		}
+		if unsetNameref {
+			delete(namerefs, name)
+		}
+		// 给名称引用赋值时赋给它引用的变量
+		target := ResolveNameref(namerefs, name)
+		if integer {
+			vars.Integers()[target] = true
+		} else if unsetInteger {
+			delete(vars.Integers(), target)
+		}
+		if hasValue && vars.Readonly()[target] {
+			failed = append(failed, i18n.Sprintf("declare: %s: 只读变量", target))
+			continue
+		}
+		if hasValue {
+			if vars.Integers()[target] {
+				var err error
+				if value, err = vars.Arithmetic(value); err != nil {
+					failed = append(failed, "declare: "+err.Error())
+					continue
+				}
+			}
+			env[target] = value
+		}
+		if export {
+			vars.Exports.Export(name)
+		}
+		if readonly {
+			vars.Readonly()[target] = true
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// declareFunctions 输出函数定义（names 为空时输出所有函数）；namesOnly 为 true 时只输出函数名，
+// 与 bash 相同，没有指定名称时输出为 declare -f 名称
+func declareFunctions(vars Variables, names []string, namesOnly bool, stdio *IO) error {
+	all := vars.Functions()
+	listAll := len(names) == 0
+	if listAll {
+		names = sortedKeys(all)
+	}
+	notFound := false
+	for _, name := range names {
+		source, ok := all[name]
+		if !ok {
+			notFound = true
+			continue
+		}
+		attrs := "-f"
+		if vars.Exports.functions[name] {
+			attrs = "-fx"
+		}
+		if namesOnly && !listAll {
+			fmt.Fprintln(stdio.Stdout, name)
+		} else if namesOnly {
+			fmt.Fprintf(stdio.Stdout, "declare %s %s\n", attrs, name)
+		} else {
+			fmt.Fprintf(stdio.Stdout, "%s () %s\n", name, source)
+		}
+	}
+	if notFound {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
+
+// WriteVariables 以 set 的格式按名称顺序输出所有变量，然后输出所有函数
+// 普通变量输出为 名称=值（需要时用单引号引用），数组输出为 名称=([0]="a" [1]="b")，输出可以作为命令重新执行
+func WriteVariables(w io.Writer, env map[string]string, vars Variables) {
+	vars = vars.withDefaults()
+	arrays, assocArrays, namerefs := vars.Arrays(), vars.AssocArrays(), vars.Namerefs()
+	for _, name := range variableNames(vars, env) {
+		if target, ok := namerefs[name]; ok {
+			fmt.Fprintf(w, "%s=%s\n", name, setQuote(target))
+		} else if values, ok := arrays[name]; ok {
+			fmt.Fprintf(w, "%s=%s\n", name, arrayValue(values, vars.ArrayIndices()[name]))
+		} else if values, ok := assocArrays[name]; ok {
+			fmt.Fprintf(w, "%s=%s\n", name, assocValue(values))
+		} else {
+			fmt.Fprintf(w, "%s=%s\n", name, setQuote(env[name]))
+		}
+	}
+	functions := vars.Functions()
+	for _, name := range sortedKeys(functions) {
+		fmt.Fprintf(w, "%s () %s\n", name, functions[name])
+	}
+}
+
+// variableNames 按名称顺序返回所有变量（包括数组），不包括特殊参数、位置参数和内部使用的变量
+// 数组在 env 中的第一个元素和 名称_LENGTH 不作为单独的变量
+func variableNames(vars Variables, env map[string]string) []string {
+	arrays, assocArrays := vars.Arrays(), vars.AssocArrays()
+	seen := make(map[string]bool)
+	for name := range arrays {
+		seen[name] = true
+	}
+	for name := range assocArrays {
+		seen[name] = true
+	}
+	for name := range vars.Namerefs() {
+		seen[name] = true
+	}
+	for name := range env {
+		if !isName(name) || strings.HasPrefix(name, "__WBASH_") {
+			continue
+		}
+		if _, ok := arrays[strings.TrimSuffix(name, "_LENGTH")]; ok && strings.HasSuffix(name, "_LENGTH") {
+			continue
+		}
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isVariable 报告 name 是否是已经设置的变量或数组
+func isVariable(vars Variables, env map[string]string, name string) bool {
+	if _, ok := vars.Namerefs()[name]; ok {
+		return true
+	}
+	if _, ok := vars.Arrays()[name]; ok {
+		return true
+	}
+	if _, ok := vars.AssocArrays()[name]; ok {
+		return true
+	}
+	_, ok := env[name]
+	return ok && isName(name)
+}
+
+// declareLine 以 declare -p 的格式表示变量，如 declare -x HOME="/root"、declare -a arr=([0]="a")
+func declareLine(vars Variables, env map[string]string, name string) string {
+	if target, ok := vars.Namerefs()[name]; ok {
+		if target == "" {
+			return "declare -n " + name
+		}
+		return fmt.Sprintf("declare -n %s=%s", name, declareQuote(target))
+	}
+	if values, ok := vars.Arrays()[name]; ok {
+		return fmt.Sprintf("declare %s %s=%s", declareAttrs(vars, name, "a"), name, arrayValue(values, vars.ArrayIndices()[name]))
+	}
+	if values, ok := vars.AssocArrays()[name]; ok {
+		return fmt.Sprintf("declare %s %s=%s", declareAttrs(vars, name, "A"), name, assocValue(values))
+	}
+	return fmt.Sprintf("declare %s %s=%s", declareAttrs(vars, name, ""), name, declareQuote(env[name]))
+}
+
+// declareAttrs 返回 declare -p 输出中变量 name 的属性选项（如 -a、-ir、-x），kind 是数组的类型选项，没有属性时为 --
+func declareAttrs(vars Variables, name, kind string) string {
+	attrs := kind
+	if vars.Integers()[name] {
+		attrs += "i"
+	}
+	if vars.Readonly()[name] {
+		attrs += "r"
+	}
+	if vars.Exports.IsExported(name) {
+		attrs += "x"
+	}
+	if attrs == "" {
+		return "--"
+	}
+	return "-" + attrs
+}
+
+// arrayValue 以 ([0]="a" [1]="b") 的形式表示数组，indices 为每个元素的下标（nil 表示从 0 连续编号）
+func arrayValue(values []string, indices []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		index := i
+		if indices != nil {
+			index = indices[i]
+		}
+		parts[i] = "[" + strconv.Itoa(index) + "]=" + declareQuote(value)
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// assocValue 以 ([key]="value" ) 的形式表示关联数组（与 bash 相同，最后一个元素后有空格），键按顺序排列
+func assocValue(values map[string]string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(&b, "[%s]=%s ", setQuote(key), declareQuote(values[key]))
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// setQuote 按 set 输出的方式引用变量值：包含空白或特殊字符时用单引号括起来
+func setQuote(value string) string {
+	if !strings.ContainsAny(value, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// sortedKeys 按顺序返回 map 的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mountInfo 已挂载的文件系统
+type mountInfo struct {
+	source string // 设备或来源，如 /dev/sda1、C:
+	target string // 挂载点
+	fsType string // 文件系统类型，未知时为空
+}
+
+// df 显示文件系统的磁盘空间使用情况
+// 用法：df [-ahkT] [-t 类型] [文件...]
+// 没有参数时列出所有挂载的文件系统（不含总大小为 0 的伪文件系统，-a 时也列出），否则列出参数所在的文件系统；
+// 大小默认以 1K 为单位，-h 以 K、M、G 为单位，-T 显示文件系统类型，-t 只显示指定类型的文件系统
+func df(args []string, env map[string]string, stdio *IO) error {
+	human, showType, all := false, false, false
+	var types []string
+	var files []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'a':
+				all = true
+			case 'h':
+				human = true
+			case 'k':
+				human = false
+			case 'T':
+				showType = true
+			case 't':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("df: -t 缺少参数")
+					}
+					i++
+					value = args[i]
+				}
+				types = append(types, value)
+				j = len(arg)
+			default:
+				return i18n.Errorf("df: -%c: 无效选项\n用法: df [-ahkT] [-t 类型] [文件...]", flag)
+			}
+		}
+	}
+
+	// 确定要显示的文件系统以及用来查询使用情况的路径
+	var mounts []mountInfo
+	var paths []string
+	var errs []string
+	if len(files) == 0 {
+		list, err := mountedFilesystems()
+		if err != nil {
+			return i18n.Errorf("df: 无法获取文件系统列表: %v", err)
+		}
+		mounts = list
+		for _, m := range list {
+			paths = append(paths, m.target)
+		}
+	} else {
+		for _, file := range files {
+			path := resolvePath(env, file)
+			if _, err := os.Stat(path); err != nil {
+				errs = append(errs, fmt.Sprintf("df: %s: %v", file, pathErrorReason(err)))
+				continue
+			}
+			m, err := mountOf(path)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("df: %s: %v", file, err))
+				continue
+			}
+			mounts = append(mounts, m)
+			paths = append(paths, path)
+		}
+	}
+
+	sizeHeader := "1K-blocks"
+	if human {
+		sizeHeader = "Size"
+	}
+	header := []string{"Filesystem", sizeHeader, "Used", "Available", "Use%", "Mounted on"}
+	if human {
+		header[3] = "Avail"
+	}
+	if showType {
+		header = append(header[:1], append([]string{"Type"}, header[1:]...)...)
+	}
+	rows := [][]string{header}
+
+	size := func(n uint64) string {
+		if human {
+			return humanSize(int64(n))
+		}
+		return strconv.FormatUint((n+1023)/1024, 10)
+	}
+	for i, m := range mounts {
+		if len(types) > 0 && !slices.Contains(types, m.fsType) {
+			continue
+		}
+		total, free, avail, err := diskUsage(paths[i])
+		if err != nil {
+			if len(files) > 0 {
+				errs = append(errs, fmt.Sprintf("df: %s: %v", m.target, err))
+			}
+			continue
+		}
+		if total == 0 && !all && len(files) == 0 {
+			continue
+		}
+		used := total - free
+		percent := "-"
+		if used+avail > 0 {
+			// 与 GNU df 一样向上取整
+			percent = strconv.FormatUint((used*100+used+avail-1)/(used+avail), 10) + "%"
+		}
+		row := []string{m.source, size(total), size(used), size(avail), percent, m.target}
+		if showType {
+			fsType := m.fsType
+			if fsType == "" {
+				fsType = "-"
+			}
+			row = append(row[:1], append([]string{fsType}, row[1:]...)...)
+		}
+		rows = append(rows, row)
+	}
+
+	// 第一列（和类型列）左对齐，数字列右对齐，挂载点不补空格
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	textColumns := 1
+	if showType {
+		textColumns = 2
+	}
+	for _, row := range rows {
+		var sb strings.Builder
+		for i, cell := range row {
+			switch {
+			case i == len(row)-1:
+				sb.WriteString(cell)
+			case i < textColumns:
+				sb.WriteString(cell + strings.Repeat(" ", widths[i]-len(cell)+1))
+			default:
+				sb.WriteString(strings.Repeat(" ", widths[i]-len(cell)) + cell + " ")
+			}
+		}
+		fmt.Fprintln(stdio.Stdout, sb.String())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
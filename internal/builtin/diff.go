@@ -0,0 +1,613 @@
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffUsage diff 命令的用法说明
+const diffUsage = "用法: diff [-qru] [-U 行数] 文件1 文件2"
+
+// diffMaxEdits 编辑距离超过这个值时不再寻找最小差异，直接把剩余部分作为整体替换
+const diffMaxEdits = 2000
+
+// diffOptions diff 命令的选项
+type diffOptions struct {
+	brief     bool // -q 只报告文件是否不同
+	recursive bool // -r 递归比较子目录
+	unified   bool // -u 使用统一格式
+	context   int  // -U 统一格式的上下文行数
+}
+
+// differ 比较文件和目录并输出差异
+type differ struct {
+	opts    diffOptions
+	env     map[string]string
+	stdio   *IO
+	out     *bufio.Writer
+	differs bool     // 是否发现了不同
+	errors  []string // 出错的文件
+}
+
+// diff 逐行比较文件，输出它们的差异
+// 用法：diff [-qru] [-U 行数] 文件1 文件2
+// 默认输出普通格式（如 2c2、< 旧行、---、> 新行），-u 输出统一格式（默认 3 行上下文，-U 指定行数），-q 只报告是否不同；
+// 两个参数都是目录时比较同名文件，-r 时递归比较子目录；文件可以是 - 表示标准输入；
+// 退出状态：0 相同，1 不同，2 出错
+func diff(args []string, env map[string]string, stdio *IO) error {
+	opts := diffOptions{context: 3}
+	var files []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		switch arg {
+		case "--":
+			parseOptions = false
+			continue
+		case "--brief":
+			opts.brief = true
+			continue
+		case "--recursive":
+			opts.recursive = true
+			continue
+		case "--unified":
+			opts.unified = true
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'q':
+				opts.brief = true
+			case 'r':
+				opts.recursive = true
+			case 'u':
+				opts.unified = true
+			case 'U':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return &StatusError{Code: 2, Message: i18n.T("diff: -U 缺少参数")}
+					}
+					i++
+					value = args[i]
+				}
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return &StatusError{Code: 2, Message: i18n.Sprintf("diff: 无效的上下文行数: %q", value)}
+				}
+				opts.unified, opts.context = true, n
+				j = len(arg)
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("diff: -%c: 无效选项\n%s", flag, i18n.T(diffUsage))}
+			}
+		}
+	}
+	if len(files) != 2 {
+		return &StatusError{Code: 2, Message: i18n.T("diff: 需要两个文件\n") + i18n.T(diffUsage)}
+	}
+
+	d := &differ{opts: opts, env: env, stdio: stdio, out: bufio.NewWriter(stdio.Stdout)}
+	d.compare(files[0], files[1], true)
+	d.out.Flush()
+
+	switch {
+	case len(d.errors) > 0:
+		return &StatusError{Code: 2, Message: strings.Join(d.errors, "\n")}
+	case d.differs:
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
+
+// stat 返回文件信息，- 表示标准输入（当作普通文件）
+func (d *differ) stat(name string) (os.FileInfo, bool) {
+	if name == "-" {
+		return nil, true
+	}
+	info, err := os.Stat(resolvePath(d.env, name))
+	if err != nil {
+		d.errors = append(d.errors, fmt.Sprintf("diff: %s: %v", name, pathErrorReason(err)))
+		return nil, false
+	}
+	return info, true
+}
+
+// compare 比较两个路径，top 表示是命令行参数（目录和文件比较时使用目录中的同名文件）
+func (d *differ) compare(a, b string, top bool) {
+	infoA, okA := d.stat(a)
+	infoB, okB := d.stat(b)
+	if !okA || !okB {
+		return
+	}
+	dirA, dirB := infoA != nil && infoA.IsDir(), infoB != nil && infoB.IsDir()
+	switch {
+	case dirA && dirB:
+		if top || d.opts.recursive {
+			d.compareDirs(a, b)
+		} else {
+			fmt.Fprintf(d.out, "Common subdirectories: %s and %s\n", a, b)
+		}
+	case top && dirA && b != "-":
+		d.compareFiles(filepath.Join(a, filepath.Base(b)), b, false)
+	case top && dirB && a != "-":
+		d.compareFiles(a, filepath.Join(b, filepath.Base(a)), false)
+	case dirA || dirB:
+		kind := func(dir bool) string {
+			if dir {
+				return "directory"
+			}
+			return "regular file"
+		}
+		fmt.Fprintf(d.out, "File %s is a %s while file %s is a %s\n", a, kind(dirA), b, kind(dirB))
+		d.differs = true
+	default:
+		d.compareFiles(a, b, !top)
+	}
+}
+
+// compareDirs 比较两个目录中的同名文件，只在一边存在的文件输出 Only in
+func (d *differ) compareDirs(a, b string) {
+	read := func(dir string) map[string]bool {
+		err := d.stdio.checkPath("open", AccessRead, resolvePath(d.env, dir))
+		var entries []os.DirEntry
+		if err == nil {
+			entries, err = os.ReadDir(resolvePath(d.env, dir))
+		}
+		if err != nil {
+			d.errors = append(d.errors, fmt.Sprintf("diff: %s: %v", dir, pathErrorReason(err)))
+			return nil
+		}
+		names := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			names[entry.Name()] = true
+		}
+		return names
+	}
+	namesA, namesB := read(a), read(b)
+	var all []string
+	for name := range namesA {
+		all = append(all, name)
+	}
+	for name := range namesB {
+		if !namesA[name] {
+			all = append(all, name)
+		}
+	}
+	sort.Strings(all)
+
+	for _, name := range all {
+		switch {
+		case !namesB[name]:
+			fmt.Fprintf(d.out, "Only in %s: %s\n", a, name)
+			d.differs = true
+		case !namesA[name]:
+			fmt.Fprintf(d.out, "Only in %s: %s\n", b, name)
+			d.differs = true
+		default:
+			d.compare(filepath.Join(a, name), filepath.Join(b, name), false)
+		}
+	}
+}
+
+// readFile 读取文件内容，- 表示标准输入
+func (d *differ) readFile(name string) ([]byte, bool) {
+	r, err := openInput(d.env, name, d.stdio)
+	if err == nil {
+		defer r.Close()
+		var data []byte
+		if data, err = io.ReadAll(r); err == nil {
+			return data, true
+		}
+	}
+	d.errors = append(d.errors, fmt.Sprintf("diff: %s: %v", name, pathErrorReason(err)))
+	return nil, false
+}
+
+// compareFiles 比较两个文件，inDir 表示是在比较目录时发现的文件（有差异时先输出 diff 命令行）
+func (d *differ) compareFiles(a, b string, inDir bool) {
+	dataA, okA := d.readFile(a)
+	dataB, okB := d.readFile(b)
+	if !okA || !okB || bytes.Equal(dataA, dataB) {
+		return
+	}
+	d.differs = true
+
+	binary := func(data []byte) bool {
+		return bytes.IndexByte(data[:min(len(data), 8192)], 0) >= 0
+	}
+	if d.opts.brief || binary(dataA) || binary(dataB) {
+		kind := "Files"
+		if !d.opts.brief {
+			kind = "Binary files"
+		}
+		fmt.Fprintf(d.out, "%s %s and %s differ\n", kind, a, b)
+		return
+	}
+
+	if inDir {
+		flags := "-r"
+		if d.opts.unified {
+			flags = "-ru"
+		}
+		fmt.Fprintf(d.out, "diff %s %s %s\n", flags, a, b)
+	}
+	linesA, linesB := splitDiffLines(dataA), splitDiffLines(dataB)
+	edits := diffLines(linesA, linesB)
+	if d.opts.unified {
+		fmt.Fprintf(d.out, "--- %s\n+++ %s\n", d.label(a), d.label(b))
+		writeUnifiedDiff(d.out, edits, linesA, linesB, d.opts.context)
+	} else {
+		writeNormalDiff(d.out, edits, linesA, linesB)
+	}
+}
+
+// label 返回统一格式中文件名后的修改时间
+func (d *differ) label(name string) string {
+	if name == "-" {
+		return "-"
+	}
+	info, err := os.Stat(resolvePath(d.env, name))
+	if err != nil {
+		return name
+	}
+	return name + "\t" + info.ModTime().Format("2006-01-02 15:04:05.000000000 -0700")
+}
+
+// splitDiffLines 按行拆分内容，每行保留末尾的换行（最后一行可能没有）
+func splitDiffLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			lines = append(lines, string(data))
+			break
+		}
+		lines = append(lines, string(data[:i+1]))
+		data = data[i+1:]
+	}
+	return lines
+}
+
+// diffEdit 编辑操作：'=' 两边相同的行，'-' 只在 a 中的行，'+' 只在 b 中的行
+type diffEdit struct {
+	kind byte
+	a, b int // 在 a 和 b 中的位置（从 0 开始），'+' 的 a 和 '-' 的 b 是插入位置
+}
+
+// diffLines 用 Myers 算法计算把 a 变为 b 的最短编辑序列
+// 编辑距离超过 diffMaxEdits 时把中间的剩余部分作为整体替换，结果仍然正确但不一定最短
+func diffLines(a, b []string) []diffEdit {
+	// 去掉相同的开头和结尾，减少需要比较的行
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []diffEdit
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, diffEdit{'=', i, i})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	for _, e := range myersDiff(midA, midB) {
+		edits = append(edits, diffEdit{e.kind, e.a + prefix, e.b + prefix})
+	}
+	for i := suffix; i > 0; i-- {
+		edits = append(edits, diffEdit{'=', len(a) - i, len(b) - i})
+	}
+	return edits
+}
+
+// myersDiff 计算 a 到 b 的编辑序列，trace[d] 保存第 d 轮开始时对角线 -d-1..d+1 到达的 x
+func myersDiff(a, b []string) []diffEdit {
+	n, m := len(a), len(b)
+	var edits []diffEdit
+	replaceAll := func() []diffEdit {
+		for i := range a {
+			edits = append(edits, diffEdit{'-', i, 0})
+		}
+		for j := range b {
+			edits = append(edits, diffEdit{'+', n, j})
+		}
+		return edits
+	}
+	if n == 0 || m == 0 {
+		return replaceAll()
+	}
+
+	limit := min(n+m, diffMaxEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return replaceAll()
+	}
+
+	// 从终点回溯
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d] 的下标 i 对应对角线 i-d-1
+		vd := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && vd[k+d] < vd[k+d+2] {
+			prevK = k + 1
+		}
+		prevX := vd[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			edits = append(edits, diffEdit{'=', x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, diffEdit{'+', x, y - 1})
+			} else {
+				edits = append(edits, diffEdit{'-', x - 1, y})
+			}
+			x, y = prevX, prevY
+		}
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// writeDiffLine 输出一行差异，行末没有换行时补上并输出提示
+func writeDiffLine(w io.Writer, prefix, line string) {
+	if strings.HasSuffix(line, "\n") {
+		fmt.Fprint(w, prefix, line)
+		return
+	}
+	fmt.Fprint(w, prefix, line, "\n\\ No newline at end of file\n")
+}
+
+// diffRange 返回普通格式中的行范围（从 1 开始），如 3 或 3,5
+func diffRange(start, count int) string {
+	if count <= 1 {
+		return strconv.Itoa(start + count)
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(start+count)
+}
+
+// writeNormalDiff 输出普通格式的差异：连续的删除和插入组成一个 a（添加）、d（删除）或 c（修改）块
+func writeNormalDiff(w io.Writer, edits []diffEdit, a, b []string) {
+	for i := 0; i < len(edits); {
+		if edits[i].kind == '=' {
+			i++
+			continue
+		}
+		startA, startB := edits[i].a, edits[i].b
+		var deleted, inserted []string
+		for ; i < len(edits) && edits[i].kind != '='; i++ {
+			if edits[i].kind == '-' {
+				deleted = append(deleted, a[edits[i].a])
+			} else {
+				inserted = append(inserted, b[edits[i].b])
+			}
+		}
+		switch {
+		case len(inserted) == 0:
+			fmt.Fprintf(w, "%sd%d\n", diffRange(startA, len(deleted)), startB)
+		case len(deleted) == 0:
+			fmt.Fprintf(w, "%da%s\n", startA, diffRange(startB, len(inserted)))
+		default:
+			fmt.Fprintf(w, "%sc%s\n", diffRange(startA, len(deleted)), diffRange(startB, len(inserted)))
+		}
+		for _, line := range deleted {
+			writeDiffLine(w, "< ", line)
+		}
+		if len(deleted) > 0 && len(inserted) > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		for _, line := range inserted {
+			writeDiffLine(w, "> ", line)
+		}
+	}
+}
+
+// writeUnifiedDiff 输出统一格式的差异，相距不超过 2*context 行的修改合并为一个块
+func writeUnifiedDiff(w io.Writer, edits []diffEdit, a, b []string, context int) {
+	for i := 0; i < len(edits); {
+		if edits[i].kind == '=' {
+			i++
+			continue
+		}
+		// 块的范围：第一个修改之前 context 行，到最后一个修改之后 context 行
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].kind != '=' {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(end+context, len(edits))
+
+		countA, countB := 0, 0
+		for _, e := range edits[start:end] {
+			if e.kind != '+' {
+				countA++
+			}
+			if e.kind != '-' {
+				countB++
+			}
+		}
+		rangeOf := func(pos, count int) string {
+			if count == 0 {
+				return strconv.Itoa(pos) + ",0"
+			}
+			if count == 1 {
+				return strconv.Itoa(pos + 1)
+			}
+			return strconv.Itoa(pos+1) + "," + strconv.Itoa(count)
+		}
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", rangeOf(edits[start].a, countA), rangeOf(edits[start].b, countB))
+		for _, e := range edits[start:end] {
+			switch e.kind {
+			case '=':
+				writeDiffLine(w, " ", a[e.a])
+			case '-':
+				writeDiffLine(w, "-", a[e.a])
+			case '+':
+				writeDiffLine(w, "+", b[e.b])
+			}
+		}
+		i = end
+	}
+}
+
+// cmp 逐字节比较两个文件
+// 用法：cmp [-ls] 文件1 [文件2]
+// 输出第一个不同的字节位置和行号；-l 列出所有不同字节的位置和值（八进制），-s 不输出；文件2 默认为标准输入；
+// 退出状态：0 相同，1 不同，2 出错
+func cmp(args []string, env map[string]string, stdio *IO) error {
+	list, silent := false, false
+	var files []string
+	parseOptions := true
+	for _, arg := range args {
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		switch arg {
+		case "--":
+			parseOptions = false
+			continue
+		case "--quiet", "--silent":
+			silent = true
+			continue
+		case "--verbose":
+			list = true
+			continue
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'l':
+				list = true
+			case 's':
+				silent = true
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("cmp: -%c: 无效选项\n用法: cmp [-ls] 文件1 [文件2]", flag)}
+			}
+		}
+	}
+	switch len(files) {
+	case 0:
+		return &StatusError{Code: 2, Message: i18n.T("cmp: 缺少操作数\n用法: cmp [-ls] 文件1 [文件2]")}
+	case 1:
+		files = append(files, "-")
+	case 2:
+	default:
+		return &StatusError{Code: 2, Message: i18n.Sprintf("cmp: 多余的操作数: %s", files[2])}
+	}
+
+	var readers [2]*bufio.Reader
+	for i, file := range files {
+		r, err := openInput(env, file, stdio)
+		if err != nil {
+			return &StatusError{Code: 2, Message: fmt.Sprintf("cmp: %s: %v", file, pathErrorReason(err))}
+		}
+		defer r.Close()
+		readers[i] = bufio.NewReader(r)
+	}
+
+	writer := bufio.NewWriter(stdio.Stdout)
+	defer writer.Flush()
+	differs := false
+	line := 1
+	var last byte
+	for pos := int64(1); ; pos++ {
+		c1, err1 := readers[0].ReadByte()
+		c2, err2 := readers[1].ReadByte()
+		if err1 != nil || err2 != nil {
+			for i, err := range []error{err1, err2} {
+				if err != nil && err != io.EOF {
+					return &StatusError{Code: 2, Message: fmt.Sprintf("cmp: %s: %v", files[i], err)}
+				}
+			}
+			if err1 == err2 {
+				break
+			}
+			// 一个文件是另一个的前缀
+			shorter := files[0]
+			if err2 != nil {
+				shorter = files[1]
+			}
+			if silent {
+				return &StatusError{Code: 1}
+			}
+			writer.Flush()
+			if pos == 1 {
+				return &StatusError{Code: 1, Message: fmt.Sprintf("cmp: EOF on %s which is empty", shorter)}
+			}
+			if list {
+				return &StatusError{Code: 1, Message: fmt.Sprintf("cmp: EOF on %s after byte %d", shorter, pos-1)}
+			}
+			// 和 GNU cmp 一样，只统计已比较的换行符：停在行尾时报告完整的行数，停在行中时报告所在的行
+			if last == '\n' {
+				return &StatusError{Code: 1, Message: fmt.Sprintf("cmp: EOF on %s after byte %d, line %d", shorter, pos-1, line-1)}
+			}
+			return &StatusError{Code: 1, Message: fmt.Sprintf("cmp: EOF on %s after byte %d, in line %d", shorter, pos-1, line)}
+		}
+		if c1 != c2 {
+			differs = true
+			if silent {
+				return &StatusError{Code: 1}
+			}
+			if !list {
+				fmt.Fprintf(writer, "%s %s differ: byte %d, line %d\n", files[0], files[1], pos, line)
+				return &StatusError{Code: 1}
+			}
+			fmt.Fprintf(writer, "%d %3o %3o\n", pos, c1, c2)
+		}
+		if c1 == '\n' {
+			line++
+		}
+		last = c1
+	}
+	if differs {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
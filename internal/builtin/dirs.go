@@ -0,0 +1,280 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DirStack 目录栈（pushd、popd、dirs）
+// 与 bash 一样，栈顶（第 0 项）总是当前目录 PWD，这里只保存其余各项
+type DirStack struct {
+	dirs []string
+}
+
+// NewDirStack 创建空的目录栈
+func NewDirStack() *DirStack {
+	return &DirStack{}
+}
+
+// Clone 复制目录栈（子shell 使用父 shell 目录栈的副本）
+func (s *DirStack) Clone() *DirStack {
+	return &DirStack{dirs: append([]string(nil), s.dirs...)}
+}
+
+// Restore 把目录栈恢复为 saved 的内容（子shell 结束后恢复）
+func (s *DirStack) Restore(saved *DirStack) {
+	s.dirs = append([]string(nil), saved.dirs...)
+}
+
+// DirStackBuiltins 返回使用目录栈 stack 的 pushd、popd、dirs 命令
+// 每个执行器有自己的目录栈，执行器创建时用这里返回的命令覆盖默认的同名命令
+func DirStackBuiltins(stack *DirStack) map[string]BuiltinFunc {
+	return map[string]BuiltinFunc{
+		"pushd": func(args []string, env map[string]string, stdio *IO) error {
+			return pushd(stack, args, env, stdio)
+		},
+		"popd": func(args []string, env map[string]string, stdio *IO) error {
+			return popd(stack, args, env, stdio)
+		},
+		"dirs": func(args []string, env map[string]string, stdio *IO) error {
+			return dirs(stack, args, env, stdio)
+		},
+	}
+}
+
+// full 返回完整的目录栈，第 0 项是当前目录
+func (s *DirStack) full(env map[string]string) []string {
+	return append([]string{workDir(env)}, s.dirs...)
+}
+
+// stackIndex 解析 +N（从栈顶数）和 -N（从栈底数）形式的参数，返回在完整目录栈中的下标
+func stackIndex(arg string, size int) (int, bool) {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return 0, false
+	}
+	n, err := strconv.Atoi(arg[1:])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if arg[0] == '-' {
+		n = size - 1 - n
+	}
+	if n < 0 || n >= size {
+		return -1, true
+	}
+	return n, true
+}
+
+// changeDir 使用 cd 切换目录，更新 PWD 和 OLDPWD
+func changeDir(name, dir string, env map[string]string) error {
+	if err := cd([]string{"--", dir}, env, &IO{Stdout: io.Discard}); err != nil {
+		return fmt.Errorf("%s: %v", name, strings.TrimPrefix(err.Error(), "cd: "))
+	}
+	return nil
+}
+
+// pushd 将目录压入目录栈并切换到该目录
+// 用法：pushd [-n] [dir | +N | -N]
+// 没有参数时交换栈顶的两个目录；+N/-N 旋转目录栈，使第 N 项成为栈顶；
+// -n 只修改目录栈，不切换目录
+func pushd(stack *DirStack, args []string, env map[string]string, stdio *IO) error {
+	noChange := false
+	if len(args) > 0 && args[0] == "-n" {
+		noChange = true
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return i18n.Errorf("pushd: 参数太多")
+	}
+
+	full := stack.full(env)
+	switch {
+	case len(args) == 0:
+		// 交换栈顶的两个目录
+		if len(full) < 2 {
+			return i18n.Errorf("pushd: 没有其他目录")
+		}
+		if noChange {
+			return nil
+		}
+		if err := changeDir("pushd", full[1], env); err != nil {
+			return err
+		}
+		stack.dirs[0] = full[0]
+	default:
+		if idx, ok := stackIndex(args[0], len(full)); ok {
+			if idx < 0 {
+				return i18n.Errorf("pushd: %s: 目录栈索引超出范围", args[0])
+			}
+			// 旋转目录栈，栈顶总是当前目录，所以 -n 时不旋转
+			if noChange || idx == 0 {
+				break
+			}
+			rotated := append(append([]string{}, full[idx:]...), full[:idx]...)
+			if err := changeDir("pushd", rotated[0], env); err != nil {
+				return err
+			}
+			stack.dirs = rotated[1:]
+			break
+		}
+
+		dir := args[0]
+		if noChange {
+			// 不切换目录，新目录放在当前目录之后
+			stack.dirs = append([]string{resolvePath(env, dir)}, stack.dirs...)
+			break
+		}
+		if err := changeDir("pushd", dir, env); err != nil {
+			return err
+		}
+		stack.dirs = append([]string{full[0]}, stack.dirs...)
+	}
+
+	printDirStack(stack.full(env), env, stdio)
+	return nil
+}
+
+// popd 从目录栈中删除目录
+// 用法：popd [-n] [+N | -N]
+// 没有参数时删除栈顶并切换到新的栈顶目录；+N/-N 删除第 N 项；-n 只修改目录栈，不切换目录
+func popd(stack *DirStack, args []string, env map[string]string, stdio *IO) error {
+	noChange := false
+	if len(args) > 0 && args[0] == "-n" {
+		noChange = true
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return i18n.Errorf("popd: 参数太多")
+	}
+	if len(stack.dirs) == 0 {
+		return i18n.Errorf("popd: 目录栈为空")
+	}
+
+	full := stack.full(env)
+	idx := 0
+	if len(args) == 1 {
+		var ok bool
+		idx, ok = stackIndex(args[0], len(full))
+		if !ok {
+			return i18n.Errorf("popd: %s: 无效参数\n用法: popd [-n] [+N | -N]", args[0])
+		}
+		if idx < 0 {
+			return i18n.Errorf("popd: %s: 目录栈索引超出范围", args[0])
+		}
+	}
+	if idx == 0 && noChange {
+		// -n 时删除当前目录之后的一项
+		idx = 1
+	}
+
+	if idx == 0 {
+		if err := changeDir("popd", full[1], env); err != nil {
+			return err
+		}
+		stack.dirs = stack.dirs[1:]
+	} else {
+		stack.dirs = append(stack.dirs[:idx-1:idx-1], stack.dirs[idx:]...)
+	}
+
+	printDirStack(stack.full(env), env, stdio)
+	return nil
+}
+
+// dirs 显示目录栈
+// 用法：dirs [-clpv] [+N | -N]
+// -c 清空目录栈（没有其他要求输出的选项时不显示）；-l 显示完整路径（不把主目录显示为 ~）；-p 每行一个目录；-v 每行一个目录并显示序号；
+// +N/-N 只显示第 N 项
+func dirs(stack *DirStack, args []string, env map[string]string, stdio *IO) error {
+	long, perLine, verbose, cleared := false, false, false, false
+	var index string
+	for _, arg := range args {
+		if _, ok := stackIndex(arg, 1); ok {
+			index = arg
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return i18n.Errorf("dirs: %s: 无效参数\n用法: dirs [-clpv] [+N | -N]", arg)
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'c':
+				stack.dirs = nil
+				cleared = true
+			case 'l':
+				long = true
+			case 'p':
+				perLine = true
+			case 'v':
+				verbose = true
+			default:
+				return i18n.Errorf("dirs: -%c: 无效选项\n用法: dirs [-clpv] [+N | -N]", flag)
+			}
+		}
+	}
+
+	if cleared && !long && !perLine && !verbose && index == "" {
+		return nil
+	}
+
+	full := stack.full(env)
+	if index != "" {
+		idx, _ := stackIndex(index, len(full))
+		if idx < 0 {
+			return i18n.Errorf("dirs: %s: 目录栈索引超出范围", index)
+		}
+		dir := full[idx]
+		if !long {
+			dir = tildeAbbrev(dir, env)
+		}
+		fmt.Fprintln(stdio.Stdout, dir)
+		return nil
+	}
+	switch {
+	case verbose:
+		for i, dir := range full {
+			if !long {
+				dir = tildeAbbrev(dir, env)
+			}
+			fmt.Fprintf(stdio.Stdout, "%2d  %s\n", i, dir)
+		}
+	case perLine:
+		for _, dir := range full {
+			if !long {
+				dir = tildeAbbrev(dir, env)
+			}
+			fmt.Fprintln(stdio.Stdout, dir)
+		}
+	case long:
+		fmt.Fprintln(stdio.Stdout, strings.Join(full, " "))
+	default:
+		printDirStack(full, env, stdio)
+	}
+	return nil
+}
+
+// printDirStack 在一行中输出目录栈，主目录显示为 ~（pushd、popd 成功后同样输出）
+func printDirStack(full []string, env map[string]string, stdio *IO) {
+	parts := make([]string, len(full))
+	for i, dir := range full {
+		parts[i] = tildeAbbrev(dir, env)
+	}
+	fmt.Fprintln(stdio.Stdout, strings.Join(parts, " "))
+}
+
+// tildeAbbrev 把主目录开头的路径显示为 ~
+func tildeAbbrev(dir string, env map[string]string) string {
+	home := homeDir(env)
+	if home == "" {
+		return dir
+	}
+	if dir == home {
+		return "~"
+	}
+	if strings.HasPrefix(dir, home+"/") {
+		return "~" + dir[len(home):]
+	}
+	return dir
+}
@@ -0,0 +1,57 @@
+//go:build darwin || freebsd
+
+package builtin
+
+import (
+	"syscall"
+)
+
+// mntNoWait getfsstat 的 MNT_NOWAIT 标志：不刷新，直接返回缓存的信息（syscall 包中没有定义）
+const mntNoWait = 2
+
+// mountedFilesystems 用 getfsstat 获取已挂载的文件系统
+func mountedFilesystems() ([]mountInfo, error) {
+	n, err := syscall.Getfsstat(nil, mntNoWait)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]syscall.Statfs_t, n)
+	if n, err = syscall.Getfsstat(stats, mntNoWait); err != nil {
+		return nil, err
+	}
+	mounts := make([]mountInfo, 0, n)
+	for _, st := range stats[:n] {
+		mounts = append(mounts, statfsMount(&st))
+	}
+	return mounts, nil
+}
+
+// mountOf 返回 path 所在的文件系统
+func mountOf(path string) (mountInfo, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return mountInfo{}, err
+	}
+	return statfsMount(&st), nil
+}
+
+// statfsMount 从 statfs 的结果中取出挂载信息
+func statfsMount(st *syscall.Statfs_t) mountInfo {
+	return mountInfo{
+		source: cString(st.Mntfromname[:]),
+		target: cString(st.Mntonname[:]),
+		fsType: cString(st.Fstypename[:]),
+	}
+}
+
+// cString 把以 0 结尾的字符数组转换为字符串
+func cString(chars []int8) string {
+	b := make([]byte, 0, len(chars))
+	for _, c := range chars {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
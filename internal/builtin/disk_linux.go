@@ -0,0 +1,77 @@
+package builtin
+
+import (
+	"bufio"
+	"gobash/internal/i18n"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mountedFilesystems 从 /proc/self/mounts 读取已挂载的文件系统
+func mountedFilesystems() ([]mountInfo, error) {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountInfo
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, mountInfo{
+			source: unescapeMountField(fields[0]),
+			target: unescapeMountField(fields[1]),
+			fsType: fields[2],
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountField 还原 /proc/self/mounts 中用 \NNN 转义的空格等字符
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// mountOf 返回 path 所在的文件系统：挂载点是 path 前缀中最长的一个（后挂载的覆盖先挂载的）
+func mountOf(path string) (mountInfo, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	mounts, err := mountedFilesystems()
+	if err != nil {
+		return mountInfo{}, err
+	}
+	var best mountInfo
+	found := false
+	for _, m := range mounts {
+		if m.target == path || m.target == "/" || strings.HasPrefix(path, strings.TrimSuffix(m.target, "/")+"/") {
+			if !found || len(m.target) >= len(best.target) {
+				best, found = m, true
+			}
+		}
+	}
+	if !found {
+		return mountInfo{}, i18n.Errorf("找不到所在的文件系统")
+	}
+	return best, nil
+}
@@ -0,0 +1,24 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package builtin
+
+import (
+	"io/fs"
+)
+
+// fileBlocks 返回文件占用的磁盘空间（使用文件大小）
+func fileBlocks(info fs.FileInfo) int64 {
+	return info.Size()
+}
+
+func diskUsage(path string) (total, free, avail uint64, err error) {
+	return 0, 0, 0, errUnsupported
+}
+
+func mountedFilesystems() ([]mountInfo, error) {
+	return nil, errUnsupported
+}
+
+func mountOf(path string) (mountInfo, error) {
+	return mountInfo{}, errUnsupported
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package builtin
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileBlocks 返回文件实际占用的磁盘空间（字节）
+func fileBlocks(info fs.FileInfo) int64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(st.Blocks) * 512
+	}
+	return info.Size()
+}
+
+// diskUsage 返回 path 所在文件系统的总大小、空闲空间和普通用户可用的空间（字节）
+func diskUsage(path string) (total, free, avail uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, 0, err
+	}
+	blockSize := uint64(st.Bsize)
+	return uint64(st.Blocks) * blockSize, uint64(st.Bfree) * blockSize, uint64(st.Bavail) * blockSize, nil
+}
@@ -0,0 +1,55 @@
+package builtin
+
+import (
+	"io/fs"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procGetDiskFreeSpaceExW = kernel32.NewProc("GetDiskFreeSpaceExW")
+	procGetLogicalDrives    = kernel32.NewProc("GetLogicalDrives")
+)
+
+// fileBlocks 返回文件占用的磁盘空间（Windows 上使用文件大小）
+func fileBlocks(info fs.FileInfo) int64 {
+	return info.Size()
+}
+
+// diskUsage 返回 path 所在磁盘的总大小、空闲空间和当前用户可用的空间（字节）
+func diskUsage(path string) (total, free, avail uint64, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	r, _, callErr := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&avail)), uintptr(unsafe.Pointer(&total)), uintptr(unsafe.Pointer(&free)))
+	if r == 0 {
+		return 0, 0, 0, callErr
+	}
+	return total, free, avail, nil
+}
+
+// mountedFilesystems 列出所有盘符
+func mountedFilesystems() ([]mountInfo, error) {
+	mask, _, err := procGetLogicalDrives.Call()
+	if mask == 0 {
+		return nil, err
+	}
+	var mounts []mountInfo
+	for i := 0; i < 26; i++ {
+		if mask&(1<<uint(i)) != 0 {
+			drive := string(rune('A'+i)) + ":"
+			mounts = append(mounts, mountInfo{source: drive, target: drive + `\`})
+		}
+	}
+	return mounts, nil
+}
+
+// mountOf 返回 path 所在的盘符
+func mountOf(path string) (mountInfo, error) {
+	volume := filepath.VolumeName(path)
+	return mountInfo{source: volume, target: volume + `\`}, nil
+}
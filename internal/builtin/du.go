@@ -0,0 +1,199 @@
+package builtin
+
+import (
+	"bufio"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// duOptions du 的选项
+type duOptions struct {
+	summarize bool  // -s 只输出每个参数的总计
+	human     bool  // -h 以 K、M、G 为单位输出
+	all       bool  // -a 同时输出文件
+	total     bool  // -c 最后输出所有参数的总计
+	apparent  bool  // -b 使用文件大小（字节）而不是占用的磁盘空间
+	maxDepth  int   // -d 只输出不超过该深度的目录，-1 表示不限制
+	blockSize int64 // 输出的单位（字节），-k 为 1024，-m 为 1048576
+}
+
+// du 统计文件和目录占用的磁盘空间
+// 用法：du [-abchkms] [-d 深度] [文件...]
+// 默认统计当前目录，对每个目录输出 "大小<TAB>路径"，大小以 1K 为单位（向上取整）；
+// -s 只输出每个参数的总计，-d 限制输出的目录深度，-h 以 K、M、G 为单位，-a 同时输出文件，-c 输出总计，-b 以字节为单位统计文件大小
+func du(args []string, env map[string]string, stdio *IO) error {
+	opts := duOptions{maxDepth: -1, blockSize: 1024}
+	var files []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--max-depth="); ok {
+			depth, err := strconv.Atoi(value)
+			if err != nil || depth < 0 {
+				return i18n.Errorf("du: 无效的深度: %q", value)
+			}
+			opts.maxDepth = depth
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'a':
+				opts.all = true
+			case 'b':
+				opts.apparent, opts.blockSize = true, 1
+			case 'c':
+				opts.total = true
+			case 'h':
+				opts.human = true
+			case 'k':
+				opts.blockSize = 1024
+			case 'm':
+				opts.blockSize = 1024 * 1024
+			case 's':
+				opts.summarize = true
+			case 'd':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("du: -d 缺少参数")
+					}
+					i++
+					value = args[i]
+				}
+				depth, err := strconv.Atoi(value)
+				if err != nil || depth < 0 {
+					return i18n.Errorf("du: 无效的深度: %q", value)
+				}
+				opts.maxDepth = depth
+				j = len(arg)
+			default:
+				return i18n.Errorf("du: -%c: 无效选项\n用法: du [-abchkms] [-d 深度] [文件...]", flag)
+			}
+		}
+	}
+	if opts.summarize {
+		if opts.maxDepth > 0 {
+			return i18n.Errorf("du: -s 和 -d 不能同时使用")
+		}
+		opts.maxDepth = 0
+	}
+	if len(files) == 0 {
+		files = []string{"."}
+	}
+
+	writer := bufio.NewWriter(stdio.Stdout)
+	defer writer.Flush()
+	var total int64
+	var errs []string
+	for _, file := range files {
+		if err := stdio.checkPath("open", AccessRead, resolvePath(env, file)); err != nil {
+			errs = append(errs, fmt.Sprintf("du: %s: %v", file, pathErrorReason(err)))
+			continue
+		}
+		size, fileErrs := duWalk(file, resolvePath(env, file), opts, writer)
+		total += size
+		errs = append(errs, fileErrs...)
+	}
+	if opts.total {
+		fmt.Fprintf(writer, "%s\ttotal\n", opts.format(total))
+	}
+
+	if len(errs) > 0 {
+		writer.Flush()
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// duEntry 正在统计的目录
+type duEntry struct {
+	path  string
+	size  int64
+	depth int
+}
+
+// duWalk 用 filepath.WalkDir 统计 root 占用的空间，按 du 的顺序（子目录在父目录之前）输出，返回总大小
+// display 是输出时使用的路径（用户给出的参数）
+func duWalk(display, root string, opts duOptions, w io.Writer) (int64, []string) {
+	var errs []string
+	var total int64
+	// stack 保存从 root 到当前位置的目录，目录统计完成（遍历离开它）时出栈并输出
+	var stack []*duEntry
+	pop := func() {
+		entry := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if opts.maxDepth < 0 || entry.depth <= opts.maxDepth {
+			fmt.Fprintf(w, "%s\t%s\n", opts.format(entry.size), entry.path)
+		}
+		if len(stack) > 0 {
+			stack[len(stack)-1].size += entry.size
+		} else {
+			total = entry.size
+		}
+	}
+
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		shown, depth := display, 0
+		if rel, relErr := filepath.Rel(root, path); relErr == nil && rel != "." {
+			shown = strings.TrimSuffix(display, "/") + "/" + filepath.ToSlash(rel)
+			depth = strings.Count(rel, string(filepath.Separator)) + 1
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("du: %s: %v", shown, pathErrorReason(err)))
+			return nil
+		}
+		for len(stack) > 0 && stack[len(stack)-1].depth >= depth {
+			pop()
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("du: %s: %v", shown, pathErrorReason(err)))
+			return nil
+		}
+		size := info.Size()
+		if !opts.apparent {
+			size = fileBlocks(info)
+		}
+		if d.IsDir() {
+			stack = append(stack, &duEntry{path: shown, size: size, depth: depth})
+			return nil
+		}
+		if len(stack) == 0 {
+			// 参数本身是文件
+			total = size
+			fmt.Fprintf(w, "%s\t%s\n", opts.format(size), shown)
+			return nil
+		}
+		stack[len(stack)-1].size += size
+		if opts.all && (opts.maxDepth < 0 || depth <= opts.maxDepth) {
+			fmt.Fprintf(w, "%s\t%s\n", opts.format(size), shown)
+		}
+		return nil
+	})
+	for len(stack) > 0 {
+		pop()
+	}
+	return total, errs
+}
+
+// format 按选项输出大小：-h 时以 K、M、G 为单位，否则以 blockSize 为单位向上取整
+func (opts duOptions) format(size int64) string {
+	if opts.human {
+		return humanSize(size)
+	}
+	return strconv.FormatInt((size+opts.blockSize-1)/opts.blockSize, 10)
+}
@@ -0,0 +1,131 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"gobash/internal/i18n"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// envUsage env 命令的用法说明
+const envUsage = "用法: env [-i] [-u 变量名]... [变量名=值]... [命令 [参数...]]"
+
+// env 显示环境变量，或在修改后的环境中执行命令
+// 用法：env [-i] [-u 变量名]... [变量名=值]... [命令 [参数...]]
+// -i 从空环境开始，-u 删除变量，变量名=值 设置变量；没有命令时按名称顺序输出环境变量，
+// 否则用构造的环境执行命令（内置命令使用这个环境的副本，外部命令使用由它生成的环境变量数组），不影响当前shell；
+// 退出状态：env 本身出错时为 125，命令无法执行时为 126，命令未找到时为 127，否则是命令的退出状态
+func env(args []string, env map[string]string, stdio *IO) error {
+	ignore := false
+	var unsets []string
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-" {
+			ignore = true
+			continue
+		}
+		if arg == "--" {
+			i++
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		switch {
+		case arg == "--ignore-environment":
+			ignore = true
+			continue
+		case strings.HasPrefix(arg, "--unset="):
+			unsets = append(unsets, strings.TrimPrefix(arg, "--unset="))
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'i':
+				ignore = true
+			case 'u':
+				name := arg[j+1:]
+				if name == "" {
+					if i+1 >= len(args) {
+						return &StatusError{Code: 125, Message: i18n.T("env: -u 缺少参数\n") + i18n.T(envUsage)}
+					}
+					i++
+					name = args[i]
+				}
+				unsets = append(unsets, name)
+				j = len(arg)
+			default:
+				return &StatusError{Code: 125, Message: i18n.Sprintf("env: -%c: 无效选项\n%s", flag, i18n.T(envUsage))}
+			}
+		}
+	}
+
+	// 构造新的环境：从当前shell导出的变量开始
+	newEnv := make(map[string]string)
+	if !ignore {
+		for k, v := range env {
+			if stdio.exported(k) {
+				newEnv[k] = v
+			}
+		}
+	}
+	for _, name := range unsets {
+		if name == "" || strings.Contains(name, "=") {
+			return &StatusError{Code: 125, Message: i18n.Sprintf("env: 无效的变量名: %q", name)}
+		}
+		delete(newEnv, name)
+	}
+	for ; i < len(args); i++ {
+		name, value, ok := strings.Cut(args[i], "=")
+		if !ok || name == "" {
+			break
+		}
+		newEnv[name] = value
+	}
+
+	command := args[i:]
+	if len(command) == 0 {
+		names := make([]string, 0, len(newEnv))
+		for k := range newEnv {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			fmt.Fprintf(stdio.Stdout, "%s=%s\n", k, newEnv[k])
+		}
+		return nil
+	}
+
+	// 新环境中的变量都传给命令
+	cmdStdio := *stdio
+	cmdStdio.Exports = nil
+	stdio = &cmdStdio
+	if fn, ok := builtins[command[0]]; ok {
+		// 内置命令通过 PWD 确定工作目录，-i 时也保留当前目录
+		if _, ok := newEnv["PWD"]; !ok {
+			newEnv["PWD"] = workDir(env)
+		}
+		return fn(command[1:], newEnv, stdio)
+	}
+
+	cmd, err := stdio.command(stdio.ctx(), command[0], command[1:], newEnv)
+	if err == nil {
+		cmd.Env = stdio.environ(newEnv)
+		cmd.Dir = workDir(env)
+		cmd.Stdin = stdio.Stdin
+		cmd.Stdout = stdio.Stdout
+		cmd.Stderr = stdio.Stderr
+		err = cmd.Run()
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil, errors.As(err, &exitErr):
+		return err
+	case errors.Is(err, exec.ErrNotFound):
+		return &StatusError{Code: 127, Message: i18n.Sprintf("env: %s: 命令未找到", command[0])}
+	}
+	return &StatusError{Code: 126, Message: fmt.Sprintf("env: %s: %v", command[0], err)}
+}
@@ -0,0 +1,237 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"sort"
+	"strings"
+)
+
+// exportUsage export 命令的用法说明
+const exportUsage = "用法: export [-fn] [-p] [名称[=值] ...]"
+
+// Exports 变量和函数的导出属性
+// 与 bash 相同，只有导出的变量传给外部命令：从环境继承的变量，以及用 export、declare -x 导出的变量；
+// 还记录用 export -f 导出的函数
+type Exports struct {
+	variables map[string]bool
+	functions map[string]bool
+}
+
+// NewExports 创建空的导出表（没有导出的变量和函数）
+func NewExports() *Exports {
+	return &Exports{variables: make(map[string]bool), functions: make(map[string]bool)}
+}
+
+// Clone 复制导出表（子shell 使用父 shell 导出表的副本）
+func (x *Exports) Clone() *Exports {
+	clone := NewExports()
+	for name := range x.variables {
+		clone.variables[name] = true
+	}
+	for name := range x.functions {
+		clone.functions[name] = true
+	}
+	return clone
+}
+
+// IsExported 报告变量 name 是否传给外部命令
+func (x *Exports) IsExported(name string) bool {
+	return x.variables[name]
+}
+
+// Export 给变量 name 加上导出属性
+func (x *Exports) Export(name string) {
+	x.variables[name] = true
+}
+
+// Unexport 去掉变量 name 的导出属性
+func (x *Exports) Unexport(name string) {
+	delete(x.variables, name)
+}
+
+// ExportFunction 导出函数 name（如从环境中导入的函数）
+func (x *Exports) ExportFunction(name string) {
+	x.functions[name] = true
+}
+
+// Functions 按名称顺序返回导出的函数
+func (x *Exports) Functions() []string {
+	names := make([]string, 0, len(x.functions))
+	for name := range x.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FunctionEnvName 返回导出函数 name 在环境中使用的变量名（与 bash 相同，为 BASH_FUNC_name%%），
+// 变量的值是 "() " 加上函数体，子 gobash（以及 bash）启动时据此重新定义函数
+func FunctionEnvName(name string) string {
+	return "BASH_FUNC_" + name + "%%"
+}
+
+// ParseFunctionEnvName 从环境变量名中取出导出函数的函数名，不是导出函数时返回 false
+func ParseFunctionEnvName(key string) (string, bool) {
+	if !strings.HasPrefix(key, "BASH_FUNC_") || !strings.HasSuffix(key, "%%") {
+		return "", false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(key, "BASH_FUNC_"), "%%")
+	return name, name != ""
+}
+
+// ExportBuiltin 返回使用导出表 exports 的 export 命令
+// function 返回函数体的源代码，函数不存在时返回 false；为 nil 时没有可以导出的函数
+// 每个执行器有自己的导出表，执行器创建时用这里返回的命令覆盖默认的 export
+func ExportBuiltin(exports *Exports, function func(name string) (string, bool)) BuiltinFunc {
+	if exports == nil {
+		exports = NewExports()
+	}
+	if function == nil {
+		function = func(string) (string, bool) { return "", false }
+	}
+	return func(args []string, env map[string]string, stdio *IO) error {
+		return exportCmd(exports, function, args, env, stdio)
+	}
+}
+
+// export 使用独立导出表的 export 命令（执行器会用 ExportBuiltin 替换它）
+var export = ExportBuiltin(nil, nil)
+
+// exportCmd 设置变量的导出属性
+// 用法：export [-fn] [-p] [名称[=值] ...]
+// 没有名称或使用 -p 时以 declare -x 格式输出导出的变量（-f 时输出导出的函数）；
+// -n 去掉变量的导出属性（变量仍然保留），-f 导出（与 -n 一起时取消导出）函数，子 gobash 进程会继承这些函数
+func exportCmd(exports *Exports, function func(name string) (string, bool), args []string, env map[string]string, stdio *IO) error {
+	list, unexport, functions := false, false, false
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'p':
+				list = true
+			case 'n':
+				unexport = true
+			case 'f':
+				functions = true
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("export: -%c: 无效选项\n%s", flag, i18n.T(exportUsage))}
+			}
+		}
+	}
+	args = args[i:]
+
+	if len(args) == 0 || (list && !unexport) {
+		if functions {
+			for _, name := range exports.Functions() {
+				if source, ok := function(name); ok {
+					fmt.Fprintf(stdio.Stdout, "%s () %s\ndeclare -fx %s\n", name, source, name)
+				}
+			}
+			return nil
+		}
+		names := make([]string, 0, len(env))
+		for name := range env {
+			if isName(name) && !strings.HasPrefix(name, "__WBASH_") && exports.IsExported(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stdio.Stdout, "declare -x %s=%s\n", name, declareQuote(env[name]))
+		}
+		return nil
+	}
+
+	if functions {
+		var failed []string
+		for _, name := range args {
+			if _, ok := function(name); !ok {
+				failed = append(failed, i18n.Sprintf("export: %s: 不是函数", name))
+				continue
+			}
+			if unexport {
+				delete(exports.functions, name)
+			} else {
+				exports.functions[name] = true
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%s", strings.Join(failed, "\n"))
+		}
+		return nil
+	}
+
+	// 处理参数，支持两种格式：
+	// 1. export VAR=value（一个参数）
+	// 2. export VAR value（两个参数，当 parser 将 VAR='value' 解析为两个参数时）
+	var failed []string
+	for i := 0; i < len(args); i++ {
+		key, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue && !unexport && i+1 < len(args) && !strings.Contains(args[i+1], "=") {
+			// 下一个参数可能是值（当 parser 将 VAR='value' 解析为两个参数时）
+			value, hasValue = args[i+1], true
+			i++
+		}
+		if !isName(key) {
+			failed = append(failed, i18n.Sprintf("export: `%s': 不是有效的标识符", args[i]))
+			continue
+		}
+		if hasValue {
+			// 移除引号（如果有）
+			if len(value) >= 2 {
+				if (value[0] == '"' && value[len(value)-1] == '"') ||
+					(value[0] == '\'' && value[len(value)-1] == '\'') {
+					value = value[1 : len(value)-1]
+				}
+			}
+			env[key] = value
+		}
+		// 只有变量名时保留现有值
+		if unexport {
+			exports.Unexport(key)
+		} else {
+			exports.Export(key)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// isName 检查 s 是否是有效的变量名（字母或下划线开头，只包含字母、数字和下划线）
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, ch := range s {
+		if ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (i > 0 && ch >= '0' && ch <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// declareQuote 用双引号引用变量值（declare -p 格式），转义 "、\、$ 和 `
+func declareQuote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, ch := range value {
+		if ch == '"' || ch == '\\' || ch == '$' || ch == '`' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(ch)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
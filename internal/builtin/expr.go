@@ -0,0 +1,413 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// exprError expr 的错误，Code 为退出状态（2 表示表达式无效，3 表示其他错误）
+type exprError struct {
+	code    int
+	message string
+}
+
+func (e *exprError) Error() string {
+	return e.message
+}
+
+// exprInvalid 返回表达式无效的错误（退出状态 2）
+func exprInvalid(format string, args ...interface{}) error {
+	return &exprError{code: 2, message: i18n.Sprintf(format, args...)}
+}
+
+// exprCmd 计算表达式并输出结果
+// 用法：expr 表达式
+// 运算符（优先级从低到高）：| & < <= = == != >= > + - * / % :，以及 match 字符串 正则、substr 字符串 位置 长度、
+// index 字符串 字符集、length 字符串、+ 单词（把单词当作字符串，即使它是运算符）和括号；
+// : 和 match 从开头匹配基本正则表达式，有 \( \) 时结果为第一个分组匹配的内容，否则为匹配的字符数。
+// 结果既不是空字符串也不是 0 时退出状态为 0，否则为 1；表达式无效时为 2，其他错误（如正则表达式无效）为 3
+func exprCmd(args []string, env map[string]string, stdio *IO) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return &StatusError{Code: 2, Message: i18n.T("expr: 缺少操作数")}
+	}
+	p := &exprParser{args: args}
+	result, err := p.or()
+	if err == nil && p.pos < len(args) {
+		err = exprInvalid("语法错误: 意外的参数 `%s'", args[p.pos])
+	}
+	if err != nil {
+		code := 2
+		if e, ok := err.(*exprError); ok {
+			code = e.code
+		}
+		return &StatusError{Code: code, Message: "expr: " + err.Error()}
+	}
+	fmt.Fprintln(stdio.Stdout, result)
+	if exprNull(result) {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
+
+// exprParser 按优先级递归下降计算 expr 的参数
+type exprParser struct {
+	args []string
+	pos  int
+}
+
+// peek 返回下一个参数，没有时返回空字符串
+func (p *exprParser) peek() (string, bool) {
+	if p.pos < len(p.args) {
+		return p.args[p.pos], true
+	}
+	return "", false
+}
+
+// accept 下一个参数是 ops 中的运算符时跳过它并返回该运算符
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	next, ok := p.peek()
+	if !ok {
+		return "", false
+	}
+	for _, op := range ops {
+		if next == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// or 计算 a | b：a 既不是空字符串也不是 0 时为 a，否则 b 不是空字符串也不是 0 时为 b，否则为 0
+func (p *exprParser) or() (string, error) {
+	left, err := p.and()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, ok := p.accept("|"); !ok {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case !exprNull(left):
+		case !exprNull(right):
+			left = right
+		default:
+			left = "0"
+		}
+	}
+}
+
+// and 计算 a & b：a 和 b 都不是空字符串也不是 0 时为 a，否则为 0
+func (p *exprParser) and() (string, error) {
+	left, err := p.compare()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, ok := p.accept("&"); !ok {
+			return left, nil
+		}
+		right, err := p.compare()
+		if err != nil {
+			return "", err
+		}
+		if exprNull(left) || exprNull(right) {
+			left = "0"
+		}
+	}
+}
+
+// compare 计算比较运算，两边都是整数时按数值比较，否则按字符串比较，结果为 1 或 0
+func (p *exprParser) compare() (string, error) {
+	left, err := p.sum()
+	if err != nil {
+		return "", err
+	}
+	for {
+		op, ok := p.accept("<", "<=", "=", "==", "!=", ">=", ">")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.sum()
+		if err != nil {
+			return "", err
+		}
+		var cmp int
+		a, aErr := exprInteger(left)
+		b, bErr := exprInteger(right)
+		if aErr == nil && bErr == nil {
+			cmp = 0
+			if a < b {
+				cmp = -1
+			} else if a > b {
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(left, right)
+		}
+		var holds bool
+		switch op {
+		case "<":
+			holds = cmp < 0
+		case "<=":
+			holds = cmp <= 0
+		case "=", "==":
+			holds = cmp == 0
+		case "!=":
+			holds = cmp != 0
+		case ">=":
+			holds = cmp >= 0
+		case ">":
+			holds = cmp > 0
+		}
+		left = "0"
+		if holds {
+			left = "1"
+		}
+	}
+}
+
+// sum 计算 + 和 -
+func (p *exprParser) sum() (string, error) {
+	left, err := p.product()
+	if err != nil {
+		return "", err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.product()
+		if err != nil {
+			return "", err
+		}
+		if left, err = exprArithmetic(op, left, right); err != nil {
+			return "", err
+		}
+	}
+}
+
+// product 计算 *、/ 和 %
+func (p *exprParser) product() (string, error) {
+	left, err := p.match()
+	if err != nil {
+		return "", err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.match()
+		if err != nil {
+			return "", err
+		}
+		if left, err = exprArithmetic(op, left, right); err != nil {
+			return "", err
+		}
+	}
+}
+
+// match 计算 字符串 : 正则表达式
+func (p *exprParser) match() (string, error) {
+	left, err := p.primary()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, ok := p.accept(":"); !ok {
+			return left, nil
+		}
+		pattern, err := p.primary()
+		if err != nil {
+			return "", err
+		}
+		if left, err = exprMatch(left, pattern); err != nil {
+			return "", err
+		}
+	}
+}
+
+// primary 计算单个参数、括号中的表达式和 match、substr、index、length、+ 等关键字
+func (p *exprParser) primary() (string, error) {
+	token, ok := p.peek()
+	if !ok {
+		if p.pos > 0 {
+			return "", exprInvalid("语法错误: `%s' 之后缺少参数", p.args[p.pos-1])
+		}
+		return "", exprInvalid("语法错误: 缺少参数")
+	}
+	p.pos++
+	if len(p.args) == 1 {
+		// 只有一个参数时它就是结果，即使是运算符或关键字
+		return token, nil
+	}
+	switch token {
+	case "(":
+		result, err := p.or()
+		if err != nil {
+			return "", err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return "", exprInvalid("语法错误: 缺少 `)'")
+		}
+		return result, nil
+	case "+":
+		// + 之后的单词总是作为字符串
+		return p.operand(token)
+	case "length":
+		s, err := p.operand(token)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(utf8.RuneCountInString(s)), nil
+	case "match":
+		s, err := p.operand(token)
+		if err != nil {
+			return "", err
+		}
+		pattern, err := p.operand(token)
+		if err != nil {
+			return "", err
+		}
+		return exprMatch(s, pattern)
+	case "index":
+		s, err := p.operand(token)
+		if err != nil {
+			return "", err
+		}
+		chars, err := p.operand(token)
+		if err != nil {
+			return "", err
+		}
+		for i, r := range []rune(s) {
+			if strings.ContainsRune(chars, r) {
+				return strconv.Itoa(i + 1), nil
+			}
+		}
+		return "0", nil
+	case "substr":
+		s, err := p.operand(token)
+		if err != nil {
+			return "", err
+		}
+		var bounds [2]int64
+		for i := range bounds {
+			arg, err := p.operand(token)
+			if err != nil {
+				return "", err
+			}
+			if bounds[i], err = exprInteger(arg); err != nil {
+				return "", err
+			}
+		}
+		runes := []rune(s)
+		start, length := bounds[0], bounds[1]
+		if start < 1 || length < 1 || start > int64(len(runes)) {
+			return "", nil
+		}
+		end := int64(len(runes))
+		if length < end-start+1 {
+			end = start - 1 + length
+		}
+		return string(runes[start-1 : end]), nil
+	case ")":
+		return "", exprInvalid("语法错误: 意外的参数 `)'")
+	}
+	return token, nil
+}
+
+// operand 读取关键字 keyword 的一个参数（参数总是作为字符串，即使它是运算符）
+func (p *exprParser) operand(keyword string) (string, error) {
+	token, ok := p.peek()
+	if !ok {
+		return "", exprInvalid("语法错误: `%s' 之后缺少参数", keyword)
+	}
+	p.pos++
+	return token, nil
+}
+
+// exprNull 判断结果是否为空字符串或 0
+func exprNull(s string) bool {
+	if s == "" {
+		return true
+	}
+	n, err := exprInteger(s)
+	return err == nil && n == 0
+}
+
+// exprInteger 把参数解析为整数（可以有前导的 -）
+func exprInteger(s string) (int64, error) {
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return 0, exprInvalid("非整数参数")
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, exprInvalid("数值超出范围")
+	}
+	return n, nil
+}
+
+// exprArithmetic 计算整数运算 a op b
+func exprArithmetic(op, left, right string) (string, error) {
+	a, err := exprInteger(left)
+	if err != nil {
+		return "", err
+	}
+	b, err := exprInteger(right)
+	if err != nil {
+		return "", err
+	}
+	var n int64
+	switch op {
+	case "+":
+		n = a + b
+	case "-":
+		n = a - b
+	case "*":
+		n = a * b
+	case "/", "%":
+		if b == 0 {
+			return "", exprInvalid("除以零")
+		}
+		if op == "/" {
+			n = a / b
+		} else {
+			n = a % b
+		}
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+// exprMatch 从开头匹配基本正则表达式，有分组时返回第一个分组的内容，否则返回匹配的字符数
+func exprMatch(s, pattern string) (string, error) {
+	re, err := regexp.Compile("^(?:" + convertBRE(pattern) + ")")
+	if err != nil {
+		return "", &exprError{code: 3, message: i18n.Sprintf("无效的正则表达式 `%s'", pattern)}
+	}
+	match := re.FindStringSubmatch(s)
+	if re.NumSubexp() > 0 {
+		if match == nil {
+			return "", nil
+		}
+		return match[1], nil
+	}
+	if match == nil {
+		return "0", nil
+	}
+	return strconv.Itoa(utf8.RuneCountInString(match[0])), nil
+}
@@ -0,0 +1,372 @@
+package builtin
+
+import (
+	"bufio"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// grepOptions grep 的选项
+type grepOptions struct {
+	ignoreCase   bool // -i 忽略大小写
+	lineNumbers  bool // -n 显示行号
+	onlyMatching bool // -o 只输出匹配的部分
+	invert       bool // -v 输出不匹配的行
+	count        bool // -c 只输出匹配的行数
+	quiet        bool // -q 不输出，只通过退出状态表示是否匹配
+	listFiles    bool // -l 只输出有匹配的文件名
+	recursive    bool // -r 递归搜索目录
+	extended     bool // -E 扩展正则表达式
+	fixed        bool // -F 模式是普通字符串
+	wordRegexp   bool // -w 匹配整个单词
+	lineRegexp   bool // -x 匹配整行
+	noFilename   bool // -h 不输出文件名
+	withFilename bool // -H 总是输出文件名
+	noMessages   bool // -s 不输出文件不存在等错误消息
+}
+
+// grep 文本搜索
+// 用法：grep [-cEFhHilnoqrsvwx] [-e 模式]... 模式 [文件...]
+// 默认使用基本正则表达式（BRE），-E 使用扩展正则表达式，-F 按普通字符串匹配
+// 退出状态：有匹配的行时为 0，没有时为 1，出错时为 2（-q 时只要有匹配就是 0）
+func grep(args []string, env map[string]string, stdio *IO) error {
+	var opts grepOptions
+	var patterns []string
+	var files []string
+	hasPattern := false
+
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			if !hasPattern {
+				patterns = append(patterns, arg)
+				hasPattern = true
+			} else {
+				files = append(files, arg)
+			}
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch arg[j] {
+			case 'i':
+				opts.ignoreCase = true
+			case 'n':
+				opts.lineNumbers = true
+			case 'o':
+				opts.onlyMatching = true
+			case 'v':
+				opts.invert = true
+			case 'c':
+				opts.count = true
+			case 'q':
+				opts.quiet = true
+			case 'l':
+				opts.listFiles = true
+			case 'r', 'R':
+				opts.recursive = true
+			case 'E':
+				opts.extended = true
+			case 'F':
+				opts.fixed = true
+			case 'w':
+				opts.wordRegexp = true
+			case 'x':
+				opts.lineRegexp = true
+			case 'h':
+				opts.noFilename = true
+			case 'H':
+				opts.withFilename = true
+			case 's':
+				opts.noMessages = true
+			case 'e':
+				// -e 模式 或 -e模式
+				pattern := arg[j+1:]
+				if pattern == "" {
+					if i+1 >= len(args) {
+						return &StatusError{Code: 2, Message: i18n.T("grep: -e 缺少参数")}
+					}
+					i++
+					pattern = args[i]
+				}
+				patterns = append(patterns, pattern)
+				hasPattern = true
+				j = len(arg)
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("grep: -%c: 无效选项\n用法: grep [-cEFhHilnoqrsvwx] [-e 模式]... 模式 [文件...]", arg[j])}
+			}
+		}
+	}
+
+	if !hasPattern {
+		return &StatusError{Code: 2, Message: i18n.T("grep: 缺少模式\n用法: grep [-cEFhHilnoqrsvwx] [-e 模式]... 模式 [文件...]")}
+	}
+	re, err := compileGrepPattern(patterns, opts)
+	if err != nil {
+		return &StatusError{Code: 2, Message: i18n.Sprintf("grep: 无效的正则表达式: %v", err)}
+	}
+
+	if len(files) == 0 {
+		if opts.recursive {
+			files = []string{"."}
+		} else {
+			files = []string{"-"}
+		}
+	}
+
+	g := &grepper{opts: opts, re: re, env: env, stdio: stdio}
+	g.showNames = (len(files) > 1 || opts.recursive) && !opts.noFilename || opts.withFilename
+	for _, file := range files {
+		if g.quit {
+			break
+		}
+		g.searchPath(file)
+	}
+
+	switch {
+	case g.matched && (opts.quiet || !g.failed):
+		return nil
+	case g.failed:
+		return &StatusError{Code: 2}
+	}
+	return &StatusError{Code: 1}
+}
+
+// grepper 保存一次 grep 调用的状态
+type grepper struct {
+	opts      grepOptions
+	re        *regexp.Regexp
+	env       map[string]string
+	stdio     *IO
+	showNames bool     // 输出行前是否加上文件名
+	matched   bool     // 是否有匹配的行
+	quit      bool     // -q 时找到匹配后不再继续搜索
+	failed    bool     // 是否有文件不存在等错误
+}
+
+// compileGrepPattern 把模式编译为正则表达式，多个模式之间是“或”的关系
+func compileGrepPattern(patterns []string, opts grepOptions) (*regexp.Regexp, error) {
+	parts := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		switch {
+		case opts.fixed:
+			pattern = regexp.QuoteMeta(pattern)
+		case !opts.extended:
+			pattern = convertBRE(pattern)
+		}
+		if opts.wordRegexp {
+			pattern = `\b(?:` + pattern + `)\b`
+		}
+		if opts.lineRegexp {
+			pattern = `^(?:` + pattern + `)$`
+		}
+		parts[i] = "(?:" + pattern + ")"
+	}
+	expr := strings.Join(parts, "|")
+	if opts.ignoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// convertBRE 把基本正则表达式转换为 Go 的正则表达式语法
+// BRE 中 \( \) \{ \} \| \+ \? 是特殊字符，不加反斜杠的 ( ) { } | + ? 是普通字符
+func convertBRE(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			next := pattern[i]
+			switch {
+			case strings.IndexByte("(){}|+?", next) >= 0:
+				sb.WriteByte(next)
+			case next == '<' || next == '>':
+				// 单词的开头和结尾
+				sb.WriteString(`\b`)
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(next)
+			}
+		case strings.IndexByte("(){}|+?", c) >= 0:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '*' && (i == 0 || pattern[i-1] == '^' && i == 1):
+			// 开头的 * 是普通字符
+			sb.WriteString(`\*`)
+		case c == '[':
+			// 方括号表达式原样复制，其中的字符没有特殊含义
+			end := bracketEnd(pattern, i)
+			sb.WriteString(pattern[i:end])
+			i = end - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// bracketEnd 返回从 start 开始的方括号表达式结束后的位置，没有结束的 ] 时返回字符串长度
+func bracketEnd(pattern string, start int) int {
+	i := start + 1
+	if i < len(pattern) && pattern[i] == '^' {
+		i++
+	}
+	// 紧跟在 [ 或 [^ 之后的 ] 是普通字符
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+	for ; i < len(pattern); i++ {
+		if pattern[i] == '[' && i+1 < len(pattern) && (pattern[i+1] == ':' || pattern[i+1] == '.' || pattern[i+1] == '=') {
+			// 字符类 [:alpha:] 等
+			if end := strings.Index(pattern[i+2:], string(pattern[i+1])+"]"); end >= 0 {
+				i += end + 3
+				continue
+			}
+		}
+		if pattern[i] == ']' {
+			return i + 1
+		}
+	}
+	return len(pattern)
+}
+
+// fail 记录错误，没有 -s 时把错误消息输出到标准错误
+func (g *grepper) fail(msg string) {
+	g.failed = true
+	if !g.opts.noMessages {
+		fmt.Fprintf(g.stdio.Stderr, "grep: %s\n", msg)
+	}
+}
+
+// searchPath 搜索文件，- 表示标准输入，-r 时递归搜索目录
+func (g *grepper) searchPath(path string) {
+	if path == "-" {
+		g.search(g.stdio.Stdin, "(standard input)")
+		return
+	}
+
+	fullPath := resolvePath(g.env, path)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		g.fail(fmt.Sprintf("%s: %v", path, pathErrorReason(err)))
+		return
+	}
+	if !info.IsDir() {
+		g.searchFile(path, fullPath)
+		return
+	}
+	if !g.opts.recursive {
+		g.fail(i18n.Sprintf("%s: 是一个目录", path))
+		return
+	}
+	if err := g.stdio.checkPath("open", AccessRead, fullPath); err != nil {
+		g.fail(fmt.Sprintf("%s: %v", path, pathErrorReason(err)))
+		return
+	}
+
+	filepath.WalkDir(fullPath, func(p string, d os.DirEntry, err error) error {
+		if g.quit {
+			return filepath.SkipAll
+		}
+		// 输出中显示的路径以参数中的路径开头
+		display := path
+		if rel := strings.TrimPrefix(p, fullPath); rel != "" {
+			display = strings.TrimSuffix(path, "/") + rel
+		}
+		if err != nil {
+			g.fail(fmt.Sprintf("%s: %v", display, pathErrorReason(err)))
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		g.searchFile(display, p)
+		return nil
+	})
+}
+
+// searchFile 打开并搜索一个文件
+func (g *grepper) searchFile(display, fullPath string) {
+	file, err := g.stdio.open(fullPath)
+	if err != nil {
+		g.fail(fmt.Sprintf("%s: %v", display, pathErrorReason(err)))
+		return
+	}
+	defer file.Close()
+	g.search(file, display)
+}
+
+// search 逐行搜索 r，name 是输出中显示的文件名
+func (g *grepper) search(r io.Reader, name string) {
+	out := g.stdio.Stdout
+	prefix := ""
+	if g.showNames {
+		prefix = name + ":"
+	}
+
+	reader := bufio.NewReader(r)
+	count := 0
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			if err != io.EOF {
+				g.fail(fmt.Sprintf("%s: %v", name, err))
+			}
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if g.re.MatchString(line) == g.opts.invert {
+			continue
+		}
+		count++
+		g.matched = true
+		if g.opts.quiet {
+			g.quit = true
+			return
+		}
+		if g.opts.listFiles {
+			break
+		}
+		if g.opts.count {
+			continue
+		}
+
+		linePrefix := prefix
+		if g.opts.lineNumbers {
+			linePrefix += fmt.Sprintf("%d:", lineNum)
+		}
+		if g.opts.onlyMatching {
+			// -v 时没有匹配的部分可以输出
+			if !g.opts.invert {
+				for _, match := range g.re.FindAllString(line, -1) {
+					if match != "" {
+						fmt.Fprintf(out, "%s%s\n", linePrefix, match)
+					}
+				}
+			}
+			continue
+		}
+		fmt.Fprintf(out, "%s%s\n", linePrefix, line)
+	}
+
+	switch {
+	case g.opts.listFiles:
+		if count > 0 {
+			fmt.Fprintln(out, name)
+		}
+	case g.opts.count:
+		fmt.Fprintf(out, "%s%d\n", prefix, count)
+	}
+}
@@ -0,0 +1,439 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpUsage http 命令的用法说明
+const httpUsage = "用法: http [-fiILOsS] [-X 方法] [-H 头部]... [-d 数据]... [-o 文件] [-m 秒数] [--retry 次数] [方法] URL"
+
+// httpOptions http 命令的选项
+type httpOptions struct {
+	method     string        // -X 或位置参数指定的请求方法
+	headers    []string      // -H "名称: 值"
+	data       []string      // -d 请求体，多个时用 & 连接，@文件 读取文件内容，@- 读取标准输入
+	output     string        // -o 输出文件，- 表示标准输出
+	remoteName bool          // -O 使用 URL 的最后一部分作为输出文件名
+	silent     bool          // -s 不输出错误消息
+	showError  bool          // -S 与 -s 一起使用时仍然输出错误消息
+	fail       bool          // -f HTTP 状态码不小于 400 时失败（退出状态 22），不输出响应内容
+	include    bool          // -i 输出响应头
+	head       bool          // -I 只请求并输出响应头
+	location   bool          // -L 跟随重定向
+	maxTime    time.Duration // -m 整个请求的超时时间
+	retry      int           // --retry 暂时性错误时的重试次数
+	retryDelay time.Duration // --retry-delay 重试间隔，默认从 1 秒开始每次加倍
+	user       string        // -u 用户名:密码（Basic 认证）
+	userAgent  string        // -A User-Agent
+	url        string
+}
+
+// httpShortOptions 短选项对应的长选项
+var httpShortOptions = map[byte]string{
+	'X': "--request", 'H': "--header", 'd': "--data", 'o': "--output", 'm': "--max-time",
+	'u': "--user", 'A': "--user-agent", 's': "--silent", 'S': "--show-error", 'f': "--fail",
+	'i': "--include", 'I': "--head", 'L': "--location", 'O': "--remote-name",
+}
+
+// httpValueOptions 需要参数的长选项
+var httpValueOptions = map[string]bool{
+	"--request": true, "--header": true, "--data": true, "--output": true, "--max-time": true,
+	"--user": true, "--user-agent": true, "--retry": true, "--retry-delay": true,
+}
+
+// httpCmd 发送 HTTP 请求并输出响应内容，选项与 curl 兼容
+// 用法：http [-fiILOsS] [-X 方法] [-H 头部]... [-d 数据]... [-o 文件] [-m 秒数] [--retry 次数] [方法] URL
+// 方法也可以写在 URL 之前（如 http POST https://...），有 -d 时默认为 POST；URL 没有协议时使用 http://；
+// 退出状态与 curl 相同：3 URL 错误，6 无法解析主机，7 无法连接，22 HTTP 错误（-f），23 写入失败，28 超时
+func httpCmd(args []string, env map[string]string, stdio *IO) error {
+	opts, err := parseHTTPArgs(args)
+	if err != nil {
+		return &StatusError{Code: 2, Message: "http: " + err.Error() + "\n" + i18n.T(httpUsage)}
+	}
+	fail := func(code int, format string, a ...any) error {
+		if opts.silent && !opts.showError {
+			return &StatusError{Code: code}
+		}
+		return &StatusError{Code: code, Message: "http: " + i18n.Sprintf(format, a...)}
+	}
+
+	// 请求体
+	var body []byte
+	for i, data := range opts.data {
+		if i > 0 {
+			body = append(body, '&')
+		}
+		if name, ok := strings.CutPrefix(data, "@"); ok {
+			var content []byte
+			if name == "-" {
+				content, err = io.ReadAll(stdio.Stdin)
+			} else {
+				if err = stdio.checkPath("open", AccessRead, resolvePath(env, name)); err == nil {
+					content, err = os.ReadFile(resolvePath(env, name))
+				}
+			}
+			if err != nil {
+				return fail(26, "无法读取数据 %s: %v", name, pathErrorReason(err))
+			}
+			// 与 curl -d 一样去掉换行
+			content = bytes.ReplaceAll(bytes.ReplaceAll(content, []byte("\r"), nil), []byte("\n"), nil)
+			body = append(body, content...)
+			continue
+		}
+		body = append(body, data...)
+	}
+
+	method := opts.method
+	switch {
+	case method != "":
+	case opts.head:
+		method = http.MethodHead
+	case opts.data != nil:
+		method = http.MethodPost
+	default:
+		method = http.MethodGet
+	}
+	rawURL := opts.url
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" {
+		return fail(3, "URL 格式错误: %s", opts.url)
+	}
+
+	// 输出位置
+	output := opts.output
+	if opts.remoteName && output == "" {
+		output = path.Base(target.Path)
+		if output == "" || output == "/" || output == "." {
+			return fail(23, "无法从 URL 获取文件名: %s", opts.url)
+		}
+	}
+
+	ctx := stdio.ctx()
+	if opts.maxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.maxTime)
+		defer cancel()
+	}
+	if err := stdio.Check(AccessNetwork, target.String()); err != nil {
+		return fail(7, "%s: %v", opts.url, err)
+	}
+	client := &http.Client{}
+	if !opts.location {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	} else {
+		// 跟随的重定向同样按策略检查
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return i18n.Errorf("重定向次数过多")
+			}
+			return stdio.Check(AccessNetwork, req.URL.String())
+		}
+	}
+
+	var resp *http.Response
+	delay := time.Second
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+		if err != nil {
+			return fail(3, "%v", err)
+		}
+		if body == nil {
+			req.Body, req.ContentLength = http.NoBody, 0
+		}
+		if opts.data != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("User-Agent", "gobash-http")
+		if opts.userAgent != "" {
+			req.Header.Set("User-Agent", opts.userAgent)
+		}
+		if opts.user != "" {
+			name, password, _ := strings.Cut(opts.user, ":")
+			req.SetBasicAuth(name, password)
+		}
+		for _, header := range opts.headers {
+			name, value, _ := strings.Cut(header, ":")
+			req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+
+		resp, err = client.Do(req)
+		var denied *DeniedError
+		transient := err != nil && ctx.Err() == nil && !errors.As(err, &denied)
+		if err == nil {
+			switch resp.StatusCode {
+			case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+				http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				transient = true
+			}
+		}
+		if !transient || attempt >= opts.retry {
+			if err != nil {
+				return fail(httpErrorCode(err), "%s: %v", opts.url, httpErrorReason(err))
+			}
+			break
+		}
+
+		// 暂时性错误，等待后重试
+		if resp != nil {
+			resp.Body.Close()
+		}
+		wait := delay
+		if opts.retryDelay > 0 {
+			wait = opts.retryDelay
+		}
+		if !opts.silent {
+			i18n.Fprintf(stdio.Stderr, "http: 请求失败，%s 后重试（剩余 %d 次）\n", wait, opts.retry-attempt)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fail(28, "%s: %v", opts.url, httpErrorReason(ctx.Err()))
+		}
+		delay *= 2
+	}
+	defer resp.Body.Close()
+
+	if opts.fail && resp.StatusCode >= 400 {
+		return fail(22, "%s: 服务器返回错误: %s", opts.url, resp.Status)
+	}
+
+	var w io.Writer = stdio.Stdout
+	if output != "" && output != "-" {
+		f, err := stdio.create(resolvePath(env, output), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+		if err != nil {
+			return fail(23, "%s: %v", output, pathErrorReason(err))
+		}
+		defer f.Close()
+		w = f
+	}
+	if opts.include || opts.head {
+		if err := writeHTTPHead(w, resp); err != nil {
+			return fail(23, "写入失败: %v", err)
+		}
+	}
+	if method == http.MethodHead {
+		return nil
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			return fail(23, "写入失败: %v", err)
+		}
+		return fail(httpErrorCode(err), "%s: %v", opts.url, httpErrorReason(err))
+	}
+	return nil
+}
+
+// parseHTTPArgs 解析 http 命令的参数
+func parseHTTPArgs(args []string) (httpOptions, error) {
+	var opts httpOptions
+	var operands []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			operands = append(operands, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+
+		// 长选项：--name=value 或 --name value
+		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue := strings.Cut(arg, "=")
+			switch {
+			case httpValueOptions[name] && !hasValue:
+				if i+1 >= len(args) {
+					return opts, i18n.Errorf("%s 缺少参数", name)
+				}
+				i++
+				value = args[i]
+			case !httpValueOptions[name] && hasValue:
+				return opts, i18n.Errorf("%s 不需要参数", name)
+			}
+			if err := opts.set(name, value); err != nil {
+				return opts, err
+			}
+			continue
+		}
+
+		// 短选项可以组合（如 -sSfL），需要参数的选项使用剩余部分或下一个参数（如 -o文件、-so 文件）
+		for j := 1; j < len(arg); j++ {
+			name, ok := httpShortOptions[arg[j]]
+			if !ok {
+				return opts, i18n.Errorf("-%c: 无效选项", arg[j])
+			}
+			value := ""
+			if httpValueOptions[name] {
+				value = arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return opts, i18n.Errorf("-%c 缺少参数", arg[j])
+					}
+					i++
+					value = args[i]
+				}
+				j = len(arg)
+			}
+			if err := opts.set(name, value); err != nil {
+				return opts, err
+			}
+		}
+	}
+
+	// URL 之前可以有请求方法，如 http GET https://...
+	if len(operands) == 2 && opts.method == "" && isHTTPMethod(operands[0]) {
+		opts.method, operands = operands[0], operands[1:]
+	}
+	switch {
+	case len(operands) == 0:
+		return opts, i18n.Errorf("缺少 URL")
+	case len(operands) > 1:
+		return opts, i18n.Errorf("多余的参数: %s", operands[1])
+	}
+	opts.url = operands[0]
+	return opts, nil
+}
+
+// set 设置长选项 name，value 是选项的参数
+func (opts *httpOptions) set(name, value string) error {
+	var err error
+	switch name {
+	case "--request":
+		opts.method = strings.ToUpper(value)
+	case "--header":
+		if !strings.Contains(value, ":") {
+			return i18n.Errorf("无效的头部 %q，格式为 \"名称: 值\"", value)
+		}
+		opts.headers = append(opts.headers, value)
+	case "--data":
+		opts.data = append(opts.data, value)
+	case "--output":
+		opts.output = value
+	case "--user":
+		opts.user = value
+	case "--user-agent":
+		opts.userAgent = value
+	case "--max-time":
+		opts.maxTime, err = parseHTTPSeconds(value)
+	case "--retry-delay":
+		opts.retryDelay, err = parseHTTPSeconds(value)
+	case "--retry":
+		opts.retry, err = strconv.Atoi(value)
+		if err != nil || opts.retry < 0 {
+			err = i18n.Errorf("无效的次数: %q", value)
+		}
+	case "--silent":
+		opts.silent = true
+	case "--show-error":
+		opts.showError = true
+	case "--fail":
+		opts.fail = true
+	case "--include":
+		opts.include = true
+	case "--head":
+		opts.head = true
+	case "--location":
+		opts.location = true
+	case "--remote-name":
+		opts.remoteName = true
+	default:
+		return i18n.Errorf("%s: 无效选项", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// isHTTPMethod 检查参数是否是大写的 HTTP 方法
+func isHTTPMethod(s string) bool {
+	switch s {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// parseHTTPSeconds 解析秒数（可以是小数）
+func parseHTTPSeconds(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || seconds < 0 {
+		return 0, i18n.Errorf("无效的秒数: %q", s)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// writeHTTPHead 按 HTTP 格式输出状态行和响应头（头部按名称排序）
+func writeHTTPHead(w io.Writer, resp *http.Response) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\r\n", resp.Proto, resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&sb, "%s: %s\r\n", name, value)
+		}
+	}
+	sb.WriteString("\r\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// httpErrorCode 返回与 curl 相同的错误退出状态
+func httpErrorCode(err error) int {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout():
+		return 28
+	case errors.As(err, &dnsErr):
+		return 6
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return 7
+	case errors.Is(err, context.Canceled):
+		return 130
+	}
+	return 56 // 接收数据失败
+}
+
+// httpErrorReason 返回去掉请求方法和 URL 之后的错误原因
+func httpErrorReason(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return i18n.NewError("超时")
+	case errors.Is(err, context.Canceled):
+		return i18n.NewError("已取消")
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package builtin
 
 import (
 	"fmt"
+	"gobash/internal/i18n"
 	"os"
 	"strconv"
 	"strings"
@@ -11,7 +12,9 @@ import (
 // 定义作业管理器的接口，用于builtin包与executor包之间的通信
 type JobManager interface {
 	GetJob(jobID int) (Job, bool)
+	GetJobByPID(pid int) (Job, bool)
 	GetAllJobs() []Job
+	RemoveJob(jobID int)
 	SetCurrentJob(jobID int)
 	GetCurrentJob() Job
 }
@@ -25,7 +28,11 @@ type Job interface {
 	GetStatus() JobStatus
 	GetProcess() *os.Process
 	SetStatus(status JobStatus)
-	Wait() error // 等待作业完成
+	Wait() error                   // 等待作业完成或被停止
+	Continue(foreground bool) error // 让停止的作业继续运行，foreground 为 true 时把终端交给作业
+	ExitCode() int                  // 作业结束后的退出状态
+	Signal(sig int) (bool, error)   // 向在 shell 内部运行的作业发送信号，作业是外部命令时返回 false
+	NoHangup()                      // shell 退出时不向作业发送 SIGHUP（disown -h）
 }
 
 // JobStatus 作业状态
@@ -51,23 +58,40 @@ func (js JobStatus) String() string {
 	}
 }
 
-// 全局变量存储JobManager引用（用于jobs/fg/bg命令）
-var globalJobManager JobManager
-
-// SetJobManager 设置JobManager引用
-// 由executor包调用，设置全局的作业管理器引用
-func SetJobManager(jm JobManager) {
-	globalJobManager = jm
+// JobBuiltins 返回使用作业管理器 jm 的 jobs、fg、bg、kill、wait、disown 命令
+// 每个执行器有自己的作业管理器，执行器创建时用这里返回的命令覆盖默认的同名命令；
+// jm 为 nil 时命令返回未初始化错误
+func JobBuiltins(jm JobManager) map[string]BuiltinFunc {
+	return map[string]BuiltinFunc{
+		"jobs": func(args []string, env map[string]string, stdio *IO) error {
+			return jobs(jm, args, stdio)
+		},
+		"fg": func(args []string, env map[string]string, stdio *IO) error {
+			return fg(jm, args, stdio)
+		},
+		"bg": func(args []string, env map[string]string, stdio *IO) error {
+			return bg(jm, args, stdio)
+		},
+		"kill": func(args []string, env map[string]string, stdio *IO) error {
+			return kill(jm, args, stdio)
+		},
+		"wait": func(args []string, env map[string]string, stdio *IO) error {
+			return waitJobs(jm, args, stdio)
+		},
+		"disown": func(args []string, env map[string]string, stdio *IO) error {
+			return disown(jm, args)
+		},
+	}
 }
 
 // jobs 显示作业列表
 // 显示所有后台作业的列表，包括作业ID、状态和命令
-func jobs(args []string, env map[string]string) error {
-	if globalJobManager == nil {
-		return fmt.Errorf("jobs: job manager未初始化")
+func jobs(jm JobManager, args []string, stdio *IO) error {
+	if jm == nil {
+		return i18n.Errorf("jobs: job manager未初始化")
 	}
 
-	allJobs := globalJobManager.GetAllJobs()
+	allJobs := jm.GetAllJobs()
 
 	if len(allJobs) == 0 {
 		return nil // 没有作业，不输出任何内容
@@ -75,7 +99,7 @@ func jobs(args []string, env map[string]string) error {
 
 	for _, job := range allJobs {
 		status := job.GetStatus().String()
-		fmt.Printf("[%d] %s %s\n", job.GetID(), status, job.GetCmd())
+		fmt.Fprintf(stdio.Stdout, "[%d] %s %s\n", job.GetID(), status, job.GetCmd())
 	}
 
 	return nil
@@ -84,9 +108,9 @@ func jobs(args []string, env map[string]string) error {
 // fg 将后台任务转到前台
 // 将指定的后台作业转到前台执行，并等待其完成
 // 支持 %1 或 1 格式的作业ID，如果不指定则使用当前作业或最后一个作业
-func fg(args []string, env map[string]string) error {
-	if globalJobManager == nil {
-		return fmt.Errorf("fg: job manager未初始化")
+func fg(jm JobManager, args []string, stdio *IO) error {
+	if jm == nil {
+		return i18n.Errorf("fg: job manager未初始化")
 	}
 
 	var job Job
@@ -94,11 +118,11 @@ func fg(args []string, env map[string]string) error {
 
 	if len(args) == 0 {
 		// 没有参数，使用当前作业或最后一个作业
-		job = globalJobManager.GetCurrentJob()
+		job = jm.GetCurrentJob()
 		if job == nil {
-			allJobs := globalJobManager.GetAllJobs()
+			allJobs := jm.GetAllJobs()
 			if len(allJobs) == 0 {
-				return fmt.Errorf("fg: 当前没有作业")
+				return i18n.Errorf("fg: 当前没有作业")
 			}
 			job = allJobs[len(allJobs)-1]
 		}
@@ -110,26 +134,38 @@ func fg(args []string, env map[string]string) error {
 		}
 		jobID, err := strconv.Atoi(jobIDStr)
 		if err != nil {
-			return fmt.Errorf("fg: 无效的作业ID: %s", args[0])
+			return i18n.Errorf("fg: 无效的作业ID: %s", args[0])
 		}
-		job, ok = globalJobManager.GetJob(jobID)
+		job, ok = jm.GetJob(jobID)
 		if !ok {
-			return fmt.Errorf("fg: 作业 %d 不存在", jobID)
+			return i18n.Errorf("fg: 作业 %d 不存在", jobID)
 		}
 	}
 
 	if job.GetStatus() == JobDone {
-		return fmt.Errorf("fg: 作业 %d 已完成", job.GetID())
+		return i18n.Errorf("fg: 作业 %d 已完成", job.GetID())
 	}
 
 	// 设置当前作业
-	globalJobManager.SetCurrentJob(job.GetID())
+	jm.SetCurrentJob(job.GetID())
+
+	// 启用作业控制时作业的进程组获得终端，停止的作业继续运行
+	if err := job.Continue(true); err != nil {
+		return fmt.Errorf("fg: %v", err)
+	}
 
 	// 等待作业完成（使用Job的Wait方法，避免重复Wait进程）
 	if err := job.Wait(); err != nil {
 		return err
 	}
+	if job.GetStatus() == JobStopped {
+		// 作业再次被 Ctrl+Z 停止
+		fmt.Fprintf(stdio.Stderr, "\n[%d]+  Stopped                 %s\n", job.GetID(), job.GetCmd())
+		return &StatusError{Code: 148} // 128 + SIGTSTP
+	}
 	job.SetStatus(JobDone)
+	// 在前台结束的作业不再通知，从作业表中移除
+	jm.RemoveJob(job.GetID())
 
 	return nil
 }
@@ -137,10 +173,10 @@ func fg(args []string, env map[string]string) error {
 // bg 继续后台任务
 // 继续执行被停止的后台作业
 // 支持 %1 或 1 格式的作业ID，如果不指定则使用当前作业或最后一个作业
-// 注意：Windows平台不支持SIGCONT信号，此功能在Windows上有限制
-func bg(args []string, env map[string]string) error {
-	if globalJobManager == nil {
-		return fmt.Errorf("bg: job manager未初始化")
+// 注意：只有 Linux 上启用作业控制时作业才会真正停止和继续，其他平台上只修改作业状态
+func bg(jm JobManager, args []string, stdio *IO) error {
+	if jm == nil {
+		return i18n.Errorf("bg: job manager未初始化")
 	}
 
 	var job Job
@@ -148,11 +184,11 @@ func bg(args []string, env map[string]string) error {
 
 	if len(args) == 0 {
 		// 没有参数，使用当前作业或最后一个作业
-		job = globalJobManager.GetCurrentJob()
+		job = jm.GetCurrentJob()
 		if job == nil {
-			allJobs := globalJobManager.GetAllJobs()
+			allJobs := jm.GetAllJobs()
 			if len(allJobs) == 0 {
-				return fmt.Errorf("bg: 当前没有作业")
+				return i18n.Errorf("bg: 当前没有作业")
 			}
 			job = allJobs[len(allJobs)-1]
 		}
@@ -164,30 +200,167 @@ func bg(args []string, env map[string]string) error {
 		}
 		jobID, err := strconv.Atoi(jobIDStr)
 		if err != nil {
-			return fmt.Errorf("bg: 无效的作业ID: %s", args[0])
+			return i18n.Errorf("bg: 无效的作业ID: %s", args[0])
 		}
-		job, ok = globalJobManager.GetJob(jobID)
+		job, ok = jm.GetJob(jobID)
 		if !ok {
-			return fmt.Errorf("bg: 作业 %d 不存在", jobID)
+			return i18n.Errorf("bg: 作业 %d 不存在", jobID)
 		}
 	}
 
 	if job.GetStatus() == JobDone {
-		return fmt.Errorf("bg: 作业 %d 已完成", job.GetID())
+		return i18n.Errorf("bg: 作业 %d 已完成", job.GetID())
 	}
 
 	if job.GetStatus() == JobRunning {
-		return fmt.Errorf("bg: 作业 %d 已在运行", job.GetID())
+		return i18n.Errorf("bg: 作业 %d 已在运行", job.GetID())
+	}
+
+	// 如果作业被停止，向作业的进程发送SIGCONT信号继续执行
+	// Windows上无法真正恢复进程，这里只是标记为运行
+	if err := job.Continue(false); err != nil {
+		return fmt.Errorf("bg: %v", err)
+	}
+	fmt.Fprintf(stdio.Stdout, "[%d] %d\n", job.GetID(), job.GetPID())
+
+	return nil
+}
+
+// waitJobs 等待后台作业结束
+// 用法：wait [pid|%作业 ...]，pid 是作业的进程号（如 $!）
+// 没有参数时等待所有作业，退出状态为 0；否则依次等待每个作业，退出状态是最后一个作业的退出状态，
+// 参数不是当前shell的作业时为 127。结束的作业从作业表中移除
+func waitJobs(jm JobManager, args []string, stdio *IO) error {
+	if jm == nil {
+		return i18n.Errorf("wait: job manager未初始化")
+	}
+	if len(args) == 0 {
+		for _, job := range jm.GetAllJobs() {
+			if err := job.Wait(); err != nil {
+				return err
+			}
+			if job.GetStatus() != JobStopped {
+				jm.RemoveJob(job.GetID())
+			}
+		}
+		return nil
+	}
+
+	var status error
+	for _, arg := range args {
+		var job Job
+		var ok bool
+		if jobSpec, isJob := strings.CutPrefix(arg, "%"); isJob {
+			jobID, err := strconv.Atoi(jobSpec)
+			if err != nil {
+				return &StatusError{Code: 2, Message: i18n.Sprintf("wait: %s: 无效的作业ID", arg)}
+			}
+			if job, ok = jm.GetJob(jobID); !ok {
+				status = &StatusError{Code: 127, Message: i18n.Sprintf("wait: %s: 作业不存在", arg)}
+				continue
+			}
+		} else {
+			pid, err := strconv.Atoi(arg)
+			if err != nil {
+				return &StatusError{Code: 2, Message: i18n.Sprintf("wait: `%s': 不是进程号或有效的作业号", arg)}
+			}
+			if job, ok = jm.GetJobByPID(pid); !ok {
+				status = &StatusError{Code: 127, Message: i18n.Sprintf("wait: 进程 %d 不是当前shell的子进程", pid)}
+				continue
+			}
+		}
+		if err := job.Wait(); err != nil {
+			return err
+		}
+		status = nil
+		if job.GetStatus() == JobStopped {
+			status = &StatusError{Code: 148} // 128 + SIGTSTP
+			continue
+		}
+		jm.RemoveJob(job.GetID())
+		if code := job.ExitCode(); code != 0 {
+			status = &StatusError{Code: code}
+		}
 	}
+	return status
+}
 
-	// 如果作业被停止，发送SIGCONT信号继续执行
-	if job.GetProcess() != nil {
-		// Windows不支持SIGCONT，这里简化处理
-		// 在Unix系统上，可以使用 job.GetProcess().Signal(syscall.SIGCONT)
-		// Windows上无法真正恢复进程，这里只是标记为运行
-		job.SetStatus(JobRunning)
-		fmt.Printf("[%d] %d\n", job.GetID(), job.GetPID())
+// disown 从作业表中移除作业：jobs、fg、wait 不再能使用它，shell 退出时也不向它发送 SIGHUP（shopt -s huponexit）
+// 用法：disown [-ahr] [%作业|pid ...]
+// 没有参数时移除当前作业（没有当前作业时为最新的作业）；-a 移除所有作业，-r 只移除正在运行的作业，
+// -h 不移除作业，只是让它在 shell 退出时不收到 SIGHUP
+func disown(jm JobManager, args []string) error {
+	if jm == nil {
+		return i18n.Errorf("disown: job manager未初始化")
+	}
+	all, running, keep := false, false, false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'a':
+				all = true
+			case 'r':
+				running = true
+			case 'h':
+				keep = true
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("disown: -%c: 无效选项\n用法: disown [-h] [-ar] [作业 ...]", flag)}
+			}
+		}
+		args = args[1:]
 	}
 
+	var targets []Job
+	var errs []string
+	switch {
+	case len(args) > 0:
+		for _, arg := range args {
+			_, job, err := killTarget(jm, arg)
+			if err == nil && job == nil {
+				err = i18n.Errorf("%s: 作业不存在", arg)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("disown: %v", err))
+				continue
+			}
+			targets = append(targets, job)
+		}
+	case all || running:
+		targets = jm.GetAllJobs()
+	default:
+		job := jm.GetCurrentJob()
+		if job == nil {
+			for _, j := range jm.GetAllJobs() {
+				if job == nil || j.GetID() > job.GetID() {
+					job = j
+				}
+			}
+		}
+		if job == nil {
+			return i18n.Errorf("disown: 当前没有作业")
+		}
+		targets = []Job{job}
+	}
+	for _, job := range targets {
+		if running && job.GetStatus() != JobRunning {
+			continue
+		}
+		disownJob(jm, job, keep)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
 	return nil
 }
+
+// disownJob 从作业表中移除作业，keep 为 true 时（disown -h）保留作业，只是不再发送 SIGHUP
+func disownJob(jm JobManager, job Job, keep bool) {
+	job.NoHangup()
+	if !keep {
+		jm.RemoveJob(job.GetID())
+	}
+}
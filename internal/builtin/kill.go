@@ -0,0 +1,183 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"strconv"
+	"strings"
+)
+
+// signalInfo 信号名称（不含 SIG 前缀）和编号
+type signalInfo struct {
+	name string
+	num  int
+}
+
+// defaultSignal kill 和 pkill 默认发送的信号（TERM）
+const defaultSignal = 15
+
+// parseSignal 解析信号名称或编号，名称不区分大小写，可以带 SIG 前缀，0 表示只检查进程是否存在
+func parseSignal(s string) (int, error) {
+	if num, err := strconv.Atoi(s); err == nil {
+		if _, ok := signalName(num); ok || num == 0 {
+			return num, nil
+		}
+		return 0, i18n.Errorf("%s: 无效的信号", s)
+	}
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	for _, sig := range signals {
+		if sig.name == name {
+			return sig.num, nil
+		}
+	}
+	return 0, i18n.Errorf("%s: 无效的信号", s)
+}
+
+// signalName 返回信号编号对应的名称
+func signalName(num int) (string, bool) {
+	for _, sig := range signals {
+		if sig.num == num {
+			return sig.name, true
+		}
+	}
+	return "", false
+}
+
+// kill 向进程或作业发送信号
+// 用法：kill [-s 信号 | -n 编号 | -信号] pid|%作业... 或 kill -l [信号...]
+// 默认发送 TERM，信号 0 只检查进程是否存在；%N 表示作业 N 的进程（需要作业管理器）；
+// -l 列出所有信号，带参数时在信号名称和编号之间转换
+func kill(jm JobManager, args []string, stdio *IO) error {
+	sig := defaultSignal
+	list := false
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		switch arg {
+		case "-l", "-L":
+			list = true
+			continue
+		case "-s", "-n":
+			if i+1 >= len(args) {
+				return i18n.Errorf("kill: %s 缺少参数", arg)
+			}
+			i++
+			num, err := parseSignal(args[i])
+			if err != nil {
+				return fmt.Errorf("kill: %v", err)
+			}
+			sig = num
+			continue
+		}
+		num, err := parseSignal(arg[1:])
+		if err != nil {
+			return i18n.Errorf("kill: %v\n用法: kill [-s 信号 | -n 编号 | -信号] pid|%%作业... 或 kill -l [信号...]", err)
+		}
+		sig = num
+	}
+	targets := args[i:]
+
+	if list {
+		return listSignals(targets, stdio)
+	}
+	if len(targets) == 0 {
+		return i18n.Errorf("kill: 缺少操作数\n用法: kill [-s 信号 | -n 编号 | -信号] pid|%%作业... 或 kill -l [信号...]")
+	}
+
+	var errs []string
+	for _, target := range targets {
+		pid, job, err := killTarget(jm, target)
+		if err == nil {
+			// 在 shell 内部运行的后台作业（管道、循环等）由作业自己处理信号
+			handled := false
+			if job != nil {
+				handled, err = job.Signal(sig)
+			}
+			if !handled {
+				err = sendSignal(pid, sig)
+			}
+			if err != nil {
+				err = fmt.Errorf("(%d) - %v", pid, err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("kill: %v", err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// killTarget 把 kill 的参数解析为进程号和它所属的作业（不是作业时为 nil），%N 表示作业 N
+func killTarget(jm JobManager, target string) (int, Job, error) {
+	if jobSpec, ok := strings.CutPrefix(target, "%"); ok {
+		if jm == nil {
+			return 0, nil, i18n.Errorf("%s: job manager未初始化", target)
+		}
+		jobID, err := strconv.Atoi(jobSpec)
+		if err != nil {
+			return 0, nil, i18n.Errorf("%s: 无效的作业ID", target)
+		}
+		job, ok := jm.GetJob(jobID)
+		if !ok {
+			return 0, nil, i18n.Errorf("%s: 作业不存在", target)
+		}
+		return job.GetPID(), job, nil
+	}
+	pid, err := strconv.Atoi(target)
+	if err != nil {
+		return 0, nil, i18n.Errorf("%s: 参数必须是进程号或作业号", target)
+	}
+	if jm != nil {
+		if job, ok := jm.GetJobByPID(pid); ok {
+			return pid, job, nil
+		}
+	}
+	return pid, nil, nil
+}
+
+// listSignals 实现 kill -l：没有参数时列出所有信号名称，否则把编号转换为名称、名称转换为编号
+// 编号大于 128 时按“128+信号”的退出状态处理
+func listSignals(args []string, stdio *IO) error {
+	if len(args) == 0 {
+		names := make([]string, len(signals))
+		for i, sig := range signals {
+			names[i] = sig.name
+		}
+		fmt.Fprintln(stdio.Stdout, strings.Join(names, " "))
+		return nil
+	}
+	var errs []string
+	for _, arg := range args {
+		if num, err := strconv.Atoi(arg); err == nil {
+			if num > 128 {
+				num -= 128
+			}
+			if name, ok := signalName(num); ok {
+				fmt.Fprintln(stdio.Stdout, name)
+				continue
+			}
+			errs = append(errs, i18n.Sprintf("kill: %s: 无效的信号", arg))
+			continue
+		}
+		num, err := parseSignal(arg)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("kill: %v", err))
+			continue
+		}
+		fmt.Fprintln(stdio.Stdout, num)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"strconv"
+	"strings"
+)
+
+// LetBuiltin 返回使用 eval 计算算术表达式的 let 命令
+// 执行器传入与 $((...)) 相同的求值函数，表达式中的赋值（i=1、j+=2）和自增自减（i++）修改 shell 变量；
+// eval 为 nil 时只能计算整数和变量的值
+func LetBuiltin(eval func(expr string) (int64, error)) BuiltinFunc {
+	return func(args []string, env map[string]string, stdio *IO) error {
+		evaluate := eval
+		if evaluate == nil {
+			evaluate = func(expr string) (int64, error) {
+				expr = strings.TrimSpace(expr)
+				if value, ok := env[expr]; ok && isName(expr) {
+					expr = value
+				}
+				return strconv.ParseInt(expr, 10, 64)
+			}
+		}
+		return letCmd(evaluate, args)
+	}
+}
+
+// let 只能计算整数和变量的 let 命令（执行器会用 LetBuiltin 替换它）
+var let = LetBuiltin(nil)
+
+// letCmd 依次计算每个参数中的算术表达式
+// 用法：let 表达式 [表达式 ...]
+// 最后一个表达式的值为 0 时退出状态为 1，否则为 0
+func letCmd(eval func(expr string) (int64, error), args []string) error {
+	if len(args) == 0 {
+		return &StatusError{Code: 2, Message: i18n.T("let: 需要表达式\n用法: let 表达式 [表达式 ...]")}
+	}
+	var result int64
+	for _, arg := range args {
+		value, err := eval(arg)
+		if err != nil {
+			return &StatusError{Code: 1, Message: fmt.Sprintf("let: %v", err)}
+		}
+		result = value
+	}
+	if result == 0 {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
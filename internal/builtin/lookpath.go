@@ -0,0 +1,183 @@
+package builtin
+
+import (
+	"bufio"
+	"context"
+	"gobash/internal/i18n"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// LookPath 按 shell 的 PATH 查找外部命令 name，返回可执行文件的路径
+// Windows 上按 PATHEXT 补全扩展名（mytool 找到 mytool.exe、mytool.bat 等），没有这些扩展名但以 #! 开头的脚本也能找到；
+// 名称中包含路径分隔符时不搜索 PATH（相对路径相对于 shell 的工作目录）。找不到时返回的错误包装 exec.ErrNotFound
+func LookPath(name string, env map[string]string) (string, error) {
+	if _, ok := env["PATH"]; !ok {
+		// 没有 PATH 变量时（如嵌入方传入的空环境）使用进程的 PATH
+		env = map[string]string{"PATH": os.Getenv("PATH"), "PATHEXT": os.Getenv("PATHEXT"), "PWD": workDir(env)}
+	}
+	if matches := searchPath(name, env, false); len(matches) > 0 {
+		return resolvePath(env, matches[0]), nil
+	}
+
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		// 文件存在但不可执行时仍然返回它，由执行时报告权限错误
+		if info, err := os.Stat(resolvePath(env, name)); err == nil && info.Mode().IsRegular() {
+			return resolvePath(env, name), nil
+		}
+	} else if runtime.GOOS == "windows" {
+		for _, dir := range filepath.SplitList(env["PATH"]) {
+			if candidate := filepath.Join(dir, name); dir != "" && hasShebang(candidate) {
+				return resolvePath(env, candidate), nil
+			}
+		}
+	}
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+// ShellPath 执行脚本时代替找不到的 bash、sh 的 shell（gobash 可执行文件的路径），为空时不代替
+// 由 cmd/gobash 设置为自己的路径；嵌入 gobash 的程序不是 shell，默认为空
+var ShellPath string
+
+// SelfExec 检查是否用 ShellPath 代替找不到的 bash、sh：需要设置了 ShellPath，
+// 并且启用了 shopt -s selfexec 或者环境变量 GOBASH_SELF_EXEC 不为空也不为 0
+func SelfExec(env map[string]string) bool {
+	if ShellPath == "" {
+		return false
+	}
+	if hasShellOption(env, "selfexec") {
+		return true
+	}
+	value := env["GOBASH_SELF_EXEC"]
+	return value != "" && value != "0"
+}
+
+// Command 创建执行外部命令 name 的 exec.Cmd（ctx 为 nil 时不随 context 终止），命令按 LookPath 查找
+// 以 #! 开头的脚本由 shell 按 #! 行选择解释器执行（不依赖操作系统）：解释器不存在时在 PATH 中查找。
+// SelfExec 时找不到的 bash、sh 命令以及 #! 行指定的 bash、sh（如 Windows 上的 #!/usr/bin/env bash）用 ShellPath 代替，
+// Windows 上没有 PATHEXT 中的扩展名、也没有 #! 行的文件作为 shell 脚本用 ShellPath 执行。
+// 调用方负责设置环境变量、工作目录和标准输入输出
+func Command(ctx context.Context, name string, args []string, env map[string]string) (*exec.Cmd, error) {
+	program, err := LookPath(name, env)
+	if err != nil {
+		if !isShellName(path.Base(filepath.ToSlash(TrimPathExt(name, env)))) || !SelfExec(env) {
+			return nil, err
+		}
+		// bash helper.sh、/bin/sh helper.sh 在没有安装 bash 时由 gobash 执行
+		program = ShellPath
+	}
+	argv := append([]string{name}, args...)
+	// 没有执行权限的文件交给操作系统报告错误
+	if runtime.GOOS == "windows" && !hasPathExt(program, pathExts(env)) || runtime.GOOS != "windows" && isExecutable(program) {
+		interpreter, err := shebangCommand(program, env)
+		if err != nil {
+			return nil, err
+		}
+		if interpreter == nil && runtime.GOOS == "windows" && SelfExec(env) {
+			interpreter = []string{ShellPath, program}
+		}
+		if interpreter != nil {
+			program = interpreter[0]
+			argv = append(interpreter, args...)
+		}
+	}
+
+	// 批处理文件由 cmd.exe 执行，参数按 cmd.exe 的规则转义（见 cmdline.go）
+	var cmdLine string
+	if runtime.GOOS == "windows" && isBatchFile(program) {
+		comspec := commandProcessor()
+		if cmdLine, err = batchCommandLine(comspec, program, argv[1:]); err != nil {
+			return nil, err
+		}
+		program = comspec
+	}
+
+	var cmd *exec.Cmd
+	if ctx == nil {
+		cmd = exec.Command(program)
+	} else {
+		cmd = exec.CommandContext(ctx, program)
+	}
+	cmd.Args = argv
+	if cmdLine != "" {
+		setCommandLine(cmd, cmdLine)
+	}
+	return cmd, nil
+}
+
+// TrimPathExt 去掉 Windows 上 PATHEXT 中的扩展名（mytool.exe 作为命令名 mytool），其他系统原样返回
+func TrimPathExt(name string, env map[string]string) string {
+	if runtime.GOOS == "windows" && hasPathExt(name, pathExts(env)) {
+		return strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name
+}
+
+// shebangLine 读取脚本的 #! 行（不含 #!），不是脚本时返回 false
+func shebangLine(script string) (string, bool) {
+	f, err := os.Open(script)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	// 先检查开头的两个字节，不读取可执行文件的内容
+	if prefix, _ := r.Peek(2); string(prefix) != "#!" {
+		return "", false
+	}
+	line, _ := r.ReadString('\n')
+	return strings.TrimSpace(line[2:]), true
+}
+
+// hasShebang 判断 path 是否是以 #! 开头的普通文件
+func hasShebang(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	_, ok := shebangLine(path)
+	return ok
+}
+
+// shebangCommand 返回执行脚本时的命令行（解释器、#! 行中的参数和脚本路径），脚本没有 #! 行时返回 nil
+// 解释器路径不存在时（如 Windows 上的 /usr/bin/python3）按它的文件名在 PATH 中查找；#!/usr/bin/env prog 直接在 PATH 中查找 prog
+func shebangCommand(script string, env map[string]string) ([]string, error) {
+	line, ok := shebangLine(script)
+	if !ok {
+		return nil, nil
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	interpreter, args := fields[0], fields[1:]
+	if path.Base(filepath.ToSlash(interpreter)) == "env" && len(args) > 0 {
+		if args[0] == "-S" {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return nil, i18n.Errorf("%s: #! 行缺少解释器", script)
+		}
+		interpreter, args = args[0], args[1:]
+	} else if isExecutable(interpreter) {
+		return append(append([]string{interpreter}, args...), script), nil
+	}
+	program, err := LookPath(path.Base(filepath.ToSlash(interpreter)), env)
+	if err != nil {
+		if isShellName(path.Base(filepath.ToSlash(interpreter))) && SelfExec(env) {
+			// 没有安装 bash 时由 gobash 执行；#! 行中的 bash 选项（如 -e）gobash 的命令行不支持，忽略
+			return []string{ShellPath, script}, nil
+		}
+		return nil, i18n.Errorf("%s: 找不到解释器 %s: %w", script, interpreter, err)
+	}
+	return append(append([]string{program}, args...), script), nil
+}
+
+// isShellName 检查命令名是否是 bash 或 sh
+func isShellName(name string) bool {
+	return name == "bash" || name == "sh"
+}
@@ -0,0 +1,414 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+)
+
+// lsOptions ls 的选项
+type lsOptions struct {
+	long       bool // -l 长格式
+	all        bool // -a 显示所有文件，包括 . 和 ..
+	almostAll  bool // -A 显示隐藏文件，不包括 . 和 ..
+	recursive  bool // -R 递归列出子目录
+	human      bool // -h 以 K、M、G 显示大小
+	sortTime   bool // -t 按修改时间排序（最新的在前）
+	sortSize   bool // -S 按大小排序（最大的在前）
+	reverse    bool // -r 反向排序
+	onePerLine bool // -1 每行一个文件
+	columns    bool // -C 按列输出（输出不是终端时也按列输出）
+	dirOnly    bool // -d 列出目录本身，而不是目录的内容
+	color      bool // --color 按文件类型给文件名加颜色
+}
+
+// lsEntry 要列出的一个文件
+type lsEntry struct {
+	name string
+	info os.FileInfo
+}
+
+// ls 列出目录内容
+// 用法：ls [-1aACdhlrRSt] [--color[=WHEN]] [文件...]
+// 先列出作为参数的文件，再依次列出各个目录的内容；有多个参数或 -R 时在目录内容前输出目录名
+// 输出到终端时按终端宽度分列显示，否则每行一个文件
+// --color（或 --color=always）按文件类型给文件名加颜色，--color=auto 只在输出到终端时加颜色，颜色可以用 LS_COLORS 设置
+// 有文件不存在时继续列出其他文件，退出状态为 2
+func ls(args []string, env map[string]string, stdio *IO) error {
+	var opts lsOptions
+	var paths []string
+	parseOptions := true
+	for _, arg := range args {
+		if parseOptions && arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			paths = append(paths, arg)
+			continue
+		}
+		if arg == "--color" || strings.HasPrefix(arg, "--color=") {
+			when := strings.TrimPrefix(strings.TrimPrefix(arg, "--color"), "=")
+			switch when {
+			case "", "always", "yes", "force":
+				opts.color = true
+			case "auto", "tty", "if-tty":
+				if f, ok := stdio.Stdout.(*os.File); ok {
+					opts.color = EnableVirtualTerminal(f)
+				}
+			case "never", "no", "none":
+				opts.color = false
+			default:
+				return i18n.Errorf("ls: --color 的参数 '%s' 无效（可以是 always、auto 或 never）", when)
+			}
+			continue
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'l':
+				opts.long = true
+			case 'a':
+				opts.all = true
+			case 'A':
+				opts.almostAll = true
+			case 'R':
+				opts.recursive = true
+			case 'h':
+				opts.human = true
+			case 't':
+				opts.sortTime = true
+			case 'S':
+				opts.sortSize = true
+			case 'r':
+				opts.reverse = true
+			case '1':
+				opts.onePerLine = true
+			case 'C':
+				opts.columns = true
+			case 'd':
+				opts.dirOnly = true
+			default:
+				return i18n.Errorf("ls: -%c: 无效选项\n用法: ls [-1aACdhlrRSt] [--color[=WHEN]] [文件...]", flag)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	l := &lister{opts: opts, env: env, stdio: stdio}
+	if opts.color {
+		l.colors = parseLSColors(env["LS_COLORS"])
+	}
+	l.width, l.useColumns = lsLayout(opts, env, stdio.Stdout)
+
+	// 先列出文件参数，再列出目录参数
+	var files []lsEntry
+	var dirs []lsEntry
+	for _, path := range paths {
+		// 展开 ~
+		if strings.HasPrefix(path, "~") {
+			home := homeDir(env)
+			if home != "" {
+				path = strings.Replace(path, "~", home, 1)
+			}
+		}
+		info, err := os.Stat(resolvePath(env, path))
+		if err != nil {
+			// 指向不存在文件的符号链接也要列出
+			info, err = os.Lstat(resolvePath(env, path))
+		}
+		if err != nil {
+			l.fail(i18n.Sprintf("无法访问 '%s': %v", path, pathErrorReason(err)))
+			continue
+		}
+		if info.IsDir() && !opts.dirOnly {
+			dirs = append(dirs, lsEntry{name: path, info: info})
+		} else {
+			files = append(files, lsEntry{name: path, info: info})
+		}
+	}
+
+	l.sortEntries(files)
+	l.sortEntries(dirs)
+	if len(files) > 0 {
+		l.print(files, "")
+	}
+	showHeader := len(paths) > 1 || opts.recursive
+	for i, dir := range dirs {
+		if i > 0 || len(files) > 0 {
+			fmt.Fprintln(stdio.Stdout)
+		}
+		l.listDir(dir.name, resolvePath(env, dir.name), showHeader)
+	}
+
+	if len(l.errors) > 0 {
+		return &StatusError{Code: 2, Message: strings.Join(l.errors, "\n")}
+	}
+	return nil
+}
+
+// lister 保存一次 ls 调用的选项、输出和遇到的错误
+type lister struct {
+	opts       lsOptions
+	env        map[string]string
+	stdio      *IO
+	colors     *lsColors // 使用 --color 时文件名的颜色
+	width      int  // 按列输出时的总宽度
+	useColumns bool // 是否按列输出
+	errors     []string
+}
+
+// fail 记录错误，ls 遇到错误时继续列出其他文件
+func (l *lister) fail(msg string) {
+	l.errors = append(l.errors, "ls: "+msg)
+}
+
+// listDir 列出目录 dir 的内容，display 是输出中显示的目录名
+func (l *lister) listDir(display, dir string, showHeader bool) {
+	if showHeader {
+		fmt.Fprintf(l.stdio.Stdout, "%s:\n", display)
+	}
+
+	err := l.stdio.checkPath("open", AccessRead, dir)
+	var dirEntries []os.DirEntry
+	if err == nil {
+		dirEntries, err = os.ReadDir(dir)
+	}
+	if err != nil {
+		l.fail(i18n.Sprintf("无法打开目录 '%s': %v", display, pathErrorReason(err)))
+		return
+	}
+
+	var entries []lsEntry
+	if l.opts.all {
+		for _, name := range []string{".", ".."} {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				entries = append(entries, lsEntry{name: name, info: info})
+			}
+		}
+	}
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !l.opts.all && !l.opts.almostAll {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// 读取目录后文件被删除
+			continue
+		}
+		entries = append(entries, lsEntry{name: name, info: info})
+	}
+	l.sortEntries(entries)
+	l.print(entries, dir)
+
+	if !l.opts.recursive {
+		return
+	}
+	for _, entry := range entries {
+		// 不进入 . 和 ..，也不跟随指向目录的符号链接
+		if entry.name == "." || entry.name == ".." || !entry.info.IsDir() {
+			continue
+		}
+		fmt.Fprintln(l.stdio.Stdout)
+		l.listDir(filepath.Join(display, entry.name), filepath.Join(dir, entry.name), true)
+	}
+}
+
+// sortEntries 按名称、修改时间（-t）或大小（-S）排序，-r 反向
+func (l *lister) sortEntries(entries []lsEntry) {
+	less := func(a, b lsEntry) bool {
+		switch {
+		case l.opts.sortSize && a.info.Size() != b.info.Size():
+			return a.info.Size() > b.info.Size()
+		case l.opts.sortTime && !l.opts.sortSize && !a.info.ModTime().Equal(b.info.ModTime()):
+			return a.info.ModTime().After(b.info.ModTime())
+		}
+		return a.name < b.name
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if l.opts.reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
+
+// print 输出一组文件，dir 是文件所在的目录（列出文件参数时为空）
+func (l *lister) print(entries []lsEntry, dir string) {
+	if l.opts.long {
+		for _, entry := range entries {
+			l.printLong(entry, dir)
+		}
+		return
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = l.colorize(entry, dir)
+	}
+	if !l.useColumns {
+		for _, name := range names {
+			fmt.Fprintln(l.stdio.Stdout, name)
+		}
+		return
+	}
+	printColumns(l.stdio.Stdout, names, l.width)
+}
+
+// printLong 以长格式输出文件信息：类型和权限、大小、修改时间、文件名，符号链接显示指向的目标
+func (l *lister) printLong(entry lsEntry, dir string) {
+	info := entry.info
+	mode := info.Mode()
+	kind := "-"
+	switch {
+	case mode.IsDir():
+		kind = "d"
+	case mode&os.ModeSymlink != 0:
+		kind = "l"
+	}
+
+	size := strconv.FormatInt(info.Size(), 10)
+	if l.opts.human {
+		size = humanSize(info.Size())
+	}
+	name := l.colorize(entry, dir)
+	if mode&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(l.entryPath(entry, dir)); err == nil {
+			name += " -> " + target
+		}
+	}
+	modTime := info.ModTime().Format("Jan 02 15:04")
+	fmt.Fprintf(l.stdio.Stdout, "%s%s %8s %s %s\n", kind, mode.Perm().String()[1:], size, modTime, name)
+}
+
+// humanSize 以 K、M、G 等单位显示大小（1024 进制），小于 10 时保留一位小数
+func humanSize(size int64) string {
+	if size < 1024 {
+		return strconv.FormatInt(size, 10)
+	}
+	value := float64(size)
+	unit := 0
+	units := "KMGTPE"
+	for value >= 1024 && unit < len(units) {
+		value /= 1024
+		unit++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, units[unit-1])
+	}
+	return fmt.Sprintf("%.0f%c", value, units[unit-1])
+}
+
+// lsLayout 决定是否按列输出以及输出宽度
+// 输出到终端或使用 -C 时按列输出，宽度取 COLUMNS，没有设置时取终端宽度（默认 80）
+func lsLayout(opts lsOptions, env map[string]string, out io.Writer) (int, bool) {
+	if opts.onePerLine {
+		return 0, false
+	}
+	terminal := false
+	if f, ok := out.(*os.File); ok {
+		terminal = readline.IsTerminal(int(f.Fd()))
+	}
+	if !terminal && !opts.columns {
+		return 0, false
+	}
+
+	if width, err := strconv.Atoi(env["COLUMNS"]); err == nil && width > 0 {
+		return width, true
+	}
+	if terminal {
+		if width := readline.GetScreenWidth(); width > 0 {
+			return width, true
+		}
+	}
+	return 80, true
+}
+
+// printColumns 按列输出文件名（先从上到下再从左到右），使用尽量少的行数使总宽度不超过 width
+func printColumns(out io.Writer, names []string, width int) {
+	if len(names) == 0 {
+		return
+	}
+	const gap = 2
+
+	var colWidths []int
+	rows := 1
+	for ; rows < len(names); rows++ {
+		cols := (len(names) + rows - 1) / rows
+		colWidths = make([]int, cols)
+		total := 0
+		for i, name := range names {
+			col := i / rows
+			if w := nameWidth(name); w > colWidths[col] {
+				colWidths[col] = w
+			}
+		}
+		for _, w := range colWidths {
+			total += w + gap
+		}
+		if total-gap <= width {
+			break
+		}
+	}
+	if rows == len(names) {
+		// 每行一个文件
+		colWidths = []int{0}
+	}
+
+	for row := 0; row < rows; row++ {
+		var line strings.Builder
+		for col := 0; col < len(colWidths); col++ {
+			i := col*rows + row
+			if i >= len(names) {
+				break
+			}
+			line.WriteString(names[i])
+			// 最后一列或下一列没有文件时不补空格
+			if col+1 < len(colWidths) && (col+1)*rows+row < len(names) {
+				line.WriteString(strings.Repeat(" ", colWidths[col]-nameWidth(names[i])+gap))
+			}
+		}
+		fmt.Fprintln(out, line.String())
+	}
+}
+
+// nameWidth 返回文件名显示的宽度，不计算颜色的转义序列
+func nameWidth(name string) int {
+	width := 0
+	for i := 0; i < len(name); {
+		if name[i] == '\033' {
+			if end := strings.IndexByte(name[i:], 'm'); end >= 0 {
+				i += end + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(name[i:])
+		i += size
+		width++
+	}
+	return width
+}
+
+// pathErrorReason 返回文件操作错误的原因（去掉 os.PathError 中的操作和路径）
+func pathErrorReason(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		if os.IsNotExist(pathErr.Err) {
+			return i18n.Errorf("没有那个文件或目录")
+		}
+		if os.IsPermission(pathErr.Err) {
+			return i18n.Errorf("权限不够")
+		}
+		return pathErr.Err
+	}
+	return err
+}
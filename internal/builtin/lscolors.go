@@ -0,0 +1,106 @@
+package builtin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// defaultLSColors 没有设置 LS_COLORS 时使用的颜色（与 dircolors 的默认值相同）
+const defaultLSColors = "di=01;34:ln=01;36:ex=01;32:pi=40;33:so=01;35:bd=40;33;01:cd=40;33;01:or=40;31;01"
+
+// lsColors ls --color 使用的颜色，值是 SGR 参数（如 01;34）
+type lsColors struct {
+	types map[string]string // 按文件类型：di 目录、ln 符号链接、ex 可执行文件、fi 普通文件、or 目标不存在的符号链接等
+	exts  []lsColorPattern  // 按文件名后缀（LS_COLORS 中的 *.tar=01;31）
+}
+
+// lsColorPattern 文件名后缀及其颜色
+type lsColorPattern struct {
+	suffix string
+	code   string
+}
+
+// parseLSColors 解析 LS_COLORS（key=value 以冒号分隔），它设置的颜色覆盖默认值
+// ln=target 表示符号链接使用指向的文件的颜色
+func parseLSColors(value string) *lsColors {
+	colors := &lsColors{types: make(map[string]string)}
+	for _, spec := range strings.Split(defaultLSColors+":"+value, ":") {
+		key, code, ok := strings.Cut(spec, "=")
+		if !ok || key == "" {
+			continue
+		}
+		if strings.HasPrefix(key, "*") {
+			colors.exts = append(colors.exts, lsColorPattern{suffix: key[1:], code: code})
+			continue
+		}
+		colors.types[key] = code
+	}
+	return colors
+}
+
+// code 返回文件的颜色，path 是文件的路径（用于判断符号链接指向的文件）
+func (c *lsColors) code(name, path string, info os.FileInfo, env map[string]string) string {
+	mode := info.Mode()
+	switch {
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Stat(path)
+		if err != nil {
+			if code, ok := c.types["or"]; ok {
+				return code
+			}
+			return c.types["ln"]
+		}
+		if c.types["ln"] == "target" {
+			return c.code(name, path, target, env)
+		}
+		return c.types["ln"]
+	case mode.IsDir():
+		return c.types["di"]
+	case mode&os.ModeNamedPipe != 0:
+		return c.types["pi"]
+	case mode&os.ModeSocket != 0:
+		return c.types["so"]
+	case mode&os.ModeCharDevice != 0:
+		return c.types["cd"]
+	case mode&os.ModeDevice != 0:
+		return c.types["bd"]
+	}
+
+	// 普通文件：可执行文件优先，其次按后缀
+	if runtime.GOOS == "windows" {
+		if hasPathExt(name, pathExts(env)) {
+			return c.types["ex"]
+		}
+	} else if mode.Perm()&0111 != 0 {
+		return c.types["ex"]
+	}
+	// 后面的设置覆盖前面的，从后往前查找
+	for i := len(c.exts) - 1; i >= 0; i-- {
+		if suffix := c.exts[i].suffix; len(name) >= len(suffix) && strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+			return c.exts[i].code
+		}
+	}
+	return c.types["fi"]
+}
+
+// colorize 返回加上颜色的文件名，没有使用 --color 时原样返回
+func (l *lister) colorize(entry lsEntry, dir string) string {
+	if l.colors == nil {
+		return entry.name
+	}
+	code := l.colors.code(entry.name, l.entryPath(entry, dir), entry.info, l.env)
+	if code == "" || code == "0" || code == "00" {
+		return entry.name
+	}
+	return "\033[" + code + "m" + entry.name + "\033[0m"
+}
+
+// entryPath 返回文件的路径，dir 是文件所在的目录（列出文件参数时为空，文件名相对于工作目录）
+func (l *lister) entryPath(entry lsEntry, dir string) string {
+	if dir == "" {
+		return resolvePath(l.env, entry.name)
+	}
+	return filepath.Join(dir, entry.name)
+}
@@ -0,0 +1,385 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"gobash/internal/i18n"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// basename 输出路径的最后一部分，可以去掉后缀
+// 用法：basename 名称 [后缀] 或 basename [-az] [-s 后缀] 名称...
+// 名称末尾的 / 会被忽略；后缀与整个名称相同时不去掉
+func basename(args []string, env map[string]string, stdio *IO) error {
+	multiple, zero := false, false
+	suffix := ""
+	var names []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			names = append(names, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--suffix="); ok {
+			suffix, multiple = value, true
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'a':
+				multiple = true
+			case 'z':
+				zero = true
+			case 's':
+				suffix = arg[j+1:]
+				if suffix == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("basename: -s 缺少参数")
+					}
+					i++
+					suffix = args[i]
+				}
+				multiple = true
+				j = len(arg)
+			default:
+				return i18n.Errorf("basename: -%c: 无效选项\n用法: basename 名称 [后缀] 或 basename [-az] [-s 后缀] 名称...", flag)
+			}
+		}
+	}
+
+	switch {
+	case len(names) == 0:
+		return i18n.Errorf("basename: 缺少操作数\n用法: basename 名称 [后缀] 或 basename [-az] [-s 后缀] 名称...")
+	case !multiple && len(names) == 2:
+		suffix = names[1]
+		names = names[:1]
+	case !multiple && len(names) > 2:
+		return i18n.Errorf("basename: 多余的操作数: %s", names[2])
+	}
+
+	end := "\n"
+	if zero {
+		end = "\x00"
+	}
+	for _, name := range names {
+		base := baseName(name)
+		if suffix != "" && base != suffix {
+			base = strings.TrimSuffix(base, suffix)
+		}
+		fmt.Fprint(stdio.Stdout, base+end)
+	}
+	return nil
+}
+
+// baseName 返回路径的最后一部分（忽略末尾的 /，全部是 / 时返回 /）
+func baseName(name string) string {
+	trimmed := strings.TrimRight(name, "/")
+	if trimmed == "" {
+		if name == "" {
+			return ""
+		}
+		return "/"
+	}
+	return trimmed[strings.LastIndex(trimmed, "/")+1:]
+}
+
+// dirname 输出路径中最后一个 / 之前的部分，没有 / 时输出 .
+// 用法：dirname [-z] 名称...
+func dirname(args []string, env map[string]string, stdio *IO) error {
+	zero := false
+	var names []string
+	parseOptions := true
+	for _, arg := range args {
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			names = append(names, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		for _, flag := range arg[1:] {
+			if flag != 'z' {
+				return i18n.Errorf("dirname: -%c: 无效选项\n用法: dirname [-z] 名称...", flag)
+			}
+			zero = true
+		}
+	}
+	if len(names) == 0 {
+		return i18n.Errorf("dirname: 缺少操作数\n用法: dirname [-z] 名称...")
+	}
+
+	end := "\n"
+	if zero {
+		end = "\x00"
+	}
+	for _, name := range names {
+		fmt.Fprint(stdio.Stdout, dirName(name)+end)
+	}
+	return nil
+}
+
+// dirName 返回路径的目录部分（忽略末尾的 /）
+func dirName(name string) string {
+	trimmed := strings.TrimRight(name, "/")
+	if trimmed == "" {
+		if name == "" {
+			return "."
+		}
+		return "/"
+	}
+	i := strings.LastIndex(trimmed, "/")
+	if i < 0 {
+		return "."
+	}
+	if dir := strings.TrimRight(trimmed[:i], "/"); dir != "" {
+		return dir
+	}
+	return "/"
+}
+
+// realpath 输出解析符号链接、. 和 .. 之后的绝对路径
+// 用法：realpath [-e|-m] [-sqz] 文件...
+// 默认除最后一部分外路径必须存在；-e 整个路径必须存在，-m 路径可以不存在，-s 不解析符号链接
+func realpath(args []string, env map[string]string, stdio *IO) error {
+	mode := byte(0) // 0 默认、'e' 必须存在、'm' 可以不存在
+	noSymlinks, quiet, zero := false, false, false
+	var files []string
+	parseOptions := true
+	for _, arg := range args {
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'e', 'm':
+				mode = byte(flag)
+			case 's':
+				noSymlinks = true
+			case 'q':
+				quiet = true
+			case 'z':
+				zero = true
+			default:
+				return i18n.Errorf("realpath: -%c: 无效选项\n用法: realpath [-e|-m] [-sqz] 文件...", flag)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return i18n.Errorf("realpath: 缺少操作数\n用法: realpath [-e|-m] [-sqz] 文件...")
+	}
+
+	end := "\n"
+	if zero {
+		end = "\x00"
+	}
+	var errs []string
+	for _, file := range files {
+		if file == "" {
+			errs = append(errs, i18n.T("realpath: '': 没有那个文件或目录"))
+			continue
+		}
+		resolved, err := resolveRealPath(resolvePath(env, file), mode, noSymlinks)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("realpath: %s: %v", file, pathErrorReason(err)))
+			continue
+		}
+		fmt.Fprint(stdio.Stdout, resolved+end)
+	}
+	if len(errs) > 0 {
+		if quiet {
+			return &StatusError{Code: 1}
+		}
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// resolveRealPath 解析绝对路径 path 中的符号链接
+// 存在的最长前缀用 EvalSymlinks 解析，剩余部分按 . 和 .. 规范化后拼接；mode 决定哪些部分必须存在
+func resolveRealPath(path string, mode byte, noSymlinks bool) (string, error) {
+	path = filepath.Clean(path)
+	if noSymlinks {
+		if mode == 'e' {
+			if _, err := os.Lstat(path); err != nil {
+				return "", err
+			}
+		}
+		return path, nil
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if mode == 'e' || !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	// 找到存在的最长前缀
+	existing, rest := path, ""
+	for {
+		parent := filepath.Dir(existing)
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+		if resolved, err = filepath.EvalSymlinks(existing); err == nil {
+			break
+		}
+		if parent == filepath.Dir(parent) {
+			return "", err
+		}
+	}
+	// 默认只允许最后一部分不存在
+	if mode == 0 && strings.ContainsRune(filepath.Clean(rest), filepath.Separator) {
+		return "", &fs.PathError{Op: "realpath", Path: path, Err: fs.ErrNotExist}
+	}
+	return filepath.Join(resolved, rest), nil
+}
+
+// mktemp 创建临时文件或目录并输出它的路径
+// 用法：mktemp [-dqtu] [-p 目录] [--suffix=后缀] [模板]
+// 模板末尾至少要有 3 个 X，它们会被替换为随机字符，默认模板为 tmp.XXXXXXXXXX；
+// 没有指定模板或使用 -t 时在 -p 指定的目录、TMPDIR 或系统临时目录中创建，否则相对于当前目录
+func mktemp(args []string, env map[string]string, stdio *IO) error {
+	makeDir, dryRun, quiet, useTmpDir := false, false, false, false
+	dir, hasDir := "", false
+	suffix := ""
+	var templates []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			templates = append(templates, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--suffix="); ok {
+			suffix = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--tmpdir="); ok {
+			dir, hasDir = value, true
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'd':
+				makeDir = true
+			case 'u':
+				dryRun = true
+			case 'q':
+				quiet = true
+			case 't':
+				useTmpDir = true
+			case 'p':
+				dir = arg[j+1:]
+				if dir == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("mktemp: -p 缺少参数")
+					}
+					i++
+					dir = args[i]
+				}
+				hasDir = true
+				j = len(arg)
+			default:
+				return i18n.Errorf("mktemp: -%c: 无效选项\n用法: mktemp [-dqtu] [-p 目录] [--suffix=后缀] [模板]", flag)
+			}
+		}
+	}
+	if len(templates) > 1 {
+		return i18n.Errorf("mktemp: 多余的操作数: %s", templates[1])
+	}
+
+	template := "tmp.XXXXXXXXXX"
+	if len(templates) == 1 {
+		template = templates[0]
+	} else {
+		useTmpDir = true
+	}
+	if useTmpDir && strings.Contains(template, "/") {
+		return i18n.Errorf("mktemp: 模板 %q 不能包含目录分隔符", template)
+	}
+	// 模板中的 X 必须在末尾（或 --suffix 之前）
+	prefix := strings.TrimRight(template, "X")
+	count := len(template) - len(prefix)
+	if count < 3 {
+		return i18n.Errorf("mktemp: 模板 %q 末尾的 X 太少", template)
+	}
+
+	// -t、-p 或没有指定模板时在临时目录中创建，否则模板相对于当前目录
+	if useTmpDir || hasDir {
+		if dir == "" {
+			dir = env["TMPDIR"]
+		}
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		prefix = strings.TrimSuffix(dir, "/") + "/" + prefix
+	}
+
+	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	random := make([]byte, count)
+	for attempt := 0; attempt < 100; attempt++ {
+		for i := range random {
+			random[i] = chars[rand.Intn(len(chars))]
+		}
+		name := prefix + string(random) + suffix
+		path := resolvePath(env, name)
+
+		var err error
+		switch {
+		case dryRun:
+			if _, err = os.Lstat(path); err == nil {
+				err = fs.ErrExist
+			} else if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		case makeDir:
+			if err = stdio.checkPath("mkdir", AccessWrite, path); err == nil {
+				err = os.Mkdir(path, 0700)
+			}
+		default:
+			var f *os.File
+			if err = stdio.checkPath("open", AccessWrite, path); err == nil {
+				f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+			}
+			if err == nil {
+				f.Close()
+			}
+		}
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			if quiet {
+				return &StatusError{Code: 1}
+			}
+			kind := i18n.T("文件")
+			if makeDir {
+				kind = i18n.T("目录")
+			}
+			return i18n.Errorf("mktemp: 无法通过模板 %q 创建%s: %v", template, kind, pathErrorReason(err))
+		}
+		fmt.Fprintln(stdio.Stdout, name)
+		return nil
+	}
+	return i18n.Errorf("mktemp: 无法通过模板 %q 创建唯一的名称", template)
+}
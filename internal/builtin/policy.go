@@ -0,0 +1,101 @@
+package builtin
+
+import (
+	"context"
+	"gobash/internal/i18n"
+	"os"
+	"os/exec"
+)
+
+// 访问策略
+//
+// 嵌入方可以设置策略函数（IO.Policy），shell 在执行外部命令、打开重定向的文件、内置命令读写文件和访问网络之前调用它，
+// 策略返回错误时不执行该操作，用于在服务中安全地运行不受信任的脚本。
+// 被拒绝的外部命令退出状态为 126，被拒绝的文件操作和网络访问与相应的操作失败一样报错
+
+// AccessKind 策略检查的操作类型
+type AccessKind string
+
+const (
+	AccessExec    AccessKind = "exec"    // 执行外部命令，Target 为命令名，Args 为参数
+	AccessRead    AccessKind = "read"    // 读取文件或列出目录，Target 为绝对路径
+	AccessWrite   AccessKind = "write"   // 创建、修改或删除文件和目录，Target 为绝对路径
+	AccessNetwork AccessKind = "network" // 访问网络，Target 为 URL
+)
+
+// Access 需要策略检查的操作
+type Access struct {
+	Kind   AccessKind
+	Target string
+	Args   []string
+}
+
+// Policy 访问策略，返回错误时拒绝操作，错误信息作为拒绝的原因显示
+type Policy func(access Access) error
+
+// DeniedError 策略拒绝操作时返回的错误
+// errors.Is(err, os.ErrPermission) 为 true，Unwrap 返回策略返回的错误
+type DeniedError struct {
+	Access Access
+	Err    error
+}
+
+func (e *DeniedError) Error() string {
+	if e.Err == nil || e.Err.Error() == "" {
+		return i18n.T("操作被策略拒绝")
+	}
+	return e.Err.Error()
+}
+
+func (e *DeniedError) Unwrap() error {
+	return e.Err
+}
+
+// Is 让被拒绝的操作与没有权限的操作一样处理
+func (e *DeniedError) Is(target error) bool {
+	return target == os.ErrPermission
+}
+
+// Check 按 stdio 的策略检查操作，没有设置策略时允许所有操作；拒绝时返回 *DeniedError
+func (stdio *IO) Check(kind AccessKind, target string, args ...string) error {
+	if stdio.Policy == nil {
+		return nil
+	}
+	access := Access{Kind: kind, Target: target, Args: args}
+	if err := stdio.Policy(access); err != nil {
+		return &DeniedError{Access: access, Err: err}
+	}
+	return nil
+}
+
+// checkPath 检查是否允许以 kind 方式访问文件 path，拒绝时返回与文件操作失败相同形式的 *os.PathError
+func (stdio *IO) checkPath(op string, kind AccessKind, path string) error {
+	if err := stdio.Check(kind, path); err != nil {
+		return &os.PathError{Op: op, Path: path, Err: err}
+	}
+	return nil
+}
+
+// open 策略允许读取时打开文件 path
+func (stdio *IO) open(path string) (*os.File, error) {
+	if err := stdio.checkPath("open", AccessRead, path); err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// create 策略允许写入时以 flag 打开文件 path，新文件按 perm 去掉 umask 中的位创建
+func (stdio *IO) create(path string, flag int, perm os.FileMode) (*os.File, error) {
+	if err := stdio.checkPath("open", AccessWrite, path); err != nil {
+		return nil, err
+	}
+	return CreateFile(path, flag, perm, stdio.umask())
+}
+
+// command 策略允许执行时创建执行外部命令 name 的 exec.Cmd（见 Command）
+func (stdio *IO) command(ctx context.Context, name string, args []string, env map[string]string) (*exec.Cmd, error) {
+	if err := stdio.Check(AccessExec, name, args...); err != nil {
+		return nil, err
+	}
+	return Command(ctx, name, args, env)
+}
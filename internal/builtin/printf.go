@@ -0,0 +1,341 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// printfUsage printf 命令的用法说明
+const printfUsage = "用法: printf [-v 变量名] 格式 [参数 ...]"
+
+// PrintfBuiltin 返回使用 assign 给变量赋值的 printf 命令（printf -v 使用）
+// 执行器用它处理名称引用和数组元素；assign 为 nil 时直接写入 env
+func PrintfBuiltin(assign func(name, value string) error) BuiltinFunc {
+	return func(args []string, env map[string]string, stdio *IO) error {
+		set := assign
+		if set == nil {
+			set = func(name, value string) error {
+				env[name] = value
+				return nil
+			}
+		}
+		return printfCmd(set, args, stdio)
+	}
+}
+
+// printfCommand 直接写入 env 的 printf 命令（执行器会用 PrintfBuiltin 替换它）
+var printfCommand = PrintfBuiltin(nil)
+
+// printfCmd 按格式输出参数
+// 用法：printf [-v 变量名] 格式 [参数 ...]
+// 格式支持 %s %b %q %c %d %i %o %u %x %X %e %E %f %F %g %G %% 以及标志、宽度和精度（可以是 *），
+// 格式中的转义序列（\n、\t、\NNN 等）被解释；参数比格式需要的多时重复使用格式；-v 把结果赋给变量而不输出
+func printfCmd(assign func(name, value string) error, args []string, stdio *IO) error {
+	varName := ""
+	for len(args) > 0 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		if args[0] == "-v" {
+			if len(args) < 2 {
+				return &StatusError{Code: 2, Message: i18n.T("printf: -v: 需要参数\n") + i18n.T(printfUsage)}
+			}
+			varName = args[1]
+			if !isAssignable(varName) {
+				return i18n.Errorf("printf: `%s': 不是有效的标识符", varName)
+			}
+			args = args[2:]
+			continue
+		}
+		if strings.HasPrefix(args[0], "-v") && len(args[0]) > 2 {
+			varName = args[0][2:]
+			if !isAssignable(varName) {
+				return i18n.Errorf("printf: `%s': 不是有效的标识符", varName)
+			}
+			args = args[1:]
+			continue
+		}
+		break
+	}
+	if len(args) == 0 {
+		return &StatusError{Code: 2, Message: i18n.T(printfUsage)}
+	}
+
+	p := &printfState{args: args[1:], stderr: stdio}
+	var out strings.Builder
+	for {
+		consumed := len(p.args)
+		if p.format(&out, args[0]) {
+			break
+		}
+		// 参数用完或者格式不使用参数时结束
+		if len(p.args) == 0 || len(p.args) == consumed {
+			break
+		}
+	}
+
+	if varName != "" {
+		if err := assign(varName, out.String()); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprint(stdio.Stdout, out.String())
+	}
+	if p.failed {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
+
+// isAssignable 检查 name 是否可以赋值：变量名或数组元素（如 arr[1]）
+func isAssignable(name string) bool {
+	base, index, ok := strings.Cut(name, "[")
+	return isName(base) && (!ok || strings.HasSuffix(index, "]"))
+}
+
+// printfState printf 的剩余参数和出错状态
+type printfState struct {
+	args   []string
+	stderr *IO
+	failed bool // 有参数不是有效的数字
+}
+
+// next 返回下一个参数，参数用完时返回空字符串
+func (p *printfState) next() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	arg := p.args[0]
+	p.args = p.args[1:]
+	return arg
+}
+
+// format 按格式输出一遍，遇到 %b 参数中的 \c 时返回 true（停止所有输出）
+func (p *printfState) format(out *strings.Builder, format string) bool {
+	for i := 0; i < len(format); i++ {
+		ch := format[i]
+		if ch == '\\' {
+			n, stop := printfEscape(out, format[i:])
+			if stop {
+				return true
+			}
+			i += n - 1
+			continue
+		}
+		if ch != '%' {
+			out.WriteByte(ch)
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			out.WriteByte('%')
+			i++
+			continue
+		}
+
+		// %[标志][宽度][.精度]转换
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0", format[j]) >= 0 {
+			j++
+		}
+		spec := format[i:j]
+		if j < len(format) && format[j] == '*' {
+			spec += strconv.Itoa(int(p.integer(p.next())))
+			j++
+		} else {
+			start := j
+			for j < len(format) && isDigitByte(format[j]) {
+				j++
+			}
+			spec += format[start:j]
+		}
+		if j < len(format) && format[j] == '.' {
+			j++
+			if j < len(format) && format[j] == '*' {
+				spec += "." + strconv.Itoa(int(p.integer(p.next())))
+				j++
+			} else {
+				start := j
+				for j < len(format) && isDigitByte(format[j]) {
+					j++
+				}
+				spec += "." + format[start:j]
+			}
+		}
+		if j == len(format) {
+			// 不完整的转换原样输出
+			out.WriteString(format[i:])
+			return false
+		}
+
+		switch verb := format[j]; verb {
+		case 's':
+			fmt.Fprintf(out, spec+"s", p.next())
+		case 'b':
+			text, stop := expandEchoEscapes(printfOctalEscapes(p.next()))
+			fmt.Fprintf(out, spec+"s", text)
+			if stop {
+				return true
+			}
+		case 'q':
+			fmt.Fprintf(out, spec+"s", shellQuote(p.next()))
+		case 'c':
+			arg := p.next()
+			if arg != "" {
+				_, size := utf8.DecodeRuneInString(arg)
+				arg = arg[:size]
+			}
+			fmt.Fprintf(out, spec+"s", arg)
+		case 'd', 'i':
+			fmt.Fprintf(out, spec+"d", p.integer(p.next()))
+		case 'o', 'u', 'x', 'X':
+			if verb == 'u' {
+				verb = 'd'
+			}
+			fmt.Fprintf(out, spec+string(verb), uint64(p.integer(p.next())))
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			if verb == 'F' {
+				verb = 'f'
+			}
+			if !strings.Contains(spec, ".") {
+				spec += ".6"
+			}
+			fmt.Fprintf(out, spec+string(verb), p.float(p.next()))
+		default:
+			i18n.Fprintf(p.stderr.Stderr, "printf: %%%c: 无效的格式字符\n", verb)
+			p.failed = true
+			out.WriteString(format[i : j+1])
+		}
+		i = j
+	}
+	return false
+}
+
+// integer 把参数转换为整数：支持十进制、0 开头的八进制、0x 开头的十六进制，以及 'c（字符的编码）
+// 不是有效的数字时输出错误并记录失败
+func (p *printfState) integer(arg string) int64 {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return 0
+	}
+	if arg[0] == '\'' || arg[0] == '"' {
+		if len(arg) == 1 {
+			return 0
+		}
+		r, _ := utf8.DecodeRuneInString(arg[1:])
+		return int64(r)
+	}
+	if n, err := strconv.ParseInt(arg, 0, 64); err == nil {
+		return n
+	}
+	if n, err := strconv.ParseUint(arg, 0, 64); err == nil {
+		return int64(n)
+	}
+	i18n.Fprintf(p.stderr.Stderr, "printf: %s: 无效的数字\n", arg)
+	p.failed = true
+	return 0
+}
+
+// float 把参数转换为浮点数，不是有效的数字时输出错误并记录失败
+func (p *printfState) float(arg string) float64 {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return 0
+	}
+	if arg[0] == '\'' || arg[0] == '"' {
+		return float64(p.integer(arg))
+	}
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		i18n.Fprintf(p.stderr.Stderr, "printf: %s: 无效的数字\n", arg)
+		p.failed = true
+		return 0
+	}
+	return f
+}
+
+// printfEscape 解释格式开头的一个转义序列并写入 out，返回消耗的字节数；遇到 \c 时第二个返回值为 true
+func printfEscape(out *strings.Builder, s string) (int, bool) {
+	if len(s) < 2 {
+		out.WriteString(s)
+		return len(s), false
+	}
+	switch c := s[1]; {
+	case c >= '0' && c <= '7':
+		// \NNN：最多 3 位八进制数字
+		value, n := parseEscapeDigits(s[1:], 8, 3)
+		out.WriteByte(byte(value))
+		return n + 1, false
+	case c == '"' || c == '\'' || c == '?':
+		out.WriteByte(c)
+		return 2, false
+	}
+	// 其他转义与 echo -e 相同，找出这个转义序列的长度后交给 expandEchoEscapes
+	n := 2
+	switch s[1] {
+	case 'x':
+		_, digits := parseEscapeDigits(s[2:], 16, 2)
+		n += digits
+	case 'u':
+		_, digits := parseEscapeDigits(s[2:], 16, 4)
+		n += digits
+	case 'U':
+		_, digits := parseEscapeDigits(s[2:], 16, 8)
+		n += digits
+	}
+	text, stop := expandEchoEscapes(s[:n])
+	out.WriteString(text)
+	return n, stop
+}
+
+// printfOctalEscapes 把 %b 参数中的 \NNN 改写为 echo -e 使用的 \0NNN
+func printfOctalEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if s[i+1] >= '1' && s[i+1] <= '7' {
+				b.WriteString(`\0`)
+				continue
+			}
+			// \\ 和 \0NNN 原样保留
+			b.WriteByte(s[i])
+			b.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// shellQuote 按 printf %q 的方式引用字符串，结果可以作为 shell 输入重新读取
+// 包含控制字符时使用 $'...' 形式，否则用反斜杠转义特殊字符
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return "$'" + strings.NewReplacer(
+				`\`, `\\`, `'`, `\'`, "\n", `\n`, "\t", `\t`, "\r", `\r`, "\a", `\a`, "\b", `\b`,
+				"\f", `\f`, "\v", `\v`, "\x1b", `\E`,
+			).Replace(s) + "'"
+		}
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(" \t'\"\\$`|&;<>()*?[]{}~#!^,", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isDigitByte 判断字节是否为十进制数字
+func isDigitByte(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,360 @@
+package builtin
+
+import (
+	"bufio"
+	"fmt"
+	"gobash/internal/i18n"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// processInfo 系统中的一个进程
+type processInfo struct {
+	pid     int
+	ppid    int
+	name    string // 进程名（Linux 上最多 15 个字符）
+	cmdline string // 完整命令行，无法获取时与进程名相同
+}
+
+// ps 列出系统中的进程
+// 用法：ps [-Aef] [-p pid列表] [--no-headers]
+// 输出 PID、PPID 和命令，默认列出所有进程（-A、-e 为兼容选项）；-f 显示完整命令行，-p 只显示指定的进程（逗号分隔）
+func ps(args []string, env map[string]string, stdio *IO) error {
+	full, headers := false, true
+	var pids []int
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--no-headers" {
+			headers = false
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return i18n.Errorf("ps: 多余的参数: %s\n用法: ps [-Aef] [-p pid列表] [--no-headers]", arg)
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'A', 'e':
+			case 'f':
+				full = true
+			case 'p':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("ps: -p 缺少参数")
+					}
+					i++
+					value = args[i]
+				}
+				list, err := parsePidList(value)
+				if err != nil {
+					return fmt.Errorf("ps: %v", err)
+				}
+				pids = append(pids, list...)
+				j = len(arg)
+			default:
+				return i18n.Errorf("ps: -%c: 无效选项\n用法: ps [-Aef] [-p pid列表] [--no-headers]", flag)
+			}
+		}
+	}
+
+	procs, err := listProcesses()
+	if err != nil {
+		return i18n.Errorf("ps: 无法获取进程列表: %v", err)
+	}
+	writer := bufio.NewWriter(stdio.Stdout)
+	defer writer.Flush()
+	if headers {
+		fmt.Fprintf(writer, "%7s %7s %s\n", "PID", "PPID", "CMD")
+	}
+	found := false
+	for _, p := range procs {
+		if len(pids) > 0 && !containsPid(pids, p.pid) {
+			continue
+		}
+		cmd := p.name
+		if full {
+			cmd = p.cmdline
+		}
+		fmt.Fprintf(writer, "%7d %7d %s\n", p.pid, p.ppid, cmd)
+		found = true
+	}
+	// 与 procps 一样，-p 指定的进程都不存在时退出状态为 1
+	if len(pids) > 0 && !found {
+		return &StatusError{Code: 1, Message: i18n.T("ps: 没有匹配的进程")}
+	}
+	return nil
+}
+
+// pgrepOptions pgrep 和 pkill 的选项
+type pgrepOptions struct {
+	full       bool   // -f 匹配完整命令行而不是进程名
+	ignoreCase bool   // -i 忽略大小写
+	exact      bool   // -x 整个进程名（或命令行）必须匹配
+	invert     bool   // -v 选择不匹配的进程
+	newest     bool   // -n 只选择进程号最大（最新）的进程
+	oldest     bool   // -o 只选择进程号最小（最早）的进程
+	parents    []int  // -P 只选择这些进程的子进程
+	list       bool   // -l 同时输出进程名（pgrep）
+	listFull   bool   // -a 同时输出完整命令行（pgrep）
+	count      bool   // -c 只输出匹配的进程数（pgrep）
+	delimiter  string // -d 进程号之间的分隔符（pgrep）
+	echo       bool   // -e 输出被终止的进程（pkill）
+	signal     int    // 发送的信号（pkill）
+	pattern    string
+}
+
+// parsePgrepArgs 解析 pgrep 或 pkill 的参数，usage 出错时附带的用法说明
+func parsePgrepArgs(cmdName string, args []string) (pgrepOptions, error) {
+	opts := pgrepOptions{delimiter: "\n", signal: defaultSignal}
+	usage := i18n.T("用法: pgrep [-acfilnovx] [-d 分隔符] [-P ppid列表] 模式")
+	flags := "acdfilnoPvx"
+	if cmdName == "pkill" {
+		usage = i18n.T("用法: pkill [-信号] [-efinovx] [-P ppid列表] 模式")
+		flags = "efinoPvx"
+	}
+	var patterns []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			patterns = append(patterns, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if cmdName == "pkill" {
+			// -9、-KILL 等指定信号
+			if c := arg[1]; c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' && c != 'P' {
+				sig, err := parseSignal(arg[1:])
+				if err != nil {
+					return opts, fmt.Errorf("%s: %v\n%s", cmdName, err, usage)
+				}
+				opts.signal = sig
+				continue
+			}
+			if value, ok := strings.CutPrefix(arg, "--signal="); ok || arg == "--signal" {
+				if !ok {
+					if i+1 >= len(args) {
+						return opts, i18n.Errorf("%s: --signal 缺少参数", cmdName)
+					}
+					i++
+					value = args[i]
+				}
+				sig, err := parseSignal(value)
+				if err != nil {
+					return opts, fmt.Errorf("%s: %v", cmdName, err)
+				}
+				opts.signal = sig
+				continue
+			}
+		}
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			if strings.IndexByte(flags, flag) < 0 {
+				return opts, i18n.Errorf("%s: -%c: 无效选项\n%s", cmdName, flag, usage)
+			}
+			switch flag {
+			case 'a':
+				opts.listFull = true
+			case 'c':
+				opts.count = true
+			case 'e':
+				opts.echo = true
+			case 'f':
+				opts.full = true
+			case 'i':
+				opts.ignoreCase = true
+			case 'l':
+				opts.list = true
+			case 'n':
+				opts.newest = true
+			case 'o':
+				opts.oldest = true
+			case 'v':
+				opts.invert = true
+			case 'x':
+				opts.exact = true
+			case 'd', 'P':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return opts, i18n.Errorf("%s: -%c 缺少参数", cmdName, flag)
+					}
+					i++
+					value = args[i]
+				}
+				if flag == 'd' {
+					opts.delimiter = value
+				} else {
+					list, err := parsePidList(value)
+					if err != nil {
+						return opts, fmt.Errorf("%s: %v", cmdName, err)
+					}
+					opts.parents = append(opts.parents, list...)
+				}
+				j = len(arg)
+			}
+		}
+	}
+
+	switch {
+	case len(patterns) > 1:
+		return opts, i18n.Errorf("%s: 只能指定一个模式\n%s", cmdName, usage)
+	case len(patterns) == 0 && len(opts.parents) == 0:
+		return opts, i18n.Errorf("%s: 没有指定匹配条件\n%s", cmdName, usage)
+	case opts.newest && opts.oldest:
+		return opts, i18n.Errorf("%s: -n 和 -o 不能同时使用", cmdName)
+	}
+	if len(patterns) == 1 {
+		opts.pattern = patterns[0]
+	}
+	return opts, nil
+}
+
+// matchProcesses 返回匹配 pgrep/pkill 选项的进程（不包括 shell 自身）
+func matchProcesses(opts pgrepOptions) ([]processInfo, error) {
+	pattern := opts.pattern
+	if opts.exact {
+		pattern = "^(?:" + pattern + ")$"
+	}
+	if opts.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, i18n.Errorf("无效的模式 %q: %v", opts.pattern, err)
+	}
+	procs, err := listProcesses()
+	if err != nil {
+		return nil, i18n.Errorf("无法获取进程列表: %v", err)
+	}
+
+	self := os.Getpid()
+	var matched []processInfo
+	for _, p := range procs {
+		if p.pid == self || len(opts.parents) > 0 && !containsPid(opts.parents, p.ppid) {
+			continue
+		}
+		text := p.name
+		if opts.full {
+			text = p.cmdline
+		}
+		if re.MatchString(text) != opts.invert {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) > 0 && (opts.newest || opts.oldest) {
+		sort.Slice(matched, func(i, j int) bool { return matched[i].pid < matched[j].pid })
+		if opts.newest {
+			matched = matched[len(matched)-1:]
+		} else {
+			matched = matched[:1]
+		}
+	}
+	return matched, nil
+}
+
+// pgrep 按名称查找进程并输出进程号
+// 用法：pgrep [-acfilnovx] [-d 分隔符] [-P ppid列表] 模式
+// 模式是匹配进程名的正则表达式（-f 匹配完整命令行）；-l/-a 同时输出进程名/命令行，-c 只输出个数；
+// 有匹配时退出状态为 0，没有匹配为 1，参数错误为 2
+func pgrep(args []string, env map[string]string, stdio *IO) error {
+	opts, err := parsePgrepArgs("pgrep", args)
+	if err != nil {
+		return &StatusError{Code: 2, Message: err.Error()}
+	}
+	matched, err := matchProcesses(opts)
+	if err != nil {
+		return &StatusError{Code: 2, Message: "pgrep: " + err.Error()}
+	}
+
+	if opts.count {
+		fmt.Fprintln(stdio.Stdout, len(matched))
+	} else {
+		items := make([]string, len(matched))
+		for i, p := range matched {
+			items[i] = strconv.Itoa(p.pid)
+			switch {
+			case opts.listFull:
+				items[i] += " " + p.cmdline
+			case opts.list:
+				items[i] += " " + p.name
+			}
+		}
+		if len(items) > 0 {
+			fmt.Fprintln(stdio.Stdout, strings.Join(items, opts.delimiter))
+		}
+	}
+	if len(matched) == 0 {
+		return &StatusError{Code: 1, Message: i18n.T("pgrep: 没有匹配的进程")}
+	}
+	return nil
+}
+
+// pkill 按名称查找进程并发送信号（默认 TERM）
+// 用法：pkill [-信号] [-efinovx] [-P ppid列表] 模式
+// 匹配规则与 pgrep 相同，信号可以是名称或编号（如 -9、-KILL、--signal HUP），-e 输出被终止的进程；
+// 至少向一个进程发送了信号时退出状态为 0，没有匹配为 1，参数错误为 2
+func pkill(args []string, env map[string]string, stdio *IO) error {
+	opts, err := parsePgrepArgs("pkill", args)
+	if err != nil {
+		return &StatusError{Code: 2, Message: err.Error()}
+	}
+	matched, err := matchProcesses(opts)
+	if err != nil {
+		return &StatusError{Code: 2, Message: "pkill: " + err.Error()}
+	}
+
+	var errs []string
+	signaled := 0
+	for _, p := range matched {
+		if err := sendSignal(p.pid, opts.signal); err != nil {
+			errs = append(errs, i18n.Sprintf("pkill: 无法终止进程 %d: %v", p.pid, err))
+			continue
+		}
+		signaled++
+		if opts.echo {
+			fmt.Fprintf(stdio.Stdout, "%s killed (pid %d)\n", p.name, p.pid)
+		}
+	}
+	switch {
+	case signaled == 0 && len(errs) > 0:
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	case signaled == 0:
+		return &StatusError{Code: 1, Message: i18n.T("pkill: 没有匹配的进程")}
+	case len(errs) > 0:
+		fmt.Fprintln(stdio.Stderr, strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// parsePidList 解析逗号或空格分隔的进程号列表
+func parsePidList(s string) ([]int, error) {
+	var pids []int
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		pid, err := strconv.Atoi(field)
+		if err != nil || pid < 0 {
+			return nil, i18n.Errorf("无效的进程号: %q", field)
+		}
+		pids = append(pids, pid)
+	}
+	if len(pids) == 0 {
+		return nil, i18n.Errorf("进程号列表为空")
+	}
+	return pids, nil
+}
+
+// containsPid 检查进程号是否在列表中
+func containsPid(pids []int, pid int) bool {
+	for _, p := range pids {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package builtin
+
+import (
+	"gobash/internal/i18n"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listProcesses 从 /proc 读取所有进程，按进程号排序
+func listProcesses() ([]processInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var procs []processInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		// 读取期间退出的进程直接跳过
+		p, err := readProcess(pid)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, p)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].pid < procs[j].pid })
+	return procs, nil
+}
+
+// readProcess 从 /proc/<pid>/stat 和 /proc/<pid>/cmdline 读取进程信息
+func readProcess(pid int) (processInfo, error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	stat, err := os.ReadFile(dir + "/stat")
+	if err != nil {
+		return processInfo{}, err
+	}
+	// 格式为 "pid (名称) 状态 ppid ..."，名称中可能有空格和括号，以最后一个 ) 为准
+	s := string(stat)
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return processInfo{}, i18n.Errorf("%s/stat 格式错误", dir)
+	}
+	fields := strings.Fields(s[end+1:])
+	if len(fields) < 2 {
+		return processInfo{}, i18n.Errorf("%s/stat 格式错误", dir)
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	p := processInfo{pid: pid, ppid: ppid, name: s[open+1 : end]}
+
+	// 命令行参数以 NUL 分隔；内核线程没有命令行，与 ps 一样显示为 [名称]
+	cmdline, _ := os.ReadFile(dir + "/cmdline")
+	p.cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	if p.cmdline == "" {
+		p.cmdline = "[" + p.name + "]"
+	}
+	return p, nil
+}
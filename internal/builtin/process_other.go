@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package builtin
+
+// listProcesses 当前平台没有 /proc，不支持列出进程
+func listProcesses() ([]processInfo, error) {
+	return nil, errUnsupported
+}
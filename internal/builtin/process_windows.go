@@ -0,0 +1,38 @@
+package builtin
+
+import (
+	"sort"
+	"syscall"
+	"unsafe"
+)
+
+// listProcesses 用 CreateToolhelp32Snapshot 获取所有进程，按进程号排序
+// Windows 上无法直接获取其他进程的命令行，命令行与进程名（可执行文件名）相同
+func listProcesses() ([]processInfo, error) {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	if err := syscall.Process32First(snapshot, &entry); err != nil {
+		return nil, err
+	}
+	var procs []processInfo
+	for {
+		name := syscall.UTF16ToString(entry.ExeFile[:])
+		procs = append(procs, processInfo{
+			pid:     int(entry.ProcessID),
+			ppid:    int(entry.ParentProcessID),
+			name:    name,
+			cmdline: name,
+		})
+		if err := syscall.Process32Next(snapshot, &entry); err != nil {
+			break
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].pid < procs[j].pid })
+	return procs, nil
+}
@@ -0,0 +1,305 @@
+package builtin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gobash/internal/i18n"
+)
+
+// readUsage read 命令的用法说明
+const readUsage = "用法: read [-r] [-a 数组] [-d 分隔符] [-n 字符数] [-N 字符数] [-p 提示符] [-u 文件描述符] [名称 ...]"
+
+// ReadBuiltin 返回使用变量表 vars 和 assign 给变量赋值的 read 命令
+// 执行器用 assign 处理名称引用和数组元素，-a 把字段存入 vars.Arrays()；assign 为 nil 时直接写入 env。
+// fd 返回 shell 打开的文件描述符（如 ${COPROC[0]}），供 -u 读取；fd 为 nil 时 -u 只能使用 0
+func ReadBuiltin(vars Variables, assign func(name, value string) error, fd func(n int) (io.Reader, bool)) BuiltinFunc {
+	if vars.Arrays == nil {
+		arrays := make(map[string][]string)
+		vars.Arrays = func() map[string][]string { return arrays }
+	}
+	if vars.ArrayIndices == nil {
+		vars.ArrayIndices = func() map[string][]int { return nil }
+	}
+	return func(args []string, env map[string]string, stdio *IO) error {
+		set := assign
+		if set == nil {
+			set = func(name, value string) error {
+				env[name] = value
+				return nil
+			}
+		}
+		return readCmd(vars, set, fd, args, env, stdio)
+	}
+}
+
+// read 使用空变量表的 read 命令（执行器会用 ReadBuiltin 替换它）
+var read = ReadBuiltin(Variables{}, nil, nil)
+
+// readOptions read 命令的选项
+type readOptions struct {
+	raw    bool   // -r：反斜杠不是转义字符
+	array  string // -a：把字段依次存入数组
+	delim  byte   // -d：行结束符（默认换行，-d '' 是 NUL）
+	nchars int    // -n/-N：最多读取的字符数，-1 表示不限制
+	exact  bool   // -N：读满 nchars 个字符，忽略行结束符，不分割字段
+	prompt string // -p：从终端读取时先输出的提示符
+	fd     string // -u：从这个文件描述符读取，为空时读取标准输入
+}
+
+// readCmd 从标准输入读取一行，按 IFS 分割为字段赋给变量
+// 用法：read [-r] [-a 数组] [-d 分隔符] [-n 字符数] [-N 字符数] [-p 提示符] [-u 文件描述符] [名称 ...]
+// 第一个字段赋给第一个名称，依此类推，最后一个名称得到剩余的部分；没有名称时整行（不去掉空白）赋给 REPLY。
+// 没有 -r 时反斜杠转义下一个字符（被转义的字符不分割字段），反斜杠加换行是续行。
+// 每次只读取一个字节，不会多读行结束符之后的内容，这样循环中的其他命令可以继续读取同一个标准输入；
+// 遇到文件结束时仍然给变量赋值，退出状态为 1
+func readCmd(vars Variables, assign func(name, value string) error, fd func(n int) (io.Reader, bool), args []string, env map[string]string, stdio *IO) error {
+	opts := readOptions{delim: '\n', nchars: -1}
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			if flag == 'r' {
+				opts.raw = true
+				continue
+			}
+			if !strings.ContainsRune("adnNpu", rune(flag)) {
+				return &StatusError{Code: 2, Message: i18n.Sprintf("read: -%c: 无效选项\n%s", flag, i18n.T(readUsage))}
+			}
+			// 选项的值可以紧跟在选项后面（-n3）或者是下一个参数（-n 3）
+			value := arg[j+1:]
+			if value == "" {
+				if i+1 >= len(args) {
+					return &StatusError{Code: 2, Message: i18n.Sprintf("read: -%c: 需要参数\n%s", flag, i18n.T(readUsage))}
+				}
+				i++
+				value = args[i]
+			}
+			switch flag {
+			case 'a':
+				opts.array = value
+			case 'd':
+				opts.delim = 0
+				if value != "" {
+					opts.delim = value[0]
+				}
+			case 'n', 'N':
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return i18n.Errorf("read: %s: 无效的字符数", value)
+				}
+				opts.nchars, opts.exact = n, flag == 'N'
+			case 'p':
+				opts.prompt = value
+			case 'u':
+				opts.fd = value
+			}
+			break
+		}
+	}
+	names := args[i:]
+	if opts.array != "" {
+		names = []string{opts.array}
+	}
+	for _, name := range names {
+		if !isAssignable(name) {
+			return i18n.Errorf("read: `%s': 不是有效的标识符", name)
+		}
+	}
+
+	input := stdio.Stdin
+	if opts.fd != "" {
+		n, err := strconv.Atoi(opts.fd)
+		var ok bool
+		switch {
+		case err != nil || n < 0:
+		case n == 0:
+			ok = true
+		case fd != nil:
+			input, ok = fd(n)
+		}
+		if !ok {
+			return i18n.Errorf("read: %s: 无效的文件描述符", opts.fd)
+		}
+	}
+	if opts.prompt != "" && isTerminal(input) {
+		fmt.Fprint(stdio.Stderr, opts.prompt)
+	}
+	chars, escaped, err := readInput(input, opts)
+	if err != nil && err != io.EOF {
+		return i18n.Errorf("read: 读取错误: %v", err)
+	}
+
+	ifs, ok := env["IFS"]
+	if !ok {
+		ifs = " \t\n"
+	}
+	switch {
+	case opts.array != "":
+		fields := splitReadFields(chars, escaped, ifs, -1)
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			values[i] = string(field)
+		}
+		vars.Arrays()[opts.array] = values
+		delete(vars.ArrayIndices(), opts.array)
+		delete(env, opts.array)
+	case len(names) == 0:
+		// REPLY 得到整行，不去掉空白
+		if err := assign("REPLY", string(chars)); err != nil {
+			return err
+		}
+	case opts.exact:
+		if err := assign(names[0], string(chars)); err != nil {
+			return err
+		}
+		for _, name := range names[1:] {
+			if err := assign(name, ""); err != nil {
+				return err
+			}
+		}
+	default:
+		fields := splitReadFields(chars, escaped, ifs, len(names))
+		for i, name := range names {
+			value := ""
+			if i < len(fields) {
+				value = string(fields[i])
+			}
+			if err := assign(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	if err == io.EOF {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
+
+// readInput 从 r 中逐个字节读取一行，返回读到的字符和每个字符是否被反斜杠转义
+// 到达行结束符、读满 -n/-N 指定的字符数时返回；在此之前遇到文件结束时返回 io.EOF
+func readInput(r io.Reader, opts readOptions) ([]rune, []bool, error) {
+	var chars []rune
+	var escaped []bool
+	for opts.nchars < 0 || len(chars) < opts.nchars {
+		c, err := readRune(r)
+		if err != nil {
+			return chars, escaped, err
+		}
+		if !opts.exact && len(c) == 1 && c[0] == opts.delim {
+			return chars, escaped, nil
+		}
+		if !opts.raw && len(c) == 1 && c[0] == '\\' {
+			c, err = readRune(r)
+			if err != nil {
+				return chars, escaped, err
+			}
+			if len(c) == 1 && c[0] == '\n' {
+				// 续行：反斜杠和换行都去掉
+				continue
+			}
+			chars = append(chars, decodeRune(c))
+			escaped = append(escaped, true)
+			continue
+		}
+		chars = append(chars, decodeRune(c))
+		escaped = append(escaped, false)
+	}
+	return chars, escaped, nil
+}
+
+// readRune 从 r 中读取一个 UTF-8 字符的所有字节（每次一个字节），无效的字节单独返回
+func readRune(r io.Reader) ([]byte, error) {
+	buf := make([]byte, 1, utf8.UTFMax)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	for !utf8.FullRune(buf) {
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(r, b); err != nil {
+			break
+		}
+		buf = append(buf, b[0])
+	}
+	return buf, nil
+}
+
+// decodeRune 把 readRune 读到的字节转换为字符，无效的字节按 Latin-1 保留
+func decodeRune(b []byte) rune {
+	if r, size := utf8.DecodeRune(b); r != utf8.RuneError || size > 1 {
+		return r
+	}
+	return rune(b[0])
+}
+
+// splitReadFields 按 IFS 把读到的字符分割为字段（被转义的字符不是分隔符）
+// n > 0 时最多分割出 n 个字段，最后一个字段是剩余的部分（去掉首尾的 IFS 空白；剩余部分只有一个字段时
+// 还去掉它后面的分隔符）；n < 0 时分割出所有字段。IFS 为空时不分割，也不去掉空白
+func splitReadFields(chars []rune, escaped []bool, ifs string, n int) [][]rune {
+	if ifs == "" {
+		return [][]rune{chars}
+	}
+	isSpace := func(i int) bool {
+		return !escaped[i] && strings.ContainsRune(ifs, chars[i]) && strings.ContainsRune(" \t\n", chars[i])
+	}
+	isDelim := func(i int) bool {
+		return !escaped[i] && strings.ContainsRune(ifs, chars[i])
+	}
+
+	var fields [][]rune
+	i := 0
+	for i < len(chars) && isSpace(i) {
+		i++
+	}
+	for i < len(chars) {
+		if n > 0 && len(fields) == n-1 {
+			rest, restEscaped := chars[i:], escaped[i:]
+			end := len(rest)
+			for end > 0 && !restEscaped[end-1] && strings.ContainsRune(ifs, rest[end-1]) && strings.ContainsRune(" \t\n", rest[end-1]) {
+				end--
+			}
+			if sub := splitReadFields(rest[:end], restEscaped[:end], ifs, -1); len(sub) == 1 {
+				return append(fields, sub[0])
+			}
+			return append(fields, rest[:end])
+		}
+		start := i
+		for i < len(chars) && !isDelim(i) {
+			i++
+		}
+		fields = append(fields, chars[start:i])
+		// 一个分隔符是连续的 IFS 空白，其中可以有一个非空白的 IFS 字符
+		for i < len(chars) && isSpace(i) {
+			i++
+		}
+		if i < len(chars) && isDelim(i) && !isSpace(i) {
+			i++
+			for i < len(chars) && isSpace(i) {
+				i++
+			}
+		}
+	}
+	return fields
+}
+
+// isTerminal 判断 r 是否是终端（read -p 只在从终端读取时输出提示符）
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
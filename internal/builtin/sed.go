@@ -0,0 +1,554 @@
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sedAddress sed 命令的地址：行号、$（最后一行）或 /正则表达式/
+type sedAddress struct {
+	line int
+	last bool
+	re   *regexp.Regexp
+}
+
+// sedCommand 一条 sed 命令
+type sedCommand struct {
+	addr1, addr2 *sedAddress
+	negate       bool // 地址后的 !：对不匹配的行执行
+	name         byte // 命令：s、p、d、q、=
+	inRange      bool // 地址范围是否已经开始
+	rangeEnd     int  // addr2 是行号时，范围结束的行号
+
+	// s 命令
+	re          *regexp.Regexp
+	replacement string
+	global      bool // g 替换所有匹配
+	occurrence  int  // N 从第 N 个匹配开始替换
+	print       bool // p 替换后输出
+}
+
+// sed 流编辑器
+// 用法：sed [-nEr] [-i[后缀]] [-e 脚本]... [脚本] [文件...]
+// 支持 s/正则/替换/[gpiN]、p、d、q、= 命令，地址可以是行号、$、/正则/ 以及 地址1,地址2 范围，地址后加 ! 表示取反；
+// 多条命令用 ; 或换行分隔；默认使用基本正则表达式（BRE），-E/-r 使用扩展正则表达式
+func sed(args []string, env map[string]string, stdio *IO) error {
+	quiet := false
+	extended := false
+	inPlace := false
+	backupSuffix := ""
+	var scripts []string
+	var files []string
+	hasScript := false
+
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			if !hasScript {
+				scripts = append(scripts, arg)
+				hasScript = true
+			} else {
+				files = append(files, arg)
+			}
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if arg == "--quiet" || arg == "--silent" {
+			quiet = true
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch arg[j] {
+			case 'n':
+				quiet = true
+			case 'E', 'r':
+				extended = true
+			case 'i':
+				// -i后缀：编辑前把原文件备份为 文件名+后缀
+				inPlace = true
+				backupSuffix = arg[j+1:]
+				j = len(arg)
+			case 'e':
+				script := arg[j+1:]
+				if script == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("sed: -e 缺少参数")
+					}
+					i++
+					script = args[i]
+				}
+				scripts = append(scripts, script)
+				hasScript = true
+				j = len(arg)
+			default:
+				return i18n.Errorf("sed: -%c: 无效选项\n用法: sed [-nEr] [-i[后缀]] [-e 脚本]... [脚本] [文件...]", arg[j])
+			}
+		}
+	}
+	if !hasScript {
+		return i18n.Errorf("sed: 缺少脚本\n用法: sed [-nEr] [-i[后缀]] [-e 脚本]... [脚本] [文件...]")
+	}
+
+	commands, err := parseSedScript(strings.Join(scripts, "\n"), extended)
+	if err != nil {
+		return err
+	}
+
+	if inPlace {
+		if len(files) == 0 {
+			return i18n.Errorf("sed: -i 需要指定文件")
+		}
+		// 每个文件单独编辑，结果写回文件
+		for _, file := range files {
+			path := resolvePath(env, file)
+			err := stdio.checkPath("open", AccessRead, path)
+			if err == nil {
+				err = stdio.checkPath("open", AccessWrite, path)
+			}
+			var data []byte
+			if err == nil {
+				data, err = os.ReadFile(path)
+			}
+			if err != nil {
+				return &StatusError{Code: 2, Message: i18n.Sprintf("sed: 无法读取 %s: %v", file, pathErrorReason(err))}
+			}
+			if backupSuffix != "" {
+				if err := stdio.checkPath("open", AccessWrite, path+backupSuffix); err != nil {
+					return fmt.Errorf("sed: %v", err)
+				}
+				if err := os.WriteFile(path+backupSuffix, data, 0644); err != nil {
+					return fmt.Errorf("sed: %v", err)
+				}
+			}
+			var out bytes.Buffer
+			runSed(freshSedCommands(commands), []io.Reader{bytes.NewReader(data)}, quiet, &out)
+			if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+				return fmt.Errorf("sed: %v", err)
+			}
+		}
+		return nil
+	}
+
+	// 所有输入文件连接成一个输入流
+	var inputs []io.Reader
+	var errs []string
+	if len(files) == 0 {
+		inputs = append(inputs, stdio.Stdin)
+	}
+	for _, file := range files {
+		if file == "-" {
+			inputs = append(inputs, stdio.Stdin)
+			continue
+		}
+		f, err := stdio.open(resolvePath(env, file))
+		if err != nil {
+			errs = append(errs, i18n.Sprintf("sed: 无法读取 %s: %v", file, pathErrorReason(err)))
+			continue
+		}
+		defer f.Close()
+		inputs = append(inputs, f)
+	}
+	runSed(commands, inputs, quiet, stdio.Stdout)
+	if len(errs) > 0 {
+		return &StatusError{Code: 2, Message: strings.Join(errs, "\n")}
+	}
+	return nil
+}
+
+// freshSedCommands 复制命令并清除地址范围的状态（-i 时每个文件重新开始）
+func freshSedCommands(commands []*sedCommand) []*sedCommand {
+	fresh := make([]*sedCommand, len(commands))
+	for i, cmd := range commands {
+		c := *cmd
+		c.inRange = false
+		fresh[i] = &c
+	}
+	return fresh
+}
+
+// runSed 对输入逐行执行命令
+func runSed(commands []*sedCommand, inputs []io.Reader, quiet bool, out io.Writer) {
+	reader := bufio.NewReader(io.MultiReader(inputs...))
+	next, hasNext := readSedLine(reader)
+	for lineNum := 1; hasNext; lineNum++ {
+		pattern := next
+		next, hasNext = readSedLine(reader)
+		last := !hasNext
+
+		deleted, quit := false, false
+		for _, cmd := range commands {
+			if !cmd.matches(pattern, lineNum, last) {
+				continue
+			}
+			switch cmd.name {
+			case 's':
+				var replaced bool
+				pattern, replaced = cmd.substitute(pattern)
+				if replaced && cmd.print {
+					fmt.Fprintln(out, pattern)
+				}
+			case 'p':
+				fmt.Fprintln(out, pattern)
+			case '=':
+				fmt.Fprintln(out, lineNum)
+			case 'd':
+				deleted = true
+			case 'q':
+				quit = true
+			}
+			if deleted || quit {
+				break
+			}
+		}
+		if !deleted && !quiet {
+			fmt.Fprintln(out, pattern)
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// readSedLine 读取一行（不含换行符），没有更多输入时第二个返回值为 false
+func readSedLine(reader *bufio.Reader) (string, bool) {
+	line, err := reader.ReadString('\n')
+	if line == "" && err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(line, "\n"), true
+}
+
+// matches 检查命令的地址是否选中当前行
+func (cmd *sedCommand) matches(line string, lineNum int, last bool) bool {
+	var selected bool
+	switch {
+	case cmd.addr1 == nil:
+		selected = true
+	case cmd.addr2 == nil:
+		selected = cmd.addr1.matches(line, lineNum, last)
+	case cmd.inRange:
+		selected = true
+		switch {
+		case cmd.addr2.re != nil:
+			cmd.inRange = !cmd.addr2.matches(line, lineNum, last)
+		case cmd.addr2.last:
+			cmd.inRange = !last
+		default:
+			cmd.inRange = lineNum < cmd.rangeEnd
+		}
+	case cmd.addr1.matches(line, lineNum, last):
+		selected = true
+		// 地址2 是行号时，小于等于当前行号则范围只有一行
+		switch {
+		case cmd.addr2.last:
+			cmd.inRange = !last
+		case cmd.addr2.re != nil:
+			cmd.inRange = true
+		default:
+			cmd.rangeEnd = cmd.addr2.line
+			cmd.inRange = lineNum < cmd.rangeEnd
+		}
+	}
+	return selected != cmd.negate
+}
+
+// matches 检查地址是否匹配当前行
+func (a *sedAddress) matches(line string, lineNum int, last bool) bool {
+	switch {
+	case a.last:
+		return last
+	case a.re != nil:
+		return a.re.MatchString(line)
+	}
+	return a.line == lineNum
+}
+
+// substitute 执行 s 命令，返回替换后的内容以及是否有替换
+func (cmd *sedCommand) substitute(line string) (string, bool) {
+	matches := cmd.re.FindAllStringSubmatchIndex(line, -1)
+	var sb strings.Builder
+	pos := 0
+	replaced := false
+	for i, match := range matches {
+		n := i + 1
+		if n < cmd.occurrence || (!cmd.global && n > cmd.occurrence) {
+			continue
+		}
+		sb.WriteString(line[pos:match[0]])
+		sb.WriteString(expandSedReplacement(cmd.replacement, line, match))
+		pos = match[1]
+		replaced = true
+	}
+	if !replaced {
+		return line, false
+	}
+	sb.WriteString(line[pos:])
+	return sb.String(), true
+}
+
+// expandSedReplacement 展开替换文本中的 &（整个匹配）、\1 到 \9（分组）和 \n（换行符）
+func expandSedReplacement(replacement, line string, match []int) string {
+	var sb strings.Builder
+	for i := 0; i < len(replacement); i++ {
+		c := replacement[i]
+		switch {
+		case c == '&':
+			sb.WriteString(line[match[0]:match[1]])
+		case c == '\\' && i+1 < len(replacement):
+			i++
+			next := replacement[i]
+			switch {
+			case next >= '1' && next <= '9':
+				group := int(next - '0')
+				if 2*group+1 < len(match) && match[2*group] >= 0 {
+					sb.WriteString(line[match[2*group]:match[2*group+1]])
+				}
+			case next == 'n':
+				sb.WriteByte('\n')
+			case next == 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(next)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// sedParser 解析 sed 脚本
+type sedParser struct {
+	script   string
+	pos      int
+	extended bool
+}
+
+// parseSedScript 解析 sed 脚本，命令之间用 ; 或换行分隔
+func parseSedScript(script string, extended bool) ([]*sedCommand, error) {
+	p := &sedParser{script: script, extended: extended}
+	var commands []*sedCommand
+	for {
+		p.skip(" \t\n;")
+		if p.pos >= len(p.script) {
+			return commands, nil
+		}
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+}
+
+// skip 跳过 chars 中的字符
+func (p *sedParser) skip(chars string) {
+	for p.pos < len(p.script) && strings.IndexByte(chars, p.script[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// errorf 返回带有出错位置的解析错误
+func (p *sedParser) errorf(format string, args ...interface{}) error {
+	return i18n.Errorf("sed: 字符 %d: %s", p.pos+1, i18n.Sprintf(format, args...))
+}
+
+// parseCommand 解析一条命令：[地址1[,地址2]][!]命令
+func (p *sedParser) parseCommand() (*sedCommand, error) {
+	cmd := &sedCommand{}
+	var err error
+	if cmd.addr1, err = p.parseAddress(); err != nil {
+		return nil, err
+	}
+	if cmd.addr1 != nil && p.pos < len(p.script) && p.script[p.pos] == ',' {
+		p.pos++
+		if cmd.addr2, err = p.parseAddress(); err != nil {
+			return nil, err
+		}
+		if cmd.addr2 == nil {
+			return nil, p.errorf("缺少地址")
+		}
+	}
+	p.skip(" \t")
+	if p.pos < len(p.script) && p.script[p.pos] == '!' {
+		cmd.negate = true
+		p.pos++
+		p.skip(" \t")
+	}
+	if p.pos >= len(p.script) {
+		return nil, p.errorf("缺少命令")
+	}
+
+	cmd.name = p.script[p.pos]
+	p.pos++
+	switch cmd.name {
+	case 'p', 'd', 'q', '=':
+	case 's':
+		if err := p.parseSubstitute(cmd); err != nil {
+			return nil, err
+		}
+	default:
+		p.pos--
+		return nil, p.errorf("未知的命令: '%c'", cmd.name)
+	}
+
+	// 命令之后只能是分隔符
+	p.skip(" \t")
+	if p.pos < len(p.script) && p.script[p.pos] != ';' && p.script[p.pos] != '\n' {
+		return nil, p.errorf("命令后有多余的字符: '%c'", p.script[p.pos])
+	}
+	return cmd, nil
+}
+
+// parseAddress 解析地址，没有地址时返回 nil
+func (p *sedParser) parseAddress() (*sedAddress, error) {
+	if p.pos >= len(p.script) {
+		return nil, nil
+	}
+	c := p.script[p.pos]
+	switch {
+	case c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.script) && p.script[p.pos] >= '0' && p.script[p.pos] <= '9' {
+			p.pos++
+		}
+		line, _ := strconv.Atoi(p.script[start:p.pos])
+		if line == 0 {
+			return nil, p.errorf("无效的行号: 0")
+		}
+		return &sedAddress{line: line}, nil
+	case c == '$':
+		p.pos++
+		return &sedAddress{last: true}, nil
+	case c == '/' || c == '\\':
+		if c == '\\' {
+			// \c正则c：使用其他分隔符
+			p.pos++
+			if p.pos >= len(p.script) {
+				return nil, p.errorf("缺少分隔符")
+			}
+		}
+		delim := p.script[p.pos]
+		p.pos++
+		pattern, err := p.readDelimited(delim, true)
+		if err != nil {
+			return nil, err
+		}
+		ignoreCase := false
+		if p.pos < len(p.script) && p.script[p.pos] == 'I' {
+			ignoreCase = true
+			p.pos++
+		}
+		re, err := p.compile(pattern, ignoreCase)
+		if err != nil {
+			return nil, err
+		}
+		return &sedAddress{re: re}, nil
+	}
+	return nil, nil
+}
+
+// parseSubstitute 解析 s 命令的正则表达式、替换文本和标志
+func (p *sedParser) parseSubstitute(cmd *sedCommand) error {
+	if p.pos >= len(p.script) {
+		return p.errorf("s 命令缺少分隔符")
+	}
+	delim := p.script[p.pos]
+	if delim == '\\' || delim == '\n' {
+		return p.errorf("s 命令的分隔符无效")
+	}
+	p.pos++
+	pattern, err := p.readDelimited(delim, true)
+	if err != nil {
+		return err
+	}
+	if cmd.replacement, err = p.readDelimited(delim, false); err != nil {
+		return err
+	}
+
+	cmd.occurrence = 1
+	ignoreCase := false
+	for p.pos < len(p.script) {
+		c := p.script[p.pos]
+		switch {
+		case c == 'g':
+			cmd.global = true
+		case c == 'p':
+			cmd.print = true
+		case c == 'i' || c == 'I':
+			ignoreCase = true
+		case c >= '1' && c <= '9':
+			start := p.pos
+			for p.pos+1 < len(p.script) && p.script[p.pos+1] >= '0' && p.script[p.pos+1] <= '9' {
+				p.pos++
+			}
+			cmd.occurrence, _ = strconv.Atoi(p.script[start : p.pos+1])
+		default:
+			cmd.re, err = p.compile(pattern, ignoreCase)
+			return err
+		}
+		p.pos++
+	}
+	cmd.re, err = p.compile(pattern, ignoreCase)
+	return err
+}
+
+// readDelimited 读取到未转义的分隔符为止（跳过分隔符）
+// \分隔符 表示分隔符本身；isRegex 为 true 时保留其他转义，替换文本中也保留转义（由 expandSedReplacement 处理）
+func (p *sedParser) readDelimited(delim byte, isRegex bool) (string, error) {
+	var sb strings.Builder
+	for p.pos < len(p.script) {
+		c := p.script[p.pos]
+		switch {
+		case c == delim:
+			p.pos++
+			return sb.String(), nil
+		case c == '\\' && p.pos+1 < len(p.script):
+			next := p.script[p.pos+1]
+			if next == delim {
+				sb.WriteByte(delim)
+			} else if next == 'n' && isRegex {
+				sb.WriteByte('\n')
+			} else {
+				sb.WriteByte('\\')
+				sb.WriteByte(next)
+			}
+			p.pos += 2
+		case c == '[' && isRegex:
+			// 方括号表达式中的分隔符不结束正则表达式
+			end := bracketEnd(p.script, p.pos)
+			sb.WriteString(p.script[p.pos:end])
+			p.pos = end
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("缺少结束的分隔符 '%c'", delim)
+}
+
+// compile 编译正则表达式，默认是基本正则表达式
+func (p *sedParser) compile(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	if !p.extended {
+		pattern = convertBRE(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, i18n.Errorf("sed: 无效的正则表达式: %v", err)
+	}
+	return re, nil
+}
@@ -0,0 +1,188 @@
+package builtin
+
+import (
+	"bufio"
+	"fmt"
+	"gobash/internal/i18n"
+	"strconv"
+	"strings"
+)
+
+// seq 输出数字序列
+// 用法：seq [-w] [-s 分隔符] [-f 格式] [起始 [步长]] 结束
+// 起始和步长默认为 1，可以是负数和小数（输出的小数位数与参数中最多的相同）；
+// -w 用前导 0 使所有数字等宽，-s 指定数字之间的分隔符（默认换行），-f 使用 printf 风格的浮点数格式（如 %.2f）
+func seq(args []string, env map[string]string, stdio *IO) error {
+	equalWidth := false
+	separator := "\n"
+	format := ""
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		// 负数是操作数而不是选项
+		if len(operands) > 0 || !strings.HasPrefix(arg, "-") || len(arg) == 1 || isSeqNumber(arg) {
+			operands = append(operands, arg)
+			continue
+		}
+		if arg == "--" {
+			operands = append(operands, args[i+1:]...)
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--separator="); ok {
+			separator = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--format="); ok {
+			format = value
+			continue
+		}
+		if arg == "--equal-width" {
+			equalWidth = true
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'w':
+				equalWidth = true
+			case 's', 'f':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("seq: -%c 缺少参数", flag)
+					}
+					i++
+					value = args[i]
+				}
+				if flag == 's' {
+					separator = value
+				} else {
+					format = value
+				}
+				j = len(arg)
+			default:
+				return i18n.Errorf("seq: -%c: 无效选项\n用法: seq [-w] [-s 分隔符] [-f 格式] [起始 [步长]] 结束", flag)
+			}
+		}
+	}
+
+	switch {
+	case len(operands) == 0:
+		return i18n.Errorf("seq: 缺少操作数\n用法: seq [-w] [-s 分隔符] [-f 格式] [起始 [步长]] 结束")
+	case len(operands) > 3:
+		return i18n.Errorf("seq: 多余的操作数: %s", operands[3])
+	case equalWidth && format != "":
+		return i18n.Errorf("seq: -w 和 -f 不能同时使用")
+	}
+	if format != "" {
+		if err := checkSeqFormat(format); err != nil {
+			return err
+		}
+	}
+
+	// 解析起始、步长和结束，记录最多的小数位数
+	values := []float64{1, 1, 0}
+	precision := 0
+	positions := map[int][]int{1: {2}, 2: {0, 2}, 3: {0, 1, 2}}[len(operands)]
+	for i, operand := range operands {
+		value, err := strconv.ParseFloat(operand, 64)
+		if err != nil || !isSeqNumber(strings.TrimPrefix(operand, "+")) {
+			return i18n.Errorf("seq: 无效的浮点数参数: %q", operand)
+		}
+		values[positions[i]] = value
+		if dot := strings.IndexByte(operand, '.'); dot >= 0 && len(operand)-dot-1 > precision {
+			precision = len(operand) - dot - 1
+		}
+	}
+	first, step, last := values[0], values[1], values[2]
+	if step == 0 {
+		return i18n.Errorf("seq: 步长不能为 0: %q", operands[1])
+	}
+
+	formatNumber := func(v float64) string {
+		if format != "" {
+			return fmt.Sprintf(format, v)
+		}
+		if v == 0 {
+			v = 0 // 避免输出 -0
+		}
+		return strconv.FormatFloat(v, 'f', precision, 64)
+	}
+	width := 0
+	if equalWidth {
+		width = max(len(formatNumber(first)), len(formatNumber(last)))
+	}
+
+	writer := bufio.NewWriter(stdio.Stdout)
+	defer writer.Flush()
+	count := 0
+	for i := 0; ; i++ {
+		// 用 first + i*step 计算，避免小数累加的误差；比较结束值时允许一点浮点误差（如 0.1+2*0.1）
+		v := first + float64(i)*step
+		if diff := (v - last) / step; diff > 1e-9 {
+			break
+		}
+		s := formatNumber(v)
+		if len(s) < width {
+			// 前导 0 放在负号之后
+			sign := ""
+			if strings.HasPrefix(s, "-") {
+				sign, s = "-", s[1:]
+			}
+			s = sign + strings.Repeat("0", width-len(s)-len(sign)) + s
+		}
+		if count > 0 {
+			writer.WriteString(separator)
+		}
+		writer.WriteString(s)
+		count++
+	}
+	if count > 0 {
+		writer.WriteString("\n")
+	}
+	return nil
+}
+
+// isSeqNumber 检查参数是否是数字（用于区分负数和选项）
+func isSeqNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" || s == "." {
+		return false
+	}
+	dot := false
+	for _, c := range s {
+		switch {
+		case c == '.' && !dot:
+			dot = true
+		case c < '0' || c > '9':
+			return false
+		}
+	}
+	return true
+}
+
+// checkSeqFormat 检查 -f 格式中只有一个浮点数转换（%e、%f、%g）
+func checkSeqFormat(format string) error {
+	conversions := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0123456789.", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) || strings.IndexByte("eEfFgG", format[j]) < 0 {
+			return i18n.Errorf("seq: 格式 %q 中的转换无效，只支持 %%e、%%f、%%g", format)
+		}
+		conversions++
+		i = j
+	}
+	if conversions != 1 {
+		return i18n.Errorf("seq: 格式 %q 必须包含且只能包含一个浮点数转换", format)
+	}
+	return nil
+}
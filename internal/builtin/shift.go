@@ -2,6 +2,7 @@ package builtin
 
 import (
 	"fmt"
+	"gobash/internal/i18n"
 	"strconv"
 	"strings"
 )
@@ -9,15 +10,15 @@ import (
 // shift 移动位置参数
 // shift [n] - 将位置参数向左移动 n 个位置（默认为 1）
 // 例如：shift 2 会将 $3 变成 $1，$4 变成 $2，等等
-func shift(args []string, env map[string]string) error {
+func shift(args []string, env map[string]string, stdio *IO) error {
 	n := 1
 	if len(args) > 0 {
 		parsed, err := strconv.Atoi(args[0])
 		if err != nil {
-			return fmt.Errorf("shift: %s: 需要数字参数", args[0])
+			return i18n.Errorf("shift: %s: 需要数字参数", args[0])
 		}
 		if parsed < 0 {
-			return fmt.Errorf("shift: %d: 参数必须是正数", parsed)
+			return i18n.Errorf("shift: %d: 参数必须是正数", parsed)
 		}
 		n = parsed
 	}
@@ -31,7 +32,7 @@ func shift(args []string, env map[string]string) error {
 	}
 	
 	if n > argCount {
-		return fmt.Errorf("shift: %d: 不能移动超过参数个数 (%d)", n, argCount)
+		return i18n.Errorf("shift: %d: 不能移动超过参数个数 (%d)", n, argCount)
 	}
 	
 	// 移动位置参数：将 $n+1 变成 $1，$n+2 变成 $2，等等
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package builtin
+
+// signals 当前平台不支持信号
+var signals []signalInfo
+
+func sendSignal(pid, sig int) error {
+	return errUnsupported
+}
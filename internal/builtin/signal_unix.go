@@ -0,0 +1,61 @@
+//go:build unix
+
+package builtin
+
+import (
+	"errors"
+	"gobash/internal/i18n"
+	"sort"
+	"syscall"
+)
+
+// signals 支持的信号，按编号排序
+var signals = sortedSignals([]signalInfo{
+	{"HUP", int(syscall.SIGHUP)},
+	{"INT", int(syscall.SIGINT)},
+	{"QUIT", int(syscall.SIGQUIT)},
+	{"ILL", int(syscall.SIGILL)},
+	{"TRAP", int(syscall.SIGTRAP)},
+	{"ABRT", int(syscall.SIGABRT)},
+	{"BUS", int(syscall.SIGBUS)},
+	{"FPE", int(syscall.SIGFPE)},
+	{"KILL", int(syscall.SIGKILL)},
+	{"USR1", int(syscall.SIGUSR1)},
+	{"SEGV", int(syscall.SIGSEGV)},
+	{"USR2", int(syscall.SIGUSR2)},
+	{"PIPE", int(syscall.SIGPIPE)},
+	{"ALRM", int(syscall.SIGALRM)},
+	{"TERM", int(syscall.SIGTERM)},
+	{"CHLD", int(syscall.SIGCHLD)},
+	{"CONT", int(syscall.SIGCONT)},
+	{"STOP", int(syscall.SIGSTOP)},
+	{"TSTP", int(syscall.SIGTSTP)},
+	{"TTIN", int(syscall.SIGTTIN)},
+	{"TTOU", int(syscall.SIGTTOU)},
+	{"URG", int(syscall.SIGURG)},
+	{"XCPU", int(syscall.SIGXCPU)},
+	{"XFSZ", int(syscall.SIGXFSZ)},
+	{"VTALRM", int(syscall.SIGVTALRM)},
+	{"PROF", int(syscall.SIGPROF)},
+	{"WINCH", int(syscall.SIGWINCH)},
+	{"IO", int(syscall.SIGIO)},
+	{"SYS", int(syscall.SIGSYS)},
+})
+
+// sortedSignals 按编号排序信号表（各系统的信号编号不同）
+func sortedSignals(list []signalInfo) []signalInfo {
+	sort.Slice(list, func(i, j int) bool { return list[i].num < list[j].num })
+	return list
+}
+
+// sendSignal 向进程发送信号，pid 为负数时发送给进程组
+func sendSignal(pid, sig int) error {
+	err := syscall.Kill(pid, syscall.Signal(sig))
+	switch {
+	case errors.Is(err, syscall.ESRCH):
+		return i18n.NewError("没有那个进程")
+	case errors.Is(err, syscall.EPERM):
+		return i18n.NewError("不允许的操作")
+	}
+	return err
+}
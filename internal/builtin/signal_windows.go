@@ -0,0 +1,36 @@
+package builtin
+
+import (
+	"gobash/internal/i18n"
+	"os"
+	"syscall"
+)
+
+// signals Windows 上可以使用的信号，按编号排序
+var signals = []signalInfo{
+	{"HUP", int(syscall.SIGHUP)},
+	{"INT", int(syscall.SIGINT)},
+	{"QUIT", int(syscall.SIGQUIT)},
+	{"KILL", int(syscall.SIGKILL)},
+	{"TERM", int(syscall.SIGTERM)},
+}
+
+// sendSignal 向进程发送信号
+// Windows 没有信号，INT、KILL、TERM 等终止信号直接结束进程，信号 0 只检查进程是否存在
+func sendSignal(pid, sig int) error {
+	if pid <= 0 {
+		return i18n.Errorf("Windows 不支持进程组")
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return i18n.Errorf("没有那个进程")
+	}
+	defer p.Release()
+	if sig == 0 {
+		return nil
+	}
+	if _, ok := signalName(sig); !ok {
+		return i18n.Errorf("Windows 不支持信号 %d", sig)
+	}
+	return p.Kill()
+}
@@ -0,0 +1,85 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sleep 暂停指定的时间
+// 用法：sleep 时间[smhd]...
+// 时间可以是小数，后缀 s 表示秒（默认）、m 表示分钟、h 表示小时、d 表示天，多个时间相加；infinity 表示一直暂停
+// 执行被取消（如 timeout 超时）时立即返回；收到中断信号时以退出状态 130 结束
+func sleep(args []string, env map[string]string, stdio *IO) error {
+	if len(args) == 0 {
+		return i18n.Errorf("sleep: 缺少操作数\n用法: sleep 时间[smhd]...")
+	}
+	var total time.Duration
+	for _, arg := range args {
+		d, err := parseDurationArg(arg)
+		if err != nil {
+			return i18n.Errorf("sleep: 无效的时间间隔: %q", arg)
+		}
+		if total > math.MaxInt64-d {
+			total = math.MaxInt64
+		} else {
+			total += d
+		}
+	}
+
+	timer := time.NewTimer(total)
+	defer timer.Stop()
+	// 与前台外部命令一样，等待期间由 sleep 处理中断信号
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	ctx := stdio.ctx()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sleep: %w", ctx.Err())
+	case <-sigChan:
+		return &StatusError{Code: 130, Message: i18n.T("sleep: 被中断")}
+	}
+}
+
+// parseDurationArg 解析 sleep 和 timeout 的时间参数：可以是小数，后缀 s、m、h、d 分别表示秒、分钟、小时、天
+func parseDurationArg(s string) (time.Duration, error) {
+	if s == "infinity" || s == "inf" {
+		return math.MaxInt64, nil
+	}
+	unit := time.Second
+	number := s
+	if n := len(s); n > 1 {
+		switch s[n-1] {
+		case 's':
+			number = s[:n-1]
+		case 'm':
+			unit, number = time.Minute, s[:n-1]
+		case 'h':
+			unit, number = time.Hour, s[:n-1]
+		case 'd':
+			unit, number = 24*time.Hour, s[:n-1]
+		}
+	}
+	// 只接受普通的十进制数字（ParseFloat 还接受 inf、nan 和十六进制）
+	if number == "" || strings.Trim(number, "0123456789.") != "" {
+		return 0, i18n.Errorf("无效的时间间隔: %s", s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, i18n.Errorf("无效的时间间隔: %s", s)
+	}
+	if d := value * float64(unit); d < math.MaxInt64 {
+		return time.Duration(d), nil
+	}
+	return math.MaxInt64, nil
+}
@@ -0,0 +1,249 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// statUsage stat 命令的用法说明
+const statUsage = "用法: stat [-L] [-c 格式] 文件..."
+
+// stat 显示文件的详细信息
+// 用法：stat [-L] [-c 格式 | --printf=格式] 文件...
+// 默认输出文件名、大小、类型、权限和修改时间；-c 按格式输出（末尾加换行），--printf 还解释 \n 等转义且不加换行；
+// 格式支持 %n 文件名、%N 带链接目标的文件名、%s 大小、%b 块数、%a 八进制权限、%A 权限字符串、
+// %F 文件类型、%u/%g 所有者和组的 ID、%U/%G 所有者和组的名称、%i inode 号、%h 硬链接数、
+// %Y 修改时间（秒）、%y 修改时间、%% 百分号（Windows 上 %u %g %U %G %i %h 输出 ?）；-L 跟随符号链接
+func stat(args []string, env map[string]string, stdio *IO) error {
+	follow := false
+	format, hasFormat, escapes := "", false, false
+	var files []string
+	parseOptions := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--format="); ok {
+			format, hasFormat, escapes = value+"\n", true, false
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--printf="); ok {
+			format, hasFormat, escapes = value, true, true
+			continue
+		}
+		if arg == "--dereference" {
+			follow = true
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'L':
+				follow = true
+			case 'c':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return i18n.Errorf("stat: -c 缺少参数\n%s", i18n.T(statUsage))
+					}
+					i++
+					value = args[i]
+				}
+				format, hasFormat, escapes = value+"\n", true, false
+				j = len(arg)
+			default:
+				return i18n.Errorf("stat: -%c: 无效选项\n%s", flag, i18n.T(statUsage))
+			}
+		}
+	}
+	if len(files) == 0 {
+		return i18n.Errorf("stat: 缺少操作数\n%s", i18n.T(statUsage))
+	}
+	if escapes {
+		format, _ = expandEchoEscapes(format)
+	}
+
+	var errs []string
+	for _, file := range files {
+		path := resolvePath(env, file)
+		var info fs.FileInfo
+		var err error
+		if follow {
+			info, err = os.Stat(path)
+		} else {
+			info, err = os.Lstat(path)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("stat: %s: %v", file, pathErrorReason(err)))
+			continue
+		}
+		if hasFormat {
+			fmt.Fprint(stdio.Stdout, formatStat(format, file, path, info))
+			continue
+		}
+		// 默认格式与 GNU stat 相似，但只包含各平台都有的信息
+		title := file
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if target, err := os.Readlink(path); err == nil {
+				title += " -> " + target
+			}
+		}
+		fmt.Fprintf(stdio.Stdout, "  File: %s\n", title)
+		fmt.Fprint(stdio.Stdout, formatStat("  Size: %-10s\tBlocks: %-10b %F\n"+
+			"Access: (%04a/%A)\n"+
+			"Modify: %y\n", file, path, info))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// formatStat 按 stat -c 的格式输出文件信息，% 和格式字符之间可以有 printf 风格的宽度（如 %-10s、%04a）
+func formatStat(format, name, path string, info fs.FileInfo) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+		// 宽度和对齐标志
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-0123456789", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			sb.WriteString(format[i:])
+			break
+		}
+		flags := format[i+1 : j]
+		i = j
+
+		mode := info.Mode()
+		var value string
+		switch format[j] {
+		case '%':
+			sb.WriteByte('%')
+			continue
+		case 'n':
+			value = name
+		case 'N':
+			value = "'" + name + "'"
+			if mode&fs.ModeSymlink != 0 {
+				if target, err := os.Readlink(path); err == nil {
+					value += " -> '" + target + "'"
+				}
+			}
+		case 's':
+			value = strconv.FormatInt(info.Size(), 10)
+		case 'b':
+			value = strconv.FormatInt((fileBlocks(info)+511)/512, 10)
+		case 'B':
+			value = "512"
+		case 'a':
+			value = strconv.FormatUint(uint64(statPermBits(mode)), 8)
+		case 'A':
+			value = statModeString(mode)
+		case 'F':
+			value = statFileType(info)
+		case 'Y':
+			value = strconv.FormatInt(info.ModTime().Unix(), 10)
+		case 'y':
+			value = info.ModTime().Format("2006-01-02 15:04:05.000000000 -0700")
+		case 'u', 'g', 'U', 'G', 'i', 'h':
+			var ok bool
+			if value, ok = statSysInfo(info, format[j]); !ok {
+				value = "?"
+			}
+		default:
+			// 不支持的格式字符输出 ?
+			value = "?"
+		}
+		sb.WriteString(fmt.Sprintf("%"+flags+"s", value))
+	}
+	return sb.String()
+}
+
+// statPermBits 返回 Unix 风格的权限位，包括 setuid、setgid 和 sticky 位
+func statPermBits(mode fs.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+// statModeString 返回 ls -l 风格的类型和权限字符串，如 -rw-r--r--、drwxrwxrwt
+func statModeString(mode fs.FileMode) string {
+	kind := byte('-')
+	switch {
+	case mode.IsDir():
+		kind = 'd'
+	case mode&fs.ModeSymlink != 0:
+		kind = 'l'
+	case mode&fs.ModeNamedPipe != 0:
+		kind = 'p'
+	case mode&fs.ModeSocket != 0:
+		kind = 's'
+	case mode&fs.ModeCharDevice != 0:
+		kind = 'c'
+	case mode&fs.ModeDevice != 0:
+		kind = 'b'
+	}
+	perm := []byte(mode.Perm().String())
+	perm[0] = kind
+	// 特殊位显示在对应的执行位上，没有执行权限时为大写
+	special := func(set bool, pos int, lower, upper byte) {
+		if !set {
+			return
+		}
+		if perm[pos] == 'x' {
+			perm[pos] = lower
+		} else {
+			perm[pos] = upper
+		}
+	}
+	special(mode&fs.ModeSetuid != 0, 3, 's', 'S')
+	special(mode&fs.ModeSetgid != 0, 6, 's', 'S')
+	special(mode&fs.ModeSticky != 0, 9, 't', 'T')
+	return string(perm)
+}
+
+// statFileType 返回文件类型的描述，与 GNU stat 的 %F 相同
+func statFileType(info fs.FileInfo) string {
+	mode := info.Mode()
+	switch {
+	case mode.IsRegular() && info.Size() == 0:
+		return "regular empty file"
+	case mode.IsRegular():
+		return "regular file"
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symbolic link"
+	case mode&fs.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character special file"
+	case mode&fs.ModeDevice != 0:
+		return "block special file"
+	}
+	return "unknown"
+}
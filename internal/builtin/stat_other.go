@@ -0,0 +1,10 @@
+//go:build !unix
+
+package builtin
+
+import "io/fs"
+
+// statSysInfo 没有 syscall.Stat_t 的平台上无法获取所有者、inode 和链接数，stat 输出 ?
+func statSysInfo(info fs.FileInfo, verb byte) (string, bool) {
+	return "", false
+}
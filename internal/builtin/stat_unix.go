@@ -0,0 +1,42 @@
+//go:build unix
+
+package builtin
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// statSysInfo 从 syscall.Stat_t 读取 %u %g %U %G %i %h 对应的值，
+// 查不到用户名或组名时与 GNU stat 一样输出 UNKNOWN
+func statSysInfo(info fs.FileInfo, verb byte) (string, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	gid := strconv.FormatUint(uint64(st.Gid), 10)
+	switch verb {
+	case 'u':
+		return uid, true
+	case 'g':
+		return gid, true
+	case 'U':
+		if u, err := user.LookupId(uid); err == nil {
+			return u.Username, true
+		}
+		return "UNKNOWN", true
+	case 'G':
+		if g, err := user.LookupGroupId(gid); err == nil {
+			return g.Name, true
+		}
+		return "UNKNOWN", true
+	case 'i':
+		return strconv.FormatUint(uint64(st.Ino), 10), true
+	case 'h':
+		return strconv.FormatUint(uint64(st.Nlink), 10), true
+	}
+	return "", false
+}
@@ -0,0 +1,90 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"os"
+	"strings"
+)
+
+// tee 把标准输入复制到标准输出和文件
+// 用法：tee [-a] [文件...]
+// -a 追加到文件末尾而不是覆盖；文件 - 表示标准输出；某个文件无法打开或写入时继续写其他文件，最后返回错误
+func tee(args []string, env map[string]string, stdio *IO) error {
+	appendMode := false
+	var files []string
+	parseOptions := true
+	for _, arg := range args {
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		if arg == "--append" {
+			appendMode = true
+			continue
+		}
+		for _, flag := range arg[1:] {
+			if flag != 'a' {
+				return i18n.Errorf("tee: -%c: 无效选项\n用法: tee [-a] [文件...]", flag)
+			}
+			appendMode = true
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	names := []string{i18n.T("标准输出")}
+	writers := []io.Writer{stdio.Stdout}
+	var errs []string
+	for _, file := range files {
+		if file == "-" {
+			names = append(names, i18n.T("标准输出"))
+			writers = append(writers, stdio.Stdout)
+			continue
+		}
+		f, err := stdio.create(resolvePath(env, file), flags, 0666)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("tee: %s: %v", file, pathErrorReason(err)))
+			continue
+		}
+		defer f.Close()
+		names = append(names, file)
+		writers = append(writers, f)
+	}
+
+	// 逐块复制，写入失败的输出不再使用，其他输出继续写
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := stdio.Stdin.Read(buf)
+		if n > 0 {
+			for i, w := range writers {
+				if w == nil {
+					continue
+				}
+				if _, err := w.Write(buf[:n]); err != nil {
+					errs = append(errs, fmt.Sprintf("tee: %s: %v", names[i], err))
+					writers[i] = nil
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			errs = append(errs, i18n.Sprintf("tee: 读取标准输入失败: %v", readErr))
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package builtin
+
+import (
+	"os"
+
+	"github.com/chzyer/readline"
+)
+
+// EnableVirtualTerminal 检查 f 是否是终端，其他系统的终端总是解释 ANSI 转义序列
+func EnableVirtualTerminal(f *os.File) bool {
+	return readline.IsTerminal(int(f.Fd()))
+}
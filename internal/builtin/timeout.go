@@ -0,0 +1,82 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gobash/internal/i18n"
+	"os/exec"
+	"strings"
+)
+
+// TimeoutBuiltin 返回 timeout 命令，run 执行命令行（args[0] 是命令名），超时通过 IO.Context 通知 run
+// 执行器用自己的 run 创建 timeout，这样函数、内置命令和外部命令都可以在超时后被终止；
+// run 为 nil 时只能执行默认内置命令表中的命令和外部命令
+func TimeoutBuiltin(run BuiltinFunc) BuiltinFunc {
+	if run == nil {
+		run = runTimeoutCommand
+	}
+	return func(args []string, env map[string]string, stdio *IO) error {
+		return timeout(run, args, env, stdio)
+	}
+}
+
+// timeout 执行命令，超过指定时间后终止命令
+// 用法：timeout 时间[smhd] 命令 [参数...]
+// 超时时退出状态为 124，否则是命令的退出状态；时间为 0 时不限制；timeout 本身出错时退出状态为 125
+func timeout(run BuiltinFunc, args []string, env map[string]string, stdio *IO) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	} else if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		return &StatusError{Code: 125, Message: i18n.Sprintf("timeout: %s: 无效选项\n用法: timeout 时间[smhd] 命令 [参数...]", args[0])}
+	}
+	if len(args) < 2 {
+		return &StatusError{Code: 125, Message: i18n.T("timeout: 缺少操作数\n用法: timeout 时间[smhd] 命令 [参数...]")}
+	}
+	d, err := parseDurationArg(args[0])
+	if err != nil {
+		return &StatusError{Code: 125, Message: i18n.Sprintf("timeout: 无效的时间间隔: %q", args[0])}
+	}
+
+	parent := stdio.ctx()
+	ctx := parent
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, d)
+		defer cancel()
+	}
+	cmdStdio := *stdio
+	cmdStdio.Context = ctx
+	err = run(args[1:], env, &cmdStdio)
+
+	// 只有 timeout 自己的时限到期才返回 124，外层的取消原样返回；与 GNU timeout 相同，超时不输出消息
+	if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		return &StatusError{Code: 124}
+	}
+	return err
+}
+
+// runTimeoutCommand 执行默认内置命令表中的命令或外部命令，stdio.Context 被取消时终止外部命令
+func runTimeoutCommand(args []string, env map[string]string, stdio *IO) error {
+	if fn, ok := builtins[args[0]]; ok {
+		return fn(args[1:], env, stdio)
+	}
+
+	cmd, err := stdio.command(stdio.ctx(), args[0], args[1:], env)
+	if err == nil {
+		cmd.Env = stdio.environ(env)
+		cmd.Dir = workDir(env)
+		cmd.Stdin = stdio.Stdin
+		cmd.Stdout = stdio.Stdout
+		cmd.Stderr = stdio.Stderr
+		err = cmd.Run()
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil, errors.As(err, &exitErr):
+		return err
+	case errors.Is(err, exec.ErrNotFound):
+		return &StatusError{Code: 127, Message: i18n.Sprintf("timeout: %s: 命令未找到", args[0])}
+	}
+	return &StatusError{Code: 126, Message: fmt.Sprintf("timeout: %s: %v", args[0], err)}
+}
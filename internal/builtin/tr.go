@@ -0,0 +1,280 @@
+package builtin
+
+import (
+	"bufio"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tr 转换、删除或压缩字符，从标准输入读取，写到标准输出
+// 用法：tr [-cdst] 字符集1 [字符集2]
+// 字符集支持 a-z 范围、[:upper:] 等字符类、[c*n] 重复以及 \n、\t、\NNN 等转义；
+// -d 删除字符集1中的字符，-s 把连续重复的字符压缩为一个，-c 使用字符集1的补集，-t 把字符集1截断为字符集2的长度
+func tr(args []string, env map[string]string, stdio *IO) error {
+	complement, deleteChars, squeeze, truncate := false, false, false, false
+	var sets []string
+	parseOptions := true
+	for _, arg := range args {
+		if !parseOptions || !strings.HasPrefix(arg, "-") || arg == "-" {
+			sets = append(sets, arg)
+			continue
+		}
+		if arg == "--" {
+			parseOptions = false
+			continue
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'c', 'C':
+				complement = true
+			case 'd':
+				deleteChars = true
+			case 's':
+				squeeze = true
+			case 't':
+				truncate = true
+			default:
+				return i18n.Errorf("tr: -%c: 无效选项\n用法: tr [-cdst] 字符集1 [字符集2]", flag)
+			}
+		}
+	}
+
+	// 检查字符集个数
+	translate := !deleteChars && len(sets) == 2
+	switch {
+	case len(sets) == 0:
+		return i18n.Errorf("tr: 缺少操作数\n用法: tr [-cdst] 字符集1 [字符集2]")
+	case len(sets) > 2:
+		return i18n.Errorf("tr: 多余的操作数: %s", sets[2])
+	case deleteChars && squeeze && len(sets) != 2:
+		return i18n.Errorf("tr: 同时删除和压缩时需要两个字符集")
+	case deleteChars && !squeeze && len(sets) != 1:
+		return i18n.Errorf("tr: 删除时只能指定一个字符集")
+	case !deleteChars && !squeeze && len(sets) != 2:
+		return i18n.Errorf("tr: 转换时需要两个字符集")
+	}
+
+	set1, err := expandTrSet(sets[0], 0)
+	if err != nil {
+		return err
+	}
+	inSet1 := make(map[rune]bool, len(set1))
+	for _, r := range set1 {
+		inSet1[r] = true
+	}
+	selected := func(r rune) bool {
+		return inSet1[r] != complement
+	}
+
+	// 转换表：字符集2比字符集1短时用最后一个字符补齐（-t 时截断字符集1）
+	var mapping map[rune]rune
+	var complementTo rune
+	var set2 []rune
+	if len(sets) == 2 {
+		if set2, err = expandTrSet(sets[1], len(set1)); err != nil {
+			return err
+		}
+	}
+	if translate {
+		if len(set2) == 0 {
+			return i18n.Errorf("tr: 字符集2不能为空")
+		}
+		if complement {
+			// 补集中的所有字符都转换为字符集2的最后一个字符
+			complementTo = set2[len(set2)-1]
+		} else {
+			if truncate && len(set1) > len(set2) {
+				set1 = set1[:len(set2)]
+			}
+			mapping = make(map[rune]rune, len(set1))
+			for i, r := range set1 {
+				if i < len(set2) {
+					mapping[r] = set2[i]
+				} else {
+					mapping[r] = set2[len(set2)-1]
+				}
+			}
+		}
+	}
+
+	// 压缩使用最后一个字符集
+	inSet2 := make(map[rune]bool, len(set2))
+	for _, r := range set2 {
+		inSet2[r] = true
+	}
+	squeezed := func(r rune) bool {
+		if len(sets) == 2 {
+			return inSet2[r]
+		}
+		return selected(r)
+	}
+
+	reader := bufio.NewReader(stdio.Stdin)
+	writer := bufio.NewWriter(stdio.Stdout)
+	defer writer.Flush()
+	last, hasLast := rune(0), false
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("tr: %v", err)
+		}
+
+		switch {
+		case deleteChars:
+			if selected(r) {
+				continue
+			}
+		case translate && complement:
+			if selected(r) {
+				r = complementTo
+			}
+		case translate:
+			if mapped, ok := mapping[r]; ok {
+				r = mapped
+			}
+		}
+
+		if squeeze && hasLast && r == last && squeezed(r) {
+			continue
+		}
+		writer.WriteRune(r)
+		last, hasLast = r, true
+	}
+}
+
+// trClasses 字符类包含的字符（只包含 ASCII 字符，按顺序排列，使 [:upper:] 和 [:lower:] 可以一一对应）
+var trClasses = map[string]func(r rune) bool{
+	"alnum":  func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha":  unicode.IsLetter,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl":  unicode.IsControl,
+	"digit":  unicode.IsDigit,
+	"graph":  func(r rune) bool { return unicode.IsGraphic(r) && r != ' ' },
+	"lower":  unicode.IsLower,
+	"print":  unicode.IsPrint,
+	"punct":  unicode.IsPunct,
+	"space":  unicode.IsSpace,
+	"upper":  unicode.IsUpper,
+	"xdigit": func(r rune) bool { return strings.ContainsRune("0123456789abcdefABCDEF", r) },
+}
+
+// expandTrSet 展开字符集，fillTo 是 [c*] 需要填充到的长度（字符集1的长度）
+func expandTrSet(set string, fillTo int) ([]rune, error) {
+	chars := []rune(set)
+	var result []rune
+	fillAt := -1 // [c*] 的位置
+	var fillChar rune
+
+	for i := 0; i < len(chars); i++ {
+		// 字符类 [:name:]
+		if chars[i] == '[' && i+1 < len(chars) && chars[i+1] == ':' {
+			if end := strings.Index(string(chars[i+2:]), ":]"); end >= 0 {
+				name := string(chars[i+2:])[:end]
+				class, ok := trClasses[name]
+				if !ok {
+					return nil, i18n.Errorf("tr: 无效的字符类: %s", name)
+				}
+				for r := rune(0); r < 128; r++ {
+					if class(r) {
+						result = append(result, r)
+					}
+				}
+				i += 2 + len([]rune(name)) + 1
+				continue
+			}
+		}
+
+		// 重复 [c*n] 或 [c*]
+		if chars[i] == '[' && i+3 < len(chars) {
+			c, next := trChar(chars, i+1)
+			if next < len(chars) && chars[next] == '*' {
+				if end := strings.IndexRune(string(chars[next+1:]), ']'); end >= 0 {
+					countStr := string(chars[next+1:])[:end]
+					if countStr == "" {
+						fillAt, fillChar = len(result), c
+					} else {
+						base := 10
+						if strings.HasPrefix(countStr, "0") {
+							base = 8
+						}
+						count, err := strconv.ParseInt(countStr, base, 32)
+						if err != nil {
+							return nil, i18n.Errorf("tr: 无效的重复次数: %s", countStr)
+						}
+						for n := int64(0); n < count; n++ {
+							result = append(result, c)
+						}
+					}
+					i = next + 1 + len([]rune(countStr))
+					continue
+				}
+			}
+		}
+
+		c, next := trChar(chars, i)
+		// 范围 a-z
+		if next+1 < len(chars) && chars[next] == '-' {
+			end, after := trChar(chars, next+1)
+			if end < c {
+				return nil, i18n.Errorf("tr: 范围 %c-%c 的结束字符小于起始字符", c, end)
+			}
+			for r := c; r <= end; r++ {
+				result = append(result, r)
+			}
+			i = after - 1
+			continue
+		}
+		result = append(result, c)
+		i = next - 1
+	}
+
+	if fillAt >= 0 && fillTo > len(result) {
+		fill := make([]rune, fillTo-len(result))
+		for i := range fill {
+			fill[i] = fillChar
+		}
+		result = append(result[:fillAt], append(fill, result[fillAt:]...)...)
+	}
+	return result, nil
+}
+
+// trChar 读取位置 i 的一个字符（处理 \n、\t、\\、\NNN 等转义），返回字符和下一个位置
+func trChar(chars []rune, i int) (rune, int) {
+	if chars[i] != '\\' || i+1 >= len(chars) {
+		return chars[i], i + 1
+	}
+	switch c := chars[i+1]; c {
+	case 'a':
+		return '\a', i + 2
+	case 'b':
+		return '\b', i + 2
+	case 'f':
+		return '\f', i + 2
+	case 'n':
+		return '\n', i + 2
+	case 'r':
+		return '\r', i + 2
+	case 't':
+		return '\t', i + 2
+	case 'v':
+		return '\v', i + 2
+	default:
+		if c >= '0' && c <= '7' {
+			// 最多 3 位八进制数字
+			value, j := rune(0), i+1
+			for j < len(chars) && j < i+4 && chars[j] >= '0' && chars[j] <= '7' {
+				value = value*8 + chars[j] - '0'
+				j++
+			}
+			return value, j
+		}
+		return c, i + 2
+	}
+}
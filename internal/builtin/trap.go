@@ -0,0 +1,90 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"strconv"
+	"strings"
+)
+
+// trapConditions 支持的陷阱条件（按 trap -p 的输出顺序）：EXIT 在 shell 退出之前执行，
+// INT 和 TERM 在收到信号后执行，DEBUG 在每个简单命令执行之前执行，ERR 在命令失败之后执行
+var trapConditions = []string{"EXIT", "INT", "TERM", "DEBUG", "ERR"}
+
+// TrapBuiltin 返回使用陷阱表 traps（条件 -> 命令）的 trap 命令
+// 每个执行器有自己的陷阱表，执行器在相应的执行点执行其中的命令
+func TrapBuiltin(traps map[string]string) BuiltinFunc {
+	return func(args []string, env map[string]string, stdio *IO) error {
+		return trap(traps, args, stdio)
+	}
+}
+
+// trap 设置或显示陷阱
+// 用法：trap [-p] [命令] [条件...]
+// 命令为 - 或者只给出条件时删除陷阱，命令为空字符串时忽略该条件；没有参数或 -p 时按 trap -- '命令' 条件 的格式显示陷阱。
+// 条件名不区分大小写，信号可以带 SIG 前缀或者使用编号，0 表示 EXIT；目前只支持 EXIT、INT、TERM、DEBUG 和 ERR，其他信号报错
+func trap(traps map[string]string, args []string, stdio *IO) error {
+	show := false
+	if len(args) > 0 && args[0] == "-p" {
+		show = true
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	if show || len(args) == 0 {
+		names := args
+		if len(names) == 0 {
+			names = trapConditions
+		}
+		for _, name := range names {
+			cond, err := trapCondition(name)
+			if err != nil {
+				return err
+			}
+			if action, ok := traps[cond]; ok {
+				fmt.Fprintf(stdio.Stdout, "trap -- '%s' %s\n", strings.ReplaceAll(action, "'", `'\''`), cond)
+			}
+		}
+		return nil
+	}
+
+	action, names := args[0], args[1:]
+	if len(names) == 0 {
+		// trap 条件：恢复该条件的默认行为
+		action, names = "-", args
+	}
+	for _, name := range names {
+		cond, err := trapCondition(name)
+		if err != nil {
+			return err
+		}
+		if action == "-" {
+			delete(traps, cond)
+		} else {
+			traps[cond] = action
+		}
+	}
+	return nil
+}
+
+// trapCondition 返回条件名的标准形式（大写，不带 SIG 前缀）
+func trapCondition(name string) (string, error) {
+	cond := strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	if num, err := strconv.Atoi(name); err == nil {
+		cond = "EXIT"
+		if num != 0 {
+			cond, _ = signalName(num)
+		}
+	}
+	for _, c := range trapConditions {
+		if c == cond {
+			return c, nil
+		}
+	}
+	if _, err := parseSignal(name); err == nil || cond == "RETURN" {
+		return "", i18n.Errorf("trap: %s: 不支持的条件（目前只支持 EXIT、INT、TERM、DEBUG 和 ERR）", name)
+	}
+	return "", i18n.Errorf("trap: %s: 无效的信号", name)
+}
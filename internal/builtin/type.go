@@ -0,0 +1,146 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+)
+
+// typeUsage type 命令的用法说明
+const typeUsage = "用法: type [-afptP] 名称 [名称 ...]"
+
+// shellKeywords shell 的保留字（type 报告为 keyword）
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"case": true, "esac": true, "for": true, "while": true, "until": true,
+	"do": true, "done": true, "in": true, "function": true, "time": true,
+	"{": true, "}": true, "!": true, "[[": true, "]]": true,
+}
+
+// Commands type 命令查询的命令表
+// 别名由 shell 层保存，函数和内置命令由执行器保存，为 nil 的查询表示没有这类命令（Builtin 为 nil 时使用默认的内置命令表）
+type Commands struct {
+	Alias    func(name string) (string, bool)      // 返回别名的值
+	Function func(name string) (string, bool)      // 返回函数体的源代码
+	Builtin  func(name string) (BuiltinFunc, bool) // 查找内置命令
+}
+
+// TypeBuiltin 返回使用命令表 commands 的 type 命令
+// 每个执行器用自己的函数表、内置命令表和 shell 的别名表创建 type
+func TypeBuiltin(commands Commands) BuiltinFunc {
+	if commands.Alias == nil {
+		commands.Alias = func(string) (string, bool) { return "", false }
+	}
+	if commands.Function == nil {
+		commands.Function = func(string) (string, bool) { return "", false }
+	}
+	if commands.Builtin == nil {
+		commands.Builtin = func(name string) (BuiltinFunc, bool) {
+			fn, ok := builtins[name]
+			return fn, ok
+		}
+	}
+	return func(args []string, env map[string]string, stdio *IO) error {
+		return typeNames(commands, args, env, stdio)
+	}
+}
+
+// typeCmd 使用默认内置命令表的 type 命令（执行器会用 TypeBuiltin 替换它）
+var typeCmd = TypeBuiltin(Commands{})
+
+// typeNames 显示命令的类型
+// 用法：type [-afptP] 名称 [名称 ...]
+// 依次查找别名、保留字、函数、内置命令和 PATH 中的文件，报告第一个匹配；
+// -a 报告所有匹配（包括 PATH 中所有同名文件），-t 只输出类型（alias、keyword、function、builtin 或 file），
+// -p 只在名称是文件时输出文件路径，-P 忽略别名、函数和内置命令，总是在 PATH 中查找，-f 不查找函数；
+// 有名称没有找到时退出状态为 1
+func typeNames(commands Commands, args []string, env map[string]string, stdio *IO) error {
+	all, noFunctions, kindOnly, pathOnly, forcePath := false, false, false, false, false
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'a':
+				all = true
+			case 'f':
+				noFunctions = true
+			case 't':
+				kindOnly = true
+			case 'p':
+				pathOnly = true
+			case 'P':
+				forcePath = true
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("type: -%c: 无效选项\n%s", flag, i18n.T(typeUsage))}
+			}
+		}
+	}
+	names := args[i:]
+	if len(names) == 0 {
+		return nil
+	}
+
+	notFound := false
+	for _, name := range names {
+		found := false
+		report := func(kind, description string) {
+			found = true
+			switch {
+			case kindOnly:
+				fmt.Fprintln(stdio.Stdout, kind)
+			case pathOnly || forcePath:
+				if kind == "file" {
+					fmt.Fprintln(stdio.Stdout, description)
+				}
+			case kind == "file":
+				fmt.Fprintf(stdio.Stdout, "%s is %s\n", name, description)
+			default:
+				fmt.Fprintf(stdio.Stdout, "%s %s\n", name, description)
+			}
+		}
+
+		if !forcePath {
+			if value, ok := commands.Alias(name); ok {
+				report("alias", fmt.Sprintf("is aliased to `%s'", value))
+			}
+			if (all || !found) && shellKeywords[name] {
+				report("keyword", "is a shell keyword")
+			}
+			if (all || !found) && !noFunctions {
+				if source, ok := commands.Function(name); ok {
+					report("function", fmt.Sprintf("is a function\n%s () %s", name, source))
+				}
+			}
+			if all || !found {
+				if _, ok := commands.Builtin(name); ok {
+					report("builtin", "is a shell builtin")
+				}
+			}
+		}
+		// -p 时名称已经是别名、函数或内置命令就不再查找文件
+		if all || !found {
+			for _, path := range searchPath(name, env, all) {
+				report("file", path)
+			}
+		}
+
+		if !found {
+			notFound = true
+			if !kindOnly && !pathOnly && !forcePath {
+				fmt.Fprintf(stdio.Stderr, "type: %s: not found\n", name)
+			}
+		}
+	}
+
+	if notFound {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
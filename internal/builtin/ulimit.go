@@ -0,0 +1,171 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"strconv"
+	"strings"
+)
+
+// ulimitUsage ulimit 命令的用法说明
+const ulimitUsage = "用法: ulimit [-SHa] [-cdfnstuv [限制]]"
+
+// ulimitResource ulimit 可以查看和设置的资源
+type ulimitResource struct {
+	flag  byte
+	name  string
+	unit  string // 显示的单位，空表示个数
+	scale uint64 // 每个单位对应的资源值（如 kbytes 为 1024 字节）
+}
+
+// ulimitResources ulimit 支持的资源（按 -a 输出的顺序）
+var ulimitResources = []ulimitResource{
+	{'c', "core file size", "blocks", 1024},
+	{'d', "data seg size", "kbytes", 1024},
+	{'f', "file size", "blocks", 1024},
+	{'n', "open files", "", 1},
+	{'s', "stack size", "kbytes", 1024},
+	{'t', "cpu time", "seconds", 1},
+	{'u', "max user processes", "", 1},
+	{'v', "virtual memory", "kbytes", 1024},
+}
+
+// findUlimitResource 按选项字母查找资源
+func findUlimitResource(flag byte) (ulimitResource, bool) {
+	for _, res := range ulimitResources {
+		if res.flag == flag {
+			return res, true
+		}
+	}
+	return ulimitResource{}, false
+}
+
+// ulimitOp 一个资源选项以及要设置的限制（limit 为空表示只查看）
+type ulimitOp struct {
+	res   ulimitResource
+	limit string
+}
+
+// ulimit 查看或设置进程的资源限制
+// 用法：ulimit [-SHa] [-cdfnstuv [限制]]
+// 没有资源选项时为 -f；没有限制时输出当前的软限制（-H 时为硬限制），-a 输出所有资源；
+// 限制可以是数字（按资源的单位）、unlimited、soft 或 hard，没有 -S/-H 时同时设置软限制和硬限制。
+// 资源限制属于整个进程（Unix 上通过 setrlimit 设置），之后启动的外部命令都会继承；不支持资源限制的平台（如 Windows）上
+// 所有资源都显示为 unlimited，设置不会生效
+func ulimit(args []string, env map[string]string, stdio *IO) error {
+	soft, hard, all := false, false, false
+	var ops []ulimitOp
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			operands = append(operands, arg)
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch flag := arg[j]; flag {
+			case 'S':
+				soft = true
+			case 'H':
+				hard = true
+			case 'a':
+				all = true
+			default:
+				res, ok := findUlimitResource(flag)
+				if !ok {
+					return &StatusError{Code: 2, Message: i18n.Sprintf("ulimit: -%c: 无效选项\n%s", flag, i18n.T(ulimitUsage))}
+				}
+				op := ulimitOp{res: res}
+				// 最后一个选项后面的参数是这个资源的限制
+				if j == len(arg)-1 && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					op.limit = args[i]
+				}
+				ops = append(ops, op)
+			}
+		}
+	}
+	if len(operands) > 1 || (len(operands) == 1 && len(ops) > 0) {
+		return i18n.Errorf("ulimit: 参数太多\n%s", i18n.T(ulimitUsage))
+	}
+	if len(ops) == 0 && !all {
+		res, _ := findUlimitResource('f')
+		op := ulimitOp{res: res}
+		if len(operands) == 1 {
+			op.limit = operands[0]
+		}
+		ops = append(ops, op)
+	}
+	if all {
+		ops = ops[:0]
+		for _, res := range ulimitResources {
+			ops = append(ops, ulimitOp{res: res})
+		}
+	}
+
+	var errs []string
+	for _, op := range ops {
+		res := op.res
+		curSoft, curHard, err := getRlimit(res.flag)
+		if err != nil {
+			errs = append(errs, i18n.Sprintf("ulimit: %s: 无法获取限制: %v", res.name, err))
+			continue
+		}
+		if op.limit == "" {
+			value := curSoft
+			if hard && !soft {
+				value = curHard
+			}
+			if len(ops) > 1 {
+				label := res.name
+				if res.unit != "" {
+					fmt.Fprintf(stdio.Stdout, "%-28s(%s, -%c) %s\n", label, res.unit, res.flag, formatRlimit(value, res.scale))
+				} else {
+					fmt.Fprintf(stdio.Stdout, "%-28s(-%c) %s\n", label, res.flag, formatRlimit(value, res.scale))
+				}
+			} else {
+				fmt.Fprintln(stdio.Stdout, formatRlimit(value, res.scale))
+			}
+			continue
+		}
+
+		var value uint64
+		switch op.limit {
+		case "unlimited":
+			value = rlimInfinity
+		case "soft":
+			value = curSoft
+		case "hard":
+			value = curHard
+		default:
+			n, err := strconv.ParseUint(op.limit, 10, 64)
+			if err != nil {
+				errs = append(errs, i18n.Sprintf("ulimit: %s: 无效的数字", op.limit))
+				continue
+			}
+			value = n * res.scale
+		}
+		newSoft, newHard := curSoft, curHard
+		if soft || !hard {
+			newSoft = value
+		}
+		if hard || !soft {
+			newHard = value
+		}
+		if err := setRlimit(res.flag, newSoft, newHard); err != nil {
+			errs = append(errs, i18n.Sprintf("ulimit: %s: 无法修改限制: %v", res.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// formatRlimit 按单位显示资源限制
+func formatRlimit(value, scale uint64) string {
+	if value == rlimInfinity {
+		return "unlimited"
+	}
+	return strconv.FormatUint(value/scale, 10)
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+
+package builtin
+
+// rlimInfinity 表示没有限制的资源值
+const rlimInfinity = ^uint64(0)
+
+// getRlimit 当前平台不支持资源限制，所有资源都没有限制
+func getRlimit(flag byte) (soft, hard uint64, err error) {
+	return rlimInfinity, rlimInfinity, nil
+}
+
+// setRlimit 当前平台不支持资源限制，设置不生效
+func setRlimit(flag byte, soft, hard uint64) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+//go:build linux || darwin
+
+package builtin
+
+import "golang.org/x/sys/unix"
+
+// rlimInfinity 表示没有限制的资源值
+const rlimInfinity = unix.RLIM_INFINITY
+
+// rlimitResources ulimit 的选项字母对应的资源
+var rlimitResources = map[byte]int{
+	'c': unix.RLIMIT_CORE,
+	'd': unix.RLIMIT_DATA,
+	'f': unix.RLIMIT_FSIZE,
+	'n': unix.RLIMIT_NOFILE,
+	's': unix.RLIMIT_STACK,
+	't': unix.RLIMIT_CPU,
+	'u': unix.RLIMIT_NPROC,
+	'v': unix.RLIMIT_AS,
+}
+
+// getRlimit 返回资源的软限制和硬限制
+func getRlimit(flag byte) (soft, hard uint64, err error) {
+	var lim unix.Rlimit
+	if err := unix.Getrlimit(rlimitResources[flag], &lim); err != nil {
+		return 0, 0, err
+	}
+	return uint64(lim.Cur), uint64(lim.Max), nil
+}
+
+// setRlimit 设置资源的软限制和硬限制
+func setRlimit(flag byte, soft, hard uint64) error {
+	return unix.Setrlimit(rlimitResources[flag], &unix.Rlimit{Cur: soft, Max: hard})
+}
@@ -0,0 +1,255 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// umaskUsage umask 命令的用法说明
+const umaskUsage = "用法: umask [-p] [-S] [模式]"
+
+// Umask 文件创建掩码
+// 每个 shell 有自己的掩码（初始值为进程的 umask），重定向和内置命令创建文件时使用，不修改进程的 umask
+type Umask struct {
+	mu   sync.Mutex
+	mask os.FileMode
+}
+
+// NewUmask 创建掩码，初始值为进程启动时的 umask
+func NewUmask() *Umask {
+	return &Umask{mask: processUmask}
+}
+
+// Clone 复制掩码（子shell 使用父 shell 掩码的副本）
+func (u *Umask) Clone() *Umask {
+	return &Umask{mask: u.Mask()}
+}
+
+// Mask 返回当前的掩码
+func (u *Umask) Mask() os.FileMode {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.mask
+}
+
+// Set 设置掩码（只保留权限位）
+func (u *Umask) Set(mask os.FileMode) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.mask = mask & os.ModePerm
+}
+
+// defaultUmask 没有指定掩码的 IO 使用的掩码
+var defaultUmask = NewUmask()
+
+// umask 返回命令使用的文件创建掩码，没有设置时使用默认掩码
+func (stdio *IO) umask() *Umask {
+	if stdio.Umask == nil {
+		return defaultUmask
+	}
+	return stdio.Umask
+}
+
+// CreateFile 以 flag 打开文件，文件不存在时按 perm 去掉掩码中的位创建
+// 新文件的权限只由 umask 决定，不受进程 umask 的影响；已经存在的文件权限不变。umask 为 nil 时使用默认掩码
+func CreateFile(path string, flag int, perm os.FileMode, umask *Umask) (*os.File, error) {
+	if umask == nil {
+		umask = defaultUmask
+	}
+	mode := perm &^ umask.Mask()
+	_, statErr := os.Lstat(path)
+	f, err := os.OpenFile(path, flag, mode)
+	if err != nil {
+		return nil, err
+	}
+	if flag&os.O_CREATE != 0 && os.IsNotExist(statErr) && runtime.GOOS != "windows" {
+		f.Chmod(mode)
+	}
+	return f, nil
+}
+
+// makeDir 按 0777 去掉掩码中的位创建目录；parents 为 true 时同时创建不存在的上级目录（与 mkdir -p 一样，上级目录总是允许所有者写入和进入）
+func makeDir(path string, parents bool, umask *Umask) error {
+	mode := os.ModePerm &^ umask.Mask()
+	if !parents {
+		return mkdirMode(path, mode)
+	}
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: path, Err: i18n.Errorf("不是目录")}
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := makeDir(parent, true, &Umask{mask: umask.Mask() &^ 0300}); err != nil {
+			return err
+		}
+	}
+	if err := mkdirMode(path, mode); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+// mkdirMode 创建权限为 mode 的目录，不受进程 umask 的影响
+func mkdirMode(path string, mode os.FileMode) error {
+	if err := os.Mkdir(path, mode); err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" {
+		return os.Chmod(path, mode)
+	}
+	return nil
+}
+
+// umaskCmd 显示或设置文件创建掩码
+// 用法：umask [-p] [-S] [模式]
+// 没有模式时输出当前掩码（4 位八进制），-S 以符号形式（如 u=rwx,g=rx,o=rx）输出允许的权限，-p 输出可以重新执行的 umask 命令；
+// 模式可以是八进制数（如 022）或符号形式（如 u=rwx,g=rx,o=、g-w、a+r），符号形式修改的是允许的权限
+func umaskCmd(args []string, env map[string]string, stdio *IO) error {
+	symbolic, reusable := false, false
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' || strings.ContainsAny(arg[1:], "rwx=+-,") {
+			break
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'S':
+				symbolic = true
+			case 'p':
+				reusable = true
+			default:
+				return &StatusError{Code: 2, Message: i18n.Sprintf("umask: -%c: 无效选项\n%s", flag, i18n.T(umaskUsage))}
+			}
+		}
+	}
+	args = args[i:]
+	umask := stdio.umask()
+
+	if len(args) == 0 {
+		mask := umask.Mask()
+		prefix := ""
+		if reusable {
+			prefix = "umask "
+		}
+		if symbolic {
+			if reusable {
+				prefix = "umask -S "
+			}
+			fmt.Fprintf(stdio.Stdout, "%s%s\n", prefix, symbolicPerm(os.ModePerm&^mask))
+		} else {
+			fmt.Fprintf(stdio.Stdout, "%s%04o\n", prefix, uint32(mask))
+		}
+		return nil
+	}
+	if len(args) > 1 {
+		return i18n.Errorf("umask: 参数太多\n%s", i18n.T(umaskUsage))
+	}
+
+	mode := args[0]
+	if mode[0] >= '0' && mode[0] <= '9' {
+		n, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || n > 0777 {
+			return i18n.Errorf("umask: %s: 八进制数超出范围", mode)
+		}
+		umask.Set(os.FileMode(n))
+	} else {
+		allowed, err := applySymbolicMode(os.ModePerm&^umask.Mask(), mode)
+		if err != nil {
+			return fmt.Errorf("umask: %v", err)
+		}
+		umask.Set(os.ModePerm &^ allowed)
+	}
+	if symbolic {
+		fmt.Fprintln(stdio.Stdout, symbolicPerm(os.ModePerm&^umask.Mask()))
+	}
+	return nil
+}
+
+// symbolicPerm 以 u=rwx,g=rx,o=rx 的形式表示权限
+func symbolicPerm(perm os.FileMode) string {
+	parts := make([]string, 0, 3)
+	for i, who := range "ugo" {
+		bits := perm >> uint(6-3*i) & 7
+		s := string(who) + "="
+		if bits&4 != 0 {
+			s += "r"
+		}
+		if bits&2 != 0 {
+			s += "w"
+		}
+		if bits&1 != 0 {
+			s += "x"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ",")
+}
+
+// applySymbolicMode 把符号形式的模式（逗号分隔的 [ugoa]*[+-=][rwx]* 子句）应用到权限 perm 上
+func applySymbolicMode(perm os.FileMode, mode string) (os.FileMode, error) {
+	for _, clause := range strings.Split(mode, ",") {
+		j := 0
+		var who os.FileMode
+		for ; j < len(clause) && strings.IndexByte("ugoa", clause[j]) >= 0; j++ {
+			switch clause[j] {
+			case 'u':
+				who |= 0700
+			case 'g':
+				who |= 0070
+			case 'o':
+				who |= 0007
+			case 'a':
+				who |= 0777
+			}
+		}
+		if who == 0 {
+			who = 0777
+		}
+		if j == len(clause) {
+			return 0, i18n.Errorf("%s: 无效的符号模式", mode)
+		}
+		for j < len(clause) {
+			op := clause[j]
+			if op != '+' && op != '-' && op != '=' {
+				return 0, i18n.Errorf("%s: 无效的符号模式运算符 `%c'", mode, op)
+			}
+			j++
+			var bits os.FileMode
+			for ; j < len(clause) && strings.IndexByte("rwx", clause[j]) >= 0; j++ {
+				switch clause[j] {
+				case 'r':
+					bits |= 0444
+				case 'w':
+					bits |= 0222
+				case 'x':
+					bits |= 0111
+				}
+			}
+			if j < len(clause) && strings.IndexByte("+-=", clause[j]) < 0 {
+				return 0, i18n.Errorf("%s: 无效的符号模式字符 `%c'", mode, clause[j])
+			}
+			switch op {
+			case '+':
+				perm |= bits & who
+			case '-':
+				perm &^= bits & who
+			case '=':
+				perm = perm&^who | bits&who
+			}
+		}
+	}
+	return perm, nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package builtin
+
+import "os"
+
+// processUmask 没有 umask 的平台上使用的默认掩码（与常见的 Unix 默认值相同）
+var processUmask os.FileMode = 0022
@@ -0,0 +1,18 @@
+//go:build unix
+
+package builtin
+
+import (
+	"os"
+	"syscall"
+)
+
+// processUmask 进程启动时的 umask
+var processUmask = readProcessUmask()
+
+// readProcessUmask 读取进程的 umask（只能通过设置再恢复的方式读取，在包初始化时完成）
+func readProcessUmask() os.FileMode {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return os.FileMode(mask)
+}
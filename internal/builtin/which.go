@@ -0,0 +1,157 @@
+package builtin
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// whichUsage which 命令的用法说明
+const whichUsage = "用法: which [-a] 命令 [命令 ...]"
+
+// defaultPathExt PATHEXT 未设置时 Windows 可执行文件的扩展名
+const defaultPathExt = ".COM;.EXE;.BAT;.CMD"
+
+// which 查找命令路径
+// 用法：which [-a] 命令 [命令 ...]
+// 内置命令输出 "命令: shell builtin"，否则输出 PATH 中第一个匹配的可执行文件，-a 输出所有匹配；
+// PATH 按当前系统的分隔符拆分（Unix 为 :，Windows 为 ;），Windows 上按 PATHEXT 尝试扩展名；
+// 有命令没有找到时退出状态为 1
+func which(args []string, env map[string]string, stdio *IO) error {
+	all := false
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		for _, flag := range arg[1:] {
+			if flag != 'a' {
+				return &StatusError{Code: 2, Message: i18n.Sprintf("which: -%c: 无效选项\n%s", flag, i18n.T(whichUsage))}
+			}
+			all = true
+		}
+	}
+	names := args[i:]
+	if len(names) == 0 {
+		return i18n.Errorf("which: 缺少操作数\n%s", i18n.T(whichUsage))
+	}
+
+	notFound := false
+	for _, name := range names {
+		found := false
+		if _, ok := builtins[name]; ok {
+			fmt.Fprintf(stdio.Stdout, "%s: shell builtin\n", name)
+			found = true
+			if !all {
+				continue
+			}
+		}
+		for _, path := range searchPath(name, env, all) {
+			fmt.Fprintln(stdio.Stdout, path)
+			found = true
+		}
+		if !found {
+			notFound = true
+		}
+	}
+
+	// 与 GNU which 一样，找不到时不输出消息，只返回非零退出状态
+	if notFound {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
+
+// searchPath 在 PATH 中查找可执行文件 name，all 为 false 时只返回第一个匹配
+// 名称中包含路径分隔符时不搜索 PATH，直接检查这个文件（相对路径相对于 shell 的工作目录）
+func searchPath(name string, env map[string]string, all bool) []string {
+	if name == "" {
+		return nil
+	}
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		for _, candidate := range executableNames(name, env) {
+			if isExecutable(resolvePath(env, candidate)) {
+				return []string{candidate}
+			}
+		}
+		return nil
+	}
+
+	var matches []string
+	for _, dir := range filepath.SplitList(env["PATH"]) {
+		if dir == "" {
+			continue
+		}
+		for _, candidate := range executableNames(name, env) {
+			fullPath := filepath.Join(dir, candidate)
+			if isExecutable(fullPath) {
+				matches = append(matches, fullPath)
+				if !all {
+					return matches
+				}
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// executableNames 返回查找命令 name 时依次尝试的文件名
+// Unix 上只有 name 本身；Windows 上名称已经带有 PATHEXT 中的扩展名时先尝试 name，再依次加上 PATHEXT 中的扩展名
+func executableNames(name string, env map[string]string) []string {
+	if runtime.GOOS != "windows" {
+		return []string{name}
+	}
+	exts := pathExts(env)
+	var names []string
+	if hasPathExt(name, exts) {
+		names = append(names, name)
+	}
+	for _, ext := range exts {
+		names = append(names, name+ext)
+	}
+	return names
+}
+
+// pathExts 返回 PATHEXT 中的扩展名（小写），PATHEXT 未设置时使用默认值
+func pathExts(env map[string]string) []string {
+	pathExt := env["PATHEXT"]
+	if pathExt == "" {
+		pathExt = defaultPathExt
+	}
+	var exts []string
+	for _, ext := range strings.Split(pathExt, ";") {
+		if ext != "" {
+			exts = append(exts, strings.ToLower(ext))
+		}
+	}
+	return exts
+}
+
+// hasPathExt 判断文件名是否带有 exts 中的扩展名（不区分大小写）
+func hasPathExt(name string, exts []string) bool {
+	nameExt := strings.ToLower(filepath.Ext(name))
+	for _, ext := range exts {
+		if nameExt == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// isExecutable 检查 path 是否是可执行的普通文件（Windows 上只要求是普通文件，由扩展名决定是否可执行）
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,331 @@
+package builtin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// xargsOptions xargs 的选项
+type xargsOptions struct {
+	maxArgs      int    // -n 每次执行最多使用的参数个数，0 表示不限制
+	replace      string // -I 替换字符串，每行输入执行一次命令
+	nullDelim    bool   // -0 输入以 NUL 字符分隔
+	delimiter    string // -d 输入分隔符
+	maxProcs     int    // -P 同时执行的命令数
+	noRunIfEmpty bool   // -r 没有输入时不执行命令
+	trace        bool   // -t 执行前把命令输出到标准错误
+}
+
+// XargsBuiltin 返回 xargs 命令，lookup 用于查找内置命令（为 nil 时使用默认的内置命令表）
+// 每个执行器用自己的内置命令表创建 xargs，这样通过 RegisterBuiltin 注册的命令也可以被 xargs 执行
+func XargsBuiltin(lookup func(name string) (BuiltinFunc, bool)) BuiltinFunc {
+	if lookup == nil {
+		lookup = func(name string) (BuiltinFunc, bool) {
+			fn, ok := builtins[name]
+			return fn, ok
+		}
+	}
+	return func(args []string, env map[string]string, stdio *IO) error {
+		return xargs(lookup, args, env, stdio)
+	}
+}
+
+// xargs 从标准输入读取参数并执行命令
+// 用法：xargs [-0rt] [-d 分隔符] [-n 个数] [-I 替换字符串] [-P 进程数] [命令 [参数...]]
+// 默认命令是 echo；输入默认以空白分隔，支持引号和反斜杠转义；命令可以是内置命令或外部命令
+// 退出状态：命令未找到时为 127，有命令失败时为 123
+func xargs(lookup func(name string) (BuiltinFunc, bool), args []string, env map[string]string, stdio *IO) error {
+	opts := xargsOptions{maxProcs: 1}
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			switch flag {
+			case '0':
+				opts.nullDelim = true
+				continue
+			case 'r':
+				opts.noRunIfEmpty = true
+				continue
+			case 't':
+				opts.trace = true
+				continue
+			case 'd', 'n', 'I', 'P':
+			default:
+				return i18n.Errorf("xargs: -%c: 无效选项\n用法: xargs [-0rt] [-d 分隔符] [-n 个数] [-I 替换字符串] [-P 进程数] [命令 [参数...]]", flag)
+			}
+
+			// 需要参数的选项：参数可以紧跟在选项后面，也可以是下一个参数
+			value := arg[j+1:]
+			if value == "" {
+				if i+1 >= len(args) {
+					return i18n.Errorf("xargs: -%c 缺少参数", flag)
+				}
+				i++
+				value = args[i]
+			}
+			j = len(arg)
+
+			var err error
+			switch flag {
+			case 'd':
+				opts.delimiter = value
+			case 'I':
+				opts.replace = value
+			case 'n':
+				opts.maxArgs, err = strconv.Atoi(value)
+				if err == nil && opts.maxArgs < 1 {
+					err = i18n.Errorf("必须大于 0")
+				}
+			case 'P':
+				opts.maxProcs, err = strconv.Atoi(value)
+				if err == nil && opts.maxProcs < 0 {
+					err = i18n.Errorf("不能小于 0")
+				}
+			}
+			if err != nil {
+				return i18n.Errorf("xargs: -%c %s: 无效的数值: %v", flag, value, err)
+			}
+		}
+	}
+
+	command := args[i:]
+	if len(command) == 0 {
+		command = []string{"echo"}
+	}
+
+	items, err := readXargsItems(stdio.Stdin, opts)
+	if err != nil {
+		return fmt.Errorf("xargs: %v", err)
+	}
+
+	// 按 -I 或 -n 把参数分成多次执行
+	var batches [][]string
+	switch {
+	case opts.replace != "":
+		for _, item := range items {
+			cmdline := make([]string, len(command))
+			for j, part := range command {
+				cmdline[j] = strings.ReplaceAll(part, opts.replace, item)
+			}
+			batches = append(batches, cmdline)
+		}
+	case len(items) == 0:
+		if !opts.noRunIfEmpty {
+			batches = append(batches, command)
+		}
+	default:
+		size := len(items)
+		if opts.maxArgs > 0 {
+			size = opts.maxArgs
+		}
+		for start := 0; start < len(items); start += size {
+			end := start + size
+			if end > len(items) {
+				end = len(items)
+			}
+			batches = append(batches, append(append([]string{}, command...), items[start:end]...))
+		}
+	}
+
+	// 并行执行时输出需要加锁
+	out, errOut := stdio.Stdout, stdio.Stderr
+	if opts.maxProcs != 1 {
+		var mu sync.Mutex
+		out = &lockedWriter{w: stdio.Stdout, mu: &mu}
+		errOut = &lockedWriter{w: stdio.Stderr, mu: &mu}
+	}
+
+	// 同时执行的命令数不超过 -P（0 表示不限制）
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	status := 0
+	slots := make(chan struct{}, len(batches)+1)
+	if opts.maxProcs > 0 {
+		slots = make(chan struct{}, opts.maxProcs)
+	}
+	for _, cmdline := range batches {
+		if opts.trace {
+			fmt.Fprintln(errOut, strings.Join(cmdline, " "))
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(cmdline []string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			code := runXargsCommand(lookup, cmdline, env, &IO{Stdin: strings.NewReader(""), Stdout: out, Stderr: errOut, Umask: stdio.Umask, Policy: stdio.Policy})
+			mu.Lock()
+			defer mu.Unlock()
+			// 命令未找到优先于命令失败
+			if code == 127 || (code != 0 && status != 127) {
+				status = code
+			}
+		}(cmdline)
+	}
+	wg.Wait()
+
+	switch status {
+	case 0:
+		return nil
+	case 127:
+		return &StatusError{Code: 127, Message: i18n.Sprintf("xargs: %s: 命令未找到", command[0])}
+	}
+	// 与 GNU xargs 相同，命令失败时只设置退出状态 123，不输出消息
+	return &StatusError{Code: 123}
+}
+
+// runXargsCommand 执行一次命令，返回退出状态
+// 内置命令使用环境变量的副本执行，与外部命令一样不影响当前shell
+func runXargsCommand(lookup func(name string) (BuiltinFunc, bool), cmdline []string, env map[string]string, stdio *IO) int {
+	if fn, ok := lookup(cmdline[0]); ok {
+		envCopy := make(map[string]string, len(env))
+		for k, v := range env {
+			envCopy[k] = v
+		}
+		err := fn(cmdline[1:], envCopy, stdio)
+		if err == nil {
+			return 0
+		}
+		// 只表示退出状态的错误（如 false、grep 没有匹配）没有消息，不输出
+		if msg := err.Error(); msg != "" {
+			if !strings.HasPrefix(msg, cmdline[0]+":") {
+				msg = cmdline[0] + ": " + msg
+			}
+			fmt.Fprintln(stdio.Stderr, msg)
+		}
+		var coded interface{ ExitCode() int }
+		if errors.As(err, &coded) {
+			return coded.ExitCode()
+		}
+		return 1
+	}
+
+	cmd, err := stdio.command(nil, cmdline[0], cmdline[1:], env)
+	if err == nil {
+		cmd.Env = stdio.environ(env)
+		cmd.Dir = workDir(env)
+		cmd.Stdout = stdio.Stdout
+		cmd.Stderr = stdio.Stderr
+		err = cmd.Run()
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	case errors.Is(err, exec.ErrNotFound):
+		return 127
+	}
+	fmt.Fprintf(stdio.Stderr, "xargs: %s: %v\n", cmdline[0], err)
+	return 126
+}
+
+// readXargsItems 读取输入中的参数
+// -0 和 -d 时按分隔符切分；-I 时每行是一个参数（去掉行首空白）；否则按空白切分，支持引号和反斜杠转义
+func readXargsItems(r io.Reader, opts xargsOptions) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	input := string(data)
+
+	switch {
+	case opts.nullDelim || opts.delimiter != "":
+		delim := "\x00"
+		if opts.delimiter != "" {
+			delim = opts.delimiter
+		}
+		items := strings.Split(input, delim)
+		if len(items) > 0 && items[len(items)-1] == "" {
+			items = items[:len(items)-1]
+		}
+		return items, nil
+	case opts.replace != "":
+		var items []string
+		scanner := bufio.NewScanner(strings.NewReader(input))
+		for scanner.Scan() {
+			if line := strings.TrimLeft(scanner.Text(), " \t"); line != "" {
+				items = append(items, line)
+			}
+		}
+		return items, scanner.Err()
+	}
+
+	var items []string
+	var current strings.Builder
+	inItem := false
+	var quote rune
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\n' {
+				return nil, unmatchedQuote(quote)
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inItem = true
+		case c == '\\' && i+1 < len(runes):
+			i++
+			current.WriteRune(runes[i])
+			inItem = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inItem {
+				items = append(items, current.String())
+				current.Reset()
+				inItem = false
+			}
+		default:
+			current.WriteRune(c)
+			inItem = true
+		}
+	}
+	if quote != 0 {
+		return nil, unmatchedQuote(quote)
+	}
+	if inItem {
+		items = append(items, current.String())
+	}
+	return items, nil
+}
+
+// unmatchedQuote 返回输入中有未闭合的引号时的错误
+func unmatchedQuote(quote rune) error {
+	if quote == '"' {
+		return i18n.Errorf("未匹配的双引号")
+	}
+	return i18n.Errorf("未匹配的单引号")
+}
+
+// lockedWriter 并行执行命令时保证每次写入不会交错
+type lockedWriter struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
@@ -29,9 +29,10 @@ func getGobashExe(t *testing.T) string {
 		return exePath
 	}
 	
-	// 尝试构建
+	// 尝试在项目根目录构建
 	t.Logf("gobash 可执行文件不存在，尝试构建...")
 	buildCmd := exec.Command("go", "build", "-o", exeName, "./cmd/gobash")
+	buildCmd.Dir = ".."
 	if err := buildCmd.Run(); err != nil {
 		t.Fatalf("构建 gobash 失败: %v", err)
 	}
@@ -367,3 +368,39 @@ func TestCompatibilityRedirection(t *testing.T) {
 	}
 }
 
+
+// TestCompatibilityCommandLineOptions 测试代替 bash 执行时命令行中的 bash 风格选项和 -c 之后的参数
+func TestCompatibilityCommandLineOptions(t *testing.T) {
+	gobashExe := getGobashExe(t)
+	script := filepath.Join(t.TempDir(), "x.sh")
+	if err := os.WriteFile(script, []byte("echo start\nfalse\necho after\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{script}, "start\nafter\n", 0},
+		{[]string{"-e", script}, "start\n", 1},
+		{[]string{"-euo", "pipefail", "-c", "false | true; echo no"}, "", 1},
+		{[]string{"+e", "-o", "pipefail", "-c", "false | true; echo $?"}, "1\n", 0},
+		{[]string{"-ec", "echo a; false; echo b"}, "a\n", 1},
+		{[]string{"-x", "-c", "echo hi"}, "+ echo hi\nhi\n", 0},
+		{[]string{"-c", `echo "[$0][$1][$#]"`, "foo", "bar"}, "[foo][bar][1]\n", 0},
+		{[]string{"-o", "nosuch", "-c", "echo no"}, "gobash: nosuch: invalid option name\n", 2},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(gobashExe, tt.args...)
+		cmd.Env = append(os.Environ(), "LANG=C", "LC_ALL=C")
+		out, err := cmd.CombinedOutput()
+		code := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code = exitErr.ExitCode()
+		}
+		if string(out) != tt.want || code != tt.code {
+			t.Errorf("gobash %q 输出 %q（退出码 %d），期望 %q（退出码 %d）", tt.args, out, code, tt.want, tt.code)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/builtin"
+	"gobash/internal/i18n"
+	"gobash/internal/parser"
+	"strconv"
+	"strings"
+)
+
+// arithmetic 计算算术表达式，$((...))、((...)) 和 let 共用
+// 逗号分隔的多个表达式依次求值，结果为最后一个表达式的值；表达式中的赋值和自增自减会修改变量
+func (e *Executor) arithmetic(expr string) (int64, error) {
+	var result int64
+	for _, part := range splitArithmeticList(expr) {
+		if strings.TrimSpace(part) == "" {
+			if strings.TrimSpace(expr) == "" {
+				return 0, nil
+			}
+			return 0, i18n.Errorf("%s: 语法错误: 需要操作数", strings.TrimSpace(expr))
+		}
+		expanded, err := e.expandVariablesInArithmeticExpression(strings.TrimSpace(part))
+		if err != nil {
+			return 0, err
+		}
+		if result, err = evaluateArithmeticExpression(expanded, e); err != nil {
+			return 0, fmt.Errorf("%s: %v", strings.TrimSpace(part), err)
+		}
+	}
+	return result, nil
+}
+
+// arithmeticInt 计算算术表达式，结果转换为 int（用于子字符串的偏移量和长度等）
+func (e *Executor) arithmeticInt(expr string) (int, error) {
+	result, err := e.arithmetic(expr)
+	return int(result), err
+}
+
+// arithmeticValue 计算算术表达式，返回十进制表示的结果（给有整数属性的变量赋值时使用）
+func (e *Executor) arithmeticValue(expr string) (string, error) {
+	result, err := e.arithmetic(expr)
+	return strconv.FormatInt(result, 10), err
+}
+
+// executeArithmeticCommand 执行 ((expr))：表达式的值非零时退出状态为 0，为零时为 1
+func (e *Executor) executeArithmeticCommand(stmt *parser.ArithmeticCommand) error {
+	e.xtraceArithmetic(stmt.Expression)
+	result, err := e.arithmetic(stmt.Expression)
+	if err != nil {
+		return &builtin.StatusError{Code: 1, Message: "((: " + err.Error()}
+	}
+	if result == 0 {
+		return &builtin.StatusError{Code: 1}
+	}
+	return nil
+}
+
+// splitArithmeticList 按不在括号和引号中的逗号拆分算术表达式
+func splitArithmeticList(expr string) []string {
+	var parts []string
+	start, depth := 0, 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case ',':
+			if depth == 0 {
+				parts = append(parts, expr[start:i])
+				start = i + 1
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '\'', '"':
+			if end := strings.IndexByte(expr[i+1:], expr[i]); end >= 0 {
+				i += end + 1
+			}
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+// arithmeticOperandEnd 返回从 from 开始的表达式的结束位置：不在括号和引号中的逗号或右括号，没有时为 s 的末尾
+func arithmeticOperandEnd(s string, from int) int {
+	depth := 0
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return i
+			}
+		case '\'', '"':
+			if end := strings.IndexByte(s[i+1:], s[i]); end >= 0 {
+				i += end + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// isNameStart 判断 c 是否可以作为变量名的开头
+func isNameStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// readArithmeticVariable 从 s[i] 开始读取变量名和可选的下标 [expr]，返回变量名、下标和结束位置
+func readArithmeticVariable(s string, i int) (name, subscript string, end int) {
+	end = i
+	for end < len(s) && (isNameStart(s[end]) || s[end] >= '0' && s[end] <= '9') {
+		end++
+	}
+	name = s[i:end]
+	if end < len(s) && s[end] == '[' {
+		if close := strings.IndexByte(s[end:], ']'); close > 0 {
+			subscript = s[end+1 : end+close]
+			end += close + 1
+		}
+	}
+	return name, subscript, end
+}
+
+// skipArithmeticSpaces 返回从 i 开始第一个不是空白的位置
+func skipArithmeticSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// endsWithOperand 判断已经展开的表达式是否以操作数（数字、变量名或右括号）结束
+func endsWithOperand(s string) bool {
+	s = strings.TrimRight(s, " \t\n")
+	if s == "" {
+		return false
+	}
+	c := s[len(s)-1]
+	return isNameStart(c) || c >= '0' && c <= '9' || c == ')'
+}
+
+// arithmeticAssignOperators 算术表达式中的赋值运算符（较长的在前）
+var arithmeticAssignOperators = []string{"<<=", ">>=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "="}
+
+// arithmeticAssignOperator 返回 s 开头的赋值运算符，不是赋值时返回空字符串（== 是比较运算符）
+func arithmeticAssignOperator(s string) string {
+	for _, op := range arithmeticAssignOperators {
+		if strings.HasPrefix(s, op) && !strings.HasPrefix(s[len(op):], "=") {
+			return op
+		}
+	}
+	return ""
+}
+
+// applyArithmeticOperator 计算复合赋值 a op= b 的值
+func applyArithmeticOperator(op string, a, b int64) (int64, error) {
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	case "<<":
+		return a << uint64(b), nil
+	case ">>":
+		return a >> uint64(b), nil
+	case "&":
+		return a & b, nil
+	case "|":
+		return a | b, nil
+	case "^":
+		return a ^ b, nil
+	}
+	return 0, i18n.Errorf("%s=: 无效的赋值运算符", op)
+}
+
+// arithmeticSubscript 计算数组下标：关联数组的下标是字符串，普通数组的下标是算术表达式
+func (e *Executor) arithmeticSubscript(name, subscript string) (string, error) {
+	if e.arrayTypes[e.resolveNameref(name)] == "assoc" {
+		return e.expandSubscript(subscript), nil
+	}
+	index, err := e.arithmetic(subscript)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(index, 10), nil
+}
+
+// arithmeticVariable 返回变量（或数组元素）的整数值，未设置或为空时为 0
+func (e *Executor) arithmeticVariable(name, subscript string) (int64, error) {
+	if subscript != "" {
+		key, err := e.arithmeticSubscript(name, subscript)
+		if err != nil {
+			return 0, err
+		}
+		name += "[" + key + "]"
+	}
+	value := strings.TrimSpace(e.indirectValue(name))
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, i18n.Errorf("%s: 无效的数值", value)
+	}
+	return n, nil
+}
+
+// setArithmeticVariable 把算术运算的结果赋给变量（或数组元素），名称引用指向的变量同样被修改
+func (e *Executor) setArithmeticVariable(name, subscript string, value int64) error {
+	if subscript != "" {
+		key, err := e.arithmeticSubscript(name, subscript)
+		if err != nil {
+			return err
+		}
+		name += "[" + key + "]"
+	}
+	return e.assignVariable(name, strconv.FormatInt(value, 10))
+}
@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"strings"
 	"testing"
 	"gobash/internal/lexer"
 	"gobash/internal/parser"
@@ -82,3 +83,44 @@ func TestArrayInString(t *testing.T) {
 	}
 }
 
+
+// TestArrayAppend 测试 += 追加数组元素和字符串（整数属性的变量做加法），普通数组下标的算术求值，以及稀疏数组的下标
+func TestArrayAppend(t *testing.T) {
+	input := `arr=(a b); arr+=(c "d e"); echo "${#arr[@]} ${arr[@]}"
+i=1; arr[${i}]=B; arr[i+1]=C; arr[-1]=Z; arr[1]+=X; echo "${arr[@]}"
+arr+=([6]=q); echo "${#arr[@]} ${arr[6]}"
+s=ab; s+=cd; n=1; n+=2; echo $s $n
+x=1; x+=2 env | grep '^x='; echo $x
+declare -A m; m[k]=v; m[k]+=w; echo ${m[k]}
+sp=(); sp[2]=two; sp[i+2]=three; sp+=(four); echo "${!sp[@]} ${sp[@]} ${#sp[@]} ${sp[-1]}"
+declare -i d=5; d+=1; d+=2*2; echo $d
+arr[-10]=no`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err == nil || !strings.Contains(err.Error(), "arr[-10]") {
+		t.Errorf("负数下标超出范围应该出错，得到 %v", err)
+	}
+	expected := "4 a b c d e\na BX C Z\n5 q\nabcd 12\nx=12\n1\nvw\n2 3 4 two three four 3 four\n10\n"
+	if stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+}
+
+// TestArrayUnset 测试 unset 删除整个数组或一个元素：其他元素的下标不变，之后的 += 不再使用删除的元素
+func TestArrayUnset(t *testing.T) {
+	input := `{
+arr=(1 2); unset arr; echo "[${arr[@]}]"; arr+=(x); echo "${!arr[@]} ${arr[@]}"
+a=(0 1 2); unset 'a[1]'; echo "${!a[@]} ${a[@]}"; a+=(3); unset 'a[-1]'; echo "${!a[@]} ${a[@]}"
+unset 'a[0]'; echo "[$a] ${a[@]}"; unset 'a[@]'; echo "[${a[@]}]"
+declare -A m=([k]=v [j]=w); unset 'm[k]'; echo "${!m[@]} ${m[@]}"
+f() { :; }; unset f; type f >/dev/null 2>&1 || echo no-f
+readonly r=1; unset r; echo "rc=$? $r"
+}`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "[]\n0 x\n0 2 0 2\n0 2 0 2\n[] 2\n[]\nj w\nno-f\nrc=1 1\n"
+	if stdout != expected || !strings.Contains(stderr, "r: 不能取消设置") {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, expected)
+	}
+}
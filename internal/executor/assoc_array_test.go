@@ -76,7 +76,7 @@ func TestAssocArrayAccess(t *testing.T) {
 	}
 	
 	// 验证访问
-	value := e.getArrayElement("arr[key1]")
+	value, _ := e.getArrayElement("arr[key1]")
 	if value != "value1" {
 		t.Errorf("关联数组访问失败，期望 'value1'，得到 '%s'", value)
 	}
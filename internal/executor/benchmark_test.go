@@ -16,7 +16,7 @@ func BenchmarkVariableExpansion(b *testing.B) {
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = e.expandWord(text)
+		_ = e.expandWord(text, false)
 	}
 }
 
@@ -50,7 +50,7 @@ func BenchmarkArithmeticExpansion(b *testing.B) {
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = e.evaluateArithmetic(expr)
+		_, _ = e.evaluateArithmetic(expr)
 	}
 }
 
@@ -68,3 +68,51 @@ func BenchmarkCommandExecution(b *testing.B) {
 	}
 }
 
+// BenchmarkStringInterpolation 基准测试双引号字符串中的变量展开
+func BenchmarkStringInterpolation(b *testing.B) {
+	e := New()
+	e.SetEnv("name", "world")
+	e.SetEnv("i", "42")
+	
+	text := "hello $name number $i ${name:-x}"
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = e.expandString(text)
+	}
+}
+
+// BenchmarkFunctionCall 基准测试函数调用（保存和恢复位置参数、调用栈）
+func BenchmarkFunctionCall(b *testing.B) {
+	e := New()
+	e.Execute(parser.New(lexer.New("f() { r=$1; }")).ParseProgram())
+	program := parser.New(lexer.New("f a b c")).ParseProgram()
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.Execute(program)
+	}
+}
+
+// BenchmarkInterpolationLoop 基准测试循环中带字符串插值的赋值和函数调用
+func BenchmarkInterpolationLoop(b *testing.B) {
+	input := `{
+name=world
+f() { local x="$1-$2"; r="$x"; }
+i=0
+while [ $i -lt 1000 ]; do
+  s="hello $name number $i ${name:-x}"
+  f "$s" $i
+  i=$((i + 1))
+done
+}`
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := New()
+		if err := e.Execute(program); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
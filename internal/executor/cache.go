@@ -0,0 +1,34 @@
+package executor
+
+import "sync"
+
+// cacheLimit 缓存的最大项数，超过时清空。eval、trap 等每次执行新文本时缓存不会无限增长
+const cacheLimit = 4096
+
+// cache 按键缓存只依赖于键的计算结果，可以在多个执行器（子shell、管道中的命令）中同时使用
+type cache[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]V
+}
+
+func newCache[K comparable, V any]() *cache[K, V] {
+	return &cache[K, V]{m: make(map[K]V)}
+}
+
+// get 返回 key 的缓存值，没有缓存时调用 compute 计算并缓存
+func (c *cache[K, V]) get(key K, compute func() V) V {
+	c.mu.Lock()
+	value, ok := c.m[key]
+	c.mu.Unlock()
+	if ok {
+		return value
+	}
+	value = compute()
+	c.mu.Lock()
+	if len(c.m) >= cacheLimit {
+		clear(c.m)
+	}
+	c.m[key] = value
+	c.mu.Unlock()
+	return value
+}
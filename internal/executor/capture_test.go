@@ -0,0 +1,36 @@
+package executor
+
+import (
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"os"
+	"testing"
+)
+
+// TestCapture 测试捕获输出：内置命令、外部命令、命令替换和 set -x 的输出都写入执行器的输出流，
+// 不修改进程的 os.Stdout/os.Stderr
+func TestCapture(t *testing.T) {
+	p := parser.New(lexer.New(`x=$(echo sub); echo builtin $x; sh -c 'echo external; echo oops >&2'`))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("解析失败: %v", p.Errors())
+	}
+
+	e := New()
+	e.SetOptions(map[string]bool{"x": true})
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	stdout, stderr, err := e.Capture(program)
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if os.Stdout != oldStdout || os.Stderr != oldStderr {
+		t.Error("Capture 不应该修改 os.Stdout/os.Stderr")
+	}
+
+	if want := "builtin sub\nexternal\n"; stdout != want {
+		t.Errorf("标准输出 %q，期望 %q", stdout, want)
+	}
+	if want := "++ echo sub\n+ x=sub\n+ echo builtin sub\n+ sh -c 'echo external; echo oops >&2'\noops\n"; stderr != want {
+		t.Errorf("错误输出 %q，期望 %q", stderr, want)
+	}
+}
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"gobash/internal/builtin"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"strings"
+	"testing"
+)
+
+// runSubstScript 解析并执行脚本，返回标准输出和执行错误
+func runSubstScript(t *testing.T, e *Executor, script string) (string, error) {
+	t.Helper()
+	p := parser.New(lexer.New(script))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("解析失败: %v", p.Errors())
+	}
+	stdout, _, err := e.Capture(program)
+	return stdout, err
+}
+
+// TestCommandSubstitutionScope 测试命令替换在子shell中执行：其中的赋值、数组修改和 cd 不影响当前shell
+func TestCommandSubstitutionScope(t *testing.T) {
+	e := New()
+	out, err := runSubstScript(t, e, `x=1; arr=(a b); w=$(x=2; arr[0]=z; cd /; echo $x ${arr[0]}); echo "$x ${arr[0]} $w"`)
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if out != "1 a 2 z\n" {
+		t.Errorf("输出 %q，期望 %q", out, "1 a 2 z\n")
+	}
+	if pwd, _ := e.GetEnv("PWD"); pwd == "/" {
+		t.Error("命令替换中的 cd 不应该改变当前shell的工作目录")
+	}
+}
+
+// TestCommandSubstitutionExitStatus 测试只有赋值的命令以命令替换的退出状态作为 $?
+func TestCommandSubstitutionExitStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"失败", `{ x=$(false); echo $?; }`, "1\n"},
+		{"exit 的退出码", `{ y=$(echo hi; exit 3); echo "$y $?"; }`, "hi 3\n"},
+		{"成功", `x=$(true); echo $?`, "0\n"},
+		{"多个赋值", `{ A=1 B=$(false) C="$(echo $A $B)"; echo "$? [$C]"; }`, "0 [1 ]\n"},
+		{"最后一个命令替换", `{ x=$(exit 4) y=2; echo $? $y; }`, "4 2\n"},
+		{"命令的退出状态", `echo $(false) $?`, " 1\n"},
+		{"去掉末尾的换行符", `a=$(printf 'a\n\n\n'); echo "[$a]"`, "[a]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := runSubstScript(t, New(), tt.script)
+			if out != tt.want {
+				t.Errorf("输出 %q，期望 %q", out, tt.want)
+			}
+		})
+	}
+}
+
+// TestCommandSubstitutionErrexit 测试 set -e 时 x=$(exit 4) 终止脚本，退出码为命令替换的退出状态
+func TestCommandSubstitutionErrexit(t *testing.T) {
+	e := New()
+	e.SetOptions(map[string]bool{"e": true})
+	out, err := runSubstScript(t, e, "x=$(exit 4)\necho notreached")
+	exitErr, ok := err.(*ScriptExitError)
+	if !ok {
+		t.Fatalf("期望 ScriptExitError，得到 %v", err)
+	}
+	if exitErr.Code != 4 {
+		t.Errorf("退出码 %d，期望 4", exitErr.Code)
+	}
+	if strings.Contains(out, "notreached") {
+		t.Error("set -e 应该在赋值失败后终止脚本")
+	}
+}
+
+// TestCommandSubstitutionRegisteredBuiltin 测试命令替换中可以使用通过 RegisterBuiltin 注册的命令
+func TestCommandSubstitutionRegisteredBuiltin(t *testing.T) {
+	e := New()
+	e.RegisterBuiltin("greet", func(args []string, env map[string]string, stdio *builtin.IO) error {
+		stdio.Stdout.Write([]byte("hello\n"))
+		return nil
+	})
+	out, err := runSubstScript(t, e, `echo "$(greet)!"`)
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if out != "hello!\n" {
+		t.Errorf("输出 %q，期望 %q", out, "hello!\n")
+	}
+}
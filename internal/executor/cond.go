@@ -0,0 +1,237 @@
+package executor
+
+import (
+	"gobash/internal/builtin"
+	"gobash/internal/i18n"
+	"gobash/internal/parser"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// [[ ]] 条件命令的求值
+//
+// 条件表达式在解析时已经构造为语法树，这里按树求值，&& 和 || 短路求值；单词不进行单词分割和路径名展开。
+// == 和 != 的右边是模式，=~ 的右边是 POSIX 扩展正则表达式，其中引号中的部分（包括 \ 转义的字符）按字面意义匹配；
+// =~ 匹配时整个匹配和各个子表达式的匹配保存在数组 BASH_REMATCH 中。-eq 等数值比较的两边按算术表达式求值
+
+// executeCondCommand 执行 [[ ]] 命令，条件为假时退出状态为 1，正则表达式无效时为 2（与 bash 相同，不输出错误信息）
+func (e *Executor) executeCondCommand(cmd *parser.CommandStatement) error {
+	if cmd.Cond == nil {
+		return i18n.Errorf("[[: 缺少参数")
+	}
+	result, err := e.evaluateCondition(cmd.Cond)
+	if err != nil {
+		return err
+	}
+	if !result {
+		// 条件为假，退出状态为 1（与 (( )) 相同，不输出错误信息）
+		return &builtin.StatusError{Code: 1}
+	}
+	return nil
+}
+
+// evaluateCondition 求值条件表达式
+func (e *Executor) evaluateCondition(cond parser.CondExpression) (bool, error) {
+	switch c := cond.(type) {
+	case *parser.CondBinary:
+		left, err := e.evaluateCondition(c.Left)
+		if err != nil || left == (c.Op == "||") {
+			return left, err
+		}
+		return e.evaluateCondition(c.Right)
+	case *parser.CondNot:
+		result, err := e.evaluateCondition(c.Expr)
+		return !result, err
+	case *parser.CondParen:
+		return e.evaluateCondition(c.Expr)
+	case *parser.CondWord:
+		value, err := e.evaluateExpression(c.Word)
+		if err != nil {
+			return false, err
+		}
+		e.xtraceCond("-n", xtraceQuote(value))
+		return value != "", nil
+	case *parser.CondUnary:
+		operand, err := e.evaluateExpression(c.Operand)
+		if err != nil {
+			return false, err
+		}
+		e.xtraceCond(c.Op, xtraceQuote(operand))
+		return e.condUnary(c.Op, operand), nil
+	case *parser.CondCompare:
+		return e.condCompare(c)
+	}
+	return false, i18n.Errorf("[[: 缺少参数")
+}
+
+// condUnary 求值一元测试
+func (e *Executor) condUnary(op, operand string) bool {
+	switch op {
+	case "-n":
+		return operand != ""
+	case "-z":
+		return operand == ""
+	case "-v":
+		name := e.resolveNameref(operand)
+		_, isArray := e.arrays[name]
+		_, isVar := e.env[name]
+		return isArray || isVar
+	}
+
+	stat := os.Stat
+	if op == "-h" || op == "-L" {
+		stat = os.Lstat
+	}
+	info, err := stat(e.resolvePath(operand))
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	switch op {
+	case "-f":
+		return mode.IsRegular()
+	case "-d":
+		return mode.IsDir()
+	case "-s":
+		return info.Size() > 0
+	case "-h", "-L":
+		return mode&os.ModeSymlink != 0
+	case "-p":
+		return mode&os.ModeNamedPipe != 0
+	case "-S":
+		return mode&os.ModeSocket != 0
+	case "-b":
+		return mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0
+	case "-c":
+		return mode&os.ModeCharDevice != 0
+	}
+	// -e、-a，以及与 test 命令相同简化为文件存在即可的 -r、-w、-x
+	return true
+}
+
+// condCompare 求值二元测试
+func (e *Executor) condCompare(c *parser.CondCompare) (bool, error) {
+	left, err := e.evaluateExpression(c.Left)
+	if err != nil {
+		return false, err
+	}
+	switch c.Op {
+	case "==", "=", "!=":
+		pattern, err := e.condPattern(c.Right, escapePattern)
+		if err != nil {
+			return false, err
+		}
+		e.xtraceCond(xtraceQuote(left), c.Op, pattern)
+		return matchPattern(left, pattern) == (c.Op != "!="), nil
+	case "=~":
+		return e.condRegexMatch(left, c.Right)
+	}
+
+	right, err := e.evaluateExpression(c.Right)
+	if err != nil {
+		return false, err
+	}
+	e.xtraceCond(xtraceQuote(left), c.Op, xtraceQuote(right))
+	switch c.Op {
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "-nt", "-ot", "-ef":
+		return e.condFiles(c.Op, left, right), nil
+	}
+
+	// 数值比较，两边是算术表达式
+	a, err := e.arithmetic(left)
+	if err != nil {
+		return false, &builtin.StatusError{Code: 1, Message: "[[: " + err.Error()}
+	}
+	b, err := e.arithmetic(right)
+	if err != nil {
+		return false, &builtin.StatusError{Code: 1, Message: "[[: " + err.Error()}
+	}
+	switch c.Op {
+	case "-eq":
+		return a == b, nil
+	case "-ne":
+		return a != b, nil
+	case "-lt":
+		return a < b, nil
+	case "-le":
+		return a <= b, nil
+	case "-gt":
+		return a > b, nil
+	default: // -ge
+		return a >= b, nil
+	}
+}
+
+// condRegexMatch 用 =~ 右边的正则表达式匹配 value，并设置 BASH_REMATCH
+func (e *Executor) condRegexMatch(value string, word parser.Expression) (bool, error) {
+	pattern, err := e.condPattern(word, regexp.QuoteMeta)
+	if err != nil {
+		return false, err
+	}
+	e.xtraceCond(xtraceQuote(value), "=~", pattern)
+	re, err := regexp.CompilePOSIX(pattern)
+	if err != nil {
+		return false, &builtin.StatusError{Code: 2}
+	}
+	match := re.FindStringSubmatch(value)
+	if match == nil {
+		e.unsetArray("BASH_REMATCH")
+		return false, nil
+	}
+	e.setArray("BASH_REMATCH", nil, match)
+	return true, nil
+}
+
+// condFiles 比较两个文件：-nt 修改时间较新（或右边的文件不存在），-ot 修改时间较旧（或左边的文件不存在），-ef 是同一个文件
+func (e *Executor) condFiles(op, left, right string) bool {
+	a, errA := os.Stat(e.resolvePath(left))
+	b, errB := os.Stat(e.resolvePath(right))
+	switch op {
+	case "-nt":
+		return errA == nil && (errB != nil || a.ModTime().After(b.ModTime()))
+	case "-ot":
+		return errB == nil && (errA != nil || a.ModTime().Before(b.ModTime()))
+	default: // -ef
+		return errA == nil && errB == nil && os.SameFile(a, b)
+	}
+}
+
+// condPattern 展开 ==、!= 和 =~ 右边的单词：不带引号的部分保留特殊含义，引号中的部分用 quote 转义
+func (e *Executor) condPattern(word parser.Expression, quote func(string) string) (string, error) {
+	parts := []parser.Expression{word}
+	if concat, ok := word.(*parser.ConcatExpression); ok {
+		parts = concat.Parts
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		value, err := e.evaluateExpression(part)
+		if err != nil {
+			return "", err
+		}
+		if _, quoted := part.(*parser.StringLiteral); quoted {
+			value = quote(value)
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+// escapePattern 转义字符串中的模式字符，使 matchPattern 按字面意义匹配
+func escapePattern(s string) string {
+	if !strings.ContainsAny(s, `*?[\`) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package executor
+
+import "os"
+
+// trackForeground 只在 Windows 上需要（把前台命令加入作业对象）
+func trackForeground(p *os.Process) {}
+
+// interruptProcess 把 shell 收到的信号转发给前台命令
+func interruptProcess(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
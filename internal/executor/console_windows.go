@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// setProcessGroup 让外部命令在自己的控制台进程组中运行（Windows 没有作业控制，group 总是 nil）
+// 新进程组中的程序不响应控制台的 Ctrl+C：前台命令的 Ctrl+C 由 shell 接收后转发（见 interruptProcess），
+// 后台作业则不会被终端上的 Ctrl+C 终止
+func setProcessGroup(cmd *exec.Cmd, group *processGroup) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+var (
+	foregroundJobOnce sync.Once
+	foregroundJob     windows.Handle
+)
+
+// trackForeground 把前台命令加入 shell 的作业对象
+// 作业对象设置了 JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE，shell 退出（包括被强制结束）时系统终止其中的进程，前台命令不会成为孤儿
+func trackForeground(p *os.Process) {
+	foregroundJobOnce.Do(func() {
+		job, err := windows.CreateJobObject(nil, nil)
+		if err != nil {
+			return
+		}
+		info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+			BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+				LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+			},
+		}
+		if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+			uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+			windows.CloseHandle(job)
+			return
+		}
+		foregroundJob = job
+	})
+	if foregroundJob == 0 {
+		return
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(p.Pid))
+	if err != nil {
+		return
+	}
+	defer windows.CloseHandle(process)
+	_ = windows.AssignProcessToJobObject(foregroundJob, process)
+}
+
+// interruptProcess 把 shell 收到的 Ctrl+C 或 Ctrl+Break 转发给前台命令的进程组
+// Windows 不能向指定的进程组发送 CTRL_C_EVENT，因此发送 CTRL_BREAK_EVENT（控制台程序默认同样会退出）
+func interruptProcess(p *os.Process, sig os.Signal) error {
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(p.Pid))
+}
+
+// canControlTerminal Windows 不支持作业控制
+func canControlTerminal(tty *os.File) bool {
+	return false
+}
+
+func setTerminalForeground(tty *os.File, pgid int) error {
+	return nil
+}
+
+func shellProcessGroup() int {
+	return 0
+}
+
+func continueProcesses(pid, pgid int) error {
+	return nil
+}
+
+// waitStopped Windows 上的进程不会被停止
+func waitStopped(pid int) (syscall.Signal, bool) {
+	return 0, false
+}
@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"testing"
+	"time"
+)
+
+// TestExecuteContext 测试取消执行：外部命令、管道、循环和命令替换都能在超时后停止
+func TestExecuteContext(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"外部命令", "sleep 5"},
+		{"管道", "sleep 5 | cat"},
+		{"while 循环", "while true; do x=1; done"},
+		{"for 循环", "for i in 1 2 3 4 5; do sleep 1; done"},
+		{"命令替换", "x=$(sleep 5)"},
+		{"函数", "f() { sleep 5; }; f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			program := p.ParseProgram()
+			if len(p.Errors()) > 0 {
+				t.Fatalf("解析 %q 失败: %v", tt.input, p.Errors())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := New().ExecuteContext(ctx, program)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("期望 context.DeadlineExceeded，得到 %v", err)
+			}
+			if ExitStatus(err) != 130 {
+				t.Errorf("退出码 %d，期望 130", ExitStatus(err))
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("取消后应该立即停止，实际耗时 %s", elapsed)
+			}
+		})
+	}
+}
+
+// TestCommandTimeout 测试单个命令超时：命令以 124 失败，脚本继续执行
+func TestCommandTimeout(t *testing.T) {
+	p := parser.New(lexer.New("{ sleep 5; STATUS=$?; }"))
+	program := p.ParseProgram()
+
+	e := New()
+	e.SetCommandTimeout(100 * time.Millisecond)
+	if err := e.Execute(program); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if status := e.env["STATUS"]; status != "124" {
+		t.Errorf("STATUS = %q，期望 124", status)
+	}
+}
+
+// TestTimeoutBuiltin 测试 timeout：外部命令、内置命令、函数和循环超时后被终止，退出码为 124
+func TestTimeoutBuiltin(t *testing.T) {
+	tests := []struct {
+		input  string
+		status string
+	}{
+		{"{ timeout 0.1 sleep 5; STATUS=$?; }", "124"},
+		{"{ timeout 0.1 /bin/sh -c 'sleep 5'; STATUS=$?; }", "124"},
+		{"f() { while true; do sleep 0.01; done; }; { timeout 0.1 f; STATUS=$?; }", "124"},
+		{"{ timeout 5 true; STATUS=$?; }", "0"},
+		{"{ timeout 5 sh -c 'exit 3'; STATUS=$?; }", "3"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		e := New()
+		start := time.Now()
+		if err := e.Execute(program); err != nil {
+			t.Errorf("%q: 执行失败: %v", tt.input, err)
+		}
+		if status := e.env["STATUS"]; status != tt.status {
+			t.Errorf("%q: STATUS = %q，期望 %q", tt.input, status, tt.status)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("%q: 超时后应该立即停止，实际耗时 %s", tt.input, elapsed)
+		}
+	}
+}
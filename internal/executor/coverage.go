@@ -0,0 +1,259 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"gobash/internal/parser"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 覆盖率
+//
+// 设置了 Coverage 时，执行器记录脚本文件中每一行语句的执行次数。执行脚本前由 CoverProgram 遍历语法树，
+// 把所有语句（包括函数体和没有执行的分支）所在的行登记为可执行的行，报告中执行次数为 0 的行就是没有覆盖的行。
+// 只统计脚本文件（设置了脚本名时），-c 和交互式输入不统计；陷阱中的命令不计入
+
+// FileCoverage 一个脚本文件的覆盖率
+type FileCoverage struct {
+	Script string      // 脚本名
+	Hits   map[int]int // 可执行的行 -> 执行次数
+}
+
+// Covered 返回执行过的行数
+func (f FileCoverage) Covered() int {
+	n := 0
+	for _, hits := range f.Hits {
+		if hits > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Lines 返回按行号排序的可执行的行
+func (f FileCoverage) Lines() []int {
+	lines := make([]int, 0, len(f.Hits))
+	for line := range f.Hits {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Coverage 记录脚本的行覆盖率，可以在多个执行器（子shell、管道中的命令）中同时使用
+type Coverage struct {
+	mu    sync.Mutex
+	files map[string]map[int]int
+}
+
+// NewCoverage 创建 Coverage
+func NewCoverage() *Coverage {
+	return &Coverage{files: make(map[string]map[int]int)}
+}
+
+// SetCoverage 设置记录行覆盖率的 Coverage，nil 表示不记录
+func (e *Executor) SetCoverage(c *Coverage) {
+	e.coverage = c
+}
+
+// CoverProgram 把即将执行的脚本中所有语句所在的行登记为可执行的行（设置了 Coverage 和脚本名时）
+func (e *Executor) CoverProgram(program *parser.Program) {
+	if e.coverage == nil || e.scriptName == "" || program == nil {
+		return
+	}
+	e.coverage.mu.Lock()
+	defer e.coverage.mu.Unlock()
+	hits := e.coverage.file(e.scriptName)
+	for _, stmt := range program.Statements {
+		statementLines(stmt, hits)
+	}
+}
+
+// cover 记录执行了 line 行的语句
+func (e *Executor) cover(line int) {
+	if e.coverage == nil || e.scriptName == "" || e.inTrap || line <= 0 {
+		return
+	}
+	e.coverage.mu.Lock()
+	e.coverage.file(e.scriptName)[line]++
+	e.coverage.mu.Unlock()
+}
+
+// coverStatement 记录执行了语句 stmt（只记录 coverable 的语句）
+func (e *Executor) coverStatement(stmt parser.Statement, line int) {
+	if coverable(stmt) {
+		e.cover(line)
+	}
+}
+
+// coverable 检查语句的执行是否计入它所在的行
+// 管道、命令链等只是包装其中的命令，if 和 while 由条件计入，这样每条命令只计一次
+func coverable(stmt parser.Statement) bool {
+	switch stmt.(type) {
+	case *parser.IfStatement, *parser.WhileStatement, *parser.PipelineStatement, *parser.CommandChain,
+		*parser.NegatedStatement, *parser.TimedStatement, *parser.BackgroundStatement:
+		return false
+	}
+	return true
+}
+
+// file 返回脚本的行 -> 执行次数，调用者持有锁
+func (c *Coverage) file(script string) map[int]int {
+	hits, ok := c.files[script]
+	if !ok {
+		hits = make(map[int]int)
+		c.files[script] = hits
+	}
+	return hits
+}
+
+// Files 返回按脚本名排序的各脚本的覆盖率
+func (c *Coverage) Files() []FileCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files := make([]FileCoverage, 0, len(c.files))
+	for script, hits := range c.files {
+		f := FileCoverage{Script: script, Hits: make(map[int]int, len(hits))}
+		for line, n := range hits {
+			f.Hits[line] = n
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Script < files[j].Script })
+	return files
+}
+
+// WriteReport 把每个脚本覆盖的行数、百分比和没有覆盖的行写到 w
+func (c *Coverage) WriteReport(w io.Writer) {
+	i18n.Fprintf(w, "覆盖率:\n")
+	total, covered := 0, 0
+	for _, f := range c.Files() {
+		n := f.Covered()
+		total += len(f.Hits)
+		covered += n
+		i18n.Fprintf(w, "  %s: %d/%d 行 (%.1f%%)\n", f.Script, n, len(f.Hits), percent(n, len(f.Hits)))
+		if missed := missedLines(f); missed != "" {
+			i18n.Fprintf(w, "    未覆盖的行: %s\n", missed)
+		}
+	}
+	i18n.Fprintf(w, "  合计: %d/%d 行 (%.1f%%)\n", covered, total, percent(covered, total))
+}
+
+// WriteLCOV 以 LCOV 跟踪文件的格式把每一行的执行次数写到 w
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	for _, f := range c.Files() {
+		var b strings.Builder
+		fmt.Fprintf(&b, "TN:\nSF:%s\n", f.Script)
+		for _, line := range f.Lines() {
+			fmt.Fprintf(&b, "DA:%d,%d\n", line, f.Hits[line])
+		}
+		fmt.Fprintf(&b, "LF:%d\nLH:%d\nend_of_record\n", len(f.Hits), f.Covered())
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// percent 返回 n 占 total 的百分比，total 为 0 时为 100
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// missedLines 把没有执行过的行合并成区间，如 "4, 7-9"
+func missedLines(f FileCoverage) string {
+	var ranges []string
+	start, prev := 0, 0
+	flush := func() {
+		if start == 0 {
+			return
+		}
+		if start == prev {
+			ranges = append(ranges, strconv.Itoa(start))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", start, prev))
+		}
+		start = 0
+	}
+	for _, line := range f.Lines() {
+		if f.Hits[line] > 0 {
+			flush()
+			continue
+		}
+		// 中间隔着非可执行的行（空行、注释）的未覆盖行也合并到同一个区间
+		if start == 0 {
+			start = line
+		}
+		prev = line
+	}
+	flush()
+	return strings.Join(ranges, ", ")
+}
+
+// statementLines 把语句及其中嵌套的语句所在的行登记到 hits（已有的执行次数不变）
+func statementLines(stmt parser.Statement, hits map[int]int) {
+	if stmt == nil {
+		return
+	}
+	if pos := parser.StatementPos(stmt); pos.IsValid() && coverable(stmt) {
+		if _, ok := hits[pos.Line]; !ok {
+			hits[pos.Line] = 0
+		}
+	}
+	block := func(b *parser.BlockStatement) {
+		if b != nil {
+			for _, s := range b.Statements {
+				statementLines(s, hits)
+			}
+		}
+	}
+	switch s := stmt.(type) {
+	case *parser.IfStatement:
+		block(s.Condition)
+		block(s.Consequence)
+		for _, elif := range s.Elif {
+			block(elif.Condition)
+			block(elif.Consequence)
+		}
+		block(s.Alternative)
+	case *parser.ForStatement:
+		block(s.Body)
+	case *parser.WhileStatement:
+		block(s.Condition)
+		block(s.Body)
+	case *parser.FunctionStatement:
+		block(s.Body)
+	case *parser.CaseStatement:
+		for _, clause := range s.Cases {
+			block(clause.Body)
+		}
+	case *parser.BlockStatement:
+		block(s)
+	case *parser.SubshellCommand:
+		block(s.Body)
+	case *parser.GroupCommand:
+		block(s.Body)
+	case *parser.CoprocStatement:
+		statementLines(s.Statement, hits)
+	case *parser.PipelineStatement:
+		for _, cmd := range s.Commands {
+			statementLines(cmd, hits)
+		}
+	case *parser.CommandChain:
+		statementLines(s.Left, hits)
+		statementLines(s.Right, hits)
+	case *parser.NegatedStatement:
+		statementLines(s.Statement, hits)
+	case *parser.TimedStatement:
+		statementLines(s.Statement, hits)
+	case *parser.BackgroundStatement:
+		statementLines(s.Statement, hits)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package executor
+
+import "time"
+
+// cpuTimes 在不支持 getrusage 的系统上总是返回 0
+func cpuTimes() (user, sys time.Duration) {
+	return 0, 0
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package executor
+
+import (
+	"syscall"
+	"time"
+)
+
+// cpuTimes 返回 shell 进程和已经结束的子进程累计使用的用户态和内核态 CPU 时间
+func cpuTimes() (user, sys time.Duration) {
+	for _, who := range []int{syscall.RUSAGE_SELF, syscall.RUSAGE_CHILDREN} {
+		var usage syscall.Rusage
+		if syscall.Getrusage(who, &usage) != nil {
+			continue
+		}
+		user += time.Duration(usage.Utime.Nano())
+		sys += time.Duration(usage.Stime.Nano())
+	}
+	return user, sys
+}
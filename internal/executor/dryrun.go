@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/parser"
+	"strings"
+)
+
+// 试运行
+//
+// 试运行模式下外部命令不会真正执行：展开后的命令和重定向按 set -x 的格式输出到标准错误，命令被视为执行成功
+// （退出状态为 0，没有输出）。修改文件、访问网络、向进程发送信号或执行其他命令的内置命令，以及带有写入文件的重定向的命令
+// 同样只输出不执行；其他内置命令、变量赋值、函数和控制结构正常执行，使展开的结果尽量真实。
+// 复合命令上写入文件的重定向不会创建文件，输出被丢弃
+
+// dryRunPrefix 试运行时输出的命令前缀
+const dryRunPrefix = "[dry-run] "
+
+// dryRunBuiltins 试运行时只输出不执行的内置命令
+var dryRunBuiltins = map[string]bool{
+	"rm": true, "rmdir": true, "mkdir": true, "touch": true, "tee": true, "mktemp": true,
+	"tar": true, "gzip": true, "gunzip": true, "http": true,
+	"kill": true, "pkill": true, "xargs": true, "timeout": true,
+}
+
+// SetDryRun 设置试运行模式：外部命令和有副作用的内置命令只输出不执行
+func (e *Executor) SetDryRun(dryRun bool) {
+	e.dryRun = dryRun
+}
+
+// dryRunSkips 检查试运行时是否只输出内置命令 name 而不执行它
+func (e *Executor) dryRunSkips(name string, args []string, redirects []*parser.Redirect) bool {
+	if !e.dryRun {
+		return false
+	}
+	for _, redirect := range redirects {
+		if e.dryRunDiscards(redirect) {
+			return true
+		}
+	}
+	if dryRunBuiltins[name] {
+		return true
+	}
+	switch name {
+	case "sed":
+		// sed -i 修改文件
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-i") || arg == "--in-place" {
+				return true
+			}
+		}
+	case "command":
+		// command -v、command -V 只显示命令的信息
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				return true
+			}
+			if strings.ContainsAny(arg, "vV") {
+				return false
+			}
+		}
+	case "env":
+		// env 后面有命令时执行该命令，否则只显示环境变量
+		for i := 0; i < len(args); i++ {
+			switch {
+			case args[i] == "-u":
+				i++
+			case strings.HasPrefix(args[i], "-"), strings.Contains(args[i], "="):
+			default:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dryRunDiscards 检查试运行时重定向是否写入文件（不打开文件，输出被丢弃）；写到 /dev/null 的重定向照常执行
+func (e *Executor) dryRunDiscards(redirect *parser.Redirect) bool {
+	if !e.dryRun {
+		return false
+	}
+	switch redirect.Type {
+	case parser.REDIRECT_OUTPUT, parser.REDIRECT_APPEND, parser.REDIRECT_CLOBBER, parser.REDIRECT_RW:
+	default:
+		return false
+	}
+	switch t := redirect.Target.(type) {
+	case *parser.Identifier, *parser.StringLiteral, *parser.Variable:
+		if target, err := e.expandExpression(t); err == nil && target == "/dev/null" {
+			return false
+		}
+	}
+	return true
+}
+
+// dryRunPrint 输出试运行时没有执行的命令
+func (e *Executor) dryRunPrint(words []string, redirects []*parser.Redirect) {
+	fmt.Fprintln(e.Stdio().Stderr, dryRunPrefix+e.formatCommand(words, redirects))
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 动态变量
+//
+// RANDOM、SECONDS、EPOCHSECONDS 和 EPOCHREALTIME 的值在每次读取时计算：RANDOM 是 0 到 32767 的随机数（赋值设置随机数种子），
+// SECONDS 是 shell 启动（或最后一次给 SECONDS 赋值）以来的秒数加上赋的值，EPOCHSECONDS 和 EPOCHREALTIME 是当前的 Unix 时间
+// （整数秒和带微秒的秒）。与 bash 相同，用 unset 删除之后它们成为普通的变量。
+// LINENO 由执行器在执行每条语句时更新（见 location.go），UID、EUID、PPID 和 HOSTNAME 在创建执行器时设置。
+// 这些变量都不传给外部命令（环境中已有的 HOSTNAME 除外）
+
+// dynamicVariables 读取时计算值的变量：value 是变量现在的值，assigned 表示读取之前给变量赋过值
+var dynamicVariables = map[string]func(e *Executor, value string, assigned bool) string{
+	"RANDOM": func(e *Executor, value string, assigned bool) string {
+		if assigned {
+			seed, _ := strconv.ParseInt(value, 10, 64)
+			e.random = rand.New(rand.NewSource(seed))
+		}
+		return strconv.Itoa(e.random.Intn(32768))
+	},
+	"SECONDS": func(e *Executor, value string, assigned bool) string {
+		if assigned {
+			e.secondsBase, _ = strconv.ParseInt(value, 10, 64)
+			e.secondsStart = time.Now()
+		}
+		return strconv.FormatInt(e.secondsBase+int64(time.Since(e.secondsStart)/time.Second), 10)
+	},
+	"EPOCHSECONDS": func(e *Executor, value string, assigned bool) string {
+		return strconv.FormatInt(time.Now().Unix(), 10)
+	},
+	"EPOCHREALTIME": func(e *Executor, value string, assigned bool) string {
+		now := time.Now()
+		return fmt.Sprintf("%d.%06d", now.Unix(), now.Nanosecond()/1000)
+	},
+}
+
+// initShellVariables 设置动态变量和 UID、EUID、PPID，它们都不导出
+func (e *Executor) initShellVariables() {
+	e.secondsStart = time.Now()
+	for name := range dynamicVariables {
+		e.env[name] = ""
+		e.refreshDynamic(name)
+	}
+	if uid := os.Getuid(); uid >= 0 {
+		e.env["UID"] = strconv.Itoa(uid)
+		e.env["EUID"] = strconv.Itoa(os.Geteuid())
+	}
+	e.env["PPID"] = strconv.Itoa(os.Getppid())
+}
+
+// initHostname 环境中没有 HOSTNAME 时设置为主机名（不导出）
+func (e *Executor) initHostname() {
+	if _, ok := e.env["HOSTNAME"]; ok {
+		return
+	}
+	if host, err := os.Hostname(); err == nil {
+		e.env["HOSTNAME"] = host
+	}
+}
+
+// refreshDynamic 读取变量 name 之前，如果它是动态变量，把它现在的值保存到 e.env 中
+func (e *Executor) refreshDynamic(name string) {
+	compute, ok := dynamicVariables[name]
+	if !ok {
+		return
+	}
+	value, set := e.env[name]
+	if !set {
+		// unset 之后是普通的变量
+		return
+	}
+	value = compute(e, value, value != e.dynamicValues[name])
+	e.env[name] = value
+	e.dynamicValues[name] = value
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"testing"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+)
+
+// TestErrexit 测试 set -e 的语义：失败的命令返回 ScriptExitError，条件上下文中不生效
+func TestErrexit(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantExit bool // 是否应该因为 set -e 退出
+		code     int
+	}{
+		{"简单命令失败", "false", true, 1},
+		{"外部命令的退出码", "sh -c 'exit 3'", true, 3},
+		{"if 条件", "if false; then true; fi", false, 0},
+		{"while 条件", "while false; do true; done", false, 0},
+		{"&& 左侧", "false && true", false, 0},
+		{"|| 左侧", "false || true", false, 0},
+		{"|| 右侧", "true || false; false || false", true, 1},
+		{"! 取反", "! true", false, 0},
+		{"函数体", "f() { false; true; }; f", true, 1},
+		{"代码块中间的命令", "{ false; true; }", true, 1},
+		{"子shell", "(exit 4)", true, 4},
+		{"条件中调用的函数", "g() { false; true; }; if g; then true; fi", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New()
+			e.SetOptions(map[string]bool{"e": true})
+			p := parser.New(lexer.New(tt.input))
+			program := p.ParseProgram()
+			if len(p.Errors()) > 0 {
+				t.Fatalf("解析 %q 失败: %v", tt.input, p.Errors())
+			}
+
+			var exitErr *ScriptExitError
+			for _, stmt := range program.Statements {
+				err := e.Execute(&parser.Program{Statements: []parser.Statement{stmt}})
+				if se, ok := err.(*ScriptExitError); ok {
+					exitErr = se
+					break
+				}
+			}
+
+			if tt.wantExit {
+				if exitErr == nil {
+					t.Fatalf("%q 应该因为 set -e 退出", tt.input)
+				}
+				if exitErr.Code != tt.code {
+					t.Errorf("退出码 %d，期望 %d", exitErr.Code, tt.code)
+				}
+			} else if exitErr != nil {
+				t.Errorf("%q 不应该因为 set -e 退出，得到 %v", tt.input, exitErr)
+			}
+		})
+	}
+}
+
+// TestBlockContinuesAfterFailure 测试未开启 set -e 时，代码块中间的命令失败不会中断执行
+func TestBlockContinuesAfterFailure(t *testing.T) {
+	e := New()
+	p := parser.New(lexer.New("if true; then false; RESULT=done; fi"))
+	program := p.ParseProgram()
+	if err := e.Execute(program); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if got := e.env["RESULT"]; got != "done" {
+		t.Errorf("RESULT = %q，期望 done", got)
+	}
+}
+
+// TestErrexitCommandSubstitution 测试命令替换不继承 set -e（shopt -s inherit_errexit 时继承），其中的命令失败后继续执行
+func TestErrexitCommandSubstitution(t *testing.T) {
+	input := `x=$(echo a; false; echo b); echo "[$x]"`
+	e := New()
+	e.SetOptions(map[string]bool{"e": true})
+	if stdout, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram()); err != nil || stdout != "[a\nb]\n" {
+		t.Errorf("set -e 时输出 %q（%v），期望 %q", stdout, err, "[a\nb]\n")
+	}
+
+	// 继承 set -e 时命令替换在 false 处终止，退出状态 1 使赋值失败
+	e = New()
+	e.SetOptions(map[string]bool{"e": true, "inherit_errexit": true})
+	stdout, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if exitErr, ok := err.(*ScriptExitError); !ok || exitErr.Code != 1 || stdout != "" {
+		t.Errorf("inherit_errexit 时输出 %q（%v），期望因为 set -e 退出", stdout, err)
+	}
+}
+
+// TestFalseAndTestAreSilent 测试 false 和条件为假的 test、[ 只设置退出状态，代码块中间、ERR 陷阱和 set -e 时都不输出错误消息
+func TestFalseAndTestAreSilent(t *testing.T) {
+	input := `trap 'echo "trap $?"' ERR
+{ false; [ a = b ]; test -d /nonexistent; test; echo ok; }`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil || stderr != "" || stdout != "trap 1\ntrap 1\ntrap 1\ntrap 1\nok\n" {
+		t.Errorf("输出 %q，错误输出 %q（%v）", stdout, stderr, err)
+	}
+
+	e := New()
+	e.SetOptions(map[string]bool{"e": true})
+	_, stderr, err = e.Capture(parser.New(lexer.New("[ a = b ]")).ParseProgram())
+	exitErr, ok := err.(*ScriptExitError)
+	if !ok || exitErr.Code != 1 || exitErr.Err == nil || exitErr.Err.Error() != "" || stderr != "" {
+		t.Errorf("set -e 时 [ a = b ] 应该只以状态 1 退出: %v，错误输出 %q", err, stderr)
+	}
+}
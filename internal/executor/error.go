@@ -1,62 +1,94 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
+	"gobash/internal/builtin"
+	"gobash/internal/i18n"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"io/fs"
+	"os/exec"
 	"strings"
+	"syscall"
 )
 
 // ExecutionErrorType 执行器错误类型
 type ExecutionErrorType int
 
 const (
-	ExecutionErrorTypeCommandNotFound ExecutionErrorType = iota // 命令未找到
-	ExecutionErrorTypeCommandFailed                              // 命令执行失败
-	ExecutionErrorTypeRedirectError                              // 重定向错误
-	ExecutionErrorTypePipeError                                  // 管道错误
-	ExecutionErrorTypeVariableError                              // 变量错误
-	ExecutionErrorTypeArithmeticError                            // 算术错误
-	ExecutionErrorTypeInvalidExpression                          // 无效表达式
-	ExecutionErrorTypeInterrupted                                // 命令被中断
-	ExecutionErrorTypeUnknownStatement                           // 未知语句类型
+	ExecutionErrorTypeCommandNotFound   ExecutionErrorType = iota // 命令未找到
+	ExecutionErrorTypeCommandFailed                               // 命令执行失败
+	ExecutionErrorTypeRedirectError                               // 重定向错误
+	ExecutionErrorTypePipeError                                   // 管道错误
+	ExecutionErrorTypeVariableError                               // 变量错误
+	ExecutionErrorTypeArithmeticError                             // 算术错误
+	ExecutionErrorTypeInvalidExpression                           // 无效表达式
+	ExecutionErrorTypeInterrupted                                 // 命令被中断
+	ExecutionErrorTypeUnknownStatement                            // 未知语句类型
+	ExecutionErrorTypeNotExecutable                               // 命令不能执行（没有执行权限、是目录等）
+	ExecutionErrorTypeUnboundVariable                             // 展开未定义的变量（set -u），Message 是变量名
 )
 
 // ExecutionError 表示执行器错误
 type ExecutionError struct {
 	Type        ExecutionErrorType
 	Message     string
-	Command     string   // 命令名
-	Args        []string // 命令参数
-	exitCode    int      // 退出码（如果可用）
-	Context     string   // 错误上下文（如文件名、行号等）
-	OriginalErr error    // 原始错误（如果可用）
+	Command     string         // 命令名
+	Args        []string       // 命令参数
+	exitCode    int            // 退出码（如果可用）
+	Context     string         // 错误上下文（如文件名、行号等）
+	OriginalErr error          // 原始错误（如果可用）
+	Pos         lexer.Position // 出错的语句在脚本中的位置（未知时为零值）
 }
 
 // Error 实现 error 接口
+// 命令无法启动和重定向失败时与 bash 相同，只输出命令名（或文件名）和原因，如 nosuchcmd: command not found
 func (e *ExecutionError) Error() string {
 	var msg string
 	switch e.Type {
 	case ExecutionErrorTypeCommandNotFound:
-		msg = fmt.Sprintf("命令未找到: %s", e.Command)
+		// 带路径的命令与 bash 相同报告文件不存在
+		if strings.ContainsAny(e.Command, `/\`) {
+			if e.OriginalErr == nil || errors.Is(e.OriginalErr, exec.ErrNotFound) {
+				return fmt.Sprintf("%s: %s", e.Command, i18n.T("没有那个文件或目录"))
+			}
+			return fmt.Sprintf("%s: %s", e.Command, systemErrorReason(e.OriginalErr))
+		}
+		return i18n.Sprintf("%s: 未找到命令", e.Command)
+	case ExecutionErrorTypeNotExecutable:
+		if e.OriginalErr == nil {
+			return fmt.Sprintf("%s: %s", e.Command, i18n.T("权限不够"))
+		}
+		if errors.Is(e.OriginalErr, syscall.ENOEXEC) {
+			return fmt.Sprintf("%s: %s", i18n.Sprintf("%s: 无法执行二进制文件", e.Command), systemErrorReason(e.OriginalErr))
+		}
+		return fmt.Sprintf("%s: %s", e.Command, systemErrorReason(e.OriginalErr))
+	case ExecutionErrorTypeRedirectError:
+		if e.OriginalErr != nil {
+			return e.OriginalErr.Error()
+		}
+		return e.Message
 	case ExecutionErrorTypeCommandFailed:
 		if e.exitCode != 0 {
-			msg = fmt.Sprintf("命令执行失败: %s (退出码: %d)", e.Command, e.exitCode)
+			msg = i18n.Sprintf("命令执行失败: %s (退出码: %d)", e.Command, e.exitCode)
 		} else {
-			msg = fmt.Sprintf("命令执行失败: %s", e.Command)
+			msg = i18n.Sprintf("命令执行失败: %s", e.Command)
 		}
-	case ExecutionErrorTypeRedirectError:
-		msg = fmt.Sprintf("重定向错误: %s", e.Message)
 	case ExecutionErrorTypePipeError:
-		msg = fmt.Sprintf("管道错误: %s", e.Message)
+		msg = i18n.Sprintf("管道错误: %s", e.Message)
 	case ExecutionErrorTypeVariableError:
-		msg = fmt.Sprintf("变量错误: %s", e.Message)
+		msg = i18n.Sprintf("变量错误: %s", e.Message)
 	case ExecutionErrorTypeArithmeticError:
-		msg = fmt.Sprintf("算术错误: %s", e.Message)
+		msg = i18n.Sprintf("算术错误: %s", e.Message)
 	case ExecutionErrorTypeInvalidExpression:
-		msg = fmt.Sprintf("无效表达式: %s", e.Message)
+		msg = i18n.Sprintf("无效表达式: %s", e.Message)
 	case ExecutionErrorTypeInterrupted:
-		msg = "命令被中断"
+		msg = i18n.T("命令被中断")
 	case ExecutionErrorTypeUnknownStatement:
-		msg = fmt.Sprintf("未知语句类型: %s", e.Message)
+		msg = i18n.Sprintf("未知语句类型: %s", e.Message)
+	case ExecutionErrorTypeUnboundVariable:
+		msg = i18n.Sprintf("%s: 未绑定的变量", e.Message)
 	default:
 		msg = e.Message
 	}
@@ -92,6 +124,8 @@ func (e *ExecutionError) ExitCode() int {
 	switch e.Type {
 	case ExecutionErrorTypeCommandNotFound:
 		return 127 // bash 中命令未找到的退出码
+	case ExecutionErrorTypeNotExecutable:
+		return 126 // bash 中命令不能执行的退出码
 	case ExecutionErrorTypeCommandFailed:
 		return 1 // 命令执行失败
 	case ExecutionErrorTypeRedirectError, ExecutionErrorTypePipeError:
@@ -107,11 +141,52 @@ func (e *ExecutionError) ExitCode() int {
 	}
 }
 
+// Position 返回出错的语句在脚本中的位置
+func (e *ExecutionError) Position() lexer.Position {
+	return e.Pos
+}
+
+// Unwrap 返回原始错误，便于使用 errors.Is/errors.As 判断（如 context.Canceled）
+func (e *ExecutionError) Unwrap() error {
+	return e.OriginalErr
+}
+
+// systemErrorReason 返回系统调用错误的原因：与 bash 相同是 strerror 风格的描述（如 No such file or directory），
+// 去掉 Go 错误中的操作和路径（如 open /x:、fork/exec /x:）
+func systemErrorReason(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return i18n.T("没有那个文件或目录")
+	case errors.Is(err, fs.ErrPermission):
+		return i18n.T("权限不够")
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		msg := errno.Error()
+		return strings.ToUpper(msg[:1]) + msg[1:]
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
 // String 返回错误的字符串表示
 func (e *ExecutionError) String() string {
 	return e.Error()
 }
 
+// 各层的错误都实现 ShellError
+var (
+	_ ShellError = (*ExecutionError)(nil)
+	_ ShellError = (*ScriptExitError)(nil)
+	_ ShellError = (*builtin.ExitError)(nil)
+	_ ShellError = (*builtin.StatusError)(nil)
+	_ ShellError = (*lexer.LexerError)(nil)
+	_ ShellError = (*parser.ParseError)(nil)
+)
+
 // newExecutionError 创建新的执行器错误
 func newExecutionError(errType ExecutionErrorType, message string, command string, args []string, exitCode int, context string, originalErr error) *ExecutionError {
 	return &ExecutionError{
@@ -125,3 +200,27 @@ func newExecutionError(errType ExecutionErrorType, message string, command strin
 	}
 }
 
+// unboundVariableError 返回 set -u 时展开未定义的变量 name 的错误
+func unboundVariableError(name string) *ExecutionError {
+	return newExecutionError(ExecutionErrorTypeUnboundVariable, name, "", nil, 0, "", nil)
+}
+
+// isUnboundVariable 判断 err 是否是展开未定义的变量的错误
+func isUnboundVariable(err error) bool {
+	var execErr *ExecutionError
+	return errors.As(err, &execErr) && execErr.Type == ExecutionErrorTypeUnboundVariable
+}
+
+// StatusOnly 检查错误是否只表示退出状态：没有消息的错误，以及外部命令、子shell、管道等以非零状态结束的 ExecutionError。
+// 与 bash 相同，这样的错误不输出消息
+func StatusOnly(err error) bool {
+	if err == nil || err.Error() == "" {
+		return true
+	}
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) || execErr.Type != ExecutionErrorTypeCommandFailed || execErr.Context != "" {
+		return false
+	}
+	var exitErr *exec.ExitError
+	return execErr.OriginalErr == nil || errors.As(execErr.OriginalErr, &exitErr)
+}
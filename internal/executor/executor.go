@@ -2,11 +2,12 @@
 package executor
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"gobash/internal/builtin"
+	"gobash/internal/i18n"
 	"gobash/internal/lexer"
 	"gobash/internal/parser"
 	"io"
@@ -14,10 +15,14 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+	"unicode"
 )
 
 // BreakError 表示break语句
@@ -45,27 +50,112 @@ func (e *ContinueLevelError) Error() string {
 }
 
 // ScriptExitError 表示脚本退出错误，包含退出码
+// set -e 生效时命令失败会返回该错误，一直传播到脚本的最外层，由调用者决定如何退出
 type ScriptExitError struct {
 	Code int
+	Err  error // 导致退出的命令错误，可能为 nil
 }
 
 func (e *ScriptExitError) Error() string {
 	return fmt.Sprintf("script exit %d", e.Code)
 }
 
+// ExitCode 返回脚本的退出码
+func (e *ScriptExitError) ExitCode() int {
+	return e.Code
+}
+
+// Position 返回导致退出的命令错误的位置
+func (e *ScriptExitError) Position() lexer.Position {
+	if shellErr, ok := e.Err.(ShellError); ok {
+		return shellErr.Position()
+	}
+	return lexer.Position{}
+}
+
+// Unwrap 返回导致退出的命令错误
+func (e *ScriptExitError) Unwrap() error {
+	return e.Err
+}
+
+// FuncNestError 表示函数的嵌套层数超过了限制（FUNCNEST）
+// 与 bash 相同，它中止整条命令（&& 和 || 的右边也不再执行），退出状态为 1
+type FuncNestError struct {
+	Name  string
+	Limit int
+}
+
+func (e *FuncNestError) Error() string {
+	return i18n.Sprintf("%s: 超过最大函数嵌套层数 (%d)", e.Name, e.Limit)
+}
+
+// ExitCode 返回退出状态 1
+func (e *FuncNestError) ExitCode() int {
+	return 1
+}
+
+// ShellError gobash 各层错误的公共接口：词法错误（lexer.LexerError）、语法错误（parser.ParseError）、
+// 执行错误（ExecutionError）、exit（builtin.ExitError）、内置命令的退出状态（builtin.StatusError）和 set -e 退出（ScriptExitError）
+// ExitCode 与 bash 兼容：语法错误为 2，命令未找到为 127，不能执行为 126，被信号终止为 128+信号编号；
+// Position 在位置未知时返回零值
+type ShellError interface {
+	error
+	ExitCode() int
+	Position() lexer.Position
+	Unwrap() error
+}
+
 // Executor 执行器
 // 负责解释执行AST，处理命令执行、管道、重定向、环境变量展开等功能
 type Executor struct {
 	env         map[string]string
 	arrays      map[string][]string          // 数组存储：数组名 -> 元素列表
+	arrayIndices map[string][]int            // 稀疏数组的下标：数组名 -> 每个元素的下标（递增），没有记录的数组下标从 0 连续编号
 	assocArrays map[string]map[string]string // 关联数组存储：数组名 -> (键 -> 值)
 	arrayTypes  map[string]string            // 数组类型：数组名 -> "array" 或 "assoc"
 	builtins    map[string]builtin.BuiltinFunc
 	functions   map[string]*parser.FunctionStatement
 	options     map[string]bool // shell选项状态
 	jobs        *JobManager     // 作业管理器
-	localVars   map[string]bool // 局部变量集合：变量名 -> true（表示该变量是局部变量）
-	stdoutWriter io.Writer       // 标准输出写入器（用于命令替换等场景）
+	frames      []*callFrame    // 函数调用栈，最内层的调用在最后
+	traps       map[string]string // trap 设置的陷阱：条件（EXIT、INT、TERM、DEBUG、ERR）-> 命令
+	inTrap      bool              // 正在执行陷阱命令，其中的命令不再触发陷阱
+	signals     chan os.Signal    // 接收设置了陷阱的信号（见 handleSignals），nil 表示没有监听信号
+	signalTraps string            // 正在监听的信号的陷阱条件（如 "INT TERM"）
+	random      *rand.Rand      // 随机数生成器（算术函数 rand/srand），每个执行器独立
+	dirStack    *builtin.DirStack // pushd/popd 使用的目录栈
+	exports     *builtin.Exports  // 导出的变量（传给外部命令）和 export -f 导出的函数
+	aliasLookup func(name string) (string, bool) // 查询 shell 层的别名（type 使用），nil 表示没有别名
+	umask       *builtin.Umask    // 文件创建掩码（umask 命令修改，重定向和内置命令创建文件时使用）
+	namerefs    map[string]string // 名称引用（declare -n）：引用名 -> 被引用的变量名
+	integers    map[string]bool   // 有整数属性（declare -i）的变量，赋值时按算术表达式求值
+	readonly    map[string]bool   // 只读变量（declare -r、readonly），不能再赋值
+	fds         map[int]*os.File  // 标准流之外打开的文件描述符（coproc 的管道），重定向 n>&m、n<&m 可以使用
+	substExitCode int           // 当前命令展开时最后一个命令替换的退出状态，-1 表示没有命令替换
+	stdin        io.Reader       // 标准输入，nil 表示使用进程的 os.Stdin
+	stdout       io.Writer       // 标准输出（命令替换时为捕获输出的缓冲区），nil 表示使用进程的 os.Stdout
+	stderr       io.Writer       // 标准错误输出，nil 表示使用进程的 os.Stderr
+	traceSink    TraceSink       // 跟踪事件接收者，nil 表示输出到标准错误
+	traceDepth   int             // 正在执行的命令嵌套深度（用于跟踪输出缩进）
+	xtraceLevel  int             // 命令替换的嵌套层数（用于 set -x 前缀）
+	ctx            context.Context // 取消执行的 context，nil 表示不可取消
+	commandTimeout time.Duration   // 单个外部命令的超时时间，0 表示不限制
+	substLimit     int             // 命令替换输出的最大字节数，0 表示不限制
+	policy         builtin.Policy  // 嵌入方设置的访问策略，nil 表示允许所有操作
+	dryRun         bool            // 试运行：外部命令和有副作用的内置命令只输出不执行（见 dryrun.go）
+	profiler       *Profiler       // 记录命令耗时的性能分析器，nil 表示不记录（子shell 共用）
+	coverage       *Coverage       // 记录脚本行覆盖率，nil 表示不记录（子shell 共用）
+	terminal       *os.File        // 作业控制使用的终端，nil 表示没有启用作业控制（子shell中总是 nil）
+	pgroup         *processGroup   // 管道中的命令所属作业的进程组，nil 表示命令自己成为一个作业
+	inNotFoundHandler bool         // 正在执行 command_not_found_handle，其中找不到的命令不再调用它
+	scriptName     string          // 错误消息中显示的脚本名，空表示不显示
+	interactive    bool            // 是否是交互式 shell（错误消息不带行号）
+	lineno         int             // 正在执行的语句所在的行号（$LINENO），0 表示未知
+	dynamicValues  map[string]string // 动态变量最后一次计算的值，读取时值不同说明赋过值（见 dynamic.go）
+	secondsStart   time.Time         // $SECONDS 开始计时的时间
+	secondsBase    int64             // 给 SECONDS 赋的值
+	// 大于 0 时处于条件上下文（if/while 条件、&& 和 || 的左侧、! 取反），set -e 不生效
+	errexitSuppressed int
 }
 
 // New 创建新的执行器
@@ -73,19 +163,72 @@ func New() *Executor {
 	e := &Executor{
 		env:         make(map[string]string),
 		arrays:      make(map[string][]string),
+		arrayIndices: make(map[string][]int),
 		assocArrays: make(map[string]map[string]string),
 		arrayTypes:  make(map[string]string),
-		builtins:    builtin.GetBuiltins(),
+		builtins:    make(map[string]builtin.BuiltinFunc),
 		functions:   make(map[string]*parser.FunctionStatement),
 		options:     make(map[string]bool),
 		jobs:        NewJobManager(),
-		localVars:   make(map[string]bool),
-		stdoutWriter: os.Stdout, // 默认使用标准输出
-	}
-	// 初始化环境变量
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		dirStack:    builtin.NewDirStack(),
+		exports:     builtin.NewExports(),
+		umask:       builtin.NewUmask(),
+		namerefs:    make(map[string]string),
+		integers:    make(map[string]bool),
+		readonly:    make(map[string]bool),
+		fds:         make(map[int]*os.File),
+		traps:       make(map[string]string),
+		dynamicValues: make(map[string]string),
+	}
+	// 复制内置命令表，RegisterBuiltin 只影响当前执行器
+	for name, fn := range builtin.GetBuiltins() {
+		e.builtins[name] = fn
+	}
+	// jobs、fg、bg、kill 使用当前执行器的作业管理器
+	for name, fn := range builtin.JobBuiltins(e.jobs) {
+		e.builtins[name] = fn
+	}
+	// pushd、popd、dirs 使用当前执行器的目录栈
+	for name, fn := range builtin.DirStackBuiltins(e.dirStack) {
+		e.builtins[name] = fn
+	}
+	// xargs 通过当前执行器的内置命令表查找命令
+	e.builtins["xargs"] = builtin.XargsBuiltin(e.lookupBuiltin)
+	// timeout 在子shell中执行命令，超时后终止外部命令并停止内置命令和循环
+	e.builtins["timeout"] = builtin.TimeoutBuiltin(e.runArgs)
+	// export 使用当前执行器的导出表和函数表
+	e.builtins["export"] = builtin.ExportBuiltin(e.exports, e.functionSource)
+	// type 查询当前执行器的函数和内置命令以及 shell 的别名
+	e.builtins["type"] = builtin.TypeBuiltin(e.commands())
+	// declare 查询当前执行器的数组、函数和名称引用
+	e.builtins["declare"] = builtin.DeclareBuiltin(e.Variables())
+	e.builtins["local"] = builtin.LocalBuiltin(e.Variables())
+	e.builtins["readonly"] = builtin.ReadonlyBuiltin(e.Variables())
+	e.builtins["unset"] = builtin.UnsetBuiltin(e.unsetVariable, e.unsetFunction)
+	// printf -v 通过当前执行器赋值，支持名称引用和数组元素
+	e.builtins["printf"] = builtin.PrintfBuiltin(e.assignVariable)
+	e.builtins["read"] = builtin.ReadBuiltin(e.Variables(), e.assignVariable, e.openFD)
+	e.builtins["let"] = builtin.LetBuiltin(e.arithmetic)
+	// return 只能在函数中使用
+	e.builtins["return"] = builtin.ReturnBuiltin(e.inFunction)
+	e.builtins["trap"] = builtin.TrapBuiltin(e.traps)
+	e.initShellVariables()
+	e.initVersionVariables()
+	// 初始化环境变量（继承的变量都是导出的），父进程用 export -f 导出的函数（BASH_FUNC_name%%）重新定义为函数
 	for _, env := range os.Environ() {
 		key, value := splitEnv(env)
+		if name, ok := builtin.ParseFunctionEnvName(key); ok {
+			e.importFunction(name, value)
+			continue
+		}
 		e.env[key] = value
+		e.exports.Export(key)
+	}
+	e.initHostname()
+	// 工作目录从进程的当前目录开始，之后由 cd 修改 PWD
+	if dir, err := os.Getwd(); err == nil {
+		e.env["PWD"] = dir
 	}
 	// 初始化位置参数：如果没有参数，$# 为 0
 	e.env["#"] = "0"
@@ -96,6 +239,12 @@ func New() *Executor {
 // SetOptions 设置shell选项
 func (e *Executor) SetOptions(options map[string]bool) {
 	e.options = options
+	// set -b 时后台作业结束或停止时立即输出通知
+	if options["b"] {
+		e.jobs.SetNotify(e.Stdio().Stderr)
+	} else {
+		e.jobs.SetNotify(nil)
+	}
 }
 
 // GetOptions 获取shell选项
@@ -103,11 +252,129 @@ func (e *Executor) GetOptions() map[string]bool {
 	return e.options
 }
 
+// RegisterBuiltin 注册（或覆盖）内置命令
+// 用于 shell 层实现需要访问 shell 状态的命令（如 alias、history、set）
+func (e *Executor) RegisterBuiltin(name string, fn builtin.BuiltinFunc) {
+	e.builtins[name] = fn
+}
+
+// SetAliasLookup 设置查询别名的函数（别名由 shell 层保存），type 等命令通过它识别别名
+func (e *Executor) SetAliasLookup(lookup func(name string) (string, bool)) {
+	e.aliasLookup = lookup
+}
+
+// commands 返回 type 使用的命令表：shell 的别名、当前执行器的函数和内置命令
+func (e *Executor) commands() builtin.Commands {
+	return builtin.Commands{
+		Alias: func(name string) (string, bool) {
+			if e.aliasLookup == nil {
+				return "", false
+			}
+			return e.aliasLookup(name)
+		},
+		Function: e.functionSource,
+		Builtin:  e.lookupBuiltin,
+	}
+}
+
+// Variables 返回 declare 和 set 使用的变量表：当前执行器的数组、关联数组、名称引用、函数和导出表
+func (e *Executor) Variables() builtin.Variables {
+	return builtin.Variables{
+		Exports:     e.exports,
+		Arrays:      func() map[string][]string { return e.arrays },
+		ArrayIndices: func() map[string][]int { return e.arrayIndices },
+		AssocArrays: func() map[string]map[string]string { return e.assocArrays },
+		Namerefs:    func() map[string]string { return e.namerefs },
+		Integers:    func() map[string]bool { return e.integers },
+		Readonly:    func() map[string]bool { return e.readonly },
+		Arithmetic:  e.arithmeticValue,
+		Functions: func() map[string]string {
+			functions := make(map[string]string, len(e.functions))
+			for name, fn := range e.functions {
+				functions[name] = fn.Source
+			}
+			return functions
+		},
+	}
+}
+
+// lookupBuiltin 在当前执行器的内置命令表中查找命令
+func (e *Executor) lookupBuiltin(name string) (builtin.BuiltinFunc, bool) {
+	fn, ok := e.builtins[name]
+	return fn, ok
+}
+
+// SetStdio 设置标准输入、输出和错误输出，nil 表示使用进程的标准流
+// 内置命令、外部命令、set -x 输出和错误信息都通过这三个流读写，不会修改进程的 os.Stdout 等全局变量
+func (e *Executor) SetStdio(in io.Reader, out, errOut io.Writer) {
+	e.stdin = in
+	e.stdout = out
+	e.stderr = errOut
+}
+
+// Stdio 返回当前使用的标准输入、输出和错误输出
+func (e *Executor) Stdio() *builtin.IO {
+	stdio := builtin.StdIO()
+	stdio.Context = e.ctx
+	stdio.Umask = e.umask
+	stdio.Policy = e.policy
+	stdio.Exports = e.exports
+	if e.stdin != nil {
+		stdio.Stdin = e.stdin
+	}
+	if e.stdout != nil {
+		stdio.Stdout = e.stdout
+	}
+	if e.stderr != nil {
+		stdio.Stderr = e.stderr
+	}
+	return stdio
+}
+
+// Capture 执行程序，返回捕获的标准输出和错误输出
+// 执行期间标准输出和错误输出写入缓冲区，执行结束后恢复原来的设置；标准输入不变
+func (e *Executor) Capture(program *parser.Program) (stdout, stderr string, err error) {
+	var outBuf, errBuf bytes.Buffer
+	savedOut, savedErr := e.stdout, e.stderr
+	e.stdout, e.stderr = &outBuf, &errBuf
+	defer func() {
+		e.stdout, e.stderr = savedOut, savedErr
+	}()
+
+	err = e.Execute(program)
+	return outBuf.String(), errBuf.String(), err
+}
+
+// NotifyJobs 把状态改变（结束或停止）之后还没有通知的后台作业输出到标准错误，交互式 shell 在显示提示符之前调用
+func (e *Executor) NotifyJobs() {
+	for _, line := range e.jobs.Notifications() {
+		fmt.Fprintln(e.Stdio().Stderr, line)
+	}
+}
+
 // GetJobManager 获取作业管理器
 func (e *Executor) GetJobManager() *JobManager {
 	return e.jobs
 }
 
+// SetCommandTimeout 设置单个外部命令（包括管道）的超时时间，0 表示不限制
+// 超时的命令被终止，退出码为 124（与 timeout 命令一致），脚本继续执行
+func (e *Executor) SetCommandTimeout(timeout time.Duration) {
+	e.commandTimeout = timeout
+}
+
+// SetSubstitutionLimit 设置命令替换输出的最大字节数，0 表示不限制
+// 超过限制时结果截断到限制的长度，之后的写入失败（外部命令因管道关闭而退出），命令替换的退出状态为 1
+func (e *Executor) SetSubstitutionLimit(limit int) {
+	e.substLimit = limit
+}
+
+// SetPolicy 设置访问策略：执行外部命令、打开重定向的文件、内置命令读写文件和访问网络之前调用 policy，
+// 它返回错误时拒绝该操作（见 builtin.Policy），nil 表示允许所有操作
+func (e *Executor) SetPolicy(policy builtin.Policy) {
+	e.policy = policy
+}
+
 // Execute 执行程序
 func (e *Executor) Execute(program *parser.Program) error {
 	for _, stmt := range program.Statements {
@@ -118,20 +385,81 @@ func (e *Executor) Execute(program *parser.Program) error {
 	return nil
 }
 
+// ExecuteContext 在 ctx 下执行程序
+// ctx 被取消或超时后，正在运行的外部命令被终止，之后的语句、循环和命令替换都不再执行，
+// 返回的错误可以用 errors.Is(err, context.Canceled) 或 context.DeadlineExceeded 判断
+func (e *Executor) ExecuteContext(ctx context.Context, program *parser.Program) error {
+	saved := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = saved }()
+	return e.Execute(program)
+}
+
+// execContext 返回当前执行的 context
+func (e *Executor) execContext() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// interrupted 检查执行是否已被取消，已取消时返回中断错误
+func (e *Executor) interrupted() error {
+	if e.ctx == nil || e.ctx.Err() == nil {
+		return nil
+	}
+	return newExecutionError(ExecutionErrorTypeInterrupted, "", "", nil, 0, "", e.ctx.Err())
+}
+
+// commandContext 返回运行外部命令使用的 context，设置了命令超时时附加超时
+func (e *Executor) commandContext() (context.Context, context.CancelFunc) {
+	if e.commandTimeout > 0 {
+		return context.WithTimeout(e.execContext(), e.commandTimeout)
+	}
+	return context.WithCancel(e.execContext())
+}
+
+// commandWaitError 外部命令因执行被取消或命令超时而结束时返回对应的错误，否则返回 nil
+func (e *Executor) commandWaitError(cmdCtx context.Context, cmdName string, args []string) error {
+	if err := e.interrupted(); err != nil {
+		return err
+	}
+	if cmdCtx.Err() == context.DeadlineExceeded {
+		return newExecutionError(ExecutionErrorTypeCommandFailed, i18n.T("命令超时"), cmdName, args, 124,
+			i18n.Sprintf("超过 %s", e.commandTimeout), nil)
+	}
+	return nil
+}
+
 // executeStatement 执行语句
-func (e *Executor) executeStatement(stmt parser.Statement) error {
+// 执行前记录语句所在的行号，语句返回的执行器错误没有位置时记录为语句的位置
+func (e *Executor) executeStatement(stmt parser.Statement) (err error) {
 	if stmt == nil {
 		return nil // 空语句，直接返回
 	}
+	if err := e.interrupted(); err != nil {
+		return err
+	}
+	defer func() {
+		// 与 bash 相同，非交互式 shell 展开未定义的变量（set -u）时终止脚本，退出状态为 1
+		if _, exiting := err.(*ScriptExitError); !exiting && isUnboundVariable(err) && !e.interactive {
+			err = &ScriptExitError{Code: 1, Err: err}
+		}
+	}()
+	if pos := parser.StatementPos(stmt); pos.IsValid() {
+		e.setLineNumber(pos.Line)
+		e.coverStatement(stmt, pos.Line)
+		defer func() { setErrorPosition(err, pos) }()
+	}
 	switch s := stmt.(type) {
 	case *parser.CommandStatement:
 		return e.executeCommand(s)
 	case *parser.IfStatement:
-		return e.executeIf(s)
+		return e.withRedirects(s.Redirects, func() error { return e.executeIf(s) })
 	case *parser.ForStatement:
-		return e.executeFor(s)
+		return e.withRedirects(s.Redirects, func() error { return e.executeFor(s) })
 	case *parser.WhileStatement:
-		return e.executeWhile(s)
+		return e.withRedirects(s.Redirects, func() error { return e.executeWhile(s) })
 	case *parser.FunctionStatement:
 		// 存储函数定义
 		e.functions[s.Name] = s
@@ -139,81 +467,347 @@ func (e *Executor) executeStatement(stmt parser.Statement) error {
 	case *parser.BlockStatement:
 		return e.executeBlock(s)
 	case *parser.GroupCommand:
-		// 命令组 { command; }，执行其中的命令
-		return e.executeBlock(s.Body)
+		// 命令组 { command; }，在当前shell中执行其中的命令
+		return e.withRedirects(s.Redirects, func() error { return e.executeBlock(s.Body) })
 	case *parser.ArrayAssignmentStatement:
 		return e.executeArrayAssignment(s)
 	case *parser.CaseStatement:
-		return e.executeCaseStatement(s)
+		return e.withRedirects(s.Redirects, func() error { return e.executeCaseStatement(s) })
 	case *parser.BreakStatement:
 		return e.executeBreak(s)
 	case *parser.ContinueStatement:
 		return e.executeContinue(s)
+	case *parser.CommandChain:
+		return e.executeCommandChain(s)
+	case *parser.NegatedStatement:
+		return e.executeNegated(s)
+	case *parser.TimedStatement:
+		return e.executeTimed(s)
+	case *parser.BackgroundStatement:
+		return e.executeBackground(s.Statement)
+	case *parser.CoprocStatement:
+		return e.executeCoproc(s)
+	case *parser.PipelineStatement:
+		err := e.executePipeline(s)
+		e.setExitStatus(err)
+		return e.checkErrexit(err)
+	case *parser.ArithmeticCommand:
+		err := e.withRedirects(s.Redirects, func() error { return e.executeArithmeticCommand(s) })
+		e.setExitStatus(err)
+		return e.checkErrexit(err)
+	case *parser.SubshellCommand:
+		// 子shell以非零状态退出时同样触发 ERR 陷阱和 set -e
+		e.setBashCommand(s)
+		err := e.withRedirects(s.Redirects, func() error { return e.executeSubshell(s) })
+		e.setExitStatus(err)
+		return e.checkErrexit(err)
 	default:
 		return newExecutionError(ExecutionErrorTypeUnknownStatement,
 			fmt.Sprintf("unknown statement type: %T", stmt), "", nil, 0, "", nil)
 	}
 }
 
-// executeCommand 执行命令
+// executeBackground 在子shell中异步执行后台语句：管道、命令链、循环、子shell、命令组，以及内置命令和函数
+// 整个语句登记为一个作业，$! 是作业的进程号（kill 和 wait 可以使用）；
+// 作业不随当前执行的 context 取消，启用作业控制时其中的外部命令在作业自己的进程组中运行
+func (e *Executor) executeBackground(stmt parser.Statement) error {
+	e.startJob(e.fork(), stmt, nil)
+	return nil
+}
+
+// startJob 在子shell sub 中异步执行后台语句 stmt 并登记为作业，返回作业的进程号
+// 语句结束或作业被 kill 终止时调用 finished（可以为 nil）
+func (e *Executor) startJob(sub *Executor, stmt parser.Statement, finished func()) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	if finished != nil {
+		context.AfterFunc(ctx, finished)
+	}
+	sub.ctx = ctx
+	group := e.newProcessGroup(false)
+	if group != nil {
+		sub.pgroup = group
+	}
+	pid := nextTaskPID()
+	jobID := e.jobs.AddTask(stmt.String(), pid, group, cancel, func() error {
+		defer cancel()
+		return sub.executeStatement(stmt)
+	})
+	e.env["!"] = strconv.Itoa(pid)
+	e.reportJobStart(e.Stdio().Stderr, jobID, pid)
+	return pid
+}
+
+// reportJobStart 输出后台作业的作业号和进程号（[1] 12345），与 bash 相同只有交互式 shell 输出
+func (e *Executor) reportJobStart(w io.Writer, jobID, pid int) {
+	if e.interactive {
+		fmt.Fprintf(w, "[%d] %d\n", jobID, pid)
+	}
+}
+
+// executeCoproc 执行协进程 coproc [NAME] command：命令作为后台作业执行，标准输入和标准输出分别连接到一个管道
+// 数组 NAME（默认 COPROC）的元素 0 是读取命令输出的文件描述符，元素 1 是写入命令输入的文件描述符，
+// NAME_PID 是作业的进程号，如 echo 1+2 >&${COPROC[1]}; read x <&${COPROC[0]}
+func (e *Executor) executeCoproc(stmt *parser.CoprocStatement) error {
+	name := stmt.Name
+	if name == "" {
+		name = "COPROC"
+	}
+	inR, inW, err := os.Pipe()
+	if err != nil {
+		return newExecutionError(ExecutionErrorTypePipeError,
+			i18n.T("创建管道失败"), "coproc", nil, 1, "", err)
+	}
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		inR.Close()
+		inW.Close()
+		return newExecutionError(ExecutionErrorTypePipeError,
+			i18n.T("创建管道失败"), "coproc", nil, 1, "", err)
+	}
+	// 同名的上一个协进程的文件描述符不再使用
+	for _, value := range e.arrays[name] {
+		if fd, err := strconv.Atoi(value); err == nil && e.fds[fd] != nil {
+			e.fds[fd].Close()
+			delete(e.fds, fd)
+		}
+	}
+	readFD := e.allocFD()
+	e.fds[readFD] = outR
+	writeFD := e.allocFD()
+	e.fds[writeFD] = inW
+
+	sub := e.fork()
+	sub.stdin, sub.stdout = inR, outW
+	// 命令结束后关闭它使用的管道端，shell 读取命令的输出时读到文件结束；
+	// 被 kill 终止时关闭管道也让阻塞在读取输入上的命令（如 read）返回
+	pid := e.startJob(sub, stmt.Statement, func() {
+		inR.Close()
+		outW.Close()
+	})
+	e.setArray(name, nil, []string{strconv.Itoa(readFD), strconv.Itoa(writeFD)})
+	delete(e.env, name)
+	e.env[name+"_PID"] = strconv.Itoa(pid)
+	return nil
+}
+
+// allocFD 返回一个没有使用的文件描述符：与 bash 相同，从 63 开始向下分配（不会与脚本使用的 3～9 冲突）
+func (e *Executor) allocFD() int {
+	fd := 63
+	for e.fds[fd] != nil && fd > 10 {
+		fd--
+	}
+	return fd
+}
+
+// openFD 返回 shell 打开的文件描述符 fd（如 ${COPROC[0]}），供 read -u 使用
+func (e *Executor) openFD(fd int) (io.Reader, bool) {
+	f, ok := e.fds[fd]
+	if !ok {
+		return nil, false
+	}
+	return f, true
+}
+
+// executeCommandChain 执行由 && 或 || 连接的命令
+// &&：左边成功时才执行右边；||：左边失败时才执行右边
+// 左边的命令处于条件上下文，失败时不触发 set -e
+func (e *Executor) executeCommandChain(chain *parser.CommandChain) error {
+	err := e.inCondition(func() error {
+		return e.executeStatement(chain.Left)
+	})
+	if err != nil && !isFailureStatus(err) {
+		// exit、break、continue 等需要直接向上传播
+		return err
+	}
+
+	switch chain.Operator {
+	case "&&":
+		if err != nil {
+			return err
+		}
+	case "||":
+		if err == nil {
+			return nil
+		}
+	}
+	return e.executeStatement(chain.Right)
+}
+
+// executeNegated 执行 ! pipeline，对退出状态取反
+// 被取反的命令处于条件上下文，失败时不触发 set -e
+func (e *Executor) executeNegated(stmt *parser.NegatedStatement) error {
+	err := e.inCondition(func() error {
+		return e.executeStatement(stmt.Statement)
+	})
+	if err != nil && !isFailureStatus(err) {
+		return err
+	}
+	if err != nil {
+		e.env["?"] = "0"
+		return nil
+	}
+	e.env["?"] = "1"
+	return newExecutionError(ExecutionErrorTypeCommandFailed,
+		i18n.T("取反后的退出状态为 1"), stmt.String(), nil, 1, "", nil)
+}
+
+// executeCommand 执行简单命令（或管道），记录退出状态 $?，并处理 set -e
 func (e *Executor) executeCommand(cmd *parser.CommandStatement) error {
-	if cmd == nil || cmd.Command == nil {
+	if err := e.interrupted(); err != nil {
+		return err
+	}
+	// 空命令（只有重定向或者什么都没有）不触发 DEBUG 陷阱，也不记录性能数据
+	empty := cmd == nil || cmd.Command == nil && len(cmd.Assignments) == 0
+	if err := e.handleSignals(); err != nil {
+		return err
+	}
+	if !empty {
+		e.setBashCommand(cmd)
+		if err := e.runTrap("DEBUG"); err != nil {
+			return err
+		}
+	}
+	var err error
+	if !empty {
+		err = e.profileCommand(cmd, func() error {
+			if e.tracing() {
+				return e.traceCommand(cmd)
+			}
+			return e.runCommand(cmd)
+		})
+	} else {
+		err = e.runCommand(cmd)
+	}
+	if err == nil {
+		// 命令替换等展开过程中执行可能已被取消
+		err = e.interrupted()
+	}
+	e.setExitStatus(err)
+	return e.checkErrexit(err)
+}
+
+// setExitStatus 根据命令的结果设置 $?，exit、break 等控制流错误不改变 $?
+func (e *Executor) setExitStatus(err error) {
+	if err == nil || isFailureStatus(err) {
+		e.env["?"] = strconv.Itoa(ExitStatus(err))
+	}
+}
+
+// inCondition 在条件上下文中执行 fn，其中的命令失败时不触发 set -e
+func (e *Executor) inCondition(fn func() error) error {
+	e.errexitSuppressed++
+	defer func() { e.errexitSuppressed-- }()
+	return fn()
+}
+
+// checkErrexit 命令失败时执行 ERR 陷阱，set -e 生效时再将错误转换为 ScriptExitError 终止脚本
+// 不直接调用 os.Exit，这样嵌入 gobash 的程序和 EXIT 清理逻辑都能正常工作
+func (e *Executor) checkErrexit(err error) error {
+	if err == nil || !isFailureStatus(err) || e.errexitSuppressed > 0 {
+		return err
+	}
+	if trapErr := e.runTrap("ERR"); trapErr != nil {
+		return trapErr
+	}
+	if !e.options["e"] {
+		return err
+	}
+	return &ScriptExitError{Code: ExitStatus(err), Err: err}
+}
+
+// reportScriptExit 子shell因为 set -e 或 set -u 终止时输出导致终止的错误，只表示退出状态的错误不输出
+func (e *Executor) reportScriptExit(err *ScriptExitError) {
+	if !StatusOnly(err.Err) {
+		fmt.Fprintf(e.Stdio().Stderr, "%s: %v\n", e.errorPrefix(), err.Err)
+	}
+}
+
+// ExitStatus 从命令返回的错误中提取退出码（nil 为 0）
+// ShellError、exec.ExitError 以及嵌入方自定义的错误类型都可以通过 ExitCode 提供退出码，其他错误为 1
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var coded interface{ ExitCode() int }
+	if !errors.As(err, &coded) {
+		return 1
+	}
+	if exitErr, ok := coded.(*exec.ExitError); ok {
+		return processExitCode(exitErr.ProcessState)
+	}
+	return coded.ExitCode()
+}
+
+// processExitCode 返回外部命令的退出码，被信号终止时与 bash 相同为 128+信号编号
+func processExitCode(state *os.ProcessState) int {
+	if state == nil {
+		return 1
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
+
+// isFailureStatus 检查错误是否只是表示命令执行失败（非零退出码），
+// 而不是 exit、break、continue 等控制流错误，或者执行被取消
+func isFailureStatus(err error) bool {
+	switch err.(type) {
+	case *builtin.ExitError, *builtin.ReturnError, *ScriptExitError, *FuncNestError, *BreakLevelError, *ContinueLevelError:
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return err != BreakError && err != ContinueError
+}
+
+// executeSubshell 执行子shell命令 (command)
+// 命令在派生的子shell执行器中执行：子shell 可以使用当前shell的变量、数组和函数，
+// 其中对变量、函数、选项和工作目录的修改不影响当前shell
+func (e *Executor) executeSubshell(stmt *parser.SubshellCommand) error {
+	sub := e.fork()
+	err := sub.RunExitTrap(sub.executeBlock(stmt.Body))
+	var code int
+	switch exitErr := err.(type) {
+	case *builtin.ExitError:
+		code = exitErr.Code
+	case *builtin.ReturnError:
+		code = exitErr.Code
+	case *ScriptExitError:
+		e.reportScriptExit(exitErr)
+		code = exitErr.Code
+	default:
+		return err
+	}
+	// 子shell中的 exit、return 以及 set -e、set -u 导致的退出只退出子shell
+	if code == 0 {
+		return nil
+	}
+	return newExecutionError(ExecutionErrorTypeCommandFailed,
+		"", i18n.T("子shell"), nil, code, "", nil)
+}
+
+// runCommand 执行命令（由 executeCommand 调用）
+func (e *Executor) runCommand(cmd *parser.CommandStatement) error {
+	if cmd == nil || cmd.Command == nil && len(cmd.Assignments) == 0 {
 		return nil // 空命令，直接返回
 	}
+	e.substExitCode = -1
+	if cmd.Command == nil {
+		return e.executeAssignments(cmd.Assignments)
+	}
+	if len(cmd.Assignments) > 0 {
+		return e.executeWithAssignments(cmd)
+	}
 
 	// 获取命令名
-	cmdName := e.evaluateExpression(cmd.Command)
+	cmdName, err := e.evaluateExpression(cmd.Command)
+	if err != nil {
+		return err
+	}
 	if cmdName == "" {
-		return fmt.Errorf("命令名为空")
-	}
-
-	// 检查是否是简单的变量赋值 VAR=value
-	// 注意：需要检查第一个 = 号，因为值中可能也包含 =（虽然不常见）
-	if strings.Contains(cmdName, "=") {
-		// 找到第一个 = 号的位置
-		eqIndex := strings.Index(cmdName, "=")
-		if eqIndex > 0 {
-			// 检查变量名部分是否包含 [（关联数组赋值 arr[key]=value）
-			varNamePart := strings.TrimSpace(cmdName[:eqIndex])
-			if !strings.Contains(varNamePart, "[") {
-				// 这是简单的变量赋值
-				varName := varNamePart
-				varValue := strings.TrimSpace(cmdName[eqIndex+1:])
-
-				// 检查变量名是否有效（只包含字母、数字和下划线，且不能以数字开头）
-				if varName != "" {
-					isValidVarName := true
-					for i, ch := range varName {
-						if i == 0 {
-							if !((ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_') {
-								isValidVarName = false
-								break
-							}
-						} else {
-							if !((ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_') {
-								isValidVarName = false
-								break
-							}
-						}
-					}
-
-					if isValidVarName {
-						// 移除引号（如果有）
-						if len(varValue) >= 2 {
-							if (varValue[0] == '"' && varValue[len(varValue)-1] == '"') ||
-								(varValue[0] == '\'' && varValue[len(varValue)-1] == '\'') {
-								varValue = varValue[1 : len(varValue)-1]
-							}
-						}
-						// 展开变量值中的变量（单引号字符串中的变量不应该展开，但这里已经移除了引号）
-						varValue = e.expandVariablesInString(varValue)
-						// 设置环境变量
-						e.SetEnv(varName, varValue)
-						return nil
-					}
-				}
-			}
-		}
+		return i18n.Errorf("命令名为空")
 	}
 
 	// 检查是否是关联数组赋值 arr[key]=value
@@ -221,95 +815,72 @@ func (e *Executor) executeCommand(cmd *parser.CommandStatement) error {
 		return e.executeAssocArrayAssignment(cmdName, cmd.Args)
 	}
 
-	// 检查是否为内置命令或特殊命令（[ 或 [[）
-	if cmdName == "[" || cmdName == "[[" {
-		// 处理 [ 或 [[ 命令（test命令）
+	// [[ 命令，求值解析时构造的条件表达式
+	if cmdName == "[[" {
+		return e.executeCondCommand(cmd)
+	}
+
+	// 检查是否为内置命令或特殊命令（[）
+	if cmdName == "[" {
+		// 处理 [ 命令（test命令）
 		args := make([]string, len(cmd.Args))
 		for i, arg := range cmd.Args {
-			argValue := e.evaluateExpression(arg)
-			// 检查未定义的变量（set -u）
-			if strings.HasPrefix(argValue, "__UNDEFINED_VAR__") {
-				varName := strings.TrimPrefix(argValue, "__UNDEFINED_VAR__")
-				return fmt.Errorf("未定义的变量: %s", varName)
+			argValue, err := e.evaluateExpression(arg)
+			if err != nil {
+				return err
 			}
 			args[i] = argValue
 		}
 
-		// 移除结束括号（] 或 ]]）
-		if len(args) > 0 {
-			lastArg := args[len(args)-1]
-			if lastArg == "]" || lastArg == "]]" {
-				args = args[:len(args)-1]
-			}
-		}
-
-		// 对于 [[ 命令，需要支持 && 和 || 运算符
-		if cmdName == "[[" {
-			result, err := e.evaluateDoubleBracketExpression(args)
-			if err != nil {
-				if e.options["e"] {
-					fmt.Fprintf(os.Stderr, "gobash: [[: %v\n", err)
-					os.Exit(1)
-				}
-				return err
-			}
-			if !result {
-				// 条件为假，返回退出码错误（ExitCode=1），这样while循环可以正确处理
-				if e.options["e"] {
-					fmt.Fprintf(os.Stderr, "gobash: [[: 条件为假\n")
-					os.Exit(1)
-				}
-				// 返回一个ExitError，退出码为1
-				// 创建一个命令来获取ExitError
-				cmd := exec.Command("cmd", "/c", "exit", "1")
-				_ = cmd.Run()
-				if cmd.ProcessState != nil {
-					return &exec.ExitError{ProcessState: cmd.ProcessState}
-				}
-				// 如果无法创建ExitError，返回一个普通错误
-				return fmt.Errorf("test failed")
-			}
-			return nil
+		// 移除结束括号 ]
+		if len(args) > 0 && args[len(args)-1] == "]" {
+			args = args[:len(args)-1]
 		}
 
 		// 对于 [ 命令，调用test命令
 		testFunc := e.builtins["test"]
 		if testFunc == nil {
-			return fmt.Errorf("test命令未找到")
+			return i18n.Errorf("test命令未找到")
 		}
 
-		if err := testFunc(args, e.env); err != nil {
-			// 如果设置了 -e 选项且命令失败，输出错误信息后退出
-			if e.options["e"] {
-				fmt.Fprintf(os.Stderr, "gobash: test: %v\n", err)
-				os.Exit(1)
-			}
-			return err
+		if len(cmd.Redirects) > 0 {
+			return e.executeBuiltinWithRedirect(cmdName, testFunc, args, cmd.Redirects)
 		}
-
-		return nil
+		return e.callBuiltin(cmdName, testFunc, args, e.Stdio())
 	}
 
 	// 检查是否为内置命令
 	if builtinFunc, ok := e.builtins[cmdName]; ok {
+		// 后台执行的内置命令（如 sleep 5 &）在子shell中异步运行，不阻塞当前shell
+		if cmd.Background {
+			foreground := *cmd
+			foreground.Background = false
+			return e.executeBackground(&foreground)
+		}
+
 		args := make([]string, len(cmd.Args))
+		var compounds []*parser.ArrayAssignmentStatement
 		for i, arg := range cmd.Args {
-			argValue := e.evaluateExpression(arg)
-			// 检查未定义的变量（set -u）
-			if strings.HasPrefix(argValue, "__UNDEFINED_VAR__") {
-				varName := strings.TrimPrefix(argValue, "__UNDEFINED_VAR__")
-				return fmt.Errorf("未定义的变量: %s", varName)
+			if declaresVariables(cmdName) {
+				// declare a=(1 2) 等数组初始值由执行器赋值，内置命令只收到名称
+				if stmt := compoundAssignment(arg); stmt != nil {
+					compounds = append(compounds, stmt)
+					args[i] = stmt.Name
+					continue
+				}
+			}
+			argValue, err := e.evaluateExpression(arg)
+			if err != nil {
+				return err
 			}
 			args[i] = argValue
 		}
 
 		// 如果设置了 -x 选项，显示执行的命令
-		if e.options["x"] {
-			fmt.Fprintf(os.Stderr, "+ %s", cmdName)
-			for _, arg := range args {
-				fmt.Fprintf(os.Stderr, " %s", arg)
-			}
-			fmt.Fprintf(os.Stderr, "\n")
+		e.xtrace(append([]string{cmdName}, args...))
+		if e.dryRunSkips(cmdName, args, cmd.Redirects) {
+			e.dryRunPrint(append([]string{cmdName}, args...), cmd.Redirects)
+			return nil
 		}
 
 		// 对于 local 命令，需要设置函数上下文标记
@@ -317,712 +888,631 @@ func (e *Executor) executeCommand(cmd *parser.CommandStatement) error {
 			// 检查是否在函数中（通过检查调用栈，简化实现：总是允许）
 			// 实际上，local 只能在函数中使用，但这里简化处理
 			e.env["__WBASH_IN_FUNCTION__"] = "1"
+			e.saveLocals(args)
 		}
 
-		// 处理内置命令的重定向
-		if len(cmd.Redirects) > 0 {
-			err := e.executeBuiltinWithRedirect(cmdName, builtinFunc, args, cmd.Redirects)
-			// 检查是否是 exit 命令，如果是，直接返回，不包装
-			if _, ok := err.(*builtin.ExitError); ok {
-				return err
-			}
-			// 如果设置了 -e 选项且命令失败，输出错误信息后退出
-			if err != nil && e.options["e"] {
-				fmt.Fprintf(os.Stderr, "gobash: %s: %v\n", cmdName, err)
-				os.Exit(1)
-			}
-			return err
-		}
-
-		// 为需要访问JobManager的命令设置引用
-		if cmdName == "jobs" || cmdName == "fg" || cmdName == "bg" {
-			builtin.SetJobManager(e.jobs)
-		}
-
-		// 如果设置了自定义 stdout writer，使用 fmt.Fprintln 直接写入
-		// 这样可以支持 bytes.Buffer 和其他 io.Writer 类型
-		if e.stdoutWriter != nil {
-			// 如果是 *os.File，直接修改 os.Stdout（为了兼容外部命令）
-			if f, ok := e.stdoutWriter.(*os.File); ok {
-				// 关键修复：如果 os.Stdout 已经是这个文件，不需要再次设置
-				// 这样可以避免在命令替换中过早恢复 os.Stdout
-				if os.Stdout != f {
-					oldStdout := os.Stdout
-					os.Stdout = f
-					defer func() {
-						os.Stdout = oldStdout
-					}()
-				}
-			} else {
-				// 如果是 bytes.Buffer 或其他 io.Writer
-				// 关键修复：在命令替换中，让内置命令直接写入到 stdoutWriter
-				// 方法：使用 fmt.Fprintf 直接写入到 stdoutWriter，而不是通过 os.Stdout
-				// 但是，内置命令（如 echo）使用 fmt.Println，它只能写入到 os.Stdout
-				// 所以我们需要在 executeBuiltinCommand 中处理这种情况
-				// 
-				// 实际上，在命令替换中，os.Stdout 已经被设置为管道
-				// 但是，为了确保内置命令的输出被正确捕获，我们需要让内置命令直接写入到 stdoutWriter
-				// 
-				// 关键：在命令替换中，os.Stdout 已经被设置为管道，会最终写入到 bytes.Buffer
-				// 但是 fmt.Println 使用了内部缓冲，可能不会被正确捕获
-				// 所以我们需要确保在命令执行后，所有缓冲都被刷新
-				// 这已经在 executeCommandSubstitution 中通过关闭管道来实现
-				// 
-				// 但是，更好的方法是：让内置命令直接写入到 stdoutWriter
-				// 我们可以通过设置 os.Stdout 为一个包装器来实现
-				// 但是，fmt.Println 只能写入到 os.Stdout，所以我们需要设置 os.Stdout
-				// 
-				// 实际上，在命令替换中，os.Stdout 已经被设置为管道
-				// 所以这里不需要额外处理，fmt.Println 会写入到管道
-				// 然后被读取 goroutine 读取并写入到 bytes.Buffer
-				// 
-				// 关键修复：在命令替换中，os.Stdout 已经被设置为管道
-				// 但是 fmt.Println 使用了内部缓冲，可能不会被正确捕获
-				// 所以我们需要确保在命令执行后，所有缓冲都被刷新
-				// 这已经在 executeCommandSubstitution 中通过关闭管道来实现
-				// 
-				// 实际上，在命令替换中，os.Stdout 已经被设置为管道
-				// 所以这里不需要额外处理，fmt.Println 会写入到管道
-				// 然后被读取 goroutine 读取并写入到 bytes.Buffer
-				// 
-				// 但是，问题在于 fmt.Println 的缓冲可能没有被正确刷新
-				// 所以我们需要在 executeCommandSubstitution 中确保缓冲被刷新
-			}
-		}
-
-		if err := builtinFunc(args, e.env); err != nil {
-			// 检查是否是 exit 命令，如果是，直接返回，不包装
-			if _, ok := err.(*builtin.ExitError); ok {
+		// 已经是只读变量的名称不能再赋数组初始值
+		for _, stmt := range compounds {
+			if err := e.checkReadonly(e.resolveNameref(stmt.Name)); err != nil {
 				return err
 			}
-			// 如果设置了 -e 选项且命令失败，输出错误信息后退出
-			if e.options["e"] {
-				fmt.Fprintf(os.Stderr, "gobash: %s: %v\n", cmdName, err)
-				os.Exit(1)
-			}
-			return fmt.Errorf("%s: %v", cmdName, err)
 		}
 
-		// 处理declare命令的特殊情况
-		if cmdName == "declare" {
-			// 检查是否声明了关联数组
-			if assocName, ok := e.env["__WBASH_DECLARE_ASSOC__"]; ok {
-				// 初始化关联数组
-				if e.assocArrays[assocName] == nil {
-					e.assocArrays[assocName] = make(map[string]string)
-				}
-				e.arrayTypes[assocName] = "assoc"
-				delete(e.env, "__WBASH_DECLARE_ASSOC__")
-			}
-			// 检查是否声明了普通变量
-			if varName, ok := e.env["__WBASH_DECLARE_VAR__"]; ok {
-				e.arrayTypes[varName] = "var"
-				delete(e.env, "__WBASH_DECLARE_VAR__")
-			}
+		// 处理内置命令的重定向
+		var err error
+		if len(cmd.Redirects) > 0 {
+			err = e.executeBuiltinWithRedirect(cmdName, builtinFunc, args, cmd.Redirects)
+		} else {
+			err = e.callBuiltin(cmdName, builtinFunc, args, e.Stdio())
 		}
 
-		// 处理local命令的特殊情况
+		// local 声明的变量已由 saveLocals 记录在调用帧中
 		if cmdName == "local" {
-			// 检查是否声明了局部变量
-			if localVarsStr, ok := e.env["__WBASH_LOCAL_VARS__"]; ok {
-				// 解析局部变量名列表（逗号分隔）
-				localVarNames := strings.Split(localVarsStr, ",")
-				for _, varName := range localVarNames {
-					varName = strings.TrimSpace(varName)
-					if varName != "" {
-						// 标记为局部变量
-						e.localVars[varName] = true
-					}
-				}
-				delete(e.env, "__WBASH_LOCAL_VARS__")
-			}
+			delete(e.env, "__WBASH_LOCAL_VARS__")
 		}
-
-		return nil
+		if err != nil || !declaresVariables(cmdName) {
+			return err
+		}
+		return e.declareArrays(args, compounds)
 	}
 
 	// 检查是否为定义的函数
 	if fn, ok := e.functions[cmdName]; ok {
-		return e.executeFunction(fn, cmd.Args)
-	}
-
-	// 如果设置了 -x 选项，显示执行的命令
-	if e.options["x"] {
-		cmdName := e.evaluateExpression(cmd.Command)
-		fmt.Fprintf(os.Stderr, "+ %s", cmdName)
-		for _, arg := range cmd.Args {
-			argValue := e.evaluateExpression(arg)
-			fmt.Fprintf(os.Stderr, " %s", argValue)
+		if cmd.Background {
+			foreground := *cmd
+			foreground.Background = false
+			return e.executeBackground(&foreground)
 		}
-		fmt.Fprintf(os.Stderr, "\n")
+		return e.executeFunction(fn, cmd.Args)
 	}
 
 	// 执行外部命令
-	err := e.executeExternalCommand(cmd)
-	// 如果设置了 -e 选项且命令失败，输出错误信息后退出
-	if err != nil && e.options["e"] {
-		// 输出错误信息到 stderr
-		fmt.Fprintf(os.Stderr, "gobash: %v\n", err)
-		os.Exit(1)
-	}
-	return err
+	return e.executeExternalCommand(cmd)
 }
 
 // executeBuiltinWithRedirect 执行带重定向的内置命令
+// 重定向只作用于传给内置命令的 IO，不修改进程的标准流
 func (e *Executor) executeBuiltinWithRedirect(cmdName string, builtinFunc builtin.BuiltinFunc, args []string, redirects []*parser.Redirect) error {
-	// 保存原始的stdout和stderr
-	oldStdout := os.Stdout
-	oldStderr := os.Stderr
+	// 与复合命令使用相同的重定向处理（包括 here-document、here-string 和 n>&m）
+	stdio, files, err := e.redirectStdio(e.Stdio(), redirects)
+	if err != nil {
+		return newExecutionError(ExecutionErrorTypeRedirectError,
+			i18n.T("重定向错误"), cmdName, args, 0, "", err)
+	}
+	defer closeFiles(files)
 
-	// 处理重定向
-	var files []*os.File
-	defer func() {
-		// 恢复原始的stdout和stderr
-		os.Stdout = oldStdout
-		os.Stderr = oldStderr
-		// 关闭所有打开的文件
-		for _, f := range files {
-			f.Close()
+	// 执行内置命令
+	return e.callBuiltin(cmdName, builtinFunc, args, stdio)
+}
+
+// callBuiltin 执行内置命令，命令的错误消息写到命令自己的标准错误（遵守 2>/dev/null 等重定向，管道中的命令也不会丢失），
+// 返回只表示退出状态的 StatusError；exit、return 等控制流错误原样返回。
+// 设置了命令超时时内置命令也受超时限制：sleep 等通过 stdio.Context 得知超时并提前返回，退出码为 124
+func (e *Executor) callBuiltin(cmdName string, builtinFunc builtin.BuiltinFunc, args []string, stdio *builtin.IO) error {
+	ctx, cancel := e.commandContext()
+	defer cancel()
+	stdio.Context = ctx
+
+	err := builtinFunc(args, e.env, stdio)
+	if err == nil {
+		return nil
+	}
+	if ctxErr := e.commandWaitError(ctx, cmdName, args); ctxErr != nil {
+		return ctxErr
+	}
+	if !isFailureStatus(err) {
+		return err
+	}
+	// 只表示退出状态、没有消息的错误（如 realpath -q、http -s）不输出
+	if msg := err.Error(); msg != "" && !StatusOnly(err) {
+		// 消息已经以命令名开头时（如 ls: cannot access ...）不再重复
+		if !strings.HasPrefix(msg, cmdName+":") {
+			msg = cmdName + ": " + msg
 		}
-	}()
+		fmt.Fprintf(stdio.Stderr, "%s: %s\n", e.errorPrefix(), msg)
+	}
+	return &builtin.StatusError{Code: ExitStatus(err)}
+}
 
-	for _, redirect := range redirects {
-		target := e.evaluateExpression(redirect.Target)
-		if target == "" {
-			return fmt.Errorf("redirect target is empty")
+// executeAssignments 执行只有赋值的命令（x=1 y="$(cmd)"，由 runCommand 调用）
+// 赋值从左到右进行，值与命令参数一样展开（双引号中展开变量，单引号中不展开），但不进行单词分割；
+// 命令的退出状态是其中最后一个命令替换的退出状态，没有命令替换时为 0
+func (e *Executor) executeAssignments(assignments []*parser.AssignmentWord) error {
+	for _, assign := range assignments {
+		value, err := e.assignmentValue(assign)
+		if err != nil {
+			return err
 		}
+		e.xtraceAssignment(assign.Name, value)
+		e.SetEnv(assign.Name, value)
+	}
+	if err := e.interrupted(); err != nil {
+		return err
+	}
+	if e.substExitCode > 0 {
+		return newExecutionError(ExecutionErrorTypeCommandFailed,
+			"", assignments[len(assignments)-1].Name, nil, e.substExitCode, "", nil)
+	}
+	return nil
+}
 
-		switch redirect.Type {
-		case parser.REDIRECT_OUTPUT:
-			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-			if err != nil {
-				return fmt.Errorf("重定向错误: %v", err)
-			}
-			files = append(files, file)
-			if redirect.FD == 1 {
-				os.Stdout = file
-			} else if redirect.FD == 2 {
-				os.Stderr = file
+// assignmentValue 求值赋值的值，VAR+=value 时追加到变量原来的值之后；
+// 变量有整数属性（declare -i）时值按算术表达式求值，VAR+=value 把值加到原来的值上
+func (e *Executor) assignmentValue(assign *parser.AssignmentWord) (string, error) {
+	var value string
+	if assign.Value != nil {
+		var err error
+		if value, err = e.evaluateExpression(assign.Value); err != nil {
+			return "", err
+		}
+	}
+	name := e.resolveNameref(assign.Name)
+	if err := e.checkReadonly(name); err != nil {
+		return "", err
+	}
+	if e.integers[name] {
+		if assign.Append {
+			old := e.env[name]
+			if old == "" {
+				old = "0"
 			}
-		case parser.REDIRECT_APPEND:
-			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			value = "(" + old + ")+(" + value + ")"
+		}
+		return e.arithmeticValue(value)
+	}
+	if assign.Append {
+		value = e.env[name] + value
+	}
+	return value, nil
+}
+
+// executeWithAssignments 执行带有变量赋值前缀的命令（VAR=value cmd）
+// 与 bash 相同，先展开命令名和参数（其中的 $VAR 是赋值之前的值），再从左到右进行赋值；
+// 赋值只在命令执行期间有效：这些变量在命令执行期间是导出的，外部命令在环境中得到它们，内置命令和函数执行期间可以读取，
+// 命令结束后恢复原来的值和导出属性（原来没有设置的变量被删除）
+func (e *Executor) executeWithAssignments(cmd *parser.CommandStatement) error {
+	command := *cmd
+	command.Assignments = nil
+	cmdName, err := e.evaluateExpression(cmd.Command)
+	if err != nil {
+		return err
+	}
+	// [[ 的参数是解析时构造的条件表达式，执行时才求值
+	if cmdName != "[[" {
+		command.Command = &parser.StringLiteral{Value: cmdName}
+		command.Args = make([]parser.Expression, len(cmd.Args))
+		for i, arg := range cmd.Args {
+			value, err := e.evaluateExpression(arg)
 			if err != nil {
-				return fmt.Errorf("重定向错误: %v", err)
+				return err
 			}
-			files = append(files, file)
-			if redirect.FD == 1 {
-				os.Stdout = file
-			} else if redirect.FD == 2 {
-				os.Stderr = file
+			command.Args[i] = &parser.StringLiteral{Value: value}
+		}
+	}
+
+	type savedVar struct {
+		name     string
+		value    string
+		set      bool
+		exported bool
+	}
+	var saved []savedVar
+	defer func() {
+		// 按相反的顺序恢复，同一个变量赋值多次时恢复为最早的值
+		for i := len(saved) - 1; i >= 0; i-- {
+			if saved[i].set {
+				e.env[saved[i].name] = saved[i].value
+			} else {
+				delete(e.env, saved[i].name)
 			}
-		case parser.REDIRECT_INPUT:
-			file, err := os.Open(target)
-			if err != nil {
-				return fmt.Errorf("重定向错误: %v", err)
+			if !saved[i].exported {
+				e.exports.Unexport(saved[i].name)
 			}
-			files = append(files, file)
-			os.Stdin = file
 		}
+	}()
+	for _, assign := range cmd.Assignments {
+		value, err := e.assignmentValue(assign)
+		if err != nil {
+			return err
+		}
+		name := e.resolveNameref(assign.Name)
+		old, set := e.env[name]
+		saved = append(saved, savedVar{name: name, value: old, set: set, exported: e.exports.IsExported(name)})
+		e.xtraceAssignment(assign.Name, value)
+		e.env[name] = value
+		e.exports.Export(name)
 	}
+	return e.runCommand(&command)
+}
 
-	// 执行内置命令
-	if err := builtinFunc(args, e.env); err != nil {
-		return fmt.Errorf("%s: %v", cmdName, err)
+// runArgs 在子shell中执行已经展开的命令行 args（args[0] 是命令名，不再展开），供 timeout 使用
+// 子shell 在 stdio.Context 下执行，超时后外部命令被终止，循环和 sleep 等内置命令也会停止
+func (e *Executor) runArgs(args []string, env map[string]string, stdio *builtin.IO) error {
+	sub := e.fork()
+	sub.ctx = stdio.Context
+	sub.stdin, sub.stdout, sub.stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+	words := make([]parser.Expression, len(args))
+	for i, arg := range args {
+		words[i] = &parser.StringLiteral{Value: arg}
 	}
+	return sub.executeCommand(&parser.CommandStatement{Command: words[0], Args: words[1:]})
+}
 
-	return nil
+// runScriptFile 把没有 #! 行、操作系统不能执行（ENOEXEC）的文件 path 作为 shell 脚本在子shell中执行，与 bash 相同
+// $0 是命令名 name，位置参数是 args；开头有 NUL 字节的文件是二进制文件，报告不能执行（退出码 126）
+func (e *Executor) runScriptFile(path, name string, args []string, stdio *builtin.IO) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return startError(name, args, err)
+	}
+	if head := data[:min(len(data), 80)]; bytes.IndexByte(head, 0) >= 0 {
+		return startError(name, args, syscall.ENOEXEC)
+	}
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if errs := p.AllErrors(); len(errs) > 0 {
+		fmt.Fprintf(stdio.Stderr, "gobash: %s: %v\n", name, errs[0])
+		return &builtin.StatusError{Code: 2}
+	}
+	sub := e.fork()
+	sub.stdin, sub.stdout, sub.stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+	sub.env["0"] = name
+	sub.setPositional(args)
+	return sub.executeSubshell(&parser.SubshellCommand{Body: &parser.BlockStatement{Statements: program.Statements}})
 }
 
+// startError 返回外部命令无法启动的错误：文件没有执行权限或不是可执行的格式时退出码为 126，其他情况（通常是找不到命令）为 127
+func startError(cmdName string, args []string, err error) error {
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.ENOEXEC) {
+		return newExecutionError(ExecutionErrorTypeNotExecutable,
+			i18n.T("无法执行命令"), cmdName, args, 0, "", err)
+	}
+	return newExecutionError(ExecutionErrorTypeCommandNotFound,
+		i18n.T("无法启动命令"), cmdName, args, 0, "", err)
+}
+
+// notFoundHandler 在 PATH 中找不到命令时调用的函数
+const notFoundHandler = "command_not_found_handle"
+
+// hasNotFoundHandler 检查找不到命令 cmdName 时是否调用 command_not_found_handle：
+// 与 bash 相同，只对不含路径分隔符的命令名调用，处理函数中找不到的命令不再调用它
+func (e *Executor) hasNotFoundHandler(cmdName string) bool {
+	if e.inNotFoundHandler || strings.ContainsAny(cmdName, "/"+string(filepath.Separator)) {
+		return false
+	}
+	_, ok := e.functions[notFoundHandler]
+	return ok
+}
+
+// runNotFoundHandler 以命令名和参数调用 command_not_found_handle，它的退出状态作为命令的退出状态
+// 与 bash 相同，处理函数在子shell中执行（其中的 exit 只结束处理函数），命令的重定向对它生效
+func (e *Executor) runNotFoundHandler(cmdName string, args []string, redirects []*parser.Redirect) error {
+	stdio, files, err := e.redirectStdio(e.Stdio(), redirects)
+	if err != nil {
+		return newExecutionError(ExecutionErrorTypeRedirectError,
+			i18n.T("重定向错误"), cmdName, args, 0, "", err)
+	}
+	defer closeFiles(files)
+
+	sub := e.fork()
+	sub.inNotFoundHandler = true
+	sub.stdin, sub.stdout, sub.stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+	words := make([]parser.Expression, 0, len(args)+1)
+	for _, arg := range append([]string{cmdName}, args...) {
+		words = append(words, &parser.StringLiteral{Value: arg})
+	}
+	err = sub.executeCommand(&parser.CommandStatement{
+		Command: &parser.StringLiteral{Value: notFoundHandler},
+		Args:    words,
+	})
+	if exitErr, ok := err.(*builtin.ExitError); ok {
+		if exitErr.Code == 0 {
+			return nil
+		}
+		return &builtin.StatusError{Code: exitErr.Code}
+	}
+	return err
+}
+
+// interruptGracePeriod 前台命令收到转发的中断信号后，shell 等待它自己退出的时间，超时后强制终止
+const interruptGracePeriod = 2 * time.Second
+
 // executeExternalCommand 执行外部命令
 func (e *Executor) executeExternalCommand(cmd *parser.CommandStatement) error {
-	cmdName := e.evaluateExpression(cmd.Command)
+	cmdName, err := e.evaluateExpression(cmd.Command)
+	if err != nil {
+		return err
+	}
 	if cmdName == "" {
-		return fmt.Errorf("命令名为空")
+		return i18n.Errorf("命令名为空")
 	}
 
 	// 构建参数
 	args := make([]string, len(cmd.Args))
 	for i, arg := range cmd.Args {
-		argValue := e.evaluateExpression(arg)
-		// 检查未定义的变量（set -u）
-		if strings.HasPrefix(argValue, "__UNDEFINED_VAR__") {
-			varName := strings.TrimPrefix(argValue, "__UNDEFINED_VAR__")
-			return newExecutionError(ExecutionErrorTypeVariableError,
-				fmt.Sprintf("未定义的变量: %s", varName), cmdName, args[:i], 0, "", nil)
+		argValue, err := e.evaluateExpression(arg)
+		if err != nil {
+			return err
 		}
 		args[i] = argValue
 	}
 
-	// 创建命令
-	execCmd := exec.Command(cmdName, args...)
-	execCmd.Env = e.getEnvArray()
-
-	// 处理重定向
-	if err := e.setupRedirects(execCmd, cmd.Redirects); err != nil {
-		return newExecutionError(ExecutionErrorTypeRedirectError,
-			"重定向错误", cmdName, args, 0, "", err)
-	}
-
-	// 处理管道
-	if cmd.Pipe != nil {
-		return e.executePipe(cmd, cmd.Pipe)
-	}
-
-	// 设置标准输入输出（如果没有重定向）
-	if execCmd.Stdin == nil {
-		execCmd.Stdin = os.Stdin
-	}
-	if execCmd.Stdout == nil {
-		execCmd.Stdout = os.Stdout
+	if e.dryRun {
+		e.xtrace(append([]string{cmdName}, args...))
+		e.dryRunPrint(append([]string{cmdName}, args...), cmd.Redirects)
+		return nil
 	}
-	if execCmd.Stderr == nil {
-		execCmd.Stderr = os.Stderr
+	if err := e.Stdio().Check(builtin.AccessExec, cmdName, args...); err != nil {
+		return startError(cmdName, args, err)
 	}
 
-	// 执行命令
+	// 创建命令：前台命令随执行的 context 取消（或命令超时）而终止，后台作业不受影响
+	// 命令按 shell 的 PATH 查找（Windows 上按 PATHEXT 补全扩展名，脚本交给 #! 行指定的解释器）
+	var execCmd *exec.Cmd
+	cmdCtx := e.execContext()
+	var cancel context.CancelFunc
 	if cmd.Background {
-		if err := execCmd.Start(); err != nil {
-			// 检查是否是命令未找到
-			if _, ok := err.(*exec.ExitError); !ok {
-				// 通常是 "executable file not found" 错误
-				return newExecutionError(ExecutionErrorTypeCommandNotFound,
-					"无法启动命令", cmdName, args, 0, "", err)
+		execCmd, err = builtin.Command(nil, cmdName, args, e.env)
+	} else {
+		cmdCtx, cancel = e.commandContext()
+		defer func() {
+			// 被 Ctrl+Z 停止的命令转为作业，命令结束后才取消 context（见下面的 stopped）
+			if cancel != nil {
+				cancel()
 			}
-			return newExecutionError(ExecutionErrorTypeCommandFailed,
-				"无法启动命令", cmdName, args, 0, "", err)
-		}
-		// 构建命令字符串用于显示
-		cmdStr := cmdName
-		for _, arg := range args {
-			cmdStr += " " + arg
-		}
-		// 添加到作业管理器
-		jobID := e.jobs.AddJob(execCmd, cmdStr)
-		fmt.Fprintf(os.Stderr, "[%d] %d\n", jobID, execCmd.Process.Pid)
-		return nil
+		}()
+		execCmd, err = builtin.Command(cmdCtx, cmdName, args, e.env)
 	}
-
-	// 对于前台命令，使用 Start() + Wait() 而不是 Run()，以便处理信号
-	if err := execCmd.Start(); err != nil {
-		// 检查是否是命令未找到
-		if _, ok := err.(*exec.ExitError); !ok {
-			// 通常是 "executable file not found" 错误
-			return newExecutionError(ExecutionErrorTypeCommandNotFound,
-				"无法启动命令", cmdName, args, 0, "", err)
-		}
-		return newExecutionError(ExecutionErrorTypeCommandFailed,
-			"无法启动命令", cmdName, args, 0, "", err)
-	}
-
-	// 设置信号处理，当收到 SIGINT (Ctrl+C) 时，向子进程发送信号
-	// os.Interrupt 在所有平台都可用（Windows/Linux/macOS）
-	// syscall.SIGTERM 在 Unix 系统上可用，Windows 上会被 signal.Notify 自动忽略
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	// 使用 goroutine 等待命令完成
-	done := make(chan error, 1)
-	go func() {
-		done <- execCmd.Wait()
-	}()
-
-	// 等待命令完成或收到信号
-	select {
-	case err := <-done:
-		// 命令完成，停止信号监听
-		signal.Stop(sigChan)
-		if err != nil {
-			// 检查是否是命令未找到
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				// 命令执行失败，返回退出码
-				exitCode := 1
-				if exitErr.ProcessState != nil {
-					exitCode = exitErr.ProcessState.ExitCode()
-				}
-				return newExecutionError(ExecutionErrorTypeCommandFailed,
-					"命令执行失败", cmdName, args, exitCode, "", err)
-			}
-			// 命令未找到或无法执行
-			return newExecutionError(ExecutionErrorTypeCommandNotFound,
-				"命令未找到或无法执行", cmdName, args, 0, "", err)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) && e.hasNotFoundHandler(cmdName) {
+			return e.runNotFoundHandler(cmdName, args, cmd.Redirects)
 		}
-		return nil
-	case sig := <-sigChan:
-		// 收到中断信号，向子进程发送相同的信号
-		if execCmd.Process != nil {
-			// 尝试优雅地终止进程
-			// 注意：在 Windows 上，某些信号可能不被支持，Signal() 可能返回错误
-			// 我们忽略这个错误，因为如果 Signal() 失败，我们会用 Kill() 作为后备
-			_ = execCmd.Process.Signal(sig)
-			// 等待一小段时间让进程有机会退出
-			select {
-			case <-done:
-				// 进程已经退出
-			default:
-				// 如果进程没有退出，强制终止
-				execCmd.Process.Kill()
-				<-done
+		// 带路径的命令是目录时与 bash 相同，报告不能执行（退出码 126）
+		if strings.ContainsAny(cmdName, "/"+string(filepath.Separator)) {
+			if info, statErr := os.Stat(e.resolvePath(cmdName)); statErr == nil && info.IsDir() {
+				return newExecutionError(ExecutionErrorTypeNotExecutable,
+					i18n.T("无法执行命令"), cmdName, args, 0, "", i18n.NewError("是一个目录"))
 			}
 		}
-		signal.Stop(sigChan)
-		// 返回中断错误
-		return fmt.Errorf("命令被中断")
-	}
-}
-
-// executePipe 执行管道
-func (e *Executor) executePipe(left, right *parser.CommandStatement) error {
-	leftCmdName := e.evaluateExpression(left.Command)
-	if leftCmdName == "" {
-		return fmt.Errorf("管道左侧命令名为空")
+		return startError(cmdName, args, err)
 	}
-
-	leftArgs := make([]string, len(left.Args))
-	for i, arg := range left.Args {
-		leftArgs[i] = e.evaluateExpression(arg)
-	}
-
-	rightCmdName := e.evaluateExpression(right.Command)
-	if rightCmdName == "" {
-		return fmt.Errorf("管道右侧命令名为空")
+	execCmd.Env = e.getEnvArray()
+	if builtin.ShellPath != "" && execCmd.Path == builtin.ShellPath && builtin.SelfExec(e.env) {
+		// 由 gobash 执行的脚本中的 bash、sh 同样由 gobash 执行（shopt -s selfexec 不传给子进程）
+		execCmd.Env = append(execCmd.Env, "GOBASH_SELF_EXEC=1")
 	}
+	execCmd.Dir = e.Dir()
 
-	rightArgs := make([]string, len(right.Args))
-	for i, arg := range right.Args {
-		rightArgs[i] = e.evaluateExpression(arg)
+	// 处理重定向：与内置命令和复合命令使用相同的处理（here-document 和 here-string 通过内存中的 Reader 作为标准输入），
+	// 打开的文件在命令启动后由 shell 关闭（子进程持有自己的副本）
+	stdio := e.Stdio()
+	redirected, files, err := e.redirectStdio(stdio, cmd.Redirects)
+	if err != nil {
+		return newExecutionError(ExecutionErrorTypeRedirectError,
+			i18n.T("重定向错误"), cmdName, args, 0, "", err)
 	}
+	defer closeFiles(files)
+	execCmd.Stdin, execCmd.Stdout, execCmd.Stderr = redirected.Stdin, redirected.Stdout, redirected.Stderr
 
-	// 创建左侧命令
-	leftCmd := exec.Command(leftCmdName, leftArgs...)
-	leftCmd.Env = e.getEnvArray()
-
-	// 创建右侧命令
-	rightCmd := exec.Command(rightCmdName, rightArgs...)
-	rightCmd.Env = e.getEnvArray()
+	// 如果设置了 -x 选项，显示执行的命令
+	e.xtrace(append([]string{cmdName}, args...))
 
-	// 设置管道
-	pipe, err := leftCmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("创建管道失败: %v", err)
+	// 启用作业控制时命令在作业的进程组中运行：管道中的命令属于管道的作业（e.pgroup），
+	// 其他命令（包括后台命令）自己成为一个作业，前台作业结束或停止后 shell 收回终端
+	group := e.pgroup
+	if group == nil || cmd.Background {
+		group = e.newProcessGroup(!cmd.Background)
+		if group != nil && !cmd.Background {
+			defer group.reclaimTerminal()
+		}
 	}
-	rightCmd.Stdin = pipe
-	rightCmd.Stdout = os.Stdout
-	rightCmd.Stderr = os.Stderr
 
-	// 启动右侧命令
-	if err := rightCmd.Start(); err != nil {
-		return fmt.Errorf("启动右侧命令 '%s' 失败: %v", rightCmdName, err)
-	}
+	// 构建命令字符串用于显示
+	cmdStr := strings.Join(append([]string{cmdName}, args...), " ")
 
-	// 启动左侧命令
-	if err := leftCmd.Start(); err != nil {
-		rightCmd.Process.Kill()
-		return fmt.Errorf("启动左侧命令 '%s' 失败: %v", leftCmdName, err)
+	// 执行命令
+	if cmd.Background {
+		if err := startProcess(execCmd, group); err != nil {
+			return startError(cmdName, args, err)
+		}
+		// 添加到作业管理器
+		jobID := e.jobs.AddJob(execCmd, cmdStr, group)
+		e.env["!"] = strconv.Itoa(execCmd.Process.Pid)
+		e.reportJobStart(stdio.Stderr, jobID, execCmd.Process.Pid)
+		return nil
 	}
 
 	// 设置信号处理，当收到 SIGINT (Ctrl+C) 时，向子进程发送信号
-	// os.Interrupt 在所有平台都可用（Windows/Linux/macOS）
+	// os.Interrupt 在所有平台都可用（Windows/Linux/macOS），Windows 上控制台的 Ctrl+C 和 Ctrl+Break 都是 os.Interrupt
 	// syscall.SIGTERM 在 Unix 系统上可用，Windows 上会被 signal.Notify 自动忽略
+	// 在启动命令之前开始监听，shell 不会在命令启动的过程中被 Ctrl+C 终止
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// 使用 goroutine 等待两个命令完成
-	done := make(chan error, 2)
-	go func() {
-		done <- leftCmd.Wait()
-	}()
+	// 对于前台命令，使用 Start() + Wait() 而不是 Run()，以便处理信号
+	if err := startProcess(execCmd, group); err != nil {
+		signal.Stop(sigChan)
+		if errors.Is(err, syscall.ENOEXEC) {
+			return e.runScriptFile(execCmd.Path, cmdName, args, redirected)
+		}
+		return startError(cmdName, args, err)
+	}
+	trackForeground(execCmd.Process)
+
+	// 使用 goroutine 等待命令完成
+	done := make(chan error, 1)
 	go func() {
-		done <- rightCmd.Wait()
+		done <- waitProcess(execCmd, group)
 	}()
 
+	// 命令自己成为前台作业时，被 Ctrl+Z 停止后转为停止的作业（管道中的命令由管道处理）
+	var stopped chan syscall.Signal
+	if group != nil && group != e.pgroup {
+		stopped = group.stopped
+	}
+
 	// 等待命令完成或收到信号
 	select {
-	case err := <-done:
-		// 第一个命令完成，检查是否有错误
-		if err != nil {
-			// 如果左侧命令失败，终止右侧命令
-			if rightCmd.Process != nil {
-				rightCmd.Process.Kill()
-			}
-			signal.Stop(sigChan)
-			return fmt.Errorf("执行左侧命令 '%s' 失败: %v", leftCmdName, err)
-		}
-		// 关闭管道，让右侧命令知道输入结束
-		pipe.Close()
-		// 等待右侧命令完成
-		err = <-done
+	case err := <-done:
+		// 命令完成，停止信号监听
 		signal.Stop(sigChan)
 		if err != nil {
-			// 获取退出码（如果可用）
-			exitCode := 1
+			if ctxErr := e.commandWaitError(cmdCtx, cmdName, args); ctxErr != nil {
+				return ctxErr
+			}
+			// 检查是否是命令未找到
 			if exitErr, ok := err.(*exec.ExitError); ok {
-				if exitErr.ProcessState != nil {
-					exitCode = exitErr.ProcessState.ExitCode()
-				}
+				// 命令执行失败，返回退出码
+				return newExecutionError(ExecutionErrorTypeCommandFailed,
+					i18n.T("命令执行失败"), cmdName, args, processExitCode(exitErr.ProcessState), "", err)
 			}
-			return newExecutionError(ExecutionErrorTypePipeError,
-				"等待右侧命令完成失败", rightCmdName, nil, exitCode, "", err)
+			// 命令未找到或无法执行
+			return newExecutionError(ExecutionErrorTypeCommandNotFound,
+				i18n.T("命令未找到或无法执行"), cmdName, args, 0, "", err)
 		}
 		return nil
-	case sig := <-sigChan:
-		// 收到中断信号，向两个进程发送相同的信号
-		// 注意：在 Windows 上，某些信号可能不被支持，Signal() 可能返回错误
-		// 我们忽略这个错误，因为如果 Signal() 失败，我们会用 Kill() 作为后备
-		if leftCmd.Process != nil {
-			_ = leftCmd.Process.Signal(sig)
-		}
-		if rightCmd.Process != nil {
-			_ = rightCmd.Process.Signal(sig)
-		}
-		// 等待一小段时间让进程有机会退出
-		select {
-		case <-done:
+	case sig := <-stopped:
+		signal.Stop(sigChan)
+		group.reclaimTerminal()
+		finished := make(chan struct{})
+		release := cancel
+		cancel = nil
+		go func() {
 			<-done
-		default:
-			// 如果进程没有退出，强制终止
-			if leftCmd.Process != nil {
-				leftCmd.Process.Kill()
-			}
-			if rightCmd.Process != nil {
-				rightCmd.Process.Kill()
+			if release != nil {
+				release()
+			}
+			close(finished)
+		}()
+		jobID := e.jobs.addStoppedJob(cmdStr, execCmd.Process, group, finished)
+		fmt.Fprintf(stdio.Stderr, "\n[%d]+  Stopped                 %s\n", jobID, cmdStr)
+		return &builtin.StatusError{Code: 128 + int(sig)}
+	case sig := <-sigChan:
+		// 收到中断信号，向子进程发送相同的信号
+		if execCmd.Process != nil {
+			// 尝试优雅地终止进程（Windows 上向命令的进程组发送 Ctrl+Break）
+			// 我们忽略转发失败的错误，因为进程没有退出时我们会用 Kill() 作为后备
+			_ = interruptProcess(execCmd.Process, sig)
+			// 等待一小段时间让进程有机会退出
+			select {
+			case <-done:
+				// 进程已经退出
+			case <-time.After(interruptGracePeriod):
+				// 如果进程没有退出，强制终止
+				execCmd.Process.Kill()
+				<-done
 			}
-			<-done
-			<-done
 		}
 		signal.Stop(sigChan)
 		// 返回中断错误
-		return fmt.Errorf("命令被中断")
+		return i18n.Errorf("命令被中断")
 	}
 }
 
-// setupRedirects 设置重定向
-func (e *Executor) setupRedirects(cmd *exec.Cmd, redirects []*parser.Redirect) error {
-	for _, redirect := range redirects {
-		// 对于 heredoc，target 可能为空（因为内容在 HereDoc 中）
-		target := ""
-		if redirect.Target != nil {
-			target = e.evaluateExpression(redirect.Target)
-		}
-		
-		// 只有非 heredoc 类型才检查 target 是否为空
-		if target == "" && redirect.Type != parser.REDIRECT_HEREDOC && redirect.Type != parser.REDIRECT_HEREDOC_STRIP {
-			return fmt.Errorf("redirect target is empty")
+// executePipeline 执行管道
+// 与 bash 一样，管道中的每个命令都在子shell中执行（见 fork），相邻命令之间用 os.Pipe 连接：
+// 外部命令直接读写管道的文件描述符，内置命令、函数和复合命令在各自的 goroutine 中运行。
+// 管道的退出状态是最后一个命令的退出状态；开启 pipefail 时是最后一个失败命令的退出状态
+func (e *Executor) executePipeline(pipeline *parser.PipelineStatement) error {
+	n := len(pipeline.Commands)
+	readers := make([]*os.File, n-1)
+	writers := make([]*os.File, n-1)
+	for i := 0; i < n-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			for j := 0; j < i; j++ {
+				readers[j].Close()
+				writers[j].Close()
+			}
+			return newExecutionError(ExecutionErrorTypePipeError,
+				i18n.T("创建管道失败"), pipeline.String(), nil, 1, "", err)
 		}
+		readers[i], writers[i] = r, w
+	}
 
-		switch redirect.Type {
-		case parser.REDIRECT_OUTPUT:
-			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-			if err != nil {
-				return err
-			}
-			if redirect.FD == 1 {
-				cmd.Stdout = file
-			} else if redirect.FD == 2 {
-				cmd.Stderr = file
-			} else {
-				file.Close()
-			}
-		case parser.REDIRECT_APPEND:
-			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-			if err != nil {
-				return err
-			}
-			if redirect.FD == 1 {
-				cmd.Stdout = file
-			} else if redirect.FD == 2 {
-				cmd.Stderr = file
-			} else {
-				file.Close()
-			}
-		case parser.REDIRECT_INPUT:
-			file, err := os.Open(target)
-			if err != nil {
-				return err
-			}
-			cmd.Stdin = file
-		case parser.REDIRECT_HEREDOC, parser.REDIRECT_HEREDOC_STRIP:
-			// Here-document 处理
-			if redirect.HereDoc != nil {
-				content := redirect.HereDoc.Content
-				if content == "" {
-					// 如果内容为空，尝试从标准输入读取（这在交互模式下可能需要）
-					// 对于脚本模式，内容应该在解析时已经填充
-					content = e.readHereDocument(redirect.HereDoc.Delimiter, redirect.HereDoc.Quoted, redirect.HereDoc.StripTabs)
-					redirect.HereDoc.Content = content
-				}
-				// 创建临时文件或使用字符串作为输入
-				reader := strings.NewReader(content)
-				cmd.Stdin = io.NopCloser(reader)
-			}
-		case parser.REDIRECT_HERESTRING:
-			// Here-string (<<<) 处理
-			if redirect.Target != nil {
-				content := e.evaluateExpression(redirect.Target)
-				reader := strings.NewReader(content)
-				cmd.Stdin = io.NopCloser(reader)
-			}
-		case parser.REDIRECT_DUP_IN:
-			// <& 复制文件描述符
-			_, err := strconv.Atoi(target)
-			if err != nil {
-				return fmt.Errorf("无效的文件描述符: %s", target)
-			}
-			// 这里简化处理，实际应该复制文件描述符
-			// 在 Go 中，这需要更复杂的处理
-		case parser.REDIRECT_DUP_OUT:
-			// >& 复制文件描述符
-			_, err := strconv.Atoi(target)
-			if err != nil {
-				return fmt.Errorf("无效的文件描述符: %s", target)
+	// 启用作业控制时整个管道是一个前台作业，管道中的外部命令在同一个进程组中运行
+	group := e.pgroup
+	if group == nil {
+		group = e.newProcessGroup(true)
+	}
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, cmd := range pipeline.Commands {
+		sub := e.fork()
+		sub.pgroup = group
+		if i > 0 {
+			sub.stdin = readers[i-1]
+		}
+		if i < n-1 {
+			sub.stdout = writers[i]
+			if pipeline.Stderr[i] {
+				sub.stderr = writers[i]
 			}
-			// 这里简化处理，实际应该复制文件描述符
-		case parser.REDIRECT_CLOBBER:
-			// >| 强制覆盖（与 > 相同，但忽略 noclobber 选项）
-			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-			if err != nil {
-				return err
+		}
+		wg.Add(1)
+		go func(i int, sub *Executor, cmd parser.Statement) {
+			defer wg.Done()
+			errs[i] = sub.executeStatement(cmd)
+			// 命令结束后关闭它使用的管道端：下一个命令读到 EOF，上一个命令再写入时失败（SIGPIPE）
+			if i < n-1 {
+				writers[i].Close()
 			}
-			if redirect.FD == 1 {
-				cmd.Stdout = file
-			} else if redirect.FD == 2 {
-				cmd.Stderr = file
-			} else {
-				file.Close()
+			if i > 0 {
+				readers[i-1].Close()
 			}
-		case parser.REDIRECT_RW:
-			// <> 读写重定向
-			file, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, 0644)
-			if err != nil {
-				return err
+		}(i, sub, cmd)
+	}
+	if group != nil && group != e.pgroup {
+		// 管道被 Ctrl+Z 停止时转为停止的作业，管道中的命令结束后作业完成
+		finished := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+			group.reclaimTerminal()
+		case sig := <-group.stopped:
+			group.reclaimTerminal()
+			jobID := e.jobs.addStoppedJob(pipeline.String(), nil, group, finished)
+			fmt.Fprintf(e.Stdio().Stderr, "\n[%d]+  Stopped                 %s\n", jobID, pipeline.String())
+			return &builtin.StatusError{Code: 128 + int(sig)}
+		}
+	}
+	wg.Wait()
+
+	if err := e.interrupted(); err != nil {
+		return err
+	}
+
+	last := n - 1
+	if e.options["pipefail"] {
+		for i := n - 1; i >= 0; i-- {
+			if errs[i] != nil && ExitStatus(errs[i]) != 0 {
+				last = i
+				break
 			}
-			cmd.Stdin = file
-			cmd.Stdout = file
 		}
 	}
-	return nil
+	// 管道的结果由调用者报告，其他命令的错误（如 nosuchcmd: command not found）在这里输出
+	for i, cmdErr := range errs {
+		if i != last && cmdErr != nil && isFailureStatus(cmdErr) && !StatusOnly(cmdErr) {
+			fmt.Fprintf(e.Stdio().Stderr, "%s: %v\n", e.errorPrefix(), cmdErr)
+		}
+	}
+	err := errs[last]
+	if err == nil || ExitStatus(err) == 0 {
+		return nil
+	}
+	if !isFailureStatus(err) {
+		// exit 和 set -e 只结束管道中的子shell，对当前shell来说只是命令失败
+		return newExecutionError(ExecutionErrorTypeCommandFailed,
+			"", pipeline.String(), nil, ExitStatus(err), "", nil)
+	}
+	return err
 }
 
 // executeIf 执行if语句
+// 依次执行 if 和 elif 的条件命令列表，执行第一个退出状态为 0 的条件之后的命令列表，都不为 0 时执行 else 的命令列表；
+// if 语句的结果是执行的命令列表的结果，没有执行任何命令列表时退出状态为 0
 func (e *Executor) executeIf(stmt *parser.IfStatement) error {
-	// 执行条件命令，检查退出码
-	if err := e.executeCommand(stmt.Condition); err == nil {
-		// 条件成功，执行consequence
-		if err := e.executeBlock(stmt.Consequence); err != nil {
-			// 检查是否是 break/continue 错误，需要向上传播
-			if err == BreakError || err == ContinueError {
-				return err
-			}
-			if _, ok := err.(*BreakLevelError); ok {
-				return err
-			}
-			if _, ok := err.(*ContinueLevelError); ok {
-				return err
-			}
-			return err
-		}
+	status, err := e.conditionStatus(stmt.Condition)
+	if err != nil {
+		return err
+	}
+	if status == 0 {
+		return e.executeBlock(stmt.Consequence)
 	}
-
-	// 条件失败，检查elif
 	for _, elif := range stmt.Elif {
-		if err := e.executeCommand(elif.Condition); err == nil {
-			if err := e.executeBlock(elif.Consequence); err != nil {
-				// 检查是否是 break/continue 错误，需要向上传播
-				if err == BreakError || err == ContinueError {
-					return err
-				}
-				if _, ok := err.(*BreakLevelError); ok {
-					return err
-				}
-				if _, ok := err.(*ContinueLevelError); ok {
-					return err
-				}
-				return err
-			}
+		if status, err = e.conditionStatus(elif.Condition); err != nil {
+			return err
+		}
+		if status == 0 {
+			return e.executeBlock(elif.Consequence)
 		}
 	}
-
-	// 执行else
 	if stmt.Alternative != nil {
-		if err := e.executeBlock(stmt.Alternative); err != nil {
-			// 检查是否是 break/continue 错误，需要向上传播
-			if err == BreakError || err == ContinueError {
-				return err
-			}
-			if _, ok := err.(*BreakLevelError); ok {
-				return err
-			}
-			if _, ok := err.(*ContinueLevelError); ok {
-				return err
-			}
-			return err
-		}
+		return e.executeBlock(stmt.Alternative)
 	}
-
+	e.env["?"] = "0"
 	return nil
 }
 
 // executeFor 执行for循环
 func (e *Executor) executeFor(stmt *parser.ForStatement) error {
-	// 如果没有in子句，使用位置参数（$1, $2, ...）
-	if len(stmt.In) == 0 {
-		// 从环境变量中获取位置参数
-		// 位置参数存储在环境变量中，键为"1", "2", "3"等
-		// 参数个数存储在"#"中
-		argCount := 0
-		if countStr, ok := e.env["#"]; ok {
-			if count, err := fmt.Sscanf(countStr, "%d", &argCount); err == nil && count == 1 {
-				// 成功解析参数个数
-			}
-		}
-
-		// 如果没有参数个数信息，尝试从$@获取
-		if argCount == 0 {
-			if allArgs, ok := e.env["@"]; ok && allArgs != "" {
-				// 从$@中提取参数（空格分隔）
-				args := strings.Fields(allArgs)
-				argCount = len(args)
-			}
-		}
-
-		// 遍历位置参数
-		for i := 1; i <= argCount; i++ {
-			key := fmt.Sprintf("%d", i)
-			if value, ok := e.env[key]; ok {
-				e.env[stmt.Variable] = value
-				if err := e.executeBlock(stmt.Body); err != nil {
-					// 检查是否是 break 或 continue
-					if err == BreakError {
-						break
-					}
-					if err == ContinueError {
-						continue
-					}
-					if breakErr, ok := err.(*BreakLevelError); ok {
-						if breakErr.Level <= 1 {
-							break
-						} else {
-							// 需要跳出更多层，向上传播
-							return err
-						}
-					}
-					if continueErr, ok := err.(*ContinueLevelError); ok {
-						if continueErr.Level <= 1 {
-							continue
-						} else {
-							// 需要继续更多层，向上传播
-							return err
-						}
-					}
-					return err
-				}
-			}
+	// 没有 in 子句时遍历位置参数（与 in "$@" 相同，参数中的空格不分割）；
+	// 有 in 子句时单词列表经过单词分割和路径名展开（见 fields.go），in 之后没有单词时不执行循环体
+	values := e.positionalParams()
+	if stmt.In != nil {
+		var err error
+		values, err = e.expandFields(stmt.In)
+		if err != nil {
+			return err
 		}
-		return nil
 	}
-
-	// 有in子句，使用指定的值列表
-	for _, item := range stmt.In {
-		value := e.evaluateExpression(item)
+	for _, value := range values {
+		e.xtraceFor(stmt.Variable, values)
 		e.env[stmt.Variable] = value
 		if err := e.executeBlock(stmt.Body); err != nil {
 			// 检查是否是 break 或 continue
@@ -1048,82 +1538,66 @@ func (e *Executor) executeFor(stmt *parser.ForStatement) error {
 					return err
 				}
 			}
-			return err
+			if !isFailureStatus(err) {
+				return err
+			}
+			// 循环体中最后一条命令失败不会结束循环（set -e 生效时已经返回 ScriptExitError）
 		}
 	}
 
 	return nil
 }
 
-// executeWhile 执行while循环
+// executeWhile 执行 while 或 until 循环
+// 条件处于条件上下文，其中的命令失败时不触发 set -e（bash 的行为）
 func (e *Executor) executeWhile(stmt *parser.WhileStatement) error {
-	// 保存原始的 set -e 状态
-	originalSetE := e.options["e"]
-	// 在 while 循环条件中，临时禁用 set -e（bash 的行为）
-	e.options["e"] = false
-
+	// 循环的退出状态是最后一次执行循环体的退出状态，没有执行循环体或用 break 退出时为 0
+	last := 0
 	for {
-		// 执行条件命令，检查退出码
-		// 如果命令返回错误（非零退出码），条件为假，退出循环
-		// 如果命令成功（零退出码），条件为真，继续执行循环体
-		err := e.executeCommand(stmt.Condition)
-		if err != nil {
-			// 检查是否是退出码错误（ExitError）
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				// 这是正常的退出码，非零表示条件为假
-				exitCode := exitErr.ExitCode()
-				if exitCode != 0 {
-					break
-				}
-			} else {
-				// 其他错误（如命令未找到），也视为条件为假
+		// while 在条件的退出状态为 0 时执行循环体，until 在不为 0 时执行循环体
+		// 条件中的 break 和 continue 与循环体中的相同，exit、return 等向上传播
+		status, err := e.conditionStatus(stmt.Condition)
+		if err == nil && (status == 0) == stmt.Until {
+			break
+		}
+		last = 0
+		if err == nil && stmt.Body != nil {
+			err = e.executeBlock(stmt.Body)
+		}
+		if err == nil {
+			continue
+		}
+		// 检查是否是 break 或 continue
+		if err == BreakError {
+			break
+		}
+		if err == ContinueError {
+			continue
+		}
+		if breakErr, ok := err.(*BreakLevelError); ok {
+			if breakErr.Level <= 1 {
 				break
 			}
+			// 需要跳出更多层，向上传播
+			return err
 		}
-		// 条件为真，执行循环体（恢复原始的 set -e 状态）
-		e.options["e"] = originalSetE
-		// 检查循环体是否为空
-		if stmt.Body != nil && len(stmt.Body.Statements) > 0 {
-			if err := e.executeBlock(stmt.Body); err != nil {
-				// 检查是否是 break 或 continue
-				if err == BreakError {
-					e.options["e"] = originalSetE
-					break
-				}
-				if err == ContinueError {
-					e.options["e"] = false
-					continue
-				}
-				if breakErr, ok := err.(*BreakLevelError); ok {
-					if breakErr.Level <= 1 {
-						e.options["e"] = originalSetE
-						break
-					} else {
-						// 需要跳出更多层，向上传播
-						e.options["e"] = originalSetE
-						return err
-					}
-				}
-				if continueErr, ok := err.(*ContinueLevelError); ok {
-					if continueErr.Level <= 1 {
-						e.options["e"] = false
-						continue
-					} else {
-						// 需要继续更多层，向上传播
-						e.options["e"] = false
-						return err
-					}
-				}
-				// 在循环体中，如果 set -e 启用且出错，应该退出
-				e.options["e"] = originalSetE
-				return err
+		if continueErr, ok := err.(*ContinueLevelError); ok {
+			if continueErr.Level <= 1 {
+				continue
 			}
+			// 需要继续更多层，向上传播
+			return err
+		}
+		if !isFailureStatus(err) {
+			return err
 		}
-		// 再次禁用 set -e 用于下一次条件检查
-		e.options["e"] = false
+		// 循环体中最后一条命令失败不会结束循环（set -e 生效时已经返回 ScriptExitError）
+		last = ExitStatus(err)
+	}
+	e.env["?"] = strconv.Itoa(last)
+	if last != 0 {
+		return &builtin.StatusError{Code: last}
 	}
-	// 恢复原始的 set -e 状态
-	e.options["e"] = originalSetE
 	return nil
 }
 
@@ -1144,28 +1618,59 @@ func (e *Executor) executeContinue(stmt *parser.ContinueStatement) error {
 }
 
 // executeBlock 执行代码块
+// 中间的命令失败不会中断执行（set -e 生效时命令会返回 ScriptExitError），
+// 代码块的结果是最后一条命令的结果
 func (e *Executor) executeBlock(block *parser.BlockStatement) error {
-	for _, stmt := range block.Statements {
-		if err := e.executeStatement(stmt); err != nil {
-			// 传播 break/continue 错误
-			if err == BreakError || err == ContinueError {
-				return err
-			}
-			if _, ok := err.(*BreakLevelError); ok {
-				return err
-			}
-			if _, ok := err.(*ContinueLevelError); ok {
-				return err
-			}
+	var lastErr error
+	for i, stmt := range block.Statements {
+		err := e.executeStatement(stmt)
+		if err != nil && !isFailureStatus(err) {
+			// 传播 exit、break、continue 以及 set -e 导致的退出
 			return err
 		}
+		if i < len(block.Statements)-1 && !StatusOnly(err) {
+			fmt.Fprintf(e.Stdio().Stderr, "%s: %v\n", e.errorPrefix(), err)
+		}
+		lastErr = err
 	}
-	return nil
+	return lastErr
+}
+
+// conditionStatus 执行 if/elif/while/until 的条件命令列表，返回最后一条命令的退出状态
+// 条件中的命令失败时不触发 set -e，也不输出错误信息；exit、return、break 等作为错误返回
+func (e *Executor) conditionStatus(cond *parser.BlockStatement) (int, error) {
+	if cond == nil {
+		return 0, nil
+	}
+	status := 0
+	err := e.inCondition(func() error {
+		for _, stmt := range cond.Statements {
+			err := e.executeStatement(stmt)
+			if err != nil && !isFailureStatus(err) {
+				return err
+			}
+			status = ExitStatus(err)
+		}
+		return nil
+	})
+	return status, err
 }
 
 // executeArrayAssignment 执行数组赋值
 // 例如：arr=(1 2 3) 或 arr=([0]=a [1]=b [2]=c)
 func (e *Executor) executeArrayAssignment(stmt *parser.ArrayAssignmentStatement) error {
+	if name := e.resolveNameref(stmt.Name); name != stmt.Name {
+		// 给名称引用赋值数组时赋给它引用的数组
+		resolved := *stmt
+		resolved.Name = name
+		stmt = &resolved
+	}
+	if err := e.checkReadonly(stmt.Name); err != nil {
+		return err
+	}
+	if e.arrayTypes[stmt.Name] == "assoc" {
+		return e.assignAssocArray(stmt)
+	}
 	// 检查是否是带索引的数组赋值
 	if len(stmt.IndexedValues) > 0 {
 		// 带索引的数组赋值 arr=([0]=a [1]=b [2]=c)
@@ -1188,8 +1693,11 @@ func (e *Executor) executeArrayAssignment(stmt *parser.ArrayAssignmentStatement)
 						e.assocArrays[stmt.Name] = make(map[string]string)
 					}
 					// 展开索引中的变量
-					key := e.expandVariablesInString(indexStr)
-					value := e.evaluateExpression(valueExpr)
+					key := e.expandSubscript(indexStr)
+					value, err := e.evaluateExpression(valueExpr)
+					if err != nil {
+						return err
+					}
 					e.assocArrays[stmt.Name][key] = value
 				} else {
 					// 创建关联数组
@@ -1198,8 +1706,11 @@ func (e *Executor) executeArrayAssignment(stmt *parser.ArrayAssignmentStatement)
 					}
 					e.arrayTypes[stmt.Name] = "assoc"
 					// 展开索引中的变量
-					key := e.expandVariablesInString(indexStr)
-					value := e.evaluateExpression(valueExpr)
+					key := e.expandSubscript(indexStr)
+					value, err := e.evaluateExpression(valueExpr)
+					if err != nil {
+						return err
+					}
 					e.assocArrays[stmt.Name][key] = value
 				}
 				continue
@@ -1209,17 +1720,26 @@ func (e *Executor) executeArrayAssignment(stmt *parser.ArrayAssignmentStatement)
 			if index > maxIndex {
 				maxIndex = index
 			}
-			value := e.evaluateExpression(valueExpr)
+			value, err := e.evaluateExpression(valueExpr)
+			if err != nil {
+				return err
+			}
 			indexedMap[index] = value
 		}
 
 		// 如果是数字索引，创建普通数组
 		if !hasStringKeys && maxIndex >= 0 {
-			values := make([]string, maxIndex+1)
+			var indices []int
+			var values []string
+			if stmt.Append {
+				// arr+=([i]=v) 在原来的元素上赋值
+				indices, values = e.appendBase(stmt.Name)
+			}
+			e.setArray(stmt.Name, indices, values)
 			for i, val := range indexedMap {
-				values[i] = val
+				e.setArrayElement(stmt.Name, i, val)
 			}
-			e.arrays[stmt.Name] = values
+			values = e.arrays[stmt.Name]
 			e.arrayTypes[stmt.Name] = "array"
 			// 设置环境变量
 			if len(values) > 0 {
@@ -1240,13 +1760,26 @@ func (e *Executor) executeArrayAssignment(stmt *parser.ArrayAssignmentStatement)
 		return nil
 	}
 
-	// 普通数组赋值 arr=(1 2 3)
-	values := make([]string, 0, len(stmt.Values))
+	// 普通数组赋值 arr=(1 2 3)，arr+=(4 5) 把元素追加到数组末尾（最大下标之后）
+	var indices []int
+	var values []string
+	if stmt.Append {
+		indices, values = e.appendBase(stmt.Name)
+	}
+	next := 0
+	if len(indices) > 0 {
+		next = indices[len(indices)-1] + 1
+	}
 	for _, expr := range stmt.Values {
-		value := e.evaluateExpression(expr)
+		value, err := e.evaluateExpression(expr)
+		if err != nil {
+			return err
+		}
+		indices = append(indices, next)
 		values = append(values, value)
+		next++
 	}
-	e.arrays[stmt.Name] = values
+	e.setArray(stmt.Name, indices, values)
 	e.arrayTypes[stmt.Name] = "array"
 	// 同时设置环境变量，使用特殊格式存储数组长度
 	e.env[stmt.Name+"_LENGTH"] = fmt.Sprintf("%d", len(values))
@@ -1257,10 +1790,118 @@ func (e *Executor) executeArrayAssignment(stmt *parser.ArrayAssignmentStatement)
 	return nil
 }
 
+// assignAssocArray 给关联数组赋值 m=([键]=值 ...)，所有下标（包括数字）都是键；
+// 不带 + 时先清空原来的元素，m+=(...) 在原来的元素上赋值
+func (e *Executor) assignAssocArray(stmt *parser.ArrayAssignmentStatement) error {
+	if !stmt.Append || e.assocArrays[stmt.Name] == nil {
+		e.assocArrays[stmt.Name] = make(map[string]string)
+	}
+	for indexStr, valueExpr := range stmt.IndexedValues {
+		value, err := e.evaluateExpression(valueExpr)
+		if err != nil {
+			return err
+		}
+		e.assocArrays[stmt.Name][e.expandSubscript(indexStr)] = value
+	}
+	return nil
+}
+
+// declaresVariables 报告内置命令 name 是否声明变量（参数中可以有 名称=(...) 形式的数组初始值）
+func declaresVariables(name string) bool {
+	return name == "declare" || name == "local" || name == "readonly"
+}
+
+// compoundAssignment 把 declare 等命令的 名称=(...) 参数解析为数组赋值，不是这种形式时返回 nil
+func compoundAssignment(arg parser.Expression) *parser.ArrayAssignmentStatement {
+	ident, ok := arg.(*parser.Identifier)
+	if !ok || !strings.Contains(ident.Value, "=(") || !strings.HasSuffix(ident.Value, ")") {
+		return nil
+	}
+	p := parser.New(lexer.New(ident.Value))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 || len(program.Statements) != 1 {
+		return nil
+	}
+	stmt, _ := program.Statements[0].(*parser.ArrayAssignmentStatement)
+	return stmt
+}
+
+// declareArrays 在 declare、local、readonly 成功后声明数组：-A 的名称成为关联数组，
+// -a 的名称没有值时成为普通数组，然后给 compounds 中的数组赋初始值
+func (e *Executor) declareArrays(args []string, compounds []*parser.ArrayAssignmentStatement) error {
+	indexed, assoc := false, false
+	var names []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			indexed = indexed || strings.Contains(arg, "a")
+			assoc = assoc || strings.Contains(arg, "A")
+			continue
+		}
+		if !strings.HasPrefix(arg, "+") {
+			names = append(names, e.resolveNameref(arg))
+		}
+	}
+	for _, name := range names {
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if assoc && e.arrayTypes[name] != "assoc" {
+			delete(e.env, name)
+			e.unsetArray(name)
+			e.assocArrays[name] = make(map[string]string)
+			e.arrayTypes[name] = "assoc"
+		} else if indexed && !assoc && e.arrayTypes[name] != "array" {
+			indices, values := e.appendBase(name)
+			e.setArray(name, indices, values)
+			e.arrayTypes[name] = "array"
+		}
+	}
+	for _, stmt := range compounds {
+		name := e.resolveNameref(stmt.Name)
+		if e.arrayTypes[name] != "assoc" && !stmt.Append {
+			delete(e.env, name)
+		}
+		// 本次声明为只读的数组先赋初始值
+		readonly := e.readonly[name]
+		delete(e.readonly, name)
+		err := e.executeArrayAssignment(stmt)
+		if readonly {
+			e.readonly[name] = true
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkReadonly 变量 name 是只读变量时返回错误，退出状态为 1
+func (e *Executor) checkReadonly(name string) error {
+	if e.readonly[name] {
+		e.env["?"] = "1"
+		return &builtin.StatusError{Code: 1, Message: i18n.Sprintf("%s: 只读变量", name)}
+	}
+	return nil
+}
+
+// appendBase 返回 arr+=(...) 追加之前数组原来的下标和元素的副本，变量不是数组时它的值作为下标 0 的元素
+func (e *Executor) appendBase(name string) ([]int, []string) {
+	if arr, ok := e.arrays[name]; ok {
+		return append([]int(nil), e.indicesOf(name)...), append([]string(nil), arr...)
+	}
+	if value, ok := e.env[name]; ok {
+		return []int{0}, []string{value}
+	}
+	return nil, nil
+}
+
 // executeCaseStatement 执行case语句
 func (e *Executor) executeCaseStatement(stmt *parser.CaseStatement) error {
 	// 求值case的值
-	value := e.evaluateExpression(stmt.Value)
+	value, err := e.evaluateExpression(stmt.Value)
+	if err != nil {
+		return err
+	}
 
 	// 遍历所有case子句
 	for _, caseClause := range stmt.Cases {
@@ -1291,7 +1932,7 @@ func (e *Executor) executeCaseStatement(stmt *parser.CaseStatement) error {
 	return nil
 }
 
-// matchPattern 简单的模式匹配（支持 * 和 ? 通配符）
+// matchPattern 简单的模式匹配（支持 *、? 通配符和 [...] 方括号表达式）
 func matchPattern(value, pattern string) bool {
 	// 如果模式是 *，匹配所有
 	if pattern == "*" {
@@ -1303,6 +1944,16 @@ func matchPattern(value, pattern string) bool {
 	valueIdx := 0
 
 	for patternIdx < len(pattern) && valueIdx < len(value) {
+		if pattern[patternIdx] == '[' {
+			if matched, width, ok := matchBracket(pattern[patternIdx:], value[valueIdx]); ok {
+				if !matched {
+					return false
+				}
+				patternIdx += width
+				valueIdx++
+				continue
+			}
+		}
 		if pattern[patternIdx] == '*' {
 			// * 匹配任意字符序列
 			if patternIdx == len(pattern)-1 {
@@ -1320,6 +1971,13 @@ func matchPattern(value, pattern string) bool {
 			// ? 匹配单个字符
 			patternIdx++
 			valueIdx++
+		} else if pattern[patternIdx] == '\\' && patternIdx+1 < len(pattern) {
+			// 转义的字符（包括引号中的 * 和 ?）按字面意义匹配
+			if pattern[patternIdx+1] != value[valueIdx] {
+				return false
+			}
+			patternIdx += 2
+			valueIdx++
 		} else if pattern[patternIdx] == value[valueIdx] {
 			patternIdx++
 			valueIdx++
@@ -1328,40 +1986,112 @@ func matchPattern(value, pattern string) bool {
 		}
 	}
 
-	// 如果都匹配完了，返回true
-	return patternIdx == len(pattern) && valueIdx == len(value)
+	// 值匹配完后，模式末尾剩下的 * 可以匹配空串
+	for valueIdx == len(value) && patternIdx < len(pattern) && pattern[patternIdx] == '*' {
+		patternIdx++
+	}
+
+	// 如果都匹配完了，返回true
+	return patternIdx == len(pattern) && valueIdx == len(value)
+}
+
+// matchBracket 用 pattern 开头的方括号表达式匹配字符 c，返回是否匹配和表达式的长度
+// 支持范围（a-z）、取反（[!...] 或 [^...]）、转义的字符和 [:alpha:] 等字符类，开头的 ] 是普通字符；
+// 没有结束的 ] 时 ok 为 false，[ 按字面意义匹配
+func matchBracket(pattern string, c byte) (matched bool, width int, ok bool) {
+	i := 1
+	negate := i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^')
+	if negate {
+		i++
+	}
+	for start := i; i < len(pattern); i++ {
+		ch := pattern[i]
+		if ch == ']' && i > start {
+			return matched != negate, i + 1, true
+		}
+		if ch == '[' && strings.HasPrefix(pattern[i+1:], ":") {
+			if end := strings.Index(pattern[i+2:], ":]"); end >= 0 {
+				matched = matched || matchCharClass(pattern[i+2:i+2+end], c)
+				i += end + 3
+				continue
+			}
+		}
+		if ch == '\\' && i+1 < len(pattern) {
+			i++
+			ch = pattern[i]
+		}
+		hi := ch
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			i += 2
+			if pattern[i] == '\\' && i+1 < len(pattern) {
+				i++
+			}
+			hi = pattern[i]
+		}
+		matched = matched || ch <= c && c <= hi
+	}
+	return false, 0, false
+}
+
+// matchCharClass 报告字符 c 是否属于方括号表达式中的字符类 [:name:]
+func matchCharClass(name string, c byte) bool {
+	r := rune(c)
+	switch name {
+	case "alpha":
+		return unicode.IsLetter(r)
+	case "digit":
+		return unicode.IsDigit(r)
+	case "alnum":
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	case "upper":
+		return unicode.IsUpper(r)
+	case "lower":
+		return unicode.IsLower(r)
+	case "space":
+		return unicode.IsSpace(r)
+	case "blank":
+		return c == ' ' || c == '\t'
+	case "punct":
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	case "xdigit":
+		return strings.IndexByte("0123456789abcdefABCDEF", c) >= 0
+	case "cntrl":
+		return unicode.IsControl(r)
+	case "print":
+		return unicode.IsPrint(r)
+	case "graph":
+		return unicode.IsGraphic(r) && c != ' '
+	}
+	return false
 }
 
 // getArrayElement 获取数组元素
 // 支持 ${arr[0]} 和 $arr[0] 格式（普通数组）
 // 支持 ${arr[key]} 和 $arr[key] 格式（关联数组）
-func (e *Executor) getArrayElement(varExpr string) string {
+// 设置了 -u 选项时，数组或元素不存在返回未定义变量的错误
+func (e *Executor) getArrayElement(varExpr string) (string, error) {
 	// 解析数组名和索引
 	// 格式：arr[0] 或 arr[key]
 	idx := strings.Index(varExpr, "[")
 	if idx == -1 {
-		return ""
+		return "", nil
 	}
-	arrName := varExpr[:idx]
+	arrName := e.resolveNameref(varExpr[:idx])
 	idxEnd := strings.Index(varExpr, "]")
 	if idxEnd == -1 {
-		return ""
+		return "", nil
 	}
 	indexStr := varExpr[idx+1 : idxEnd]
 
 	// 检查是否是关联数组
 	if arrayType, ok := e.arrayTypes[arrName]; ok && arrayType == "assoc" {
-		// 关联数组：使用字符串键
-		assocArr, ok := e.assocArrays[arrName]
-		if !ok {
-			if e.options["u"] {
-				return "__UNDEFINED_VAR__" + arrName
-			}
-			return ""
+		// 关联数组：使用字符串键，展开键中的变量
+		key := e.expandSubscript(indexStr)
+		value, ok := e.assocArrays[arrName][key]
+		if !ok && e.options["u"] {
+			return "", unboundVariableError(arrName + "[" + key + "]")
 		}
-		// 展开键中的变量
-		key := e.expandVariablesInString(indexStr)
-		return assocArr[key]
+		return value, nil
 	}
 
 	// 普通数组：尝试解析为数字索引
@@ -1369,34 +2099,33 @@ func (e *Executor) getArrayElement(varExpr string) string {
 	if err != nil {
 		// 如果不是数字，可能是关联数组但未声明类型，尝试作为字符串键
 		if assocArr, ok := e.assocArrays[arrName]; ok {
-			key := e.expandVariablesInString(indexStr)
-			return assocArr[key]
+			key := e.expandSubscript(indexStr)
+			return assocArr[key], nil
 		}
-		return ""
+		return "", nil
 	}
 
-	// 获取普通数组
-	arr, ok := e.arrays[arrName]
+	// 获取普通数组的元素，负数下标从最大下标之后倒数
+	if index < 0 {
+		index += e.arrayEnd(arrName)
+	}
+	value, ok := e.arrayElement(arrName, index)
 	if !ok {
-		// 如果设置了 -u 选项，未定义的数组应该报错
+		// 如果设置了 -u 选项，未定义的数组元素应该报错
 		if e.options["u"] {
-			return "__UNDEFINED_VAR__" + arrName
+			return "", unboundVariableError(fmt.Sprintf("%s[%d]", arrName, index))
 		}
-		return ""
-	}
-
-	// 检查索引范围
-	if index < 0 || index >= len(arr) {
-		return ""
+		return "", nil
 	}
 
-	return arr[index]
+	return value, nil
 }
 
 // expandArray 展开数组
 // 如果 quoted 为 true，返回每个元素作为单独的词（用空格分隔）
 // 如果 quoted 为 false，返回所有元素作为一个词（用 IFS 的第一个字符分隔）
 func (e *Executor) expandArray(arrName string, quoted bool) string {
+	arrName = e.resolveNameref(arrName)
 	// 检查是否是关联数组
 	if arrayType, ok := e.arrayTypes[arrName]; ok && arrayType == "assoc" {
 		assocArr, ok := e.assocArrays[arrName]
@@ -1458,28 +2187,36 @@ func (e *Executor) executeAssocArrayAssignment(assignment string, args []parser.
 	// 解析 arr[key]=value 格式
 	eqIdx := strings.Index(assignment, "=")
 	if eqIdx == -1 {
-		return fmt.Errorf("无效的赋值语句: %s", assignment)
+		return i18n.Errorf("无效的赋值语句: %s", assignment)
 	}
 
 	leftSide := assignment[:eqIdx]
 	rightSide := assignment[eqIdx+1:]
+	// arr[key]+=value 把值追加到元素原来的值之后
+	leftSide, appended := strings.CutSuffix(leftSide, "+")
 
 	// 解析 arr[key]
 	idx := strings.Index(leftSide, "[")
 	if idx == -1 {
-		return fmt.Errorf("无效的数组赋值: %s", assignment)
+		return i18n.Errorf("无效的数组赋值: %s", assignment)
+	}
+	arrName := e.resolveNameref(leftSide[:idx])
+	if err := e.checkReadonly(arrName); err != nil {
+		return err
 	}
-	arrName := leftSide[:idx]
 	idxEnd := strings.Index(leftSide, "]")
 	if idxEnd == -1 {
-		return fmt.Errorf("无效的数组赋值: %s", assignment)
+		return i18n.Errorf("无效的数组赋值: %s", assignment)
 	}
 	keyStr := leftSide[idx+1 : idxEnd]
 
 	// 获取值（如果有参数，使用第一个参数；否则使用rightSide）
 	value := rightSide
 	if len(args) > 0 {
-		value = e.evaluateExpression(args[0])
+		var err error
+		if value, err = e.evaluateExpression(args[0]); err != nil {
+			return err
+		}
 	}
 
 	// 检查是否是关联数组
@@ -1489,672 +2226,824 @@ func (e *Executor) executeAssocArrayAssignment(assignment string, args []parser.
 			e.assocArrays[arrName] = make(map[string]string)
 		}
 		// 展开键中的变量
-		key := e.expandVariablesInString(keyStr)
+		key := e.expandSubscript(keyStr)
+		if appended {
+			value = e.assocArrays[arrName][key] + value
+		}
 		e.assocArrays[arrName][key] = value
 		return nil
 	}
 
-	// 如果不是关联数组，尝试作为普通数组处理（数字索引）
-	index, err := strconv.Atoi(keyStr)
-	if err == nil {
-		// 数字索引，作为普通数组处理
-		if e.arrays[arrName] == nil {
-			e.arrays[arrName] = make([]string, 0)
-		}
-		// 扩展数组以容纳索引
-		if index >= len(e.arrays[arrName]) {
-			newArr := make([]string, index+1)
-			copy(newArr, e.arrays[arrName])
-			e.arrays[arrName] = newArr
-		}
-		e.arrays[arrName][index] = value
-		e.arrayTypes[arrName] = "array"
-		return nil
+	// 普通数组，下标按算术表达式求值（arr[i+1]、arr[$i]、arr[-1]）
+	index, err := e.arrayIndex(arrName, keyStr)
+	if err != nil {
+		return err
 	}
-
-	// 既不是关联数组也不是数字索引，创建关联数组
-	if e.assocArrays[arrName] == nil {
-		e.assocArrays[arrName] = make(map[string]string)
+	if appended {
+		old, _ := e.arrayElement(arrName, index)
+		value = old + value
 	}
-	e.arrayTypes[arrName] = "assoc"
-	key := e.expandVariablesInString(keyStr)
-	e.assocArrays[arrName][key] = value
+	e.setArrayElement(arrName, index, value)
+	e.arrayTypes[arrName] = "array"
 	return nil
 }
 
-// evaluateExpression 求值表达式
-func (e *Executor) evaluateExpression(expr parser.Expression) string {
+// arrayIndex 求值普通数组 arrName 的下标 subscript（算术表达式），负数从数组末尾倒数
+func (e *Executor) arrayIndex(arrName, subscript string) (int, error) {
+	n, err := e.arithmetic(subscript)
+	if err != nil {
+		return 0, err
+	}
+	index := int(n)
+	if index < 0 {
+		index += e.arrayEnd(arrName)
+	}
+	if index < 0 {
+		return 0, i18n.Errorf("%s[%s]: 数组下标错误", arrName, subscript)
+	}
+	return index, nil
+}
+
+// indicesOf 返回普通数组 name 每个元素的下标（递增）
+func (e *Executor) indicesOf(name string) []int {
+	if indices, ok := e.arrayIndices[name]; ok {
+		return indices
+	}
+	indices := make([]int, len(e.arrays[name]))
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+// setArray 把普通数组 name 设置为下标 indices（递增）上的元素 values，下标从 0 连续编号时不记录下标
+func (e *Executor) setArray(name string, indices []int, values []string) {
+	e.arrays[name] = values
+	delete(e.arrayIndices, name)
+	for i, index := range indices {
+		if index != i {
+			e.arrayIndices[name] = indices
+			break
+		}
+	}
+}
+
+// unsetArray 删除普通数组 name
+func (e *Executor) unsetArray(name string) {
+	delete(e.arrays, name)
+	delete(e.arrayIndices, name)
+}
+
+// unsetVariable 删除变量、整个数组或数组元素 name（unset 使用），返回变量是否存在；
+// 名称引用删除它引用的变量，nameref 为 true（unset -n）时删除名称引用本身；只读变量不能删除
+func (e *Executor) unsetVariable(name string, nameref bool) (bool, error) {
+	if nameref {
+		_, found := e.namerefs[name]
+		delete(e.namerefs, name)
+		return found, nil
+	}
+	name = e.resolveNameref(name)
+	base, subscript, hasSubscript := strings.Cut(name, "[")
+	if e.readonly[base] {
+		return true, i18n.Errorf("%s: 不能取消设置: 只读变量", base)
+	}
+	_, found := e.env[base]
+	found = found || e.arrayTypes[base] != ""
+	subscript = strings.TrimSuffix(subscript, "]")
+	if hasSubscript && subscript != "@" && subscript != "*" {
+		// unset 'arr[i]' 只删除一个元素，其他元素的下标不变
+		if e.arrayTypes[base] == "assoc" {
+			delete(e.assocArrays[base], e.expandSubscript(subscript))
+			return found, nil
+		}
+		index, err := e.arrayIndex(base, subscript)
+		if err != nil {
+			return found, err
+		}
+		e.unsetArrayElement(base, index)
+		return found, nil
+	}
+	delete(e.env, base)
+	delete(e.env, base+"_LENGTH")
+	e.takeArray(base)
+	delete(e.integers, base)
+	e.exports.Unexport(base)
+	return found, nil
+}
+
+// unsetFunction 删除函数 name（unset -f 使用）
+func (e *Executor) unsetFunction(name string) {
+	delete(e.functions, name)
+}
+
+// unsetArrayElement 删除普通数组 name 下标为 index 的元素，其他元素的下标不变
+func (e *Executor) unsetArrayElement(name string, index int) {
+	indices, values := e.indicesOf(name), e.arrays[name]
+	i := sort.SearchInts(indices, index)
+	if i == len(indices) || indices[i] != index {
+		return
+	}
+	e.setArray(name, append(indices[:i:i], indices[i+1:]...), append(values[:i:i], values[i+1:]...))
+	if index == 0 {
+		// 下标 0 的元素也是 $name 的值
+		delete(e.env, name)
+	}
+}
+
+// arrayElement 返回普通数组 name 下标为 index 的元素，元素不存在时 ok 为 false
+func (e *Executor) arrayElement(name string, index int) (value string, ok bool) {
+	indices := e.indicesOf(name)
+	i := sort.SearchInts(indices, index)
+	if i < len(indices) && indices[i] == index {
+		return e.arrays[name][i], true
+	}
+	return "", false
+}
+
+// setArrayElement 设置普通数组 name 下标为 index 的元素，中间的下标不会产生空元素
+func (e *Executor) setArrayElement(name string, index int, value string) {
+	indices, values := e.indicesOf(name), e.arrays[name]
+	i := sort.SearchInts(indices, index)
+	if i < len(indices) && indices[i] == index {
+		values[i] = value
+		return
+	}
+	indices = append(indices[:i:i], append([]int{index}, indices[i:]...)...)
+	values = append(values[:i:i], append([]string{value}, values[i:]...)...)
+	e.setArray(name, indices, values)
+}
+
+// arrayEnd 返回普通数组 name 的最大下标加一：arr+=(...) 从这里追加元素，负数下标从这里倒数
+func (e *Executor) arrayEnd(name string) int {
+	indices := e.indicesOf(name)
+	if len(indices) == 0 {
+		return 0
+	}
+	return indices[len(indices)-1] + 1
+}
+
+// expandExpression 求值表达式（由 evaluateExpression 调用，见 trace.go）
+// 设置了 -u 选项时，展开未定义的变量返回错误
+func (e *Executor) expandExpression(expr parser.Expression) (string, error) {
 	switch ex := expr.(type) {
 	case *parser.Identifier:
-		return ex.Value
+		// 未加引号的 ~、~/path、~+、~- 进行波浪号展开
+		if strings.HasPrefix(ex.Value, "~") {
+			return e.tildeExpand(ex.Value), nil
+		}
+		return ex.Value, nil
 	case *parser.StringLiteral:
 		// 只有双引号字符串才展开变量，单引号字符串不展开
 		if ex.IsQuote {
-			result := e.expandVariablesInString(ex.Value)
-			// 检查未定义的变量（set -u）
-			if strings.Contains(result, "__UNDEFINED_VAR__") {
-				// 提取第一个未定义的变量名
-				start := strings.Index(result, "__UNDEFINED_VAR__")
-				if start != -1 {
-					rest := result[start+len("__UNDEFINED_VAR__"):]
-					varName := ""
-					for _, ch := range rest {
-						if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' {
-							varName += string(ch)
-						} else {
-							break
-						}
-					}
-					return "__UNDEFINED_VAR__" + varName
-				}
+			return e.expandString(ex.Value)
+		}
+		return ex.Value, nil
+	case *parser.ConcatExpression:
+		// 由多个相邻片段组成的单词，依次求值后拼接
+		var result strings.Builder
+		for _, part := range ex.Parts {
+			value, err := e.evaluateExpression(part)
+			if err != nil {
+				return "", err
 			}
-			return result
+			result.WriteString(value)
 		}
-		return ex.Value
+		return result.String(), nil
 	case *parser.ParamExpandExpression:
 		// 参数展开表达式 ${VAR...}
 		result, err := e.expandParamExpression(ex)
 		if err != nil {
-			// 如果展开失败，返回错误信息（简化处理）
-			return ""
-		}
-		return result
-	case *parser.Variable:
-		// 检查是否是数组访问 ${arr[0]} 或 $arr[0]
-		if strings.Contains(ex.Name, "[") && strings.Contains(ex.Name, "]") {
-			return e.getArrayElement(ex.Name)
-		}
-		// 检查是否是数组变量（返回所有元素，空格分隔）
-		if arr, ok := e.arrays[ex.Name]; ok {
-			return strings.Join(arr, " ")
-		}
-		// 检查是否是特殊变量 $#, $@, $*, $?, $!, $$, $0
-		if ex.Name == "#" {
-			if value, ok := e.env["#"]; ok {
-				return value
-			}
-			return "0"
-		}
-		if ex.Name == "@" {
-			if value, ok := e.env["@"]; ok {
-				return value
-			}
-			return ""
-		}
-		if ex.Name == "*" {
-			if value, ok := e.env["@"]; ok {
-				return value
-			}
-			return ""
-		}
-		if ex.Name == "?" {
-			if value, ok := e.env["?"]; ok {
-				return value
-			}
-			return "0"
-		}
-		if ex.Name == "!" {
-			if value, ok := e.env["!"]; ok {
-				return value
-			}
-			return "0"
-		}
-		if ex.Name == "$" {
-			// $$ 当前进程的PID
-			return fmt.Sprintf("%d", os.Getpid())
-		}
-		if ex.Name == "0" {
-			if value, ok := e.env["0"]; ok {
-				return value
-			}
-			return os.Args[0]
-		}
-		// 检查是否是位置参数 $1, $2, ...
-		if len(ex.Name) > 0 {
-			isAllDigits := true
-			for _, ch := range ex.Name {
-				if ch < '0' || ch > '9' {
-					isAllDigits = false
-					break
-				}
-			}
-			if isAllDigits {
-				if value, ok := e.env[ex.Name]; ok {
-					return value
-				}
-				// 如果设置了 -u 选项，未定义的位置参数应该报错
-				if e.options["u"] {
-					return "__UNDEFINED_VAR__" + ex.Name
-				}
-				return ""
+			if isUnboundVariable(err) {
+				return "", err
 			}
+			// 其他展开错误返回空字符串（简化处理）
+			return "", nil
 		}
-		if value, ok := e.env[ex.Name]; ok {
-			return value
-		}
-		// 如果设置了 -u 选项，未定义的变量应该报错
-		if e.options["u"] {
-			// 返回特殊标记，让调用者知道变量未定义
-			return "__UNDEFINED_VAR__" + ex.Name
-		}
-		return ""
+		return result, nil
+	case *parser.Variable:
+		return e.expandVariable(ex.Name)
 	case *parser.CommandSubstitution:
 		// 执行命令替换
-		return e.executeCommandSubstitution(ex.Command)
+		return e.executeCommandSubstitution(ex.Command), nil
 	case *parser.ArithmeticExpansion:
 		// 执行算术展开
 		return e.evaluateArithmetic(ex.Expression)
 	case *parser.ProcessSubstitution:
 		// 执行进程替换
-		return e.executeProcessSubstitution(ex.Command, ex.IsInput)
+		return e.executeProcessSubstitution(ex.Command, ex.IsInput), nil
 	default:
-		return ""
+		return "", nil
 	}
 }
 
-// expandVariablesInString 展开字符串中的变量（如 "TEST=$TEST"）
-// 优先处理转义序列（如 \$），然后处理变量展开
+// expandVariablesInString 展开字符串中的变量（如 "TEST=$TEST"），忽略 set -u 的未定义变量错误
+// 用于提示符、PS4、here-document 和数组下标等不报告未定义变量的地方
 func (e *Executor) expandVariablesInString(s string) string {
-	// 如果字符串为空，直接返回
-	if len(s) == 0 {
-		return ""
+	result, _ := e.expandString(s)
+	return result
+}
+
+// expandSubscript 展开关联数组的下标（如 m[$k]、m["a b"]、m[a\ b]），与未加引号的单词一样去掉引号，但不进行单词分割
+func (e *Executor) expandSubscript(s string) string {
+	result, _ := e.expandText(s, quoteNone)
+	return result
+}
+
+// expandString 展开双引号字符串中的变量、参数展开、命令替换和算术展开（见 expand.go）
+// 反斜杠只转义 $、`、"、\ 和换行；设置了 -u 选项时，遇到未定义的变量返回错误
+func (e *Executor) expandString(s string) (string, error) {
+	return e.expandText(s, quoteDouble)
+}
+
+// isDigit 判断是否为数字
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+// getEnvArray 获取传给外部命令的环境变量数组
+// 包括导出的变量（见 builtin.Exports），以及 export -f 导出的函数
+func (e *Executor) getEnvArray() []string {
+	env := make([]string, 0, len(e.env))
+	for k, v := range e.env {
+		if e.exports.IsExported(k) {
+			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	for _, name := range e.exports.Functions() {
+		if source, ok := e.functionSource(name); ok {
+			env = append(env, fmt.Sprintf("%s=() %s", builtin.FunctionEnvName(name), source))
+		}
 	}
+	return env
+}
 
-	var result strings.Builder
-	i := 0
-	for i < len(s) {
-		// 处理转义序列
-		if s[i] == '\\' && i+1 < len(s) {
-			escaped := s[i+1]
-
-			if escaped == '$' {
-				// \$ 转义：直接输出字面量 $，不输出 \
-				result.WriteByte('$')
-				i += 2 // 跳过 \ 和 $
-
-				// 如果后面是 $((...)) 格式，需要完整保留作为字面量
-				if i+1 < len(s) && s[i] == '(' && s[i+1] == '(' {
-					result.WriteString("((")
-					i += 2
-					// 找到匹配的 ))
-					parenDepth := 0
-					for i < len(s) {
-						if s[i] == '(' {
-							parenDepth++
-							result.WriteByte(s[i])
-							i++
-						} else if s[i] == ')' {
-							result.WriteByte(s[i])
-							i++
-							if parenDepth == 0 && i < len(s) && s[i] == ')' {
-								result.WriteByte(s[i])
-								i++
-								break
-							} else if parenDepth > 0 {
-								parenDepth--
-							}
-						} else {
-							result.WriteByte(s[i])
-							i++
-						}
-					}
-				}
-				continue
-			} else if escaped == '"' {
-				// \" 转义：只输出 "，不输出 \
-				result.WriteByte('"')
-				i += 2 // 跳过 \ 和 "
-				continue
-			} else {
-				i += 2 // 跳过 \ 和转义字符
-				switch escaped {
-				case '\\':
-					// \\ 转义：输出单个 \
-					result.WriteByte('\\')
-				default:
-					// 其他转义序列（\n, \t等）保持字面量（两个字符）
-					result.WriteByte('\\')
-					result.WriteByte(escaped)
-				}
-			}
-		} else if s[i] == '$' && i+1 < len(s) {
-			// 检查是否是算术展开 $((...))
-			if i+2 < len(s) && s[i+1] == '(' && s[i+2] == '(' {
-				// 找到匹配的 ))
-				i += 3 // 跳过 $((
-				startPos := i
-				parenDepth := 0 // 单括号深度
-				for i < len(s) {
-					if s[i] == '(' {
-						parenDepth++
-						i++
-					} else if s[i] == ')' {
-						if parenDepth > 0 {
-							// 这个 ) 匹配一个普通的 (
-							parenDepth--
-							i++
-						} else {
-							// parenDepth == 0，这可能是算术展开的第一个 )
-							if i+1 < len(s) && s[i+1] == ')' {
-								// 找到 ))，这是算术展开的结束
-								// 提取算术表达式
-								expr := s[startPos:i]
-								// 计算算术表达式
-								result.WriteString(e.evaluateArithmetic(expr))
-								i += 2 // 跳过 ))
-								break
-							} else {
-								// 只有一个 )，这是一个错误（括号不匹配）
-								// 保留原样
-								result.WriteString("$((")
-								result.WriteString(s[startPos : i+1])
-								i++
-								break
-							}
-						}
-					} else {
-						i++
-					}
-				}
-				continue
-			}
+// functionSource 返回函数 name 的函数体源代码，函数不存在时返回 false
+func (e *Executor) functionSource(name string) (string, bool) {
+	fn, ok := e.functions[name]
+	if !ok {
+		return "", false
+	}
+	return fn.Source, true
+}
 
-			// 检查是否是命令替换 $(...)
-			if i+1 < len(s) && s[i+1] == '(' {
-				// $(...) 格式
-				i += 2 // 跳过 $(
-				startPos := i
-				parenDepth := 0
-				for i < len(s) {
-					if s[i] == '(' {
-						parenDepth++
-						i++
-					} else if s[i] == ')' {
-						if parenDepth > 0 {
-							// 这个 ) 匹配一个普通的 (
-							parenDepth--
-							i++
-						} else {
-							// parenDepth == 0，这是命令替换的结束 )
-							// 提取命令
-							command := s[startPos:i]
-							// 执行命令替换
-							result.WriteString(e.executeCommandSubstitution(command))
-							i++ // 跳过 )
-							break
-						}
-					} else {
-						i++
-					}
-				}
-				continue
-			}
+// importFunction 定义从环境中继承的导出函数，value 的格式为 "() 函数体"
+// 无法解析的定义被忽略
+func (e *Executor) importFunction(name, value string) {
+	if !strings.HasPrefix(value, "()") {
+		return
+	}
+	p := parser.New(lexer.New(name + " " + value))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 || len(program.Statements) != 1 {
+		return
+	}
+	fn, ok := program.Statements[0].(*parser.FunctionStatement)
+	if !ok || fn.Name != name {
+		return
+	}
+	e.functions[name] = fn
+	e.exports.ExportFunction(name)
+}
 
-			// 正常展开变量
-			// 处理变量展开
-			var varName strings.Builder
+// Dir 返回执行器的工作目录
+// 工作目录保存在变量 PWD 中（cd 只修改 PWD），不使用进程的工作目录，同一进程中的多个执行器互不影响
+func (e *Executor) Dir() string {
+	if dir := e.env["PWD"]; dir != "" {
+		return dir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
 
-			// 处理特殊变量 $#, $@, $*, $?, $!, $$, $0, $1, $2, ...
-			if i+1 < len(s) && s[i+1] == '#' {
-				// $# 参数个数
-				i += 2
-				if value, ok := e.env["#"]; ok {
-					result.WriteString(value)
-				} else {
-					result.WriteString("0")
-				}
-				continue
-			} else if i+1 < len(s) && s[i+1] == '@' {
-				// $@ 所有参数
-				i += 2
-				if value, ok := e.env["@"]; ok {
-					result.WriteString(value)
-				}
-				continue
-			} else if i+1 < len(s) && s[i+1] == '*' {
-				// $* 所有参数（与$@类似）
-				i += 2
-				if value, ok := e.env["@"]; ok {
-					result.WriteString(value)
-				}
-				continue
-			} else if i+1 < len(s) && s[i+1] == '?' {
-				// $? 上一个命令的退出码
-				i += 2
-				if value, ok := e.env["?"]; ok {
-					result.WriteString(value)
-				} else {
-					result.WriteString("0")
-				}
-				continue
-			} else if i+1 < len(s) && s[i+1] == '!' {
-				// $! 最后一个后台进程的PID
-				i += 2
-				if value, ok := e.env["!"]; ok {
-					result.WriteString(value)
-				} else {
-					result.WriteString("0")
-				}
-				continue
-			} else if i+1 < len(s) && s[i+1] == '$' {
-				// $$ 当前进程的PID
-				i += 2
-				result.WriteString(fmt.Sprintf("%d", os.Getpid()))
-				continue
-			} else if i+1 < len(s) && s[i+1] == '0' {
-				// $0 脚本名
-				i += 2
-				if value, ok := e.env["0"]; ok {
-					result.WriteString(value)
-				} else {
-					result.WriteString(os.Args[0])
-				}
-				continue
-			} else if i+1 < len(s) && isDigit(s[i+1]) {
-				// $1, $2, ... 位置参数
-				i++
-				for i < len(s) && isDigit(s[i]) {
-					varName.WriteByte(s[i])
-					i++
-				}
-				varNameStr := varName.String()
-				if value, ok := e.env[varNameStr]; ok {
-					result.WriteString(value)
-				} else if e.options["u"] {
-					result.WriteString("__UNDEFINED_VAR__" + varNameStr)
-				}
-				continue
-			}
+// resolvePath 把相对路径解析为相对于执行器工作目录的路径
+func (e *Executor) resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(e.Dir(), path)
+}
 
-			if i+1 < len(s) && s[i+1] == '{' {
-				// ${VAR} 或 ${arr[0]} 格式
-				i += 2
-				for i < len(s) && s[i] != '}' {
-					varName.WriteByte(s[i])
-					i++
-				}
-				if i < len(s) && s[i] == '}' {
-					i++
-					varNameStr := varName.String()
-					// 检查是否是数组访问
-					if strings.Contains(varNameStr, "[") {
-						value := e.getArrayElement(varNameStr)
-						if value != "" {
-							result.WriteString(value)
-						} else if e.options["u"] && !strings.Contains(value, "__UNDEFINED_VAR__") {
-							result.WriteString("__UNDEFINED_VAR__" + varNameStr)
-						}
-					} else {
-						// 检查是否是数组变量（返回所有元素）
-						if arr, ok := e.arrays[varNameStr]; ok {
-							result.WriteString(strings.Join(arr, " "))
-						} else if value, ok := e.env[varNameStr]; ok {
-							result.WriteString(value)
-						} else if e.options["u"] {
-							// 如果设置了 -u 选项，未定义的变量返回特殊标记
-							result.WriteString("__UNDEFINED_VAR__" + varNameStr)
-						}
-					}
-					continue
-				}
-			} else if i+1 < len(s) && (isLetter(s[i+1]) || s[i+1] == '_') {
-				// $VAR 格式，可能包含数组访问 $arr[0]
-				i++
-				// 关键修复：只在变量名后跟 [ 时才包含 [ 和 ]
-				// 否则，只提取变量名部分（字母、数字、下划线）
-				for i < len(s) && (isLetter(s[i]) || isDigit(s[i]) || s[i] == '_') {
-					varName.WriteByte(s[i])
-					i++
-				}
-				// 检查是否是数组访问
-				if i < len(s) && s[i] == '[' {
-					// 有数组访问，包含 [ 和 ]
-					for i < len(s) && s[i] != ']' {
-						varName.WriteByte(s[i])
-						i++
-					}
-					if i < len(s) && s[i] == ']' {
-						varName.WriteByte(s[i])
-						i++
-					}
-				}
-				varNameStr := varName.String()
-				// 检查是否是数组访问
-				if strings.Contains(varNameStr, "[") {
-					value := e.getArrayElement(varNameStr)
-					if value != "" {
-						result.WriteString(value)
-					} else if e.options["u"] && !strings.Contains(value, "__UNDEFINED_VAR__") {
-						result.WriteString("__UNDEFINED_VAR__" + varNameStr)
-					}
-				} else {
-					// 检查是否是数组变量（返回所有元素）
-					if arr, ok := e.arrays[varNameStr]; ok {
-						result.WriteString(strings.Join(arr, " "))
-					} else if value, ok := e.env[varNameStr]; ok {
-						result.WriteString(value)
-					} else if e.options["u"] {
-						// 如果设置了 -u 选项，未定义的变量返回特殊标记
-						result.WriteString("__UNDEFINED_VAR__" + varNameStr)
-					}
-				}
-				continue
-			}
-			// 不是变量，保留原字符
-			result.WriteByte(s[i])
-			i++
-		} else {
-			result.WriteByte(s[i])
-			i++
+// SetEnv 设置环境变量
+// 只修改执行器自己的变量表，不修改进程的环境变量；导出的变量（见 Export）通过 getEnvArray 传给外部命令
+func (e *Executor) SetEnv(key, value string) {
+	if target, ok := e.namerefs[key]; ok && target == "" {
+		// 还没有引用目标的名称引用（declare -n ref）：赋值设置它引用的变量名
+		e.namerefs[key] = value
+		return
+	}
+	key = e.resolveNameref(key)
+	if strings.Contains(key, "[") {
+		// 名称引用指向数组元素（declare -n ref='arr[1]'）
+		e.executeAssocArrayAssignment(key+"="+value, nil)
+		return
+	}
+	e.env[key] = value
+}
+
+// assignVariable 给变量赋值（printf -v 使用），name 可以是数组元素 arr[i]，名称引用赋值给它引用的变量
+func (e *Executor) assignVariable(name, value string) error {
+	if strings.Contains(name, "[") {
+		return e.executeAssocArrayAssignment(name+"="+value, nil)
+	}
+	if err := e.checkReadonly(e.resolveNameref(name)); err != nil {
+		return err
+	}
+	e.SetEnv(name, value)
+	return nil
+}
+
+// resolveNameref 返回名称引用最终引用的变量名，name 不是名称引用时原样返回
+func (e *Executor) resolveNameref(name string) string {
+	return builtin.ResolveNameref(e.namerefs, name)
+}
+
+// indirectValue 返回 ${!name} 间接引用的值，name 可以是变量名、数组元素 arr[i] 或整个数组 arr[@]、arr[*]
+func (e *Executor) indirectValue(name string) string {
+	name = e.resolveNameref(name)
+	if base, index, ok := strings.Cut(name, "["); ok {
+		switch index {
+		case "@]":
+			return e.expandArray(base, true)
+		case "*]":
+			return e.expandArray(base, false)
+		}
+		value, _ := e.getArrayElement(name)
+		return value
+	}
+	e.refreshDynamic(name)
+	return e.env[name]
+}
+
+// expandIndirect 展开 ${!name}：名称引用展开为它引用的变量名，${!arr[@]} 展开为数组的下标，
+// 其他变量展开为以它的值为名称的变量（可以是数组元素或整个数组）的值
+func (e *Executor) expandIndirect(name string) string {
+	if target, ok := e.namerefs[name]; ok {
+		return target
+	}
+	for _, suffix := range []string{"[@]", "[*]"} {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			return e.arrayKeys(base)
 		}
 	}
-	return result.String()
+	return e.indirectValue(e.env[name])
 }
 
-// isLetter 判断是否为字母
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+// arrayKeys 返回 ${!arr[@]} 展开的数组下标（关联数组为键）
+func (e *Executor) arrayKeys(arrName string) string {
+	arrName = e.resolveNameref(arrName)
+	if values, ok := e.assocArrays[arrName]; ok && e.arrayTypes[arrName] == "assoc" {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return strings.Join(keys, " ")
+	}
+	indices := e.indicesOf(arrName)
+	keys := make([]string, len(indices))
+	for i, index := range indices {
+		keys[i] = strconv.Itoa(index)
+	}
+	return strings.Join(keys, " ")
 }
 
-// isDigit 判断是否为数字
-func isDigit(ch byte) bool {
-	return '0' <= ch && ch <= '9'
+// Export 给变量 name 加上导出属性（与 export name 相同），之后执行的外部命令在环境中得到它
+func (e *Executor) Export(name string) {
+	e.exports.Export(name)
 }
 
-// getEnvArray 获取环境变量数组
-func (e *Executor) getEnvArray() []string {
-	env := make([]string, 0, len(e.env))
-	for k, v := range e.env {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
+// GetEnv 获取环境变量
+func (e *Executor) GetEnv(key string) (string, bool) {
+	value, ok := e.env[key]
+	return value, ok
+}
+
+// ExpandPrompt 返回展开后的提示符变量 name（如 PS1、PS2），变量未设置时返回 fallback
+// 与 bash 相同，先替换 \u、\w 等转义序列，再展开变量和命令替换
+func (e *Executor) ExpandPrompt(name, fallback string) string {
+	value, ok := e.env[name]
+	if !ok {
+		return fallback
+	}
+	return e.expandVariablesInString(e.decodePromptEscapes(value))
+}
+
+// GetEnvMap 获取环境变量映射（用于builtin命令）
+func (e *Executor) GetEnvMap() map[string]string {
+	return e.env
+}
+
+// defaultFuncNest 没有设置 FUNCNEST 时函数的最大嵌套层数，避免无限递归耗尽 Go 的栈
+const defaultFuncNest = 2000
+
+// callFrame 函数调用帧：函数返回时恢复调用者的位置参数，以及本次调用中 local 声明的变量
+type callFrame struct {
+	name       string             // 被调用的函数名（FUNCNAME）
+	line       int                // 调用函数的命令所在的行（BASH_LINENO）
+	positional []string           // 调用者的位置参数（$1...$N）
+	locals     map[string]*string // local 声明的变量在声明前的值，nil 表示之前没有定义
+	arrays     map[string]*savedArray // local 声明的变量在声明前的数组，nil 表示之前不是数组
+	namerefs   map[string]string  // 调用前的名称引用
+}
+
+// savedArray 保存的数组状态（local 声明的数组在函数返回时恢复）
+type savedArray struct {
+	kind    string            // 数组类型："array" 或 "assoc"
+	values  []string          // 普通数组的元素
+	indices []int             // 普通数组的下标
+	assoc   map[string]string // 关联数组的元素
+}
+
+// clone 复制调用帧（子shell 使用副本）
+func (f *callFrame) clone() *callFrame {
+	c := &callFrame{name: f.name, line: f.line, positional: f.positional, namerefs: f.namerefs,
+		locals: make(map[string]*string, len(f.locals)), arrays: make(map[string]*savedArray, len(f.arrays))}
+	for k, v := range f.locals {
+		c.locals[k] = v
+	}
+	for k, v := range f.arrays {
+		c.arrays[k] = v
+	}
+	return c
+}
+
+// inFunction 判断当前是否在函数中执行
+func (e *Executor) inFunction() bool {
+	return len(e.frames) > 0
+}
+
+// funcNest 返回函数的最大嵌套层数：FUNCNEST 大于 0 时使用它，否则为 defaultFuncNest
+func (e *Executor) funcNest() int {
+	if n, err := strconv.Atoi(e.env["FUNCNEST"]); err == nil && n > 0 {
+		return n
+	}
+	return defaultFuncNest
+}
+
+// saveLocals 在 local 执行之前记录它声明的变量的当前值，函数返回时恢复
+// 同一次调用中重复声明的变量只记录第一次声明前的值
+func (e *Executor) saveLocals(args []string) {
+	if !e.inFunction() {
+		return
+	}
+	frame := e.frames[len(e.frames)-1]
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if _, ok := frame.locals[name]; ok {
+			continue
+		}
+		if value, ok := e.env[name]; ok {
+			frame.locals[name] = &value
+		} else {
+			frame.locals[name] = nil
+		}
+		// 局部变量不继承外层的数组
+		frame.arrays[name] = e.takeArray(name)
 	}
-	return env
-}
-
-// SetEnv 设置环境变量
-func (e *Executor) SetEnv(key, value string) {
-	e.env[key] = value
-	os.Setenv(key, value)
 }
 
-// GetEnv 获取环境变量
-func (e *Executor) GetEnv(key string) (string, bool) {
-	value, ok := e.env[key]
-	return value, ok
+// takeArray 删除数组 name 并返回它原来的状态，name 不是数组时返回 nil
+func (e *Executor) takeArray(name string) *savedArray {
+	var saved *savedArray
+	switch e.arrayTypes[name] {
+	case "array":
+		saved = &savedArray{kind: "array", values: e.arrays[name], indices: e.indicesOf(name)}
+	case "assoc":
+		saved = &savedArray{kind: "assoc", assoc: e.assocArrays[name]}
+	}
+	e.unsetArray(name)
+	delete(e.assocArrays, name)
+	delete(e.arrayTypes, name)
+	return saved
 }
 
-// GetEnvMap 获取环境变量映射（用于builtin命令）
-func (e *Executor) GetEnvMap() map[string]string {
-	return e.env
+// restoreArray 把数组 name 恢复为 takeArray 保存的状态，saved 为 nil 时删除数组
+func (e *Executor) restoreArray(name string, saved *savedArray) {
+	e.takeArray(name)
+	if saved == nil {
+		return
+	}
+	e.arrayTypes[name] = saved.kind
+	if saved.kind == "assoc" {
+		e.assocArrays[name] = saved.assoc
+	} else {
+		e.setArray(name, saved.indices, saved.values)
+	}
 }
 
 // executeFunction 执行函数
+// 每次调用压入一个调用帧（FUNCNAME 等数组随之更新，见 updateCallStack）：
+// 位置参数设置为函数的参数，函数返回时恢复调用者的位置参数和 local 声明的变量，
+// 函数中对其他变量的修改保留（动态作用域，与 bash 相同）；return 的退出状态作为函数的退出状态。
+// 嵌套层数超过 FUNCNEST 时中止整条命令
 func (e *Executor) executeFunction(fn *parser.FunctionStatement, args []parser.Expression) error {
-	// 保存当前环境变量
-	oldEnv := make(map[string]string)
-	for k, v := range e.env {
-		oldEnv[k] = v
+	// 先求值所有参数，再设置为位置参数
+	values := make([]string, len(args))
+	for i, arg := range args {
+		value, err := e.evaluateExpression(arg)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+
+	if limit := e.funcNest(); len(e.frames) >= limit {
+		e.env["?"] = "1"
+		return &FuncNestError{Name: fn.Name, Limit: limit}
 	}
 
-	// 保存当前局部变量集合
-	oldLocalVars := make(map[string]bool)
-	for k, v := range e.localVars {
-		oldLocalVars[k] = v
+	frame := &callFrame{
+		name:       fn.Name,
+		line:       e.lineno,
+		positional: e.positionalParams(),
+		locals:     make(map[string]*string),
+		arrays:     make(map[string]*savedArray),
+		namerefs:   make(map[string]string, len(e.namerefs)),
 	}
+	for k, v := range e.namerefs {
+		frame.namerefs[k] = v
+	}
+	e.frames = append(e.frames, frame)
+	e.updateCallStack()
 
 	// 设置函数上下文标记（用于 local 命令检查）
 	e.env["__WBASH_IN_FUNCTION__"] = "1"
 
 	// 设置函数参数为位置参数（$1, $2, ...）
-	for i, arg := range args {
-		argValue := e.evaluateExpression(arg)
-		e.env[fmt.Sprintf("%d", i+1)] = argValue
-	}
-	e.env["#"] = fmt.Sprintf("%d", len(args)) // $# 参数个数
-	e.env["@"] = strings.Join(func() []string {
-		result := make([]string, len(args))
-		for i, arg := range args {
-			result[i] = e.evaluateExpression(arg)
-		}
-		return result
-	}(), " ") // $@ 所有参数
+	e.setPositional(values)
+
+	// 如果设置了 -x 选项，显示函数调用
+	e.xtrace(append([]string{fn.Name}, values...))
 
 	// 执行函数体
 	err := e.executeBlock(fn.Body)
+	if retErr, ok := err.(*builtin.ReturnError); ok {
+		err = nil
+		if retErr.Code != 0 {
+			err = &builtin.StatusError{Code: retErr.Code}
+		}
+	}
 
-	// 恢复环境变量（但保留新设置的环境变量，除非是局部变量）
-	for k, v := range oldEnv {
-		if e.localVars[k] {
-			// 局部变量在函数返回时被删除
-			delete(e.env, k)
-			delete(e.localVars, k)
-		} else if _, exists := e.env[k]; !exists {
-			// 如果变量不存在了，删除它
+	// 恢复 local 声明的变量和名称引用
+	for k, old := range frame.locals {
+		e.restoreArray(k, frame.arrays[k])
+		if old != nil {
+			e.env[k] = *old
+		} else {
 			delete(e.env, k)
+		}
+		if target, ok := frame.namerefs[k]; ok {
+			e.namerefs[k] = target
 		} else {
-			// 恢复旧值（非局部变量）
-			e.env[k] = v
+			delete(e.namerefs, k)
 		}
 	}
 
-	// 恢复局部变量集合
-	e.localVars = oldLocalVars
+	// 恢复调用者的位置参数
+	e.setPositional(frame.positional)
 
-	// 清理函数上下文标记
-	delete(e.env, "__WBASH_IN_FUNCTION__")
+	e.frames = e.frames[:len(e.frames)-1]
+	e.updateCallStack()
+	// 函数返回后 $LINENO 回到调用函数的命令所在的行
+	e.setLineNumber(frame.line)
+	if !e.inFunction() {
+		// 清理函数上下文标记
+		delete(e.env, "__WBASH_IN_FUNCTION__")
+	}
+	return err
+}
 
-	// 清理位置参数
-	for i := 1; i <= len(args); i++ {
-		delete(e.env, fmt.Sprintf("%d", i))
+// positionalParams 返回位置参数 $1...$N（N 是 $#）
+func (e *Executor) positionalParams() []string {
+	n, _ := strconv.Atoi(e.env["#"])
+	values := make([]string, n)
+	for i := range values {
+		values[i] = e.env[strconv.Itoa(i+1)]
 	}
-	delete(e.env, "#")
-	delete(e.env, "@")
+	return values
+}
 
-	return err
+// setPositional 把位置参数设置为 values，去掉原来多余的位置参数
+// 位置参数总是 $1...$N（shift 和函数调用都同时更新 $#），所以只需要删除原来的 $1...$N
+func (e *Executor) setPositional(values []string) {
+	n, _ := strconv.Atoi(e.env["#"])
+	for i := len(values) + 1; i <= n; i++ {
+		delete(e.env, strconv.Itoa(i))
+	}
+	for i, value := range values {
+		e.env[strconv.Itoa(i+1)] = value
+	}
+	e.env["#"] = strconv.Itoa(len(values)) // $# 参数个数
+	e.env["@"] = strings.Join(values, " ")  // $@ 所有参数
 }
 
 // executeCommandSubstitution 执行命令替换
-// 正确处理嵌套的命令替换、转义和退出码
+// 命令在派生的子执行器中运行（见 fork），其中的赋值、cd、函数定义等不影响当前shell；
+// 输出写入缓冲区，末尾的换行符全部去掉；命令替换的退出状态记录在 substExitCode 中，
+// 只有赋值的命令（如 x=$(false)）以它作为自己的退出状态，从而影响 $? 和 set -e
 func (e *Executor) executeCommandSubstitution(command string) string {
-	// 先展开命令字符串中的变量和嵌套的命令替换
-	// 注意：命令替换中的命令本身不应该进行单词分割和路径名展开
-	expandedCommand := e.expandCommandSubstitutionCommand(command)
-
-	// 解析和执行命令
-	l := lexer.New(expandedCommand)
+	// 命令文本原样解析，变量在子执行器中展开，这样 $(x=1; echo $x) 能看到子shell中的赋值
+	l := lexer.New(command)
+	if e.lineno > 0 {
+		// 命令替换中的行号从外层命令所在的行开始
+		l.SetLine(e.lineno)
+	}
 	p := parser.New(l)
 	program := p.ParseProgram()
 
 	if len(p.Errors()) > 0 {
-		// 解析错误，返回空字符串
+		// 解析错误，bash 的退出状态为 2
+		e.substExitCode = 2
 		return ""
 	}
 
-	// 使用 bytes.Buffer 直接捕获输出，而不是管道
-	// 这样可以避免管道的同步问题和 fmt.Println 的缓冲问题
-	var output bytes.Buffer
-
-	// 执行命令（在子shell环境中）
-	// 注意：命令替换在子shell中执行，不应该影响当前shell的状态
-	// 创建新的 Executor 实例来执行命令替换，避免递归调用时的状态干扰
-	subExecutor := New()
-	// 复制当前环境变量到子shell
-	for k, v := range e.env {
-		subExecutor.env[k] = v
-	}
-	// 复制函数定义到子shell
-	for k, v := range e.functions {
-		subExecutor.functions[k] = v
-	}
-	// 复制选项到子shell
-	for k, v := range e.options {
-		subExecutor.options[k] = v
-	}
-	// 关键修复：使用临时文件代替管道来捕获输出
-	// 这样可以避免管道和缓冲的问题
-	// 创建临时文件
-	tmpFile, err := os.CreateTemp("", "gobash_cmd_subst_*")
-	if err != nil {
-		return ""
+	output := &substOutput{limit: e.substLimit}
+	sub := e.fork()
+	sub.xtraceLevel = e.xtraceLevel + 1
+	sub.stdout = output
+	// 与 bash 相同，命令替换不继承 set -e（shopt -s inherit_errexit 时继承）
+	if !e.options["inherit_errexit"] {
+		sub.options["e"] = false
 	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath) // 确保删除临时文件
-	
-	// 关键：设置 subExecutor.stdoutWriter 为临时文件
-	// 这样在 executeBuiltinCommand 中，stdoutWriter 会被正确处理
-	subExecutor.stdoutWriter = tmpFile
-	
-	// 保存当前的标准输出
-	oldStdout := os.Stdout
-	// 设置标准输出到临时文件
-	os.Stdout = tmpFile
-	
-	// 执行命令
-	execErr := subExecutor.Execute(program)
-	
-	// 关键修复：在恢复 os.Stdout 之前，先同步并关闭临时文件
-	// 这样可以确保 fmt.Println 的缓冲被刷新到文件
-	tmpFile.Sync() // 同步文件，确保所有缓冲都被写入
-	tmpFile.Close()
-	// 恢复标准输出
-	os.Stdout = oldStdout
-	
-	// 读取临时文件的内容
-	if execErr == nil {
-		data, err := os.ReadFile(tmpPath)
-		if err == nil {
-			output.Write(data)
+
+	// 与子shell 相同，其中的命令失败后继续执行之后的命令；
+	// exit、set -e 和 set -u 只终止命令替换的子shell，退出码就是命令替换的退出状态
+	if execErr := sub.RunExitTrap(sub.executeBlock(&parser.BlockStatement{Statements: program.Statements})); execErr != nil {
+		if exitErr, ok := execErr.(*ScriptExitError); ok {
+			e.reportScriptExit(exitErr)
+		} else if isFailureStatus(execErr) && !StatusOnly(execErr) {
+			// 最后一个命令的错误（之前的命令的错误已经由 executeBlock 输出）
+			fmt.Fprintf(e.Stdio().Stderr, "%s: %v\n", e.errorPrefix(), execErr)
 		}
+		e.substExitCode = ExitStatus(execErr)
+	} else {
+		e.substExitCode = sub.getExitCode()
+	}
+	if output.exceeded {
+		i18n.Fprintf(e.Stdio().Stderr, "%s: 命令替换: 输出超过 %d 字节的限制，已截断\n", e.errorPrefix(), e.substLimit)
+		e.substExitCode = 1
 	}
+	// 与 bash 一样，同一命令中后面的 $? 已经是命令替换的退出状态
+	e.env["?"] = strconv.Itoa(e.substExitCode)
 
-	// 恢复退出码（命令替换不应该改变当前shell的退出码，除非命令替换本身失败）
-	// 但我们需要保存命令替换的退出码，以便在需要时使用
-	// 这里简化处理，不恢复退出码，因为命令替换的退出码通常不影响当前shell
+	// 与 bash 一样去掉输出末尾的所有换行符，命令失败时仍然返回已经输出的内容
+	return strings.TrimRight(output.String(), "\n")
+}
 
-	// 处理执行错误
-	if execErr != nil {
-		// 执行错误，返回空字符串
-		return ""
-	}
+// errSubstitutionLimit 命令替换的输出超过限制后写入返回的错误
+var errSubstitutionLimit = i18n.NewError("命令替换的输出超过了限制")
+
+// substOutput 命令替换的输出缓冲区，可以同时写入（如 $(cmd1 & cmd2)）
+// 设置了限制时只保留前 limit 个字节，超过后写入返回 errSubstitutionLimit
+type substOutput struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
 
-	// 返回输出（移除末尾的换行符，如果存在）
-	// 注意：output 是 bytes.Buffer，通过管道从 os.Stdout 读取的数据会写入到这里
-	result := output.String()
-	
-	// 移除末尾的换行符（如果存在）
-	// 注意：命令替换的输出通常不包含末尾的换行符，但为了兼容性，我们移除它
-	if len(result) > 0 && result[len(result)-1] == '\n' {
-		result = result[:len(result)-1]
+func (o *substOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.limit > 0 && o.buf.Len()+len(p) > o.limit {
+		n, _ := o.buf.Write(p[:o.limit-o.buf.Len()])
+		o.exceeded = true
+		return n, errSubstitutionLimit
 	}
+	return o.buf.Write(p)
+}
 
-	return result
+// String 返回写入的输出
+func (o *substOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
 }
 
-// expandCommandSubstitutionCommand 展开命令替换中的命令字符串
-// 处理变量展开和嵌套的命令替换，但不进行单词分割和路径名展开
-func (e *Executor) expandCommandSubstitutionCommand(command string) string {
-	// 展开变量和嵌套的命令替换
-	result := e.expandVariablesInString(command)
-	return result
+// fork 派生子shell执行器（用于命令替换）
+// 子执行器拥有变量、数组、函数、选项和内置命令表的副本，修改不会影响当前执行器；
+// 标准输入输出、跟踪设置、context 和命令超时沿用当前执行器的设置
+func (e *Executor) fork() *Executor {
+	sub := &Executor{
+		env:            make(map[string]string, len(e.env)),
+		arrays:         make(map[string][]string, len(e.arrays)),
+		arrayIndices:   make(map[string][]int, len(e.arrayIndices)),
+		assocArrays:    make(map[string]map[string]string, len(e.assocArrays)),
+		arrayTypes:     make(map[string]string, len(e.arrayTypes)),
+		builtins:       make(map[string]builtin.BuiltinFunc, len(e.builtins)),
+		functions:      make(map[string]*parser.FunctionStatement, len(e.functions)),
+		options:        make(map[string]bool, len(e.options)),
+		jobs:           NewJobManager(),
+		random:         rand.New(rand.NewSource(e.random.Int63())),
+		stdin:          e.stdin,
+		stdout:         e.stdout,
+		stderr:         e.stderr,
+		traceSink:      e.traceSink,
+		traceDepth:     e.traceDepth,
+		xtraceLevel:    e.xtraceLevel,
+		ctx:            e.ctx,
+		commandTimeout: e.commandTimeout,
+		substLimit:     e.substLimit,
+		policy:         e.policy,
+		dryRun:         e.dryRun,
+		profiler:       e.profiler,
+		coverage:       e.coverage,
+		pgroup:         e.pgroup,
+		inNotFoundHandler: e.inNotFoundHandler,
+		scriptName:     e.scriptName,
+		interactive:    e.interactive,
+		lineno:         e.lineno,
+		dynamicValues:  make(map[string]string, len(e.dynamicValues)),
+		secondsStart:   e.secondsStart,
+		secondsBase:    e.secondsBase,
+	}
+	for k, v := range e.dynamicValues {
+		sub.dynamicValues[k] = v
+	}
+	for k, v := range e.env {
+		sub.env[k] = v
+	}
+	for k, v := range e.arrays {
+		sub.arrays[k] = append([]string(nil), v...)
+	}
+	for k, v := range e.arrayIndices {
+		sub.arrayIndices[k] = append([]int(nil), v...)
+	}
+	for k, v := range e.assocArrays {
+		m := make(map[string]string, len(v))
+		for key, value := range v {
+			m[key] = value
+		}
+		sub.assocArrays[k] = m
+	}
+	for k, v := range e.arrayTypes {
+		sub.arrayTypes[k] = v
+	}
+	// 复制当前执行器的内置命令表，包括 shell 层和嵌入方注册的命令
+	for k, v := range e.builtins {
+		sub.builtins[k] = v
+	}
+	// jobs、fg、bg、kill 使用子执行器自己的作业管理器
+	for name, fn := range builtin.JobBuiltins(sub.jobs) {
+		sub.builtins[name] = fn
+	}
+	// 子shell 使用目录栈的副本
+	sub.dirStack = e.dirStack.Clone()
+	for name, fn := range builtin.DirStackBuiltins(sub.dirStack) {
+		sub.builtins[name] = fn
+	}
+	sub.builtins["xargs"] = builtin.XargsBuiltin(sub.lookupBuiltin)
+	sub.builtins["timeout"] = builtin.TimeoutBuiltin(sub.runArgs)
+	sub.exports = e.exports.Clone()
+	sub.builtins["export"] = builtin.ExportBuiltin(sub.exports, sub.functionSource)
+	sub.aliasLookup = e.aliasLookup
+	sub.umask = e.umask.Clone()
+	sub.builtins["type"] = builtin.TypeBuiltin(sub.commands())
+	sub.namerefs = make(map[string]string, len(e.namerefs))
+	for k, v := range e.namerefs {
+		sub.namerefs[k] = v
+	}
+	sub.integers = make(map[string]bool, len(e.integers))
+	for k, v := range e.integers {
+		sub.integers[k] = v
+	}
+	sub.readonly = make(map[string]bool, len(e.readonly))
+	for k, v := range e.readonly {
+		sub.readonly[k] = v
+	}
+	// 子shell 继承打开的文件描述符
+	sub.fds = make(map[int]*os.File, len(e.fds))
+	for fd, f := range e.fds {
+		sub.fds[fd] = f
+	}
+	sub.builtins["declare"] = builtin.DeclareBuiltin(sub.Variables())
+	sub.builtins["local"] = builtin.LocalBuiltin(sub.Variables())
+	sub.builtins["readonly"] = builtin.ReadonlyBuiltin(sub.Variables())
+	sub.builtins["unset"] = builtin.UnsetBuiltin(sub.unsetVariable, sub.unsetFunction)
+	sub.builtins["printf"] = builtin.PrintfBuiltin(sub.assignVariable)
+	sub.builtins["read"] = builtin.ReadBuiltin(sub.Variables(), sub.assignVariable, sub.openFD)
+	sub.builtins["let"] = builtin.LetBuiltin(sub.arithmetic)
+	sub.builtins["return"] = builtin.ReturnBuiltin(sub.inFunction)
+	sub.traps = e.inheritTraps()
+	sub.inTrap = e.inTrap
+	sub.builtins["trap"] = builtin.TrapBuiltin(sub.traps)
+	for k, v := range e.functions {
+		sub.functions[k] = v
+	}
+	for k, v := range e.options {
+		sub.options[k] = v
+	}
+	// 子shell 中的 local 不能修改当前shell的调用帧
+	sub.frames = make([]*callFrame, len(e.frames))
+	for i, frame := range e.frames {
+		sub.frames[i] = frame.clone()
+	}
+	// 条件上下文中的子shell里 set -e 同样不生效
+	sub.errexitSuppressed = e.errexitSuppressed
+	return sub
 }
 
 // getExitCode 获取当前退出码
@@ -2189,19 +3078,18 @@ func (e *Executor) executeProcessSubstitution(command string, isInput bool) stri
 	}
 
 	if isInput {
-		// <(command): 执行命令并将输出写入临时文件
-		oldStdout := os.Stdout
+		// <(command): 在子shell中执行命令并将输出写入临时文件
 		file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 		if err != nil {
 			os.Remove(tmpPath)
 			return ""
 		}
-		os.Stdout = file
+		sub := e.fork()
+		sub.stdout = file
 
-		execErr := e.Execute(program)
+		execErr := sub.Execute(program)
 
 		file.Close()
-		os.Stdout = oldStdout
 
 		if execErr != nil {
 			os.Remove(tmpPath)
@@ -2223,155 +3111,163 @@ func (e *Executor) executeProcessSubstitution(command string, isInput bool) stri
 	return tmpPath
 }
 
-// evaluateArithmetic 计算算术表达式
-func (e *Executor) evaluateArithmetic(expr string) string {
-	// 移除空白字符
-	expr = strings.TrimSpace(expr)
-	if expr == "" {
-		return "0"
-	}
-
-	// 展开变量（但保留引号，因为字符串字面量需要引号）
-	// 我们需要一个特殊的展开函数，它只展开变量，但保留引号
-	expr = e.expandVariablesInArithmeticExpression(expr)
-
-	// 简单的算术表达式求值
-	// 支持: +, -, *, /, %, (, )
-	// 使用递归下降解析器
-
-	result, err := evaluateArithmeticExpression(expr, e)
+// evaluateArithmetic 计算算术表达式（$((...)) 展开），表达式无效（如除以零）时返回错误
+func (e *Executor) evaluateArithmetic(expr string) (string, error) {
+	result, err := e.arithmetic(expr)
 	if err != nil {
-		return "0"
+		return "", err
 	}
-
-	return fmt.Sprintf("%d", result)
+	return strconv.FormatInt(result, 10), nil
 }
 
 // expandVariablesInArithmeticExpression 在算术表达式中展开变量，但保留引号
 // 这个函数专门用于算术表达式，它展开 $VAR 格式的变量，也展开没有 $ 前缀的变量名（算术展开的特殊规则）
-// 在算术展开 $((...)) 中，变量名可以直接使用，不需要 $ 前缀
-func (e *Executor) expandVariablesInArithmeticExpression(s string) string {
+// 在算术展开 $((...)) 中，变量名可以直接使用，不需要 $ 前缀；变量名后可以跟下标（arr[i]），
+// 赋值（=、+=、-= 等）和自增自减（++、--）在展开时执行，展开为运算后的值
+func (e *Executor) expandVariablesInArithmeticExpression(s string) (string, error) {
 	if len(s) == 0 {
-		return ""
+		return "", nil
 	}
 
 	var result strings.Builder
 	i := 0
 	for i < len(s) {
-		// 如果遇到引号，跳过整个引号字符串（不展开其中的变量）
+		// 如果遇到引号，原样保留整个引号字符串（不展开其中的变量，由算术函数处理）
 		if s[i] == '\'' || s[i] == '"' {
-			quote := s[i]
-			result.WriteByte(quote) // 保留引号
-			i++
-			// 跳过引号内的内容
-			for i < len(s) && s[i] != quote {
-				if s[i] == '\\' && i+1 < len(s) {
-					// 处理转义字符
-					result.WriteByte(s[i])
-					result.WriteByte(s[i+1])
-					i += 2
-				} else {
-					result.WriteByte(s[i])
-					i++
-				}
-			}
-			if i < len(s) {
-				result.WriteByte(quote) // 保留结束引号
-				i++
+			end := lexer.SkipQuoted(s, i)
+			if end < 0 {
+				end = len(s)
 			}
+			result.WriteString(s[i:end])
+			i = end
 			continue
 		}
 
-		// 处理变量展开 $VAR 或直接变量名（算术展开中变量名可以直接使用）
-		if s[i] == '$' && i+1 < len(s) {
-			// 检查是否是 $VAR 格式（不是 $((...))）
-			if i+2 < len(s) && s[i+1] == '(' && s[i+2] == '(' {
-				// $((...)) 格式，保留原样（不应该在这里出现，因为这是递归调用）
-				result.WriteByte(s[i])
-				i++
+		// 展开 $VAR、${...}、$(...)、$((...)) 和 `...`
+		if s[i] == '$' || s[i] == '`' {
+			if exp, end, ok := lexer.ScanExpansion(s, i, false); ok {
+				value, err := e.expandPart(exp, false)
+				if err != nil {
+					return "", err
+				}
+				result.WriteString(value)
+				i = end
 				continue
 			}
+			result.WriteByte(s[i])
+			i++
+			continue
+		}
 
-			// 展开变量
-			varName := ""
-			if i+1 < len(s) && s[i+1] == '{' {
-				// ${VAR} 格式
-				i += 2 // 跳过 ${
-				start := i
-				for i < len(s) && s[i] != '}' {
-					i++
+		// 前置自增自减 ++name、--name（前面是操作数时是减号或加号，如 5--x）
+		if (s[i] == '+' || s[i] == '-') && i+1 < len(s) && s[i+1] == s[i] && !endsWithOperand(result.String()) {
+			j := skipArithmeticSpaces(s, i+2)
+			if j < len(s) && isNameStart(s[j]) {
+				name, subscript, end := readArithmeticVariable(s, j)
+				value, err := e.arithmeticVariable(name, subscript)
+				if err != nil {
+					return "", err
 				}
-				if i < len(s) {
-					varName = s[start:i]
-					i++ // 跳过 }
-				}
-			} else if i+1 < len(s) {
-				// $VAR 格式
-				start := i + 1
-				for i+1 < len(s) && ((s[i+1] >= 'a' && s[i+1] <= 'z') ||
-					(s[i+1] >= 'A' && s[i+1] <= 'Z') ||
-					(s[i+1] >= '0' && s[i+1] <= '9') ||
-					s[i+1] == '_') {
-					i++
+				if s[i] == '+' {
+					value++
+				} else {
+					value--
 				}
-				varName = s[start : i+1]
-				i++
-			}
-
-			if varName != "" {
-				// 获取变量值
-				varValue := e.env[varName]
-				if varValue == "" {
-					varValue = os.Getenv(varName)
+				if err := e.setArithmeticVariable(name, subscript, value); err != nil {
+					return "", err
 				}
-				result.WriteString(varValue)
-			} else {
-				result.WriteByte(s[i])
-				i++
+				fmt.Fprintf(&result, "(%d)", value)
+				i = end
+				continue
 			}
-			continue
 		}
 
 		// 检查是否是变量名（没有 $ 前缀，算术展开的特殊规则）
 		// 变量名必须以字母或下划线开头，后面可以跟字母、数字或下划线
-		if (s[i] >= 'a' && s[i] <= 'z') || (s[i] >= 'A' && s[i] <= 'Z') || s[i] == '_' {
-			// 提取变量名
+		if isNameStart(s[i]) {
 			start := i
-			for i < len(s) && ((s[i] >= 'a' && s[i] <= 'z') ||
-				(s[i] >= 'A' && s[i] <= 'Z') ||
-				(s[i] >= '0' && s[i] <= '9') ||
-				s[i] == '_') {
-				i++
+			name, subscript, end := readArithmeticVariable(s, i)
+			if end < len(s) && s[end] == '(' {
+				// 算术函数调用（如 abs(x)、max(a, b)），保留函数名
+				result.WriteString(name)
+				i = start + len(name)
+				continue
 			}
-			varName := s[start:i]
+			i = end
 
 			// 检查是否是运算符或关键字（如 and, or, not 等）
 			// 如果是运算符，不展开
 			operators := []string{"and", "or", "not", "eq", "ne", "lt", "le", "gt", "ge"}
 			isOperator := false
 			for _, op := range operators {
-				if varName == op {
+				if name == op {
 					isOperator = true
 					break
 				}
 			}
+			if isOperator {
+				// 是运算符，保留原样
+				result.WriteString(s[start:i])
+				continue
+			}
 
-			if !isOperator {
-				// 获取变量值
-				varValue := e.env[varName]
-				if varValue == "" {
-					varValue = os.Getenv(varName)
+			j := skipArithmeticSpaces(s, i)
+			if op := arithmeticAssignOperator(s[j:]); op != "" {
+				// 赋值 name op= expr：右边一直到当前括号或逗号结束
+				rhsEnd := arithmeticOperandEnd(s, j+len(op))
+				value, err := e.arithmetic(s[j+len(op) : rhsEnd])
+				if err != nil {
+					return "", err
 				}
-				// 如果变量值不为空，展开它；如果为空，保留变量名（可能是未定义的变量）
-				if varValue != "" {
-					result.WriteString(varValue)
-				} else {
-					// 未定义的变量，在算术表达式中应该被视为 0
-					result.WriteString("0")
+				if op != "=" {
+					current, err := e.arithmeticVariable(name, subscript)
+					if err != nil {
+						return "", err
+					}
+					if value, err = applyArithmeticOperator(op[:len(op)-1], current, value); err != nil {
+						return "", err
+					}
+				}
+				if err := e.setArithmeticVariable(name, subscript, value); err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&result, "(%d)", value)
+				i = rhsEnd
+				continue
+			}
+			if strings.HasPrefix(s[j:], "++") || strings.HasPrefix(s[j:], "--") {
+				// 后置自增自减 name++、name--：展开为运算前的值
+				value, err := e.arithmeticVariable(name, subscript)
+				if err != nil {
+					return "", err
+				}
+				next := value + 1
+				if s[j] == '-' {
+					next = value - 1
 				}
+				if err := e.setArithmeticVariable(name, subscript, next); err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&result, "(%d)", value)
+				i = j + 2
+				continue
+			}
+
+			if subscript != "" {
+				value, err := e.arithmeticVariable(name, subscript)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&result, "(%d)", value)
+				continue
+			}
+			// 获取变量值，未定义的变量在算术表达式中视为 0
+			name = e.resolveNameref(name)
+			e.refreshDynamic(name)
+			if varValue := e.env[name]; varValue != "" {
+				result.WriteString(varValue)
 			} else {
-				// 是运算符，保留原样
-				result.WriteString(varName)
+				result.WriteString("0")
 			}
 			continue
 		}
@@ -2381,7 +3277,7 @@ func (e *Executor) expandVariablesInArithmeticExpression(s string) string {
 		i++
 	}
 
-	return result.String()
+	return result.String(), nil
 }
 
 // evaluateArithmeticExpression 计算算术表达式
@@ -2418,7 +3314,7 @@ func parseArithmeticExpression(expr string, pos *int) (int64, error) {
 	return parseArithmeticExpressionWithExecutor(expr, pos, nil)
 }
 
-// parseArithmeticExpressionWithExecutor 解析算术表达式（处理逻辑或 ||，支持 Executor）
+// parseArithmeticExpressionWithExecutor 解析算术表达式（处理逻辑或 || 和条件运算符 ?:，支持 Executor）
 func parseArithmeticExpressionWithExecutor(expr string, pos *int, e *Executor) (int64, error) {
 	result, err := parseArithmeticAndExpressionWithExecutor(expr, pos, e)
 	if err != nil {
@@ -2445,7 +3341,32 @@ func parseArithmeticExpressionWithExecutor(expr string, pos *int, e *Executor) (
 		}
 	}
 
-	return result, nil
+	return parseArithmeticConditional(expr, pos, e, result)
+}
+
+// parseArithmeticConditional 解析条件运算符 cond ? a : b 中 ? 之后的部分（cond 的值已经计算），右结合；
+// 后面没有 ? 时返回 cond
+func parseArithmeticConditional(expr string, pos *int, e *Executor, cond int64) (int64, error) {
+	if *pos >= len(expr) || expr[*pos] != '?' {
+		return cond, nil
+	}
+	*pos++
+	then, err := parseArithmeticExpressionWithExecutor(expr, pos, e)
+	if err != nil {
+		return 0, err
+	}
+	if *pos >= len(expr) || expr[*pos] != ':' {
+		return 0, fmt.Errorf("`:' expected for conditional expression")
+	}
+	*pos++
+	otherwise, err := parseArithmeticExpressionWithExecutor(expr, pos, e)
+	if err != nil {
+		return 0, err
+	}
+	if cond != 0 {
+		return then, nil
+	}
+	return otherwise, nil
 }
 
 // parseArithmeticAndExpression 解析逻辑与表达式（处理 &&）
@@ -2918,7 +3839,7 @@ func parseArithmeticFactorWithExecutor(expr string, pos *int, e *Executor) (int6
 						return 0, err
 					}
 					// 调用算术函数
-					result, err := evaluateArithmeticFunction(funcName, args)
+					result, err := evaluateArithmeticFunction(funcName, args, e)
 					if err != nil {
 						return 0, fmt.Errorf("arithmetic function %s: %v", funcName, err)
 					}
@@ -2967,11 +3888,6 @@ func isDigitArith(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-// isLetterArith 判断是否为字母（用于算术表达式）
-func isLetterArith(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
-}
-
 // parseArithmeticStringArg 解析字符串参数（变量展开或字符串字面量）
 // 从原始表达式（未展开）中解析字符串参数
 // 返回字符串值和新的位置
@@ -2985,162 +3901,31 @@ func parseArithmeticStringArg(expr string, pos *int, e *Executor) (string, error
 		return "", fmt.Errorf("unexpected end of expression")
 	}
 
-	// 检查是否是字符串字面量
+	// 检查是否是字符串字面量，单引号中的内容原样使用，双引号中的内容与双引号字符串一样展开
 	if expr[*pos] == '"' || expr[*pos] == '\'' {
-		quote := expr[*pos]
-		*pos++ // 跳过开始引号
-		start := *pos
-
-		// 查找结束引号
-		for *pos < len(expr) && expr[*pos] != quote {
-			if expr[*pos] == '\\' && *pos+1 < len(expr) {
-				*pos += 2 // 跳过转义字符
-			} else {
-				*pos++
-			}
-		}
-
-		if *pos >= len(expr) {
+		end := lexer.SkipQuoted(expr, *pos)
+		if end < 0 {
 			return "", fmt.Errorf("unclosed string literal")
 		}
-
-		str := expr[start:*pos]
-		*pos++ // 跳过结束引号
+		str := expr[*pos+1 : end-1]
+		*pos = end
+		if expr[end-1] == '"' && e != nil {
+			return e.expandString(str)
+		}
 		return str, nil
 	}
 
 	// 检查是否是变量展开 $VAR、${VAR}、${VAR:-default}、$(command)、$((expr)) 等
 	if expr[*pos] == '$' {
-		startPos := *pos // 记录 $ 的位置
-		*pos++           // 跳过 $
-
-		// 检查是否是算术展开 $((...))
-		if *pos < len(expr) && expr[*pos] == '(' && *pos+1 < len(expr) && expr[*pos+1] == '(' {
-			// $((...)) 格式
-			*pos += 2 // 跳过 ((
-			parenDepth := 0
-			for *pos < len(expr) {
-				if *pos+1 < len(expr) && expr[*pos] == ')' && expr[*pos+1] == ')' && parenDepth == 0 {
-					// 找到匹配的 ))
-					*pos += 2 // 跳过 ))
-					// 提取完整的算术展开表达式
-					arithExpr := expr[startPos:*pos]
-					// 使用 expandVariablesInString 展开（它会处理算术展开）
-					if e == nil {
-						return "", fmt.Errorf("Executor instance required for variable expansion")
-					}
-					return e.expandVariablesInString(arithExpr), nil
-				} else if expr[*pos] == '(' {
-					parenDepth++
-					*pos++
-				} else if expr[*pos] == ')' {
-					if parenDepth > 0 {
-						parenDepth--
-					}
-					*pos++
-				} else {
-					*pos++
-				}
-			}
-			return "", fmt.Errorf("unclosed arithmetic expansion")
-		}
-
-		// 检查是否是命令替换 $(...)
-		if *pos < len(expr) && expr[*pos] == '(' {
-			// $(...) 格式
-			*pos++ // 跳过 (
-			parenDepth := 0
-			for *pos < len(expr) {
-				if expr[*pos] == ')' && parenDepth == 0 {
-					// 找到匹配的 )
-					*pos++ // 跳过 )
-					// 提取完整的命令替换表达式
-					cmdExpr := expr[startPos:*pos]
-					// 使用 expandVariablesInString 展开（它会处理命令替换）
-					if e == nil {
-						return "", fmt.Errorf("Executor instance required for variable expansion")
-					}
-					return e.expandVariablesInString(cmdExpr), nil
-				} else if expr[*pos] == '(' {
-					parenDepth++
-					*pos++
-				} else if expr[*pos] == ')' {
-					if parenDepth > 0 {
-						parenDepth--
-					}
-					*pos++
-				} else {
-					*pos++
-				}
-			}
-			return "", fmt.Errorf("unclosed command substitution")
-		}
-
-		// 检查是否是参数展开 ${...}
-		if *pos < len(expr) && expr[*pos] == '{' {
-			// ${...} 格式（可能是 ${VAR}、${VAR:-default}、${VAR#pattern} 等）
-			*pos++ // 跳过 {
-			braceDepth := 0
-			for *pos < len(expr) {
-				if expr[*pos] == '}' && braceDepth == 0 {
-					// 找到匹配的 }
-					*pos++ // 跳过 }
-					// 提取完整的参数展开表达式
-					paramExpr := expr[startPos:*pos]
-					// 使用 expandVariablesInString 展开（它会处理参数展开）
-					if e == nil {
-						return "", fmt.Errorf("Executor instance required for variable expansion")
-					}
-					return e.expandVariablesInString(paramExpr), nil
-				} else if expr[*pos] == '{' {
-					braceDepth++
-					*pos++
-				} else if expr[*pos] == '}' {
-					if braceDepth > 0 {
-						braceDepth--
-					}
-					*pos++
-				} else {
-					*pos++
-				}
-			}
-			return "", fmt.Errorf("unclosed parameter expansion")
-		}
-
-		// 处理简单变量 $VAR 或特殊变量 $#, $@, $*, $?, $!, $$, $0, $1, $2, ...
-		// 提取变量名直到遇到分隔符（逗号、右括号、空格等）
-		varEndPos := *pos
-		if *pos < len(expr) {
-			// 处理特殊变量
-			if expr[*pos] == '#' || expr[*pos] == '@' || expr[*pos] == '*' ||
-				expr[*pos] == '?' || expr[*pos] == '!' || expr[*pos] == '$' || expr[*pos] == '0' {
-				varEndPos = *pos + 1
-			} else if isDigitArith(expr[*pos]) {
-				// 位置参数 $1, $2, ...
-				for varEndPos < len(expr) && isDigitArith(expr[varEndPos]) {
-					varEndPos++
-				}
-			} else if isLetterArith(expr[*pos]) || expr[*pos] == '_' {
-				// 普通变量 $VAR
-				for varEndPos < len(expr) &&
-					(isLetterArith(expr[varEndPos]) || isDigitArith(expr[varEndPos]) ||
-						expr[varEndPos] == '_' || expr[varEndPos] == '[' || expr[varEndPos] == ']') {
-					varEndPos++
-				}
-			} else {
-				return "", fmt.Errorf("invalid variable name after $")
-			}
-		}
-
-		// 提取完整的变量展开表达式
-		varExpr := expr[startPos:varEndPos]
-		*pos = varEndPos
-
-		// 使用 expandVariablesInString 展开（它会处理所有类型的变量展开）
 		if e == nil {
 			return "", fmt.Errorf("Executor instance required for variable expansion")
 		}
-		return e.expandVariablesInString(varExpr), nil
+		exp, end, ok := lexer.ScanExpansion(expr, *pos, false)
+		if !ok {
+			return "", fmt.Errorf("invalid expansion after $")
+		}
+		*pos = end
+		return e.expandPart(exp, false)
 	}
 
 	// 如果既不是引号也不是 $，可能是已经展开的变量值（字符串字面量）
@@ -3319,7 +4104,7 @@ type ArithmeticFunctionArg struct {
 
 // evaluateArithmeticFunction 计算算术函数
 // 为了向后兼容，保留接受 []int64 的版本
-func evaluateArithmeticFunction(name string, args []int64) (int64, error) {
+func evaluateArithmeticFunction(name string, args []int64, e *Executor) (int64, error) {
 	switch name {
 	case "abs":
 		if len(args) != 1 {
@@ -3379,19 +4164,24 @@ func evaluateArithmeticFunction(name string, args []int64) (int64, error) {
 		if len(args) > 0 {
 			return 0, fmt.Errorf("rand takes no arguments, got %d", len(args))
 		}
-		// 使用简单的线性同余生成器
-		// 注意：这不是线程安全的，但对于单线程 shell 足够了
-		return int64(rand.Intn(32768)), nil
+		// 每个执行器有自己的随机数生成器，srand 不影响其他执行器
+		if e == nil {
+			return int64(rand.Intn(32768)), nil
+		}
+		return int64(e.random.Intn(32768)), nil
 
 	case "srand":
 		// srand 函数设置随机数种子
 		if len(args) > 1 {
 			return 0, fmt.Errorf("srand requires 0 or 1 argument, got %d", len(args))
 		}
+		if e == nil {
+			return 0, nil
+		}
 		if len(args) == 1 {
-			rand.Seed(args[0])
+			e.random = rand.New(rand.NewSource(args[0]))
 		} else {
-			rand.Seed(time.Now().UnixNano())
+			e.random = rand.New(rand.NewSource(time.Now().UnixNano()))
 		}
 		return 0, nil
 
@@ -3550,168 +4340,6 @@ func evaluateArithmeticFunctionWithMixedArgs(name string, args []ArithmeticFunct
 	}
 }
 
-// evaluateDoubleBracketExpression 计算 [[ 表达式（支持 && 和 ||）
-func (e *Executor) evaluateDoubleBracketExpression(args []string) (bool, error) {
-	if len(args) == 0 {
-		return false, fmt.Errorf("[[: 缺少参数")
-	}
-
-	// 移除结束括号 ]]
-	if len(args) > 0 && args[len(args)-1] == "]]" {
-		args = args[:len(args)-1]
-	}
-
-	// 使用递归下降解析器处理 && 和 ||
-	return e.parseDoubleBracketExpression(args, 0)
-}
-
-// parseDoubleBracketExpression 解析 [[ 表达式（处理 || 运算符，优先级最低）
-func (e *Executor) parseDoubleBracketExpression(args []string, pos int) (bool, error) {
-	left, newPos, err := e.parseDoubleBracketAndExpression(args, pos)
-	if err != nil {
-		return false, err
-	}
-	pos = newPos
-
-	for pos < len(args) && args[pos] == "||" {
-		pos++ // 跳过 ||
-		right, newPos, err := e.parseDoubleBracketAndExpression(args, pos)
-		if err != nil {
-			return false, err
-		}
-		pos = newPos
-		left = left || right
-	}
-
-	return left, nil
-}
-
-// parseDoubleBracketAndExpression 解析 && 表达式（优先级高于 ||）
-func (e *Executor) parseDoubleBracketAndExpression(args []string, pos int) (bool, int, error) {
-	left, newPos, err := e.parseDoubleBracketPrimaryExpression(args, pos)
-	if err != nil {
-		return false, pos, err
-	}
-	pos = newPos
-
-	for pos < len(args) && args[pos] == "&&" {
-		pos++ // 跳过 &&
-		right, newPos, err := e.parseDoubleBracketPrimaryExpression(args, pos)
-		if err != nil {
-			return false, pos, err
-		}
-		pos = newPos
-		left = left && right
-	}
-
-	return left, pos, nil
-}
-
-// parseDoubleBracketPrimaryExpression 解析基本表达式（单个测试或括号表达式）
-func (e *Executor) parseDoubleBracketPrimaryExpression(args []string, pos int) (bool, int, error) {
-	if pos >= len(args) {
-		return false, pos, fmt.Errorf("[[: 表达式不完整")
-	}
-
-	// 处理括号表达式
-	if args[pos] == "(" {
-		pos++
-		// 找到匹配的右括号
-		depth := 1
-		endPos := pos
-		for endPos < len(args) && depth > 0 {
-			if args[endPos] == "(" {
-				depth++
-			} else if args[endPos] == ")" {
-				depth--
-			}
-			if depth > 0 {
-				endPos++
-			}
-		}
-		if depth != 0 {
-			return false, pos, fmt.Errorf("[[: 括号不匹配")
-		}
-
-		// 递归解析括号内的表达式
-		result, err := e.parseDoubleBracketExpression(args[pos:endPos], 0)
-		if err != nil {
-			return false, pos, err
-		}
-		return result, endPos + 1, nil
-	}
-
-	// 处理 ! 运算符
-	if args[pos] == "!" {
-		pos++
-		result, newPos, err := e.parseDoubleBracketPrimaryExpression(args, pos)
-		if err != nil {
-			return false, pos, err
-		}
-		return !result, newPos, nil
-	}
-
-	// 处理单个测试表达式
-	// 找到测试表达式的结束位置（遇到 &&, ||, ), 或到达末尾）
-	endPos := pos
-	for endPos < len(args) {
-		if args[endPos] == "&&" || args[endPos] == "||" || args[endPos] == ")" {
-			break
-		}
-		endPos++
-	}
-
-	// 提取测试表达式
-	testArgs := args[pos:endPos]
-	if len(testArgs) == 0 {
-		return false, pos, fmt.Errorf("[[: 空表达式")
-	}
-
-	// 调用 test 命令来求值
-	testFunc := e.builtins["test"]
-	if testFunc == nil {
-		return false, pos, fmt.Errorf("test命令未找到")
-	}
-
-	// 临时修改环境变量，调用 test 命令
-	// 注意：test 命令返回 error 表示失败，nil 表示成功
-	err := testFunc(testArgs, e.env)
-	result := err == nil
-
-	return result, endPos, nil
-}
-
-// readHereDocument 读取 Here-document 内容
-// 从标准输入读取，直到找到分隔符
-func (e *Executor) readHereDocument(delimiter string, quoted bool, stripTabs bool) string {
-	var content strings.Builder
-	scanner := bufio.NewScanner(os.Stdin)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 检查是否是分隔符
-		if strings.TrimSpace(line) == delimiter {
-			break
-		}
-
-		// 如果 stripTabs 为 true，剥离前导制表符
-		if stripTabs {
-			line = strings.TrimLeft(line, "\t")
-		}
-
-		// 如果 quoted 为 false，展开变量
-		if !quoted {
-			line = e.expandVariablesInString(line)
-		}
-
-		content.WriteString(line)
-		content.WriteString("\n")
-	}
-
-	return content.String()
-}
-
 // splitEnv 分割环境变量字符串
 func splitEnv(env string) (string, string) {
 	for i := 0; i < len(env); i++ {
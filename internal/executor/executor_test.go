@@ -1,14 +1,28 @@
 package executor
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+	"gobash/internal/builtin"
+	"gobash/internal/i18n"
 	"gobash/internal/lexer"
 	"gobash/internal/parser"
 )
 
+// TestMain 使用中文消息运行测试（测试检查的是源代码中的中文错误消息）
+func TestMain(m *testing.M) {
+	i18n.SetLanguage(i18n.Chinese)
+	os.Exit(m.Run())
+}
+
 func TestNew(t *testing.T) {
 	e := New()
 	if e == nil {
@@ -132,6 +146,198 @@ func TestExecuteExport(t *testing.T) {
 	}
 }
 
+// TestExportedVariables 测试只有导出的变量传给外部命令：从环境继承的变量，export、declare -x 导出的变量，以及命令前的赋值
+func TestExportedVariables(t *testing.T) {
+	input := `{ v=1; sh -c 'echo "[$v]"'; env | grep -c '^v='; declare -p v
+export v; sh -c 'echo "[$v]"'; declare -p v
+declare -x d=2; sh -c 'echo "[$d]"'
+w=3 sh -c 'echo "[$w]"'; sh -c 'echo "[$w]"'
+sh -c 'echo "[$PATH]"' | grep -c /; }`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v（%q）", err, stderr)
+	}
+	want := "[]\n0\ndeclare -- v=\"1\"\n[1]\ndeclare -x v=\"1\"\n[2]\n[3]\n[]\n1\n"
+	if stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+}
+
+func TestExportAttributes(t *testing.T) {
+	e := New()
+	program := parser.New(lexer.New("greet() {\n  echo \"hi $1\"\n}\nexport -f greet\nHIDDEN=1\nexport -n HIDDEN")).ParseProgram()
+	if err := e.Execute(program); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+
+	e.setPositional([]string{"a", "b"})
+	env := e.getEnvArray()
+	wantFunc := "BASH_FUNC_greet%%=() {\n  echo \"hi $1\"\n}"
+	var hasFunc bool
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "HIDDEN=") {
+			t.Errorf("export -n 的变量不应该传给外部命令: %q", kv)
+		}
+		if strings.HasPrefix(kv, "1=") || strings.HasPrefix(kv, "#=") || strings.HasPrefix(kv, "@=") {
+			t.Errorf("位置参数不应该传给外部命令: %q", kv)
+		}
+		hasFunc = hasFunc || kv == wantFunc
+	}
+	if !hasFunc {
+		t.Errorf("外部命令的环境中没有导出的函数 %q", wantFunc)
+	}
+
+	// 子 gobash 从环境中导入函数
+	child := New()
+	child.importFunction("greet", strings.TrimPrefix(wantFunc, "BASH_FUNC_greet%%="))
+	var out strings.Builder
+	child.SetStdio(nil, &out, nil)
+	if err := child.Execute(parser.New(lexer.New("greet bob")).ParseProgram()); err != nil || out.String() != "hi bob\n" {
+		t.Errorf("导入的函数输出 %q（%v）", out.String(), err)
+	}
+	if env := child.getEnvArray(); !strings.Contains(strings.Join(env, "\n"), "BASH_FUNC_greet%%=") {
+		t.Error("导入的函数应该继续导出")
+	}
+}
+
+// TestPrefixAssignment 测试命令之前的赋值只在执行这条命令时有效
+func TestPrefixAssignment(t *testing.T) {
+	input := `{
+show() { echo "in f: $A $B"; }
+A=1 B=$A show
+echo "after: [$A] [$B]"
+B=old
+B=new env | grep '^B='
+echo "B=$B"
+B=x A=y B=z show
+echo "restored: [$A] $B"
+x=1 y=2
+echo "plain: $x $y"
+unset a; a=1 echo "[$a]"
+}`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "in f: 1 1\nafter: [] []\nB=new\nB=old\nin f: y z\nrestored: [] old\nplain: 1 2\n[]\n"
+	if stdout != expected || stderr != "" {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, expected)
+	}
+}
+
+// TestDeclarePrint 测试 declare -p/-f/-F 和不带参数的 declare（与 set 格式相同）输出数组、关联数组和函数
+func TestDeclarePrint(t *testing.T) {
+	input := `arr=(a "b c"); declare -A m; m[k]=v; f() { echo hi; }; x='it s'; export -n x
+declare -p arr m x; declare -F; declare -f f; declare`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	for _, want := range []string{
+		"declare -a arr=([0]=\"a\" [1]=\"b c\")\ndeclare -A m=([k]=\"v\" )\ndeclare -- x=\"it s\"\ndeclare -f f\nf () { echo hi; }\n",
+		"\narr=([0]=\"a\" [1]=\"b c\")\n", "\nm=([k]=\"v\" )\n", "\nx='it s'\n", "\nf () { echo hi; }\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("输出 %q 中没有 %q", stdout, want)
+		}
+	}
+	if strings.Contains(stdout, "arr_LENGTH") || strings.Contains(stdout, "__WBASH_") {
+		t.Error("declare 不应该输出内部变量")
+	}
+}
+
+// TestDeclareRoundTrip 测试 declare -p 的输出可以重新执行：执行后得到相同的变量、数组和属性
+func TestDeclareRoundTrip(t *testing.T) {
+	input := `declare -a arr=(a "b c" '$x'); arr[5]=z; declare -A m=([k]="v w" [1]=one); declare -i n=2+3
+declare -r r=ro; export e='q"t'; declare -ar ra=(1 2)
+declare -p arr m n r e ra`
+	first, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	second, stderr, err := New().Capture(parser.New(lexer.New(first + "declare -p arr m n r e ra")).ParseProgram())
+	if err != nil || stderr != "" {
+		t.Fatalf("重新执行 %q 失败: %v %s", first, err, stderr)
+	}
+	if second != first {
+		t.Errorf("重新执行后输出 %q，期望 %q", second, first)
+	}
+	for _, want := range []string{`declare -a arr=([0]="a" [1]="b c" [2]="\$x" [5]="z")`, `declare -i n="5"`, `declare -r r="ro"`, "declare -ar ra=", "declare -x e="} {
+		if !strings.Contains(first, want) {
+			t.Errorf("输出 %q 中没有 %q", first, want)
+		}
+	}
+
+	// 只读变量不能再赋值，local 声明的数组在函数返回后恢复，declare -F 名称只输出函数名
+	input = `{
+readonly r=1; r=2; echo $? $r
+a=(o1 o2); f() { local -a a=(1 2 3); local m=([x]=y); echo ${a[@]}; }; f; echo ${a[@]}; declare -F f
+}`
+	stdout, stderr, _ := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if stdout != "1 1\n1 2 3\no1 o2\nf\n" || !strings.Contains(stderr, "r: 只读变量") {
+		t.Errorf("输出 %q，错误输出 %q", stdout, stderr)
+	}
+}
+
+// TestNameref 测试名称引用：读取和赋值都作用于被引用的变量，支持数组、${!ref}、local -n 和 printf -v
+func TestNameref(t *testing.T) {
+	input := `x=1; declare -n ref=x; ref=2; echo "$x ${ref} ${!ref}"
+arr=(a b c); declare -n r=arr; r[1]=B; echo ${r[@]} ${r[2]} ${!r[@]}
+n='arr[1]'; m='arr[@]'; echo ${!n} ${!m}
+setv() { local -n out=$1; out=set; }; setv y; echo "$y"
+printf -v ref '%03d' 7; printf -v 'arr[0]' '%s!' A; echo $x ${arr[0]}
+declare -p ref; declare +n ref; ref=9; echo $x $ref`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	want := "2 2 x\na B c c 0 1 2\nB a B c\nset\n007 A!\ndeclare -n ref=\"x\"\n007 9\n"
+	if stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+
+	// 名称引用不能引用自身，local -n 声明的名称引用在函数返回后删除
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(`f() { local -n o=$1; }; f z; declare -p o || declare -n s=s`)).ParseProgram())
+	if stdout != "" || !strings.Contains(stderr, "o: not found") || err == nil || !strings.Contains(stderr, "不能引用自身") {
+		t.Errorf("输出 %q，错误输出 %q（%v）", stdout, stderr, err)
+	}
+}
+
+func TestArithmeticAssignment(t *testing.T) {
+	input := `i=1; j=3; let i++ j+=2; echo $i $j
+((i += 10)); ((k = i * 2, k--)); echo $i $k
+echo $((x = 5)) $((++x)) $((x++)) $x $((y = z = 2)) $z
+arr=(1 2 3); ((arr[1] *= 5)); ((arr[i-10]++)); echo ${arr[@]}
+declare -n r=i; ((r--)); echo $i $((abs(-3)))
+let 0 || echo let-zero; (( 0 )) || echo cmd-zero; ((1)) && echo cmd-true
+((echo nested); echo subshell)`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	want := "2 5\n12 23\n5 6 6 7 2 2\n1 10 4\n11 3\nlet-zero\ncmd-zero\ncmd-true\nnested\nsubshell\n"
+	if stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+
+	// 条件运算符在 $((...))、((...)) 和 let 中相同
+	stdout, _, err = New().Capture(parser.New(lexer.New(`x=4; ((y = x > 3 ? 7 : 8)); let "z = 0 ? 1 : x ? 2 : 3"; echo $((1?2:3)) $(( (x<0) ? -1 : x*2 )) $y $z`)).ParseProgram())
+	if err != nil || stdout != "2 8 7 2\n" {
+		t.Errorf("条件运算符输出 %q（%v）", stdout, err)
+	}
+
+	// 无效的表达式退出状态为 1，$((...)) 不再展开为 0
+	for _, input := range []string{"((1 / 0))", "echo $((1 / 0))", "x=$((1 ? 2))"} {
+		stdout, _, err = New().Capture(parser.New(lexer.New(input)).ParseProgram())
+		if ExitStatus(err) != 1 || stdout != "" {
+			t.Errorf("%s 输出 %q，返回 %v", input, stdout, err)
+		}
+	}
+	if _, _, err = New().Capture(parser.New(lexer.New("echo $((1 / 0))")).ParseProgram()); err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("$((1 / 0)) 返回 %v", err)
+	}
+}
+
 func TestExecuteIfStatement(t *testing.T) {
 	e := New()
 	
@@ -145,6 +351,38 @@ func TestExecuteIfStatement(t *testing.T) {
 	if err != nil {
 		t.Errorf("执行if语句失败: %v", err)
 	}
+
+	// 条件成立时只执行 then 分支，不再执行 elif 和 else
+	input = `if true; then echo then; else echo else; fi
+if false; then echo then; elif true; then echo elif; elif true; then echo elif2; else echo else; fi
+if false; then echo then; else echo else; fi`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if expected := "then\nelif\nelse\n"; stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+
+	// 条件是命令列表，以最后一条命令的退出状态为条件；没有执行任何分支时退出状态为 0
+	input = `{
+if false; true; then echo list; fi
+if true && false; then echo and; elif echo a | grep -q a; then echo pipeline; fi
+if ! (( 0 )); then echo negated; fi
+if sh -c 'exit 3'; then :; else echo "else $?"; fi
+if false; then :; fi
+echo "none $?"
+f() { if return 4; then echo unreachable; fi; }
+f
+echo "return $?"
+}`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if expected := "list\npipeline\nnegated\nelse 3\nnone 0\nreturn 4\n"; stdout != expected || stderr != "" {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, expected)
+	}
 }
 
 func TestExecuteForStatement(t *testing.T) {
@@ -160,6 +398,107 @@ func TestExecuteForStatement(t *testing.T) {
 	if err != nil {
 		t.Errorf("执行for循环失败: %v", err)
 	}
+
+	// in 之后没有单词时不执行循环体；没有 in 子句时遍历位置参数，参数中的空格不分割
+	input = `{
+for x in; do echo "empty $x"; done
+f() { for a; do echo "arg $a"; done; for b in "$@"; do echo "quoted $b"; done; }
+f "1 2" 3
+}`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "arg 1 2\narg 3\nquoted 1 2\nquoted 3\n"
+	if stdout != expected || stderr != "" {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, expected)
+	}
+}
+
+func TestExecuteForExpansion(t *testing.T) {
+	// 单词列表进行单词分割和路径名展开，"$@" 和 "${arr[@]}" 每个元素一个单词
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", ".hidden.txt", "c.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input := `{
+for f in *.txt "*.txt" none*; do echo "glob $f"; done
+for w in $(echo "x  y"; echo z) "$(echo "p q")"; do echo "subst $w"; done
+arr=("a b" c)
+for e in "${arr[@]}"; do echo "quoted $e"; done
+for e in ${arr[@]} "${arr[*]}"; do echo "split $e"; done
+f() { for a in "$@" x"$@"y; do echo "args $a"; done; }
+f "1 2" 3
+empty=
+for v in $empty "" a$empty; do echo "empty [$v]"; done
+IFS=:
+path=/bin:/usr/bin
+for d in $path; do echo "ifs $d"; done
+}`
+	e := New()
+	e.SetEnv("PWD", dir)
+	stdout, stderr, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := `glob a.txt
+glob b.txt
+glob *.txt
+glob none*
+subst x
+subst y
+subst z
+subst p q
+quoted a b
+quoted c
+split a
+split b
+split c
+split a b c
+args 1 2
+args 3
+args x1 2
+args 3y
+empty []
+empty [a]
+ifs /bin
+ifs /usr/bin
+`
+	if stdout != expected || stderr != "" {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, expected)
+	}
+}
+
+func TestExecuteWhileStatement(t *testing.T) {
+	// 条件可以是内置命令、(( ))、[[ ]] 和命令列表；until 在条件不成立时执行循环体
+	input := `{
+i=0
+while (( i < 3 )); do i=$((i + 1)); done
+echo "while $i"
+until [[ $i -eq 0 ]]; do i=$((i - 1)); done
+echo "until $i"
+n=0
+while true; do n=$((n + 1)); if [ $n -ge 5 ]; then break; fi; done
+echo "true $n"
+while n=$((n - 1)); [ $n -gt 0 ]; do :; done
+echo "list $n"
+while false; do :; done
+echo "status $?"
+while [ $n -lt 2 ]; do n=$((n + 1)); [ $n -gt 5 ]; done
+echo "body status $?"
+while break; do echo unreachable; done
+echo until
+}`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "while 3\nuntil 0\ntrue 5\nlist 0\nstatus 0\nbody status 1\nuntil\n"
+	if stdout != expected || stderr != "" {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, expected)
+	}
 }
 
 func TestExecuteFunction(t *testing.T) {
@@ -195,6 +534,697 @@ func TestExecuteFunction(t *testing.T) {
 	}
 }
 
+// TestNestedFunctions 测试在函数、if 和循环中定义的函数，以及子shell、命令替换、管道和进程替换中使用函数：
+// 子shell 继承当前shell的函数，子shell 中定义的函数不影响当前shell
+func TestNestedFunctions(t *testing.T) {
+	input := `outer() { inner() { echo "inner $1"; }; echo outer; }
+outer
+inner a
+if true; then cond() { echo cond; }; fi
+cond
+for i in 1; do loop() { echo "loop $i"; }; done
+loop
+f() { echo "f $1"; }
+echo "$(f subst) $(echo "$(f nested)")"
+( f paren )
+f pipe | cat
+cat <(f procsub)
+g() { h() { echo deep; }; h; }
+echo "$(g)"
+( sub() { echo sub; } )
+sub 2>/dev/null || echo "sub not defined"
+cat <(leak() { :; })
+leak 2>/dev/null || echo "leak not defined"`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "outer\ninner a\ncond\nloop 1\nf subst f nested\nf paren\nf pipe\nf procsub\ndeep\nsub not defined\nleak not defined\n"
+	if stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+}
+
+// TestRecursiveFunctions 测试递归函数：每次调用有自己的位置参数和局部变量，返回时恢复调用者的
+func TestRecursiveFunctions(t *testing.T) {
+	input := `fact() { if [ $1 -le 1 ]; then echo 1; else local p=$(fact $(($1 - 1))); echo $(($1 * p)); fi; }
+fact 5
+g() { echo "g: $# $1 $2"; h z; echo "g after: $# $1 $2"; }
+h() { echo "h: $# $1"; }
+g a b
+echo "top: $#"
+cnt() { local n=$1; if [ $n -gt 0 ]; then cnt $((n-1)); fi; echo "n=$n"; }
+cnt 2
+l() { local v=inner; m; echo "l v=$v"; }
+m() { echo "m v=$v"; v=changed; w=new; }
+v=global; l; echo "global v=$v w=$w"
+depth() { if [ $1 -lt 500 ]; then depth $(($1 + 1)); else echo "depth $1"; fi; }
+depth 0`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "120\ng: 2 a b\nh: 1 z\ng after: 2 a b\ntop: 0\nn=0\nn=1\nn=2\nm v=inner\nl v=changed\nglobal v=global w=new\ndepth 500\n"
+	if stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+}
+
+// TestReturn 测试 return 的退出状态
+func TestReturn(t *testing.T) {
+	input := `f() { return 300; }; f || echo "f $?"
+g() { false; return; }; g || echo "g $?"
+k() { for i in 1 2 3; do if [ $i = 2 ]; then return 7; fi; echo "i=$i"; done; echo never; }; k || echo "k $?"
+s() { (return 4); echo "s $?"; }; s
+return 3 || echo "top $?"`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "f 44\ng 1\ni=1\nk 7\ns 4\ntop 2\n"
+	if stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+}
+
+// TestFuncNest 测试 FUNCNEST 限制函数的嵌套层数，超过时中止整条命令（|| 的右边也不执行）
+func TestFuncNest(t *testing.T) {
+	input := `FUNCNEST=3
+r() { echo d; r; }
+r || echo caught`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	var nestErr *FuncNestError
+	if !errors.As(err, &nestErr) || nestErr.Limit != 3 || ExitStatus(err) != 1 {
+		t.Fatalf("期望 FuncNestError，得到 %v", err)
+	}
+	if stdout != "d\nd\nd\n" {
+		t.Errorf("输出 %q，期望 3 行 d", stdout)
+	}
+
+	// 没有设置 FUNCNEST 时，无限递归在默认的层数处中止
+	_, _, err = New().Capture(parser.New(lexer.New("inf() { inf; }; inf")).ParseProgram())
+	if !errors.As(err, &nestErr) || nestErr.Limit != defaultFuncNest {
+		t.Errorf("期望 FuncNestError，得到 %v", err)
+	}
+}
+
+// TestCommandNotFoundHandle 测试找不到命令时调用 command_not_found_handle
+func TestCommandNotFoundHandle(t *testing.T) {
+	input := `command_not_found_handle() { echo "handle $# $*"; x=changed; exit 42; }
+x=orig; gobash_no_such_cmd a "b c" || echo "status $? x=$x"
+gobash_no_such_cmd2 2>/dev/null | tr a-z A-Z
+command_not_found_handle() { echo "inner"; gobash_no_such_cmd3; }
+gobash_no_such_cmd4 2>/dev/null || echo "status $?"`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "handle 3 gobash_no_such_cmd a b c\nstatus 42 x=orig\nHANDLE 1 GOBASH_NO_SUCH_CMD2\ninner\nstatus 127\n"
+	if stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+
+	// 命令名中有路径分隔符时不调用处理函数
+	e := New()
+	err = e.Execute(parser.New(lexer.New("command_not_found_handle() { echo no; }; ./gobash_no_such_cmd")).ParseProgram())
+	if execErr, ok := err.(*ExecutionError); !ok || execErr.Type != ExecutionErrorTypeCommandNotFound {
+		t.Errorf("./gobash_no_such_cmd 应该报告命令未找到，得到 %v", err)
+	}
+}
+
+// TestExitStatusCodes 测试与 bash 兼容的退出码：命令未找到 127、不能执行 126、被信号终止 128+信号编号
+func TestExitStatusCodes(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "noexec.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	type exitCase struct {
+		input string
+		code  int
+	}
+	tests := []exitCase{
+		{"gobash_no_such_cmd", 127},
+		{dir, 126},
+		{"exit 3", 3},
+		{"(exit 4)", 4},
+	}
+	if runtime.GOOS != "windows" {
+		// 没有执行权限的脚本；被 SIGKILL 终止的命令
+		tests = append(tests, exitCase{script, 126}, exitCase{"sh -c 'kill -9 $$'", 137})
+	}
+	for _, tt := range tests {
+		err := New().Execute(parser.New(lexer.New(tt.input)).ParseProgram())
+		if got := ExitStatus(err); got != tt.code {
+			t.Errorf("%s 的退出码 %d，期望 %d（%v）", tt.input, got, tt.code, err)
+		}
+		var shellErr ShellError
+		if !errors.As(err, &shellErr) || shellErr.ExitCode() != tt.code {
+			t.Errorf("%s 的错误应该是退出码为 %d 的 ShellError，得到 %v", tt.input, tt.code, err)
+		}
+	}
+
+	p := parser.New(lexer.New("echo a\nfi\n"))
+	p.ParseProgram()
+	var shellErr ShellError
+	if errs := p.AllErrors(); len(errs) == 0 || !errors.As(errs[0], &shellErr) || shellErr.ExitCode() != 2 || shellErr.Position().Line != 2 {
+		t.Errorf("语法错误应该是第 2 行、退出码为 2 的 ShellError，得到 %v", errs)
+	}
+}
+
+// TestScriptWithoutShebang 测试没有 #! 行的可执行文本文件作为 shell 脚本在子shell中执行，二进制文件报告不能执行
+func TestScriptWithoutShebang(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 上由 builtin.Command 按扩展名选择解释器")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "plain")
+	if err := os.WriteFile(script, []byte("echo \"$0 $# $1\"\nx=changed\nexit 4\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	binary := filepath.Join(dir, "binary")
+	if err := os.WriteFile(binary, []byte("AB\x00CD"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	input := "{\nx=orig\n" + script + " a b\necho \"status $? x=$x\"\n" + binary + "\necho \"status $?\"\n}"
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if want := script + " 2 a\nstatus 4 x=orig\nstatus 126\n"; stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "binary: 无法执行二进制文件") {
+		t.Errorf("错误输出 %q", stderr)
+	}
+}
+
+// TestUnboundVariable 测试 set -u 时展开未定义的变量返回带有变量名的错误
+func TestUnboundVariable(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+	}{
+		{"echo $gobash_unset", "gobash_unset"},
+		{`echo "a${gobash_unset}b"`, "gobash_unset"},
+		{"x=$gobash_unset", "gobash_unset"},
+		{"a=(1); echo ${a[5]}", "a[5]"},
+		{"[ -n \"$gobash_unset\" ]", "gobash_unset"},
+		{"for i in $gobash_unset; do :; done", "gobash_unset"},
+	}
+	for _, tt := range tests {
+		e := New()
+		e.SetOptions(map[string]bool{"u": true})
+		stdout, _, err := e.Capture(parser.New(lexer.New(tt.input)).ParseProgram())
+		var execErr *ExecutionError
+		if !errors.As(err, &execErr) || execErr.Type != ExecutionErrorTypeUnboundVariable || execErr.Message != tt.name {
+			t.Errorf("%s 应该报告 %s 未定义，得到 %v", tt.input, tt.name, err)
+		}
+		if stdout != "" {
+			t.Errorf("%s 不应该执行命令，输出 %q", tt.input, stdout)
+		}
+	}
+
+	// 变量的值恰好包含旧的标记文本时照常展开
+	e := New()
+	e.SetOptions(map[string]bool{"u": true})
+	stdout, _, err := e.Capture(parser.New(lexer.New("x=__UNDEFINED_VAR__y; echo $x ${gobash_unset:-d}")).ParseProgram())
+	if err != nil || stdout != "__UNDEFINED_VAR__y d\n" {
+		t.Errorf("输出 %q，错误 %v", stdout, err)
+	}
+}
+
+// TestUnboundVariableExits 测试非交互式 shell 在 set -u 时展开未定义的变量终止脚本（退出状态为 1），
+// 子shell和命令替换中只终止子shell，交互式 shell 继续执行
+func TestUnboundVariableExits(t *testing.T) {
+	input := "{ (echo $nope); echo \"sub $?\"; x=$(echo $nope); echo \"cs $?\"; if true; then echo \"$nope\"; fi; echo after; }"
+	e := New()
+	e.SetOptions(map[string]bool{"u": true})
+	stdout, stderr, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	var exitErr *ScriptExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 || !isUnboundVariable(exitErr.Err) {
+		t.Errorf("应该以退出状态 1 终止脚本，得到 %v", err)
+	}
+	if stdout != "sub 1\ncs 1\n" || strings.Count(stderr, "nope") != 2 {
+		t.Errorf("输出 %q，标准错误 %q", stdout, stderr)
+	}
+
+	e = New()
+	e.SetInteractive(true)
+	e.SetOptions(map[string]bool{"u": true})
+	stdout, _, _ = e.Capture(parser.New(lexer.New("{ echo $nope; echo after; }")).ParseProgram())
+	if stdout != "after\n" {
+		t.Errorf("交互式 shell 应该继续执行，输出 %q", stdout)
+	}
+}
+
+// TestAssignmentQuoting 测试赋值的值按片段展开：双引号中展开变量并保留空白和单引号，单引号中不展开
+func TestAssignmentQuoting(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`VAR="a 'b' c"; echo "[$VAR]"`, "[a 'b' c]\n"},
+		{`x=X; VAR='a'"b"$x'$x'; echo "[$VAR]"`, "[abX$x]\n"},
+		{`VAR="  a  b  "; echo "[$VAR]"`, "[  a  b  ]\n"},
+		{`VAR="x"y'z' W=a\ b; echo "[$VAR] [$W]"`, "[xyz] [a b]\n"},
+		{`VAR=a=b; echo "[$VAR]"`, "[a=b]\n"},
+		{`VAR=; echo "[$VAR]"`, "[]\n"},
+	}
+	for _, tt := range tests {
+		stdout, _, err := New().Capture(parser.New(lexer.New(tt.input)).ParseProgram())
+		if err != nil || stdout != tt.want {
+			t.Errorf("%s 输出 %q（%v），期望 %q", tt.input, stdout, err, tt.want)
+		}
+	}
+}
+
+// TestBackslashEscapes 测试反斜杠转义：引号外转义任意字符，双引号中只转义 $ ` " \ 和换行
+func TestBackslashEscapes(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`echo a\nb a\ b \$x \" \\`, "anb a b $x \" \\\n"},
+		{`echo "a\nb \$x \" \\ \q"`, "a\\nb $x \" \\ \\q\n"},
+		{"echo a\\\nb", "ab\n"},
+		{"echo `echo a\\\\b` `echo a\\\\\\\\b` \"`echo \\\"q\\\"`\"", "ab a\\b q\n"},
+		{`echo hi > ` + dir + `/a\ b; cat "` + dir + `/a b"`, "hi\n"},
+		{`declare -A m; m[k\ 1]=v; m["k 2"]=w; echo "${m[k 1]} ${m[k\ 2]}"`, "v w\n"},
+		{`case abc in a\*) echo 1;; "a*") echo 2;; a*) echo 3;; esac`, "3\n"},
+		{`case 'a*' in a\*) echo 1;; esac; case 'a?' in 'a?') echo 2;; esac`, "1\n2\n"},
+	}
+	for _, tt := range tests {
+		stdout, _, err := New().Capture(parser.New(lexer.New(tt.input)).ParseProgram())
+		if err != nil || stdout != tt.want {
+			t.Errorf("%s 输出 %q（%v），期望 %q", tt.input, stdout, err, tt.want)
+		}
+	}
+}
+
+// TestLineNumbers 测试 $LINENO、执行中错误消息的行号和执行器错误的位置
+func TestLineNumbers(t *testing.T) {
+	input := `echo "a $LINENO"
+if true; then
+  gobash_no_such_cmd
+  echo "b $LINENO $(echo $LINENO)"
+fi
+f() {
+  echo "f $LINENO"
+}
+f
+  gobash_no_such_cmd2`
+	e := New()
+	e.SetScriptName("t.sh")
+	stdout, stderr, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if want := "a 1\nb 4 4\nf 7\n"; stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+	if !strings.HasPrefix(stderr, "gobash: t.sh: 第3行: ") {
+		t.Errorf("错误消息应该带有脚本名和行号，得到 %q", stderr)
+	}
+	if execErr, ok := err.(*ExecutionError); !ok || execErr.Pos != (lexer.Position{Line: 10, Column: 3}) {
+		t.Errorf("错误的位置应该是第 10 行第 3 列，得到 %v", err)
+	}
+
+	// $LINENO 不传给外部命令，交互式 shell 的错误消息不带行号
+	e = New()
+	e.SetInteractive(true)
+	if _, stderr, _ := e.Capture(parser.New(lexer.New("gobash_no_such_cmd; true")).ParseProgram()); strings.Contains(stderr, "第") {
+		t.Errorf("交互式 shell 的错误消息不应该带行号，得到 %q", stderr)
+	}
+	for _, env := range e.getEnvArray() {
+		if strings.HasPrefix(env, "LINENO=") {
+			t.Errorf("LINENO 不应该传给外部命令")
+		}
+	}
+}
+
+// TestDynamicVariables 测试读取时计算值的 RANDOM、SECONDS、EPOCHSECONDS、EPOCHREALTIME 以及 PPID
+func TestDynamicVariables(t *testing.T) {
+	input := `RANDOM=42; a="$RANDOM ${RANDOM}"; RANDOM=42; b="$RANDOM $((RANDOM + 0))"
+[[ $a == "$b" ]] && echo same-seed
+SECONDS=100; echo $SECONDS
+echo $EPOCHSECONDS $EPOCHREALTIME $PPID
+unset RANDOM; echo "[$RANDOM]"`
+	e := New()
+	stdout, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	lines := strings.Split(stdout, "\n")
+	if len(lines) != 5 || lines[0] != "same-seed" || (lines[1] != "100" && lines[1] != "101") || lines[3] != "[]" {
+		t.Fatalf("输出 %q", stdout)
+	}
+	fields := strings.Fields(lines[2])
+	now := time.Now().Unix()
+	if sec, _ := strconv.ParseInt(fields[0], 10, 64); sec < now-2 || sec > now {
+		t.Errorf("EPOCHSECONDS = %s，当前时间 %d", fields[0], now)
+	}
+	if !regexp.MustCompile(`^\d+\.\d{6}$`).MatchString(fields[1]) {
+		t.Errorf("EPOCHREALTIME 的格式错误: %s", fields[1])
+	}
+	if fields[2] != strconv.Itoa(os.Getppid()) {
+		t.Errorf("PPID = %s，期望 %d", fields[2], os.Getppid())
+	}
+
+	// 每次读取 $RANDOM 得到新的值，都在 0 到 32767 之间；动态变量不传给外部命令
+	e = New()
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		value, _ := e.expandVariable("RANDOM")
+		if n, err := strconv.Atoi(value); err != nil || n < 0 || n > 32767 {
+			t.Fatalf("RANDOM = %q", value)
+		}
+		seen[value] = true
+	}
+	if len(seen) < 2 {
+		t.Error("每次读取 RANDOM 应该得到新的随机数")
+	}
+	for _, env := range e.getEnvArray() {
+		if strings.HasPrefix(env, "RANDOM=") || strings.HasPrefix(env, "SECONDS=") {
+			t.Errorf("%s 不应该传给外部命令", env)
+		}
+	}
+}
+
+// TestVersionVariables 测试 GOBASH_VERSION、BASH_VERSION、BASH_VERSINFO 和 OSTYPE 等平台变量，它们不传给外部命令
+func TestVersionVariables(t *testing.T) {
+	input := `echo "$GOBASH_VERSION ${BASH_VERSINFO[0]}.${BASH_VERSINFO[1]} ${BASH_VERSION:0:3}"
+case $OSTYPE in linux-gnu) echo linux;; darwin*) echo darwin;; msys) echo windows;; *) echo other;; esac
+[[ $MACHTYPE == "$HOSTTYPE"-*-"$OSTYPE" && ${BASH_VERSINFO[5]} == "$MACHTYPE" ]] && echo machtype`
+	e := New()
+	stdout, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	platform := map[string]string{"linux": "linux", "darwin": "darwin", "windows": "windows"}[runtime.GOOS]
+	if platform == "" {
+		platform = "other"
+	}
+	if want := Version + " 5.2 5.2\n" + platform + "\nmachtype\n"; stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+	for _, env := range e.getEnvArray() {
+		if strings.HasPrefix(env, "OSTYPE=") || strings.HasPrefix(env, "BASH_VERSION=") {
+			t.Errorf("%s 不应该传给外部命令", env)
+		}
+	}
+}
+
+// TestCallStack 测试 FUNCNAME、BASH_SOURCE 和 BASH_LINENO 数组，不带下标时与其他数组一样是第一个元素
+func TestCallStack(t *testing.T) {
+	input := `echo "top ${#FUNCNAME[@]} ${BASH_SOURCE[*]} ${BASH_LINENO[*]}"
+inner() {
+  echo "inner ${FUNCNAME[*]} | ${BASH_SOURCE[*]} | ${BASH_LINENO[*]} | $LINENO"
+}
+outer() {
+  inner
+  echo "outer ${FUNCNAME[0]} ${#FUNCNAME[@]} $LINENO $FUNCNAME $BASH_LINENO $BASH_SOURCE"
+}
+outer
+echo "$(inner)"
+echo "after ${#FUNCNAME[@]} $LINENO"
+a=(x y); echo "[$a]" [${a}]`
+	e := New()
+	e.SetScriptName("t.sh")
+	stdout, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	want := "top 0 t.sh 0\n" +
+		"inner inner outer main | t.sh t.sh t.sh | 6 9 0 | 3\n" +
+		"outer outer 2 7 outer 9 t.sh\n" +
+		"inner inner main | t.sh t.sh | 10 0 | 3\n" +
+		"after 0 11\n" +
+		"[x] [x]\n"
+	if stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+}
+
+// TestTraps 测试 DEBUG、ERR、EXIT 和信号陷阱的执行点
+func TestTraps(t *testing.T) {
+	input := `{
+trap 'echo "ERR $? $LINENO $BASH_COMMAND"' ERR
+false
+if false; then :; fi
+false || true
+f() { false; return 3; }
+f
+( exit 4 )
+echo "status $?"
+trap 'echo "D $BASH_COMMAND"' DEBUG
+y=2
+trap - DEBUG
+trap - ERR
+false
+true
+}`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	want := "ERR 1 3 false\nERR 3 7 return 3\nERR 4 8 ( exit 4 )\nstatus 4\nD y=2\nD trap - DEBUG\n"
+	if stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+
+	// ERR 陷阱在 set -e 退出之前执行，其中的 exit 结束脚本
+	e := New()
+	e.SetOptions(map[string]bool{"e": true})
+	stdout, _, err = e.Capture(parser.New(lexer.New("trap 'echo cleanup; exit 9' ERR\nfalse\necho never")).ParseProgram())
+	if ExitStatus(err) != 9 || stdout != "cleanup\n" {
+		t.Errorf("输出 %q，错误 %v", stdout, err)
+	}
+
+	// 子shell 和命令替换结束时执行自己的 EXIT 陷阱，不继承当前shell 的 EXIT 陷阱
+	input = `{
+trap 'echo outer' EXIT
+( trap 'echo sub $?' EXIT; exit 2 )
+x=$(trap 'echo cs' EXIT; echo v)
+echo "$x"
+( echo none )
+}`
+	stdout, _, err = New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if want := "sub 2\nv\ncs\nnone\n"; err != nil || stdout != want {
+		t.Errorf("输出 %q（%v），期望 %q", stdout, err, want)
+	}
+
+	// 收到设置了陷阱的信号后在下一个命令之前执行陷阱，shell 不会被终止
+	if runtime.GOOS != "windows" {
+		input = `{
+trap 'got=TERM' TERM
+kill -TERM $$
+i=0
+while [ -z "$got" ] && [ $i -lt 100000 ]; do i=$((i + 1)); done
+trap - TERM
+echo "got=$got"
+}`
+		stdout, _, err = New().Capture(parser.New(lexer.New(input)).ParseProgram())
+		if err != nil || stdout != "got=TERM\n" {
+			t.Errorf("输出 %q（%v）", stdout, err)
+		}
+	}
+}
+
+// TestDryRun 测试试运行：外部命令和修改文件的命令只输出，其他命令正常执行
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	input := `{
+name=world
+dir=$(mktemp -d)
+echo "hello $name dir=$dir"
+rm -rf "build dir"
+sh -c 'exit 3' && echo ok
+echo report > out.txt
+{ echo a; } >> out.txt
+echo quiet > /dev/null
+x=$(printf '%s' abc | tr a-z A-Z)
+echo "$x"
+sed -i 's/a/b/' file
+env FOO=1 make
+}`
+	e := New()
+	e.SetEnv("PWD", dir)
+	e.SetDryRun(true)
+	stdout, stderr, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if want := "hello world dir=\nok\nABC\n"; stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+	want := "[dry-run] mktemp -d\n[dry-run] rm -rf 'build dir'\n[dry-run] sh -c 'exit 3'\n[dry-run] echo report > out.txt\n" +
+		"[dry-run] sed -i s/a/b/ file\n[dry-run] env FOO=1 make\n"
+	if stderr != want {
+		t.Errorf("错误输出 %q，期望 %q", stderr, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.txt")); !os.IsNotExist(err) {
+		t.Errorf("试运行不应该创建文件: %v", err)
+	}
+}
+
+func TestDoubleBracketStatus(t *testing.T) {
+	e := New()
+	err := e.Execute(parser.New(lexer.New("[[ a == b ]]")).ParseProgram())
+	if _, ok := err.(*builtin.StatusError); !ok || ExitStatus(err) != 1 {
+		t.Fatalf("[[ ]] 条件为假应该返回退出状态 1 的 StatusError，实际为 %#v", err)
+	}
+
+	input := `{
+[[ a == b ]] || echo "false $?"
+if [[ -n x && a != b ]]; then echo true; fi
+i=0
+while [[ $i -lt 3 ]]; do i=$((i + 1)); done
+echo "$i"
+}`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if want := "false 1\ntrue\n3\n"; stdout != want || stderr != "" {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, want)
+	}
+}
+
+func TestDoubleBracketExpression(t *testing.T) {
+	input := `{
+x='a*b'
+[[ $x == a* ]] && echo "pattern"
+[[ axb == "$x" ]] || echo "quoted pattern"
+[[ abc == a\*c ]] || echo "escaped pattern"
+[[ $x != a?b ]] || echo "not equal"
+v=foo123
+[[ $v =~ ^([a-z]+)([0-9]+)$ ]] && echo "match ${BASH_REMATCH[1]} ${BASH_REMATCH[2]}"
+[[ a.c =~ a"."c ]] && [[ abc =~ a"."c ]] || echo "quoted regex"
+[[ x =~ "(" ]] || echo "literal paren $?"
+[[ x =~ ( ]] || echo "invalid regex $?"
+[[ a < b && ! b < a ]] && echo "less"
+[[ 1+1 -eq 2 && 3 -gt 2 ]] && echo "arithmetic"
+[[ ( -n x || -z x ) && -d / && ! -f / ]] && echo "grouped"
+[[ $undefined || -v undefined ]] || echo "empty"
+[[ "-f" ]] && echo "quoted operator"
+}`
+	stdout, stderr, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	want := "pattern\nquoted pattern\nescaped pattern\nnot equal\nmatch foo 123\nquoted regex\n" +
+		"literal paren 1\ninvalid regex 2\nless\narithmetic\ngrouped\nempty\nquoted operator\n"
+	if stdout != want || stderr != "" {
+		t.Errorf("输出 %q，错误输出 %q，期望 %q", stdout, stderr, want)
+	}
+}
+
+func TestCommandSubstitutionLimit(t *testing.T) {
+	input := `{
+big=$(seq 1 20000)
+echo "${#big}"
+x=$(echo 12345; echo 67890)
+echo "$x $?"
+y=$(echo ok)
+echo "$y $?"
+}`
+	e := New()
+	stdout, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if want := "108893\n12345\n67890 0\nok 0\n"; stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+
+	e = New()
+	e.SetSubstitutionLimit(8)
+	stdout, stderr, _ := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if want := "7\n12345\n67 1\nok 0\n"; stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "8") {
+		t.Errorf("错误输出 %q 应该报告超过限制", stderr)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	input := `{
+f() { echo "$1"; }
+for i in 1 2 3; do
+  f $i
+done
+x=$(echo done)
+}`
+	e := New()
+	profiler := NewProfiler()
+	e.SetProfiler(profiler)
+	if _, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram()); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, entry := range profiler.Entries() {
+		counts[entry.Command] = entry.Count
+	}
+	if counts["f $i"] != 3 || counts["echo \"$1\""] != 3 || counts["echo done"] != 1 {
+		t.Errorf("统计 %v", counts)
+	}
+	entries := profiler.Entries()
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Total > entries[i-1].Total {
+			t.Errorf("没有按总耗时排序: %v", entries)
+		}
+	}
+	var report strings.Builder
+	profiler.WriteReport(&report, 1)
+	if lines := strings.Split(strings.TrimSpace(report.String()), "\n"); len(lines) != 3 {
+		t.Errorf("报告 %q 应该只有一条统计", report.String())
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	input := `f() {
+  echo in f
+}
+if [ "$x" = y ]; then
+  f
+else
+  echo no
+fi
+for i in 1 2; do
+  echo $i | cat
+done
+`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	e := New()
+	coverage := NewCoverage()
+	e.SetCoverage(coverage)
+	e.SetScriptName("test.sh")
+	e.CoverProgram(program)
+	if _, _, err := e.Capture(program); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	files := coverage.Files()
+	if len(files) != 1 || files[0].Script != "test.sh" {
+		t.Fatalf("覆盖率 %v", files)
+	}
+	want := map[int]int{1: 1, 2: 0, 4: 1, 5: 0, 7: 1, 9: 1, 10: 4}
+	if fmt.Sprint(files[0].Hits) != fmt.Sprint(want) {
+		t.Errorf("执行次数 %v，期望 %v", files[0].Hits, want)
+	}
+	var lcov strings.Builder
+	if err := coverage.WriteLCOV(&lcov); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(lcov.String(), "SF:test.sh\nDA:1,1\nDA:2,0\n") || !strings.Contains(lcov.String(), "LF:7\nLH:5\nend_of_record\n") {
+		t.Errorf("LCOV %q", lcov.String())
+	}
+	var report strings.Builder
+	coverage.WriteReport(&report)
+	if !strings.Contains(report.String(), ": 2, 5\n") {
+		t.Errorf("报告 %q 应该列出未覆盖的行", report.String())
+	}
+}
+
 func TestExecuteCommandWithRedirect(t *testing.T) {
 	e := New()
 	
@@ -311,6 +1341,168 @@ func TestJobManager(t *testing.T) {
 	}
 }
 
+// TestBackgroundStatements 测试后台执行管道、循环、子shell、命令组和函数：每个后台语句是一个作业，
+// $! 是作业的进程号，wait 返回作业的退出状态，kill 终止作业
+func TestBackgroundStatements(t *testing.T) {
+	input := `echo a | tr a A &
+wait
+for i in 1 2; do echo L$i; done &
+wait $!
+( echo sub; exit 3 ) &
+p=$!; wait $p || echo "st=$?"
+f() { echo "fn $1"; }; f x &
+wait %1 && echo "st=$?"
+while true; do :; done &
+kill -0 $! && echo alive
+kill %1; wait %1 || echo "st=$?"
+kill -0 $! 2>/dev/null || echo gone
+wait 12345 2>/dev/null || echo "st=$?"`
+	e := New()
+	stdout, _, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "A\nL1\nL2\nsub\nst=3\nfn x\nst=0\nalive\nst=143\ngone\nst=127\n"
+	if stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+	if jobs := e.GetJobManager().GetAllJobs(); len(jobs) != 0 {
+		t.Errorf("wait 之后不应该还有作业，得到 %d 个", len(jobs))
+	}
+}
+
+// TestJobNotifications 测试结束的后台作业在 NotifyJobs 时通知一次并从作业表中移除，set -b 时立即通知
+func TestJobNotifications(t *testing.T) {
+	e := New()
+	e.SetInteractive(true)
+	var stderr strings.Builder
+	e.SetStdio(nil, nil, &stderr)
+	if err := e.Execute(parser.New(lexer.New("(exit 3) &\ntrue &")).ParseProgram()); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	jm := e.GetJobManager()
+	for deadline := time.Now().Add(5 * time.Second); len(jm.GetAllJobs()) > 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stderr.Reset()
+	e.NotifyJobs()
+	expected := "[1]-  Exit 3                  ( exit 3 )\n[2]+  Done                    true\n"
+	if stderr.String() != expected {
+		t.Errorf("作业通知 %q，期望 %q", stderr.String(), expected)
+	}
+	stderr.Reset()
+	e.NotifyJobs()
+	if _, ok := jm.GetJob(1); ok || stderr.String() != "" {
+		t.Errorf("通知过的作业应该从作业表中移除，再次通知输出 %q", stderr.String())
+	}
+
+	// set -b：作业结束时立即通知
+	notified := make(chan string, 1)
+	e.SetStdio(nil, nil, chanWriter(notified))
+	e.SetOptions(map[string]bool{"b": true})
+	if err := e.Execute(parser.New(lexer.New("false &")).ParseProgram()); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	<-notified // [1] pid
+	select {
+	case line := <-notified:
+		if line != "[1]+  Exit 1                  false\n" {
+			t.Errorf("set -b 的通知 %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("set -b 时作业结束后应该立即通知")
+	}
+}
+
+// TestDisown 测试 disown 从作业表中移除作业，Hangup（shopt -s huponexit）只向没有 disown 的作业发送 SIGHUP
+func TestDisown(t *testing.T) {
+	input := `sleep 1 &
+sleep 1 &
+sleep 1 &
+disown %1 && echo ok
+disown -h %2
+disown %9 || echo "st=$?"`
+	e := New()
+	stdout, stderr, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if stdout != "ok\nst=1\n" {
+		t.Errorf("输出 %q", stdout)
+	}
+	// 非交互式 shell 启动后台作业时不输出 [1] pid
+	if strings.Contains(stderr, "[1]") {
+		t.Errorf("标准错误 %q", stderr)
+	}
+	jm := e.GetJobManager()
+	if _, ok := jm.GetJob(1); ok || len(jm.GetAllJobs()) != 2 {
+		t.Errorf("disown %%1 应该只移除作业 1，还有 %d 个作业", len(jm.GetAllJobs()))
+	}
+
+	jm.Hangup()
+	job2, _ := jm.GetJob(2)
+	job3, _ := jm.GetJob(3)
+	job3.Wait()
+	if code := job3.ExitCode(); code != 129 {
+		t.Errorf("收到 SIGHUP 的作业退出状态 %d，期望 129", code)
+	}
+	if job2.GetStatus() != JobRunning {
+		t.Error("disown -h 的作业不应该收到 SIGHUP")
+	}
+	if err := e.Execute(parser.New(lexer.New("disown -a")).ParseProgram()); err != nil || len(jm.GetAllJobs()) != 0 {
+		t.Errorf("disown -a 之后不应该还有作业（%v）", err)
+	}
+}
+
+// chanWriter 把每次写入的内容发送到 channel
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+// TestCoproc 测试协进程：通过 COPROC 数组中的文件描述符与命令双向通信
+func TestCoproc(t *testing.T) {
+	input := `coproc cat
+echo ping >&${COPROC[1]}
+read reply <&${COPROC[0]}
+echo "reply=$reply fds=${#COPROC[@]}"
+coproc DBL { while read x; do echo $((x * 2)); done; }
+for i in 1 2 3; do
+  echo $i >&${DBL[1]}
+  read -u ${DBL[0]} y
+  echo "y=$y"
+done
+kill $DBL_PID
+wait $DBL_PID || echo "st=$?"
+test -n "$COPROC_PID" && echo pid`
+	stdout, _, err := New().Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	expected := "reply=ping fds=2\ny=2\ny=4\ny=6\nst=143\npid\n"
+	if stdout != expected {
+		t.Errorf("输出 %q，期望 %q", stdout, expected)
+	}
+}
+
+// TestJobControlWithoutTerminal 测试标准输入不是终端时不启用作业控制
+func TestJobControlWithoutTerminal(t *testing.T) {
+	e := New()
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if e.EnableJobControl(f) {
+		t.Error("普通文件不应该启用作业控制")
+	}
+	if group := e.newProcessGroup(true); group != nil {
+		t.Error("没有启用作业控制时不应该创建进程组")
+	}
+}
+
 // TestHereDocument 测试 Here-document 功能
 func TestHereDocument(t *testing.T) {
 	tests := []struct {
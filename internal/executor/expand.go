@@ -0,0 +1,227 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"os"
+	"strings"
+)
+
+// 单词展开
+//
+// 双引号字符串、here-document 正文、参数展开中的单词（${x:-word}）、算术表达式和算术函数的字符串参数
+// 共用词法分析器的扫描器：lexer.ScanExpansion 识别 $name、${...}、$(...)、$((...)) 和 `...` 的范围，
+// 其中的引号、反斜杠和嵌套的展开按同样的规则跳过；expandPart 对识别出的一个展开求值，
+// expandText 按引号规则处理展开之外的文本
+
+// quoteMode 展开文本时引号和反斜杠的处理规则
+type quoteMode int
+
+const (
+	quoteDouble     quoteMode = iota // 双引号中：反斜杠只转义 $ ` " \ 和换行，单引号是普通字符
+	quoteHereDoc                     // here-document 正文：反斜杠只转义 $ ` \ 和换行，引号都是普通字符
+	quoteNone                        // 未加引号的单词：去掉引号，单引号中不展开，反斜杠转义任意字符
+	quoteDoubleWord                  // 双引号中参数展开的单词（"${x:-"a b"}"）：与双引号中相同，但其中的双引号会去掉
+)
+
+// escapable 返回反斜杠在该模式下可以转义的字符
+func (m quoteMode) escapable() string {
+	switch m {
+	case quoteDouble, quoteDoubleWord:
+		return "$`\"\\\n"
+	case quoteHereDoc:
+		return "$`\\\n"
+	}
+	return ""
+}
+
+// expandText 展开文本中的变量、参数展开、命令替换和算术展开，并按 mode 处理引号和反斜杠
+// 设置了 -u 选项时，遇到未定义的变量返回错误
+func (e *Executor) expandText(s string, mode quoteMode) (string, error) {
+	if !strings.ContainsAny(s, "$`\\'\"") {
+		// 没有展开、引号和反斜杠的文本（最常见的情况）原样返回
+		return s, nil
+	}
+	segments := compiledTexts.get(textKey{s, mode}, func() []textSegment { return compileText(s, mode) })
+	if len(segments) == 1 && segments[0].exp == nil {
+		return segments[0].literal, nil
+	}
+	var result strings.Builder
+	for _, seg := range segments {
+		if seg.exp == nil {
+			result.WriteString(seg.literal)
+			continue
+		}
+		value, err := e.expandPart(*seg.exp, seg.quoted)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(value)
+	}
+	return result.String(), nil
+}
+
+// textSegment 编译后的文本片段：展开之外的文本（已按引号规则处理），或者一个展开
+type textSegment struct {
+	literal string
+	exp     *lexer.Expansion
+	quoted  bool // 展开出现在双引号或 here-document 中
+}
+
+// textKey 编译文本的缓存键
+type textKey struct {
+	text string
+	mode quoteMode
+}
+
+// compiledTexts 编译过的文本。循环和函数体中的单词每次执行都要展开，扫描引号和展开的范围只做一次
+var compiledTexts = newCache[textKey, []textSegment]()
+
+// compileText 按 mode 的引号规则把文本分为文本片段和展开，相邻的文本片段合并为一个
+func compileText(s string, mode quoteMode) []textSegment {
+	var segments []textSegment
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, textSegment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			next := s[i+1]
+			if mode != quoteNone && strings.IndexByte(mode.escapable(), next) < 0 {
+				// 不能转义的字符保留反斜杠（如双引号中的 \n）
+				literal.WriteByte(c)
+				literal.WriteByte(next)
+			} else if next != '\n' {
+				// 反斜杠加换行是续行，两个字符都去掉
+				literal.WriteByte(next)
+			}
+			i += 2
+		case c == '\'' && mode == quoteNone:
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				literal.WriteString(s[i+1:])
+				i = len(s)
+				continue
+			}
+			literal.WriteString(s[i+1 : i+1+end])
+			i += end + 2
+		case c == '"' && (mode == quoteNone || mode == quoteDoubleWord):
+			end := lexer.SkipQuoted(s, i)
+			if end < 0 {
+				end = len(s) + 1
+			}
+			for _, seg := range compileText(s[i+1:end-1], quoteDouble) {
+				if seg.exp == nil {
+					literal.WriteString(seg.literal)
+				} else {
+					flush()
+					segments = append(segments, seg)
+				}
+			}
+			i = end
+		case c == '$' || c == '`':
+			exp, end, ok := lexer.ScanExpansion(s, i, mode == quoteDouble || mode == quoteDoubleWord)
+			if !ok {
+				literal.WriteByte(c)
+				i++
+				continue
+			}
+			flush()
+			segments = append(segments, textSegment{exp: &exp, quoted: mode != quoteNone})
+			i = end
+		default:
+			literal.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	if len(segments) == 0 {
+		segments = append(segments, textSegment{})
+	}
+	return segments
+}
+
+// expandPart 对 lexer.ScanExpansion 识别出的展开求值，quoted 表示展开出现在双引号或 here-document 中
+func (e *Executor) expandPart(exp lexer.Expansion, quoted bool) (string, error) {
+	switch exp.Kind {
+	case lexer.ExpansionParameter:
+		return e.expandVariable(exp.Body)
+	case lexer.ExpansionBraced:
+		return e.expandBraced(exp.Body, quoted)
+	case lexer.ExpansionCommand:
+		return e.executeCommandSubstitution(exp.Body), nil
+	default:
+		return e.evaluateArithmetic(exp.Body)
+	}
+}
+
+// expandBraced 展开 ${...}，body 是大括号中的内容
+// 展开出错时（如 ${x:?msg}）打印错误并展开为空字符串，只有 set -u 的未定义变量错误返回给调用者
+func (e *Executor) expandBraced(body string, quoted bool) (string, error) {
+	if len(body) > 1 && body[0] == '!' {
+		// ${!VAR} 间接引用
+		return e.expandIndirect(body[1:]), nil
+	}
+	// 缓存的是解析结果的副本，下面设置 Flags 不影响缓存
+	pe := compiledParams.get(body, func() parser.ParamExpandExpression { return *parser.ParseParamExpand(body) })
+	if pe.Op == "" && pe.Word == "" && (len(pe.VarName) <= 1 || pe.VarName[0] != '#') {
+		return e.expandVariable(pe.VarName)
+	}
+	if quoted {
+		pe.Flags |= int(ExpandFlagQuoted)
+	}
+	value, err := e.expandParamExpression(&pe)
+	if isUnboundVariable(err) {
+		return "", err
+	}
+	if err != nil {
+		fmt.Fprintf(e.Stdio().Stderr, "%s: %v\n", e.errorPrefix(), err)
+	}
+	return value, nil
+}
+
+// expandVariable 展开变量 name 的值：特殊参数（$#、$@、$? 等）、位置参数、数组元素（arr[i]）、
+// 数组（与 bash 相同，不带下标时是第一个元素 arr[0]）、名称引用和普通变量；设置了 -u 选项时，未定义的变量返回错误
+func (e *Executor) expandVariable(name string) (string, error) {
+	switch name {
+	case "#", "?", "!":
+		if value, ok := e.env[name]; ok {
+			return value, nil
+		}
+		return "0", nil
+	case "@", "*":
+		return e.env["@"], nil
+	case "$":
+		return fmt.Sprintf("%d", os.Getpid()), nil
+	case "0":
+		if value, ok := e.env["0"]; ok {
+			return value, nil
+		}
+		return os.Args[0], nil
+	}
+	if strings.Contains(name, "[") && strings.HasSuffix(name, "]") {
+		return e.getArrayElement(e.resolveNameref(name))
+	}
+	name = e.resolveNameref(name)
+	e.refreshDynamic(name)
+	if _, ok := e.arrays[name]; ok {
+		if value, ok := e.arrayElement(name, 0); ok {
+			return value, nil
+		}
+	} else if value, ok := e.env[name]; ok {
+		return value, nil
+	}
+	if e.options["u"] {
+		return "", unboundVariableError(name)
+	}
+	return "", nil
+}
+
+// compiledParams 解析过的 ${...}（键是大括号中的内容）
+var compiledParams = newCache[string, parser.ParamExpandExpression]()
@@ -0,0 +1,245 @@
+package executor
+
+import (
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"sort"
+	"strings"
+)
+
+// 单词展开为字段
+//
+// for 循环的单词列表按 bash 的规则展开为多个字段：先进行变量、参数展开、命令替换和算术展开，
+// 不在引号中的展开结果按 IFS 进行单词分割，然后对含有不在引号中的通配符的字段进行路径名展开（没有匹配的文件时保留原样）。
+// "$@" 和 "${arr[@]}" 展开为每个元素一个字段，没有元素时不产生字段；不在引号中的 $@、$*、${arr[@]} 和 ${arr[*]}
+// 的每个元素分别进行单词分割。展开为空、也没有引号的单词不产生字段
+
+// fieldPart 字段中的一段文本，quoted 表示它来自引号中（其中的通配符按字面意义匹配）
+type fieldPart struct {
+	text   string
+	quoted bool
+}
+
+// fieldBuilder 展开单词时逐段构造字段
+type fieldBuilder struct {
+	fields  [][]fieldPart
+	current []fieldPart
+	started bool // 当前字段有文本或引号（"" 也产生一个空字段）
+}
+
+// add 把一段文本加到当前字段
+func (b *fieldBuilder) add(text string, quoted bool) {
+	b.current = append(b.current, fieldPart{text: text, quoted: quoted})
+	b.started = b.started || quoted || text != ""
+}
+
+// end 结束当前字段，之后的文本属于新的字段
+func (b *fieldBuilder) end() {
+	if b.started {
+		b.fields = append(b.fields, b.current)
+	}
+	b.current, b.started = nil, false
+}
+
+// expandFields 展开单词列表，返回单词分割和路径名展开之后的所有字段
+func (e *Executor) expandFields(words []parser.Expression) ([]string, error) {
+	var result []string
+	for _, word := range words {
+		b := &fieldBuilder{}
+		if err := e.expandWordParts(b, word); err != nil {
+			return nil, err
+		}
+		b.end()
+		for _, field := range b.fields {
+			result = append(result, e.globField(field)...)
+		}
+	}
+	return result, nil
+}
+
+// expandWordParts 展开单词的各个片段，加到 b 中
+func (e *Executor) expandWordParts(b *fieldBuilder, word parser.Expression) error {
+	switch w := word.(type) {
+	case *parser.ConcatExpression:
+		for _, part := range w.Parts {
+			if err := e.expandWordParts(b, part); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parser.StringLiteral:
+		if w.IsQuote && strings.Contains(w.Value, "@") {
+			// 双引号中可能有 "$@" 或 "${arr[@]}"，逐个展开
+			return e.expandQuotedParts(b, w.Value)
+		}
+		// 单引号字符串、$'...'、反斜杠转义的字符和没有 @ 的双引号字符串作为一个整体
+		value, err := e.evaluateExpression(w)
+		if err != nil {
+			return err
+		}
+		b.add(value, true)
+		return nil
+	case *parser.Variable:
+		if values, ok := e.expansionList(w.Name); ok {
+			e.addElements(b, values, false)
+			return nil
+		}
+	case *parser.ParamExpandExpression:
+		if w.Op == "" {
+			if values, ok := e.expansionList(w.VarName + w.Word); ok {
+				e.addElements(b, values, false)
+				return nil
+			}
+		}
+	case *parser.Identifier:
+		// 不在引号中的文本（包括波浪号展开的结果）不进行单词分割
+		value, err := e.evaluateExpression(w)
+		if err != nil {
+			return err
+		}
+		b.add(value, false)
+		return nil
+	}
+
+	// 不在引号中的变量、参数展开、命令替换和算术展开，结果进行单词分割
+	value, err := e.evaluateExpression(word)
+	if err != nil {
+		return err
+	}
+	e.addSplit(b, value)
+	return nil
+}
+
+// expandQuotedParts 展开双引号字符串 s 的内容，其中的 "$@"、"${arr[@]}" 每个元素一个字段
+func (e *Executor) expandQuotedParts(b *fieldBuilder, s string) error {
+	segments := compiledTexts.get(textKey{s, quoteDouble}, func() []textSegment { return compileText(s, quoteDouble) })
+	for _, seg := range segments {
+		if seg.exp == nil {
+			b.add(seg.literal, true)
+			continue
+		}
+		if values, ok := e.quotedExpansionList(*seg.exp); ok {
+			e.addElements(b, values, true)
+			continue
+		}
+		value, err := e.expandPart(*seg.exp, true)
+		if err != nil {
+			return err
+		}
+		b.add(value, true)
+	}
+	return nil
+}
+
+// quotedExpansionList 如果双引号中的展开是 $@、${@} 或 ${arr[@]}，返回它的各个元素
+func (e *Executor) quotedExpansionList(exp lexer.Expansion) ([]string, bool) {
+	switch exp.Kind {
+	case lexer.ExpansionParameter:
+		if exp.Body == "@" {
+			return e.positionalParams(), true
+		}
+	case lexer.ExpansionBraced:
+		pe := compiledParams.get(exp.Body, func() parser.ParamExpandExpression { return *parser.ParseParamExpand(exp.Body) })
+		if pe.Op == "" && (pe.VarName == "@" || pe.Word == "[@]") {
+			return e.expansionList(pe.VarName + pe.Word)
+		}
+	}
+	return nil, false
+}
+
+// expansionList 如果 name 是 @、*、arr[@] 或 arr[*]，返回展开的各个元素
+// 不是数组的变量 ${x[@]} 展开为它的值，未设置的变量没有元素
+func (e *Executor) expansionList(name string) ([]string, bool) {
+	if name == "@" || name == "*" {
+		return e.positionalParams(), true
+	}
+	arrName := strings.TrimSuffix(strings.TrimSuffix(name, "[@]"), "[*]")
+	if arrName == name || arrName == "" || !isNameStart(arrName[0]) {
+		return nil, false
+	}
+	arrName = e.resolveNameref(arrName)
+	if values, ok := e.assocArrays[arrName]; ok && e.arrayTypes[arrName] == "assoc" {
+		// 与 ${!arr[@]} 相同按键的顺序
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		elements := make([]string, len(keys))
+		for i, key := range keys {
+			elements[i] = values[key]
+		}
+		return elements, true
+	}
+	if arr, ok := e.arrays[arrName]; ok {
+		return arr, true
+	}
+	if value, ok := e.env[arrName]; ok {
+		return []string{value}, true
+	}
+	return nil, true
+}
+
+// addElements 把多个元素加到 b 中：第一个元素接在当前字段之后，之后的每个元素开始新的字段；
+// 不在引号中时每个元素再进行单词分割
+func (e *Executor) addElements(b *fieldBuilder, values []string, quoted bool) {
+	for i, value := range values {
+		if i > 0 {
+			b.end()
+		}
+		if quoted {
+			b.add(value, true)
+		} else {
+			e.addSplit(b, value)
+		}
+	}
+}
+
+// addSplit 按 IFS 分割不在引号中的展开结果 value，加到 b 中
+// 开头的分隔符结束之前的字段，末尾的分隔符结束最后的字段；IFS 为空字符串时不分割
+func (e *Executor) addSplit(b *fieldBuilder, value string) {
+	ifs, ok := e.env["IFS"]
+	if !ok {
+		ifs = " \t\n"
+	}
+	if ifs == "" || value == "" {
+		b.add(value, false)
+		return
+	}
+	if strings.ContainsAny(value[:1], ifs) {
+		b.end()
+	}
+	words := e.wordSplit(value)
+	for i, word := range words {
+		if i > 0 {
+			b.end()
+		}
+		b.add(word, false)
+	}
+	if len(words) > 0 && strings.ContainsAny(value[len(value)-1:], ifs) {
+		b.end()
+	}
+}
+
+// globField 对字段进行路径名展开，返回匹配的文件；字段中没有不在引号中的通配符或者没有匹配时返回字段本身
+func (e *Executor) globField(field []fieldPart) []string {
+	var text, pattern strings.Builder
+	hasGlob := false
+	for _, part := range field {
+		text.WriteString(part.text)
+		if part.quoted {
+			pattern.WriteString(escapePattern(part.text))
+			continue
+		}
+		pattern.WriteString(part.text)
+		hasGlob = hasGlob || strings.ContainsAny(part.text, "*?[")
+	}
+	if !hasGlob {
+		return []string{text.String()}
+	}
+	matches := e.pathnameExpand(pattern.String())
+	if len(matches) == 1 && matches[0] == pattern.String() {
+		return []string{text.String()}
+	}
+	return matches
+}
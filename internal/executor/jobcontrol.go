@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// processGroup 一个作业（单个外部命令或整个管道）的进程组，只在启用作业控制时使用
+// 第一个启动的外部命令成为进程组组长，之后启动的命令加入同一个进程组；前台作业的进程组在命令启动时获得终端
+type processGroup struct {
+	mu         sync.Mutex
+	pgid       int                 // 进程组 ID，0 表示还没有命令启动
+	foreground bool                // 是否是前台作业
+	tty        *os.File            // 控制终端
+	stopped    chan syscall.Signal // 进程组中的命令被停止（Ctrl+Z、读终端的后台作业等）时收到停止它的信号
+}
+
+// EnableJobControl 在终端 tty 上启用作业控制（交互式 shell 使用，set +m 可以关闭）
+// 每个作业在自己的进程组中运行，前台作业的进程组获得终端：Ctrl+C 和 Ctrl+Z 只发给前台作业，后台作业读终端时被停止而不会抢走终端。
+// tty 不是终端、shell 不在终端的前台进程组中或者系统不支持时返回 false（目前只支持 Linux）
+func (e *Executor) EnableJobControl(tty *os.File) bool {
+	if !canControlTerminal(tty) {
+		return false
+	}
+	e.terminal = tty
+	return true
+}
+
+// newProcessGroup 为新作业创建进程组，没有启用作业控制（或已经用 set +m 关闭）时返回 nil
+func (e *Executor) newProcessGroup(foreground bool) *processGroup {
+	if e.terminal == nil || !e.options["m"] {
+		return nil
+	}
+	return &processGroup{foreground: foreground, tty: e.terminal, stopped: make(chan syscall.Signal, 1)}
+}
+
+// notifyStopped 通知作业它的一个命令被信号 sig 停止（管道中的多个命令同时停止时只通知一次）
+func (g *processGroup) notifyStopped(sig syscall.Signal) {
+	select {
+	case g.stopped <- sig:
+	default:
+	}
+}
+
+// startProcess 启动外部命令，group 不为 nil 时命令在作业的进程组中运行
+func startProcess(cmd *exec.Cmd, group *processGroup) error {
+	if group == nil {
+		setProcessGroup(cmd, nil)
+		return cmd.Start()
+	}
+	// 管道中的命令并发启动，加锁保证只有一个命令成为进程组组长
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	setProcessGroup(cmd, group)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if group.pgid == 0 {
+		group.pgid = cmd.Process.Pid
+	}
+	return nil
+}
+
+// waitProcess 等待外部命令结束；命令属于作业时，它被停止后通知作业，继续运行后接着等待
+func waitProcess(cmd *exec.Cmd, group *processGroup) error {
+	if group != nil {
+		for {
+			sig, ok := waitStopped(cmd.Process.Pid)
+			if !ok {
+				break
+			}
+			group.notifyStopped(sig)
+		}
+	}
+	return cmd.Wait()
+}
+
+// reclaimTerminal 前台作业结束或停止后，shell 收回终端
+func (g *processGroup) reclaimTerminal() {
+	if g.foreground {
+		_ = setTerminalForeground(g.tty, shellProcessGroup())
+	}
+}
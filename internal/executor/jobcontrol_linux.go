@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// canControlTerminal 判断 tty 是否是终端并且 shell 在它的前台进程组中
+func canControlTerminal(tty *os.File) bool {
+	if tty == nil {
+		return false
+	}
+	var pgid int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgid))); errno != 0 {
+		return false
+	}
+	return int(pgid) == syscall.Getpgrp()
+}
+
+// setProcessGroup 让命令加入作业的进程组（还没有进程组时自己成为组长），前台作业在子进程中获得终端，
+// 子进程在 exec 之前设置终端的前台进程组，避免命令启动后立即读终端时被停止
+func setProcessGroup(cmd *exec.Cmd, group *processGroup) {
+	if group == nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.SysProcAttr.Pgid = group.pgid
+	if group.foreground {
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = int(group.tty.Fd())
+	}
+}
+
+// setTerminalForeground 把终端交给进程组 pgid（tcsetpgrp）
+// shell 不在前台时设置会收到 SIGTTOU，设置期间在当前线程中阻塞这个信号
+func setTerminalForeground(tty *os.File, pgid int) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	block := uint64(1) << (uint(syscall.SIGTTOU) - 1)
+	var old uint64
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, sigBlock, uintptr(unsafe.Pointer(&block)), uintptr(unsafe.Pointer(&old)), 8, 0, 0); errno != 0 {
+		return errno
+	}
+	defer syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, sigSetmask, uintptr(unsafe.Pointer(&old)), 0, 8, 0, 0)
+
+	value := int32(pgid)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&value))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// shellProcessGroup 返回 shell 所在的进程组
+func shellProcessGroup() int {
+	return syscall.Getpgrp()
+}
+
+// continueProcesses 向作业的进程组（没有进程组时向进程 pid）发送 SIGCONT
+func continueProcesses(pid, pgid int) error {
+	if pgid > 0 {
+		return syscall.Kill(-pgid, syscall.SIGCONT)
+	}
+	return syscall.Kill(pid, syscall.SIGCONT)
+}
+
+const (
+	pPID       = 1 // waitid 的 P_PID
+	sigBlock   = 0 // rt_sigprocmask 的 SIG_BLOCK
+	sigSetmask = 2 // rt_sigprocmask 的 SIG_SETMASK
+)
+
+// siginfo waitid 返回的 siginfo_t，只使用 SIGCHLD 的 si_pid 和 si_status
+type siginfo struct {
+	signo, errno, code int32
+	_                  [unsafe.Sizeof(uintptr(0)) - 4]byte // 64 位系统上联合体按 8 字节对齐
+	pid                int32
+	uid                uint32
+	status             int32
+	_                  [128]byte
+}
+
+// waitStopped 等待进程 pid 停止或退出：停止时返回停止它的信号和 true（取走停止的通知），
+// 退出时返回 false 并保留退出状态，由 exec.Cmd.Wait 回收进程
+func waitStopped(pid int) (syscall.Signal, bool) {
+	for {
+		var info siginfo
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pPID, uintptr(pid), uintptr(unsafe.Pointer(&info)),
+			syscall.WEXITED|syscall.WSTOPPED|syscall.WNOWAIT, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return 0, false
+		}
+		// 只取走停止的通知（不包括 WEXITED），进程已经退出时 si_pid 为 0
+		info = siginfo{}
+		_, _, errno = syscall.Syscall6(syscall.SYS_WAITID, pPID, uintptr(pid), uintptr(unsafe.Pointer(&info)),
+			syscall.WSTOPPED|syscall.WNOHANG, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 || info.pid == 0 {
+			return 0, false
+		}
+		return syscall.Signal(info.status), true
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !linux && !windows
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// canControlTerminal 当前系统不支持作业控制
+func canControlTerminal(tty *os.File) bool {
+	return false
+}
+
+func setProcessGroup(cmd *exec.Cmd, group *processGroup) {}
+
+func setTerminalForeground(tty *os.File, pgid int) error {
+	return nil
+}
+
+func shellProcessGroup() int {
+	return 0
+}
+
+func continueProcesses(pid, pgid int) error {
+	return nil
+}
+
+// waitStopped 当前系统不检测命令停止
+func waitStopped(pid int) (syscall.Signal, bool) {
+	return 0, false
+}
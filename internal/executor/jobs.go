@@ -1,9 +1,15 @@
 package executor
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 	"gobash/internal/builtin"
 )
@@ -19,6 +25,16 @@ type Job struct {
 	Process   *os.Process   // 进程对象
 	cmd       *exec.Cmd     // 保存cmd引用以便Wait
 	done      chan struct{}  // 进程完成通知channel
+	stop      chan struct{}  // 作业被停止的通知（启用作业控制时）
+	group     *processGroup  // 作业的进程组，nil 表示没有启用作业控制
+	holdsTTY  bool           // fg 把终端交给了作业，Wait 返回时收回
+	exitCode  int            // 作业结束后的退出状态
+	cancel    context.CancelFunc // 终止在 shell 内部运行的作业，作业是外部命令时为 nil
+	killedBy  int            // 终止作业的信号，作业以 128+信号 的状态结束
+	reported  JobStatus      // 已经通知用户的状态（见 Notifications）
+	foreground bool          // 作业由 fg 在前台运行，状态的改变由 fg 报告
+	nohup     bool           // shell 退出时不发送 SIGHUP（disown）
+	manager   *JobManager    // 作业所在的作业管理器
 	mu        sync.Mutex    // 互斥锁
 }
 
@@ -63,15 +79,103 @@ func (j *Job) GetProcess() *os.Process {
 }
 
 // Wait 等待作业完成
-// 阻塞直到作业完成，通过done channel实现
+// 阻塞直到作业完成或被停止，通过done channel实现；作业在前台运行时返回前 shell 收回终端
 func (j *Job) Wait() error {
 	if j.done == nil {
 		return nil // 如果done channel不存在，说明作业已经完成或不存在
 	}
-	<-j.done
+	select {
+	case <-j.done:
+	case <-j.stop:
+	}
+	if j.holdsTTY {
+		j.holdsTTY = false
+		j.group.reclaimTerminal()
+	}
+	return nil
+}
+
+// ExitCode 返回作业的退出状态，作业还没有结束时为 0
+func (j *Job) ExitCode() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.exitCode
+}
+
+// Signal 向在 shell 内部运行的作业（后台的管道、循环、子shell 等）发送信号 sig：
+// 信号 0 只检查作业是否还在运行，其他信号终止作业；作业是外部命令时返回 false，由调用者向进程发送信号
+func (j *Job) Signal(sig int) (bool, error) {
+	if j.cancel == nil {
+		return false, nil
+	}
+	select {
+	case <-j.done:
+		return true, syscall.ESRCH
+	default:
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if sig != 0 {
+		j.killedBy = sig
+		j.cancel()
+	}
+	return true, nil
+}
+
+// NoHangup 让作业在 shell 退出时不收到 SIGHUP（disown）
+func (j *Job) NoHangup() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.nohup = true
+}
+
+// Continue 让作业继续运行：停止的作业收到 SIGCONT，foreground 为 true 时先把终端交给作业的进程组
+func (j *Job) Continue(foreground bool) error {
+	// 丢弃之前的停止通知，Wait 只在作业再次停止时返回
+	select {
+	case <-j.stop:
+	default:
+	}
+	j.mu.Lock()
+	j.foreground = foreground
+	j.mu.Unlock()
+	pgid := 0
+	if j.group != nil {
+		pgid = j.group.pgid
+		if foreground && pgid > 0 {
+			if err := setTerminalForeground(j.group.tty, pgid); err != nil {
+				return err
+			}
+			j.group.foreground = true
+			j.holdsTTY = true
+		}
+	}
+	if j.GetStatus() == JobStopped {
+		if err := continueProcesses(j.PID, pgid); err != nil {
+			return err
+		}
+	}
+	j.SetStatus(JobRunning)
 	return nil
 }
 
+// watchStops 作业的进程组中有命令停止时把作业标记为已停止，并唤醒等待作业的 fg
+func (j *Job) watchStops() {
+	for {
+		select {
+		case <-j.group.stopped:
+			j.SetStatus(JobStopped)
+			select {
+			case j.stop <- struct{}{}:
+			default:
+			}
+			j.manager.changed()
+		case <-j.done:
+			return
+		}
+	}
+}
+
 // 使用builtin包中定义的JobStatus类型
 type JobStatus = builtin.JobStatus
 
@@ -85,8 +189,8 @@ const (
 // 管理所有后台作业，提供添加、查询、删除作业的功能
 type JobManager struct {
 	jobs    map[int]*Job
-	nextID  int
 	current int // 当前作业ID（+表示前台，-表示后台）
+	notify  io.Writer // 作业状态改变时立即写入通知（set -b），nil 表示只在提示符之前通知
 	mu      sync.Mutex
 }
 
@@ -94,43 +198,97 @@ type JobManager struct {
 // 初始化作业管理器，返回一个新的JobManager实例
 func NewJobManager() *JobManager {
 	return &JobManager{
-		jobs:   make(map[int]*Job),
-		nextID: 1,
+		jobs: make(map[int]*Job),
 	}
 }
 
 // AddJob 添加作业
 // 将一个新的后台任务添加到管理器中，返回作业ID
-// 在goroutine中等待进程完成并更新状态
-func (jm *JobManager) AddJob(cmd *exec.Cmd, cmdStr string) int {
-	jm.mu.Lock()
-	defer jm.mu.Unlock()
+// 在goroutine中等待进程完成并更新状态；group 是作业的进程组（没有启用作业控制时为 nil）
+func (jm *JobManager) AddJob(cmd *exec.Cmd, cmdStr string, group *processGroup) int {
+	job := &Job{
+		PID:     cmd.Process.Pid,
+		Cmd:     cmdStr,
+		Status:  JobRunning,
+		Process: cmd.Process,
+		cmd:     cmd,
+	}
+	return jm.add(job, group, func() error { return waitProcess(cmd, group) })
+}
+
+// taskPIDs 分配给在 shell 内部运行的作业的进程号（$! 的值）
+// 从 2^22+1 开始每次加 2：Linux 的进程号不超过 2^22，Windows 的进程号是 4 的倍数，不会与真正的进程号重复
+var taskPIDs atomic.Int64
 
+// nextTaskPID 返回一个新的作业进程号
+func nextTaskPID() int {
+	return int(taskPIDs.Add(2)) + 1<<22 - 1
+}
+
+// AddTask 添加在 shell 内部运行的后台作业（管道、循环、子shell、内置命令和函数），返回作业ID
+// 作业的进程号是 pid，run 在goroutine中执行作业并返回它的结果；cancel 终止作业（kill），group 是作业的进程组
+func (jm *JobManager) AddTask(cmdStr string, pid int, group *processGroup, cancel context.CancelFunc, run func() error) int {
+	job := &Job{
+		PID:    pid,
+		Cmd:    cmdStr,
+		Status: JobRunning,
+		cancel: cancel,
+	}
+	return jm.add(job, group, run)
+}
+
+// addStoppedJob 添加被 Ctrl+Z 停止的前台作业，finished 在作业的命令全部结束后关闭
+func (jm *JobManager) addStoppedJob(cmdStr string, process *os.Process, group *processGroup, finished <-chan struct{}) int {
+	// 停止的通知已经由执行器输出
 	job := &Job{
-		ID:        jm.nextID,
-		PID:       cmd.Process.Pid,
-		Cmd:       cmdStr,
-		Status:    JobRunning,
-		StartTime: time.Now(),
-		Process:   cmd.Process,
-		cmd:       cmd,
-		done:      make(chan struct{}),
+		PID:      group.pgid,
+		Cmd:      cmdStr,
+		Status:   JobStopped,
+		reported: JobStopped,
+		Process:  process,
 	}
+	return jm.add(job, group, func() error {
+		<-finished
+		return nil
+	})
+}
+
+// add 登记作业并在goroutine中等待 wait 返回（作业完成），wait 的结果是作业的退出状态
+func (jm *JobManager) add(job *Job, group *processGroup, wait func() error) int {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
 
-	jm.jobs[jm.nextID] = job
-	id := jm.nextID
-	jm.nextID++
+	// 与 bash 相同，新作业的ID是作业表中最大的ID加 1（wait 移除作业后ID可以重新使用）
+	id := 1
+	for jobID := range jm.jobs {
+		if jobID >= id {
+			id = jobID + 1
+		}
+	}
+	job.ID = id
+	job.StartTime = time.Now()
+	job.done = make(chan struct{})
+	job.stop = make(chan struct{}, 1)
+	job.group = group
+	job.manager = jm
+	jm.jobs[id] = job
 
 	// 在goroutine中等待进程完成
-	go func(jobID int, doneChan chan struct{}) {
-		cmd.Wait()
-		close(doneChan)
-		jm.mu.Lock()
-		if job, ok := jm.jobs[jobID]; ok {
-			job.Status = JobDone
+	go func(doneChan chan struct{}) {
+		err := wait()
+		job.mu.Lock()
+		job.exitCode = ExitStatus(err)
+		if job.killedBy > 0 {
+			job.exitCode = 128 + job.killedBy
 		}
-		jm.mu.Unlock()
-	}(id, job.done)
+		job.mu.Unlock()
+		close(doneChan)
+		job.SetStatus(JobDone)
+		jm.changed()
+	}(job.done)
+	if group != nil {
+		go job.watchStops()
+	}
 
 	return id
 }
@@ -147,6 +305,18 @@ func (jm *JobManager) GetJob(id int) (builtin.Job, bool) {
 	return job, true
 }
 
+// GetJobByPID 根据进程号查找作业（kill 和 wait 的参数可以是 $!）
+func (jm *JobManager) GetJobByPID(pid int) (builtin.Job, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	for _, job := range jm.jobs {
+		if job.PID == pid {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
 // GetAllJobs 获取所有作业（返回接口类型以匹配builtin包的接口）
 // 返回所有未完成的作业列表（不包括已完成的作业）
 func (jm *JobManager) GetAllJobs() []builtin.Job {
@@ -156,7 +326,7 @@ func (jm *JobManager) GetAllJobs() []builtin.Job {
 	jobs := make([]builtin.Job, 0, len(jm.jobs))
 	for _, job := range jm.jobs {
 		// 只返回未完成的作业
-		if job.Status != JobDone {
+		if job.GetStatus() != JobDone {
 			jobs = append(jobs, job)
 		}
 	}
@@ -192,3 +362,127 @@ func (jm *JobManager) SetCurrentJob(id int) {
 	jm.current = id
 }
 
+
+// KillAll 终止所有还在运行的作业，用于嵌入方在脚本超出资源限制时结束它启动的后台进程
+func (jm *JobManager) KillAll() {
+	for _, job := range jm.GetAllJobs() {
+		j := job.(*Job)
+		if ok, _ := j.Signal(9); !ok && j.Process != nil {
+			j.Process.Kill()
+		}
+	}
+}
+
+// Hangup 向作业表中还没有结束的作业发送 SIGHUP，停止的作业再发送 SIGCONT 使它处理信号；disown -h 的作业除外
+// 交互式 shell 在 shopt -s huponexit 时退出之前调用。Windows 没有 SIGHUP，直接结束作业的进程
+func (jm *JobManager) Hangup() {
+	for _, job := range jm.GetAllJobs() {
+		j := job.(*Job)
+		j.mu.Lock()
+		nohup := j.nohup
+		j.mu.Unlock()
+		if nohup {
+			continue
+		}
+		if ok, _ := j.Signal(int(syscall.SIGHUP)); ok || j.Process == nil {
+			continue
+		}
+		if err := j.Process.Signal(syscall.SIGHUP); err != nil {
+			j.Process.Kill()
+		}
+		if j.GetStatus() == JobStopped {
+			pgid := 0
+			if j.group != nil {
+				pgid = j.group.pgid
+			}
+			continueProcesses(j.PID, pgid)
+		}
+	}
+}
+
+// SetNotify 设置立即通知作业状态改变的输出（set -b），nil 表示只在交互式 shell 显示提示符之前通知
+func (jm *JobManager) SetNotify(w io.Writer) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.notify = w
+}
+
+// changed 作业的状态改变了，set -b 时立即输出通知
+func (jm *JobManager) changed() {
+	jm.mu.Lock()
+	w := jm.notify
+	jm.mu.Unlock()
+	if w == nil {
+		return
+	}
+	for _, line := range jm.Notifications() {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// Notifications 返回状态改变之后还没有通知用户的作业的通知（与 bash 相同，如 "[1]+  Done                    sleep 1"），按作业ID排序
+// 已经通知结束的作业从作业表中移除；由 fg 在前台运行的作业的状态由 fg 报告，不产生通知
+func (jm *JobManager) Notifications() []string {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	ids := make([]int, 0, len(jm.jobs))
+	for id := range jm.jobs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	// 当前作业（+）是 fg、bg 最后使用的作业，没有时是最新的作业；前一个作业（-）是其余作业中最新的
+	current, previous := jm.current, 0
+	if _, ok := jm.jobs[current]; !ok && len(ids) > 0 {
+		current = ids[len(ids)-1]
+	}
+	for _, id := range ids {
+		if id != current {
+			previous = id
+		}
+	}
+
+	var lines []string
+	for _, id := range ids {
+		job := jm.jobs[id]
+		job.mu.Lock()
+		status, reported, foreground, code := job.Status, job.reported, job.foreground, job.exitCode
+		job.reported = status
+		if status != JobRunning {
+			job.foreground = false
+		}
+		job.mu.Unlock()
+		if status != JobRunning && !foreground && status != reported {
+			marker := " "
+			if id == current {
+				marker = "+"
+			} else if id == previous {
+				marker = "-"
+			}
+			lines = append(lines, fmt.Sprintf("[%d]%s  %-24s%s", id, marker, jobStateText(status, code), job.Cmd))
+		}
+		if status == JobDone {
+			delete(jm.jobs, id)
+		}
+	}
+	return lines
+}
+
+// jobStateText 返回通知中作业的状态：Stopped、Done，非零退出状态为 Exit N，被信号终止时为信号的说明
+func jobStateText(status JobStatus, code int) string {
+	if status != JobDone {
+		return status.String()
+	}
+	switch code {
+	case 0:
+		return "Done"
+	case 128 + 1:
+		return "Hangup"
+	case 128 + 2:
+		return "Interrupt"
+	case 128 + 9:
+		return "Killed"
+	case 128 + 15:
+		return "Terminated"
+	}
+	return fmt.Sprintf("Exit %d", code)
+}
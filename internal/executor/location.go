@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"errors"
+	"gobash/internal/i18n"
+	"gobash/internal/lexer"
+	"strconv"
+)
+
+// SetScriptName 设置错误消息中显示的脚本名（执行脚本文件时为脚本路径）
+// 同时设置 BASH_SOURCE 和 BASH_LINENO
+func (e *Executor) SetScriptName(name string) {
+	e.scriptName = name
+	e.updateCallStack()
+}
+
+// SetInteractive 设置是否是交互式 shell，交互式 shell 的错误消息不带行号
+func (e *Executor) SetInteractive(interactive bool) {
+	e.interactive = interactive
+}
+
+// LineNumber 返回正在执行（或最后执行）的语句所在的行号，0 表示未知
+func (e *Executor) LineNumber() int {
+	return e.lineno
+}
+
+// setLineNumber 记录正在执行的语句所在的行号，同时更新 $LINENO
+// 陷阱命令中不更新，$LINENO 仍是触发陷阱的命令所在的行
+func (e *Executor) setLineNumber(line int) {
+	if e.inTrap {
+		return
+	}
+	e.lineno = line
+	e.env["LINENO"] = strconv.Itoa(line)
+}
+
+// updateCallStack 根据函数调用栈设置 FUNCNAME、BASH_SOURCE 和 BASH_LINENO 数组
+// 元素 0 是正在执行的函数，之后依次是调用者；执行脚本时最后一个元素是脚本的最外层 main（BASH_LINENO 为 0）。
+// 不在函数中时 FUNCNAME 未定义。gobash 没有 source 命令，所有函数都定义在正在执行的脚本中
+func (e *Executor) updateCallStack() {
+	var names, sources, lines []string
+	for i := len(e.frames) - 1; i >= 0; i-- {
+		names = append(names, e.frames[i].name)
+		sources = append(sources, e.scriptName)
+		lines = append(lines, strconv.Itoa(e.frames[i].line))
+	}
+	if e.scriptName != "" {
+		if len(names) > 0 {
+			names = append(names, "main")
+		}
+		sources = append(sources, e.scriptName)
+		lines = append(lines, "0")
+	}
+	e.setCallArray("FUNCNAME", names)
+	e.setCallArray("BASH_SOURCE", sources)
+	e.setCallArray("BASH_LINENO", lines)
+}
+
+// setCallArray 设置调用栈数组，没有元素时删除数组
+func (e *Executor) setCallArray(name string, values []string) {
+	if len(values) == 0 {
+		e.unsetArray(name)
+	} else {
+		e.setArray(name, nil, values)
+	}
+}
+
+// errorPrefix 返回执行中报告错误时的前缀，参考 bash 的格式：
+// 非交互式为 "gobash: 脚本名: 第N行"（没有脚本名时为 "gobash: 第N行"），交互式为 "gobash"
+func (e *Executor) errorPrefix() string {
+	if e.interactive || e.lineno <= 0 {
+		return "gobash"
+	}
+	if e.scriptName != "" {
+		return i18n.Sprintf("gobash: %s: 第%d行", e.scriptName, e.lineno)
+	}
+	return i18n.Sprintf("gobash: 第%d行", e.lineno)
+}
+
+// setErrorPosition 为还没有记录位置的执行器错误记录出错语句的位置
+// 错误从最内层的语句向外传播，所以记录的是实际出错的命令的位置
+func setErrorPosition(err error, pos lexer.Position) {
+	var execErr *ExecutionError
+	if errors.As(err, &execErr) && !execErr.Pos.IsValid() {
+		execErr.Pos = pos
+	}
+}
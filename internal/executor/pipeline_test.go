@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"testing"
+)
+
+// TestPipeline 测试管道：内置命令、函数和复合命令都可以出现在管道中，每个命令在子shell中执行
+func TestPipeline(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"内置命令到外部命令", "echo hello | tr a-z A-Z", "HELLO\n"},
+		{"多级管道", "echo piped | cat | cat | cat", "piped\n"},
+		{"for 循环", "for i in 3 1 2; do echo $i; done | sort", "1\n2\n3\n"},
+		{"if 语句", "if true; then echo yes; fi | cat", "yes\n"},
+		{"命令组", "{ echo b; echo a; } | sort", "a\nb\n"},
+		{"函数", "f() { echo fn; }\nf | tr a-z A-Z", "FN\n"},
+		{"|& 合并错误输出", "sh -c 'echo err >&2' |& tr a-z A-Z", "ERR\n"},
+		{"子shell中的赋值不影响当前shell", "x=outer\necho | x=inner\necho $x", "outer\n"},
+		{"命令替换中的循环", `echo "$(for i in 1 2; do echo $i; done)"`, "1\n2\n"},
+		{"命令替换中的管道", `echo "$(echo b | tr b c)"`, "c\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.script))
+			program := p.ParseProgram()
+			if len(p.Errors()) > 0 {
+				t.Fatalf("解析失败: %v", p.Errors())
+			}
+			stdout, _, err := New().Capture(program)
+			if err != nil {
+				t.Fatalf("执行失败: %v", err)
+			}
+			if stdout != tt.want {
+				t.Errorf("输出 %q，期望 %q", stdout, tt.want)
+			}
+		})
+	}
+}
+
+// TestPipelineExitStatus 测试管道的退出状态：默认是最后一个命令的状态，pipefail 时是最后一个失败命令的状态
+func TestPipelineExitStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		pipefail bool
+		want     int
+	}{
+		{"最后一个命令成功", "false | true", false, 0},
+		{"最后一个命令失败", "true | sh -c 'exit 3'", false, 3},
+		{"pipefail", "sh -c 'exit 2' | true", true, 2},
+		{"pipefail 全部成功", "true | true", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.script))
+			program := p.ParseProgram()
+			if len(p.Errors()) > 0 {
+				t.Fatalf("解析失败: %v", p.Errors())
+			}
+			e := New()
+			e.SetOptions(map[string]bool{"pipefail": tt.pipefail})
+			_, _, err := e.Capture(program)
+			if got := ExitStatus(err); got != tt.want {
+				t.Errorf("退出状态 %d，期望 %d（err=%v）", got, tt.want, err)
+			}
+			if got := e.getExitCode(); got != tt.want {
+				t.Errorf("$? = %d，期望 %d", got, tt.want)
+			}
+		})
+	}
+}
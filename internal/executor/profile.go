@@ -0,0 +1,148 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 性能分析
+//
+// 设置了 Profiler 时，执行器记录每个简单命令（按脚本、行号和命令原文区分）的执行次数和总耗时，
+// 子shell、命令替换和管道中的命令记录到同一个 Profiler。命令的耗时包括其中调用的函数和命令替换，
+// 因此调用函数的那一行的耗时是整个函数的耗时
+
+// profileCommandWidth 报告中命令原文的最大显示宽度，更长的命令被截断
+const profileCommandWidth = 60
+
+// ProfileEntry 一条命令的统计
+type ProfileEntry struct {
+	Script  string        // 脚本名，-c 和交互式输入时为空
+	Line    int           // 命令所在的行
+	Command string        // 命令原文（未展开）
+	Count   int           // 执行次数
+	Total   time.Duration // 总耗时
+}
+
+// Average 返回平均每次的耗时
+func (p ProfileEntry) Average() time.Duration {
+	if p.Count == 0 {
+		return 0
+	}
+	return p.Total / time.Duration(p.Count)
+}
+
+// profileKey 区分命令的键
+type profileKey struct {
+	script  string
+	line    int
+	command string
+}
+
+// Profiler 统计命令的执行次数和耗时，可以在多个执行器（子shell、管道中的命令）中同时使用
+type Profiler struct {
+	mu      sync.Mutex
+	entries map[profileKey]*ProfileEntry
+}
+
+// NewProfiler 创建 Profiler
+func NewProfiler() *Profiler {
+	return &Profiler{entries: make(map[profileKey]*ProfileEntry)}
+}
+
+// SetProfiler 设置记录命令耗时的 Profiler，nil 表示不记录
+func (e *Executor) SetProfiler(p *Profiler) {
+	e.profiler = p
+}
+
+// record 记录一次命令执行
+func (p *Profiler) record(script string, line int, command string, d time.Duration) {
+	key := profileKey{script: script, line: line, command: command}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.entries[key]
+	if !ok {
+		entry = &ProfileEntry{Script: script, Line: line, Command: command}
+		p.entries[key] = entry
+	}
+	entry.Count++
+	entry.Total += d
+}
+
+// Entries 返回所有命令的统计，按总耗时从大到小排序（耗时相同时按位置排序）
+func (p *Profiler) Entries() []ProfileEntry {
+	p.mu.Lock()
+	entries := make([]ProfileEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, *entry)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.Total != b.Total:
+			return a.Total > b.Total
+		case a.Script != b.Script:
+			return a.Script < b.Script
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		}
+		return a.Command < b.Command
+	})
+	return entries
+}
+
+// WriteReport 把统计按总耗时从大到小写到 w，limit 大于 0 时只输出前 limit 条
+func (p *Profiler) WriteReport(w io.Writer, limit int) {
+	entries := p.Entries()
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	i18n.Fprintf(w, "性能分析（按总耗时排序）:\n")
+	fmt.Fprintf(w, "%12s %8s %12s  %-16s %s\n", "total", "count", "average", "location", "command")
+	for _, entry := range entries {
+		location := fmt.Sprintf("%d", entry.Line)
+		if entry.Script != "" {
+			location = entry.Script + ":" + location
+		}
+		fmt.Fprintf(w, "%12s %8d %12s  %-16s %s\n", formatProfileDuration(entry.Total), entry.Count,
+			formatProfileDuration(entry.Average()), location, truncateCommand(entry.Command))
+	}
+}
+
+// formatProfileDuration 以合适的单位输出耗时，保留三位有效数字左右
+func formatProfileDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	}
+	return d.String()
+}
+
+// truncateCommand 把命令压成一行，超过 profileCommandWidth 个字符时截断
+func truncateCommand(command string) string {
+	command = strings.Join(strings.Fields(command), " ")
+	if runes := []rune(command); len(runes) > profileCommandWidth {
+		return string(runes[:profileCommandWidth-3]) + "..."
+	}
+	return command
+}
+
+// profileCommand 执行命令 run，设置了 Profiler 时记录它的耗时
+func (e *Executor) profileCommand(cmd fmt.Stringer, run func() error) error {
+	if e.profiler == nil || e.inTrap {
+		return run()
+	}
+	line := e.lineno
+	start := time.Now()
+	err := run()
+	e.profiler.record(e.scriptName, line, strings.TrimSpace(cmd.String()), time.Since(start))
+	return err
+}
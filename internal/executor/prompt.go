@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodePromptEscapes 替换提示符中 bash 的转义序列：
+// \u 用户名、\h 主机名（第一个点之前）、\H 完整主机名、\w 工作目录（主目录显示为 ~）、\W 工作目录的最后一部分、
+// \$ 超级用户为 #，否则为 $、\s shell 名称、\t \T \@ \A 当前时间、\d 日期、\n 换行、\r 回车、\a 响铃、
+// \e 和 \0nn（八进制）转义字符、\\ 反斜杠；\[ 和 \] 标记不占宽度的控制序列（如颜色），直接去掉
+// 替换得到的文本中的 $ 和 \ 会被转义，之后展开变量时保持原样
+func (e *Executor) decodePromptEscapes(prompt string) string {
+	var result strings.Builder
+	for i := 0; i < len(prompt); i++ {
+		if prompt[i] != '\\' || i+1 == len(prompt) {
+			result.WriteByte(prompt[i])
+			continue
+		}
+		i++
+		switch c := prompt[i]; c {
+		case 'u':
+			result.WriteString(quotePromptText(e.promptUser()))
+		case 'h', 'H':
+			host, _ := os.Hostname()
+			if c == 'h' {
+				host, _, _ = strings.Cut(host, ".")
+			}
+			result.WriteString(quotePromptText(host))
+		case 'w', 'W':
+			result.WriteString(quotePromptText(e.promptDir(c == 'W')))
+		case '$':
+			if os.Geteuid() == 0 {
+				result.WriteByte('#')
+			} else {
+				result.WriteString("\\$")
+			}
+		case 's':
+			result.WriteString("gobash")
+		case 't':
+			result.WriteString(time.Now().Format("15:04:05"))
+		case 'T':
+			result.WriteString(time.Now().Format("03:04:05"))
+		case '@':
+			result.WriteString(time.Now().Format("03:04 PM"))
+		case 'A':
+			result.WriteString(time.Now().Format("15:04"))
+		case 'd':
+			result.WriteString(time.Now().Format("Mon Jan 02"))
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 'a':
+			result.WriteByte('\a')
+		case 'e':
+			result.WriteByte('\033')
+		case '0':
+			// \0nn：最多三位八进制数
+			end := i + 1
+			for end < len(prompt) && end < i+3 && prompt[end] >= '0' && prompt[end] <= '7' {
+				end++
+			}
+			code, _ := strconv.ParseUint(prompt[i:end], 8, 8)
+			result.WriteByte(byte(code))
+			i = end - 1
+		case '[', ']':
+		case '\\':
+			result.WriteString("\\\\")
+		default:
+			result.WriteByte('\\')
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// promptUser 返回 \u 显示的用户名
+func (e *Executor) promptUser() string {
+	for _, name := range []string{"USER", "USERNAME", "LOGNAME"} {
+		if user := e.env[name]; user != "" {
+			return user
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		// Windows 上的用户名带有域名（DOMAIN\name）
+		return u.Username[strings.LastIndex(u.Username, "\\")+1:]
+	}
+	return ""
+}
+
+// promptDir 返回 \w（base 为 false）或 \W 显示的工作目录，主目录显示为 ~
+func (e *Executor) promptDir(base bool) string {
+	dir := e.Dir()
+	home := e.env["HOME"]
+	if home == "" {
+		home = e.env["USERPROFILE"]
+	}
+	if home != "" && (dir == home || strings.HasPrefix(dir, strings.TrimSuffix(home, string(filepath.Separator))+string(filepath.Separator))) {
+		if base && dir == home {
+			return "~"
+		}
+		if !base {
+			dir = "~" + dir[len(home):]
+		}
+	}
+	if base && dir != string(filepath.Separator) {
+		dir = filepath.Base(dir)
+	}
+	return filepath.ToSlash(dir)
+}
+
+// quotePromptText 转义替换得到的文本中的 \ 和 $，展开变量时保持原样
+func quotePromptText(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	return strings.ReplaceAll(text, "$", "\\$")
+}
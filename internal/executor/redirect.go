@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/builtin"
+	"gobash/internal/i18n"
+	"gobash/internal/parser"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// redirectStdio 在 stdio 的基础上按顺序应用重定向，返回新的标准流和打开的文件
+// 文件需要在命令结束后由调用者关闭（closeFiles）；出错时已打开的文件会被关闭
+// 支持 >、>>、>|、<、<>、here-document、here-string 以及 n>&m、n<&m（m 为 0、1、2 或 coproc 等打开的文件描述符）
+func (e *Executor) redirectStdio(stdio *builtin.IO, redirects []*parser.Redirect) (*builtin.IO, []*os.File, error) {
+	result := *stdio
+	var files []*os.File
+
+	for _, redirect := range redirects {
+		target := ""
+		if redirect.Target != nil {
+			var err error
+			if target, err = e.evaluateExpression(redirect.Target); err != nil {
+				closeFiles(files)
+				return nil, nil, err
+			}
+		}
+
+		switch redirect.Type {
+		case parser.REDIRECT_HEREDOC, parser.REDIRECT_HEREDOC_STRIP:
+			if redirect.HereDoc != nil {
+				result.Stdin = strings.NewReader(e.hereDocumentContent(redirect.HereDoc))
+			}
+			continue
+		case parser.REDIRECT_HERESTRING:
+			// here-string 的内容末尾加上换行符（与 bash 一致）
+			result.Stdin = strings.NewReader(target + "\n")
+			continue
+		case parser.REDIRECT_DUP_OUT, parser.REDIRECT_DUP_IN:
+			if err := e.dupStdio(&result, redirect.FD, target); err != nil {
+				closeFiles(files)
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if target == "" {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("redirect target is empty")
+		}
+
+		var flag int
+		switch redirect.Type {
+		case parser.REDIRECT_OUTPUT, parser.REDIRECT_CLOBBER:
+			flag = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		case parser.REDIRECT_APPEND:
+			flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		case parser.REDIRECT_INPUT:
+			flag = os.O_RDONLY
+		case parser.REDIRECT_RW:
+			flag = os.O_CREATE | os.O_RDWR
+		default:
+			continue
+		}
+		if err := e.checkRedirect(redirect.Type, e.resolvePath(target)); err != nil {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("%s: %s", target, systemErrorReason(err))
+		}
+		if err := e.checkNoclobber(redirect.Type, target); err != nil {
+			closeFiles(files)
+			return nil, nil, err
+		}
+		if e.dryRunDiscards(redirect) {
+			// 试运行时不创建文件
+			switch redirect.FD {
+			case 0:
+				result.Stdin = strings.NewReader("")
+			case 1:
+				result.Stdout = io.Discard
+			case 2:
+				result.Stderr = io.Discard
+			}
+			continue
+		}
+		file, err := builtin.CreateFile(e.resolvePath(target), flag, 0666, e.umask)
+		if err != nil {
+			// 与 bash 相同只输出文件名和原因，如 /nonexist/x: No such file or directory
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("%s: %s", target, systemErrorReason(err))
+		}
+		files = append(files, file)
+
+		switch redirect.FD {
+		case 0:
+			result.Stdin = file
+		case 1:
+			result.Stdout = file
+		case 2:
+			result.Stderr = file
+		}
+	}
+	return &result, files, nil
+}
+
+// checkRedirect 按访问策略检查重定向对文件 path 的读写：<、<> 读取，>、>>、>|、<> 写入；拒绝时返回 *os.PathError
+func (e *Executor) checkRedirect(redirectType parser.RedirectType, path string) error {
+	stdio := e.Stdio()
+	var err error
+	switch redirectType {
+	case parser.REDIRECT_INPUT:
+		err = stdio.Check(builtin.AccessRead, path)
+	case parser.REDIRECT_OUTPUT, parser.REDIRECT_CLOBBER, parser.REDIRECT_APPEND:
+		err = stdio.Check(builtin.AccessWrite, path)
+	case parser.REDIRECT_RW:
+		if err = stdio.Check(builtin.AccessRead, path); err == nil {
+			err = stdio.Check(builtin.AccessWrite, path)
+		}
+	}
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return nil
+}
+
+// checkNoclobber 开启 noclobber（set -C）时 > 不覆盖已经存在的普通文件；>| 强制覆盖，/dev/null 等不是普通文件的目标不受影响
+func (e *Executor) checkNoclobber(redirectType parser.RedirectType, target string) error {
+	if redirectType != parser.REDIRECT_OUTPUT || !e.options["C"] {
+		return nil
+	}
+	if info, err := os.Stat(e.resolvePath(target)); err == nil && info.Mode().IsRegular() {
+		return i18n.Errorf("%s: 不能覆盖已存在的文件", target)
+	}
+	return nil
+}
+
+// dupStdio 处理 n>&m 和 n<&m：让文件描述符 n 指向 m 当前指向的流，m 可以是 shell 打开的文件描述符（如 ${COPROC[1]}）
+// m 为 - 时（关闭文件描述符）不做处理
+func (e *Executor) dupStdio(stdio *builtin.IO, fd int, target string) error {
+	if target == "-" {
+		return nil
+	}
+	src, err := strconv.Atoi(target)
+	if err != nil {
+		return i18n.Errorf("无效的文件描述符: %s", target)
+	}
+	if fd > 2 {
+		// 其他文件描述符暂不支持，忽略
+		return nil
+	}
+
+	if f, ok := e.fds[src]; ok {
+		switch fd {
+		case 0:
+			stdio.Stdin = f
+		case 1:
+			stdio.Stdout = f
+		case 2:
+			stdio.Stderr = f
+		}
+		return nil
+	}
+
+	switch {
+	case fd == src:
+	case fd == 2 && src == 1:
+		stdio.Stderr = stdio.Stdout
+	case fd == 1 && src == 2:
+		stdio.Stdout = stdio.Stderr
+	default:
+		return i18n.Errorf("%d: 错误的文件描述符", src)
+	}
+	return nil
+}
+
+// closeFiles 关闭重定向打开的文件
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// hereDocumentContent 返回 here-document 的正文，分隔符没有加引号时展开其中的变量和命令替换
+// 正文由词法分析器在解析时读取（交互模式下 shell 会继续读取输入直到结束分隔符），正文为空时就是空输入
+func (e *Executor) hereDocumentContent(hd *parser.HereDocument) string {
+	if hd.Quoted {
+		return hd.Content
+	}
+	content, _ := e.expandText(hd.Content, quoteHereDoc)
+	return content
+}
+
+// withRedirects 在应用了重定向的标准流下执行 fn（用于复合命令，如 while ...; done < file）
+// 重定向只在 fn 执行期间生效，之后恢复原来的标准流
+func (e *Executor) withRedirects(redirects []*parser.Redirect, fn func() error) error {
+	if len(redirects) == 0 {
+		return fn()
+	}
+
+	stdio, files, err := e.redirectStdio(e.Stdio(), redirects)
+	if err != nil {
+		// 重定向失败时复合命令不执行，退出状态为 1
+		err = newExecutionError(ExecutionErrorTypeRedirectError, err.Error(), "", nil, 1, "", nil)
+		e.setExitStatus(err)
+		return e.checkErrexit(err)
+	}
+	defer closeFiles(files)
+
+	savedIn, savedOut, savedErr := e.stdin, e.stdout, e.stderr
+	e.stdin, e.stdout, e.stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+	defer func() {
+		e.stdin, e.stdout, e.stderr = savedIn, savedOut, savedErr
+	}()
+	return fn()
+}
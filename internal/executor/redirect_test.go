@@ -0,0 +1,180 @@
+package executor
+
+import (
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestCompoundRedirect 测试复合命令上的重定向：作用于整个复合命令，执行后恢复原来的标准流
+func TestCompoundRedirect(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input"), []byte("first\nsecond\n"), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		file   string // 检查的文件，为空时检查标准输出
+		want   string
+	}{
+		{"命令组", "{ echo one; echo two; } > out", "out", "one\ntwo\n"},
+		{"for 循环追加", "for i in 1 2; do echo $i; done >> out; for i in 3; do echo $i; done >> out", "out", "1\n2\n3\n"},
+		{"if 语句的错误输出", "if true; then sh -c 'echo bad >&2'; fi 2> err", "err", "bad\n"},
+		{"while 循环的输入", "while true; do head -1; break; done < input", "", "first\n"},
+		{"子shell", "(echo sub) > out", "out", "sub\n"},
+		{"case 语句", "case x in x) echo cased;; esac > out", "out", "cased\n"},
+		{"2>&1", "{ echo to-err >&2; } 2>&1", "", "to-err\n"},
+		{"here-document", "x=7\nwhile true; do cat; break; done <<EOF\nval $x\nEOF", "", "val 7\n"},
+		{"内置命令的 here-document", "x=7\ncat <<EOF\nval $x\nEOF", "", "val 7\n"},
+		{"外部命令的 here-string", "x=7; sh -c 'cat; echo end' <<< \"val $x\"", "", "val 7\nend\n"},
+		{"外部命令的输出", "sh -c 'echo one; echo two >&2' > out 2>&1", "out", "one\ntwo\n"},
+		{"空的 here-document", "cat <<EOF\nEOF\necho end", "", "end\n"},
+		{"重定向只作用于复合命令", "{ echo in; } > out\necho after", "", "after\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(filepath.Join(dir, "out"))
+			p := parser.New(lexer.New(tt.script))
+			program := p.ParseProgram()
+			if len(p.Errors()) > 0 {
+				t.Fatalf("解析失败: %v", p.Errors())
+			}
+			e := New()
+			e.SetEnv("PWD", dir)
+			stdout, _, err := e.Capture(program)
+			if err != nil {
+				t.Fatalf("执行失败: %v", err)
+			}
+			got := stdout
+			if tt.file != "" {
+				data, err := os.ReadFile(filepath.Join(dir, tt.file))
+				if err != nil {
+					t.Fatalf("读取 %s 失败: %v", tt.file, err)
+				}
+				got = string(data)
+			}
+			if got != tt.want {
+				t.Errorf("得到 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCompoundRedirectError 测试重定向失败时复合命令不执行，退出状态为 1
+func TestCompoundRedirectError(t *testing.T) {
+	p := parser.New(lexer.New("{ echo body; } < /nonexistent/input"))
+	program := p.ParseProgram()
+	e := New()
+	stdout, _, err := e.Capture(program)
+	if err == nil {
+		t.Fatal("期望重定向错误")
+	}
+	if stdout != "" {
+		t.Errorf("重定向失败时不应该执行命令，输出 %q", stdout)
+	}
+	if code := e.getExitCode(); code != 1 {
+		t.Errorf("$? = %d，期望 1", code)
+	}
+}
+
+// TestRedirectUmask 测试重定向按 umask 创建文件（内置命令和外部命令相同），已经存在的文件保留原来的权限
+func TestRedirectUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 上没有 Unix 权限位")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "e"), nil, 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	e := New()
+	e.SetEnv("PWD", dir)
+	program := parser.New(lexer.New("umask 077; echo hi > a; (umask 0; echo hi > b); echo hi > c; sh -c 'echo hi' > d; sh -c 'echo hi' > e; echo hi >> e")).ParseProgram()
+	if err := e.Execute(program); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	for name, want := range map[string]os.FileMode{"a": 0600, "b": 0666, "c": 0600, "d": 0600, "e": 0644} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Mode().Perm() != want {
+			t.Errorf("%s 的权限应该为 %04o: %v", name, want, err)
+		}
+	}
+}
+
+// TestNoclobber 测试 set -C 时 > 不覆盖已经存在的文件，>| 和 >> 不受影响
+func TestNoclobber(t *testing.T) {
+	dir := t.TempDir()
+	e := New()
+	e.SetEnv("PWD", dir)
+	e.SetOptions(map[string]bool{"C": true})
+	tests := []struct {
+		script string
+		fails  bool
+		want   string // 执行后文件 f 的内容
+	}{
+		{"echo a > f", false, "a\n"},
+		{"echo b > f", true, "a\n"},
+		{"{ echo c; } > f", true, "a\n"},
+		{"echo d > /dev/null", false, "a\n"},
+		{"echo e >> f", false, "a\ne\n"},
+		{"echo f >| f", false, "f\n"},
+	}
+	for _, tt := range tests {
+		_, _, err := e.Capture(parser.New(lexer.New(tt.script)).ParseProgram())
+		if (err != nil) != tt.fails {
+			t.Errorf("%s: 错误 %v，期望失败 %v", tt.script, err, tt.fails)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "f")); string(data) != tt.want {
+			t.Errorf("%s: 文件内容为 %q，期望 %q", tt.script, data, tt.want)
+		}
+	}
+}
+
+// TestBuiltinRedirectStdin 测试内置命令的输入重定向只作用于传给它的 IO，不修改进程的标准输入
+func TestBuiltinRedirectStdin(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "in"), []byte("from file\n"), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	stdin := os.Stdin
+	e := New()
+	e.SetEnv("PWD", dir)
+	stdout, _, err := e.Capture(parser.New(lexer.New(`read a < in; read b <<< "from string"; echo "$a|$b"`)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if stdout != "from file|from string\n" {
+		t.Errorf("输出 %q", stdout)
+	}
+	if os.Stdin != stdin {
+		t.Error("内置命令的重定向不应该修改 os.Stdin")
+	}
+}
+
+// TestBuiltinDiagnosticsRedirect 测试内置命令的错误消息写到命令自己的标准错误：遵守 2> 重定向，管道中的命令也不丢失，消息中的命令名不重复
+func TestBuiltinDiagnosticsRedirect(t *testing.T) {
+	dir := t.TempDir()
+	e := New()
+	e.SetEnv("PWD", dir)
+	input := `ls /nonexistent 2>/dev/null; echo "ls $?"
+cd /nonexistent 2>/dev/null || echo cd
+{ ls /nonexistent; } 2>/dev/null
+ls /nonexistent 2>err; cat err`
+	stdout, stderr, _ := e.Capture(parser.New(lexer.New("{ " + input + "\n}")).ParseProgram())
+	if stderr != "" {
+		t.Fatalf("重定向的错误消息不应该输出到 shell 的标准错误: %q", stderr)
+	}
+	if !strings.HasPrefix(stdout, "ls 2\ncd\n") || !strings.Contains(stdout, "ls: ") || strings.Contains(stdout, "ls: ls:") {
+		t.Errorf("输出 %q", stdout)
+	}
+
+	_, stderr, _ = e.Capture(parser.New(lexer.New("cat /nonexistent | cat")).ParseProgram())
+	if !strings.Contains(stderr, "cat: ") || strings.Contains(stderr, "cat: cat:") {
+		t.Errorf("管道中内置命令的错误消息 %q", stderr)
+	}
+}
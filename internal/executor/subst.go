@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+	"gobash/internal/i18n"
 	"gobash/internal/parser"
 )
 
@@ -35,30 +37,50 @@ func (e *Executor) expandParamExpression(pe *parser.ParamExpandExpression) (stri
 	varName := pe.VarName
 	op := pe.Op
 	word := pe.Word
+	quoted := ExpandFlags(pe.Flags)&ExpandFlagQuoted != 0
 	
-	// 获取变量值
-	varValue := e.env[varName]
-	if varValue == "" {
-		varValue = os.Getenv(varName)
+	// ${!arr[@]} 解析为变量名 !arr 和 [@]
+	if len(varName) > 1 && varName[0] == '!' && op == "" {
+		varName, op = varName[1:], "!"
+	}
+	// ${#VAR}、${#arr[@]} 解析为变量名 #VAR、#arr
+	if len(varName) > 1 && varName[0] == '#' && op == "" {
+		return e.expandLength(varName[1:], word), nil
+	}
+	// 获取变量值（名称引用使用它引用的变量）
+	if op != "!" {
+		varName = e.resolveNameref(varName)
 	}
+	e.refreshDynamic(varName)
+	varValue := e.env[varName]
 	
 	// 处理数组访问 ${arr[0]} 或 ${arr[key]} 或 ${arr[@]} 或 ${arr[*]}
-	if strings.HasPrefix(word, "[") {
+	if strings.HasPrefix(word, "[") && (op == "" || op == "!") {
 		// 解析数组索引或展开符号
 		// 格式：[0], [key], [@], [*]
 		idxEnd := strings.Index(word, "]")
 		if idxEnd == -1 {
-			return "", fmt.Errorf("未闭合的数组索引: %s", word)
+			return "", i18n.Errorf("未闭合的数组索引: %s", word)
 		}
 		indexStr := word[1:idxEnd] // 去掉 [ 和 ]
 		
-		// 处理数组展开 ${arr[@]} 或 ${arr[*]}
+		// 处理数组展开 ${arr[@]} 或 ${arr[*]}，${!arr[@]} 展开为数组的下标
+		if (indexStr == "@" || indexStr == "*") && op == "!" {
+			return e.arrayKeys(varName), nil
+		}
 		if indexStr == "@" || indexStr == "*" {
 			return e.expandArray(varName, indexStr == "@"), nil
 		}
 		
-		// 处理数组元素访问 ${arr[0]} 或 ${arr[key]}
-		return e.getArrayElement(varName + word), nil
+		// 处理数组元素访问 ${arr[0]} 或 ${arr[key]}，${!arr[0]} 是以元素的值为名称的间接引用
+		value, err := e.getArrayElement(varName + word)
+		if err != nil {
+			return "", err
+		}
+		if op == "!" {
+			return e.indirectValue(value), nil
+		}
+		return value, nil
 	}
 	
 	// 根据操作符进行展开
@@ -70,16 +92,15 @@ func (e *Executor) expandParamExpression(pe *parser.ParamExpandExpression) (stri
 	case ":-":
 		// ${VAR:-word} - 如果 VAR 未设置或为空，使用 word
 		if varValue == "" {
-			return e.expandWord(word), nil
+			return e.expandWord(word, quoted), nil
 		}
 		return varValue, nil
 		
 	case ":=":
 		// ${VAR:=word} - 如果 VAR 未设置或为空，将 word 赋值给 VAR
 		if varValue == "" {
-			expandedWord := e.expandWord(word)
+			expandedWord := e.expandWord(word, quoted)
 			e.env[varName] = expandedWord
-			os.Setenv(varName, expandedWord)
 			return expandedWord, nil
 		}
 		return varValue, nil
@@ -91,7 +112,7 @@ func (e *Executor) expandParamExpression(pe *parser.ParamExpandExpression) (stri
 		if errorMsg == "" {
 			errorMsg = fmt.Sprintf("%s: parameter null or not set", varName)
 		} else {
-			errorMsg = e.expandWord(errorMsg)
+			errorMsg = e.expandWord(errorMsg, quoted)
 		}
 		return "", fmt.Errorf("%s", errorMsg)
 		}
@@ -100,112 +121,86 @@ func (e *Executor) expandParamExpression(pe *parser.ParamExpandExpression) (stri
 	case ":+":
 		// ${VAR:+word} - 如果 VAR 已设置且非空，使用 word，否则为空
 		if varValue != "" {
-			return e.expandWord(word), nil
+			return e.expandWord(word, quoted), nil
 		}
 		return "", nil
 		
-	case "#":
-		// ${VAR#pattern} - 删除最短匹配前缀
-		if varValue == "" {
-			return "", nil
-		}
-		pattern := e.expandWord(word)
-		if strings.HasPrefix(varValue, pattern) {
-			return varValue[len(pattern):], nil
-		}
-		return varValue, nil
-		
-	case "##":
-		// ${VAR##pattern} - 删除最长匹配前缀
-		if varValue == "" {
-			return "", nil
-		}
-		pattern := e.expandWord(word)
-		// 使用正则表达式匹配最长前缀
-		re := regexp.MustCompile("^" + regexp.QuoteMeta(pattern))
-		return re.ReplaceAllString(varValue, ""), nil
-		
-	case "%":
-		// ${VAR%pattern} - 删除最短匹配后缀
+	case "#", "##", "%", "%%":
+		// ${VAR#pattern}、${VAR##pattern} - 删除最短、最长匹配前缀
+		// ${VAR%pattern}、${VAR%%pattern} - 删除最短、最长匹配后缀
 		if varValue == "" {
 			return "", nil
 		}
-		pattern := e.expandWord(word)
-		if strings.HasSuffix(varValue, pattern) {
-			return varValue[:len(varValue)-len(pattern)], nil
-		}
-		return varValue, nil
+		return removePattern(varValue, op, e.expandWord(word, quoted)), nil
 		
-	case "%%":
-		// ${VAR%%pattern} - 删除最长匹配后缀
-		if varValue == "" {
-			return "", nil
-		}
-		pattern := e.expandWord(word)
-		// 使用正则表达式匹配最长后缀
-		re := regexp.MustCompile(regexp.QuoteMeta(pattern) + "$")
-		return re.ReplaceAllString(varValue, ""), nil
+	case "/", "//":
+		// ${VAR/pattern/string} - 替换第一个匹配，${VAR//pattern/string} - 替换所有匹配
+		pattern, replacement := splitReplacement(word)
+		return replacePattern(varValue, e.expandWord(pattern, quoted), e.expandWord(replacement, quoted), op == "//"), nil
+
+	case "/#", "/%":
+		// ${VAR/#pattern/string}、${VAR/%pattern/string} - 替换匹配的最长前缀、最长后缀
+		pattern, replacement := splitReplacement(word)
+		return replaceAnchored(varValue, op, e.expandWord(pattern, quoted), e.expandWord(replacement, quoted)), nil
 		
 	case ":":
 		// ${VAR:offset} 或 ${VAR:offset:length} - 子字符串
 		if varValue == "" {
 			return "", nil
 		}
+		// 偏移量和长度是算术表达式，按字符（而不是字节）计算
+		runes := []rune(varValue)
 		parts := strings.Split(word, ":")
 		if len(parts) == 1 {
 			// ${VAR:offset}
-			offset, err := strconv.Atoi(parts[0])
+			offset, err := e.arithmeticInt(parts[0])
 			if err != nil {
 				return "", fmt.Errorf("invalid offset: %s", parts[0])
 			}
 			if offset < 0 {
-				offset = len(varValue) + offset
+				offset = len(runes) + offset
 			}
-			if offset < 0 || offset >= len(varValue) {
+			if offset < 0 || offset >= len(runes) {
 				return "", nil
 			}
-			return varValue[offset:], nil
+			return string(runes[offset:]), nil
 		} else if len(parts) == 2 {
 			// ${VAR:offset:length}
-			offset, err := strconv.Atoi(parts[0])
+			offset, err := e.arithmeticInt(parts[0])
 			if err != nil {
 				return "", fmt.Errorf("invalid offset: %s", parts[0])
 			}
-			length, err := strconv.Atoi(parts[1])
+			length, err := e.arithmeticInt(parts[1])
 			if err != nil {
 				return "", fmt.Errorf("invalid length: %s", parts[1])
 			}
 			if offset < 0 {
-				offset = len(varValue) + offset
+				offset = len(runes) + offset
 			}
-			if offset < 0 || offset >= len(varValue) {
+			if offset < 0 || offset >= len(runes) {
 				return "", nil
 			}
 			if length < 0 {
-				length = len(varValue) - offset + length
+				length = len(runes) - offset + length
 			}
 			if length <= 0 {
 				return "", nil
 			}
 			end := offset + length
-			if end > len(varValue) {
-				end = len(varValue)
+			if end > len(runes) {
+				end = len(runes)
 			}
-			return varValue[offset:end], nil
+			return string(runes[offset:end]), nil
 		}
 		return varValue, nil
 		
+	case "^^", "^", ",,", ",":
+		// ${VAR^^pattern}、${VAR,,pattern} - 转换所有匹配的字符为大写、小写，${VAR^}、${VAR,} 只转换第一个字符
+		return e.convertCase(varValue, op, e.expandWord(word, quoted)), nil
+		
 	case "!":
 		// ${!VAR} - 间接引用
-		indirectVarName := varValue
-		if indirectVarName == "" {
-			return "", nil
-		}
-		indirectValue := e.env[indirectVarName]
-		if indirectValue == "" {
-			indirectValue = os.Getenv(indirectVarName)
-		}
-		return indirectValue, nil
+		return e.expandIndirect(varName), nil
 		
 	default:
 		// 未知操作符，返回原值
@@ -213,6 +208,115 @@ func (e *Executor) expandParamExpression(pe *parser.ParamExpandExpression) (stri
 	}
 }
 
+// patternCuts 返回 value 中所有字符边界的字节位置（包括开头和结尾）
+func patternCuts(value string) []int {
+	cuts := make([]int, 0, len(value)+1)
+	for i := range value {
+		cuts = append(cuts, i)
+	}
+	return append(cuts, len(value))
+}
+
+// removePattern 按 case 的通配符规则删除 value 中匹配 pattern 的前缀或后缀
+// op 为 # 或 ## 时删除最短或最长前缀，为 % 或 %% 时删除最短或最长后缀
+func removePattern(value, op, pattern string) string {
+	cuts := patternCuts(value)
+	switch op {
+	case "#":
+		for _, c := range cuts {
+			if matchPattern(value[:c], pattern) {
+				return value[c:]
+			}
+		}
+	case "##":
+		for i := len(cuts) - 1; i >= 0; i-- {
+			if matchPattern(value[:cuts[i]], pattern) {
+				return value[cuts[i]:]
+			}
+		}
+	case "%":
+		for i := len(cuts) - 1; i >= 0; i-- {
+			if matchPattern(value[cuts[i]:], pattern) {
+				return value[:cuts[i]]
+			}
+		}
+	case "%%":
+		for _, c := range cuts {
+			if matchPattern(value[c:], pattern) {
+				return value[:c]
+			}
+		}
+	}
+	return value
+}
+
+// splitReplacement 在第一个未转义的 / 处把 ${VAR/pattern/string} 的 word 拆成模式和替换串
+func splitReplacement(word string) (string, string) {
+	for i := 0; i < len(word); i++ {
+		if word[i] == '\\' {
+			i++
+		} else if word[i] == '/' {
+			return word[:i], word[i+1:]
+		}
+	}
+	return word, ""
+}
+
+// replacePattern 按 case 的通配符规则把 value 中匹配 pattern 的最长子串替换为 replacement
+// all 为 false 时只替换第一个匹配，模式为空时不替换
+func replacePattern(value, pattern, replacement string, all bool) string {
+	if pattern == "" {
+		return value
+	}
+	cuts := patternCuts(value)
+	var result strings.Builder
+	last := 0
+	for i := 0; i < len(cuts)-1; i++ {
+		start := cuts[i]
+		if start < last {
+			continue
+		}
+		end := -1
+		for j := len(cuts) - 1; j > i; j-- {
+			if matchPattern(value[start:cuts[j]], pattern) {
+				end = cuts[j]
+				break
+			}
+		}
+		if end == -1 {
+			continue
+		}
+		result.WriteString(value[last:start])
+		result.WriteString(replacement)
+		last = end
+		if !all {
+			break
+		}
+	}
+	result.WriteString(value[last:])
+	return result.String()
+}
+
+// replaceAnchored 按 case 的通配符规则把 value 中匹配 pattern 的最长前缀（op 为 /#）或最长后缀（op 为 /%）替换为 replacement
+// 模式为空时匹配空串，即在开头或结尾插入 replacement
+func replaceAnchored(value, op, pattern, replacement string) string {
+	cuts := patternCuts(value)
+	if op == "/#" {
+		for i := len(cuts) - 1; i >= 0; i-- {
+			if matchPattern(value[:cuts[i]], pattern) {
+				return replacement + value[cuts[i]:]
+			}
+		}
+		return value
+	}
+	for _, c := range cuts {
+		if matchPattern(value[c:], pattern) {
+			return value[:c] + replacement
+		}
+	}
+	return value
+}
+
 // wordSplit 根据 IFS 分割单词
 // 根据 bash 的行为：
 // 1. 如果 IFS 未设置或为空，不进行分割（返回单个单词）
@@ -354,7 +458,7 @@ func (e *Executor) pathnameExpand(pattern string) []string {
 	}
 	
 	// 使用 filepath.Glob 进行匹配
-	matches, err := filepath.Glob(pattern)
+	matches, err := e.glob(pattern)
 	if err != nil {
 		// 如果出错，返回原始模式
 		return []string{pattern}
@@ -385,6 +489,29 @@ func (e *Executor) pathnameExpand(pattern string) []string {
 	return matches
 }
 
+// glob 与 filepath.Glob 相同，但相对模式按执行器的工作目录匹配，返回的路径仍是相对路径
+func (e *Executor) glob(pattern string) ([]string, error) {
+	if filepath.IsAbs(pattern) {
+		return filepath.Glob(pattern)
+	}
+	dir := e.Dir()
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	// filepath.Join 会去掉开头的 ./，结果中需要加回来
+	prefix := ""
+	if strings.HasPrefix(pattern, "./") {
+		prefix = "./"
+	}
+	for i, match := range matches {
+		if rel, err := filepath.Rel(dir, match); err == nil {
+			matches[i] = prefix + rel
+		}
+	}
+	return matches, nil
+}
+
 // pathnameExpandRecursive 递归路径名展开（支持 **）
 // ** 匹配零个或多个目录和子目录
 func (e *Executor) pathnameExpandRecursive(pattern string) []string {
@@ -415,7 +542,7 @@ func (e *Executor) pathnameExpandRecursive(pattern string) []string {
 		prefixMatches := e.pathnameExpand(prefix)
 		result := []string{}
 		for _, pm := range prefixMatches {
-			info, err := os.Stat(pm)
+			info, err := os.Stat(e.resolvePath(pm))
 			if err == nil && info.IsDir() {
 				subMatches := e.matchRecursive(pm, "*")
 				result = append(result, subMatches...)
@@ -435,7 +562,7 @@ func (e *Executor) pathnameExpandRecursive(pattern string) []string {
 		prefixMatches := e.pathnameExpand(prefix)
 		result := []string{}
 		for _, pm := range prefixMatches {
-			info, err := os.Stat(pm)
+			info, err := os.Stat(e.resolvePath(pm))
 			if err == nil && info.IsDir() {
 				// 递归匹配后缀
 				subMatches := e.matchRecursive(pm, suffix)
@@ -443,7 +570,7 @@ func (e *Executor) pathnameExpandRecursive(pattern string) []string {
 			}
 			// 也检查前缀本身是否匹配完整模式
 			fullPath := filepath.Join(pm, suffix)
-			if matches, err := filepath.Glob(fullPath); err == nil {
+			if matches, err := e.glob(fullPath); err == nil {
 				result = append(result, matches...)
 			}
 		}
@@ -463,7 +590,7 @@ func (e *Executor) matchRecursive(dir string, pattern string) []string {
 	result := []string{}
 	
 	// 读取目录
-	entries, err := os.ReadDir(dir)
+	entries, err := os.ReadDir(e.resolvePath(dir))
 	if err != nil {
 		return result
 	}
@@ -544,10 +671,10 @@ func (e *Executor) tildeExpand(text string) string {
 	
 	// 处理 `~`
 	if text == "~" {
-		home := os.Getenv("HOME")
+		home := e.env["HOME"]
 		if home == "" {
 			// Windows 上使用 USERPROFILE
-			home = os.Getenv("USERPROFILE")
+			home = e.env["USERPROFILE"]
 		}
 		if home == "" {
 			// 如果都没有，返回原始文本
@@ -558,10 +685,7 @@ func (e *Executor) tildeExpand(text string) string {
 	
 	// 处理 `~+` - 当前工作目录
 	if strings.HasPrefix(text, "~+") {
-		pwd := os.Getenv("PWD")
-		if pwd == "" {
-			pwd, _ = os.Getwd()
-		}
+		pwd := e.Dir()
 		if pwd == "" {
 			return text
 		}
@@ -574,7 +698,7 @@ func (e *Executor) tildeExpand(text string) string {
 	
 	// 处理 `~-` - 上一个工作目录
 	if strings.HasPrefix(text, "~-") {
-		oldpwd := os.Getenv("OLDPWD")
+		oldpwd := e.env["OLDPWD"]
 		if oldpwd == "" {
 			return text
 		}
@@ -614,10 +738,10 @@ func (e *Executor) tildeExpand(text string) string {
 // getUserHomeDir 获取用户主目录
 func (e *Executor) getUserHomeDir(username string) string {
 	// 如果是当前用户
-	if username == "" || username == os.Getenv("USER") || username == os.Getenv("USERNAME") {
-		home := os.Getenv("HOME")
+	if username == "" || username == e.env["USER"] || username == e.env["USERNAME"] {
+		home := e.env["HOME"]
 		if home == "" {
-			home = os.Getenv("USERPROFILE")
+			home = e.env["USERPROFILE"]
 		}
 		return home
 	}
@@ -629,7 +753,7 @@ func (e *Executor) getUserHomeDir(username string) string {
 	
 	// 尝试从环境变量获取（某些系统可能设置）
 	envKey := "HOME_" + username
-	if home := os.Getenv(envKey); home != "" {
+	if home := e.env[envKey]; home != "" {
 		return home
 	}
 	
@@ -637,19 +761,69 @@ func (e *Executor) getUserHomeDir(username string) string {
 	return ""
 }
 
-// expandWord 展开 word（可能包含变量、命令替换等）
-func (e *Executor) expandWord(word string) string {
-	// 简单的实现：展开变量
-	result := e.expandVariablesInString(word)
+// expandWord 展开参数展开中的 word（如 ${VAR:-word} 的 word）
+// 参数展开在双引号中时 word 按双引号字符串展开（单引号是普通字符），否则与未加引号的单词一样去掉引号
+func (e *Executor) expandWord(word string, quoted bool) string {
+	mode := quoteNone
+	if quoted {
+		mode = quoteDoubleWord
+	}
+	result, _ := e.expandText(word, mode)
 	return result
 }
 
-// expandStringLength 展开字符串长度 ${#VAR}
-func (e *Executor) expandStringLength(varName string) string {
-	varValue := e.env[varName]
-	if varValue == "" {
-		varValue = os.Getenv(varName)
+// expandLength 展开 ${#VAR}（字符串的字符数）、${#arr[@]}（数组的元素个数）和 ${#arr[i]}（元素的字符数）
+func (e *Executor) expandLength(varName, subscript string) string {
+	varName = e.resolveNameref(varName)
+	switch subscript {
+	case "":
+		return strconv.Itoa(utf8.RuneCountInString(e.env[varName]))
+	case "[@]", "[*]":
+		if assoc, ok := e.assocArrays[varName]; ok {
+			return strconv.Itoa(len(assoc))
+		}
+		return strconv.Itoa(len(e.arrays[varName]))
+	}
+	value, _ := e.getArrayElement(varName + subscript)
+	return strconv.Itoa(utf8.RuneCountInString(value))
+}
+
+// convertCase 展开 ${VAR^^}、${VAR^}、${VAR,,}、${VAR,} 的大小写转换，pattern 不为空时只转换与它匹配的字符
+// 与 bash 一样，LC_ALL、LC_CTYPE 或 LANG 指定的 locale 为 C 或 POSIX（或都没有设置）时只转换 ASCII 字母
+func (e *Executor) convertCase(value, op, pattern string) string {
+	upper := op[0] == '^'
+	unicodeCase := e.unicodeLocale()
+	runes := []rune(value)
+	for i, r := range runes {
+		if i > 0 && len(op) == 1 {
+			break
+		}
+		if pattern != "" {
+			if matched, _ := filepath.Match(pattern, string(r)); !matched {
+				continue
+			}
+		}
+		switch {
+		case unicodeCase && upper:
+			runes[i] = unicode.ToUpper(r)
+		case unicodeCase:
+			runes[i] = unicode.ToLower(r)
+		case upper && r >= 'a' && r <= 'z':
+			runes[i] = r - 'a' + 'A'
+		case !upper && r >= 'A' && r <= 'Z':
+			runes[i] = r - 'A' + 'a'
+		}
+	}
+	return string(runes)
+}
+
+// unicodeLocale 判断当前的字符类型 locale（依次取 LC_ALL、LC_CTYPE、LANG 中第一个不为空的值）是否不是 C 或 POSIX
+func (e *Executor) unicodeLocale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := e.env[name]; locale != "" {
+			return locale != "C" && locale != "POSIX"
+		}
 	}
-	return strconv.Itoa(len(varValue))
+	return false
 }
 
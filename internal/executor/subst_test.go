@@ -1,6 +1,8 @@
 package executor
 
 import (
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
 	"os"
 	"strings"
 	"testing"
@@ -228,3 +230,91 @@ func TestExpandArray(t *testing.T) {
 	}
 }
 
+
+func TestExpandUnicode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`x=中文abc; echo ${#x} ${x:1:3} ${x: -2} "${#x}" "${x:0:2}"`, "5 文ab bc 5 中文\n"},
+		{`arr=(é b c); echo ${#arr[@]} ${#arr[0]} ${#arr}`, "3 1 1\n"},
+		{`x=héllo; echo ${x^^} ${x^} ${x,,} "${x^^[lh]}"`, "HéLLO Héllo héllo HéLLo\n"},
+		{`x=héllo; LANG=zh_CN.UTF-8; echo ${x^^} ${x^^[é]}`, "HÉLLO hÉllo\n"},
+		{`x=ÉCOLE; LC_ALL=C; LANG=en_US.UTF-8; echo ${x,,} ${x,}`, "École ÉCOLE\n"},
+	}
+	for _, tt := range tests {
+		stdout, _, err := New().Capture(parser.New(lexer.New(tt.input)).ParseProgram())
+		if err != nil || stdout != tt.want {
+			t.Errorf("%s 输出 %q（%v），期望 %q", tt.input, stdout, err, tt.want)
+		}
+	}
+}
+
+// TestExpandText 测试双引号字符串、here-document 和未加引号的单词按各自的引号规则展开
+func TestExpandText(t *testing.T) {
+	e := New()
+	e.env["x"] = "hello"
+	e.env["e"] = ""
+	e.env["p"] = "/a/b.c.d"
+	e.env["1"] = "one"
+	e.arrays["a"] = []string{"p", "q"}
+
+	tests := []struct {
+		input string
+		mode  quoteMode
+		want  string
+	}{
+		{`${e:-"a b"} ${e:-'q'}`, quoteDoubleWord, "a b 'q'"},
+		{`$x ${x} $1 $10 ${a[1]} $a[0]`, quoteDouble, "hello hello one one0 q p"},
+		{"\\$x \\\"q\\\" \\\\ \\n \\`", quoteDouble, "$x \"q\" \\ \\n `"},
+		{"a\\\nb", quoteDouble, "ab"},
+		{`'$x' $`, quoteDouble, "'hello' $"},
+		{`${e:-$(echo })} ${e:-"a}b"}`, quoteDouble, "} a}b"},
+		{`${e:-'q'} ${e:-it's}`, quoteDouble, "'q' it's"},
+		{`$(echo ")") $((1+(2*3))) $(( $(echo 2) * 3 ))`, quoteDouble, ") 7 6"},
+		{"`echo bq` $(case a in a) echo c;; esac)", quoteDouble, "bq c"},
+		{`${#x} ${x:1:2} ${x^^} ${#a[@]}`, quoteDouble, "5 el HELLO 2"},
+		{`\"$x\" \$x \\`, quoteHereDoc, `\"hello\" $x \`},
+		{`'$x' "$x" a\ b \$x`, quoteNone, "$x hello a b $x"},
+		{`"a b"'c d'`, quoteNone, "a bc d"},
+	}
+	for _, tt := range tests {
+		got, err := e.expandText(tt.input, tt.mode)
+		if err != nil || got != tt.want {
+			t.Errorf("expandText(%q, %d) = %q（%v），期望 %q", tt.input, tt.mode, got, err, tt.want)
+		}
+	}
+
+	// 参数展开的单词：在双引号中时引号是普通字符，否则去掉引号
+	for _, tt := range []struct {
+		input, want string
+	}{
+		{`${e:-"a b"}`, "a b"},
+		{`${e:-'q'} "${e:-'q'}"`, "q 'q'"},
+		{`${e:-\$x}`, "$x"},
+		{`${p##*/} ${p%.*} ${p%%.*} ${p#*.}`, "b.c.d /a/b.c /a/b c.d"},
+		{`${p/b/B} ${p//./_} ${p/x/y} ${p/.*/}`, "/a/B.c.d /a/b_c_d /a/b.c.d /a/b"},
+		{`${x/#h/H} ${x/%o/O} ${x/#e/E} ${x/%l/L} ${x/#/<} ${x/%/>}`, "Hello hellO hello hello <hello hello>"},
+		{`${x/[l]/L} ${x//[!a-z]/-} ${p//[!a-z]/-} ${p//[b-c]/X} ${p%[.]*}`, "heLlo hello -a-b-c-d /a/X.X.d /a/b.c"},
+		{`${x//[[:alpha:]]/a} ${p/#*\//} ${p/a#b/-}`, "aaaaa b.c.d /a/b.c.d"},
+	} {
+		out, err := runSubstScript(t, e, "echo "+tt.input)
+		if err != nil || out != tt.want+"\n" {
+			t.Errorf("echo %s 输出 %q（%v），期望 %q", tt.input, out, err, tt.want)
+		}
+	}
+}
+
+// TestExpandTextUnbound 测试 set -u 时各种展开中的未定义变量都返回错误
+func TestExpandTextUnbound(t *testing.T) {
+	e := New()
+	e.options["u"] = true
+	for _, input := range []string{"$nope", "${nope}", "${a[3]}", "$9", "${#nope}x$nope"} {
+		if _, err := e.expandText(input, quoteDouble); !isUnboundVariable(err) {
+			t.Errorf("expandText(%q) 应该返回未绑定变量错误，得到 %v", input, err)
+		}
+	}
+	if got, err := e.expandText("${nope:-d}", quoteDouble); err != nil || got != "d" {
+		t.Errorf("${nope:-d} = %q（%v）", got, err)
+	}
+}
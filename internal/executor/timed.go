@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/parser"
+	"strings"
+	"time"
+)
+
+// defaultTimeFormat 没有设置 TIMEFORMAT 时 time 使用的输出格式（与 bash 相同）
+const defaultTimeFormat = "\nreal\t%3lR\nuser\t%3lU\nsys\t%3lS"
+
+// posixTimeFormat time -p 使用的输出格式
+const posixTimeFormat = "real %2R\nuser %2U\nsys %2S"
+
+// executeTimed 执行 time [-p] pipeline，结束后把实际时间、用户态和内核态 CPU 时间写到标准错误
+// 输出格式由 TIMEFORMAT 决定（设置为空时不输出），-p 使用 POSIX 格式；管道的退出状态不变
+func (e *Executor) executeTimed(stmt *parser.TimedStatement) error {
+	start := time.Now()
+	startUser, startSys := cpuTimes()
+	var err error
+	if stmt.Statement != nil {
+		err = e.executeStatement(stmt.Statement)
+	} else {
+		e.env["?"] = "0"
+	}
+	if err != nil && !isFailureStatus(err) {
+		// exit、break 等控制流直接向上传播，不输出时间
+		return err
+	}
+	real := time.Since(start)
+	user, sys := cpuTimes()
+
+	format, ok := e.env["TIMEFORMAT"]
+	if !ok {
+		format = defaultTimeFormat
+	}
+	if stmt.Posix {
+		format = posixTimeFormat
+	}
+	if format != "" {
+		fmt.Fprintln(e.Stdio().Stderr, formatTimes(format, real, user-startUser, sys-startSys))
+	}
+	return err
+}
+
+// formatTimes 按 TIMEFORMAT 的格式输出时间
+// %[p][l]R、%[p][l]U、%[p][l]S 分别表示实际时间、用户态和内核态 CPU 时间，p 是小数位数（0 到 3，默认 3），
+// l 使用 XmY.YYYs 的长格式；%P 是 CPU 使用率（(U + S) / R），%% 是 %
+func formatTimes(format string, real, user, sys time.Duration) string {
+	var out strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			out.WriteByte(format[i])
+			continue
+		}
+		j := i + 1
+		precision, long := 3, false
+		if format[j] >= '0' && format[j] <= '9' {
+			precision = min(int(format[j]-'0'), 3)
+			j++
+		}
+		if j < len(format) && format[j] == 'l' {
+			long = true
+			j++
+		}
+		if j == len(format) {
+			out.WriteString(format[i:])
+			break
+		}
+		switch format[j] {
+		case 'R':
+			out.WriteString(formatDuration(real, precision, long))
+		case 'U':
+			out.WriteString(formatDuration(user, precision, long))
+		case 'S':
+			out.WriteString(formatDuration(sys, precision, long))
+		case 'P':
+			percent := 0.0
+			if real > 0 {
+				percent = float64(user+sys) / float64(real) * 100
+			}
+			fmt.Fprintf(&out, "%.2f", percent)
+		case '%':
+			out.WriteByte('%')
+		default:
+			// 无法识别的格式原样输出
+			out.WriteString(format[i : j+1])
+		}
+		i = j
+	}
+	return out.String()
+}
+
+// formatDuration 以秒为单位输出时间，保留 precision 位小数（截断而不是四舍五入）；long 为 true 时输出 XmY.YYYs
+func formatDuration(d time.Duration, precision int, long bool) string {
+	if d < 0 {
+		d = 0
+	}
+	seconds := int64(d / time.Second)
+	unit := time.Second
+	for i := 0; i < precision; i++ {
+		unit /= 10
+	}
+	fraction := int64(d % time.Second / unit)
+
+	var out string
+	if long {
+		out = fmt.Sprintf("%dm%d", seconds/60, seconds%60)
+	} else {
+		out = fmt.Sprintf("%d", seconds)
+	}
+	if precision > 0 {
+		out += fmt.Sprintf(".%0*d", precision, fraction)
+	}
+	if long {
+		out += "s"
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// TestTimed 测试 time 关键字：时间写到标准错误，输出格式由 TIMEFORMAT 和 -p 决定，退出状态不变
+func TestTimed(t *testing.T) {
+	tests := []struct {
+		input  string
+		stdout string
+		stderr string // 正则表达式
+	}{
+		{"time echo hi", "hi\n", `^\nreal\t0m0\.\d{3}s\nuser\t0m\d+\.\d{3}s\nsys\t0m\d+\.\d{3}s\n$`},
+		{"time -p echo hi | cat", "hi\n", `^real 0\.\d\d\nuser \d+\.\d\d\nsys \d+\.\d\d\n$`},
+		{"TIMEFORMAT='%0R|%1lR|%%|%x'; time sleep 0.1", "", `^0\|0m0\.1s\|%\|%x\n$`},
+		{"TIMEFORMAT=; time echo quiet", "quiet\n", `^$`},
+		{"TIMEFORMAT=x; time ! true || echo failed", "failed\n", `^x\n`},
+		{"TIMEFORMAT=x; time; echo $?", "0\n", `^x\n$`},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Errorf("%q 解析失败: %v", tt.input, p.Errors())
+			continue
+		}
+		stdout, stderr, _ := New().Capture(program)
+		if stdout != tt.stdout {
+			t.Errorf("%q 标准输出 %q，期望 %q", tt.input, stdout, tt.stdout)
+		}
+		if !regexp.MustCompile(tt.stderr).MatchString(stderr) {
+			t.Errorf("%q 错误输出 %q 不匹配 %q", tt.input, stderr, tt.stderr)
+		}
+	}
+}
+
+func TestFormatTimes(t *testing.T) {
+	real, user, sys := 61*time.Second+234567*time.Microsecond, 500*time.Millisecond, 250*time.Millisecond
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{defaultTimeFormat, "\nreal\t1m1.234s\nuser\t0m0.500s\nsys\t0m0.250s"},
+		{posixTimeFormat, "real 61.23\nuser 0.50\nsys 0.25"},
+		{"%R %0U %9S %P%%", "61.234 0 0.250 1.22%"},
+		{"%l", "%l"},
+	}
+	for _, tt := range tests {
+		if got := formatTimes(tt.format, real, user, sys); got != tt.want {
+			t.Errorf("formatTimes(%q) = %q，期望 %q", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/parser"
+	"io"
+	"strings"
+	"time"
+)
+
+// TraceEventType 跟踪事件类型
+type TraceEventType string
+
+const (
+	TraceCommandStart TraceEventType = "command-start" // 命令开始执行
+	TraceCommandEnd   TraceEventType = "command-end"   // 命令执行结束
+	TraceExpansion    TraceEventType = "expansion"     // 变量、命令替换、算术等展开
+)
+
+// TraceEvent 跟踪事件
+type TraceEvent struct {
+	Type     TraceEventType
+	Time     time.Time
+	Depth    int           // 命令嵌套深度（函数、命令替换等），顶层为 1
+	Command  string        // 命令文本（command-start/command-end）或展开前的单词（expansion）
+	Value    string        // 展开后的值（仅 expansion）
+	ExitCode int           // 退出码（仅 command-end）
+	Duration time.Duration // 执行耗时（仅 command-end）
+}
+
+// TraceSink 跟踪事件的接收者
+type TraceSink interface {
+	Trace(event TraceEvent)
+}
+
+// TraceSinkFunc 将普通函数适配为 TraceSink
+type TraceSinkFunc func(event TraceEvent)
+
+// Trace 实现 TraceSink 接口
+func (f TraceSinkFunc) Trace(event TraceEvent) {
+	f(event)
+}
+
+// writerTraceSink 将跟踪事件按行写入 io.Writer
+type writerTraceSink struct {
+	w io.Writer
+}
+
+// NewWriterTraceSink 创建写入 io.Writer 的跟踪接收者，每个事件一行
+func NewWriterTraceSink(w io.Writer) TraceSink {
+	return &writerTraceSink{w: w}
+}
+
+// Trace 实现 TraceSink 接口
+func (s *writerTraceSink) Trace(event TraceEvent) {
+	indent := strings.Repeat("  ", event.Depth-1)
+	stamp := event.Time.Format("15:04:05.000")
+	switch event.Type {
+	case TraceCommandEnd:
+		fmt.Fprintf(s.w, "[trace %s] %s%s: %s (exit=%d, %s)\n", stamp, indent, event.Type, event.Command, event.ExitCode, event.Duration)
+	case TraceExpansion:
+		fmt.Fprintf(s.w, "[trace %s] %s%s: %s => %q\n", stamp, indent, event.Type, event.Command, event.Value)
+	default:
+		fmt.Fprintf(s.w, "[trace %s] %s%s: %s\n", stamp, indent, event.Type, event.Command)
+	}
+}
+
+// SetTraceSink 设置跟踪事件的接收者，nil 表示输出到执行器的标准错误输出
+// 跟踪通过 set -o functrace 或环境变量 GOBASH_TRACE 开启
+func (e *Executor) SetTraceSink(sink TraceSink) {
+	e.traceSink = sink
+}
+
+// tracing 检查是否开启了跟踪
+func (e *Executor) tracing() bool {
+	if e.options["functrace"] {
+		return true
+	}
+	value := e.env["GOBASH_TRACE"]
+	return value != "" && value != "0"
+}
+
+// emitTrace 发送跟踪事件
+func (e *Executor) emitTrace(event TraceEvent) {
+	event.Time = time.Now()
+	event.Depth = e.traceDepth
+	if event.Depth < 1 {
+		event.Depth = 1
+	}
+	sink := e.traceSink
+	if sink == nil {
+		sink = NewWriterTraceSink(e.Stdio().Stderr)
+	}
+	sink.Trace(event)
+}
+
+// traceCommand 执行命令，发送命令开始和结束事件
+func (e *Executor) traceCommand(cmd *parser.CommandStatement) error {
+	text := strings.TrimSpace(cmd.String())
+	e.traceDepth++
+	defer func() { e.traceDepth-- }()
+
+	e.emitTrace(TraceEvent{Type: TraceCommandStart, Command: text})
+	start := time.Now()
+	err := e.runCommand(cmd)
+	e.emitTrace(TraceEvent{
+		Type:     TraceCommandEnd,
+		Command:  text,
+		ExitCode: ExitStatus(err),
+		Duration: time.Since(start),
+	})
+	return err
+}
+
+// evaluateExpression 求值表达式，开启跟踪时为展开（变量、命令替换、算术等）发送事件
+// 设置了 -u 选项时，展开未定义的变量返回错误
+func (e *Executor) evaluateExpression(expr parser.Expression) (string, error) {
+	value, err := e.expandExpression(expr)
+	if err != nil || !e.tracing() {
+		return value, err
+	}
+	switch ex := expr.(type) {
+	case *parser.Variable, *parser.CommandSubstitution, *parser.ArithmeticExpansion,
+		*parser.ProcessSubstitution, *parser.ParamExpandExpression:
+		e.emitTrace(TraceEvent{Type: TraceExpansion, Command: ex.String(), Value: value})
+	case *parser.StringLiteral:
+		if ex.IsQuote && value != ex.Value {
+			e.emitTrace(TraceEvent{Type: TraceExpansion, Command: ex.String(), Value: value})
+		}
+	}
+	return value, nil
+}
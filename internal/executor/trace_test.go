@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"testing"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+)
+
+// TestTraceEvents 测试跟踪事件（命令开始、结束和展开）
+func TestTraceEvents(t *testing.T) {
+	var events []TraceEvent
+	e := New()
+	e.SetTraceSink(TraceSinkFunc(func(event TraceEvent) {
+		events = append(events, event)
+	}))
+
+	run := func(input string) {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("解析 %q 失败: %v", input, p.Errors())
+		}
+		e.Execute(program)
+	}
+
+	// 未开启跟踪时不产生事件
+	run("X=abc")
+	if len(events) != 0 {
+		t.Fatalf("未开启跟踪时不应产生事件，得到 %d 个", len(events))
+	}
+
+	e.SetOptions(map[string]bool{"functrace": true})
+	run("true $X")
+
+	var types []TraceEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	expected := []TraceEventType{TraceCommandStart, TraceExpansion, TraceCommandEnd}
+	if len(types) != len(expected) {
+		t.Fatalf("事件类型 %v，期望 %v", types, expected)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Fatalf("事件类型 %v，期望 %v", types, expected)
+		}
+	}
+	if events[1].Command != "$X" || events[1].Value != "abc" {
+		t.Errorf("展开事件 %q => %q，期望 $X => abc", events[1].Command, events[1].Value)
+	}
+	if events[2].ExitCode != 0 {
+		t.Errorf("退出码 %d，期望 0", events[2].ExitCode)
+	}
+
+	// 通过环境变量开启跟踪
+	events = nil
+	e.SetOptions(map[string]bool{})
+	e.SetEnv("GOBASH_TRACE", "1")
+	run("false")
+	if len(events) != 2 || events[1].ExitCode == 0 {
+		t.Errorf("GOBASH_TRACE 开启后应记录 false 的非零退出码，得到 %+v", events)
+	}
+}
@@ -0,0 +1,168 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/builtin"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// 陷阱
+//
+// trap 设置的命令保存在 e.traps 中，执行器在这些执行点执行它们：
+// DEBUG 在每个简单命令执行之前（$BASH_COMMAND 是将要执行的命令），
+// ERR 在命令失败之后，与 set -e 的例外相同（if/while 条件、&& 和 || 左边、! 取反的命令不触发），
+// EXIT 在脚本、-c 命令、交互式 shell、子shell 或命令替换结束之前（包括 exit 和 set -e 导致的退出），
+// INT 和 TERM 在收到信号之后的下一个简单命令之前（正在运行的外部命令先收到信号）。
+// 与 bash 相同，函数、子shell 和命令替换中只有设置了 set -T（DEBUG）或 set -E（ERR）时才执行这两个陷阱；
+// 子shell 不继承 EXIT 和信号的陷阱，只继承被忽略（命令为空字符串）的信号
+
+// trapOptions 让陷阱在函数和子shell中也生效的选项
+var trapOptions = map[string]string{"DEBUG": "T", "ERR": "E"}
+
+// trapSignals 可以设置陷阱的信号
+var trapSignals = []struct {
+	cond string
+	sig  os.Signal
+}{{"INT", os.Interrupt}, {"TERM", syscall.SIGTERM}}
+
+// isTrapSignal 判断陷阱条件 cond 是否是信号
+func isTrapSignal(cond string) bool {
+	for _, s := range trapSignals {
+		if s.cond == cond {
+			return true
+		}
+	}
+	return false
+}
+
+// inheritTraps 返回子shell 继承的陷阱：只有设置了对应的选项时才继承 DEBUG 和 ERR，信号只继承忽略
+func (e *Executor) inheritTraps() map[string]string {
+	traps := make(map[string]string, len(e.traps))
+	for cond, action := range e.traps {
+		if option, ok := trapOptions[cond]; ok && e.options[option] || isTrapSignal(cond) && action == "" {
+			traps[cond] = action
+		}
+	}
+	return traps
+}
+
+// handleSignals 按陷阱表监听信号，然后执行已经收到的信号的陷阱
+// 设置了陷阱（或者被忽略）的信号不再终止 shell；删除陷阱后恢复信号的默认处理
+func (e *Executor) handleSignals() error {
+	var conds []string
+	var trapped []os.Signal
+	for _, s := range trapSignals {
+		if _, ok := e.traps[s.cond]; ok {
+			conds = append(conds, s.cond)
+			trapped = append(trapped, s.sig)
+		}
+	}
+	if key := strings.Join(conds, " "); key != e.signalTraps {
+		// 先用新的通道监听再停止旧的通道，仍然有陷阱的信号在切换过程中不会终止 shell
+		var signals chan os.Signal
+		if len(trapped) > 0 {
+			signals = make(chan os.Signal, len(trapped))
+			signal.Notify(signals, trapped...)
+		}
+		e.stopSignals()
+		e.signals, e.signalTraps = signals, key
+	}
+	for e.signals != nil {
+		select {
+		case sig := <-e.signals:
+			for _, s := range trapSignals {
+				if s.sig == sig {
+					if err := e.runTrap(s.cond); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// stopSignals 停止监听信号，恢复信号的默认处理
+func (e *Executor) stopSignals() {
+	if e.signals != nil {
+		signal.Stop(e.signals)
+		e.signals, e.signalTraps = nil, ""
+	}
+}
+
+// RunExitTrap 在 shell 结束之前执行 EXIT 陷阱（只执行一次），err 是执行的结果
+// 陷阱中的 $? 是 shell 的退出状态；陷阱中的 exit 改变退出码，返回对应的 ExitError，否则返回 err
+func (e *Executor) RunExitTrap(err error) error {
+	if trapErr := e.handleSignals(); trapErr != nil && err == nil {
+		err = trapErr
+	}
+	e.stopSignals()
+	action, ok := e.traps["EXIT"]
+	if !ok || action == "" || e.inTrap {
+		return err
+	}
+	delete(e.traps, "EXIT")
+	if err != nil {
+		e.env["?"] = strconv.Itoa(ExitStatus(err))
+	}
+	if trapErr := e.executeTrap(action); trapErr != nil {
+		if exitErr, ok := trapErr.(*builtin.ExitError); ok {
+			return exitErr
+		}
+	}
+	return err
+}
+
+// commandTexts 语句的源代码，循环中的命令每次执行都要设置 $BASH_COMMAND，只输出一次
+var commandTexts = newCache[parser.Statement, string]()
+
+// setBashCommand 记录正在执行的命令（$BASH_COMMAND），陷阱命令中不更新
+func (e *Executor) setBashCommand(stmt parser.Statement) {
+	if !e.inTrap {
+		e.env["BASH_COMMAND"] = commandTexts.get(stmt, stmt.String)
+	}
+}
+
+// runTrap 执行条件 cond 的陷阱命令，陷阱命令中的命令不再触发陷阱
+func (e *Executor) runTrap(cond string) error {
+	action := e.traps[cond]
+	if action == "" || e.inTrap {
+		return nil
+	}
+	if option, ok := trapOptions[cond]; ok && e.inFunction() && !e.options[option] {
+		return nil
+	}
+	return e.executeTrap(action)
+}
+
+// executeTrap 执行陷阱命令 action，$? 和 $LINENO 在执行前后不变；
+// 陷阱命令中的 exit 等控制流错误返回给调用者，其他错误只输出到标准错误
+func (e *Executor) executeTrap(action string) error {
+	p := parser.New(lexer.New(action))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		fmt.Fprintf(e.Stdio().Stderr, "%s: %s\n", e.errorPrefix(), errs[0])
+		return nil
+	}
+
+	status := e.env["?"]
+	e.inTrap = true
+	err := e.executeBlock(&parser.BlockStatement{Statements: program.Statements})
+	e.inTrap = false
+	if err != nil && !isFailureStatus(err) {
+		return err
+	}
+	if !StatusOnly(err) {
+		fmt.Fprintf(e.Stdio().Stderr, "%s: %v\n", e.errorPrefix(), err)
+	}
+	e.env["?"] = status
+	return nil
+}
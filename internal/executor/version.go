@@ -0,0 +1,61 @@
+package executor
+
+import "runtime"
+
+// Version gobash 的版本号，是 $GOBASH_VERSION 的值；cmd/gobash 在创建 shell 之前设置（构建时用 -ldflags "-X main.version=..." 指定）
+var Version = "dev"
+
+// bashVersion 兼容的 bash 版本，脚本可以用 $BASH_VERSION 和 ${BASH_VERSINFO[0]} 判断支持的功能（关联数组、${x^^} 等）
+var bashVersion = []string{"5", "2", "0", "1", "release"}
+
+// initVersionVariables 设置 GOBASH_VERSION、BASH_VERSION、BASH_VERSINFO 以及 OSTYPE、HOSTTYPE、MACHTYPE，它们都不导出
+// OSTYPE 与各平台上的 bash 相同（Linux 为 linux-gnu，macOS 为 darwin，Windows 与 Git Bash 相同为 msys），脚本可以据此区分平台
+func (e *Executor) initVersionVariables() {
+	machType := MachType()
+	e.env["GOBASH_VERSION"] = Version
+	e.env["BASH_VERSION"] = bashVersion[0] + "." + bashVersion[1] + "." + bashVersion[2] + "(" + bashVersion[3] + ")-" + bashVersion[4]
+	e.arrays["BASH_VERSINFO"] = append(append([]string(nil), bashVersion...), machType)
+	e.arrayTypes["BASH_VERSINFO"] = "array"
+	e.env["OSTYPE"] = osType()
+	e.env["HOSTTYPE"] = hostType()
+	e.env["MACHTYPE"] = machType
+}
+
+// MachType 返回 $MACHTYPE 的值（CPU-厂商-系统），如 x86_64-pc-linux-gnu、arm64-apple-darwin
+func MachType() string {
+	vendor := "unknown"
+	switch {
+	case runtime.GOOS == "darwin":
+		vendor = "apple"
+	case runtime.GOARCH == "amd64" || runtime.GOARCH == "386":
+		vendor = "pc"
+	}
+	return hostType() + "-" + vendor + "-" + osType()
+}
+
+// osType 返回 $OSTYPE 的值
+func osType() string {
+	switch runtime.GOOS {
+	case "linux":
+		return "linux-gnu"
+	case "windows":
+		return "msys"
+	}
+	return runtime.GOOS
+}
+
+// hostType 返回 $HOSTTYPE 的值（与 uname -m 相同的 CPU 名称）
+func hostType() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64"
+	case "386":
+		return "i686"
+	case "arm64":
+		if runtime.GOOS == "darwin" {
+			return "arm64"
+		}
+		return "aarch64"
+	}
+	return runtime.GOARCH
+}
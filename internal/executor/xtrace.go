@@ -0,0 +1,150 @@
+package executor
+
+import (
+	"fmt"
+	"gobash/internal/parser"
+	"strings"
+)
+
+// xtracePrefix 返回 set -x 输出的前缀
+// 前缀是展开后的 PS4（默认 "+ "），与 bash 一样，命令替换每深一层，首字符重复一次
+func (e *Executor) xtracePrefix() string {
+	ps4, ok := e.env["PS4"]
+	if !ok {
+		ps4 = "+ "
+	}
+	ps4 = e.expandVariablesInString(ps4)
+	if ps4 == "" {
+		return ""
+	}
+	return strings.Repeat(ps4[:1], e.xtraceLevel) + ps4
+}
+
+// xtrace 在开启 set -x 时输出一条展开后的简单命令
+// words 是命令名和参数（已展开）；与 bash 相同，重定向不输出
+func (e *Executor) xtrace(words []string) {
+	if !e.options["x"] {
+		return
+	}
+	fmt.Fprintln(e.Stdio().Stderr, e.xtracePrefix()+e.formatCommand(words, nil))
+}
+
+// xtraceCond 在开启 set -x 时输出 [[ ]] 中求值的一个测试（已展开），与 bash 相同，每个测试单独输出一行
+func (e *Executor) xtraceCond(words ...string) {
+	if !e.options["x"] {
+		return
+	}
+	fmt.Fprintf(e.Stdio().Stderr, "%s[[ %s ]]\n", e.xtracePrefix(), strings.Join(words, " "))
+}
+
+// xtraceArithmetic 在开启 set -x 时输出 (( 表达式 ))
+// 表达式中的变量展开后输出；含有命令替换或算术展开时输出原文，避免其中的命令和赋值执行两次
+func (e *Executor) xtraceArithmetic(expr string) {
+	if !e.options["x"] {
+		return
+	}
+	expr = strings.TrimSpace(expr)
+	if !strings.Contains(expr, "$(") && !strings.Contains(expr, "`") {
+		expr = e.expandVariablesInString(expr)
+	}
+	fmt.Fprintf(e.Stdio().Stderr, "%s(( %s ))\n", e.xtracePrefix(), expr)
+}
+
+// xtraceFor 在开启 set -x 时输出 for 循环的头部 for 变量 in 单词...（已展开），与 bash 相同，每次迭代之前输出一次
+func (e *Executor) xtraceFor(name string, values []string) {
+	if !e.options["x"] {
+		return
+	}
+	fmt.Fprintln(e.Stdio().Stderr, e.xtracePrefix()+e.formatCommand(append([]string{"for", name, "in"}, values...), nil))
+}
+
+// formatCommand 按 set -x 的格式渲染展开后的简单命令：单词按需要加引号，重定向附加在后面
+func (e *Executor) formatCommand(words []string, redirects []*parser.Redirect) string {
+	var out strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			out.WriteByte(' ')
+		}
+		out.WriteString(xtraceQuote(word))
+	}
+	for _, redirect := range redirects {
+		if text := e.xtraceRedirect(redirect); text != "" {
+			out.WriteByte(' ')
+			out.WriteString(text)
+		}
+	}
+	return out.String()
+}
+
+// xtraceAssignment 在开启 set -x 时输出变量赋值 name=value
+func (e *Executor) xtraceAssignment(name, value string) {
+	if !e.options["x"] {
+		return
+	}
+	fmt.Fprintf(e.Stdio().Stderr, "%s%s=%s\n", e.xtracePrefix(), name, xtraceQuote(value))
+}
+
+// xtraceRedirect 渲染重定向，here-document 的正文不输出
+func (e *Executor) xtraceRedirect(redirect *parser.Redirect) string {
+	fd := ""
+	switch redirect.Type {
+	case parser.REDIRECT_OUTPUT, parser.REDIRECT_APPEND, parser.REDIRECT_CLOBBER, parser.REDIRECT_DUP_OUT:
+		if redirect.FD != 1 {
+			fd = fmt.Sprintf("%d", redirect.FD)
+		}
+	case parser.REDIRECT_INPUT, parser.REDIRECT_DUP_IN, parser.REDIRECT_RW:
+		if redirect.FD != 0 {
+			fd = fmt.Sprintf("%d", redirect.FD)
+		}
+	}
+
+	target := ""
+	switch t := redirect.Target.(type) {
+	case nil:
+	case *parser.Identifier, *parser.StringLiteral, *parser.Variable:
+		target, _ = e.expandExpression(t)
+	default:
+		// 展开命令替换等会再次执行命令，这里只输出原文
+		target = t.String()
+	}
+
+	switch redirect.Type {
+	case parser.REDIRECT_OUTPUT:
+		return fd + "> " + xtraceQuote(target)
+	case parser.REDIRECT_APPEND:
+		return fd + ">> " + xtraceQuote(target)
+	case parser.REDIRECT_CLOBBER:
+		return fd + ">| " + xtraceQuote(target)
+	case parser.REDIRECT_INPUT:
+		return fd + "< " + xtraceQuote(target)
+	case parser.REDIRECT_RW:
+		return fd + "<> " + xtraceQuote(target)
+	case parser.REDIRECT_DUP_OUT:
+		return fd + ">&" + target
+	case parser.REDIRECT_DUP_IN:
+		return fd + "<&" + target
+	case parser.REDIRECT_HERESTRING:
+		return "<<< " + xtraceQuote(target)
+	case parser.REDIRECT_HEREDOC, parser.REDIRECT_HEREDOC_STRIP:
+		if redirect.HereDoc != nil {
+			op := "<<"
+			if redirect.Type == parser.REDIRECT_HEREDOC_STRIP {
+				op = "<<-"
+			}
+			return op + redirect.HereDoc.Delimiter
+		}
+	}
+	return ""
+}
+
+// xtraceQuote 按 bash set -x 的方式引用单词：
+// 含空白或特殊字符的单词用单引号括起来，其中的单引号先结束引用、转义后再重新开始引用
+func xtraceQuote(word string) string {
+	if word == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(word, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"testing"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+)
+
+// TestXtraceQuote 测试 set -x 输出中单词的引用
+func TestXtraceQuote(t *testing.T) {
+	tests := []struct {
+		word     string
+		expected string
+	}{
+		{"hello", "hello"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a|b", "'a|b'"},
+		{"/usr/bin/env", "/usr/bin/env"},
+	}
+
+	for _, tt := range tests {
+		if got := xtraceQuote(tt.word); got != tt.expected {
+			t.Errorf("xtraceQuote(%q) = %q, 期望 %q", tt.word, got, tt.expected)
+		}
+	}
+}
+
+// TestXtracePrefix 测试 PS4 前缀的展开和命令替换的嵌套层次
+func TestXtracePrefix(t *testing.T) {
+	e := New()
+	delete(e.env, "PS4")
+	if got := e.xtracePrefix(); got != "+ " {
+		t.Errorf("默认前缀为 %q，期望 %q", got, "+ ")
+	}
+
+	e.xtraceLevel = 2
+	if got := e.xtracePrefix(); got != "+++ " {
+		t.Errorf("嵌套两层时前缀为 %q，期望 %q", got, "+++ ")
+	}
+
+	e.xtraceLevel = 0
+	e.SetEnv("NAME", "demo")
+	e.SetEnv("PS4", "[$NAME] ")
+	if got := e.xtracePrefix(); got != "[demo] " {
+		t.Errorf("PS4 展开后为 %q，期望 %q", got, "[demo] ")
+	}
+}
+
+// TestXtraceRedirect 测试重定向的渲染
+func TestXtraceRedirect(t *testing.T) {
+	e := New()
+	e.SetEnv("OUT", "out file")
+	tests := []struct {
+		redirect *parser.Redirect
+		expected string
+	}{
+		{&parser.Redirect{Type: parser.REDIRECT_OUTPUT, FD: 1, Target: &parser.Identifier{Value: "log"}}, "> log"},
+		{&parser.Redirect{Type: parser.REDIRECT_APPEND, FD: 2, Target: &parser.Variable{Name: "OUT"}}, "2>> 'out file'"},
+		{&parser.Redirect{Type: parser.REDIRECT_INPUT, FD: 0, Target: &parser.Identifier{Value: "in"}}, "< in"},
+		{&parser.Redirect{Type: parser.REDIRECT_DUP_OUT, FD: 2, Target: &parser.Identifier{Value: "1"}}, "2>&1"},
+		{&parser.Redirect{Type: parser.REDIRECT_HERESTRING, FD: 0, Target: &parser.StringLiteral{Value: "a b"}}, "<<< 'a b'"},
+		{&parser.Redirect{Type: parser.REDIRECT_HEREDOC, FD: 0, HereDoc: &parser.HereDocument{Delimiter: "EOF"}}, "<<EOF"},
+	}
+
+	for _, tt := range tests {
+		if got := e.xtraceRedirect(tt.redirect); got != tt.expected {
+			t.Errorf("xtraceRedirect() = %q, 期望 %q", got, tt.expected)
+		}
+	}
+}
+
+// TestXtraceCompound 测试 [[ ]]、(( )) 和 for 循环头部的跟踪输出，简单命令的重定向不输出
+func TestXtraceCompound(t *testing.T) {
+	e := New()
+	e.SetOptions(map[string]bool{"x": true})
+	input := `x=a; [[ $x == a && -n $x ]]; y=1; (( $y + 1 )); for i in 1 "a b"; do true; done; echo hi >/dev/null 2>&1`
+	_, stderr, err := e.Capture(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	want := "+ x=a\n+ [[ a == a ]]\n+ [[ -n a ]]\n+ y=1\n+ (( 1 + 1 ))\n+ for i in 1 'a b'\n+ true\n+ for i in 1 'a b'\n+ true\n+ echo hi\n"
+	if stderr != want {
+		t.Errorf("跟踪输出 %q，期望 %q", stderr, want)
+	}
+}
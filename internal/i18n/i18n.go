@@ -0,0 +1,111 @@
+// Package i18n 提供 gobash 的错误消息目录
+//
+// 源代码中的消息使用中文书写，并作为消息目录的键；显示时按当前语言查找对应的译文。
+// 默认使用英文，可以通过 GOBASH_LANG 或 LC_ALL、LC_MESSAGES、LANG 环境变量选择中文。
+package i18n
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Language 消息使用的语言
+type Language int32
+
+const (
+	English Language = iota // 英文（默认）
+	Chinese                 // 中文（源代码中的消息）
+)
+
+// current 当前使用的语言，启动时根据环境变量确定
+var current atomic.Int32
+
+// english 英文消息目录：源代码中的中文消息 -> 英文译文
+var english = merge(parserMessages, executorMessages, builtinMessages, shellMessages)
+
+// merge 合并各部分的消息目录
+func merge(catalogs ...map[string]string) map[string]string {
+	all := make(map[string]string)
+	for _, catalog := range catalogs {
+		for msg, translated := range catalog {
+			all[msg] = translated
+		}
+	}
+	return all
+}
+
+func init() {
+	SetLanguage(FromEnv(os.Getenv))
+}
+
+// FromEnv 根据环境变量选择语言：GOBASH_LANG 优先，其次依次取 LC_ALL、LC_MESSAGES、LANG 中第一个不为空的值
+// 值以 zh 开头时使用中文，其他情况（包括都没有设置）使用英文
+func FromEnv(getenv func(string) string) Language {
+	for _, name := range []string{"GOBASH_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := getenv(name); value != "" {
+			return Parse(value)
+		}
+	}
+	return English
+}
+
+// Parse 解析语言名称，如 zh、zh_CN.UTF-8、en、C；不是中文时返回英文
+func Parse(name string) Language {
+	if strings.HasPrefix(strings.ToLower(name), "zh") {
+		return Chinese
+	}
+	return English
+}
+
+// SetLanguage 设置消息使用的语言
+func SetLanguage(lang Language) {
+	current.Store(int32(lang))
+}
+
+// CurrentLanguage 返回当前消息使用的语言
+func CurrentLanguage() Language {
+	return Language(current.Load())
+}
+
+// T 返回消息在当前语言下的译文，目录中没有译文时返回消息本身
+func T(msg string) string {
+	if CurrentLanguage() == Chinese {
+		return msg
+	}
+	if translated, ok := english[msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Sprintf 与 fmt.Sprintf 相同，但格式字符串先翻译为当前语言
+func Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Errorf 与 fmt.Errorf 相同（支持 %w），但格式字符串先翻译为当前语言
+func Errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(T(format), args...)
+}
+
+// Fprintf 与 fmt.Fprintf 相同，但格式字符串先翻译为当前语言
+func Fprintf(w io.Writer, format string, args ...interface{}) (int, error) {
+	return fmt.Fprintf(w, T(format), args...)
+}
+
+// messageError 在显示时才翻译的错误，用于包级别的错误变量
+type messageError struct {
+	msg string
+}
+
+func (e *messageError) Error() string {
+	return T(e.msg)
+}
+
+// NewError 与 errors.New 相同，但错误消息在显示时翻译为当前语言
+func NewError(msg string) error {
+	return &messageError{msg: msg}
+}
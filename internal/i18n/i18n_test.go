@@ -0,0 +1,115 @@
+package i18n
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// TestFromEnv 测试根据环境变量选择语言
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want Language
+	}{
+		{map[string]string{}, English},
+		{map[string]string{"LANG": "zh_CN.UTF-8"}, Chinese},
+		{map[string]string{"LANG": "en_US.UTF-8"}, English},
+		{map[string]string{"LANG": "zh_CN.UTF-8", "LC_ALL": "C"}, English},
+		{map[string]string{"LANG": "zh_CN.UTF-8", "LC_MESSAGES": "POSIX"}, English},
+		{map[string]string{"LANG": "en_US.UTF-8", "GOBASH_LANG": "zh"}, Chinese},
+		{map[string]string{"LC_ALL": "zh_TW.UTF-8", "GOBASH_LANG": "en"}, English},
+	}
+	for _, tt := range tests {
+		if got := FromEnv(func(name string) string { return tt.env[name] }); got != tt.want {
+			t.Errorf("FromEnv(%v) = %v，期望 %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+// TestTranslate 测试按当前语言翻译消息
+func TestTranslate(t *testing.T) {
+	defer SetLanguage(CurrentLanguage())
+
+	SetLanguage(English)
+	if got := Errorf("cd: %s: 不是目录", "/x").Error(); got != "cd: /x: Not a directory" {
+		t.Errorf("英文消息 = %q", got)
+	}
+	if got := T("目录中没有的消息"); got != "目录中没有的消息" {
+		t.Errorf("目录中没有的消息应该原样返回，得到 %q", got)
+	}
+	wrapped := Errorf("无法打开脚本文件: %w", fs.ErrNotExist)
+	if !errors.Is(wrapped, fs.ErrNotExist) || !strings.HasPrefix(wrapped.Error(), "cannot open script file: ") {
+		t.Errorf("Errorf 应该支持 %%w，得到 %q", wrapped)
+	}
+
+	// NewError 在显示时才翻译
+	err := NewError("当前平台不支持")
+	if err.Error() != "not supported on this platform" {
+		t.Errorf("英文消息 = %q", err)
+	}
+	SetLanguage(Chinese)
+	if err.Error() != "当前平台不支持" {
+		t.Errorf("中文消息 = %q", err)
+	}
+	if got := Sprintf("第%d行", 3); got != "第3行" {
+		t.Errorf("中文消息 = %q", got)
+	}
+}
+
+// formatVerbs 匹配格式字符串中的转换说明
+var formatVerbs = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// TestCatalogVerbs 测试译文与原文的格式转换说明一致
+func TestCatalogVerbs(t *testing.T) {
+	for msg, translated := range english {
+		want := formatVerbs.FindAllString(msg, -1)
+		if got := formatVerbs.FindAllString(translated, -1); !slices.Equal(got, want) {
+			t.Errorf("%q 的译文 %q 的转换说明 %v 与原文 %v 不一致", msg, translated, got, want)
+		}
+	}
+}
+
+// TestCatalogComplete 测试源代码中所有中文字符串都有英文译文
+func TestCatalogComplete(t *testing.T) {
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.Contains(d.Name(), "_test") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil || !strings.ContainsFunc(value, func(r rune) bool { return unicode.Is(unicode.Han, r) }) {
+				return true
+			}
+			if _, ok := english[value]; !ok {
+				t.Errorf("%s: %q 没有英文译文", path, value)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
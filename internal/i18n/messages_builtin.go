@@ -0,0 +1,409 @@
+package i18n
+
+// builtinMessages 内置命令的消息
+var builtinMessages = map[string]string{
+	// 通用
+	"当前平台不支持":           "not supported on this platform",
+	"没有那个文件或目录":         "No such file or directory",
+	"权限不够":              "Permission denied",
+	"没有那个进程":            "No such process",
+	"不允许的操作":            "Operation not permitted",
+	"不是目录":              "Not a directory",
+	"%s: 是一个目录":         "%s: Is a directory",
+	"文件":                "file",
+	"目录":                "directory",
+	"标准输出":              "standard output",
+	"超时":                "timed out",
+	"已取消":               "canceled",
+	"%s: 无效选项":          "%s: invalid option",
+	"-%c: 无效选项":         "-%c: invalid option",
+	"%s 缺少参数":           "%s: option requires an argument",
+	"-%c 缺少参数":          "-%c: option requires an argument",
+	"%s: -%c 缺少参数":      "%s: -%c: option requires an argument",
+	"%s: -%c: 无效选项\n%s": "%s: -%c: invalid option\n%s",
+
+	// tar、gzip
+	"用法: tar {c|x|t}[vz][f 归档文件] [-C 目录] [文件...]":    "usage: tar {c|x|t}[vz][f archive] [-C dir] [file...]",
+	"tar: c、x、t 只能指定一个\n%s":                          "tar: only one of c, x and t may be specified\n%s",
+	"tar: -%c 缺少参数":                                  "tar: -%c: option requires an argument",
+	"tar: -%c: 无效选项\n%s":                             "tar: -%c: invalid option\n%s",
+	"tar: 必须指定 c、x 或 t 之一\n%s":                       "tar: one of c, x or t must be specified\n%s",
+	"tar: 不能创建空归档":                                   "tar: cowardly refusing to create an empty archive",
+	"tar: 写入归档失败: %v":                                "tar: cannot write archive: %v",
+	"tar: 无效的 gzip 数据: %v":                           "tar: invalid gzip data: %v",
+	"tar: 读取归档失败: %v":                                "tar: cannot read archive: %v",
+	"成员名包含 ..，已跳过":                                   "member name contains '..', skipped",
+	"链接目标包含 ..，已跳过":                                  "link target contains '..', skipped",
+	"不支持的成员类型 %q，已跳过":                                "unsupported member type %q, skipped",
+	"%s: -%c: 无效选项\n用法: %s [-cdfk] [-1..-9] [文件...]": "%s: -%c: invalid option\nusage: %s [-cdfk] [-1..-9] [file...]",
+	"已经有 .gz 后缀":                                     "already has .gz suffix",
+	"未知的后缀，已跳过":                                      "unknown suffix, ignored",
+	"%s 已存在（使用 -f 覆盖）":                               "%s already exists (use -f to overwrite)",
+	"无效的 gzip 数据: %v":                                "invalid gzip data: %v",
+
+	// cd、pwd、文件操作
+	"cd: -%c: 无效选项\n用法: cd [-L|-P] [dir]": "cd: -%c: invalid option\nusage: cd [-L|-P] [dir]",
+	"cd: 参数太多":                       "cd: too many arguments",
+	"cd: HOME 未设置":                   "cd: HOME not set",
+	"cd: OLDPWD 未设置":                 "cd: OLDPWD not set",
+	"cd: %s: 不是目录":                   "cd: %s: Not a directory",
+	"pwd: %s: 无效选项\n用法: pwd [-L|-P]": "pwd: %s: invalid option\nusage: pwd [-L|-P]",
+	"mkdir: 缺少操作数":                   "mkdir: missing operand",
+	"rmdir: 缺少操作数":                   "rmdir: missing operand",
+	"rm: 缺少操作数":                      "rm: missing operand",
+	"rm: %s: 是一个目录":                  "rm: %s: Is a directory",
+	"touch: 缺少操作数":                   "touch: missing operand",
+	"unalias: 缺少操作数":                 "unalias: missing operand",
+
+	// local、test
+	"local: -%c: 无效选项":      "local: -%c: invalid option",
+	"local: 缺少变量名":          "local: missing variable name",
+	"local: 只能在函数内使用":       "local: can only be used in a function",
+	"local: `%s': 不是有效的标识符": "local: `%s': not a valid identifier",
+	"local: %s: 名称引用不能引用自身": "local: %s: nameref variable self references not allowed",
+	"test: 缺少参数":            "test: missing argument",
+	"test: 不支持的表达式":         "test: unsupported expression",
+
+	// base64、校验和
+	"base64: -w 缺少参数": "base64: -w: option requires an argument",
+	"base64: -%c: 无效选项\n用法: base64 [-di] [-w 列数] [文件]":            "base64: -%c: invalid option\nusage: base64 [-di] [-w cols] [file]",
+	"base64: 无效的列数: %q":                                           "base64: invalid wrap size: %q",
+	"base64: 多余的操作数: %s":                                          "base64: extra operand: %s",
+	"base64: 无效的输入":                                               "base64: invalid input",
+	"用法: %s [-bt] [文件...] 或 %s -c [--quiet] [--status] [校验文件...]": "usage: %s [-bt] [file...] or %s -c [--quiet] [--status] [checksum-file...]",
+	"行格式不正确":                                                      "line is improperly formatted",
+	"个文件无法读取":                                                     "listed file could not be read",
+	"个校验和不匹配":                                                     "computed checksum did NOT match",
+	"%s: 警告: %d %s\n":                                             "%s: WARNING: %d %s\n",
+
+	// command
+	"command: %s: 命令未找到": "command: %s: command not found",
+
+	// cut
+	"cut: 只能指定一种列表类型": "cut: only one type of list may be specified",
+	"cut: --%s 缺少参数":  "cut: --%s: option requires an argument",
+	"cut: --%s: 无效选项": "cut: --%s: invalid option",
+	"cut: -%c 缺少参数":   "cut: -%c: option requires an argument",
+	"cut: -%c: 无效选项\n用法: cut -f 列表 [-d 分隔符] [-s] | -c 列表 | -b 列表 [--complement] [--output-delimiter=字符串] [文件...]": "cut: -%c: invalid option\nusage: cut -f list [-d delim] [-s] | -c list | -b list [--complement] [--output-delimiter=string] [file...]",
+	"cut: 必须指定字段列表 (-f)、字符列表 (-c) 或字节列表 (-b)":                                                                     "cut: you must specify a list of fields (-f), characters (-c) or bytes (-b)",
+	"cut: 分隔符必须是单个字符":     "cut: the delimiter must be a single character",
+	"cut: -s 只能和 -f 一起使用": "cut: -s is only meaningful with -f",
+	"无效的范围: %q":           "invalid range: %q",
+	"无效的范围: %s":           "invalid range: %s",
+	"范围起始值不能大于结束值: %s":    "invalid decreasing range: %s",
+	"无效的字段号: %s":          "invalid field number: %s",
+	"字段和字符从 1 开始编号: %s":   "fields and positions are numbered from 1: %s",
+
+	// date
+	"date: 多余的操作数: %s":           "date: extra operand: %s",
+	"date: 无效的日期: %s（不支持设置系统时间）": "date: invalid date: %s (setting the system time is not supported)",
+	"date: --date 缺少参数":          "date: --date: option requires an argument",
+	"date: -d 缺少参数":              "date: -d: option requires an argument",
+	"date: -%c: 无效选项\n用法: date [-uR] [-d 日期字符串] [-I[date|hours|minutes|seconds]] [+格式]": "date: -%c: invalid option\nusage: date [-uR] [-d string] [-I[date|hours|minutes|seconds]] [+format]",
+	"date: 无效的日期: %q":           "date: invalid date: %q",
+	"date: 无效的 ISO 8601 精度: %s": "date: invalid ISO 8601 precision: %s",
+	"重复的时间":                     "time specified more than once",
+	"无效的时间: %s:%s":              "invalid time: %s:%s",
+	"缺少时间单位":                    "missing time unit",
+	"无效的时间单位: %s":               "invalid time unit: %s",
+	"重复的日期":                     "date specified more than once",
+	"无效的日期: %s":                 "invalid date: %s",
+	"无法识别: %s":                  "unrecognized: %s",
+
+	// declare、export
+	"用法: declare [-aAfFgilnrtux] [-p] [名称[=值] ...]": "usage: declare [-aAfFgilnrtux] [-p] [name[=value] ...]",
+	"declare: %c%c: 无效选项\n%s":                       "declare: %c%c: invalid option\n%s",
+	"declare: `%s': 不是有效的标识符":                       "declare: `%s': not a valid identifier",
+	"declare: `%s': 名称引用的值不是有效的变量名":                 "declare: `%s': invalid variable name for name reference",
+	"declare: %s: 名称引用不能引用自身":                       "declare: %s: nameref variable self references not allowed",
+	"declare: %s: 只读变量":                             "declare: %s: readonly variable",
+	"用法: unset [-f] [-v] [-n] [名称 ...]":              "usage: unset [-f] [-v] [-n] [name ...]",
+	"unset: -%c: 无效选项\n%s":                          "unset: -%c: invalid option\n%s",
+	"unset: `%s': 不是有效的标识符":                         "unset: `%s': not a valid identifier",
+	"用法: export [-fn] [-p] [名称[=值] ...]":            "usage: export [-fn] [-p] [name[=value] ...]",
+	"export: -%c: 无效选项\n%s":                         "export: -%c: invalid option\n%s",
+	"export: %s: 不是函数":                              "export: %s: not a function",
+	"export: `%s': 不是有效的标识符":                        "export: `%s': not a valid identifier",
+
+	// df、du
+	"df: -t 缺少参数": "df: -t: option requires an argument",
+	"df: -%c: 无效选项\n用法: df [-ahkT] [-t 类型] [文件...]":    "df: -%c: invalid option\nusage: df [-ahkT] [-t type] [file...]",
+	"df: 无法获取文件系统列表: %v":                               "df: cannot read table of mounted file systems: %v",
+	"找不到所在的文件系统":                                       "cannot find the file system",
+	"du: 无效的深度: %q":                                    "du: invalid maximum depth: %q",
+	"du: -d 缺少参数":                                      "du: -d: option requires an argument",
+	"du: -%c: 无效选项\n用法: du [-abchkms] [-d 深度] [文件...]": "du: -%c: invalid option\nusage: du [-abchkms] [-d depth] [file...]",
+	"du: -s 和 -d 不能同时使用":                               "du: cannot both summarize and show all entries (-s and -d)",
+
+	// diff、cmp
+	"用法: diff [-qru] [-U 行数] 文件1 文件2":         "usage: diff [-qru] [-U lines] file1 file2",
+	"diff: -U 缺少参数":                           "diff: -U: option requires an argument",
+	"diff: 无效的上下文行数: %q":                      "diff: invalid context length: %q",
+	"diff: -%c: 无效选项\n%s":                     "diff: -%c: invalid option\n%s",
+	"diff: 需要两个文件\n":                          "diff: two files are required\n",
+	"cmp: -%c: 无效选项\n用法: cmp [-ls] 文件1 [文件2]": "cmp: -%c: invalid option\nusage: cmp [-ls] file1 [file2]",
+	"cmp: 缺少操作数\n用法: cmp [-ls] 文件1 [文件2]":     "cmp: missing operand\nusage: cmp [-ls] file1 [file2]",
+	"cmp: 多余的操作数: %s":                         "cmp: extra operand: %s",
+
+	// pushd、popd、dirs
+	"pushd: 参数太多":                                 "pushd: too many arguments",
+	"pushd: 没有其他目录":                               "pushd: no other directory",
+	"pushd: %s: 目录栈索引超出范围":                        "pushd: %s: directory stack index out of range",
+	"popd: 参数太多":                                  "popd: too many arguments",
+	"popd: 目录栈为空":                                 "popd: directory stack empty",
+	"popd: %s: 无效参数\n用法: popd [-n] [+N | -N]":     "popd: %s: invalid argument\nusage: popd [-n] [+N | -N]",
+	"popd: %s: 目录栈索引超出范围":                         "popd: %s: directory stack index out of range",
+	"dirs: %s: 无效参数\n用法: dirs [-clpv] [+N | -N]":  "dirs: %s: invalid argument\nusage: dirs [-clpv] [+N | -N]",
+	"dirs: -%c: 无效选项\n用法: dirs [-clpv] [+N | -N]": "dirs: -%c: invalid option\nusage: dirs [-clpv] [+N | -N]",
+	"dirs: %s: 目录栈索引超出范围":                         "dirs: %s: directory stack index out of range",
+
+	// env
+	"用法: env [-i] [-u 变量名]... [变量名=值]... [命令 [参数...]]": "usage: env [-i] [-u name]... [name=value]... [command [arg...]]",
+	"env: -u 缺少参数\n":     "env: -u: option requires an argument\n",
+	"env: -%c: 无效选项\n%s": "env: -%c: invalid option\n%s",
+	"env: 无效的变量名: %q":    "env: invalid variable name: %q",
+	"env: %s: 命令未找到":     "env: %s: command not found",
+
+	// expr
+	"expr: 缺少操作数":       "expr: missing operand",
+	"语法错误: 意外的参数 `%s'":  "syntax error: unexpected argument `%s'",
+	"语法错误: `%s' 之后缺少参数": "syntax error: missing argument after `%s'",
+	"语法错误: 缺少参数":        "syntax error: missing argument",
+	"语法错误: 缺少 `)'":      "syntax error: expecting `)'",
+	"语法错误: 意外的参数 `)'":   "syntax error: unexpected argument `)'",
+	"非整数参数":             "non-integer argument",
+	"数值超出范围":            "result out of range",
+	"除以零":               "division by zero",
+	"无效的正则表达式 `%s'":     "invalid regular expression `%s'",
+
+	// grep
+	"grep: -e 缺少参数": "grep: -e: option requires an argument",
+	"grep: -%c: 无效选项\n用法: grep [-cEFhHilnoqrsvwx] [-e 模式]... 模式 [文件...]": "grep: -%c: invalid option\nusage: grep [-cEFhHilnoqrsvwx] [-e pattern]... pattern [file...]",
+	"grep: 缺少模式\n用法: grep [-cEFhHilnoqrsvwx] [-e 模式]... 模式 [文件...]":      "grep: missing pattern\nusage: grep [-cEFhHilnoqrsvwx] [-e pattern]... pattern [file...]",
+	"grep: 无效的正则表达式: %v":                                                 "grep: invalid regular expression: %v",
+
+	// http
+	"用法: http [-fiILOsS] [-X 方法] [-H 头部]... [-d 数据]... [-o 文件] [-m 秒数] [--retry 次数] [方法] URL": "usage: http [-fiILOsS] [-X method] [-H header]... [-d data]... [-o file] [-m seconds] [--retry num] [method] URL",
+	"无法读取数据 %s: %v":                "cannot read data %s: %v",
+	"URL 格式错误: %s":                 "malformed URL: %s",
+	"无法从 URL 获取文件名: %s":            "cannot get a file name from URL: %s",
+	"http: 请求失败，%s 后重试（剩余 %d 次）\n": "http: request failed, retrying in %s (%d retries left)\n",
+	"%s: 服务器返回错误: %s":              "%s: the server returned an error: %s",
+	"写入失败: %v":                     "write failed: %v",
+	"%s 不需要参数":                     "%s: option takes no argument",
+	"缺少 URL":                       "missing URL",
+	"多余的参数: %s":                    "extra argument: %s",
+	"无效的头部 %q，格式为 \"名称: 值\"":       "invalid header %q, expected \"Name: value\"",
+	"无效的次数: %q":                    "invalid count: %q",
+	"无效的秒数: %q":                    "invalid number of seconds: %q",
+
+	// jobs、fg、bg、kill、wait
+	"jobs: job manager未初始化": "jobs: job control is not initialized",
+	"fg: job manager未初始化":   "fg: job control is not initialized",
+	"fg: 当前没有作业":            "fg: current: no such job",
+	"fg: 无效的作业ID: %s":       "fg: invalid job ID: %s",
+	"fg: 作业 %d 不存在":         "fg: %d: no such job",
+	"fg: 作业 %d 已完成":         "fg: job %d has terminated",
+	"bg: job manager未初始化":   "bg: job control is not initialized",
+	"bg: 当前没有作业":            "bg: current: no such job",
+	"bg: 无效的作业ID: %s":       "bg: invalid job ID: %s",
+	"bg: 作业 %d 不存在":         "bg: %d: no such job",
+	"bg: 作业 %d 已完成":         "bg: job %d has terminated",
+	"bg: 作业 %d 已在运行":        "bg: job %d already in background",
+	"%s: 无效的信号":             "%s: invalid signal specification",
+	"kill: %s 缺少参数":         "kill: %s: option requires an argument",
+	"kill: %v\n用法: kill [-s 信号 | -n 编号 | -信号] pid|%%作业... 或 kill -l [信号...]":    "kill: %v\nusage: kill [-s sigspec | -n signum | -sigspec] pid|%%jobspec... or kill -l [sigspec...]",
+	"kill: 缺少操作数\n用法: kill [-s 信号 | -n 编号 | -信号] pid|%%作业... 或 kill -l [信号...]": "kill: missing operand\nusage: kill [-s sigspec | -n signum | -sigspec] pid|%%jobspec... or kill -l [sigspec...]",
+	"%s: job manager未初始化": "%s: job control is not initialized",
+	"%s: 无效的作业ID":         "%s: invalid job ID",
+	"%s: 作业不存在":           "%s: no such job",
+	"%s: 参数必须是进程号或作业号":    "%s: arguments must be process or job IDs",
+	"kill: %s: 无效的信号":     "kill: %s: invalid signal specification",
+	"wait: job manager未初始化": "wait: job control is not initialized",
+	"wait: %s: 无效的作业ID":     "wait: %s: invalid job ID",
+	"wait: %s: 作业不存在":       "wait: %s: no such job",
+	"wait: `%s': 不是进程号或有效的作业号": "wait: `%s': not a pid or valid job spec",
+	"wait: 进程 %d 不是当前shell的子进程": "wait: pid %d is not a child of this shell",
+	"disown: job manager未初始化": "disown: job control is not initialized",
+	"disown: 当前没有作业":            "disown: current: no such job",
+	"disown: -%c: 无效选项\n用法: disown [-h] [-ar] [作业 ...]": "disown: -%c: invalid option\nusage: disown [-h] [-ar] [jobspec ... | pid ...]",
+
+	// trap
+	"trap: %s: 不支持的条件（目前只支持 EXIT、INT、TERM、DEBUG 和 ERR）": "trap: %s: unsupported condition (only EXIT, INT, TERM, DEBUG and ERR are supported)",
+	"trap: %s: 无效的信号":                     "trap: %s: invalid signal specification",
+
+	// let
+	"let: 需要表达式\n用法: let 表达式 [表达式 ...]": "let: expression expected\nusage: let arg [arg ...]",
+
+	// 查找命令
+	"%s: #! 行缺少解释器":     "%s: missing interpreter in #! line",
+	"%s: 找不到解释器 %s: %w": "%s: cannot find interpreter %s: %w",
+	"%s: 批处理文件的参数不能包含换行符: %q": "%s: batch file arguments cannot contain newlines: %q",
+
+	// ls
+	"ls: --color 的参数 '%s' 无效（可以是 always、auto 或 never）":              "ls: invalid argument '%s' for --color (valid arguments are always, auto and never)",
+	"ls: -%c: 无效选项\n用法: ls [-1aACdhlrRSt] [--color[=WHEN]] [文件...]": "ls: -%c: invalid option\nusage: ls [-1aACdhlrRSt] [--color[=WHEN]] [file...]",
+	"无法访问 '%s': %v":   "cannot access '%s': %v",
+	"无法打开目录 '%s': %v": "cannot open directory '%s': %v",
+
+	// basename、dirname、realpath、mktemp
+	"basename: -s 缺少参数": "basename: -s: option requires an argument",
+	"basename: -%c: 无效选项\n用法: basename 名称 [后缀] 或 basename [-az] [-s 后缀] 名称...": "basename: -%c: invalid option\nusage: basename name [suffix] or basename [-az] [-s suffix] name...",
+	"basename: 缺少操作数\n用法: basename 名称 [后缀] 或 basename [-az] [-s 后缀] 名称...":     "basename: missing operand\nusage: basename name [suffix] or basename [-az] [-s suffix] name...",
+	"basename: 多余的操作数: %s":                                             "basename: extra operand: %s",
+	"dirname: -%c: 无效选项\n用法: dirname [-z] 名称...":                       "dirname: -%c: invalid option\nusage: dirname [-z] name...",
+	"dirname: 缺少操作数\n用法: dirname [-z] 名称...":                           "dirname: missing operand\nusage: dirname [-z] name...",
+	"realpath: -%c: 无效选项\n用法: realpath [-e|-m] [-sqz] 文件...":           "realpath: -%c: invalid option\nusage: realpath [-e|-m] [-sqz] file...",
+	"realpath: 缺少操作数\n用法: realpath [-e|-m] [-sqz] 文件...":               "realpath: missing operand\nusage: realpath [-e|-m] [-sqz] file...",
+	"realpath: '': 没有那个文件或目录":                                          "realpath: '': No such file or directory",
+	"mktemp: -p 缺少参数":                                                  "mktemp: -p: option requires an argument",
+	"mktemp: -%c: 无效选项\n用法: mktemp [-dqtu] [-p 目录] [--suffix=后缀] [模板]": "mktemp: -%c: invalid option\nusage: mktemp [-dqtu] [-p dir] [--suffix=suffix] [template]",
+	"mktemp: 多余的操作数: %s":                                               "mktemp: extra operand: %s",
+	"mktemp: 模板 %q 不能包含目录分隔符":                                          "mktemp: invalid template, %q, contains directory separator",
+	"mktemp: 模板 %q 末尾的 X 太少":                                           "mktemp: too few X's in template %q",
+	"mktemp: 无法通过模板 %q 创建%s: %v":                                       "mktemp: template %q: failed to create %s: %v",
+	"mktemp: 无法通过模板 %q 创建唯一的名称":                                        "mktemp: template %q: cannot create a unique name",
+
+	// printf
+	"用法: printf [-v 变量名] 格式 [参数 ...]": "usage: printf [-v var] format [arguments]",
+	"printf: -v: 需要参数\n":              "printf: -v: option requires an argument\n",
+	"printf: `%s': 不是有效的标识符":          "printf: `%s': not a valid identifier",
+	"printf: %%%c: 无效的格式字符\n":         "printf: %%%c: invalid format character\n",
+	"printf: %s: 无效的数字\n":             "printf: %s: invalid number\n",
+
+	// read
+	"用法: read [-r] [-a 数组] [-d 分隔符] [-n 字符数] [-N 字符数] [-p 提示符] [-u 文件描述符] [名称 ...]": "usage: read [-r] [-a array] [-d delim] [-n nchars] [-N nchars] [-p prompt] [-u fd] [name ...]",
+	"read: -%c: 无效选项\n%s":      "read: -%c: invalid option\n%s",
+	"read: -%c: 需要参数\n%s":      "read: -%c: option requires an argument\n%s",
+	"read: %s: 无效的字符数":        "read: %s: invalid number",
+	"read: %s: 无效的文件描述符":      "read: %s: invalid file descriptor: Bad file descriptor",
+	"read: `%s': 不是有效的标识符":   "read: `%s': not a valid identifier",
+	"read: 读取错误: %v":          "read: read error: %v",
+
+	// ps、pgrep、pkill
+	"ps: 多余的参数: %s\n用法: ps [-Aef] [-p pid列表] [--no-headers]": "ps: extra argument: %s\nusage: ps [-Aef] [-p pidlist] [--no-headers]",
+	"ps: -p 缺少参数": "ps: -p: option requires an argument",
+	"ps: -%c: 无效选项\n用法: ps [-Aef] [-p pid列表] [--no-headers]": "ps: -%c: invalid option\nusage: ps [-Aef] [-p pidlist] [--no-headers]",
+	"ps: 无法获取进程列表: %v":                                       "ps: cannot list processes: %v",
+	"ps: 没有匹配的进程":                                            "ps: no matching processes",
+	"用法: pgrep [-acfilnovx] [-d 分隔符] [-P ppid列表] 模式":         "usage: pgrep [-acfilnovx] [-d delim] [-P ppidlist] pattern",
+	"用法: pkill [-信号] [-efinovx] [-P ppid列表] 模式":              "usage: pkill [-signal] [-efinovx] [-P ppidlist] pattern",
+	"%s: --signal 缺少参数":                                      "%s: --signal: option requires an argument",
+	"%s: 只能指定一个模式\n%s":                                       "%s: only one pattern can be provided\n%s",
+	"%s: 没有指定匹配条件\n%s":                                       "%s: no matching criteria specified\n%s",
+	"%s: -n 和 -o 不能同时使用":                                     "%s: -n and -o cannot be used together",
+	"无效的模式 %q: %v":                                           "invalid pattern %q: %v",
+	"无法获取进程列表: %v":                                           "cannot list processes: %v",
+	"pgrep: 没有匹配的进程":                                         "pgrep: no matching processes",
+	"pkill: 无法终止进程 %d: %v":                                   "pkill: cannot kill process %d: %v",
+	"pkill: 没有匹配的进程":                                         "pkill: no matching processes",
+	"无效的进程号: %q":                                             "invalid process ID: %q",
+	"进程号列表为空":                                                "empty process ID list",
+	"%s/stat 格式错误":                                           "%s/stat: malformed",
+
+	// sed
+	"sed: -e 缺少参数": "sed: -e: option requires an argument",
+	"sed: -%c: 无效选项\n用法: sed [-nEr] [-i[后缀]] [-e 脚本]... [脚本] [文件...]": "sed: -%c: invalid option\nusage: sed [-nEr] [-i[suffix]] [-e script]... [script] [file...]",
+	"sed: 缺少脚本\n用法: sed [-nEr] [-i[后缀]] [-e 脚本]... [脚本] [文件...]":      "sed: missing script\nusage: sed [-nEr] [-i[suffix]] [-e script]... [script] [file...]",
+	"sed: -i 需要指定文件":    "sed: -i requires input files",
+	"sed: 无法读取 %s: %v":  "sed: can't read %s: %v",
+	"sed: 字符 %d: %s":    "sed: char %d: %s",
+	"缺少地址":              "missing address",
+	"缺少命令":              "missing command",
+	"未知的命令: '%c'":       "unknown command: '%c'",
+	"命令后有多余的字符: '%c'":   "extra characters after command: '%c'",
+	"无效的行号: 0":          "invalid usage of line address 0",
+	"缺少分隔符":             "missing delimiter",
+	"s 命令缺少分隔符":         "missing delimiter for `s' command",
+	"s 命令的分隔符无效":        "invalid delimiter for `s' command",
+	"缺少结束的分隔符 '%c'":     "unterminated delimiter '%c'",
+	"sed: 无效的正则表达式: %v": "sed: invalid regular expression: %v",
+
+	// seq
+	"seq: -%c 缺少参数": "seq: -%c: option requires an argument",
+	"seq: -%c: 无效选项\n用法: seq [-w] [-s 分隔符] [-f 格式] [起始 [步长]] 结束": "seq: -%c: invalid option\nusage: seq [-w] [-s separator] [-f format] [first [increment]] last",
+	"seq: 缺少操作数\n用法: seq [-w] [-s 分隔符] [-f 格式] [起始 [步长]] 结束":     "seq: missing operand\nusage: seq [-w] [-s separator] [-f format] [first [increment]] last",
+	"seq: 多余的操作数: %s":                   "seq: extra operand: %s",
+	"seq: -w 和 -f 不能同时使用":               "seq: -w and -f cannot be used together",
+	"seq: 无效的浮点数参数: %q":                 "seq: invalid floating point argument: %q",
+	"seq: 步长不能为 0: %q":                  "seq: invalid zero increment value: %q",
+	"seq: 格式 %q 中的转换无效，只支持 %%e、%%f、%%g": "seq: format %q has an invalid conversion; only %%e, %%f and %%g are supported",
+	"seq: 格式 %q 必须包含且只能包含一个浮点数转换":       "seq: format %q must contain exactly one floating point conversion",
+
+	// shift
+	"shift: %s: 需要数字参数":          "shift: %s: numeric argument required",
+	"return: %s: 需要数字参数":         "return: %s: numeric argument required",
+	"只能在函数中使用 `return'": "can only `return' from a function",
+	"shift: %d: 参数必须是正数":         "shift: %d: shift count must be a positive number",
+	"shift: %d: 不能移动超过参数个数 (%d)": "shift: %d: shift count out of range (%d)",
+
+	// 信号
+	"Windows 不支持进程组":   "process groups are not supported on Windows",
+	"Windows 不支持信号 %d": "signal %d is not supported on Windows",
+
+	// sleep、timeout
+	"sleep: 缺少操作数\n用法: sleep 时间[smhd]...": "sleep: missing operand\nusage: sleep number[smhd]...",
+	"sleep: 无效的时间间隔: %q":                  "sleep: invalid time interval: %q",
+	"sleep: 被中断":                          "sleep: interrupted",
+	"无效的时间间隔: %s":                         "invalid time interval: %s",
+	"timeout: %s: 无效选项\n用法: timeout 时间[smhd] 命令 [参数...]": "timeout: %s: invalid option\nusage: timeout duration[smhd] command [arg...]",
+	"timeout: 缺少操作数\n用法: timeout 时间[smhd] 命令 [参数...]":    "timeout: missing operand\nusage: timeout duration[smhd] command [arg...]",
+	"timeout: 无效的时间间隔: %q":                               "timeout: invalid time interval: %q",
+	"timeout: %s: 命令未找到":                                 "timeout: %s: command not found",
+
+	// stat
+	"用法: stat [-L] [-c 格式] 文件...": "usage: stat [-L] [-c format] file...",
+	"stat: -c 缺少参数\n%s":           "stat: -c: option requires an argument\n%s",
+	"stat: -%c: 无效选项\n%s":         "stat: -%c: invalid option\n%s",
+	"stat: 缺少操作数\n%s":             "stat: missing operand\n%s",
+
+	// tee
+	"tee: -%c: 无效选项\n用法: tee [-a] [文件...]": "tee: -%c: invalid option\nusage: tee [-a] [file...]",
+	"tee: 读取标准输入失败: %v":                    "tee: read error on standard input: %v",
+
+	// tr
+	"tr: -%c: 无效选项\n用法: tr [-cdst] 字符集1 [字符集2]": "tr: -%c: invalid option\nusage: tr [-cdst] set1 [set2]",
+	"tr: 缺少操作数\n用法: tr [-cdst] 字符集1 [字符集2]":     "tr: missing operand\nusage: tr [-cdst] set1 [set2]",
+	"tr: 多余的操作数: %s":                            "tr: extra operand: %s",
+	"tr: 同时删除和压缩时需要两个字符集":                       "tr: two strings must be given when both deleting and squeezing repeats",
+	"tr: 删除时只能指定一个字符集":                          "tr: only one string may be given when deleting without squeezing repeats",
+	"tr: 转换时需要两个字符集":                            "tr: two strings must be given when translating",
+	"tr: 字符集2不能为空":                              "tr: when not truncating set1, string2 must be non-empty",
+	"tr: 无效的字符类: %s":                            "tr: invalid character class: %s",
+	"tr: 无效的重复次数: %s":                           "tr: invalid repeat count: %s",
+	"tr: 范围 %c-%c 的结束字符小于起始字符":                  "tr: range-endpoints of '%c-%c' are in reverse collating sequence order",
+
+	// type、which
+	"用法: type [-afptP] 名称 [名称 ...]": "usage: type [-afptP] name [name ...]",
+	"type: -%c: 无效选项\n%s":           "type: -%c: invalid option\n%s",
+	"用法: which [-a] 命令 [命令 ...]":    "usage: which [-a] command [command ...]",
+	"which: -%c: 无效选项\n%s":          "which: -%c: invalid option\n%s",
+	"which: 缺少操作数\n%s":              "which: missing operand\n%s",
+
+	// ulimit、umask
+	"用法: ulimit [-SHa] [-cdfnstuv [限制]]": "usage: ulimit [-SHa] [-cdfnstuv [limit]]",
+	"ulimit: -%c: 无效选项\n%s":              "ulimit: -%c: invalid option\n%s",
+	"ulimit: 参数太多\n%s":                   "ulimit: too many arguments\n%s",
+	"ulimit: %s: 无法获取限制: %v":             "ulimit: %s: cannot get limit: %v",
+	"ulimit: %s: 无效的数字":                  "ulimit: %s: invalid number",
+	"ulimit: %s: 无法修改限制: %v":             "ulimit: %s: cannot modify limit: %v",
+	"用法: umask [-p] [-S] [模式]":           "usage: umask [-p] [-S] [mode]",
+	"umask: -%c: 无效选项\n%s":               "umask: -%c: invalid option\n%s",
+	"umask: 参数太多\n%s":                    "umask: too many arguments\n%s",
+	"umask: %s: 八进制数超出范围":                "umask: %s: octal number out of range",
+	"%s: 无效的符号模式":                        "%s: invalid symbolic mode",
+	"%s: 无效的符号模式运算符 `%c'":                "%s: invalid symbolic mode operator `%c'",
+	"%s: 无效的符号模式字符 `%c'":                 "%s: invalid symbolic mode character `%c'",
+
+	// xargs
+	"xargs: -%c: 无效选项\n用法: xargs [-0rt] [-d 分隔符] [-n 个数] [-I 替换字符串] [-P 进程数] [命令 [参数...]]": "xargs: -%c: invalid option\nusage: xargs [-0rt] [-d delim] [-n max-args] [-I replace-str] [-P max-procs] [command [arg...]]",
+	"xargs: -%c 缺少参数":          "xargs: -%c: option requires an argument",
+	"必须大于 0":                   "must be greater than 0",
+	"不能小于 0":                   "must not be negative",
+	"xargs: -%c %s: 无效的数值: %v": "xargs: -%c %s: invalid number: %v",
+	"xargs: %s: 命令未找到":         "xargs: %s: command not found",
+	"未匹配的双引号":                  "unmatched double quote",
+	"未匹配的单引号":                  "unmatched single quote",
+	"操作被策略拒绝":                  "operation denied by policy",
+	"重定向次数过多":                  "too many redirects",
+}
@@ -0,0 +1,52 @@
+package i18n
+
+// executorMessages 执行器的消息
+var executorMessages = map[string]string{
+	"%s: 语法错误: 需要操作数": "%s: syntax error: operand expected",
+	"%s=: 无效的赋值运算符":   "%s=: attempted assignment to non-variable",
+	"%s: 无效的数值":       "%s: invalid number",
+
+	"%s: 未找到命令":            "%s: command not found",
+	"%s: 无法执行二进制文件":         "%s: cannot execute binary file",
+	"命令执行失败: %s (退出码: %d)": "command failed: %s (exit status %d)",
+	"命令执行失败: %s":           "command failed: %s",
+	"管道错误: %s":             "pipe error: %s",
+	"变量错误: %s":             "variable error: %s",
+	"算术错误: %s":             "arithmetic error: %s",
+	"无效表达式: %s":            "invalid expression: %s",
+	"命令被中断":                "interrupted",
+	"未知语句类型: %s":           "unknown statement type: %s",
+	"%s: 未绑定的变量":           "%s: unbound variable",
+	"%s: 只读变量":             "%s: readonly variable",
+	"%s: 不能取消设置: 只读变量":     "%s: cannot unset: readonly variable",
+	"命令超时":                 "command timed out",
+	"超过 %s":                "exceeded %s",
+	"取反后的退出状态为 1":          "negated exit status is 1",
+	"子shell":               "subshell",
+	"命令名为空":                "empty command name",
+	"未定义的变量: %s":           "unbound variable: %s",
+	"test命令未找到":            "test command not found",
+	"无法执行命令":               "cannot execute command",
+	"无法启动命令":               "cannot start command",
+	"重定向错误":                "redirection error",
+	"是一个目录":                "Is a directory",
+	"命令执行失败":               "command failed",
+	"命令未找到或无法执行":           "command not found or not executable",
+	"创建管道失败":               "cannot create pipe",
+	"无效的文件描述符: %s":         "invalid file descriptor: %s",
+	"无效的赋值语句: %s":          "invalid assignment: %s",
+	"无效的数组赋值: %s":          "invalid array assignment: %s",
+	"[[: 缺少参数":             "[[: missing argument",
+	"%d: 错误的文件描述符":         "%d: Bad file descriptor",
+	"未闭合的数组索引: %s":         "unclosed array subscript: %s",
+	"%s: 超过最大函数嵌套层数 (%d)":  "%s: maximum function nesting level exceeded (%d)",
+	"性能分析（按总耗时排序）:\n":     "profile (sorted by total time):\n",
+	"覆盖率:\n":                  "coverage:\n",
+	"  %s: %d/%d 行 (%.1f%%)\n":    "  %s: %d/%d lines (%.1f%%)\n",
+	"    未覆盖的行: %s\n":           "    not covered: %s\n",
+	"  合计: %d/%d 行 (%.1f%%)\n":   "  total: %d/%d lines (%.1f%%)\n",
+	"%s: 命令替换: 输出超过 %d 字节的限制，已截断\n": "%s: command substitution: output exceeds the limit of %d bytes, truncated\n",
+	"命令替换的输出超过了限制":           "command substitution output limit exceeded",
+	"%s[%s]: 数组下标错误": "%s[%s]: bad array subscript",
+	"%s: 不能覆盖已存在的文件": "%s: cannot overwrite existing file",
+}
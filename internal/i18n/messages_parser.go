@@ -0,0 +1,69 @@
+package i18n
+
+// parserMessages 词法分析器和语法分析器的消息
+var parserMessages = map[string]string{
+	// 位置
+	"第%d行第%d列": "line %d, column %d",
+	"第%d行":     "line %d",
+
+	// 词法错误
+	"第%d行第%d列: 词法错误：无效字符 `%s'":    "line %d, column %d: lexical error: invalid character `%s'",
+	"第%d行第%d列: 词法错误：未闭合的引号":       "line %d, column %d: lexical error: unclosed quote",
+	"第%d行第%d列: 词法错误：未闭合的字符串":      "line %d, column %d: lexical error: unclosed string",
+	"第%d行第%d列: 词法错误：无效的 UTF-8 序列": "line %d, column %d: lexical error: invalid UTF-8 sequence",
+	"第%d行第%d列: 词法错误：意外的文件结束":      "line %d, column %d: lexical error: unexpected end of file",
+	"第%d行第%d列: 词法错误：%s":           "line %d, column %d: lexical error: %s",
+	"第%d行第%d列: 词法错误：无效的转义序列 `%s'": "line %d, column %d: lexical error: invalid escape sequence `%s'",
+	"词法错误：%s":                     "lexical error: %s",
+	"无效的 UTF-8 序列在位置 %d":          "invalid UTF-8 sequence at position %d",
+	"here-document 未找到结束分隔符 `%s'": "here-document delimited by end-of-file (wanted `%s')",
+	"无效字符 `%c'":                   "invalid character `%c'",
+	"未闭合的参数展开 `${'":               "unclosed parameter expansion `${'",
+	"未闭合的引号 `%s'":                 "unclosed quote `%s'",
+	"未闭合的命令替换 ``'":                "unclosed command substitution ``'",
+	"未闭合的算术展开 `$(('":              "unclosed arithmetic expansion `$(('",
+	"未闭合的命令替换 `$('":               "unclosed command substitution `$('",
+	"未闭合的 $'...' 字符串":             "unclosed $'...' string",
+	"未闭合的 $\"...\" 字符串":           "unclosed $\"...\" string",
+	"未闭合的进程替换":                    "unclosed process substitution",
+
+	// 语法错误
+	"第%d行第%d列: 语法错误：未找到匹配的 `%s'":            "line %d, column %d: syntax error: unexpected end of file (expected `%s')",
+	"第%d行第%d列: 语法错误：未闭合的括号":                 "line %d, column %d: syntax error: unclosed parenthesis",
+	"第%d行第%d列: 语法错误：未闭合的大括号":                "line %d, column %d: syntax error: unclosed brace",
+	"第%d行第%d列: 语法错误：未闭合的控制流语句":              "line %d, column %d: syntax error: unclosed control statement",
+	"第%d行第%d列: 语法错误：意外的 token `%s'，期望 `%s'": "line %d, column %d: syntax error near unexpected token `%s', expected `%s'",
+	"第%d行第%d列: 语法错误：意外的 token `%s'":         "line %d, column %d: syntax error near unexpected token `%s'",
+	"第%d行第%d列: 语法错误：缺少 token `%s'":          "line %d, column %d: syntax error: missing token `%s'",
+	"第%d行第%d列: 语法错误：缺少 token":               "line %d, column %d: syntax error: missing token",
+	"第%d行第%d列: 语法错误：意外的文件结束，期望 `%s'":        "line %d, column %d: syntax error: unexpected end of file, expected `%s'",
+	"第%d行第%d列: 语法错误：意外的文件结束":                "line %d, column %d: syntax error: unexpected end of file",
+	"第%d行第%d列: 语法错误：未闭合的引号":                 "line %d, column %d: syntax error: unclosed quote",
+	"第%d行第%d列: 语法错误：无效的表达式 `%s'":            "line %d, column %d: syntax error: invalid expression `%s'",
+	"第%d行第%d列: %s，期望 `%s'，得到 `%s'":          "line %d, column %d: %s, expected `%s', got `%s'",
+	"第%d行第%d列: %s，得到 `%s'":                  "line %d, column %d: %s, got `%s'",
+	"语法错误：%s，期望 `%s'，得到 `%s'":               "syntax error: %s, expected `%s', got `%s'",
+	"语法错误：%s，得到 `%s'":                       "syntax error: %s, got `%s'",
+	"未找到匹配的 `%s'":                           "unexpected end of file (expected `%s')",
+	"暂不支持 C 风格的 for 循环":                     "C-style for loops are not supported yet",
+	"重定向缺少目标":                               "missing redirection target",
+	"意外的文件结束":                               "unexpected end of file",
+	"意外的 token":                             "unexpected token",
+	"需要条件二元运算符":                             "conditional binary operator expected",
+	"条件一元运算符 %s 缺少参数":                       "missing argument to conditional unary operator %s",
+	"条件二元运算符 %s 缺少参数":                       "missing argument to conditional binary operator %s",
+
+	// 期望的 token
+	"单词":  "word",
+	"模式":  "pattern",
+	"变量名": "variable name",
+	"函数名": "function name",
+	"文件名": "filename",
+
+	// 静态检查
+	"没有引用的 %s 在 %s 中为空或含有空白时会导致错误，应该用双引号括起来或改用 [[ ]]": "unquoted %s in %s breaks the test when it is empty or contains whitespace; double quote it or use [[ ]]",
+	"没有引用的 %s 会进行单词分割和路径名展开，应该用双引号括起来":             "unquoted %s is subject to word splitting and globbing; double quote it",
+	"没有引用的命令替换 %s 会进行单词分割和路径名展开，应该用双引号括起来":         "unquoted command substitution %s is subject to word splitting and globbing; double quote it",
+	"多余的 cat，可以把 cat %s | 命令 改为 命令 < %s":                "useless cat; replace cat %s | cmd with cmd < %s",
+	"变量 %s 在脚本中没有赋值":                                   "variable %s is referenced but never assigned in the script",
+}
@@ -0,0 +1,59 @@
+package i18n
+
+// shellMessages 交互式 shell、嵌入接口和命令行程序的消息
+var shellMessages = map[string]string{
+	// bind、set、shopt 等 shell 命令
+	"-r: 需要参数\n用法: bind [-lp] [-r 按键序列] [\"按键序列\": 函数名]":  "-r: option requires an argument\nusage: bind [-lp] [-r keyseq] [\"keyseq\": function-name]",
+	"-%c: 无效选项\n用法: bind [-lp] [-r 按键序列] [\"按键序列\": 函数名]": "-%c: invalid option\nusage: bind [-lp] [-r keyseq] [\"keyseq\": function-name]",
+	"%s: 缺少冒号分隔符":                           "%s: missing colon separator",
+	"%s: 未知的函数名":                            "%s: unknown function name",
+	"%s: 不支持的按键序列":                          "%s: unsupported key sequence",
+	"%s: 无效的选项名":                            "%s: invalid option name",
+	"-%c: 无效选项\n用法: shopt [-pqsu] [选项名...]": "-%c: invalid option\nusage: shopt [-pqsu] [optname ...]",
+	"不能同时使用 -s 和 -u":                        "cannot set and unset shell options simultaneously",
+	"%s: 无效的 shell 选项名":                     "%s: invalid shell option name",
+	"选项未启用":                                 "option not set",
+
+	// 错误报告和脚本
+	"gobash: %s: 第%d行": "gobash: %s: line %d",
+	"gobash: 第%d行":     "gobash: line %d",
+	"无法打开脚本文件: %w":     "cannot open script file: %w",
+	"无法打开脚本文件: %v":     "cannot open script file: %v",
+	"读取脚本失败: %v":       "cannot read script: %v",
+	"gobash: 警告: 从第 %d 行开始的 here-document 被文件结束符分隔（需要 `%s'）\n": "gobash: warning: here-document at line %d delimited by end-of-file (wanted `%s')\n",
+	"gobash: 寻找匹配的 `%s' 时遇到了文件结束符\n": "gobash: unexpected EOF while looking for matching `%s'\n",
+	"gobash: 语法错误: 意外的文件结束\n": "gobash: syntax error: unexpected end of file\n",
+
+	// pkg/interp
+	"无效的内置命令名: %q":     "invalid builtin name: %q",
+	"无效的工作目录: %v":      "invalid working directory: %v",
+	"无效的工作目录: %s 不是目录": "invalid working directory: %s is not a directory",
+	"无效的资源限制: %v":      "invalid resource limit: %v",
+	"脚本执行超时":           "script timed out",
+	"启动的外部命令数超过限制":     "too many external commands started",
+	"输出超过限制":           "output limit exceeded",
+
+	// 命令行
+	"执行命令字符串": "execute the command string",
+	"执行脚本文件":  "execute the script file",
+	"只检查语法，不执行命令（发现错误时退出码为 2）":                       "check syntax only without executing commands (exit status 2 on errors)",
+	"把脚本格式化为规范的源代码输出到标准输出，不执行命令（保留注释，不保留空行）": "print the script reformatted as canonical source to standard output without executing it (comments are kept, blank lines are not)",
+	"把脚本的语法树以 JSON 格式输出到标准输出，不执行命令":         "print the script's syntax tree as JSON to standard output without executing it",
+	"静态检查脚本中常见的问题（如没有引用的变量），不执行命令（发现问题时退出码为 1）": "check the script for common problems (such as unquoted variables) without executing it (exit status 1 when problems are found)",
+	"每个脚本的最长执行时间（如 30s、5m），超时后终止脚本，退出码为 124；0 表示不限制": "maximum run time of each script (e.g. 30s, 5m); the script is stopped with exit status 124 when exceeded; 0 means no limit",
+	"单个外部命令的最长执行时间，超时的命令退出码为 124；0 表示不限制":            "maximum run time of a single external command; a command that times out exits with status 124; 0 means no limit",
+	"命令替换输出的最大字节数，超过时截断并且退出状态为 1；0 表示不限制": "maximum number of bytes of command substitution output; longer output is truncated and the status is 1; 0 means no limit",
+	"试运行：不执行外部命令和修改文件的内置命令，只把展开后的命令输出到标准错误": "dry run: print the expanded external commands and file-modifying builtins to standard error instead of running them",
+	"统计每个命令的执行次数和耗时，脚本结束时把按总耗时排序的报告输出到标准错误": "record the run count and time of each command and print a report sorted by total time to standard error when the script exits",
+	"记录脚本中每一行的执行次数，脚本结束时把行覆盖率报告输出到标准错误": "record how often each script line runs and print a line coverage report to standard error when the script exits",
+	"记录脚本中每一行的执行次数，脚本结束时以 LCOV 格式写到指定文件":   "record how often each script line runs and write it in LCOV format to the given file when the script exits",
+	"错误: 无法写入覆盖率文件 %s: %v\n":                "error: cannot write coverage file %s: %v\n",
+	"错误: 通配符匹配失败 %s: %v\n":                           "error: glob matching failed %s: %v\n",
+	"错误: 没有找到要执行的脚本文件\n":                             "error: no script files found\n",
+	"找到 %d 个脚本文件，开始执行...\n":                          "found %d script files, running...\n",
+	"警告: 跳过 %s: %v\n":                                "warning: skipping %s: %v\n",
+	"警告: 跳过目录 %s\n":                                  "warning: skipping directory %s\n",
+	"警告: 脚本 %s 执行超时（%s），跳过\n":                        "warning: script %s timed out (%s), skipping\n",
+	"错误: %v\n": "error: %v\n",
+	"显示版本信息后退出": "print version information and exit",
+}
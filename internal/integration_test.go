@@ -1,10 +1,15 @@
 package internal
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 	"gobash/internal/executor"
 	"gobash/internal/lexer"
 	"gobash/internal/parser"
+	"gobash/internal/shell"
 )
 
 // TestLexerParserIntegration 测试词法分析器和语法分析器的集成
@@ -282,3 +287,106 @@ func TestEndToEndIntegration(t *testing.T) {
 	}
 }
 
+// TestWhileReadLoop 测试逐行读取文件的 while read 循环：循环上的重定向、read 内置命令，
+// 以及循环体中的命令和下一次迭代的 read 共用同一个标准输入
+func TestWhileReadLoop(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("a b\n  lead\\x\nlast"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "保留空白和反斜杠",
+			script: `while IFS= read -r line; do echo "[$line]"; done < input.txt`,
+			want:   "[a b]\n[  lead\\x]\n",
+		},
+		{
+			name:   "分割字段",
+			script: `while read a b; do echo "<$a|$b>"; done < input.txt`,
+			want:   "<a|b>\n<leadx|>\n",
+		},
+		{
+			name:   "最后一行没有换行符",
+			script: `n=0; while read -r l; do n=$((n+1)); done < input.txt; echo "$n $l"`,
+			want:   "2 last\n",
+		},
+		{
+			name:   "循环体中的 read 读取下一行",
+			script: `while read -r l; do read -r m; echo "$l/$m"; done < input.txt`,
+			want:   "a b/lead\\x\n",
+		},
+		{
+			name:   "循环体中的外部命令读取剩余的输入",
+			script: `while read -r l; do echo "first: $l"; cat; done < input.txt`,
+			want:   "first: a b\n  lead\\x\nlast",
+		},
+		{
+			name:   "管道",
+			script: `printf 'x\ny\n' | while read -r v; do echo "p:$v"; done`,
+			want:   "p:x\np:y\n",
+		},
+		{
+			name:   "here-document",
+			script: "while IFS=: read -r user rest; do echo $user; done <<EOF\nroot:x\nbin:y\nEOF",
+			want:   "root\nbin\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := shell.New()
+			s.Executor().SetEnv("PWD", dir)
+			var out, errOut strings.Builder
+			s.SetStdio(strings.NewReader(""), &out, &errOut)
+			if err := s.ExecuteReader(strings.NewReader(tt.script)); err != nil {
+				t.Fatalf("执行失败: %v", err)
+			}
+			if out.String() != tt.want || errOut.Len() != 0 {
+				t.Errorf("输出 %q（错误输出 %q），期望 %q", out.String(), errOut.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestReadHereStringAndPipe 测试 read 从 here-string 和管道读取，结果与 bash 相同（安装了 bash 时同时与 bash 的输出比较）
+// 与 bash 一样管道中的每个命令都在子shell中执行，echo hi | read x 不会修改当前 shell 的 x，需要在同一个子shell中使用读取的变量
+func TestReadHereStringAndPipe(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"here-string", `read x <<< "hello world"; echo "[$x]"`, "[hello world]\n"},
+		{"最后一个变量得到剩余的部分", `read a b <<< "hello big world"; echo "[$a][$b]"`, "[hello][big world]\n"},
+		{"REPLY 保留空白", `read <<< "  reply  val "; echo "[$REPLY]"`, "[  reply  val ]\n"},
+		{"read -a", `line="one two  three"; read -a arr <<< "$line"; echo "${#arr[@]} [${arr[2]}]"`, "3 [three]\n"},
+		{"IFS 只作用于 read", `IFS=: read -r f1 f2 <<< "a:b:c"; echo "[$f1][$f2]"`, "[a][b:c]\n"},
+		{"管道中的 read 在子shell中执行", `x=old; echo hi | read x; echo "[$x]"`, "[old]\n"},
+		{"在同一个子shell中使用读取的变量", `echo "p q" | { read u v; echo "[$u][$v]"; }`, "[p][q]\n"},
+	}
+	bash, _ := exec.LookPath("bash")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := shell.New()
+			var out, errOut strings.Builder
+			s.SetStdio(strings.NewReader(""), &out, &errOut)
+			if err := s.ExecuteReader(strings.NewReader(tt.script)); err != nil {
+				t.Fatalf("执行失败: %v", err)
+			}
+			if out.String() != tt.want || errOut.Len() != 0 {
+				t.Errorf("输出 %q（错误输出 %q），期望 %q", out.String(), errOut.String(), tt.want)
+			}
+			if bash == "" {
+				return
+			}
+			if bashOut, err := exec.Command(bash, "-c", tt.script).Output(); err == nil && string(bashOut) != out.String() {
+				t.Errorf("输出 %q，bash 的输出 %q", out.String(), bashOut)
+			}
+		})
+	}
+}
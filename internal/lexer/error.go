@@ -1,9 +1,28 @@
 package lexer
 
 import (
-	"fmt"
+	"gobash/internal/i18n"
 )
 
+// Position 输入中的位置（行号和列号从 1 开始），零值表示位置未知
+type Position struct {
+	Line   int
+	Column int
+}
+
+// IsValid 检查位置是否已知
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String 返回位置的显示形式，如 第3行第5列
+func (p Position) String() string {
+	if p.Column > 0 {
+		return i18n.Sprintf("第%d行第%d列", p.Line, p.Column)
+	}
+	return i18n.Sprintf("第%d行", p.Line)
+}
+
 // LexerErrorType 词法分析器错误类型
 type LexerErrorType int
 
@@ -14,6 +33,8 @@ const (
 	LexerErrorTypeInvalidUTF8                        // 无效的 UTF-8 序列
 	LexerErrorTypeUnexpectedEOF                      // 意外的文件结束
 	LexerErrorTypeInvalidEscape                      // 无效的转义序列
+	LexerErrorTypeUnclosedExpansion                  // 未闭合的展开（$(...)、${...}、$((...))、反引号）
+	LexerErrorTypeUnclosedHereDoc                    // 未找到结束分隔符的 here-document
 )
 
 // LexerError 表示词法分析器错误
@@ -30,29 +51,32 @@ func (e *LexerError) Error() string {
 	if e.Line > 0 {
 		switch e.Type {
 		case LexerErrorTypeInvalidChar:
-			return fmt.Sprintf("第%d行第%d列: 词法错误：无效字符 `%s'", 
+			return i18n.Sprintf("第%d行第%d列: 词法错误：无效字符 `%s'", 
 				e.Line, e.Column, e.Char)
 		case LexerErrorTypeUnclosedQuote:
-			return fmt.Sprintf("第%d行第%d列: 词法错误：未闭合的引号", 
+			return i18n.Sprintf("第%d行第%d列: 词法错误：未闭合的引号", 
 				e.Line, e.Column)
 		case LexerErrorTypeUnclosedString:
-			return fmt.Sprintf("第%d行第%d列: 词法错误：未闭合的字符串", 
+			return i18n.Sprintf("第%d行第%d列: 词法错误：未闭合的字符串", 
 				e.Line, e.Column)
 		case LexerErrorTypeInvalidUTF8:
-			return fmt.Sprintf("第%d行第%d列: 词法错误：无效的 UTF-8 序列", 
+			return i18n.Sprintf("第%d行第%d列: 词法错误：无效的 UTF-8 序列", 
 				e.Line, e.Column)
 		case LexerErrorTypeUnexpectedEOF:
-			return fmt.Sprintf("第%d行第%d列: 词法错误：意外的文件结束", 
+			return i18n.Sprintf("第%d行第%d列: 词法错误：意外的文件结束", 
 				e.Line, e.Column)
+		case LexerErrorTypeUnclosedExpansion, LexerErrorTypeUnclosedHereDoc:
+			return i18n.Sprintf("第%d行第%d列: 词法错误：%s", 
+				e.Line, e.Column, e.Message)
 		case LexerErrorTypeInvalidEscape:
-			return fmt.Sprintf("第%d行第%d列: 词法错误：无效的转义序列 `%s'", 
+			return i18n.Sprintf("第%d行第%d列: 词法错误：无效的转义序列 `%s'", 
 				e.Line, e.Column, e.Char)
 		default:
-			return fmt.Sprintf("第%d行第%d列: 词法错误：%s", 
+			return i18n.Sprintf("第%d行第%d列: 词法错误：%s", 
 				e.Line, e.Column, e.Message)
 		}
 	}
-	return fmt.Sprintf("词法错误：%s", e.Message)
+	return i18n.Sprintf("词法错误：%s", e.Message)
 }
 
 // String 返回错误的字符串表示
@@ -60,6 +84,52 @@ func (e *LexerError) String() string {
 	return e.Error()
 }
 
+// ExitCode 返回退出码，与 bash 的语法错误相同为 2
+func (e *LexerError) ExitCode() int {
+	return 2
+}
+
+// Position 返回错误在输入中的位置
+func (e *LexerError) Position() Position {
+	return Position{Line: e.Line, Column: e.Column}
+}
+
+// Unwrap 词法错误没有原始错误
+func (e *LexerError) Unwrap() error {
+	return nil
+}
+
+// Incomplete 检查错误是否由输入提前结束引起（如引号、命令替换、here-document 未闭合），
+// 这类错误在继续输入后可能消失
+func (e *LexerError) Incomplete() bool {
+	switch e.Type {
+	case LexerErrorTypeUnclosedQuote, LexerErrorTypeUnclosedString, LexerErrorTypeUnexpectedEOF,
+		LexerErrorTypeUnclosedExpansion, LexerErrorTypeUnclosedHereDoc:
+		return true
+	}
+	return false
+}
+
+// Closer 返回不完整的输入期望的结束符：未闭合的引号和字符串为对应的引号，展开为 }、)、)) 或反引号，
+// here-document 为结束分隔符；不是由输入提前结束引起的错误返回空字符串
+func (e *LexerError) Closer() string {
+	switch e.Type {
+	case LexerErrorTypeUnclosedQuote, LexerErrorTypeUnclosedString, LexerErrorTypeUnclosedHereDoc:
+		return e.Char
+	case LexerErrorTypeUnclosedExpansion:
+		switch e.Char {
+		case "${":
+			return "}"
+		case "$((":
+			return "))"
+		case "`":
+			return "`"
+		}
+		return ")"
+	}
+	return ""
+}
+
 
 
 
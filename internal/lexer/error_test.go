@@ -106,3 +106,31 @@ func TestLexerErrorHandling(t *testing.T) {
 
 
 
+
+// TestNeedsMore 测试不完整的输入和期望的结束符
+func TestNeedsMore(t *testing.T) {
+	tests := []struct {
+		input  string
+		closer string
+		more   bool
+	}{
+		{"echo hello\n", "", false},
+		{"echo 'abc\n", "'", true},
+		{"echo \"abc\n", "\"", true},
+		{"echo $'abc\n", "'", true},
+		{"echo ${x\n", "}", true},
+		{"echo $((1 +\n", "))", true},
+		{"echo $(date\n", ")", true},
+		{"echo `date\n", "`", true},
+		{"cat <<END\nabc\n", "END", true},
+		{"cat <<A; echo 'x\n", "'", true},
+		{"echo a \\\n", "", true},
+		{"if true; then\n", "", false},
+	}
+	for _, tt := range tests {
+		closer, more := NeedsMore(tt.input)
+		if closer != tt.closer || more != tt.more {
+			t.Errorf("NeedsMore(%q) = %q, %v, 期望 %q, %v", tt.input, closer, more, tt.closer, tt.more)
+		}
+	}
+}
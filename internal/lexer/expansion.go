@@ -0,0 +1,293 @@
+package lexer
+
+import "strings"
+
+// 展开的扫描
+//
+// 词法分析器读取双引号字符串时用它找到真正的结束引号，执行器展开双引号字符串、here-document 正文、
+// 参数展开中的单词和算术表达式时用它识别其中的展开，两处对引号、反斜杠和嵌套的处理因此完全一致
+
+// ExpansionKind 展开的种类
+type ExpansionKind int
+
+const (
+	ExpansionParameter ExpansionKind = iota // $name、$1、$?、$arr[i]
+	ExpansionBraced                         // ${...}
+	ExpansionCommand                        // $(...) 和 `...`
+	ExpansionArith                          // $((...))
+)
+
+// Expansion 扫描出的一个展开，Body 是去掉 $、括号和反引号之后的内容
+type Expansion struct {
+	Kind ExpansionKind
+	Body string
+}
+
+// ScanExpansion 识别从 s[i]（$ 或 `）开始的展开，返回展开和它之后的位置
+// 不是展开（如单独的 $、$-）或展开没有闭合时 ok 为 false，调用者应该把 s[i] 当作普通字符
+// inDouble 表示 s 是双引号中的文本：这时 ${...} 中的单引号是普通字符
+func ScanExpansion(s string, i int, inDouble bool) (exp Expansion, end int, ok bool) {
+	if s[i] == '`' {
+		j := matchBackquote(s, i+1)
+		if j < 0 {
+			return Expansion{}, i, false
+		}
+		return Expansion{ExpansionCommand, unescapeBackquote(s[i+1:j], inDouble)}, j + 1, true
+	}
+	if i+1 >= len(s) {
+		return Expansion{}, i, false
+	}
+	switch c := s[i+1]; {
+	case c == '(':
+		if i+2 < len(s) && s[i+2] == '(' {
+			if j := matchArith(s, i+3); j >= 0 {
+				return Expansion{ExpansionArith, s[i+3 : j]}, j + 2, true
+			}
+			// 不是算术展开（如 $((cd /; ls) | wc -l)），按命令替换处理
+		}
+		if j := matchParen(s, i+2); j >= 0 {
+			return Expansion{ExpansionCommand, s[i+2 : j]}, j + 1, true
+		}
+	case c == '{':
+		if j := matchBrace(s, i+2, inDouble); j >= 0 {
+			return Expansion{ExpansionBraced, s[i+2 : j]}, j + 1, true
+		}
+	case isNameStart(c):
+		j := i + 2
+		for j < len(s) && (isNameStart(s[j]) || isDigit(s[j])) {
+			j++
+		}
+		// $arr[i] 展开数组元素（gobash 的扩展，bash 中是 $arr 后跟 [i]）
+		if j < len(s) && s[j] == '[' {
+			if k := strings.IndexByte(s[j:], ']'); k >= 0 {
+				j += k + 1
+			}
+		}
+		return Expansion{ExpansionParameter, s[i+1 : j]}, j, true
+	case isDigit(c) || strings.IndexByte("#@*?!$", c) >= 0:
+		// 与 bash 一样，$10 是 $1 后跟 0，两位数的位置参数要写成 ${10}
+		return Expansion{ExpansionParameter, s[i+1 : i+2]}, i + 2, true
+	}
+	return Expansion{}, i, false
+}
+
+// SkipQuoted 跳过从 s[i] 开始的引号字符串（'...'、"..." 或 `...`），返回结束引号之后的位置，没有闭合时返回 -1
+// 双引号中的展开整体跳过，因此 "$(echo ")")" 是一个字符串
+func SkipQuoted(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == quote:
+			return i + 1
+		case c == '\\' && quote != '\'':
+			i++
+		case (c == '$' || c == '`') && quote == '"':
+			if _, end, ok := ScanExpansion(s, i, true); ok {
+				i = end - 1
+			}
+		}
+	}
+	return -1
+}
+
+// matchParen 查找与 $( 匹配的 )，i 是 $( 之后的位置，返回 ) 的位置，没有找到时返回 -1
+// 括号中是命令：引号、转义、嵌套的展开、注释和 case 语句的模式（如 a)）中的 ) 都不结束命令替换
+func matchParen(s string, i int) int {
+	depth, caseDepth := 0, 0
+	commandStart, wordStart := true, true
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i += 2
+			commandStart, wordStart = false, false
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			if i = SkipQuoted(s, i); i < 0 {
+				return -1
+			}
+			commandStart, wordStart = false, false
+			continue
+		case c == '$':
+			if _, end, ok := ScanExpansion(s, i, false); ok {
+				i = end
+				commandStart, wordStart = false, false
+				continue
+			}
+		case c == '#' && wordStart:
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			continue
+		case isNameStart(c) && wordStart:
+			j := i
+			for j < len(s) && (isNameStart(s[j]) || isDigit(s[j])) {
+				j++
+			}
+			word := s[i:j]
+			if commandStart {
+				switch word {
+				case "case":
+					caseDepth++
+				case "esac":
+					if caseDepth > 0 {
+						caseDepth--
+					}
+				}
+			}
+			switch word {
+			case "then", "do", "else", "elif", "if", "while", "until", "time":
+				// 这些关键字之后仍然是命令的开始
+			default:
+				commandStart = false
+			}
+			wordStart = false
+			i = j
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 && caseDepth == 0 {
+				return i
+			}
+			if depth > 0 {
+				depth--
+			}
+		}
+		switch {
+		case c == ' ' || c == '\t':
+			wordStart = true
+		case strings.IndexByte("\n;&|()", c) >= 0:
+			commandStart, wordStart = true, true
+		case (c == '{' || c == '!') && commandStart:
+			wordStart = false
+		default:
+			commandStart, wordStart = false, false
+		}
+		i++
+	}
+	return -1
+}
+
+// matchBrace 查找与 ${ 匹配的 }，i 是 ${ 之后的位置，返回 } 的位置，没有找到时返回 -1
+// 引号、转义和嵌套的展开中的 } 不结束参数展开（如 ${x:-"}"}、${x:-$(echo })}）
+func matchBrace(s string, i int, inDouble bool) int {
+	depth := 0
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '\\':
+			i += 2
+			continue
+		case c == '"' || c == '`' || c == '\'' && !inDouble:
+			if i = SkipQuoted(s, i); i < 0 {
+				return -1
+			}
+			continue
+		case c == '$':
+			if _, end, ok := ScanExpansion(s, i, inDouble); ok {
+				i = end
+				continue
+			}
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+		i++
+	}
+	return -1
+}
+
+// matchArith 查找与 $(( 匹配的 ))，i 是 $(( 之后的位置，返回 )) 的位置
+// 括号不平衡（如 $((a) | b)）时返回 -1，这时应该按命令替换处理
+func matchArith(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '\\':
+			i += 2
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			if i = SkipQuoted(s, i); i < 0 {
+				return -1
+			}
+			continue
+		case c == '$':
+			if _, end, ok := ScanExpansion(s, i, false); ok {
+				i = end
+				continue
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			} else if i+1 < len(s) && s[i+1] == ')' {
+				return i
+			} else {
+				return -1
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// matchBackquote 查找结束的反引号，i 是开始的反引号之后的位置，没有找到时返回 -1
+func matchBackquote(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeBackquote 去掉反引号命令替换中 \`、\\ 和 \$ 的反斜杠，在双引号中时还去掉 \" 的反斜杠
+func unescapeBackquote(s string, inDouble bool) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	escapable := "`\\$"
+	if inDouble {
+		escapable += "\""
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// UnescapeDoubleQuoted 去掉双引号字符串中 \$、\`、\"、\\ 的反斜杠，\ 加换行是续行，两个字符都去掉
+// 用于不展开变量、只需要字符串本身的地方（如 case 的模式、here-document 的分隔符）
+func UnescapeDoubleQuoted(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+			i++
+			if s[i] == '\n' {
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// isNameStart 判断是否可以作为变量名的第一个字符
+func isNameStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
@@ -2,7 +2,7 @@
 package lexer
 
 import (
-	"fmt"
+	"gobash/internal/i18n"
 	"strconv"
 	"strings"
 	"unicode"
@@ -21,6 +21,29 @@ type Lexer struct {
 	line         int           // 当前行号
 	column       int           // 当前列号
 	errors       []*LexerError // 词法分析器错误列表
+
+	tokenStart  int // 当前 token 的起始字节偏移
+	tokenLine   int // 当前 token 的起始行号
+	tokenColumn int // 当前 token 的起始列号
+
+	expectHereDocDelim bool           // 上一个 token 是 << 或 <<-，下一个 token 是分隔符
+	hereDocStrip       bool           // 待读取的分隔符是否来自 <<-
+	collectingDelim    bool           // 正在拼接由相邻片段组成的分隔符
+	hereDocDelimEnd    int            // 已读取的分隔符片段的结束偏移
+	pendingHereDocs    []pendingHereDoc // 等待在下一个换行后读取正文的 here-document
+	hereDocTokens      []Token          // 已读取、尚未返回的 HEREDOC_CONTENT token
+
+	continuationAtEOF bool // 输入是否以续行符（行尾的反斜杠）结束
+
+	comments []Comment // 跳过的注释（包括 shebang 行）
+}
+
+// pendingHereDoc 表示已经看到重定向、但正文尚未读取的 here-document
+type pendingHereDoc struct {
+	delimiter string
+	stripTabs bool
+	line      int // 分隔符所在的行和列
+	column    int
 }
 
 // New 创建新的词法分析器
@@ -35,6 +58,12 @@ func New(input string) *Lexer {
 	return l
 }
 
+// SetLine 设置输入第一行的行号（默认为 1），需要在读取 token 之前调用
+// 命令替换的命令文本从外层命令所在的行开始编号
+func (l *Lexer) SetLine(line int) {
+	l.line += line - 1
+}
+
 // readChar 读取下一个字符（支持 UTF-8）
 func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
@@ -65,7 +94,7 @@ func (l *Lexer) readChar() {
 	r, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
 	if r == utf8.RuneError && width == 1 {
 		// 无效的 UTF-8 序列，记录错误但继续处理
-		l.addError(LexerErrorTypeInvalidUTF8, fmt.Sprintf("无效的 UTF-8 序列在位置 %d", l.readPosition),
+		l.addError(LexerErrorTypeInvalidUTF8, i18n.Sprintf("无效的 UTF-8 序列在位置 %d", l.readPosition),
 			string(l.input[l.readPosition]), l.line, l.column)
 		// 当作单个字节处理
 		l.ch = l.input[l.readPosition]
@@ -126,20 +155,172 @@ func (l *Lexer) peekChar2() byte {
 }
 
 // NextToken 读取下一个token
+// 返回的 token 带有起始位置（Line/Column/Pos）和结束偏移（End），
+// 相邻的 token（前一个的 End 等于后一个的 Pos）属于同一个单词。
+// 遇到换行时，如果当前行有 here-document 重定向，会读取其正文，
+// 并在 NEWLINE 之后依次返回对应的 HEREDOC_CONTENT token。
 func (l *Lexer) NextToken() Token {
+	if len(l.hereDocTokens) > 0 {
+		tok := l.hereDocTokens[0]
+		l.hereDocTokens = l.hereDocTokens[1:]
+		return tok
+	}
+
+	l.skipWhitespace()
+	l.tokenStart = l.position
+	l.tokenLine = l.line
+	l.tokenColumn = l.column - 1
+	if l.atEOF() {
+		l.tokenStart = len(l.input)
+		l.tokenColumn = l.column
+	}
+
+	tok := l.nextToken()
+	tok.Pos = l.tokenStart
+	tok.End = l.offset()
+	if tok.Type != NEWLINE {
+		tok.Line = l.tokenLine
+		tok.Column = l.tokenColumn
+	} else {
+		// 换行符的位置是它所在行的行尾（读到换行符时行号已经加一，注释之后的换行符与注释在同一行）
+		nl := tok.End - 1
+		tok.Line = l.tokenLine
+		if l.input[l.tokenStart] == '\n' {
+			tok.Line--
+		}
+		lineStart := strings.LastIndexByte(l.input[:nl], '\n') + 1
+		tok.Column = utf8.RuneCountInString(l.input[lineStart:nl]) + 1
+	}
+
+	// 分隔符可能由多个相邻的片段组成（如 \EOF、E"OF"），依次拼接
+	if l.collectingDelim {
+		if tok.Pos == l.hereDocDelimEnd && isHereDocDelimPart(tok) {
+			l.pendingHereDocs[len(l.pendingHereDocs)-1].delimiter += tok.Literal
+			l.hereDocDelimEnd = tok.End
+		} else {
+			l.collectingDelim = false
+		}
+	}
+	if l.expectHereDocDelim {
+		l.expectHereDocDelim = false
+		if isHereDocDelimPart(tok) {
+			l.pendingHereDocs = append(l.pendingHereDocs, pendingHereDoc{
+				delimiter: tok.Literal,
+				stripTabs: l.hereDocStrip,
+				line:      tok.Line,
+				column:    tok.Column,
+			})
+			l.collectingDelim = true
+			l.hereDocDelimEnd = tok.End
+		}
+	}
+
+	switch tok.Type {
+	case REDIRECT_HEREDOC, REDIRECT_HEREDOC_STRIP:
+		l.expectHereDocDelim = true
+		l.hereDocStrip = tok.Type == REDIRECT_HEREDOC_STRIP
+	case NEWLINE:
+		l.readHereDocBodies()
+	case EOF:
+		// 正文还没有开始（输入在 here-document 所在的行结束），错误的位置是分隔符的位置
+		for _, h := range l.pendingHereDocs {
+			l.addError(LexerErrorTypeUnclosedHereDoc, i18n.Sprintf("here-document 未找到结束分隔符 `%s'", h.delimiter),
+				h.delimiter, h.line, h.column)
+		}
+		l.pendingHereDocs = nil
+	}
+	return tok
+}
+
+// readHereDocBodies 读取当前行所有 here-document 的正文
+// 调用时当前字符位于换行符之后的下一行开头
+func (l *Lexer) readHereDocBodies() {
+	for _, h := range l.pendingHereDocs {
+		startLine := l.line
+		start := l.offset()
+		var body strings.Builder
+		found := false
+		for !l.atEOF() {
+			lineStart := l.position
+			for !l.atEOF() && l.ch != '\n' {
+				l.readChar()
+			}
+			line := strings.TrimSuffix(l.input[lineStart:l.offset()], "\r")
+			if l.ch == '\n' {
+				l.readChar()
+			}
+			if h.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == h.delimiter {
+				found = true
+				break
+			}
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+		if !found {
+			l.addError(LexerErrorTypeUnclosedHereDoc, i18n.Sprintf("here-document 未找到结束分隔符 `%s'", h.delimiter),
+				h.delimiter, startLine, 1)
+		}
+		l.hereDocTokens = append(l.hereDocTokens, Token{
+			Type:    HEREDOC_CONTENT,
+			Literal: body.String(),
+			Line:    startLine,
+			Column:  1,
+			Pos:     start,
+			End:     l.offset(),
+		})
+	}
+	l.pendingHereDocs = nil
+}
+
+// isHereDocDelimPart 检查 token 是否可以作为 here-document 分隔符的组成部分
+func isHereDocDelimPart(tok Token) bool {
+	switch tok.Type {
+	case IDENTIFIER, STRING, STRING_SINGLE, STRING_DOUBLE, NUMBER, ILLEGAL:
+		return true
+	}
+	return keywords[tok.Literal] == tok.Type && tok.Literal != ""
+}
+
+// atEOF 检查是否已经到达输入末尾
+func (l *Lexer) atEOF() bool {
+	return l.ch == 0 && l.chRune == 0
+}
+
+// offset 返回当前字符的字节偏移，到达末尾时返回输入长度
+func (l *Lexer) offset() int {
+	if l.atEOF() {
+		return len(l.input)
+	}
+	return l.position
+}
+
+// Input 返回词法分析器的完整输入
+func (l *Lexer) Input() string {
+	return l.input
+}
+
+// nextToken 读取下一个token（不包含位置修正和 here-document 处理）
+func (l *Lexer) nextToken() Token {
 	var tok Token
 
 	l.skipWhitespace()
 
-	// 检查是否是注释（# 开头，且不在引号内）
-	// 注意：这里简化处理，假设在 skipWhitespace 后遇到 # 就是注释
-	// 更精确的实现需要跟踪引号状态，但这里先简化
-	// 注意：引号内的 # 会在 readString 中处理，不会到达这里
+	// # 只在单词的开头才是注释，紧跟在单词之后时（如 a#b）是单词的一部分，由解析器与前面的片段拼接
+	if l.ch == '#' && l.position > 0 && !isWordBoundary(l.input[l.position-1]) {
+		tok.Literal = l.readIdentifierOrPath()
+		tok.Type = IDENTIFIER
+		tok.Line = l.line
+		tok.Column = l.column
+		return tok
+	}
+
+	// 检查是否是注释（单词开头的 #，引号内的 # 会在 readString 中处理，不会到达这里）
 	if l.ch == '#' {
 		// 跳过整行注释（直到换行符或EOF）
-		for l.ch != '\n' && l.ch != 0 {
-			l.readChar()
-		}
+		l.skipComment()
 		// 如果还有换行符，返回换行符 token
 		if l.ch == '\n' {
 			tok.Line = l.line
@@ -183,7 +364,7 @@ func (l *Lexer) NextToken() Token {
 			if l.peekChar() == '>' {
 				// &>> 追加
 				l.readChar()
-				tok = Token{Type: AND_GREATER_GREATER, Literal: string(ch) + string(l.ch) + string(l.peekChar()), Line: tok.Line, Column: tok.Column}
+				tok = Token{Type: AND_GREATER_GREATER, Literal: string(ch) + ">>", Line: tok.Line, Column: tok.Column}
 			} else {
 				// &> 覆盖
 				tok = Token{Type: AND_GREATER, Literal: string(ch) + string(l.ch), Line: tok.Line, Column: tok.Column}
@@ -216,9 +397,10 @@ func (l *Lexer) NextToken() Token {
 			tok.Type = PROCESS_SUBSTITUTION_OUT
 			tok.Line = startLine
 			tok.Column = startColumn
+			return tok
 		} else if isDigit(l.peekChar()) {
 			// 处理文件描述符重定向，如 2>
-			tok = l.readRedirectFD()
+			return l.readRedirectFD()
 		} else {
 			tok = newToken(REDIRECT_OUT, l.ch, tok.Line, tok.Column)
 		}
@@ -231,11 +413,11 @@ func (l *Lexer) NextToken() Token {
 			if peek2 == '-' {
 				// <<- Here-document with strip tabs
 				l.readChar() // 跳过 -
-				tok = Token{Type: REDIRECT_HEREDOC_STRIP, Literal: string(ch) + string(l.ch) + string(peek2), Line: tok.Line, Column: tok.Column}
+				tok = Token{Type: REDIRECT_HEREDOC_STRIP, Literal: string(ch) + "<-", Line: tok.Line, Column: tok.Column}
 			} else if peek2 == '<' {
 				// <<< Here-string
 				l.readChar() // 跳过第二个 <
-				tok = Token{Type: REDIRECT_HEREDOC_TABS, Literal: string(ch) + string(l.ch) + string(peek2), Line: tok.Line, Column: tok.Column}
+				tok = Token{Type: REDIRECT_HEREDOC_TABS, Literal: string(ch) + "<<", Line: tok.Line, Column: tok.Column}
 			} else {
 				// << Here-document
 				tok = Token{Type: REDIRECT_HEREDOC, Literal: string(ch) + string(l.ch), Line: tok.Line, Column: tok.Column}
@@ -260,6 +442,7 @@ func (l *Lexer) NextToken() Token {
 			tok.Type = PROCESS_SUBSTITUTION_IN
 			tok.Line = startLine
 			tok.Column = startColumn
+			return tok
 		} else {
 			tok = newToken(REDIRECT_IN, l.ch, tok.Line, tok.Column)
 		}
@@ -271,7 +454,7 @@ func (l *Lexer) NextToken() Token {
 			if peek2 == '&' {
 				// ;;& case 语句
 				l.readChar()
-				tok = Token{Type: SEMI_SEMI_AND, Literal: string(ch) + string(l.ch) + string(peek2), Line: tok.Line, Column: tok.Column}
+				tok = Token{Type: SEMI_SEMI_AND, Literal: string(ch) + ";&", Line: tok.Line, Column: tok.Column}
 			} else {
 				// ;; case 语句
 				tok = Token{Type: SEMI_SEMI, Literal: string(ch) + string(l.ch), Line: tok.Line, Column: tok.Column}
@@ -285,6 +468,14 @@ func (l *Lexer) NextToken() Token {
 			tok = newToken(SEMICOLON, l.ch, tok.Line, tok.Column)
 		}
 	case '(':
+		if l.peekChar() == '(' && isArithmeticCommand(l.input[l.position:]) {
+			// ((expr)) 算术命令
+			l.readChar() // 跳过第一个 (
+			l.readChar() // 跳过第二个 (
+			tok = l.readArithmeticExpansion()
+			tok.Type = ARITHMETIC_COMMAND
+			return tok
+		}
 		tok = newToken(LPAREN, l.ch, tok.Line, tok.Column)
 	case ')':
 		tok = newToken(RPAREN, l.ch, tok.Line, tok.Column)
@@ -309,19 +500,20 @@ func (l *Lexer) NextToken() Token {
 			tok = newToken(RBRACKET, l.ch, tok.Line, tok.Column)
 		}
 	case '=':
-		// = 字符应该已经在标识符读取时处理了（数组赋值 arr=(...)）
-		// 如果单独出现，作为非法字符
-		l.addError(LexerErrorTypeInvalidChar, fmt.Sprintf("无效字符 `%c'", l.ch),
-			string(l.ch), tok.Line, tok.Column)
+		// = 单独作为一个 token 返回（如 VAR=value、--opt=value 中的 =），
+		// 由解析器根据相邻关系拼接成完整的单词，这里不作为错误
 		tok = newToken(ILLEGAL, l.ch, tok.Line, tok.Column)
 	case '\'':
+		// 读取字符串、展开等的辅助函数会停在 token 之后的字符上，直接返回
 		tok = l.readString('\'')
 		tok.Type = STRING_SINGLE
+		return tok
 	case '"':
 		tok = l.readString('"')
 		tok.Type = STRING_DOUBLE
+		return tok
 	case '`':
-		tok = l.readCommandSubstitution()
+		return l.readCommandSubstitution()
 	case '\\':
 		// 检查是否是行尾的反斜杠（转义的换行符）
 		peek := l.peekChar()
@@ -331,15 +523,15 @@ func (l *Lexer) NextToken() Token {
 			l.readChar() // 跳过换行符
 			// 跳过反斜杠后的空白字符（空格、制表符等）
 			// 注意：不跳过换行符，因为已经跳过了
+			skipped := false
 			for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
 				l.readChar()
+				skipped = true
 			}
 			// 如果下一个字符是 #，跳过注释行
 			if l.ch == '#' {
 				// 跳过整行注释（直到下一个换行符或EOF）
-				for l.ch != '\n' && l.ch != 0 {
-					l.readChar()
-				}
+				l.skipComment()
 				// 如果还有换行符，跳过它
 				if l.ch == '\n' {
 					l.readChar()
@@ -348,11 +540,30 @@ func (l *Lexer) NextToken() Token {
 				for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
 					l.readChar()
 				}
+				skipped = true
+			}
+			if l.atEOF() {
+				// 输入以续行符结束，交互模式下需要继续读取下一行
+				l.continuationAtEOF = true
+			}
+			// 续行后紧跟的 token 与前一个 token 属于同一个单词（如 a\<换行>b），
+			// 中间有空白时才是新的单词
+			l.tokenLine = l.line
+			l.tokenColumn = l.column - 1
+			if skipped {
+				l.tokenStart = l.offset()
 			}
 			// 继续读取下一个 token
-			return l.NextToken()
+			return l.nextToken()
+		}
+		if l.readPosition < len(l.input) {
+			// 反斜杠转义下一个字符：被转义的字符按字面意义处理（与单引号相同），
+			// 这样 \;、\'、\$ 等不会被当作分隔符、引号或展开
+			l.readChar() // 跳过反斜杠
+			tok = Token{Type: STRING_SINGLE, Literal: string(l.chRune), Line: tok.Line, Column: tok.Column}
+		} else {
+			tok = newToken(ESCAPE, l.ch, tok.Line, tok.Column)
 		}
-		tok = newToken(ESCAPE, l.ch, tok.Line, tok.Column)
 	case '$':
 		// 检查是否是 $'...' 或 $"..." 格式
 		peek1 := l.peekChar()
@@ -401,6 +612,7 @@ func (l *Lexer) NextToken() Token {
 		} else {
 			tok = l.readVariable()
 		}
+		return tok
 	case 0:
 		// 检查是否真的到达文件末尾（chRune 也为 0）
 		// 如果 chRune 不为 0，说明是多字节字符，应该进入 default 分支处理
@@ -450,8 +662,8 @@ func (l *Lexer) NextToken() Token {
 				// 读取 [key] 或 [0]
 				bracketPart := "["
 				l.readChar() // 跳过 [
-				for l.ch != ']' && l.ch != 0 && l.ch != '\n' {
-					bracketPart += string(l.ch)
+				for l.ch != ']' && l.chRune != 0 && l.ch != '\n' {
+					bracketPart += string(l.chRune)
 					l.readChar()
 				}
 				if l.ch == ']' {
@@ -467,6 +679,16 @@ func (l *Lexer) NextToken() Token {
 						l.readChar() // 跳过 =
 						return tok
 					}
+					if l.ch == '+' && l.peekChar() == '=' {
+						// 数组元素追加 arr[key]+=
+						tok.Literal = ident + bracketPart + "+="
+						tok.Type = IDENTIFIER
+						tok.Line = l.line
+						tok.Column = l.column
+						l.readChar() // 跳过 +
+						l.readChar() // 跳过 =
+						return tok
+					}
 					// 不是赋值，只是数组访问，将 [key] 作为标识符的一部分
 					tok.Literal = ident + bracketPart
 					tok.Type = IDENTIFIER
@@ -485,6 +707,21 @@ func (l *Lexer) NextToken() Token {
 				l.readChar() // 跳过 =
 				return tok
 			}
+			// 追加赋值：arr+=(...) 与 arr=(...) 相同将 += 包含在标识符中；
+			// str+=value 将 + 包含在标识符中（name+），之后的 = 与 str=value 一样单独返回
+			if l.ch == '+' && l.peekChar() == '=' {
+				tok.Type = IDENTIFIER
+				tok.Line = l.line
+				tok.Column = l.column
+				l.readChar() // 跳过 +
+				if l.peekChar() == '(' {
+					tok.Literal = ident + "+="
+					l.readChar() // 跳过 =
+				} else {
+					tok.Literal = ident + "+"
+				}
+				return tok
+			}
 			tok.Literal = ident
 			tok.Type = LookupIdent(ident)
 			tok.Line = l.line
@@ -524,8 +761,8 @@ func (l *Lexer) NextToken() Token {
 				tok.Column = l.column
 				return tok
 			}
-			l.addError(LexerErrorTypeInvalidChar, fmt.Sprintf("无效字符 `%c'", l.ch),
-				string(l.ch), tok.Line, tok.Column)
+			l.addError(LexerErrorTypeInvalidChar, i18n.Sprintf("无效字符 `%c'", l.ch),
+				string(l.ch), l.tokenLine, l.tokenColumn)
 			tok = newToken(ILLEGAL, l.ch, tok.Line, tok.Column)
 		}
 	}
@@ -596,6 +833,7 @@ func (l *Lexer) readIdentifierOrPath() string {
 			l.chRune == '\'' ||
 			l.chRune == '"' ||
 			l.chRune == '`' ||
+			l.chRune == '\\' || // 反斜杠转义下一个字符，由 NextToken 处理
 			l.chRune == '=' { // 停止在 = 处，以便识别数组赋值
 			break
 		}
@@ -623,6 +861,7 @@ func (l *Lexer) readIdentifierOrPath() string {
 			l.chRune == '\'' ||
 			l.chRune == '"' ||
 			l.chRune == '`' ||
+			l.chRune == '\\' || // 反斜杠转义下一个字符，由 NextToken 处理
 			l.chRune == '=' {
 			// 下一个字符是分隔符，使用之前保存的结束位置
 			return l.input[position:currentEnd]
@@ -729,7 +968,7 @@ func (l *Lexer) readVariable() Token {
 		}
 		return Token{
 			Type:    VAR,
-			Literal: l.input[position:l.position],
+			Literal: l.input[position:l.offset()],
 			Line:    startLine,
 			Column:  startColumn,
 		}
@@ -742,7 +981,7 @@ func (l *Lexer) readVariable() Token {
 		// 读取完整的参数展开表达式（包括所有操作符和值）
 		// 例如：${VAR:-default}, ${VAR#pattern}, ${VAR:offset:length} 等
 		depth := 1 // 括号深度
-		for depth > 0 && l.ch != 0 {
+		for depth > 0 && !l.atEOF() {
 			if l.ch == '{' {
 				depth++
 			} else if l.ch == '}' {
@@ -762,7 +1001,7 @@ func (l *Lexer) readVariable() Token {
 				// 跳过引号内的内容
 				quote := l.ch
 				l.readChar()
-				for l.ch != quote && l.ch != 0 {
+				for l.ch != quote && !l.atEOF() {
 					if l.ch == '\\' && quote == '"' {
 						l.readChar() // 跳过转义字符
 					}
@@ -775,7 +1014,7 @@ func (l *Lexer) readVariable() Token {
 			} else if l.ch == '`' {
 				// 跳过命令替换
 				l.readChar()
-				for l.ch != '`' && l.ch != 0 {
+				for l.ch != '`' && !l.atEOF() {
 					if l.ch == '\\' {
 						l.readChar()
 					}
@@ -790,7 +1029,7 @@ func (l *Lexer) readVariable() Token {
 				l.readChar() // 跳过 $
 				l.readChar() // 跳过 (
 				cmdDepth := 1
-				for cmdDepth > 0 && l.ch != 0 {
+				for cmdDepth > 0 && !l.atEOF() {
 					if l.ch == '(' {
 						cmdDepth++
 					} else if l.ch == ')' {
@@ -805,6 +1044,7 @@ func (l *Lexer) readVariable() Token {
 			l.readChar()
 		}
 		// 如果没有找到匹配的 }，返回错误
+		l.addError(LexerErrorTypeUnclosedExpansion, i18n.T("未闭合的参数展开 `${'"), "${", l.tokenLine, l.tokenColumn)
 		return Token{Type: ILLEGAL, Literal: "${", Line: startLine, Column: startColumn}
 	} else if unicode.IsLetter(l.chRune) || l.chRune == '_' {
 		// $VAR 格式（支持 UTF-8）
@@ -887,41 +1127,24 @@ func (l *Lexer) readString(quote byte) Token {
 	var literal strings.Builder
 	
 	for l.chRune != 0 {
+		// 双引号中的转义序列和展开原样保留，由执行器展开时处理（见 ScanExpansion）；
+		// 展开中的引号和转义不结束字符串，如 "$(echo ")")"、"${x:-"a b"}"
 		if quote == '"' && l.ch == '\\' {
-			// 双引号内的转义处理
-			nextPos := l.readPosition
-			if nextPos < len(l.input) {
-				nextCh := l.input[nextPos]
-				if nextCh == '"' {
-					// \" 转义为 "，只保存 " 而不保存 \
-					literal.WriteByte('"')
-					l.readChar() // 跳过 \，移动到 "
-					l.readChar() // 跳过 "，移动到下一个字符
-					// 处理 \" 后，继续循环处理下一个字符
-					continue
-				} else if nextCh == '\n' {
-					// \n 转义为换行符，保留换行符但跳过反斜杠
-					literal.WriteByte('\n')
-					l.readChar() // 跳过 \
-					l.readChar() // 跳过 \n（readChar 会自动更新行号）
-					continue
-				} else if nextCh == '\\' {
-					// \\ 转义为单个反斜杠
-					literal.WriteByte('\\')
-					l.readChar() // 跳过第一个 \
-					l.readChar() // 跳过第二个 \
-					continue
-				} else {
-					// 其他转义序列保持原样（\$、\t等）
-					literal.WriteByte(l.ch) // 写入 \
+			literal.WriteByte(l.ch)
+			l.readChar()
+			if l.chRune != 0 {
+				literal.WriteRune(l.chRune)
+				l.readChar()
+			}
+			continue
+		}
+		if quote == '"' && (l.ch == '$' || l.ch == '`') {
+			if _, end, ok := ScanExpansion(l.input, l.position, true); ok {
+				literal.WriteString(l.input[l.position:end])
+				for l.position < end && l.chRune != 0 {
 					l.readChar()
-					if l.chRune != 0 {
-						// 使用 WriteRune 支持多字节字符
-						literal.WriteRune(l.chRune)
-						l.readChar()
-					}
-					continue
 				}
+				continue
 			}
 		}
 
@@ -945,8 +1168,8 @@ func (l *Lexer) readString(quote byte) Token {
 		// 未闭合的引号
 		result = literal.String()
 		quoteChar := string(quote)
-		l.addError(LexerErrorTypeUnclosedQuote, fmt.Sprintf("未闭合的引号 `%s'", quoteChar),
-			quoteChar, startLine, startColumn)
+		l.addError(LexerErrorTypeUnclosedQuote, i18n.Sprintf("未闭合的引号 `%s'", quoteChar),
+			quoteChar, l.tokenLine, l.tokenColumn)
 	}
 
 	return Token{
@@ -962,117 +1185,22 @@ func (l *Lexer) readString(quote byte) Token {
 func (l *Lexer) readCommandSubstitution() Token {
 	startLine := l.line
 	startColumn := l.column
-	l.readChar() // 跳过开始的反引号
 
-	var literal strings.Builder
-	backtickDepth := 1 // 反引号深度（支持嵌套的反引号，虽然 bash 不支持，但为了健壮性）
-
-	for backtickDepth > 0 && l.ch != 0 {
-		if l.ch == '`' {
-			// 检查是否是转义的反引号
-			if literal.Len() > 0 {
-				lastChar := literal.String()[literal.Len()-1]
-				if lastChar == '\\' {
-					// 转义的反引号，写入字面量
-					literal.WriteByte(l.ch)
-					l.readChar()
-					continue
-				}
-			}
-			backtickDepth--
-			if backtickDepth == 0 {
-				l.readChar() // 跳过结束反引号
-				break
-			}
-			literal.WriteByte(l.ch)
-			l.readChar()
-		} else if l.ch == '\'' || l.ch == '"' {
-			// 处理引号内的内容（引号内的反引号不应该结束命令替换）
-			quote := l.ch
-			literal.WriteByte(l.ch)
-			l.readChar()
-			for l.ch != quote && l.ch != 0 {
-				if l.ch == '\\' && quote == '"' {
-					// 双引号内的转义
-					literal.WriteByte(l.ch)
-					l.readChar()
-					if l.ch != 0 {
-						literal.WriteByte(l.ch)
-						l.readChar()
-					}
-				} else {
-					literal.WriteByte(l.ch)
-					l.readChar()
-				}
-			}
-			if l.ch == quote {
-				literal.WriteByte(l.ch)
-				l.readChar()
-			}
-		} else if l.ch == '\\' {
-			// 转义字符
-			literal.WriteByte(l.ch)
-			l.readChar()
-			if l.ch != 0 {
-				literal.WriteByte(l.ch)
-				l.readChar()
-			}
-		} else if l.ch == '$' && l.peekChar() == '(' {
-			// 嵌套的命令替换 $(...)，需要正确处理
-			literal.WriteByte(l.ch)
-			l.readChar()            // 跳过 $
-			literal.WriteByte(l.ch) // 写入 (
-			l.readChar()            // 跳过 (
-			parenDepth := 1
-			for parenDepth > 0 && l.ch != 0 {
-				if l.ch == '(' {
-					parenDepth++
-					literal.WriteByte(l.ch)
-					l.readChar()
-				} else if l.ch == ')' {
-					parenDepth--
-					literal.WriteByte(l.ch)
-					if parenDepth == 0 {
-						l.readChar()
-						break
-					}
-					l.readChar()
-				} else if l.ch == '\'' || l.ch == '"' {
-					// 处理引号
-					quote := l.ch
-					literal.WriteByte(l.ch)
-					l.readChar()
-					for l.ch != quote && l.ch != 0 {
-						if l.ch == '\\' && quote == '"' {
-							literal.WriteByte(l.ch)
-							l.readChar()
-							if l.ch != 0 {
-								literal.WriteByte(l.ch)
-								l.readChar()
-							}
-						} else {
-							literal.WriteByte(l.ch)
-							l.readChar()
-						}
-					}
-					if l.ch == quote {
-						literal.WriteByte(l.ch)
-						l.readChar()
-					}
-				} else {
-					literal.WriteByte(l.ch)
-					l.readChar()
-				}
-			}
-		} else {
-			literal.WriteByte(l.ch)
-			l.readChar()
-		}
+	// 与 bash 相同，命令在下一个没有转义的反引号处结束，其中的 \`、\\ 和 \$ 去掉反斜杠
+	exp, end, ok := ScanExpansion(l.input, l.position, false)
+	literal := exp.Body
+	if !ok {
+		literal = l.input[l.position+1:]
+		end = len(l.input)
+		l.addError(LexerErrorTypeUnclosedExpansion, i18n.T("未闭合的命令替换 ``'"), "`", l.tokenLine, l.tokenColumn)
+	}
+	for l.position < end && l.chRune != 0 {
+		l.readChar()
 	}
 
 	return Token{
 		Type:    COMMAND_SUBSTITUTION,
-		Literal: literal.String(),
+		Literal: literal,
 		Line:    startLine,
 		Column:  startColumn,
 	}
@@ -1083,21 +1211,23 @@ func (l *Lexer) readCommandSubstitution() Token {
 func (l *Lexer) readArithmeticExpansion() Token {
 	var literal strings.Builder
 	depth := 2 // 已经有两个开括号
+	closed := false
 
-	for depth > 0 && l.ch != 0 {
+	for depth > 0 && l.chRune != 0 {
 		if l.ch == '(' {
 			depth++
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		} else if l.ch == ')' {
 			depth--
 			if depth >= 2 {
 				// depth >= 2 表示这是表达式内部的 )，应该写入 literal
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 				l.readChar()
 			} else if depth == 0 {
 				// depth == 0 表示这是结束的 ))，应该跳过
 				l.readChar() // 跳过结束括号
+				closed = true
 				break
 			} else {
 				// depth == 1，这是结束的 )) 的第一个 )，不应该写入 literal
@@ -1106,6 +1236,7 @@ func (l *Lexer) readArithmeticExpansion() Token {
 				if l.ch == ')' {
 					// 这是结束的 ))，跳过
 					l.readChar()
+					closed = true
 					break
 				} else {
 					// 这不是结束的 ))，可能是其他情况，写入刚才的 )
@@ -1115,23 +1246,23 @@ func (l *Lexer) readArithmeticExpansion() Token {
 		} else if l.ch == '\'' || l.ch == '"' {
 			// 处理引号内的内容（虽然算术表达式中引号不常见，但为了健壮性处理）
 			quote := l.ch
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
-			for l.ch != quote && l.ch != 0 {
+			for l.ch != quote && l.chRune != 0 {
 				if l.ch == '\\' && quote == '"' {
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
-					if l.ch != 0 {
-						literal.WriteByte(l.ch)
+					if l.chRune != 0 {
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					}
 				} else {
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
 				}
 			}
 			if l.ch == quote {
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 				l.readChar()
 			}
 		} else if l.ch == '$' && l.peekChar() == '(' {
@@ -1139,63 +1270,63 @@ func (l *Lexer) readArithmeticExpansion() Token {
 			peek2 := l.peekChar2()
 			if peek2 == '(' {
 				// $((...)) 嵌套的算术展开，需要完整保留包括结束的 ))
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 				l.readChar()            // 跳过 $
-				literal.WriteByte(l.ch) // 写入第一个 (
+				literal.WriteRune(l.chRune) // 写入第一个 (
 				l.readChar()            // 跳过第一个 (
-				literal.WriteByte(l.ch) // 写入第二个 (
+				literal.WriteRune(l.chRune) // 写入第二个 (
 				l.readChar()            // 跳过第二个 (
 				nestedDepth := 2
-				for nestedDepth > 0 && l.ch != 0 {
+				for nestedDepth > 0 && l.chRune != 0 {
 					if l.ch == '(' {
 						nestedDepth++
-						literal.WriteByte(l.ch)
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					} else if l.ch == ')' {
 						nestedDepth--
 						if nestedDepth >= 2 {
 							// 表达式内部的 )
-							literal.WriteByte(l.ch)
+							literal.WriteRune(l.chRune)
 							l.readChar()
 						} else if nestedDepth == 0 {
 							// 结束的 ))，需要写入两个 )
-							literal.WriteByte(l.ch) // 写入第一个 )
+							literal.WriteRune(l.chRune) // 写入第一个 )
 							l.readChar()
 							if l.ch == ')' {
-								literal.WriteByte(l.ch) // 写入第二个 )
+								literal.WriteRune(l.chRune) // 写入第二个 )
 								l.readChar()
 							}
 							break
 						} else {
 							// depth == 1，这是结束的 )) 的第一个 )
-							literal.WriteByte(l.ch) // 写入第一个 )
+							literal.WriteRune(l.chRune) // 写入第一个 )
 							l.readChar()
 							if l.ch == ')' {
-								literal.WriteByte(l.ch) // 写入第二个 )
+								literal.WriteRune(l.chRune) // 写入第二个 )
 								l.readChar()
 								break
 							}
 						}
 					} else {
-						literal.WriteByte(l.ch)
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					}
 				}
 			} else {
 				// $(...) 命令替换（在算术展开中）
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 				l.readChar()            // 跳过 $
-				literal.WriteByte(l.ch) // 写入 (
+				literal.WriteRune(l.chRune) // 写入 (
 				l.readChar()            // 跳过 (
 				nestedDepth := 1
-				for nestedDepth > 0 && l.ch != 0 {
+				for nestedDepth > 0 && l.chRune != 0 {
 					if l.ch == '(' {
 						nestedDepth++
-						literal.WriteByte(l.ch)
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					} else if l.ch == ')' {
 						nestedDepth--
-						literal.WriteByte(l.ch)
+						literal.WriteRune(l.chRune)
 						if nestedDepth == 0 {
 							l.readChar()
 							break
@@ -1203,37 +1334,41 @@ func (l *Lexer) readArithmeticExpansion() Token {
 						l.readChar()
 					} else if l.ch == '\'' || l.ch == '"' {
 						quote := l.ch
-						literal.WriteByte(l.ch)
+						literal.WriteRune(l.chRune)
 						l.readChar()
-						for l.ch != quote && l.ch != 0 {
+						for l.ch != quote && l.chRune != 0 {
 							if l.ch == '\\' && quote == '"' {
-								literal.WriteByte(l.ch)
+								literal.WriteRune(l.chRune)
 								l.readChar()
-								if l.ch != 0 {
-									literal.WriteByte(l.ch)
+								if l.chRune != 0 {
+									literal.WriteRune(l.chRune)
 									l.readChar()
 								}
 							} else {
-								literal.WriteByte(l.ch)
+								literal.WriteRune(l.chRune)
 								l.readChar()
 							}
 						}
 						if l.ch == quote {
-							literal.WriteByte(l.ch)
+							literal.WriteRune(l.chRune)
 							l.readChar()
 						}
 					} else {
-						literal.WriteByte(l.ch)
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					}
 				}
 			}
 		} else {
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		}
 	}
 
+	if !closed {
+		l.addError(LexerErrorTypeUnclosedExpansion, i18n.T("未闭合的算术展开 `$(('"), "$((", l.tokenLine, l.tokenColumn)
+	}
+
 	return Token{
 		Type:    ARITHMETIC_EXPANSION,
 		Literal: literal.String(),
@@ -1242,21 +1377,45 @@ func (l *Lexer) readArithmeticExpansion() Token {
 	}
 }
 
+// isArithmeticCommand 判断以 (( 开始的 s 是否是算术命令 ((expr))
+// 与 bash 一样，开头的 (( 没有对应的 )) 时（如 ((cmd1); cmd2)）作为嵌套的子shell 处理
+func isArithmeticCommand(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 1 {
+				return i+1 < len(s) && s[i+1] == ')'
+			}
+		case '\'', '"':
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				return false
+			}
+			i += end + 1
+		}
+	}
+	return false
+}
+
 // readCommandSubstitutionParen 读取命令替换（$(command)格式）
 // 正确处理嵌套的括号、引号、命令替换等
 func (l *Lexer) readCommandSubstitutionParen() Token {
 	var literal strings.Builder
 	depth := 1 // 已经有一个开括号
 
-	for depth > 0 && l.ch != 0 {
+	for depth > 0 && l.chRune != 0 {
 		if l.ch == '(' {
 			depth++
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		} else if l.ch == ')' {
 			depth--
 			if depth > 0 {
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 			}
 			if depth == 0 {
 				l.readChar() // 跳过结束括号
@@ -1266,62 +1425,62 @@ func (l *Lexer) readCommandSubstitutionParen() Token {
 		} else if l.ch == '\'' || l.ch == '"' {
 			// 处理引号内的内容（引号内的括号不应该影响深度计数）
 			quote := l.ch
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
-			for l.ch != quote && l.ch != 0 {
+			for l.ch != quote && l.chRune != 0 {
 				if l.ch == '\\' && quote == '"' {
 					// 双引号内的转义
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
-					if l.ch != 0 {
-						literal.WriteByte(l.ch)
+					if l.chRune != 0 {
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					}
 				} else {
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
 				}
 			}
 			if l.ch == quote {
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 				l.readChar()
 			}
 		} else if l.ch == '`' {
 			// 嵌套的反引号命令替换
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
-			for l.ch != '`' && l.ch != 0 {
+			for l.ch != '`' && l.chRune != 0 {
 				if l.ch == '\\' {
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
-					if l.ch != 0 {
-						literal.WriteByte(l.ch)
+					if l.chRune != 0 {
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					}
 				} else {
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
 				}
 			}
 			if l.ch == '`' {
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 				l.readChar()
 			}
 		} else if l.ch == '$' && l.peekChar() == '(' {
 			// 嵌套的 $(...) 命令替换
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()            // 跳过 $
-			literal.WriteByte(l.ch) // 写入 (
+			literal.WriteRune(l.chRune) // 写入 (
 			l.readChar()            // 跳过 (
 			nestedDepth := 1
-			for nestedDepth > 0 && l.ch != 0 {
+			for nestedDepth > 0 && l.chRune != 0 {
 				if l.ch == '(' {
 					nestedDepth++
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
 				} else if l.ch == ')' {
 					nestedDepth--
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					if nestedDepth == 0 {
 						l.readChar()
 						break
@@ -1330,44 +1489,48 @@ func (l *Lexer) readCommandSubstitutionParen() Token {
 				} else if l.ch == '\'' || l.ch == '"' {
 					// 处理引号
 					quote := l.ch
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
-					for l.ch != quote && l.ch != 0 {
+					for l.ch != quote && l.chRune != 0 {
 						if l.ch == '\\' && quote == '"' {
-							literal.WriteByte(l.ch)
+							literal.WriteRune(l.chRune)
 							l.readChar()
-							if l.ch != 0 {
-								literal.WriteByte(l.ch)
+							if l.chRune != 0 {
+								literal.WriteRune(l.chRune)
 								l.readChar()
 							}
 						} else {
-							literal.WriteByte(l.ch)
+							literal.WriteRune(l.chRune)
 							l.readChar()
 						}
 					}
 					if l.ch == quote {
-						literal.WriteByte(l.ch)
+						literal.WriteRune(l.chRune)
 						l.readChar()
 					}
 				} else {
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 					l.readChar()
 				}
 			}
 		} else if l.ch == '\\' {
 			// 转义字符
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
-			if l.ch != 0 {
-				literal.WriteByte(l.ch)
+			if l.chRune != 0 {
+				literal.WriteRune(l.chRune)
 				l.readChar()
 			}
 		} else {
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		}
 	}
 
+	if depth > 0 {
+		l.addError(LexerErrorTypeUnclosedExpansion, i18n.T("未闭合的命令替换 `$('"), "$(", l.tokenLine, l.tokenColumn)
+	}
+
 	return Token{
 		Type:    COMMAND_SUBSTITUTION,
 		Literal: literal.String(),
@@ -1377,82 +1540,88 @@ func (l *Lexer) readCommandSubstitutionParen() Token {
 }
 
 // readDollarSingleQuote 读取 $'...' ANSI-C 字符串
+// 支持 bash 的全部转义：\a \b \e \E \f \n \r \t \v \\ \' \" \?、\nnn（八进制）、\xHH、\uHHHH、\UHHHHHHHH 和 \cX（控制字符）
 func (l *Lexer) readDollarSingleQuote() Token {
 	startLine := l.line
 	startColumn := l.column
 	var literal strings.Builder
 
-	for l.ch != 0 {
+	closed := false
+	for l.chRune != 0 {
 		if l.ch == '\'' {
 			// 找到结束引号
 			l.readChar() // 跳过结束引号
+			closed = true
 			break
 		}
-		if l.ch == '\\' {
-			// 处理转义序列
+		if l.ch != '\\' {
+			literal.WriteRune(l.chRune)
 			l.readChar()
-			if l.ch != 0 {
-				// 处理 ANSI-C 转义序列
-				switch l.ch {
-				case 'a':
-					literal.WriteByte('\a')
-				case 'b':
-					literal.WriteByte('\b')
-				case 'f':
-					literal.WriteByte('\f')
-				case 'n':
-					literal.WriteByte('\n')
-				case 'r':
-					literal.WriteByte('\r')
-				case 't':
-					literal.WriteByte('\t')
-				case 'v':
-					literal.WriteByte('\v')
-				case '\\':
-					literal.WriteByte('\\')
-				case '\'':
-					literal.WriteByte('\'')
-				case 'x':
-					// \xHH 十六进制
-					l.readChar()
-					hex := ""
-					for isHexDigit(l.ch) && len(hex) < 2 {
-						hex += string(l.ch)
-						l.readChar()
-					}
-					if len(hex) > 0 {
-						if val, err := strconv.ParseUint(hex, 16, 8); err == nil {
-							literal.WriteByte(byte(val))
-						}
-					}
-					continue
-				case '0', '1', '2', '3', '4', '5', '6', '7':
-					// \0NNN 八进制
-					oct := string(l.ch)
-					l.readChar()
-					for isOctDigit(l.ch) && len(oct) < 3 {
-						oct += string(l.ch)
-						l.readChar()
-					}
-					if val, err := strconv.ParseUint(oct, 8, 8); err == nil {
-						literal.WriteByte(byte(val))
-					}
-					continue
-				default:
-					literal.WriteByte('\\')
-					literal.WriteByte(l.ch)
-				}
+			continue
+		}
+		// 处理转义序列
+		l.readChar()
+		if l.chRune == 0 {
+			literal.WriteByte('\\')
+			break
+		}
+		if c, ok := ansiCEscapes[l.ch]; ok {
+			literal.WriteByte(c)
+			l.readChar()
+			continue
+		}
+		switch l.ch {
+		case 'x':
+			// \xHH 十六进制（一到两位）
+			l.readChar()
+			if hex := l.readDigits(isHexDigit, 2); hex != "" {
+				val, _ := strconv.ParseUint(hex, 16, 8)
+				literal.WriteByte(byte(val))
+			} else {
+				literal.WriteString("\\x")
+			}
+		case 'u', 'U':
+			// \uHHHH、\UHHHHHHHH Unicode 字符
+			max, prefix := 4, "\\u"
+			if l.ch == 'U' {
+				max, prefix = 8, "\\U"
+			}
+			l.readChar()
+			hex := l.readDigits(isHexDigit, max)
+			if hex == "" {
+				literal.WriteString(prefix)
+				continue
+			}
+			val, _ := strconv.ParseUint(hex, 16, 32)
+			literal.WriteRune(rune(val))
+		case 'c':
+			// \cX 控制字符（X 与 0x1f 按位与，\c? 是 DEL）
+			l.readChar()
+			switch {
+			case l.chRune == 0 || l.chRune >= utf8.RuneSelf:
+				literal.WriteString("\\c")
+			case l.ch == '?':
+				literal.WriteByte(0x7f)
+				l.readChar()
+			default:
+				literal.WriteByte(l.ch & 0x1f)
 				l.readChar()
 			}
-		} else {
-			literal.WriteByte(l.ch)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// \nnn 八进制（一到三位）
+			oct := l.readDigits(isOctDigit, 3)
+			val, _ := strconv.ParseUint(oct, 8, 16)
+			literal.WriteByte(byte(val))
+		default:
+			literal.WriteByte('\\')
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		}
 	}
 
 	// 检查是否未闭合
-	if l.ch == 0 {
-		l.addError(LexerErrorTypeUnclosedString, "未闭合的 $'...' 字符串", "'", startLine, startColumn)
+	if !closed {
+		l.addError(LexerErrorTypeUnclosedString, i18n.T("未闭合的 $'...' 字符串"), "'", l.tokenLine, l.tokenColumn)
 	}
 
 	return Token{
@@ -1463,22 +1632,39 @@ func (l *Lexer) readDollarSingleQuote() Token {
 	}
 }
 
+// ansiCEscapes $'...' 中单个字符的转义序列
+var ansiCEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'e': 0x1b, 'E': 0x1b, 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'\\': '\\', '\'': '\'', '"': '"', '?': '?',
+}
+
+// readDigits 读取最多 max 个满足 valid 的字符
+func (l *Lexer) readDigits(valid func(byte) bool, max int) string {
+	start := l.position
+	for l.offset()-start < max && l.chRune != 0 && l.chRune < utf8.RuneSelf && valid(l.ch) {
+		l.readChar()
+	}
+	return l.input[start:l.offset()]
+}
+
 // readDollarDoubleQuote 读取 $"..." 国际化字符串
 func (l *Lexer) readDollarDoubleQuote() Token {
 	startLine := l.line
 	startColumn := l.column
 	var literal strings.Builder
 
-	for l.ch != 0 {
+	closed := false
+	for l.chRune != 0 {
 		if l.ch == '"' {
 			// 找到结束引号
 			l.readChar() // 跳过结束引号
+			closed = true
 			break
 		}
 		if l.ch == '\\' {
 			// 处理转义序列
 			l.readChar()
-			if l.ch != 0 {
+			if l.chRune != 0 {
 				switch l.ch {
 				case '"':
 					literal.WriteByte('"')
@@ -1491,19 +1677,19 @@ func (l *Lexer) readDollarDoubleQuote() Token {
 				default:
 					// 其他转义序列保持原样
 					literal.WriteByte('\\')
-					literal.WriteByte(l.ch)
+					literal.WriteRune(l.chRune)
 				}
 				l.readChar()
 			}
 		} else {
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		}
 	}
 
 	// 检查是否未闭合
-	if l.ch == 0 {
-		l.addError(LexerErrorTypeUnclosedString, "未闭合的 $\"...\" 字符串", "\"", startLine, startColumn)
+	if !closed {
+		l.addError(LexerErrorTypeUnclosedString, i18n.T("未闭合的 $\"...\" 字符串"), "\"", l.tokenLine, l.tokenColumn)
 	}
 
 	return Token{
@@ -1519,15 +1705,15 @@ func (l *Lexer) readProcessSubstitution() Token {
 	var literal strings.Builder
 	depth := 1 // 已经有一个开括号
 
-	for depth > 0 && l.ch != 0 {
+	for depth > 0 && l.chRune != 0 {
 		if l.ch == '(' {
 			depth++
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		} else if l.ch == ')' {
 			depth--
 			if depth > 0 {
-				literal.WriteByte(l.ch)
+				literal.WriteRune(l.chRune)
 			}
 			if depth == 0 {
 				l.readChar() // 跳过结束括号
@@ -1536,18 +1722,22 @@ func (l *Lexer) readProcessSubstitution() Token {
 			l.readChar()
 		} else if l.ch == '\\' {
 			// 转义字符
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
-			if l.ch != 0 {
-				literal.WriteByte(l.ch)
+			if l.chRune != 0 {
+				literal.WriteRune(l.chRune)
 				l.readChar()
 			}
 		} else {
-			literal.WriteByte(l.ch)
+			literal.WriteRune(l.chRune)
 			l.readChar()
 		}
 	}
 
+	if depth > 0 {
+		l.addError(LexerErrorTypeUnclosedExpansion, i18n.T("未闭合的进程替换"), "(", l.tokenLine, l.tokenColumn)
+	}
+
 	return Token{
 		Type:    PROCESS_SUBSTITUTION_IN, // 临时类型，实际类型由调用者设置
 		Literal: literal.String(),
@@ -1576,6 +1766,64 @@ func (l *Lexer) addError(errType LexerErrorType, message, char string, line, col
 	l.errors = append(l.errors, err)
 }
 
+// EndsWithContinuation 检查输入是否以续行符（行尾的反斜杠）结束
+func (l *Lexer) EndsWithContinuation() bool {
+	return l.continuationAtEOF
+}
+
+// NeedsMore 检查已经扫描的输入是否需要继续输入：引号、展开或 here-document 没有结束，或者以续行符结尾。
+// closer 是最内层（开始位置最靠后）没有结束的结构期望的结束符（见 LexerError.Closer），只是续行时为空字符串；
+// 存在其他词法错误时输入不是不完整而是有错误，返回 false
+func (l *Lexer) NeedsMore() (closer string, more bool) {
+	var last *LexerError
+	for _, e := range l.errors {
+		if !e.Incomplete() {
+			return "", false
+		}
+		if last == nil || e.Line > last.Line || e.Line == last.Line && e.Column >= last.Column {
+			last = e
+		}
+	}
+	if last != nil {
+		return last.Closer(), true
+	}
+	return "", l.continuationAtEOF
+}
+
+// NeedsMore 扫描整个输入（不进行语法分析），检查它是否需要继续输入，见 (*Lexer).NeedsMore
+// 缺少 fi、done 等关键字由语法分析器判断，见 parser.(*Parser).NeedsMore
+func NeedsMore(input string) (closer string, more bool) {
+	l := New(input)
+	for l.NextToken().Type != EOF {
+	}
+	return l.NeedsMore()
+}
+
+// Comment 源代码中的一个注释
+type Comment struct {
+	Line int    // 注释所在的行
+	Text string // 注释的内容，包括开头的 #
+}
+
+// skipComment 跳过从 # 开始直到行尾（不包括换行符）的注释，并记录下来
+func (l *Lexer) skipComment() {
+	start, line := l.position, l.line
+	for l.ch != '\n' && l.chRune != 0 {
+		l.readChar()
+	}
+	l.comments = append(l.comments, Comment{Line: line, Text: strings.TrimRight(l.input[start:l.position], "\r")})
+}
+
+// Comments 按出现的顺序返回已经跳过的注释；注释不在语法树中，格式化源代码时按行号把它们放回
+func (l *Lexer) Comments() []Comment {
+	return l.comments
+}
+
+// isWordBoundary 报告字符 ch 之后是否是新单词的开头（空白和元字符之后的 # 是注释）
+func isWordBoundary(ch byte) bool {
+	return strings.IndexByte(" \t\r\n;&|()<>", ch) >= 0
+}
+
 // Errors 返回词法分析器错误列表
 func (l *Lexer) Errors() []*LexerError {
 	return l.errors
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -124,6 +125,8 @@ func TestReadVariable(t *testing.T) {
 		{"$VAR", "VAR"},
 		{"$HOME", "HOME"},
 		{"${VAR}", "VAR"},
+		{"$1", "1"},
+		{"$12", "12"},
 	}
 
 	for _, tt := range tests {
@@ -321,6 +324,29 @@ func TestUTF8Support(t *testing.T) {
 				{Type: EOF, Literal: ""},
 			},
 		},
+		{
+			name:  "中文注释",
+			input: "echo a # 中文注释\necho b",
+			expected: []Token{
+				{Type: IDENTIFIER, Literal: "echo"},
+				{Type: IDENTIFIER, Literal: "a"},
+				{Type: NEWLINE, Literal: "\n"},
+				{Type: IDENTIFIER, Literal: "echo"},
+				{Type: IDENTIFIER, Literal: "b"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "命令替换、进程替换和 $\"...\" 中的中文",
+			input: "echo $(echo \"中\" 文) <(echo 中文) $\"中文\"",
+			expected: []Token{
+				{Type: IDENTIFIER, Literal: "echo"},
+				{Type: COMMAND_SUBSTITUTION, Literal: "echo \"中\" 文"},
+				{Type: PROCESS_SUBSTITUTION_IN, Literal: "echo 中文"},
+				{Type: STRING_DOLLAR_DOUBLE, Literal: "中文"},
+				{Type: EOF, Literal: ""},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -362,3 +388,196 @@ func TestNestedArithmeticExpansion(t *testing.T) {
 	}
 }
 
+
+// TestTokenPositions 测试 token 的位置信息，以及字符串和展开之后的 token 不会丢失
+func TestTokenPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Token
+	}{
+		{"'a';b", []Token{{Type: STRING_SINGLE, Literal: "a", Pos: 0, End: 3}, {Type: SEMICOLON, Literal: ";", Pos: 3, End: 4}, {Type: IDENTIFIER, Literal: "b", Pos: 4, End: 5}}},
+		{"echo $x\ndone", []Token{{Type: IDENTIFIER, Literal: "echo", Pos: 0, End: 4}, {Type: VAR, Literal: "x", Pos: 5, End: 7}, {Type: NEWLINE, Literal: "\n", Pos: 7, End: 8}, {Type: DONE, Literal: "done", Pos: 8, End: 12}}},
+		{"a\"b\"$(c)", []Token{{Type: IDENTIFIER, Literal: "a", Pos: 0, End: 1}, {Type: STRING_DOUBLE, Literal: "b", Pos: 1, End: 4}, {Type: COMMAND_SUBSTITUTION, Literal: "c", Pos: 4, End: 8}}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("测试 %q token %d: 期望 %v %q，得到 %v %q", tt.input, i, expected.Type, expected.Literal, tok.Type, tok.Literal)
+			}
+			if tok.Pos != expected.Pos || tok.End != expected.End {
+				t.Errorf("测试 %q token %d: 位置错误，期望 %d-%d，得到 %d-%d", tt.input, i, expected.Pos, expected.End, tok.Pos, tok.End)
+			}
+		}
+	}
+}
+
+// TestHereDocumentBody 测试 here-document 正文在换行后作为 HEREDOC_CONTENT 返回
+func TestHereDocumentBody(t *testing.T) {
+	l := New("cat <<-EOF\n\thello\n\tEOF\necho")
+	expected := []struct {
+		typ     TokenType
+		literal string
+	}{
+		{IDENTIFIER, "cat"},
+		{REDIRECT_HEREDOC_STRIP, "<<-"},
+		{IDENTIFIER, "EOF"},
+		{NEWLINE, "\n"},
+		{HEREDOC_CONTENT, "hello\n"},
+		{IDENTIFIER, "echo"},
+	}
+	for i, e := range expected {
+		tok := l.NextToken()
+		if tok.Type != e.typ || tok.Literal != e.literal {
+			t.Errorf("token %d: 期望 %v %q，得到 %v %q", i, e.typ, e.literal, tok.Type, tok.Literal)
+		}
+	}
+	if l.HasErrors() {
+		t.Errorf("不应该有错误: %v", l.Errors())
+	}
+}
+
+// TestScanExpansion 测试展开范围的识别：引号、转义和嵌套的展开中的结束符不结束展开
+func TestScanExpansion(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  ExpansionKind
+		body  string
+		rest  string // 展开之后的文本
+		ok    bool
+	}{
+		{`$x-y`, ExpansionParameter, "x", "-y", true},
+		{`$arr[1]x`, ExpansionParameter, "arr[1]", "x", true},
+		{`$10`, ExpansionParameter, "1", "0", true},
+		{`$?x`, ExpansionParameter, "?", "x", true},
+		{`$-`, 0, "", "", false},
+		{`$`, 0, "", "", false},
+		{`${x:-$(echo })}y`, ExpansionBraced, "x:-$(echo })", "y", true},
+		{`${x:-"}"}`, ExpansionBraced, `x:-"}"`, "", true},
+		{`${x:-'}'}z`, ExpansionBraced, `x:-'}'`, "z", true},
+		{`${x`, 0, "", "", false},
+		{`$(echo ")")x`, ExpansionCommand, `echo ")"`, "x", true},
+		{`$(echo \))`, ExpansionCommand, `echo \)`, "", true},
+		{`$(case a in a) echo c;; esac)`, ExpansionCommand, "case a in a) echo c;; esac", "", true},
+		{"$(echo a # )\n)", ExpansionCommand, "echo a # )\n", "", true},
+		{`$(echo case a)`, ExpansionCommand, "echo case a", "", true},
+		{`$(echo $(echo ")"))`, ExpansionCommand, `echo $(echo ")")`, "", true},
+		{`$((1+(2*3))))`, ExpansionArith, "1+(2*3)", ")", true},
+		{`$(( $(echo 2) * 3 ))`, ExpansionArith, " $(echo 2) * 3 ", "", true},
+		{`$((cd /; ls) | wc)`, ExpansionCommand, "(cd /; ls) | wc", "", true},
+		{"`echo \\`a\\``b", ExpansionCommand, "echo `a`", "b", true},
+		{"`echo", 0, "", "", false},
+	}
+	for _, tt := range tests {
+		exp, end, ok := ScanExpansion(tt.input, 0, false)
+		if ok != tt.ok || ok && (exp.Kind != tt.kind || exp.Body != tt.body || tt.input[end:] != tt.rest) {
+			t.Errorf("ScanExpansion(%q) = %v %q %v，剩余 %q", tt.input, exp.Kind, exp.Body, ok, tt.input[end:])
+		}
+	}
+
+	// 双引号中 ${...} 里的单引号是普通字符
+	if exp, _, ok := ScanExpansion(`${x:-it's}`, 0, true); !ok || exp.Body != "x:-it's" {
+		t.Errorf("双引号中的 ${x:-it's} = %q %v", exp.Body, ok)
+	}
+}
+
+// TestDoubleQuotedString 测试双引号字符串保留原始的转义序列，展开中的引号不结束字符串
+func TestDoubleQuotedString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"a\"b\\c\$d"`, `a\"b\\c\$d`},
+		{`"a${x:-"b}"}c"`, `a${x:-"b}"}c`},
+		{`"$(echo ")")"`, `$(echo ")")`},
+		{"\"`echo \"q\"`\"", "`echo \"q\"`"},
+		{`"$"`, `$`},
+	}
+	for _, tt := range tests {
+		l := New(tt.input + " x")
+		tok := l.NextToken()
+		if tok.Type != STRING_DOUBLE || tok.Literal != tt.want {
+			t.Errorf("%s 得到 %s %q，期望 %q", tt.input, tok.Type, tok.Literal, tt.want)
+		}
+		if next := l.NextToken(); next.Literal != "x" {
+			t.Errorf("%s 之后的 token 是 %q", tt.input, next.Literal)
+		}
+	}
+	if got := UnescapeDoubleQuoted("a\\\"b\\\\c\\$d\\ne\\\nf"); got != "a\"b\\c$d\\nef" {
+		t.Errorf("UnescapeDoubleQuoted = %q", got)
+	}
+}
+
+// TestBackslashEscapes 测试引号外的反斜杠转义下一个字符，反引号中的 \` \\ \$ 去掉反斜杠
+func TestBackslashEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Token
+	}{
+		{`/tmp/a\ b`, []Token{{Type: IDENTIFIER, Literal: "/tmp/a"}, {Type: STRING_SINGLE, Literal: " "}, {Type: IDENTIFIER, Literal: "b"}}},
+		{`a.txt\*`, []Token{{Type: IDENTIFIER, Literal: "a.txt"}, {Type: STRING_SINGLE, Literal: "*"}}},
+		{"`echo a\\\\\\\\b \\`x\\` \\$y`", []Token{{Type: COMMAND_SUBSTITUTION, Literal: "echo a\\\\b `x` $y"}}},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, want := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Errorf("%s 的第 %d 个 token 是 %s %q，期望 %s %q", tt.input, i, tok.Type, tok.Literal, want.Type, want.Literal)
+			}
+		}
+		if tok := l.NextToken(); tok.Type != EOF {
+			t.Errorf("%s 多出 token %s %q", tt.input, tok.Type, tok.Literal)
+		}
+	}
+}
+
+// TestDollarSingleQuote 测试 $'...' 的 ANSI-C 转义
+func TestDollarSingleQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`$'a\tb\n'`, "a\tb\n"},
+		{`$'\e[31m\E[0m'`, "\x1b[31m\x1b[0m"},
+		{`$'\x41\x4g\101\0'`, "A\x04gA\x00"},
+		{`$'\u4e2d\U0001F600\u41z'`, "中😀Az"},
+		{`$'\cA\ca\c?'`, "\x01\x01\x7f"},
+		{`$'\'\"\?\\\q'`, "'\"?\\\\q"},
+		{`$'中文\x41'`, "中文A"},
+	}
+	for _, tt := range tests {
+		l := New(tt.input + " x")
+		tok := l.NextToken()
+		if tok.Type != STRING_DOLLAR_SINGLE || tok.Literal != tt.want {
+			t.Errorf("%s 得到 %s %q，期望 %q", tt.input, tok.Type, tok.Literal, tt.want)
+		}
+		if next := l.NextToken(); next.Literal != "x" {
+			t.Errorf("%s 之后的 token 是 %q", tt.input, next.Literal)
+		}
+	}
+	l := New(`echo $'x'`)
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("结束引号在输入末尾时不应该报错: %v", l.Errors())
+	}
+}
+
+// TestComments 测试只有词开头的 # 开始注释，注释按行号记录
+func TestComments(t *testing.T) {
+	l := New("# 开头\necho a#b ${#x} # 行尾\n")
+	var words []string
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		words = append(words, tok.Literal)
+	}
+	if got := strings.Join(words, " "); got != "\n echo a #b #x \n" {
+		t.Errorf("token %q", got)
+	}
+	want := []Comment{{Line: 1, Text: "# 开头"}, {Line: 2, Text: "# 行尾"}}
+	if got := l.Comments(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("注释 %v，期望 %v", got, want)
+	}
+}
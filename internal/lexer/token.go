@@ -59,6 +59,7 @@ const (
 	
 	// 算术展开
 	ARITHMETIC_EXPANSION // $((expr))
+	ARITHMETIC_COMMAND   // ((expr))
 	
 	// 进程替换
 	PROCESS_SUBSTITUTION_IN  // <(command)
@@ -82,6 +83,7 @@ const (
 	FI
 	FOR
 	WHILE
+	UNTIL
 	DO
 	DONE
 	CASE
@@ -115,6 +117,8 @@ type Token struct {
 	Literal string
 	Line    int
 	Column  int
+	Pos     int // token 在输入中的起始字节偏移
+	End     int // token 在输入中的结束字节偏移（不含）
 }
 
 // String 返回token的字符串表示
@@ -180,7 +184,6 @@ func (t TokenType) String() string {
 		return "AND_GREATER"
 	case AND_GREATER_GREATER:
 		return "AND_GREATER_GREATER"
-		return "AMPERSAND"
 	case SINGLE_QUOTE:
 		return "SINGLE_QUOTE"
 	case DOUBLE_QUOTE:
@@ -221,6 +224,8 @@ func (t TokenType) String() string {
 		return "FOR"
 	case WHILE:
 		return "WHILE"
+	case UNTIL:
+		return "UNTIL"
 	case DO:
 		return "DO"
 	case DONE:
@@ -235,6 +240,8 @@ func (t TokenType) String() string {
 		return "COMMAND_SUBSTITUTION"
 	case ARITHMETIC_EXPANSION:
 		return "ARITHMETIC_EXPANSION"
+	case ARITHMETIC_COMMAND:
+		return "ARITHMETIC_COMMAND"
 	case PROCESS_SUBSTITUTION_IN:
 		return "PROCESS_SUBSTITUTION_IN"
 	case PROCESS_SUBSTITUTION_OUT:
@@ -285,6 +292,7 @@ var keywords = map[string]TokenType{
 	"fi":       FI,
 	"for":      FOR,
 	"while":    WHILE,
+	"until":    UNTIL,
 	"do":       DO,
 	"done":     DONE,
 	"case":     CASE,
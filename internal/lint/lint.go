@@ -0,0 +1,541 @@
+// Package lint 对解析后的脚本做静态检查（gobash -lint），报告常见的问题
+package lint
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"sort"
+	"strings"
+)
+
+// 规则编号
+const (
+	RuleUnquotedExpansion    = "GB001" // 没有引用的变量展开
+	RuleUselessCat           = "GB002" // cat file | cmd 中多余的 cat
+	RuleUnquotedTest         = "GB003" // [ 或 test 中没有引用的展开
+	RuleUnquotedSubstitution = "GB004" // 没有引用的命令替换
+	RuleUndefinedVariable    = "GB005" // 使用了脚本中没有赋值的变量
+)
+
+// Warning 一条检查结果
+type Warning struct {
+	Rule    string // 规则编号，如 GB001
+	Line    int    // 所在的行
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%d: %s: %s", w.Line, w.Rule, w.Message)
+}
+
+// Check 检查程序，返回按行号排序的警告
+func Check(program *parser.Program) []Warning {
+	l := &linter{assigned: make(map[string]bool), reported: make(map[string]bool)}
+	for i, stmt := range program.Statements {
+		line := 0
+		if i < len(program.Lines) {
+			line = program.Lines[i]
+		}
+		l.statement(stmt, line)
+	}
+	l.undefined()
+	sort.SliceStable(l.warnings, func(i, j int) bool { return l.warnings[i].Line < l.warnings[j].Line })
+	return l.warnings
+}
+
+// reference 变量的一次使用
+type reference struct {
+	name string
+	line int
+}
+
+// linter 检查的状态：规则 GB001-GB004 在遍历时直接报告，GB005 在遍历完成、知道所有赋值之后报告
+type linter struct {
+	warnings   []Warning
+	assigned   map[string]bool // 脚本中赋值过的变量
+	references []reference     // 按出现顺序使用的变量
+	reported   map[string]bool // 已经报告过未定义的变量
+}
+
+func (l *linter) warn(rule string, line int, format string, args ...any) {
+	l.warnings = append(l.warnings, Warning{Rule: rule, Line: line, Message: i18n.Sprintf(format, args...)})
+}
+
+// statement 检查语句及其中嵌套的语句，line 是外层语句所在的行（语句本身没有记录位置时使用）
+func (l *linter) statement(stmt parser.Statement, line int) {
+	if pos := parser.StatementPos(stmt); pos.IsValid() {
+		line = pos.Line
+	}
+	block := func(b *parser.BlockStatement) {
+		if b != nil {
+			for _, s := range b.Statements {
+				l.statement(s, line)
+			}
+		}
+	}
+	switch s := stmt.(type) {
+	case *parser.CommandStatement:
+		l.command(s, line)
+	case *parser.IfStatement:
+		block(s.Condition)
+		block(s.Consequence)
+		for _, elif := range s.Elif {
+			block(elif.Condition)
+			block(elif.Consequence)
+		}
+		block(s.Alternative)
+		l.redirects(s.Redirects, line)
+	case *parser.ForStatement:
+		l.assigned[s.Variable] = true
+		// for 的单词列表通常就是要分割成多个单词，只检查其中使用的变量
+		for _, word := range s.In {
+			l.expression(word, line, false)
+		}
+		block(s.Body)
+		l.redirects(s.Redirects, line)
+	case *parser.WhileStatement:
+		block(s.Condition)
+		block(s.Body)
+		l.redirects(s.Redirects, line)
+	case *parser.CaseStatement:
+		l.expression(s.Value, line, false)
+		for _, clause := range s.Cases {
+			block(clause.Body)
+		}
+		l.redirects(s.Redirects, line)
+	case *parser.FunctionStatement:
+		block(s.Body)
+	case *parser.BlockStatement:
+		block(s)
+	case *parser.SubshellCommand:
+		block(s.Body)
+		l.redirects(s.Redirects, line)
+	case *parser.GroupCommand:
+		block(s.Body)
+		l.redirects(s.Redirects, line)
+	case *parser.ArithmeticCommand:
+		l.arithmetic(s.Expression)
+		l.redirects(s.Redirects, line)
+	case *parser.ArrayAssignmentStatement:
+		l.assigned[s.Name] = true
+		for _, value := range s.Values {
+			l.expression(value, line, false)
+		}
+		for _, value := range s.IndexedValues {
+			l.expression(value, line, false)
+		}
+	case *parser.PipelineStatement:
+		l.uselessCat(s, line)
+		for _, cmd := range s.Commands {
+			l.statement(cmd, line)
+		}
+	case *parser.CommandChain:
+		l.statement(s.Left, line)
+		l.statement(s.Right, line)
+	case *parser.NegatedStatement:
+		l.statement(s.Statement, line)
+	case *parser.TimedStatement:
+		l.statement(s.Statement, line)
+	case *parser.BackgroundStatement:
+		l.statement(s.Statement, line)
+	case *parser.CoprocStatement:
+		name := s.Name
+		if name == "" {
+			name = "COPROC"
+		}
+		l.assigned[name] = true
+		l.statement(s.Statement, line)
+	}
+}
+
+// condExpression 检查 [[ ]] 的条件表达式中的单词
+func (l *linter) condExpression(cond parser.CondExpression, line int) {
+	switch c := cond.(type) {
+	case *parser.CondBinary:
+		l.condExpression(c.Left, line)
+		l.condExpression(c.Right, line)
+	case *parser.CondNot:
+		l.condExpression(c.Expr, line)
+	case *parser.CondParen:
+		l.condExpression(c.Expr, line)
+	case *parser.CondUnary:
+		l.expression(c.Operand, line, false)
+	case *parser.CondCompare:
+		l.expression(c.Left, line, false)
+		l.expression(c.Right, line, false)
+	case *parser.CondWord:
+		l.expression(c.Word, line, false)
+	}
+}
+
+// command 检查简单命令
+func (l *linter) command(cmd *parser.CommandStatement, line int) {
+	name := ""
+	words := cmd.Args
+	for _, assign := range cmd.Assignments {
+		l.expression(assign, line, false)
+	}
+	if cmd.Command != nil {
+		name = literal(cmd.Command)
+		l.expression(cmd.Command, line, false)
+	}
+
+	l.commandAssignments(name, words)
+	switch name {
+	case "[[":
+		// [[ ]] 中的展开不做单词分割
+		l.condExpression(cmd.Cond, line)
+	case "[", "test":
+		for _, word := range words {
+			if expansion := unquotedExpansion(word); expansion != "" {
+				l.warn(RuleUnquotedTest, line, "没有引用的 %s 在 %s 中为空或含有空白时会导致错误，应该用双引号括起来或改用 [[ ]]", expansion, name)
+			}
+			l.expression(word, line, false)
+		}
+	case "local", "declare", "typeset", "export", "readonly":
+		// 这些命令参数中的赋值和普通赋值一样，值不做单词分割
+		for _, word := range words {
+			l.expression(word, line, !isAssignmentArg(word))
+		}
+	default:
+		for _, word := range words {
+			l.expression(word, line, true)
+		}
+	}
+	l.redirects(cmd.Redirects, line)
+}
+
+// isAssignmentArg 检查 local、export 等命令的参数是否是 name=value 形式的赋值
+func isAssignmentArg(word parser.Expression) bool {
+	if _, ok := word.(*parser.AssignmentWord); ok {
+		return true
+	}
+	// local v=$1 解析为 v、=、$1 几个片段，把开头的文本片段连起来检查
+	prefix := literal(word)
+	if concat, ok := word.(*parser.ConcatExpression); ok {
+		prefix = ""
+		for _, part := range concat.Parts {
+			text := literal(part)
+			if text == "" {
+				break
+			}
+			prefix += text
+		}
+	}
+	n := nameLength(prefix)
+	return n > 0 && n < len(prefix) && prefix[n] == '='
+}
+
+// commandAssignments 记录内置命令 read、local、export 等赋值的变量
+func (l *linter) commandAssignments(name string, args []parser.Expression) {
+	switch name {
+	case "local", "declare", "typeset", "export", "readonly", "let":
+		for _, arg := range args {
+			if word, ok := arg.(*parser.AssignmentWord); ok {
+				l.assigned[word.Name] = true
+				continue
+			}
+			word := literal(arg)
+			if concat, ok := arg.(*parser.ConcatExpression); ok && len(concat.Parts) > 0 {
+				// local y=2 解析为 y、=、2 几个片段，变量名在开头的片段中
+				word = literal(concat.Parts[0])
+			}
+			if word == "" || strings.HasPrefix(word, "-") {
+				continue
+			}
+			if n := nameLength(word); n > 0 {
+				l.assigned[word[:n]] = true
+			}
+		}
+	case "read", "mapfile", "readarray", "printf", "getopts":
+		valueOptions := map[string]string{
+			"read":      "pdntui",
+			"mapfile":   "dnOstuC",
+			"readarray": "dnOstuC",
+		}[name]
+		var names []string
+		for i := 0; i < len(args); i++ {
+			word := literal(args[i])
+			switch {
+			case name == "printf":
+				// printf -v 变量 格式
+				if word == "-v" && i+1 < len(args) {
+					names = append(names, literal(args[i+1]))
+				}
+				i = len(args)
+			case name == "getopts":
+				// getopts 选项字符串 变量
+				if i == 1 {
+					names = append(names, word)
+				}
+			case strings.HasPrefix(word, "-") && len(word) > 1:
+				if word == "-a" && i+1 < len(args) {
+					names = append(names, literal(args[i+1]))
+					i++
+				} else if strings.ContainsAny(word[len(word)-1:], valueOptions) {
+					i++
+				}
+			default:
+				names = append(names, word)
+			}
+		}
+		if name == "read" && len(names) == 0 {
+			names = append(names, "REPLY")
+		}
+		if (name == "mapfile" || name == "readarray") && len(names) == 0 {
+			names = append(names, "MAPFILE")
+		}
+		for _, n := range names {
+			l.assigned[n] = true
+		}
+	}
+}
+
+// redirects 检查重定向的目标和 here-document 中使用的变量
+func (l *linter) redirects(redirects []*parser.Redirect, line int) {
+	for _, redirect := range redirects {
+		if redirect.HereDoc != nil {
+			if !redirect.HereDoc.Quoted {
+				l.text(redirect.HereDoc.Content, line)
+			}
+			continue
+		}
+		// here-string 的展开不做单词分割，复制文件描述符的目标是数字
+		split := redirect.Type != parser.REDIRECT_HERESTRING && redirect.Type != parser.REDIRECT_DUP_IN && redirect.Type != parser.REDIRECT_DUP_OUT
+		l.expression(redirect.Target, line, split)
+	}
+}
+
+// expression 检查单词，split 表示这个位置的展开会进行单词分割（GB001、GB004）
+func (l *linter) expression(expr parser.Expression, line int, split bool) {
+	switch e := expr.(type) {
+	case *parser.Variable:
+		l.reference(e.Name, line)
+		if split && splittable(e.Name) {
+			l.warn(RuleUnquotedExpansion, line, "没有引用的 %s 会进行单词分割和路径名展开，应该用双引号括起来", e.String())
+		}
+	case *parser.ParamExpandExpression:
+		l.paramExpand(e, line)
+		if split && splittable(e.VarName) && !strings.HasPrefix(e.VarName, "#") {
+			l.warn(RuleUnquotedExpansion, line, "没有引用的 %s 会进行单词分割和路径名展开，应该用双引号括起来", e.String())
+		}
+	case *parser.CommandSubstitution:
+		l.substitution(e.Command, line)
+		if split {
+			l.warn(RuleUnquotedSubstitution, line, "没有引用的命令替换 %s 会进行单词分割和路径名展开，应该用双引号括起来", abbreviate(e.String()))
+		}
+	case *parser.ProcessSubstitution:
+		l.substitution(e.Command, line)
+	case *parser.StringLiteral:
+		if e.IsQuote {
+			l.text(e.Value, line)
+		}
+	case *parser.ConcatExpression:
+		for _, part := range e.Parts {
+			l.expression(part, line, split)
+		}
+	case *parser.AssignmentWord:
+		l.assigned[e.Name] = true
+		if e.Value != nil {
+			l.expression(e.Value, line, false)
+		}
+	}
+}
+
+// paramExpand 记录 ${...} 中使用和赋值的变量
+func (l *linter) paramExpand(e *parser.ParamExpandExpression, line int) {
+	name := strings.TrimLeft(e.VarName, "#!")
+	name, _, _ = strings.Cut(name, "[")
+	switch e.Op {
+	case ":=", "=":
+		l.assigned[name] = true
+	case ":-", "-", ":?", "?", ":+", "+":
+		// 有默认值或检查了是否设置，不算使用未定义的变量
+	default:
+		l.reference(name, line)
+	}
+}
+
+// substitution 检查命令替换、进程替换中的命令，警告的行号是外层命令所在的行
+func (l *linter) substitution(command string, line int) {
+	p := parser.New(lexer.New(command))
+	program := p.ParseProgram()
+	if len(p.AllErrors()) > 0 {
+		return
+	}
+	warnings, references := len(l.warnings), len(l.references)
+	for _, stmt := range program.Statements {
+		l.statement(stmt, line)
+	}
+	// 命令替换中的语句的位置是相对于替换的内容的，统一改成外层命令所在的行
+	for i := warnings; i < len(l.warnings); i++ {
+		l.warnings[i].Line = line
+	}
+	for i := references; i < len(l.references); i++ {
+		l.references[i].line = line
+	}
+}
+
+// text 记录双引号字符串和 here-document 中使用的变量
+func (l *linter) text(s string, line int) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			continue
+		case '$':
+		default:
+			continue
+		}
+		if i+1 >= len(s) {
+			break
+		}
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				break
+			}
+			inner := s[i+2 : i+end]
+			name := strings.TrimLeft(inner, "#!")
+			n := nameLength(name)
+			if n == 0 {
+				continue
+			}
+			switch op := name[n:]; {
+			case strings.HasPrefix(op, ":="), strings.HasPrefix(op, "="):
+				l.assigned[name[:n]] = true
+			case strings.HasPrefix(op, ":-"), strings.HasPrefix(op, "-"), strings.HasPrefix(op, ":?"), strings.HasPrefix(op, "?"),
+				strings.HasPrefix(op, ":+"), strings.HasPrefix(op, "+"):
+			default:
+				l.reference(name[:n], line)
+			}
+			i += end
+			continue
+		}
+		if n := nameLength(s[i+1:]); n > 0 {
+			l.reference(s[i+1:i+1+n], line)
+			i += n
+		}
+	}
+}
+
+// arithmetic 记录算术命令中赋值的变量，如 ((i++))、((x = 1))
+func (l *linter) arithmetic(expr string) {
+	for i := 0; i < len(expr); {
+		n := nameLength(expr[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		name := expr[i : i+n]
+		rest := strings.TrimLeft(expr[i+n:], " \t")
+		before := strings.TrimRight(expr[:i], " \t")
+		if strings.HasPrefix(rest, "++") || strings.HasPrefix(rest, "--") ||
+			strings.HasSuffix(before, "++") || strings.HasSuffix(before, "--") ||
+			(strings.HasPrefix(strings.TrimLeft(rest, "+-*/%&|^<>"), "=") && !strings.HasPrefix(rest, "==")) {
+			l.assigned[name] = true
+		}
+		i += n
+	}
+}
+
+// uselessCat 检查 cat file | cmd：cat 只读取一个文件时可以改为 cmd < file
+func (l *linter) uselessCat(pipeline *parser.PipelineStatement, line int) {
+	if len(pipeline.Commands) < 2 {
+		return
+	}
+	cat, ok := pipeline.Commands[0].(*parser.CommandStatement)
+	if !ok || literal(cat.Command) != "cat" || len(cat.Args) != 1 || len(cat.Redirects) > 0 {
+		return
+	}
+	if file := cat.Args[0].String(); !strings.HasPrefix(file, "-") {
+		if pos := cat.Pos; pos.IsValid() {
+			line = pos.Line
+		}
+		l.warn(RuleUselessCat, line, "多余的 cat，可以把 cat %s | 命令 改为 命令 < %s", file, file)
+	}
+}
+
+// reference 记录使用了变量 name
+func (l *linter) reference(name string, line int) {
+	if nameLength(name) == len(name) && name != "" {
+		l.references = append(l.references, reference{name: name, line: line})
+	}
+}
+
+// undefined 报告使用了但是在脚本中没有赋值的变量（每个变量只报告第一次使用）
+// 全大写的变量通常是环境变量（如 HOME、PATH），不报告
+func (l *linter) undefined() {
+	for _, ref := range l.references {
+		if l.assigned[ref.name] || l.reported[ref.name] || strings.ToUpper(ref.name) == ref.name {
+			continue
+		}
+		l.reported[ref.name] = true
+		l.warn(RuleUndefinedVariable, ref.line, "变量 %s 在脚本中没有赋值", ref.name)
+	}
+}
+
+// unquotedExpansion 返回单词中没有引用、会进行单词分割的第一个展开，没有时返回空字符串
+func unquotedExpansion(expr parser.Expression) string {
+	switch e := expr.(type) {
+	case *parser.Variable:
+		if splittable(e.Name) {
+			return e.String()
+		}
+	case *parser.ParamExpandExpression:
+		if splittable(e.VarName) && !strings.HasPrefix(e.VarName, "#") {
+			return e.String()
+		}
+	case *parser.CommandSubstitution:
+		return abbreviate(e.String())
+	case *parser.ConcatExpression:
+		for _, part := range e.Parts {
+			if expansion := unquotedExpansion(part); expansion != "" {
+				return expansion
+			}
+		}
+	}
+	return ""
+}
+
+// splittable 检查变量的值是否可能含有空白：$#、$?、$$、$!、$- 的值不会
+func splittable(name string) bool {
+	switch name {
+	case "#", "?", "$", "!", "-":
+		return false
+	}
+	return true
+}
+
+// literal 返回不含展开的单词的值，其他单词返回空字符串
+func literal(expr parser.Expression) string {
+	switch e := expr.(type) {
+	case *parser.Identifier:
+		return e.Value
+	case *parser.StringLiteral:
+		return e.Value
+	}
+	return ""
+}
+
+// nameLength 返回 s 开头的变量名的长度
+func nameLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
+
+// abbreviate 截断过长的单词，用于警告消息
+func abbreviate(s string) string {
+	if runes := []rune(s); len(runes) > 40 {
+		return string(runes[:37]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+package lint
+
+import (
+	"fmt"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"strings"
+	"testing"
+)
+
+// check 解析脚本并返回 "行号 规则" 形式的警告
+func check(t *testing.T, input string) []string {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.AllErrors(); len(errs) > 0 {
+		t.Fatalf("%q 解析失败: %v", input, errs)
+	}
+	var got []string
+	for _, w := range Check(program) {
+		got = append(got, fmt.Sprintf("%d %s", w.Line, w.Rule))
+	}
+	return got
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"引用的变量", "x=1\necho \"$x\" \"${x}\"\n", nil},
+		{"没有引用的变量", "x=1\necho $x ${x}/a\n", []string{"2 GB001", "2 GB001"}},
+		{"特殊参数", "echo $# $? $$\n", nil},
+		{"[[ 和赋值中不分割", "x=1\ny=$x\n[[ $x == 1 ]]\ncase $x in *) ;; esac\n", nil},
+		{"[ 中没有引用的变量", "x=1\nif [ $x == y ]; then true; fi\ntest -n \"$x\"\n", []string{"2 GB003"}},
+		{"多余的 cat", "cat a.txt | grep x\ncat -n a.txt | grep x\ncat a b | sort\n", []string{"1 GB002"}},
+		{"没有引用的命令替换", "echo $(date) \"$(date)\"\nd=$(date)\n", []string{"1 GB004"}},
+		{"命令替换中的命令", "x=1\nd=\"$(echo $x)\"\n", nil},
+		{"未定义的变量", "f() {\n  echo \"$later $name $HOME ${unset:-d} $typo\"\n}\nlater=1\nread -r name\n", []string{"2 GB005"}},
+		{"for、read 和算术命令赋值", "for i in 1 2; do echo \"$i\"; done\nread -p '?' a b\n((n++))\necho \"$a$b$n\"\n", nil},
+		{"local、declare 和 export 赋值", "f() { local y=2; echo \"$y\"; }\nexport X=1 Y\ndeclare n=v m=\"$X\"\necho \"$X $Y $n $m\"\n", nil},
+		{"local、declare 和 export 的赋值值不分割", "f() { local v=$1 w; declare -r d=$(date) e=${v}x; export E=$v; echo \"$w$d$e\"; }\nx=1\nexport $x\n", []string{"3 GB001"}},
+		{"here-document", "cat <<EOF\n$nope\nEOF\ncat <<'EOF'\n$quoted\nEOF\n", []string{"1 GB005"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := check(t, tt.input)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("警告 %v，期望 %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"gobash/internal/lexer"
+	"strings"
+)
 
 // Node AST节点接口
 type Node interface {
@@ -22,38 +26,28 @@ type Expression interface {
 // Program 程序根节点
 type Program struct {
 	Statements []Statement
+	// Lines 每条顶层语句起始的行号，与 Statements 一一对应
+	Lines []int
 }
 
 func (p *Program) String() string {
-	var out string
-	for _, s := range p.Statements {
-		out += s.String()
-	}
-	return out
+	return Format(p)
 }
 
 // CommandStatement 命令语句
 type CommandStatement struct {
-	Command     Expression
+	Assignments []*AssignmentWord // 命令名之前的变量赋值；有命令名时（VAR=value cmd）只在执行这条命令时有效
+	Command     Expression        // 命令名，只有赋值的命令（x=1 y=2）为 nil
 	Args        []Expression
 	Redirects   []*Redirect
 	Background  bool
-	Pipe        *CommandStatement
+	Pos         lexer.Position // 命令在输入中的位置
+	Cond        CondExpression // [[ ]] 命令的条件表达式（此时 Command 为 [[，没有参数）
 }
 
 func (cs *CommandStatement) statementNode() {}
 func (cs *CommandStatement) String() string {
-	var out string
-	if cs.Command != nil {
-		out += cs.Command.String()
-	}
-	for _, arg := range cs.Args {
-		out += " " + arg.String()
-	}
-	if cs.Pipe != nil {
-		out += " | " + cs.Pipe.String()
-	}
-	return out
+	return oneLine(cs)
 }
 
 // Redirect 重定向
@@ -84,49 +78,56 @@ type HereDocument struct {
 	Delimiter   string // 分隔符
 	Quoted      bool   // 分隔符是否带引号（带引号时不展开变量）
 	StripTabs   bool   // 是否剥离前导制表符（<<-）
-	Content     string // Here-document 内容（在执行时填充）
+	Content     string // Here-document 内容（在解析时由词法分析器读取）
 }
 
 // IfStatement if语句
 type IfStatement struct {
-	Condition   *CommandStatement
+	Condition   *BlockStatement // 条件命令列表，以最后一条命令的退出状态作为条件
 	Consequence *BlockStatement
 	Alternative *BlockStatement
 	Elif        []*ElifClause
+	Redirects   []*Redirect // 作用于整个 if 语句的重定向，如 if ...; fi 2> err.log
+	Pos         lexer.Position // if 关键字的位置
 }
 
 func (is *IfStatement) statementNode() {}
 func (is *IfStatement) String() string {
-	return "if statement"
+	return oneLine(is)
 }
 
 // ElifClause elif子句
 type ElifClause struct {
-	Condition   *CommandStatement
+	Condition   *BlockStatement
 	Consequence *BlockStatement
 }
 
 // ForStatement for循环
 type ForStatement struct {
-	Variable string
-	In       []Expression
-	Body     *BlockStatement
+	Variable  string
+	In        []Expression // in 之后的单词列表，没有 in 子句时为 nil（遍历位置参数）
+	Body      *BlockStatement
+	Redirects []*Redirect // 作用于整个循环的重定向
+	Pos       lexer.Position // for 关键字的位置
 }
 
 func (fs *ForStatement) statementNode() {}
 func (fs *ForStatement) String() string {
-	return "for statement"
+	return oneLine(fs)
 }
 
-// WhileStatement while循环
+// WhileStatement while 或 until 循环
 type WhileStatement struct {
-	Condition *CommandStatement
+	Condition *BlockStatement // 条件命令列表，以最后一条命令的退出状态作为条件
+	Until     bool            // until 循环：条件不成立时执行循环体
 	Body      *BlockStatement
+	Redirects []*Redirect // 作用于整个循环的重定向，如 while read l; do ...; done < file
+	Pos       lexer.Position // while 或 until 关键字的位置
 }
 
 func (ws *WhileStatement) statementNode() {}
 func (ws *WhileStatement) String() string {
-	return "while statement"
+	return oneLine(ws)
 }
 
 // BlockStatement 代码块
@@ -136,48 +137,51 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode() {}
 func (bs *BlockStatement) String() string {
-	var out string
-	for _, s := range bs.Statements {
-		out += s.String() + "\n"
-	}
-	return out
+	return oneLine(bs)
 }
 
 // FunctionStatement 函数定义
 type FunctionStatement struct {
 	Name string
 	Body *BlockStatement
+	// Source 函数体的源代码（如 "{ echo hi; }"），export -f 用它把函数传给子进程
+	Source string
+	Pos    lexer.Position // 函数定义的位置
 }
 
 func (fs *FunctionStatement) statementNode() {}
 func (fs *FunctionStatement) String() string {
-	return "function " + fs.Name
+	return oneLine(fs)
 }
 
 // ArrayAssignmentStatement 数组赋值语句
 // 例如：arr=(1 2 3) 或 arr=([0]=a [1]=b [2]=c)
 type ArrayAssignmentStatement struct {
 	Name   string
+	Append bool // arr+=(...)：在数组末尾追加元素（带索引的元素赋给对应的下标），不清空原来的元素
 	Values []Expression
 	// IndexedValues 存储带索引的数组元素 [index]=value
 	// 如果 IndexedValues 不为空，使用它；否则使用 Values
 	IndexedValues map[string]Expression // key 是索引（字符串形式，支持数字和字符串键）
+	Pos           lexer.Position        // 赋值语句的位置
 }
 
 func (as *ArrayAssignmentStatement) statementNode() {}
 func (as *ArrayAssignmentStatement) String() string {
-	return "array assignment: " + as.Name
+	return arrayAssignment(as)
 }
 
 // CaseStatement case语句
 type CaseStatement struct {
-	Value  Expression
-	Cases  []*CaseClause
+	Value     Expression
+	Cases     []*CaseClause
+	Redirects []*Redirect // 作用于整个 case 语句的重定向
+	Pos       lexer.Position // case 关键字的位置
 }
 
 func (cs *CaseStatement) statementNode() {}
 func (cs *CaseStatement) String() string {
-	return "case statement"
+	return oneLine(cs)
 }
 
 // CaseClause case子句
@@ -233,7 +237,16 @@ func (sl *StringLiteral) String() string {
 	if sl.IsQuote {
 		return "\"" + sl.Value + "\""
 	}
-	return "'" + sl.Value + "'"
+	if sl.Value != "" && !strings.ContainsAny(sl.Value, " \t\n'\"\\$`|&;<>()*?[]{}~#!=") {
+		// 不需要引号的单词原样输出
+		return sl.Value
+	}
+	if strings.Contains(sl.Value, "'") && !strings.ContainsAny(sl.Value, "\"\\$`") {
+		// 含单引号时，没有在双引号中有特殊含义的字符的单词用双引号括起来
+		return "\"" + sl.Value + "\""
+	}
+	// 单引号中的单引号先结束引用、转义后再重新开始引用
+	return "'" + strings.ReplaceAll(sl.Value, "'", `'\''`) + "'"
 }
 
 // Variable 变量
@@ -298,26 +311,139 @@ func (pe *ParamExpandExpression) String() string {
 	return fmt.Sprintf("${%s}", pe.VarName)
 }
 
+// ConcatExpression 拼接表达式
+// 由相邻（中间没有空白）的多个片段组成的单词，例如：foo$bar、"a"'b'、--opt=$val
+type ConcatExpression struct {
+	Parts []Expression
+}
+
+func (ce *ConcatExpression) expressionNode() {}
+func (ce *ConcatExpression) String() string {
+	var out string
+	for _, part := range ce.Parts {
+		out += part.String()
+	}
+	return out
+}
+
+// AssignmentWord 变量赋值 VAR=value
+// Value 是 = 之后的单词，与命令参数一样由带引号和不带引号的片段组成（如 "a 'b' c"、'a'"b"$x），没有值时为 nil
+type AssignmentWord struct {
+	Name   string
+	Value  Expression
+	Append bool // VAR+=value：把值追加到原来的值之后
+}
+
+func (aw *AssignmentWord) expressionNode() {}
+func (aw *AssignmentWord) String() string {
+	op := "="
+	if aw.Append {
+		op = "+="
+	}
+	if aw.Value == nil {
+		return aw.Name + op
+	}
+	return aw.Name + op + aw.Value.String()
+}
+
 // SubshellCommand 子shell 命令
 // 例如：(command)
 type SubshellCommand struct {
-	Body *BlockStatement
+	Body      *BlockStatement
+	Redirects []*Redirect // 作用于子shell的重定向，如 (cmd1; cmd2) > out
+	Pos       lexer.Position // 左括号的位置
 }
 
 func (sc *SubshellCommand) statementNode() {}
 func (sc *SubshellCommand) String() string {
-	return "(subshell)"
+	return oneLine(sc)
+}
+
+// ArithmeticCommand 算术命令
+// 例如：((i++)), ((x > 0)) > /dev/null
+type ArithmeticCommand struct {
+	Expression string
+	Redirects  []*Redirect
+	Pos        lexer.Position // (( 的位置
+}
+
+func (ac *ArithmeticCommand) statementNode() {}
+func (ac *ArithmeticCommand) String() string {
+	return oneLine(ac)
 }
 
 // GroupCommand 命令组
 // 例如：{ command; }
 type GroupCommand struct {
-	Body *BlockStatement
+	Body      *BlockStatement
+	Redirects []*Redirect // 作用于命令组的重定向，如 { cmd1; cmd2; } > out
+	Pos       lexer.Position // 左大括号的位置
 }
 
 func (gc *GroupCommand) statementNode() {}
 func (gc *GroupCommand) String() string {
-	return "{group}"
+	return oneLine(gc)
+}
+
+// NegatedStatement 取反的管道
+// 例如：! grep -q foo file
+type NegatedStatement struct {
+	Statement Statement
+}
+
+func (ns *NegatedStatement) statementNode() {}
+func (ns *NegatedStatement) String() string {
+	return oneLine(ns)
+}
+
+// TimedStatement 测量执行时间的管道
+// 例如：time sleep 1, time -p make | tail
+type TimedStatement struct {
+	Statement Statement // 被测量的管道，只有 time 时为 nil
+	Posix     bool      // -p：使用 POSIX 格式输出，忽略 TIMEFORMAT
+}
+
+func (ts *TimedStatement) statementNode() {}
+func (ts *TimedStatement) String() string {
+	return oneLine(ts)
+}
+
+// BackgroundStatement 后台执行的语句（简单命令之外的管道、命令链和复合命令）
+// 例如：cmd1 | cmd2 &, for i in 1 2; do echo $i; done &, ( cd /tmp; make ) &
+// 后台执行的简单命令用 CommandStatement.Background 表示
+type BackgroundStatement struct {
+	Statement Statement
+}
+
+func (bs *BackgroundStatement) statementNode() {}
+func (bs *BackgroundStatement) String() string {
+	return oneLine(bs)
+}
+
+// CoprocStatement 协进程：在后台执行命令，命令的标准输入和标准输出连接到 shell 的两个文件描述符
+// 例如：coproc bc -l, coproc WORKER { while read x; do echo $((x * 2)); done; }
+type CoprocStatement struct {
+	Name      string         // 协进程的名称（只有复合命令可以指定），为空表示默认的 COPROC
+	Statement Statement      // 协进程执行的命令
+	Pos       lexer.Position // coproc 的位置
+}
+
+func (cs *CoprocStatement) statementNode() {}
+func (cs *CoprocStatement) String() string {
+	return oneLine(cs)
+}
+
+// PipelineStatement 管道
+// 例如：cmd1 | cmd2, for i in 1 2; do echo $i; done | sort
+// 管道中的每个命令可以是简单命令或复合命令
+type PipelineStatement struct {
+	Commands []Statement
+	Stderr   []bool // Stderr[i] 为 true 表示 Commands[i] 与下一个命令之间是 |&，标准错误也写入管道
+}
+
+func (ps *PipelineStatement) statementNode() {}
+func (ps *PipelineStatement) String() string {
+	return oneLine(ps)
 }
 
 // CommandChain 命令链
@@ -330,6 +456,125 @@ type CommandChain struct {
 
 func (cc *CommandChain) statementNode() {}
 func (cc *CommandChain) String() string {
-	return fmt.Sprintf("%s %s %s", cc.Left.String(), cc.Operator, cc.Right.String())
+	return oneLine(cc)
+}
+
+
+// CondExpression [[ ]] 中的条件表达式
+type CondExpression interface {
+	Node
+	condNode()
 }
 
+// CondBinary 用 && 或 || 连接的两个条件
+// 例如：[[ -f a && -f b ]]
+type CondBinary struct {
+	Op    string // && 或 ||
+	Left  CondExpression
+	Right CondExpression
+}
+
+func (cb *CondBinary) condNode() {}
+func (cb *CondBinary) String() string {
+	return cb.Left.String() + " " + cb.Op + " " + cb.Right.String()
+}
+
+// CondNot 取反的条件
+// 例如：[[ ! -d dir ]]
+type CondNot struct {
+	Expr CondExpression
+}
+
+func (cn *CondNot) condNode() {}
+func (cn *CondNot) String() string {
+	return "! " + cn.Expr.String()
+}
+
+// CondParen 括号中的条件
+// 例如：[[ ( a || b ) && c ]]
+type CondParen struct {
+	Expr CondExpression
+}
+
+func (cp *CondParen) condNode() {}
+func (cp *CondParen) String() string {
+	return "( " + cp.Expr.String() + " )"
+}
+
+// CondUnary 一元测试
+// 例如：[[ -f file ]]、[[ -n $x ]]、[[ -v name ]]
+type CondUnary struct {
+	Op      string
+	Operand Expression
+}
+
+func (cu *CondUnary) condNode() {}
+func (cu *CondUnary) String() string {
+	return cu.Op + " " + cu.Operand.String()
+}
+
+// CondCompare 二元测试
+// 例如：[[ $x == a* ]]（右边是模式）、[[ $x =~ ^[0-9]+$ ]]（右边是正则表达式）、[[ a < b ]]、[[ $n -lt 3 ]]
+type CondCompare struct {
+	Op    string
+	Left  Expression
+	Right Expression
+}
+
+func (cc *CondCompare) condNode() {}
+func (cc *CondCompare) String() string {
+	return cc.Left.String() + " " + cc.Op + " " + cc.Right.String()
+}
+
+// CondWord 单独的单词，值不为空时为真
+// 例如：[[ $x ]]
+type CondWord struct {
+	Word Expression
+}
+
+func (cw *CondWord) condNode() {}
+func (cw *CondWord) String() string {
+	return cw.Word.String()
+}
+
+// StatementPos 返回语句在输入中的起始位置
+// 管道、命令链、取反、time 和后台语句的位置是其中第一个命令的位置；没有记录位置的语句返回无效位置
+func StatementPos(stmt Statement) lexer.Position {
+	switch s := stmt.(type) {
+	case *CommandStatement:
+		return s.Pos
+	case *IfStatement:
+		return s.Pos
+	case *ForStatement:
+		return s.Pos
+	case *WhileStatement:
+		return s.Pos
+	case *FunctionStatement:
+		return s.Pos
+	case *ArrayAssignmentStatement:
+		return s.Pos
+	case *CaseStatement:
+		return s.Pos
+	case *SubshellCommand:
+		return s.Pos
+	case *ArithmeticCommand:
+		return s.Pos
+	case *GroupCommand:
+		return s.Pos
+	case *CoprocStatement:
+		return s.Pos
+	case *PipelineStatement:
+		if len(s.Commands) > 0 {
+			return StatementPos(s.Commands[0])
+		}
+	case *CommandChain:
+		return StatementPos(s.Left)
+	case *NegatedStatement:
+		return StatementPos(s.Statement)
+	case *TimedStatement:
+		return StatementPos(s.Statement)
+	case *BackgroundStatement:
+		return StatementPos(s.Statement)
+	}
+	return lexer.Position{}
+}
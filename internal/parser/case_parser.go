@@ -1,211 +1,129 @@
 package parser
 
 import (
+	"strings"
+	"gobash/internal/i18n"
 	"gobash/internal/lexer"
 )
 
 // parseCaseStatement 解析case语句
+// case 单词 in [(] 模式 [| 模式]... ) 命令列表 ;; ... esac
 func (p *Parser) parseCaseStatement() *CaseStatement {
-	stmt := &CaseStatement{}
-	
+	caseToken := p.curToken
+	stmt := &CaseStatement{Pos: p.curPos()}
+
 	p.nextToken() // 跳过 case
-	
+
 	// 解析case的值
-	stmt.Value = p.parseExpression()
-	
-	// 跳过 in
-	if p.peekToken.Type == lexer.IN {
-		p.nextToken() // 跳过 in
+	if !isWordToken(p.curToken.Type) {
+		p.unexpectedToken(i18n.T("单词"))
+		return stmt
 	}
-	p.nextToken()
-	
-	// 解析case子句
-	for p.curToken.Type != lexer.ESAC && p.curToken.Type != lexer.EOF {
-		// 跳过换行和空白
-		for p.curToken.Type == lexer.NEWLINE || p.curToken.Type == lexer.WHITESPACE {
-			p.nextToken()
+	stmt.Value = p.parseWord()
+
+	p.skipNewlines()
+	if p.curToken.Type != lexer.IN {
+		if p.curToken.Type == lexer.EOF {
+			p.addUnclosedError(ErrorTypeUnclosedControlFlow, caseToken, "esac")
+		} else {
+			p.unexpectedToken("in")
 		}
-		
-		// 检查是否到达结束
+		return stmt
+	}
+	p.nextToken() // 跳过 in
+
+	// 解析case子句
+	for {
+		p.skipNewlines()
 		if p.curToken.Type == lexer.ESAC || p.curToken.Type == lexer.EOF {
 			break
 		}
-		
-		patterns := []string{}
-		patternStart := true
-		for p.curToken.Type != lexer.RPAREN && 
-			p.curToken.Type != lexer.ESAC &&
-			p.curToken.Type != lexer.EOF {
-			
-			if p.curToken.Type == lexer.IDENTIFIER || 
-			   p.curToken.Type == lexer.STRING ||
-			   p.curToken.Type == lexer.STRING_SINGLE ||
-			   p.curToken.Type == lexer.STRING_DOUBLE {
-				pattern := p.curToken.Literal
-				// 移除引号（如果有）
-				if (p.curToken.Type == lexer.STRING_SINGLE || p.curToken.Type == lexer.STRING_DOUBLE) && len(pattern) >= 2 {
-					if (pattern[0] == '\'' && pattern[len(pattern)-1] == '\'') ||
-					   (pattern[0] == '"' && pattern[len(pattern)-1] == '"') {
-						pattern = pattern[1 : len(pattern)-1]
-					}
-				}
-				patterns = append(patterns, pattern)
-				patternStart = false
-			} else if p.curToken.Type == lexer.PIPE {
-				// 模式分隔符 |
-				p.nextToken()
-				continue
-			} else if p.curToken.Type == lexer.RPAREN {
-				break
-			} else if !patternStart {
-				// 如果已经开始解析模式但遇到其他token，可能模式已结束
-				break
-			}
-			p.nextToken()
-		}
-		
-		// 跳过 )
-		if p.curToken.Type == lexer.RPAREN {
+
+		// 模式前可以有可选的 (
+		if p.curToken.Type == lexer.LPAREN {
 			p.nextToken()
 		}
-		
-		// 解析case体（直到遇到 ;; 或 ;& 或 ;;&）
-		body := &BlockStatement{Statements: []Statement{}}
-		foundDoubleSemicolon := false
-		for p.curToken.Type != lexer.ESAC && p.curToken.Type != lexer.EOF {
-			// 跳过空白和换行
-			for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-				p.nextToken()
-			}
-			
-			// 检查是否到达结束
-			if p.curToken.Type == lexer.ESAC || p.curToken.Type == lexer.EOF {
-				break
-			}
-			
-			// 检查是否是结束符 ;; 或 ;& 或 ;;&
-			if p.curToken.Type == lexer.SEMI_SEMI {
-				p.nextToken() // 跳过 ;;
-				foundDoubleSemicolon = true
-				// 跳过 ;; 后的空白和换行
-				for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-					p.nextToken()
-				}
-				break
-			} else if p.curToken.Type == lexer.SEMI_AND {
-				// ;& 表示 fallthrough
-				p.nextToken() // 跳过 ;&
-				foundDoubleSemicolon = true
-				// 跳过 ;& 后的空白和换行
-				for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-					p.nextToken()
-				}
-				break
-			} else if p.curToken.Type == lexer.SEMI_SEMI_AND {
-				// ;;& 表示测试下一个模式
-				p.nextToken() // 跳过 ;;&
-				foundDoubleSemicolon = true
-				// 跳过 ;;& 后的空白和换行
-				for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-					p.nextToken()
-				}
-				break
-			} else if p.curToken.Type == lexer.SEMICOLON && p.peekToken.Type == lexer.SEMICOLON {
-				// 兼容旧的 ;; 格式（两个独立的 SEMICOLON token）
-				p.nextToken() // 跳过第一个 ;
-				p.nextToken() // 跳过第二个 ;
-				foundDoubleSemicolon = true
-				// 跳过 ;; 后的空白和换行
-				for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-					p.nextToken()
-				}
-				break
+
+		patterns := []string{}
+		for {
+			if !isWordToken(p.curToken.Type) {
+				p.unexpectedToken(i18n.T("模式"))
+				p.recoverToCaseEnd()
+				return stmt
 			}
-			
-			// 检查是否是下一个case模式
-			if (p.curToken.Type == lexer.IDENTIFIER || 
-				p.curToken.Type == lexer.STRING ||
-				p.curToken.Type == lexer.STRING_SINGLE ||
-				p.curToken.Type == lexer.STRING_DOUBLE) && 
-				p.peekToken.Type == lexer.RPAREN {
-				// 这是下一个case的模式，当前case体已结束
+			patterns = append(patterns, p.parseCasePattern())
+			if p.curToken.Type != lexer.PIPE {
 				break
 			}
-			
-			// 解析语句
-			stmt := p.parseStatement()
-			if stmt != nil {
-				body.Statements = append(body.Statements, stmt)
-				// parseStatement 后，如果 peekToken 是 NEWLINE，移动到 NEWLINE
-				// 因为 parseCommandStatement 在遇到 NEWLINE 时会停止，但不会消耗 NEWLINE
-				if p.peekToken.Type == lexer.NEWLINE {
-					p.nextToken()
-				}
-			}
+			p.nextToken() // 跳过模式分隔符 |
 		}
-		
-		if len(patterns) > 0 {
-			stmt.Cases = append(stmt.Cases, &CaseClause{
-				Patterns: patterns,
-				Body:     body,
-			})
+
+		if p.curToken.Type != lexer.RPAREN {
+			p.unexpectedToken(")")
+			p.recoverToCaseEnd()
+			return stmt
 		}
-		
-		// 如果找到 ;;，需要跳过 ;; 后的所有内容，直到遇到下一个case模式或esac
-		// 如果没有找到 ;;，说明遇到了下一个 case 模式或 esac，不需要跳过，直接继续循环
-		if foundDoubleSemicolon {
-			// 在 ;; 后，跳过所有内容，直到遇到下一个case模式或esac
-			// 注意：在 body 解析循环中已经跳过了 ;; 和空白/换行，所以这里 curToken 应该指向第一个非空白/换行 token
-			for p.curToken.Type != lexer.ESAC && p.curToken.Type != lexer.EOF {
-				// 跳过空白和换行
-				for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-					p.nextToken()
-				}
-				
-				// 检查是否到达结束
-				if p.curToken.Type == lexer.ESAC || p.curToken.Type == lexer.EOF {
-					break
-				}
-				
-				// 检查是否是下一个case模式（必须在跳过之前检查）
-				if (p.curToken.Type == lexer.IDENTIFIER || 
-					p.curToken.Type == lexer.STRING ||
-					p.curToken.Type == lexer.STRING_SINGLE ||
-					p.curToken.Type == lexer.STRING_DOUBLE) && 
-					p.peekToken.Type == lexer.RPAREN {
-					// 这是下一个case的模式，停止跳过，继续循环解析它
-					break
-				}
-				// 检查是否是单独的 * 模式
-				if p.curToken.Type == lexer.IDENTIFIER && 
-				   p.curToken.Literal == "*" && 
-				   p.peekToken.Type == lexer.RPAREN {
-					// 这是下一个case的模式，停止跳过，继续循环解析它
-					break
-				}
-				// 如果遇到esac，退出循环
-				if p.curToken.Type == lexer.ESAC {
-					break
-				}
-				// 直接跳过token，直到遇到下一个case模式或esac
-				// 不要使用 parseStatement，因为它可能在遇到 SEMICOLON 时返回 nil，导致死循环
-				p.nextToken()
-			}
-			
-			// 如果遇到esac，退出case解析循环
-			if p.curToken.Type == lexer.ESAC {
-				break
-			}
-			// 如果遇到下一个case模式，继续循环解析它（不要break，让循环继续）
+		p.nextToken() // 跳过 )
+
+		// 解析case体（直到遇到 ;; 或 ;& 或 ;;& 或 esac）
+		body := p.parseBlockStatement(lexer.SEMI_SEMI, lexer.SEMI_AND, lexer.SEMI_SEMI_AND, lexer.ESAC)
+		stmt.Cases = append(stmt.Cases, &CaseClause{
+			Patterns: patterns,
+			Body:     body,
+		})
+
+		if p.curToken.Type == lexer.SEMI_SEMI || p.curToken.Type == lexer.SEMI_AND || p.curToken.Type == lexer.SEMI_SEMI_AND {
+			p.nextToken() // 跳过 ;; 或 ;& 或 ;;&
 		}
 	}
-	
+
+	p.expectClosing(lexer.ESAC, "esac", caseToken, ErrorTypeUnclosedControlFlow)
+	return stmt
+}
+
+// parseCasePattern 解析一个case模式，模式可以由多个相邻片段组成（如 "a"*）
+func (p *Parser) parseCasePattern() string {
+	var pattern strings.Builder
+	for {
+		literal := p.curToken.Literal
+		switch p.curToken.Type {
+		case lexer.STRING_DOUBLE:
+			literal = escapePattern(lexer.UnescapeDoubleQuoted(literal))
+		case lexer.STRING_SINGLE, lexer.STRING_DOLLAR_SINGLE:
+			// 引号中和反斜杠转义的字符（词法分析器把 \* 作为单引号字符串）按字面意义匹配
+			literal = escapePattern(literal)
+		}
+		pattern.WriteString(literal)
+
+		adjacent := p.peekIsAdjacent() && isWordToken(p.peekToken.Type)
+		p.nextToken()
+		if !adjacent {
+			return pattern.String()
+		}
+	}
+}
+
+// escapePattern 在模式字符 *、?、[ 和 \ 前加上反斜杠，使它们按字面意义匹配
+func escapePattern(s string) string {
+	if !strings.ContainsAny(s, "*?[\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte("*?[\\", s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// recoverToCaseEnd 出错后跳过到 esac 之后
+func (p *Parser) recoverToCaseEnd() {
+	for p.curToken.Type != lexer.ESAC && p.curToken.Type != lexer.EOF {
+		p.nextToken()
+	}
 	if p.curToken.Type == lexer.ESAC {
-		p.nextToken() // 跳过 esac
-	} else if p.curToken.Type != lexer.EOF {
-		// 未闭合的 case 语句
-		p.addError(ErrorTypeUnclosedControlFlow, "case 语句未闭合，缺少 esac", p.curToken, "esac")
+		p.nextToken()
 	}
-	
-	return stmt
 }
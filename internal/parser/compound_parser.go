@@ -0,0 +1,261 @@
+package parser
+
+import (
+	"strconv"
+	"gobash/internal/i18n"
+	"gobash/internal/lexer"
+)
+
+// parseIfStatement 解析if语句
+// if 条件; then 命令列表; [elif 条件; then 命令列表;]... [else 命令列表;] fi
+func (p *Parser) parseIfStatement() *IfStatement {
+	ifToken := p.curToken
+	stmt := &IfStatement{Pos: p.curPos()}
+
+	p.nextToken() // 跳过 if
+	stmt.Condition = p.parseConditionList(lexer.THEN, lexer.FI)
+	if !p.expectClosing(lexer.THEN, "then", ifToken, ErrorTypeUnclosedControlFlow) {
+		p.skipCompound(lexer.FI)
+		return stmt
+	}
+	stmt.Consequence = p.parseBlockStatement(lexer.ELIF, lexer.ELSE, lexer.FI)
+
+	// 解析elif
+	for p.curToken.Type == lexer.ELIF {
+		p.nextToken() // 跳过 elif
+		condition := p.parseConditionList(lexer.THEN, lexer.FI)
+		if !p.expectClosing(lexer.THEN, "then", ifToken, ErrorTypeUnclosedControlFlow) {
+			p.skipCompound(lexer.FI)
+			return stmt
+		}
+		stmt.Elif = append(stmt.Elif, &ElifClause{
+			Condition:   condition,
+			Consequence: p.parseBlockStatement(lexer.ELIF, lexer.ELSE, lexer.FI),
+		})
+	}
+
+	// 解析else
+	if p.curToken.Type == lexer.ELSE {
+		p.nextToken() // 跳过 else
+		stmt.Alternative = p.parseBlockStatement(lexer.FI)
+	}
+
+	p.expectClosing(lexer.FI, "fi", ifToken, ErrorTypeUnclosedControlFlow)
+	return stmt
+}
+
+// parseConditionList 解析 if/elif/while/until 的条件命令列表，直到遇到终止关键字（then 或 do）
+// 缺少终止关键字时在复合命令的结束关键字（closer，fi 或 done）处停止，由调用者报告缺少的关键字
+func (p *Parser) parseConditionList(terminator, closer lexer.TokenType) *BlockStatement {
+	block := p.parseBlockStatement(terminator, closer)
+	if p.curToken.Type != terminator {
+		return nil
+	}
+	if len(block.Statements) == 0 {
+		p.unexpectedToken("")
+		return nil
+	}
+	return block
+}
+
+// parseForStatement 解析for循环
+// for 变量 [in 单词...]; do 命令列表; done
+func (p *Parser) parseForStatement() *ForStatement {
+	forToken := p.curToken
+	stmt := &ForStatement{Body: &BlockStatement{Statements: []Statement{}}, Pos: p.curPos()}
+
+	p.nextToken() // 跳过 for
+
+	if p.curToken.Type == lexer.LPAREN || p.curToken.Type == lexer.ARITHMETIC_COMMAND {
+		p.addError(ErrorTypeSyntax, i18n.T("暂不支持 C 风格的 for 循环"), forToken, "")
+		p.skipCompound(lexer.DONE)
+		return stmt
+	}
+	if p.curToken.Type != lexer.IDENTIFIER || !isValidName(p.curToken.Literal) {
+		p.unexpectedToken(i18n.T("变量名"))
+		p.skipCompound(lexer.DONE)
+		return stmt
+	}
+	stmt.Variable = p.curToken.Literal
+	p.nextToken()
+
+	p.skipNewlines()
+	if p.curToken.Type == lexer.IN {
+		p.nextToken() // 跳过 in
+		// 解析列表，in 之后没有单词时 In 是空列表（不执行循环体），与没有 in 子句（In 为 nil）区分
+		stmt.In = []Expression{}
+		for isWordToken(p.curToken.Type) {
+			stmt.In = append(stmt.In, p.parseWord())
+		}
+		if p.curToken.Type != lexer.SEMICOLON && p.curToken.Type != lexer.NEWLINE {
+			p.unexpectedToken("do")
+			p.skipCompound(lexer.DONE)
+			return stmt
+		}
+		p.nextToken()
+	} else if p.curToken.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	p.skipNewlines()
+	if !p.expectClosing(lexer.DO, "do", forToken, ErrorTypeUnclosedControlFlow) {
+		p.skipCompound(lexer.DONE)
+		return stmt
+	}
+	stmt.Body = p.parseBlockStatement(lexer.DONE)
+	p.expectClosing(lexer.DONE, "done", forToken, ErrorTypeUnclosedControlFlow)
+
+	return stmt
+}
+
+// parseWhileStatement 解析 while 或 until 循环
+// while 条件; do 命令列表; done
+// until 条件; do 命令列表; done
+func (p *Parser) parseWhileStatement() *WhileStatement {
+	whileToken := p.curToken
+	stmt := &WhileStatement{Body: &BlockStatement{Statements: []Statement{}}, Until: whileToken.Type == lexer.UNTIL, Pos: p.curPos()}
+
+	p.nextToken() // 跳过 while 或 until
+	stmt.Condition = p.parseConditionList(lexer.DO, lexer.DONE)
+	if !p.expectClosing(lexer.DO, "do", whileToken, ErrorTypeUnclosedControlFlow) {
+		p.skipCompound(lexer.DONE)
+		return stmt
+	}
+	stmt.Body = p.parseBlockStatement(lexer.DONE)
+	p.expectClosing(lexer.DONE, "done", whileToken, ErrorTypeUnclosedControlFlow)
+
+	return stmt
+}
+
+// parseFunctionStatement 解析 function 关键字形式的函数定义
+// function name [()] 函数体
+func (p *Parser) parseFunctionStatement() *FunctionStatement {
+	stmt := &FunctionStatement{Pos: p.curPos()}
+
+	p.nextToken() // 跳过 function
+	if p.curToken.Type != lexer.IDENTIFIER {
+		p.unexpectedToken(i18n.T("函数名"))
+		return stmt
+	}
+	stmt.Name = p.curToken.Literal
+	p.nextToken()
+
+	if p.curToken.Type == lexer.LPAREN {
+		p.nextToken() // 跳过 (
+		if p.curToken.Type != lexer.RPAREN {
+			p.unexpectedToken(")")
+			return stmt
+		}
+		p.nextToken() // 跳过 )
+	}
+
+	p.parseFunctionBody(stmt)
+	return stmt
+}
+
+// parseFunctionDefinition 解析 name() 形式的函数定义
+func (p *Parser) parseFunctionDefinition() *FunctionStatement {
+	stmt := &FunctionStatement{Name: p.curToken.Literal, Pos: p.curPos()}
+
+	p.nextToken() // 跳过函数名
+	p.nextToken() // 跳过 (
+	if p.curToken.Type != lexer.RPAREN {
+		p.unexpectedToken(")")
+		return stmt
+	}
+	p.nextToken() // 跳过 )
+
+	p.parseFunctionBody(stmt)
+	return stmt
+}
+
+// parseFunctionBody 解析函数体（复合命令，通常是 { ... }），并记录函数体的源代码
+func (p *Parser) parseFunctionBody(stmt *FunctionStatement) {
+	p.skipNewlines()
+	start := p.curToken.Pos
+	stmt.Body = p.parseFunctionBodyCommand()
+	if p.prevEnd > start {
+		stmt.Source = p.l.Input()[start:p.prevEnd]
+	}
+}
+
+// parseFunctionBodyCommand 解析作为函数体的复合命令
+func (p *Parser) parseFunctionBodyCommand() *BlockStatement {
+	switch p.curToken.Type {
+	case lexer.LBRACE:
+		group := p.parseGroupCommand()
+		p.withTrailingRedirects(group)
+		return group.Body
+	case lexer.LPAREN, lexer.IF, lexer.FOR, lexer.WHILE, lexer.UNTIL, lexer.CASE:
+		stmt := p.parseCommand()
+		return &BlockStatement{Statements: []Statement{stmt}}
+	}
+
+	p.unexpectedToken("{")
+	return &BlockStatement{Statements: []Statement{}}
+}
+
+// parseSubshell 解析子shell命令 (command)
+func (p *Parser) parseSubshell() *SubshellCommand {
+	openToken := p.curToken
+	stmt := &SubshellCommand{Pos: p.curPos()}
+
+	p.nextToken() // 跳过 (
+	stmt.Body = p.parseBlockStatement(lexer.RPAREN)
+	p.expectClosing(lexer.RPAREN, ")", openToken, ErrorTypeUnclosedParen)
+
+	return stmt
+}
+
+// parseArithmeticCommand 解析算术命令 ((expr))（lexer 已经把整个命令读成一个 token）
+func (p *Parser) parseArithmeticCommand() *ArithmeticCommand {
+	stmt := &ArithmeticCommand{Expression: p.curToken.Literal, Pos: p.curPos()}
+	p.nextToken() // 跳过 ((expr))
+	return stmt
+}
+
+// parseGroupCommand 解析命令组 { command; }
+func (p *Parser) parseGroupCommand() *GroupCommand {
+	openToken := p.curToken
+	stmt := &GroupCommand{Pos: p.curPos()}
+
+	p.nextToken() // 跳过 {
+	stmt.Body = p.parseBlockStatement(lexer.RBRACE)
+	p.expectClosing(lexer.RBRACE, "}", openToken, ErrorTypeUnclosedBrace)
+
+	return stmt
+}
+
+// parseBreakStatement 解析break语句
+func (p *Parser) parseBreakStatement() *BreakStatement {
+	stmt := &BreakStatement{Level: 1}
+
+	p.nextToken() // 跳过 break
+
+	// 检查是否有数字参数（break n）
+	if p.curToken.Type == lexer.NUMBER || p.curToken.Type == lexer.IDENTIFIER {
+		if level, err := strconv.Atoi(p.curToken.Literal); err == nil && level > 0 {
+			stmt.Level = level
+			p.nextToken() // 跳过数字
+		}
+	}
+
+	return stmt
+}
+
+// parseContinueStatement 解析continue语句
+func (p *Parser) parseContinueStatement() *ContinueStatement {
+	stmt := &ContinueStatement{Level: 1}
+
+	p.nextToken() // 跳过 continue
+
+	// 检查是否有数字参数（continue n）
+	if p.curToken.Type == lexer.NUMBER || p.curToken.Type == lexer.IDENTIFIER {
+		if level, err := strconv.Atoi(p.curToken.Literal); err == nil && level > 0 {
+			stmt.Level = level
+			p.nextToken() // 跳过数字
+		}
+	}
+
+	return stmt
+}
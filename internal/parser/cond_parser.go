@@ -0,0 +1,221 @@
+package parser
+
+import (
+	"gobash/internal/i18n"
+	"gobash/internal/lexer"
+)
+
+// [[ ]] 条件命令的解析
+//
+// [[ 和 ]] 之间的内容在解析时构造为条件表达式树（CondExpression）：|| 的优先级最低，其次是 &&，然后是 !，
+// 括号改变优先级。运算符只在不带引号时识别（"-f" 和 '==' 是普通的字符串）。== 和 != 右边的单词是模式，
+// =~ 右边的单词是正则表达式，其中的 (、)、| 和括号中的空白都是正则表达式的一部分。
+// 单词之间可以换行
+
+// condUnaryOps 条件一元运算符
+var condUnaryOps = map[string]bool{
+	"-a": true, "-b": true, "-c": true, "-d": true, "-e": true, "-f": true, "-h": true, "-L": true,
+	"-p": true, "-r": true, "-s": true, "-S": true, "-w": true, "-x": true,
+	"-n": true, "-z": true, "-v": true,
+}
+
+// condBinaryOps 条件二元运算符（< 和 > 由 lexer 作为重定向 token 返回，单独处理）
+var condBinaryOps = map[string]bool{
+	"==": true, "=": true, "!=": true, "=~": true,
+	"-eq": true, "-ne": true, "-lt": true, "-le": true, "-gt": true, "-ge": true,
+	"-nt": true, "-ot": true, "-ef": true,
+}
+
+// parseCondCommand 解析 [[ ... ]]，当前 token 是 [[，结束时当前 token 是 ]] 之后的 token
+// 出错时跳过到 ]] 之后（没有 ]] 时到行末），返回 nil
+func (p *Parser) parseCondCommand() CondExpression {
+	open := p.curToken
+	p.inDoubleBracket = true
+	defer func() { p.inDoubleBracket = false }()
+	p.nextToken() // 跳过 [[
+
+	cond := p.parseCondOr()
+	if cond != nil {
+		p.skipNewlines()
+		if p.expectClosing(lexer.DBL_RBRACKET, "]]", open, ErrorTypeUnclosedBracket) {
+			return cond
+		}
+	}
+	p.skipCondition()
+	return nil
+}
+
+// skipCondition 条件表达式出错后跳过到 ]] 之后，没有 ]] 时停在行末或文件结束
+func (p *Parser) skipCondition() {
+	for p.curToken.Type != lexer.NEWLINE && p.curToken.Type != lexer.EOF {
+		if p.curToken.Type == lexer.DBL_RBRACKET {
+			p.nextToken()
+			return
+		}
+		p.nextToken()
+	}
+}
+
+// parseCondOr 解析用 || 连接的条件
+func (p *Parser) parseCondOr() CondExpression {
+	left := p.parseCondAnd()
+	for left != nil {
+		p.skipNewlines()
+		if p.curToken.Type != lexer.OR {
+			break
+		}
+		p.nextToken()
+		right := p.parseCondAnd()
+		if right == nil {
+			return nil
+		}
+		left = &CondBinary{Op: "||", Left: left, Right: right}
+	}
+	return left
+}
+
+// parseCondAnd 解析用 && 连接的条件
+func (p *Parser) parseCondAnd() CondExpression {
+	left := p.parseCondTerm()
+	for left != nil {
+		p.skipNewlines()
+		if p.curToken.Type != lexer.AND {
+			break
+		}
+		p.nextToken()
+		right := p.parseCondTerm()
+		if right == nil {
+			return nil
+		}
+		left = &CondBinary{Op: "&&", Left: left, Right: right}
+	}
+	return left
+}
+
+// parseCondTerm 解析取反的条件、括号中的条件、一元测试、二元测试或单独的单词
+func (p *Parser) parseCondTerm() CondExpression {
+	p.skipNewlines()
+	if p.curToken.Type == lexer.LPAREN {
+		open := p.curToken
+		p.nextToken() // 跳过 (
+		inner := p.parseCondOr()
+		if inner == nil {
+			return nil
+		}
+		p.skipNewlines()
+		if !p.expectClosing(lexer.RPAREN, ")", open, ErrorTypeUnclosedParen) {
+			return nil
+		}
+		return &CondParen{Expr: inner}
+	}
+	if !isCondWordToken(p.curToken.Type) {
+		p.unexpectedToken("")
+		return nil
+	}
+
+	word, raw := p.parseCondWord()
+	switch {
+	case raw == "!":
+		inner := p.parseCondTerm()
+		if inner == nil {
+			return nil
+		}
+		return &CondNot{Expr: inner}
+	case condUnaryOps[raw]:
+		if !isCondWordToken(p.curToken.Type) {
+			p.addError(ErrorTypeSyntax, i18n.Sprintf("条件一元运算符 %s 缺少参数", raw), p.curToken, "")
+			return nil
+		}
+		operand, _ := p.parseCondWord()
+		return &CondUnary{Op: raw, Operand: operand}
+	}
+	return p.parseCondCompare(word)
+}
+
+// parseCondCompare 解析二元测试中运算符和右边的单词（左边的单词已经解析），没有运算符时是单独的单词
+func (p *Parser) parseCondCompare(left Expression) CondExpression {
+	var op string
+	switch tok := p.curToken; {
+	case tok.Type == lexer.REDIRECT_IN || tok.Type == lexer.REDIRECT_OUT:
+		// [[ 中的 < 和 > 是字符串比较运算符
+		op = tok.Literal
+		p.nextToken()
+	case isCondWordToken(tok.Type):
+		_, raw := p.parseCondWord()
+		if !condBinaryOps[raw] {
+			p.addError(ErrorTypeSyntax, i18n.T("需要条件二元运算符"), tok, "")
+			return nil
+		}
+		op = raw
+	default:
+		return &CondWord{Word: left}
+	}
+
+	var right Expression
+	if op == "=~" {
+		right = p.parseCondRegex()
+	} else if isCondWordToken(p.curToken.Type) {
+		right, _ = p.parseCondWord()
+	}
+	if right == nil {
+		p.addError(ErrorTypeSyntax, i18n.Sprintf("条件二元运算符 %s 缺少参数", op), p.curToken, "")
+		return nil
+	}
+	return &CondCompare{Op: op, Left: left, Right: right}
+}
+
+// parseCondWord 解析条件表达式中的一个单词，同时返回它在输入中的原始文本（用于识别不带引号的运算符）
+func (p *Parser) parseCondWord() (Expression, string) {
+	start := p.curToken.Pos
+	word := p.parseWord()
+	return word, p.l.Input()[start:p.prevEnd]
+}
+
+// parseCondRegex 解析 =~ 右边的正则表达式，没有正则表达式时返回 nil
+// 正则表达式到括号之外的空白为止；(、)、| 作为字面的片段，括号中的空白原样保留
+func (p *Parser) parseCondRegex() Expression {
+	var parts []Expression
+	depth := 0
+	for {
+		switch tok := p.curToken; {
+		case tok.Type == lexer.LPAREN:
+			depth++
+			parts = append(parts, &Identifier{Value: "("})
+			p.nextToken()
+		case tok.Type == lexer.RPAREN && depth > 0:
+			depth--
+			parts = append(parts, &Identifier{Value: ")"})
+			p.nextToken()
+		case tok.Type == lexer.PIPE:
+			parts = append(parts, &Identifier{Value: "|"})
+			p.nextToken()
+		case isCondWordToken(tok.Type):
+			parts = append(parts, p.parseWord())
+		default:
+			return joinParts(parts)
+		}
+		if p.curToken.Pos != p.prevEnd {
+			if depth == 0 || p.curToken.Type == lexer.DBL_RBRACKET || p.curToken.Type == lexer.EOF {
+				return joinParts(parts)
+			}
+			parts = append(parts, &Identifier{Value: p.l.Input()[p.prevEnd:p.curToken.Pos]})
+		}
+	}
+}
+
+// joinParts 把片段组成一个单词，没有片段时返回 nil
+func joinParts(parts []Expression) Expression {
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return parts[0]
+	default:
+		return &ConcatExpression{Parts: parts}
+	}
+}
+
+// isCondWordToken 检查 token 是否可以作为条件表达式中的单词（]] 结束条件表达式）
+func isCondWordToken(t lexer.TokenType) bool {
+	return isWordToken(t) && t != lexer.DBL_RBRACKET
+}
@@ -2,6 +2,8 @@ package parser
 
 import (
 	"fmt"
+	"sort"
+	"gobash/internal/i18n"
 	"gobash/internal/lexer"
 )
 
@@ -11,6 +13,10 @@ type ParseError struct {
 	Message  string
 	Token    lexer.Token
 	Expected string // 期望的 token 类型或值
+	// Incomplete 错误是否由输入提前结束引起（如缺少 fi、done），继续输入后可能成为合法的程序
+	Incomplete bool
+	// Open 没有结束的结构开始的位置（如 if 所在的位置）；Token 是发现错误的位置（文件结束）
+	Open lexer.Position
 }
 
 // ErrorType 错误类型
@@ -26,6 +32,7 @@ const (
 	ErrorTypeUnclosedControlFlow      // 未闭合的控制流（if/fi, case/esac等）
 	ErrorTypeInvalidExpression        // 无效的表达式
 	ErrorTypeMissingToken             // 缺少 token
+	ErrorTypeUnexpectedEOF            // 意外的文件结束（输入不完整）
 )
 
 // Error 实现 error 接口
@@ -40,62 +47,69 @@ func (e *ParseError) Error() string {
 		switch e.Type {
 		case ErrorTypeUnclosedParen:
 			if e.Expected != "" {
-				return fmt.Sprintf("第%d行第%d列: 语法错误：未找到匹配的 `%s'", 
+				return i18n.Sprintf("第%d行第%d列: 语法错误：未找到匹配的 `%s'", 
 					e.Token.Line, e.Token.Column, e.Expected)
 			}
-			return fmt.Sprintf("第%d行第%d列: 语法错误：未闭合的括号", 
+			return i18n.Sprintf("第%d行第%d列: 语法错误：未闭合的括号", 
 				e.Token.Line, e.Token.Column)
 		case ErrorTypeUnclosedBrace:
 			if e.Expected != "" {
-				return fmt.Sprintf("第%d行第%d列: 语法错误：未找到匹配的 `%s'", 
+				return i18n.Sprintf("第%d行第%d列: 语法错误：未找到匹配的 `%s'", 
 					e.Token.Line, e.Token.Column, e.Expected)
 			}
-			return fmt.Sprintf("第%d行第%d列: 语法错误：未闭合的大括号", 
+			return i18n.Sprintf("第%d行第%d列: 语法错误：未闭合的大括号", 
 				e.Token.Line, e.Token.Column)
 		case ErrorTypeUnclosedControlFlow:
 			if e.Expected != "" {
-				return fmt.Sprintf("第%d行第%d列: 语法错误：未找到匹配的 `%s'", 
+				return i18n.Sprintf("第%d行第%d列: 语法错误：未找到匹配的 `%s'", 
 					e.Token.Line, e.Token.Column, e.Expected)
 			}
-			return fmt.Sprintf("第%d行第%d列: 语法错误：未闭合的控制流语句", 
+			return i18n.Sprintf("第%d行第%d列: 语法错误：未闭合的控制流语句", 
 				e.Token.Line, e.Token.Column)
 		case ErrorTypeUnexpectedToken:
 			if e.Expected != "" {
-				return fmt.Sprintf("第%d行第%d列: 语法错误：意外的 token `%s'，期望 `%s'", 
+				return i18n.Sprintf("第%d行第%d列: 语法错误：意外的 token `%s'，期望 `%s'", 
 					e.Token.Line, e.Token.Column, e.Token.Literal, e.Expected)
 			}
-			return fmt.Sprintf("第%d行第%d列: 语法错误：意外的 token `%s'", 
+			return i18n.Sprintf("第%d行第%d列: 语法错误：意外的 token `%s'", 
 				e.Token.Line, e.Token.Column, e.Token.Literal)
 		case ErrorTypeMissingToken:
 			if e.Expected != "" {
-				return fmt.Sprintf("第%d行第%d列: 语法错误：缺少 token `%s'", 
+				return i18n.Sprintf("第%d行第%d列: 语法错误：缺少 token `%s'", 
 					e.Token.Line, e.Token.Column, e.Expected)
 			}
-			return fmt.Sprintf("第%d行第%d列: 语法错误：缺少 token", 
+			return i18n.Sprintf("第%d行第%d列: 语法错误：缺少 token", 
+				e.Token.Line, e.Token.Column)
+		case ErrorTypeUnexpectedEOF:
+			if e.Expected != "" {
+				return i18n.Sprintf("第%d行第%d列: 语法错误：意外的文件结束，期望 `%s'", 
+					e.Token.Line, e.Token.Column, e.Expected)
+			}
+			return i18n.Sprintf("第%d行第%d列: 语法错误：意外的文件结束", 
 				e.Token.Line, e.Token.Column)
 		case ErrorTypeUnclosedQuote:
-			return fmt.Sprintf("第%d行第%d列: 语法错误：未闭合的引号", 
+			return i18n.Sprintf("第%d行第%d列: 语法错误：未闭合的引号", 
 				e.Token.Line, e.Token.Column)
 		case ErrorTypeInvalidExpression:
-			return fmt.Sprintf("第%d行第%d列: 语法错误：无效的表达式 `%s'", 
+			return i18n.Sprintf("第%d行第%d列: 语法错误：无效的表达式 `%s'", 
 				e.Token.Line, e.Token.Column, e.Token.Literal)
 		default:
 			// 默认格式
 			if e.Expected != "" {
-				return fmt.Sprintf("第%d行第%d列: %s，期望 `%s'，得到 `%s'", 
+				return i18n.Sprintf("第%d行第%d列: %s，期望 `%s'，得到 `%s'", 
 					e.Token.Line, e.Token.Column, e.Message, e.Expected, e.Token.Literal)
 			}
-			return fmt.Sprintf("第%d行第%d列: %s，得到 `%s'", 
+			return i18n.Sprintf("第%d行第%d列: %s，得到 `%s'", 
 				e.Token.Line, e.Token.Column, e.Message, e.Token.Literal)
 		}
 	}
 	
 	// 没有行号信息的情况
 	if e.Expected != "" {
-		return fmt.Sprintf("语法错误：%s，期望 `%s'，得到 `%s'", 
+		return i18n.Sprintf("语法错误：%s，期望 `%s'，得到 `%s'", 
 			e.Message, e.Expected, e.Token.Literal)
 	}
-	return fmt.Sprintf("语法错误：%s，得到 `%s'", e.Message, e.Token.Literal)
+	return i18n.Sprintf("语法错误：%s，得到 `%s'", e.Message, e.Token.Literal)
 }
 
 // String 返回错误的字符串表示
@@ -103,27 +117,119 @@ func (e *ParseError) String() string {
 	return e.Error()
 }
 
+// ExitCode 返回退出码，与 bash 的语法错误相同为 2
+func (e *ParseError) ExitCode() int {
+	return 2
+}
+
+// Position 返回出错的 token 的位置
+func (e *ParseError) Position() lexer.Position {
+	return lexer.Position{Line: e.Token.Line, Column: e.Token.Column}
+}
+
+// Unwrap 语法错误没有原始错误
+func (e *ParseError) Unwrap() error {
+	return nil
+}
+
 // addError 添加解析错误
 func (p *Parser) addError(errType ErrorType, message string, token lexer.Token, expected string) {
 	err := &ParseError{
-		Type:     errType,
-		Message:  message,
-		Token:    token,
-		Expected: expected,
+		Type:       errType,
+		Message:    message,
+		Token:      token,
+		Expected:   expected,
+		Incomplete: p.curToken.Type == lexer.EOF,
 	}
 	p.errors = append(p.errors, err.Error())
 	p.parseErrors = append(p.parseErrors, err)
 }
 
+// addUnclosedError 报告到达文件结束时 open 开始的结构没有结束，与 bash 相同，错误的位置是文件结束的位置
+func (p *Parser) addUnclosedError(errType ErrorType, open lexer.Token, closing string) {
+	p.addError(errType, i18n.Sprintf("未找到匹配的 `%s'", closing), p.curToken, closing)
+	p.parseErrors[len(p.parseErrors)-1].Open = lexer.Position{Line: open.Line, Column: open.Column}
+}
+
 // addErrorf 添加格式化的解析错误
 func (p *Parser) addErrorf(errType ErrorType, format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
 	err := &ParseError{
-		Type:    errType,
-		Message: message,
-		Token:   p.curToken,
+		Type:       errType,
+		Message:    message,
+		Token:      p.curToken,
+		Incomplete: p.curToken.Type == lexer.EOF,
 	}
 	p.errors = append(p.errors, err.Error())
 	p.parseErrors = append(p.parseErrors, err)
 }
 
+
+// AllErrors 返回词法错误和语法错误，按出现的位置（行、列）排序
+func (p *Parser) AllErrors() []error {
+	type positioned struct {
+		line, column int
+		err          error
+	}
+	var found []positioned
+	for _, e := range p.l.Errors() {
+		found = append(found, positioned{e.Line, e.Column, e})
+	}
+	for _, e := range p.parseErrors {
+		found = append(found, positioned{e.Token.Line, e.Token.Column, e})
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].line != found[j].line {
+			return found[i].line < found[j].line
+		}
+		return found[i].column < found[j].column
+	})
+	errs := make([]error, len(found))
+	for i, f := range found {
+		errs[i] = f.err
+	}
+	return errs
+}
+
+// Incomplete 检查输入是否不完整：存在错误且所有错误都是由输入提前结束引起的
+// （如缺少 fi、done，引号未闭合，here-document 未结束），或者输入以续行符结束。
+// 交互模式下遇到不完整的输入应该继续读取下一行，而不是报告语法错误
+func (p *Parser) Incomplete() bool {
+	_, more := p.NeedsMore()
+	return more
+}
+
+// NeedsMore 检查输入是否不完整（见 Incomplete），并返回最内层（开始位置最靠后）没有结束的结构期望的结束符：
+// 关键字（fi、done、esac、then、do）、括号、引号或 here-document 的分隔符；只是以续行符结尾时为空字符串
+func (p *Parser) NeedsMore() (closer string, more bool) {
+	line, column := 0, 0
+	for _, err := range p.AllErrors() {
+		var expected string
+		var pos lexer.Position
+		switch e := err.(type) {
+		case *ParseError:
+			if !e.Incomplete {
+				return "", false
+			}
+			expected, pos = e.Expected, lexer.Position{Line: e.Token.Line, Column: e.Token.Column}
+			if e.Open.IsValid() {
+				pos = e.Open
+			}
+		case *lexer.LexerError:
+			if !e.Incomplete() {
+				return "", false
+			}
+			expected, pos = e.Closer(), e.Position()
+		}
+		// 错误按位置排序，位置相同时取后面的
+		if !more || pos.Line > line || pos.Line == line && pos.Column >= column {
+			closer, line, column = expected, pos.Line, pos.Column
+		}
+		more = true
+	}
+	if !more {
+		return "", p.l.EndsWithContinuation()
+	}
+	return closer, true
+}
@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// 语法树的 JSON 格式（gobash -ast）
+//
+// 每个节点输出为一个对象，第一个字段 "Node" 是节点的类型名（如 "CommandStatement"），其余字段与结构体的字段相同；
+// 重定向的 Type 输出为运算符（如 ">>"）。JSON 只用于查看和调试，不能再解析回语法树
+
+// marshalNode 把节点的字段编码为 JSON 对象，并在开头加上 "Node" 字段
+// v 是去掉了 MarshalJSON 方法的同结构类型，避免递归
+func marshalNode(kind string, v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// 重定向和命令中常见的 <、>、& 不转义
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	data := bytes.TrimSpace(buf.Bytes())
+	head := `{"Node":` + strconv.Quote(kind)
+	if len(data) > 2 {
+		head += ","
+	}
+	return append([]byte(head), data[1:]...), nil
+}
+
+// MarshalText 把重定向的类型编码为运算符
+func (t RedirectType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+	type node Program
+	return marshalNode("Program", (*node)(p))
+}
+
+func (cs *CommandStatement) MarshalJSON() ([]byte, error) {
+	type node CommandStatement
+	return marshalNode("CommandStatement", (*node)(cs))
+}
+
+func (r *Redirect) MarshalJSON() ([]byte, error) {
+	type node Redirect
+	return marshalNode("Redirect", (*node)(r))
+}
+
+func (h *HereDocument) MarshalJSON() ([]byte, error) {
+	type node HereDocument
+	return marshalNode("HereDocument", (*node)(h))
+}
+
+func (is *IfStatement) MarshalJSON() ([]byte, error) {
+	type node IfStatement
+	return marshalNode("IfStatement", (*node)(is))
+}
+
+func (ec *ElifClause) MarshalJSON() ([]byte, error) {
+	type node ElifClause
+	return marshalNode("ElifClause", (*node)(ec))
+}
+
+func (fs *ForStatement) MarshalJSON() ([]byte, error) {
+	type node ForStatement
+	return marshalNode("ForStatement", (*node)(fs))
+}
+
+func (ws *WhileStatement) MarshalJSON() ([]byte, error) {
+	type node WhileStatement
+	return marshalNode("WhileStatement", (*node)(ws))
+}
+
+func (bs *BlockStatement) MarshalJSON() ([]byte, error) {
+	type node BlockStatement
+	return marshalNode("BlockStatement", (*node)(bs))
+}
+
+func (fs *FunctionStatement) MarshalJSON() ([]byte, error) {
+	type node FunctionStatement
+	return marshalNode("FunctionStatement", (*node)(fs))
+}
+
+func (as *ArrayAssignmentStatement) MarshalJSON() ([]byte, error) {
+	type node ArrayAssignmentStatement
+	return marshalNode("ArrayAssignmentStatement", (*node)(as))
+}
+
+func (cs *CaseStatement) MarshalJSON() ([]byte, error) {
+	type node CaseStatement
+	return marshalNode("CaseStatement", (*node)(cs))
+}
+
+func (c *CaseClause) MarshalJSON() ([]byte, error) {
+	type node CaseClause
+	return marshalNode("CaseClause", (*node)(c))
+}
+
+func (bs *BreakStatement) MarshalJSON() ([]byte, error) {
+	type node BreakStatement
+	return marshalNode("BreakStatement", (*node)(bs))
+}
+
+func (cs *ContinueStatement) MarshalJSON() ([]byte, error) {
+	type node ContinueStatement
+	return marshalNode("ContinueStatement", (*node)(cs))
+}
+
+func (cb *CondBinary) MarshalJSON() ([]byte, error) {
+	type node CondBinary
+	return marshalNode("CondBinary", (*node)(cb))
+}
+
+func (cn *CondNot) MarshalJSON() ([]byte, error) {
+	type node CondNot
+	return marshalNode("CondNot", (*node)(cn))
+}
+
+func (cp *CondParen) MarshalJSON() ([]byte, error) {
+	type node CondParen
+	return marshalNode("CondParen", (*node)(cp))
+}
+
+func (cu *CondUnary) MarshalJSON() ([]byte, error) {
+	type node CondUnary
+	return marshalNode("CondUnary", (*node)(cu))
+}
+
+func (cc *CondCompare) MarshalJSON() ([]byte, error) {
+	type node CondCompare
+	return marshalNode("CondCompare", (*node)(cc))
+}
+
+func (cw *CondWord) MarshalJSON() ([]byte, error) {
+	type node CondWord
+	return marshalNode("CondWord", (*node)(cw))
+}
+
+func (i *Identifier) MarshalJSON() ([]byte, error) {
+	type node Identifier
+	return marshalNode("Identifier", (*node)(i))
+}
+
+func (sl *StringLiteral) MarshalJSON() ([]byte, error) {
+	type node StringLiteral
+	return marshalNode("StringLiteral", (*node)(sl))
+}
+
+func (v *Variable) MarshalJSON() ([]byte, error) {
+	type node Variable
+	return marshalNode("Variable", (*node)(v))
+}
+
+func (cs *CommandSubstitution) MarshalJSON() ([]byte, error) {
+	type node CommandSubstitution
+	return marshalNode("CommandSubstitution", (*node)(cs))
+}
+
+func (ae *ArithmeticExpansion) MarshalJSON() ([]byte, error) {
+	type node ArithmeticExpansion
+	return marshalNode("ArithmeticExpansion", (*node)(ae))
+}
+
+func (ps *ProcessSubstitution) MarshalJSON() ([]byte, error) {
+	type node ProcessSubstitution
+	return marshalNode("ProcessSubstitution", (*node)(ps))
+}
+
+func (pe *ParamExpandExpression) MarshalJSON() ([]byte, error) {
+	type node ParamExpandExpression
+	return marshalNode("ParamExpandExpression", (*node)(pe))
+}
+
+func (ce *ConcatExpression) MarshalJSON() ([]byte, error) {
+	type node ConcatExpression
+	return marshalNode("ConcatExpression", (*node)(ce))
+}
+
+func (aw *AssignmentWord) MarshalJSON() ([]byte, error) {
+	type node AssignmentWord
+	return marshalNode("AssignmentWord", (*node)(aw))
+}
+
+func (sc *SubshellCommand) MarshalJSON() ([]byte, error) {
+	type node SubshellCommand
+	return marshalNode("SubshellCommand", (*node)(sc))
+}
+
+func (ac *ArithmeticCommand) MarshalJSON() ([]byte, error) {
+	type node ArithmeticCommand
+	return marshalNode("ArithmeticCommand", (*node)(ac))
+}
+
+func (gc *GroupCommand) MarshalJSON() ([]byte, error) {
+	type node GroupCommand
+	return marshalNode("GroupCommand", (*node)(gc))
+}
+
+func (ns *NegatedStatement) MarshalJSON() ([]byte, error) {
+	type node NegatedStatement
+	return marshalNode("NegatedStatement", (*node)(ns))
+}
+
+func (ts *TimedStatement) MarshalJSON() ([]byte, error) {
+	type node TimedStatement
+	return marshalNode("TimedStatement", (*node)(ts))
+}
+
+func (bs *BackgroundStatement) MarshalJSON() ([]byte, error) {
+	type node BackgroundStatement
+	return marshalNode("BackgroundStatement", (*node)(bs))
+}
+
+func (cs *CoprocStatement) MarshalJSON() ([]byte, error) {
+	type node CoprocStatement
+	return marshalNode("CoprocStatement", (*node)(cs))
+}
+
+func (ps *PipelineStatement) MarshalJSON() ([]byte, error) {
+	type node PipelineStatement
+	return marshalNode("PipelineStatement", (*node)(ps))
+}
+
+func (cc *CommandChain) MarshalJSON() ([]byte, error) {
+	type node CommandChain
+	return marshalNode("CommandChain", (*node)(cc))
+}
@@ -2,14 +2,21 @@
 package parser
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
+	"gobash/internal/i18n"
 	"gobash/internal/lexer"
 )
 
 // Parser 语法分析器
 // 负责将token序列解析为抽象语法树（AST），支持shell的各种语法结构
+//
+// 语法结构（自顶向下）：
+//   程序     := 命令列表
+//   命令列表 := 语句 { (; | & | 换行) 语句 }
+//   语句     := 管道 { (&& | ||) 换行* 管道 }
+//   管道     := 命令 { | 换行* 命令 }
+//   命令     := 简单命令 | 复合命令（if/for/while/case/函数/子shell/命令组）
 type Parser struct {
 	l      *lexer.Lexer
 	errors []string // 保持向后兼容，存储错误消息字符串
@@ -17,9 +24,20 @@ type Parser struct {
 
 	curToken  lexer.Token
 	peekToken lexer.Token
-	
+	// 上一个 token（已经跳过的 token）的结束字节偏移
+	prevEnd int
+
 	// 用于回退
 	savedTokens []lexer.Token
+
+	// 已解析重定向、等待正文的 here-document（正文由 lexer 在换行后以 HEREDOC_CONTENT 返回）
+	pendingHereDocs []*HereDocument
+	// 是否正在解析 [[ ... ]] 的条件表达式
+	inDoubleBracket bool
+	// 命令列表的嵌套深度，1 表示顶层
+	blockDepth int
+	// 顶层语句的起始行号
+	statementLines []int
 }
 
 // New 创建新的解析器
@@ -38,587 +56,565 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 // nextToken 移动到下一个token
+// HEREDOC_CONTENT token 不进入语法分析，直接填充到对应的 here-document 中
 func (p *Parser) nextToken() {
+	p.prevEnd = p.curToken.End
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == lexer.HEREDOC_CONTENT {
+		if len(p.pendingHereDocs) > 0 {
+			p.pendingHereDocs[0].Content = p.peekToken.Literal
+			p.pendingHereDocs = p.pendingHereDocs[1:]
+		}
+		p.peekToken = p.l.NextToken()
+	}
+}
+
+// curPos 返回当前 token 在输入中的位置
+func (p *Parser) curPos() lexer.Position {
+	return lexer.Position{Line: p.curToken.Line, Column: p.curToken.Column}
 }
 
 // ParseProgram 解析程序
+// 一次解析完整的输入，语法错误通过 ParseErrors 返回，不会中断解析
 func (p *Parser) ParseProgram() *Program {
 	program := &Program{}
-	program.Statements = []Statement{}
+	p.statementLines = nil
+	program.Statements = p.parseBlockStatement().Statements
+	program.Lines = p.statementLines
+	return program
+}
 
-	for p.curToken.Type != lexer.EOF {
-		// 跳过空白字符和换行
-		if p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-			p.nextToken()
-			continue
+// parseBlockStatement 解析命令列表，直到遇到任一终止 token（如 fi、done、}）或文件结束
+// 终止 token 只在命令开始的位置识别，不会被消耗
+func (p *Parser) parseBlockStatement(terminators ...lexer.TokenType) *BlockStatement {
+	block := &BlockStatement{Statements: []Statement{}}
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+
+	for {
+		p.skipNewlines()
+		if p.curToken.Type == lexer.EOF || isTokenIn(p.curToken.Type, terminators) {
+			return block
 		}
 
-		// 记录解析前的错误数量
 		errorCountBefore := len(p.parseErrors)
-
+		line := p.curToken.Line
 		stmt := p.parseStatement()
-		
-		// 检查是否有新的错误产生
-		if len(p.parseErrors) > errorCountBefore {
-			// 有错误产生，尝试恢复
-			lastError := p.parseErrors[len(p.parseErrors)-1]
-			if p.shouldContinueAfterError(lastError.Type) {
-				// 尝试从未闭合错误中恢复
-				if lastError.Type == ErrorTypeUnclosedParen ||
-				   lastError.Type == ErrorTypeUnclosedBrace ||
-				   lastError.Type == ErrorTypeUnclosedControlFlow {
-					p.recoverFromUnclosedError(lastError.Type)
-				} else {
-					// 使用通用错误恢复
-					p.recoverFromError()
-				}
-			}
+		if stmt == nil {
+			// 当前 token 不能作为命令的开始
+			p.unexpectedToken("")
+			p.recoverFromStatementError()
+			continue
 		}
-
-		if stmt != nil {
-			// 检查是否是命令链（; && ||）
-			stmt = p.parseCommandChain(stmt)
-			program.Statements = append(program.Statements, stmt)
+		block.Statements = append(block.Statements, stmt)
+		if p.blockDepth == 1 {
+			p.statementLines = append(p.statementLines, line)
 		}
-		// case语句已经处理了esac，curToken已经指向esac之后的位置
-		// 如果curToken不是EOF，需要移动到下一个token
-		if p.curToken.Type != lexer.EOF {
+
+		switch p.curToken.Type {
+		case lexer.SEMICOLON, lexer.NEWLINE:
 			p.nextToken()
+		case lexer.AMPERSAND:
+			// 后台执行：简单命令设置 Background，其他语句（管道、命令链、循环、子shell 等）整个放到后台
+			if cmd, ok := stmt.(*CommandStatement); ok {
+				cmd.Background = true
+			} else {
+				block.Statements[len(block.Statements)-1] = &BackgroundStatement{Statement: stmt}
+			}
+			p.nextToken()
+		default:
+			if p.curToken.Type == lexer.EOF || isTokenIn(p.curToken.Type, terminators) {
+				continue
+			}
+			// 语句后面出现了不能出现的 token
+			// 如果语句内部已经报告过错误，不再重复报告
+			if len(p.parseErrors) == errorCountBefore {
+				p.unexpectedToken("")
+			}
+			p.recoverFromStatementError()
 		}
 	}
-
-	return program
 }
 
-// parseCommandChain 解析命令链（; && ||）
-func (p *Parser) parseCommandChain(left Statement) Statement {
-	for {
-		// 跳过空白字符和换行
-		for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-			p.nextToken()
-		}
-		
-		var op string
-		if p.curToken.Type == lexer.SEMICOLON {
-			op = ";"
-			p.nextToken()
-		} else if p.curToken.Type == lexer.AND {
-			op = "&&"
-			p.nextToken()
-		} else if p.curToken.Type == lexer.OR {
-			op = "||"
-			p.nextToken()
-		} else {
-			// 没有操作符，返回
-			return left
-		}
-		
-		// 跳过空白字符和换行
-		for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-			p.nextToken()
-		}
-		
-		// 解析右侧命令
-		right := p.parseStatement()
+// parseStatement 解析语句（由 && 和 || 连接的管道）
+func (p *Parser) parseStatement() Statement {
+	left := p.parsePipeline()
+	if left == nil {
+		return nil
+	}
+
+	for p.curToken.Type == lexer.AND || p.curToken.Type == lexer.OR {
+		op := p.curToken.Literal
+		p.nextToken() // 跳过 && 或 ||
+		p.skipNewlines()
+
+		right := p.parsePipeline()
 		if right == nil {
+			p.unexpectedToken("")
 			return left
 		}
-		
-		// 创建命令链
 		left = &CommandChain{
 			Left:     left,
 			Right:    right,
 			Operator: op,
 		}
 	}
+
+	return left
 }
 
-// parseStatement 解析语句
-func (p *Parser) parseStatement() Statement {
+// parsePipeline 解析管道（cmd1 | cmd2 | ...），管道中的命令可以是复合命令
+func (p *Parser) parsePipeline() Statement {
+	// time [-p] pipeline：测量管道的执行时间
+	if p.curToken.Type == lexer.TIME {
+		stmt := &TimedStatement{}
+		p.nextToken() // 跳过 time
+		if p.curToken.Type == lexer.IDENTIFIER && p.curToken.Literal == "-p" {
+			stmt.Posix = true
+			p.nextToken()
+		}
+		// 只有 time 时不执行命令，只输出时间
+		stmt.Statement = p.parsePipeline()
+		return stmt
+	}
+	// ! pipeline：对管道的退出状态取反
+	if p.curToken.Type == lexer.IDENTIFIER && p.curToken.Literal == "!" && !p.peekIsAdjacent() {
+		p.nextToken() // 跳过 !
+		stmt := p.parsePipeline()
+		if stmt == nil {
+			p.unexpectedToken("")
+			return &NegatedStatement{}
+		}
+		return &NegatedStatement{Statement: stmt}
+	}
+
+	stmt := p.parseCommand()
+	if stmt == nil {
+		return nil
+	}
+	if p.curToken.Type != lexer.PIPE && p.curToken.Type != lexer.BAR_AND {
+		return stmt
+	}
+
+	pipeline := &PipelineStatement{Commands: []Statement{stmt}}
+	for p.curToken.Type == lexer.PIPE || p.curToken.Type == lexer.BAR_AND {
+		pipeline.Stderr = append(pipeline.Stderr, p.curToken.Type == lexer.BAR_AND)
+		p.nextToken() // 跳过 | 或 |&
+		p.skipNewlines()
+
+		cmd := p.parseCommand()
+		if cmd == nil {
+			p.unexpectedToken("")
+			return pipeline.Commands[0]
+		}
+		pipeline.Commands = append(pipeline.Commands, cmd)
+	}
+	return pipeline
+}
+
+// parseCommand 解析单个命令（简单命令或复合命令）
+// 当前 token 不能作为命令的开始时返回 nil
+func (p *Parser) parseCommand() Statement {
 	switch p.curToken.Type {
 	case lexer.IF:
-		return p.parseIfStatement()
+		return p.withTrailingRedirects(p.parseIfStatement())
 	case lexer.FOR:
-		return p.parseForStatement()
-	case lexer.WHILE:
-		return p.parseWhileStatement()
+		return p.withTrailingRedirects(p.parseForStatement())
+	case lexer.WHILE, lexer.UNTIL:
+		return p.withTrailingRedirects(p.parseWhileStatement())
+	case lexer.CASE:
+		return p.withTrailingRedirects(p.parseCaseStatement())
 	case lexer.FUNCTION:
 		return p.parseFunctionStatement()
-	case lexer.CASE:
-		return p.parseCaseStatement()
-	case lexer.BREAK:
-		return p.parseBreakStatement()
-	case lexer.CONTINUE:
-		return p.parseContinueStatement()
 	case lexer.LPAREN:
 		// 子shell (command)
-		return p.parseSubshell()
+		return p.withTrailingRedirects(p.parseSubshell())
+	case lexer.ARITHMETIC_COMMAND:
+		// 算术命令 ((expr))
+		return p.withTrailingRedirects(p.parseArithmeticCommand())
 	case lexer.LBRACE:
 		// 命令组 { command; }
-		return p.parseGroupCommand()
-	case lexer.SEMICOLON:
-		// 空语句，跳过
-		p.nextToken()
-		return p.parseStatement()
-	default:
-		// 先检查是否是函数定义格式 name() { ... }
-		// 必须在数组赋值检查之前，因为函数定义也是 IDENTIFIER + LPAREN
-		if p.curToken.Type == lexer.IDENTIFIER && p.peekToken.Type == lexer.LPAREN {
-			// 保存当前状态
-			name := p.curToken.Literal
-			p.nextToken() // 移动到 LPAREN
-			p.nextToken() // 移动到 ) 或其他
-			if p.curToken.Type == lexer.RPAREN {
-				p.nextToken() // 移动到 ) 后面
-				// 跳过空白字符
-				for p.curToken.Type == lexer.WHITESPACE {
-					p.nextToken()
-				}
-				if p.curToken.Type == lexer.LBRACE {
-					// 这是函数定义
-					stmt := &FunctionStatement{Name: name}
-					p.nextToken() // 跳过 {
-					// 函数体不应该在 FI 时停止，因为函数体中可能有 if 语句
-					stmt.Body = p.parseBlockStatementWithStopOnFI(false)
-					return stmt
-				}
-			}
-			// 不是函数定义，但 lexer 状态已改变，无法恢复
-			// 继续解析为命令
+		return p.withTrailingRedirects(p.parseGroupCommand())
+	case lexer.BREAK:
+		return p.parseBreakStatement()
+	case lexer.CONTINUE:
+		return p.parseContinueStatement()
+	case lexer.THEN, lexer.ELSE, lexer.ELIF, lexer.FI, lexer.DO, lexer.DONE, lexer.ESAC, lexer.RBRACE:
+		// 保留字不能作为命令的开始
+		return nil
+	}
+
+	if p.curToken.Type == lexer.IDENTIFIER {
+		// 协进程 coproc [NAME] command
+		if p.curToken.Literal == "coproc" {
+			return p.parseCoproc()
 		}
-		
-		// 检查是否是数组赋值 arr=(1 2 3)
-		// 注意：lexer可能将 arr= 识别为一个IDENTIFIER，所以需要检查是否以 = 结尾
-		var isArrayAssignment bool
-		var arrayName string
-		
-		if p.curToken.Type == lexer.IDENTIFIER {
-			// 检查是否是 arr=( 格式（arr= 被识别为一个token）
-			if strings.HasSuffix(p.curToken.Literal, "=") && p.peekToken.Type == lexer.LPAREN {
-				arrayName = strings.TrimSuffix(p.curToken.Literal, "=")
-				isArrayAssignment = true
-			} else if p.peekToken.Type == lexer.LPAREN {
-				// 检查是否是 arr ( 格式（但这种情况应该是函数调用）
-				// 先检查下一个token是否是 )
-				savedCur := p.curToken
-				savedPeek := p.peekToken
-				p.nextToken() // 跳过 (
-				if p.curToken.Type == lexer.RPAREN || 
-				   p.curToken.Type == lexer.IDENTIFIER || 
-				   p.curToken.Type == lexer.STRING ||
-				   p.curToken.Type == lexer.STRING_SINGLE ||
-				   p.curToken.Type == lexer.STRING_DOUBLE ||
-				   p.curToken.Type == lexer.NUMBER {
-					// 这可能是数组赋值 arr(1 2 3)，但Bash不支持这种语法
-					// 恢复状态，继续处理
-					p.curToken = savedCur
-					p.peekToken = savedPeek
-				} else {
-					// 恢复状态
-					p.curToken = savedCur
-					p.peekToken = savedPeek
-				}
-			}
+		// 数组赋值 arr=(1 2 3)（lexer 将 arr= 识别为一个 token）
+		if strings.HasSuffix(p.curToken.Literal, "=") && p.peekToken.Type == lexer.LPAREN && p.peekIsAdjacent() {
+			return p.parseArrayAssignment()
 		}
-		
-		if isArrayAssignment {
-			// 这是数组赋值 arr=(1 2 3) 或 arr=([0]=a [1]=b)
-			stmt := &ArrayAssignmentStatement{
-				Name:          arrayName,
-				Values:        []Expression{},
-				IndexedValues: make(map[string]Expression),
-			}
-			p.nextToken() // 跳过 arr= token
-			p.nextToken() // 跳过 (
-			
-			// 检查是否是带索引的数组赋值 arr=([0]=a [1]=b)
-			hasIndexedValues := false
-			
-			// 解析数组元素
-			for p.curToken.Type != lexer.RPAREN && p.curToken.Type != lexer.EOF {
-				if p.curToken.Type == lexer.RPAREN {
-					break
-				}
-				// 跳过空白字符
-				if p.curToken.Type == lexer.WHITESPACE {
-					p.nextToken()
-					continue
-				}
-				
-				// 检查是否是带索引的元素 [index]=value
-				if p.curToken.Type == lexer.LBRACKET {
-					// 这是带索引的数组元素 [index]=value
-					hasIndexedValues = true
-					p.nextToken() // 跳过 [
-					
-					// 读取索引（可能是数字、字符串或变量）
-					var indexExpr Expression
-					if p.curToken.Type == lexer.NUMBER {
-						indexExpr = p.parseExpression()
-					} else if p.curToken.Type == lexer.IDENTIFIER || 
-					          p.curToken.Type == lexer.STRING ||
-					          p.curToken.Type == lexer.STRING_SINGLE ||
-					          p.curToken.Type == lexer.STRING_DOUBLE ||
-					          p.curToken.Type == lexer.VAR ||
-					          p.curToken.Type == lexer.PARAM_EXPAND {
-						indexExpr = p.parseExpression()
-					} else {
-						// 索引为空，使用下一个可用索引
-						indexExpr = &Identifier{Value: ""}
-					}
-					
-					// 检查是否有 ]
-					if p.curToken.Type != lexer.RBRACKET {
-						// 索引表达式可能包含多个 token，继续读取直到找到 ]
-						for p.curToken.Type != lexer.RBRACKET && p.curToken.Type != lexer.EOF {
-							p.nextToken()
-						}
-					}
-					
-					if p.curToken.Type == lexer.RBRACKET {
-						p.nextToken() // 跳过 ]
-					}
-					
-					// 检查是否有 =（在 lexer 中，单独的 = 会被识别为 ILLEGAL）
-					// 但在数组赋值中，= 可能已经被包含在标识符中（如 arr[0]=value）
-					// 或者下一个 token 是 ILLEGAL（单独的 =）
-					if p.curToken.Type == lexer.ILLEGAL && p.curToken.Literal == "=" {
-						// 单独的 = token
-						p.nextToken() // 跳过 =
-					} else if strings.HasSuffix(p.curToken.Literal, "=") {
-						// token 包含 =（如 arr[0]=value 中的 =）
-						// 这种情况已经在 lexer 中处理了，当前 token 应该是值
-						// 但为了兼容，我们检查一下
-					}
-					
-					// 读取值（如果当前 token 是 =，下一个 token 是值）
-					// 如果当前 token 已经是值（因为 = 被包含在之前的 token 中），直接使用
-					var valueExpr Expression
-					if p.curToken.Type == lexer.ILLEGAL && p.curToken.Literal == "=" {
-						// 刚刚跳过了 =，现在读取值
-						valueExpr = p.parseExpression()
-					} else {
-						// 当前 token 可能就是值，或者需要解析表达式
-						valueExpr = p.parseExpression()
-					}
-					
-					// 将索引转换为字符串（用于 map 的 key）
-					indexStr := ""
-					if indexExpr != nil {
-						// 这里先保存索引表达式，在执行时再求值
-						// 暂时使用 Identifier 来存储索引的字符串表示
-						if ident, ok := indexExpr.(*Identifier); ok {
-							indexStr = ident.Value
-						} else if str, ok := indexExpr.(*StringLiteral); ok {
-							indexStr = str.Value
-						} else if num, ok := indexExpr.(*Identifier); ok && num.Value != "" {
-							// 尝试将数字字符串作为索引
-							indexStr = num.Value
-						} else {
-							// 对于复杂表达式，需要求值
-							// 这里我们创建一个特殊的表达式来标记需要求值
-							indexStr = "__EXPR__"
-						}
-					}
-					
-					// 如果索引字符串为空，表示使用下一个可用索引
-					if indexStr == "" {
-						indexStr = fmt.Sprintf("%d", len(stmt.Values))
-					}
-					
-					stmt.IndexedValues[indexStr] = valueExpr
-				} else {
-					// 普通数组元素（不带索引）
-				stmt.Values = append(stmt.Values, p.parseExpression())
-				}
-				p.nextToken()
-			}
-			
-			if p.curToken.Type == lexer.RPAREN {
-				p.nextToken() // 跳过 )
-			}
-			
-			// 如果使用了带索引的赋值，清空 Values（只使用 IndexedValues）
-			if hasIndexedValues && len(stmt.IndexedValues) > 0 {
-				stmt.Values = nil
-			}
-			
-			return stmt
+		// 函数定义 name() { ... }
+		if !strings.HasSuffix(p.curToken.Literal, "=") && p.peekToken.Type == lexer.LPAREN {
+			return p.parseFunctionDefinition()
 		}
-		
-		return p.parseCommandStatement()
 	}
-}
 
-// parseCommandStatement 解析命令语句
-func (p *Parser) parseCommandStatement() *CommandStatement {
-	stmt := &CommandStatement{}
-
-	// 如果没有有效的命令 token，返回 nil
-	if p.curToken.Type != lexer.IDENTIFIER && 
-	   p.curToken.Type != lexer.STRING &&
-	   p.curToken.Type != lexer.STRING_SINGLE &&
-	   p.curToken.Type != lexer.STRING_DOUBLE &&
-	   p.curToken.Type != lexer.LBRACKET &&
-	   p.curToken.Type != lexer.DBL_LBRACKET &&
-	   p.curToken.Type != lexer.VAR &&
-	   p.curToken.Type != lexer.DOLLAR &&
-	   p.curToken.Type != lexer.COMMAND_SUBSTITUTION &&
-	   p.curToken.Type != lexer.ARITHMETIC_EXPANSION &&
-	   p.curToken.Type != lexer.NUMBER {
+	if !isWordToken(p.curToken.Type) && !isRedirectToken(p.curToken.Type) {
 		return nil
 	}
-	
-	// 检查是否是 case 模式（如 *）后跟 )
-	// 如果是，这不是命令，返回 nil
-	if (p.curToken.Type == lexer.IDENTIFIER || 
-	    p.curToken.Type == lexer.STRING ||
-	    p.curToken.Type == lexer.STRING_SINGLE ||
-	    p.curToken.Type == lexer.STRING_DOUBLE) && 
-	    p.peekToken.Type == lexer.RPAREN {
-		// 这可能是 case 模式，不是命令
-		// 但我们需要在 case 解析上下文中才能确定
-		// 这里先返回 nil，让调用者处理
+	return p.parseCommandStatement()
+}
+
+// parseCoproc 解析协进程 coproc [NAME] command
+// 与 bash 相同，只有命令是复合命令时才能指定名称：coproc 后面的单词之后是复合命令时它是名称，否则是简单命令的命令名
+func (p *Parser) parseCoproc() Statement {
+	stmt := &CoprocStatement{Pos: p.curPos()}
+	p.nextToken() // 跳过 coproc
+	if p.curToken.Type == lexer.IDENTIFIER && isCompoundStart(p.peekToken.Type) {
+		stmt.Name = p.curToken.Literal
+		p.nextToken()
+	}
+	stmt.Statement = p.parseCommand()
+	if stmt.Statement == nil {
+		p.unexpectedToken("")
 		return nil
 	}
+	return stmt
+}
 
-	// 检查是否是变量赋值 VAR=value
-	// 如果当前是标识符，下一个是 ILLEGAL（=），再下一个是值
-	if p.curToken.Type == lexer.IDENTIFIER && p.peekToken.Type == lexer.ILLEGAL {
-		// 检查 ILLEGAL token 是否是 =
-		if p.peekToken.Literal == "=" {
-			// 这是变量赋值，将 VAR=value 作为命令名
-			varName := p.curToken.Literal
-			p.nextToken() // 跳过 VAR
-			p.nextToken() // 跳过 =
-			// 读取值（可能是字符串、标识符、算术展开等）
-			var value strings.Builder
-			for p.curToken.Type != lexer.EOF && 
-			    p.curToken.Type != lexer.NEWLINE && 
-			    p.curToken.Type != lexer.SEMICOLON &&
-			    p.curToken.Type != lexer.WHITESPACE &&
-			    p.curToken.Type != lexer.RPAREN {
-				if p.curToken.Type == lexer.STRING || 
-				   p.curToken.Type == lexer.STRING_SINGLE || 
-				   p.curToken.Type == lexer.STRING_DOUBLE {
-					// 对于字符串 token，需要保留引号以便 executor 正确处理
-					if p.curToken.Type == lexer.STRING_SINGLE {
-						value.WriteString("'")
-						value.WriteString(p.curToken.Literal)
-						value.WriteString("'")
-					} else if p.curToken.Type == lexer.STRING_DOUBLE {
-						value.WriteString("\"")
-						value.WriteString(p.curToken.Literal)
-						value.WriteString("\"")
-					} else {
-						value.WriteString(p.curToken.Literal)
-					}
-				} else if p.curToken.Type == lexer.IDENTIFIER {
-					value.WriteString(p.curToken.Literal)
-				} else if p.curToken.Type == lexer.NUMBER {
-					value.WriteString(p.curToken.Literal)
-				} else if p.curToken.Type == lexer.ARITHMETIC_EXPANSION {
-					// 处理算术展开 $((expr))
-					// lexer 返回的 Literal 只是表达式部分，需要包装成 $((expr)) 格式
-					value.WriteString("$((")
-					value.WriteString(p.curToken.Literal)
-					value.WriteString("))")
-					p.nextToken() // 移动到下一个 token
-					continue
-				} else if p.curToken.Type == lexer.DOLLAR {
-					// 处理 $VAR 或 $((expr)) 的开始
-					// 先检查是否是算术展开 $((expr))
-					if p.peekToken.Type == lexer.LPAREN {
-						peek2 := p.peekToken
-						p.nextToken() // 移动到 (
-						if p.peekToken.Type == lexer.LPAREN {
-							// $((expr)) 算术展开，读取完整的算术展开 token
-							p.curToken = peek2 // 恢复，让 lexer 读取完整的算术展开
-							p.nextToken() // 这会读取 $((expr)) 作为 ARITHMETIC_EXPANSION token
-							if p.curToken.Type == lexer.ARITHMETIC_EXPANSION {
-								value.WriteString("$(((")
-								value.WriteString(p.curToken.Literal)
-								value.WriteString("))")
-								p.nextToken() // 移动到下一个 token
-								continue
-							}
-						} else {
-							// $(command) 命令替换，恢复
-							p.curToken = peek2
-						}
-					}
-					// 普通变量展开 $VAR
-					value.WriteString("$")
-				} else {
-					break
-				}
-				p.nextToken()
-			}
-			// 将 VAR=value 作为命令名
-			stmt.Command = &Identifier{Value: varName + "=" + value.String()}
-			return stmt
+// isCompoundStart 检查 token 是否是复合命令的开始
+func isCompoundStart(t lexer.TokenType) bool {
+	switch t {
+	case lexer.IF, lexer.FOR, lexer.WHILE, lexer.UNTIL, lexer.CASE, lexer.LPAREN, lexer.ARITHMETIC_COMMAND, lexer.LBRACE:
+		return true
+	}
+	return false
+}
+
+// withTrailingRedirects 解析复合命令之后的重定向（如 done < file、} > out），记录到复合命令上
+func (p *Parser) withTrailingRedirects(stmt Statement) Statement {
+	var redirects []*Redirect
+	for isRedirectToken(p.curToken.Type) || p.curToken.Type == lexer.NUMBER && p.isRedirectFD() {
+		redirects = append(redirects, p.parseRedirects()...)
+	}
+	if len(redirects) == 0 {
+		return stmt
+	}
+
+	switch s := stmt.(type) {
+	case *IfStatement:
+		if s != nil {
+			s.Redirects = append(s.Redirects, redirects...)
+		}
+	case *ForStatement:
+		if s != nil {
+			s.Redirects = append(s.Redirects, redirects...)
+		}
+	case *WhileStatement:
+		if s != nil {
+			s.Redirects = append(s.Redirects, redirects...)
+		}
+	case *CaseStatement:
+		if s != nil {
+			s.Redirects = append(s.Redirects, redirects...)
+		}
+	case *SubshellCommand:
+		if s != nil {
+			s.Redirects = append(s.Redirects, redirects...)
+		}
+	case *GroupCommand:
+		if s != nil {
+			s.Redirects = append(s.Redirects, redirects...)
+		}
+	case *ArithmeticCommand:
+		if s != nil {
+			s.Redirects = append(s.Redirects, redirects...)
 		}
 	}
+	return stmt
+}
 
-	// 解析命令（包括 [ 和 [[ 命令）
-	if p.curToken.Type == lexer.LBRACKET {
-		// [ 命令，创建一个标识符表达式
-		stmt.Command = &Identifier{Value: "["}
-	} else if p.curToken.Type == lexer.DBL_LBRACKET {
-		// [[ 命令，创建一个标识符表达式
-		stmt.Command = &Identifier{Value: "[["}
-	} else {
-		stmt.Command = p.parseExpression()
+// parseCommandStatement 解析简单命令（可选的赋值、命令名、参数和重定向）
+func (p *Parser) parseCommandStatement() *CommandStatement {
+	stmt := &CommandStatement{Pos: p.curPos()}
+
+	// 命令前的重定向（如 > file echo hello）
+	for isRedirectToken(p.curToken.Type) || p.curToken.Type == lexer.NUMBER && p.isRedirectFD() {
+		stmt.Redirects = append(stmt.Redirects, p.parseRedirects()...)
+	}
+
+	// 变量赋值 VAR=value，紧跟的赋值同样解析为赋值（如 x=1 y=2）；
+	// 赋值之后有命令名时（VAR=value cmd）赋值只作用于这条命令，没有命令名时 Command 为 nil
+	for p.isAssignmentWord() {
+		stmt.Assignments = append(stmt.Assignments, p.parseAssignmentWord())
 	}
-	p.nextToken()
 
-	// 检查是否是 [[ 命令，需要特殊处理 && 和 ||
-	isDoubleBracket := false
-	if stmt.Command != nil {
-		if ident, ok := stmt.Command.(*Identifier); ok && ident.Value == "[[" {
-			isDoubleBracket = true
+	switch {
+	case len(stmt.Assignments) > 0 && (!isWordToken(p.curToken.Type) || p.curToken.Type == lexer.NUMBER && p.isRedirectFD()):
+		// 只有赋值的命令，之后可以有重定向（重定向之后的单词是命令名）
+	case p.curToken.Type == lexer.IDENTIFIER && strings.HasSuffix(p.curToken.Literal, "]=") && strings.Contains(p.curToken.Literal, "["),
+		p.curToken.Type == lexer.IDENTIFIER && strings.HasSuffix(p.curToken.Literal, "]+=") && strings.Contains(p.curToken.Literal, "["):
+		// 数组元素赋值 arr[key]=value 或追加 arr[key]+=value，值作为第一个参数
+		stmt.Command = &Identifier{Value: p.curToken.Literal}
+		adjacent := p.peekIsAdjacent() && isWordToken(p.peekToken.Type)
+		p.nextToken()
+		if adjacent {
+			stmt.Args = append(stmt.Args, p.parseWord())
+		}
+	case p.curToken.Type == lexer.DBL_LBRACKET:
+		// [[ 命令，条件表达式解析为语法树，]] 之后只能有重定向
+		stmt.Command = &Identifier{Value: "[["}
+		stmt.Cond = p.parseCondCommand()
+		for isRedirectToken(p.curToken.Type) || p.curToken.Type == lexer.NUMBER && p.isRedirectFD() {
+			stmt.Redirects = append(stmt.Redirects, p.parseRedirects()...)
 		}
+		return stmt
+	case isWordToken(p.curToken.Type):
+		stmt.Command = p.parseWord()
+	default:
+		// 只有重定向的命令
+		if len(stmt.Redirects) == 0 {
+			return nil
+		}
+		return stmt
 	}
-	
+
 	// 解析参数和重定向
-	for p.curToken.Type != lexer.EOF && 
-		p.curToken.Type != lexer.SEMICOLON &&
-		p.curToken.Type != lexer.NEWLINE &&
-		p.curToken.Type != lexer.PIPE &&
-		p.curToken.Type != lexer.AMPERSAND &&
-		p.curToken.Type != lexer.THEN &&
-		p.curToken.Type != lexer.DO &&
-		p.curToken.Type != lexer.DONE &&
-		p.curToken.Type != lexer.FI &&
-		p.curToken.Type != lexer.ELSE &&
-		p.curToken.Type != lexer.ELIF {
-		
-		// 如果遇到换行符，立即停止解析参数
-		if p.curToken.Type == lexer.NEWLINE {
-			break
+	for {
+		if isRedirectToken(p.curToken.Type) || p.curToken.Type == lexer.NUMBER && p.isRedirectFD() {
+			stmt.Redirects = append(stmt.Redirects, p.parseRedirects()...)
+			continue
 		}
-		
-		// 对于非 [[ 命令，遇到 && 或 || 时停止（这些是命令分隔符）
-		if !isDoubleBracket && (p.curToken.Type == lexer.AND || p.curToken.Type == lexer.OR) {
-			break
+		if isWordToken(p.curToken.Type) && stmt.Command == nil {
+			stmt.Command = p.parseWord()
+			continue
+		}
+		if isWordToken(p.curToken.Type) {
+			stmt.Args = append(stmt.Args, p.parseWord())
+			continue
 		}
-		
-		// 检查是否是 [ 或 [[ 命令的结束括号
-		if p.curToken.Type == lexer.RBRACKET {
-			// 将 ] 作为参数添加（test命令需要它）
-			stmt.Args = append(stmt.Args, &Identifier{Value: "]"})
-			// 不调用 p.nextToken()，让调用者处理 ] 之后的 token
+		break
+	}
+
+	return stmt
+}
+
+// isAssignmentWord 检查当前 token 是否是变量赋值 VAR=value 或 VAR+=value 的开始（lexer 将 VAR+ 作为一个 token）
+func (p *Parser) isAssignmentWord() bool {
+	return p.curToken.Type == lexer.IDENTIFIER &&
+		isValidName(strings.TrimSuffix(p.curToken.Literal, "+")) &&
+		p.peekToken.Type == lexer.ILLEGAL && p.peekToken.Literal == "=" &&
+		p.peekIsAdjacent()
+}
+
+// parseAssignmentWord 解析变量赋值 VAR=value 或 VAR+=value
+// 值与命令参数一样按片段解析，保留每个片段是否带引号，由执行器展开
+func (p *Parser) parseAssignmentWord() *AssignmentWord {
+	name, appended := strings.CutSuffix(p.curToken.Literal, "+")
+	assign := &AssignmentWord{Name: name, Append: appended}
+	p.nextToken() // 跳过变量名
+
+	adjacent := p.peekIsAdjacent() && isWordToken(p.peekToken.Type)
+	p.nextToken() // 跳过 =
+	if adjacent {
+		assign.Value = p.parseWord()
+	}
+	return assign
+}
+
+// parseArrayAssignment 解析数组赋值 arr=(1 2 3)、arr=([0]=a [1]=b) 或追加元素的 arr+=(4 5)
+func (p *Parser) parseArrayAssignment() *ArrayAssignmentStatement {
+	name, appended := strings.CutSuffix(strings.TrimSuffix(p.curToken.Literal, "="), "+")
+	stmt := &ArrayAssignmentStatement{
+		Name:          name,
+		Append:        appended,
+		Values:        []Expression{},
+		IndexedValues: make(map[string]Expression),
+		Pos:           p.curPos(),
+	}
+	p.nextToken() // 跳过 arr=
+	openToken := p.curToken
+	p.nextToken() // 跳过 (
+
+	hasIndexedValues := false
+	for {
+		p.skipNewlines()
+		if p.curToken.Type == lexer.RPAREN {
+			p.nextToken() // 跳过 )
 			break
 		}
-		if p.curToken.Type == lexer.DBL_RBRACKET {
-			// 将 ]] 作为参数添加（[[命令需要它）
-			stmt.Args = append(stmt.Args, &Identifier{Value: "]]"})
-			// 不调用 p.nextToken()，让调用者处理 ]] 之后的 token
+		if p.curToken.Type == lexer.EOF {
+			p.addUnclosedError(ErrorTypeUnclosedParen, openToken, ")")
 			break
 		}
-		
-		// 对于 [[ 命令，将 && 和 || 作为参数
-		if isDoubleBracket && (p.curToken.Type == lexer.AND || p.curToken.Type == lexer.OR) {
-			op := "&&"
-			if p.curToken.Type == lexer.OR {
-				op = "||"
+
+		if p.curToken.Type == lexer.LBRACKET {
+			// 带索引的数组元素 [index]=value
+			hasIndexedValues = true
+			p.nextToken() // 跳过 [
+			start := p.curToken.Pos
+			var indexTokens []lexer.Token
+			for p.curToken.Type != lexer.RBRACKET && p.curToken.Type != lexer.EOF &&
+				p.curToken.Type != lexer.NEWLINE && p.curToken.Type != lexer.RPAREN {
+				indexTokens = append(indexTokens, p.curToken)
+				p.nextToken()
 			}
-			stmt.Args = append(stmt.Args, &Identifier{Value: op})
-			p.nextToken()
+			indexStr := strings.TrimSpace(p.l.Input()[start:p.curToken.Pos])
+			if len(indexTokens) == 1 {
+				// 单个 token（包括带引号的键）直接使用其字面量
+				indexStr = indexTokens[0].Literal
+			}
+			if p.curToken.Type != lexer.RBRACKET {
+				p.unexpectedToken("]")
+				continue
+			}
+
+			valueAdjacent := false
+			if p.peekToken.Type == lexer.ILLEGAL && p.peekToken.Literal == "=" {
+				p.nextToken() // 跳过 ]
+				valueAdjacent = p.peekIsAdjacent() && isWordToken(p.peekToken.Type)
+			}
+			p.nextToken() // 跳过 ] 或 =
+
+			var valueExpr Expression = &Identifier{Value: ""}
+			if valueAdjacent {
+				valueExpr = p.parseWord()
+			}
+
+			// 如果索引字符串为空，表示使用下一个可用索引
+			if indexStr == "" {
+				indexStr = strconv.Itoa(len(stmt.Values))
+			}
+			stmt.IndexedValues[indexStr] = valueExpr
 			continue
 		}
-		
-			// 检查重定向（包括所有重定向类型）
-		if p.curToken.Type == lexer.REDIRECT_OUT ||
-		   p.curToken.Type == lexer.REDIRECT_IN ||
-		   p.curToken.Type == lexer.REDIRECT_APPEND ||
-		   p.curToken.Type == lexer.REDIRECT_HEREDOC ||
-		   p.curToken.Type == lexer.REDIRECT_HEREDOC_STRIP ||
-		   p.curToken.Type == lexer.REDIRECT_HEREDOC_TABS ||
-		   p.curToken.Type == lexer.REDIRECT_DUP_IN ||
-		   p.curToken.Type == lexer.REDIRECT_DUP_OUT ||
-		   p.curToken.Type == lexer.REDIRECT_CLOBBER ||
-		   p.curToken.Type == lexer.REDIRECT_RW {
-			redirect := p.parseRedirect()
-			if redirect != nil {
-				stmt.Redirects = append(stmt.Redirects, redirect)
-			}
-			p.nextToken()
+
+		if isWordToken(p.curToken.Type) {
+			stmt.Values = append(stmt.Values, p.parseWord())
 			continue
 		}
-		
-		// 解析参数
-		// 注意：关键字（如 case、if、for 等）在命令参数位置时应该被当作普通标识符处理
-		if p.curToken.Type == lexer.IDENTIFIER || 
-		   p.curToken.Type == lexer.STRING ||
-		   p.curToken.Type == lexer.STRING_SINGLE ||
-		   p.curToken.Type == lexer.STRING_DOUBLE ||
-		   p.curToken.Type == lexer.VAR ||
-		   p.curToken.Type == lexer.DOLLAR ||
-		   p.curToken.Type == lexer.COMMAND_SUBSTITUTION ||
-		   p.curToken.Type == lexer.ARITHMETIC_EXPANSION ||
-		   p.curToken.Type == lexer.NUMBER ||
-		   p.curToken.Type == lexer.CASE ||
-		   p.curToken.Type == lexer.IF ||
-		   p.curToken.Type == lexer.THEN ||
-		   p.curToken.Type == lexer.ELSE ||
-		   p.curToken.Type == lexer.ELIF ||
-		   p.curToken.Type == lexer.FI ||
-		   p.curToken.Type == lexer.FOR ||
-		   p.curToken.Type == lexer.WHILE ||
-		   p.curToken.Type == lexer.DO ||
-		   p.curToken.Type == lexer.DONE ||
-		   p.curToken.Type == lexer.ESAC ||
-		   p.curToken.Type == lexer.FUNCTION ||
-		   p.curToken.Type == lexer.IN ||
-		   p.curToken.Type == lexer.SELECT ||
-		   p.curToken.Type == lexer.TIME {
-			stmt.Args = append(stmt.Args, p.parseExpression())
-			// parseExpression 不移动 token，所以 curToken 仍然是当前参数 token
-			// 移动到下一个 token
+
+		p.unexpectedToken(")")
+		p.nextToken()
+	}
+
+	// 如果使用了带索引的赋值，清空 Values（只使用 IndexedValues）
+	if hasIndexedValues && len(stmt.IndexedValues) > 0 {
+		stmt.Values = nil
+	}
+
+	return stmt
+}
+
+// parseWord 解析一个单词
+// 单词由一个或多个相邻（中间没有空白）的片段组成，如 foo$bar、"a"'b'；
+// 只有一个片段时直接返回该片段，否则返回 ConcatExpression
+func (p *Parser) parseWord() Expression {
+	var parts []Expression
+	for {
+		switch {
+		case p.curToken.Type == lexer.ESCAPE:
+			// 输入末尾单独的反斜杠，忽略
+		case p.curToken.Type == lexer.IDENTIFIER && strings.HasSuffix(p.curToken.Literal, "=") &&
+			p.peekToken.Type == lexer.LPAREN && p.peekIsAdjacent():
+			// 参数中的数组字面量，如 declare -a arr=(1 2 3)，作为一个整体保留原始文本
+			start := p.curToken.Pos
 			p.nextToken()
-			// 检查当前 token 是否是换行符或语句结束标记
-			if p.curToken.Type == lexer.NEWLINE ||
-			   p.curToken.Type == lexer.SEMICOLON ||
-			   p.curToken.Type == lexer.FI ||
-			   p.curToken.Type == lexer.DONE ||
-			   p.curToken.Type == lexer.ELSE ||
-			   p.curToken.Type == lexer.ELIF ||
-			   p.curToken.Type == lexer.ESAC ||
-			   p.curToken.Type == lexer.EOF {
-				// 遇到换行符或结束标记，停止解析
-				break
-			}
-			// 继续解析下一个参数
-			continue
+			p.skipBalancedParens()
+			parts = append(parts, &Identifier{Value: p.l.Input()[start:p.curToken.End]})
+		default:
+			parts = append(parts, p.parseWordPart())
 		}
-		
-		// 如果不是参数类型的 token，移动到下一个
+
+		adjacent := p.peekIsAdjacent() && isWordToken(p.peekToken.Type) &&
+			!(p.inDoubleBracket && p.peekToken.Type == lexer.DBL_RBRACKET)
 		p.nextToken()
+		if !adjacent {
+			break
+		}
 	}
 
-	// 解析管道
-	if p.curToken.Type == lexer.PIPE {
-		p.nextToken() // 跳过 |
-		stmt.Pipe = p.parseCommandStatement()
-		return stmt
+	switch len(parts) {
+	case 0:
+		return &Identifier{Value: ""}
+	case 1:
+		return parts[0]
+	default:
+		return &ConcatExpression{Parts: parts}
 	}
+}
 
-	// 检查后台执行
-	if p.curToken.Type == lexer.AMPERSAND {
-		stmt.Background = true
+// parseWordPart 解析单词中的一个片段（不移动 token）
+func (p *Parser) parseWordPart() Expression {
+	switch p.curToken.Type {
+	case lexer.DOLLAR:
+		// 单独的 $ 按字面意义处理
+		return &Identifier{Value: "$"}
+	case lexer.LBRACKET, lexer.RBRACKET, lexer.DBL_LBRACKET, lexer.DBL_RBRACKET,
+		lexer.LBRACE, lexer.RBRACE, lexer.BREAK, lexer.CONTINUE:
+		return &Identifier{Value: p.curToken.Literal}
+	default:
+		return p.parseExpression()
 	}
+}
 
-	return stmt
+// skipBalancedParens 跳过一对匹配的括号（当前 token 为左括号），结束时当前 token 为右括号
+func (p *Parser) skipBalancedParens() {
+	openToken := p.curToken
+	depth := 0
+	for {
+		switch p.curToken.Type {
+		case lexer.LPAREN:
+			depth++
+		case lexer.RPAREN:
+			depth--
+			if depth == 0 {
+				return
+			}
+		case lexer.EOF:
+			p.addUnclosedError(ErrorTypeUnclosedParen, openToken, ")")
+			return
+		}
+		p.nextToken()
+	}
+}
+
+// isRedirectFD 检查当前数字 token 是否是重定向的文件描述符（如 2>file 中的 2）
+func (p *Parser) isRedirectFD() bool {
+	return isRedirectToken(p.peekToken.Type) && p.peekIsAdjacent()
+}
+
+// parseRedirects 解析一个重定向（可能带文件描述符），结束时当前 token 为重定向之后的 token
+// &> 和 &>> 会展开为两个重定向（标准输出重定向到文件，标准错误复制到标准输出）
+func (p *Parser) parseRedirects() []*Redirect {
+	fd := -1
+	if p.curToken.Type == lexer.NUMBER {
+		fd, _ = strconv.Atoi(p.curToken.Literal)
+		p.nextToken() // 跳过文件描述符
+	}
+
+	bothOutputs := p.curToken.Type == lexer.AND_GREATER || p.curToken.Type == lexer.AND_GREATER_GREATER
+	redirect := p.parseRedirect()
+	if redirect == nil {
+		return nil
+	}
+	if fd >= 0 {
+		redirect.FD = fd
+	}
+	if bothOutputs {
+		return []*Redirect{redirect, {Type: REDIRECT_DUP_OUT, FD: 2, Target: &Identifier{Value: "1"}}}
+	}
+	return []*Redirect{redirect}
 }
 
 // parseRedirect 解析重定向
+// 调用时当前 token 为重定向操作符，返回时当前 token 为重定向目标之后的 token
 func (p *Parser) parseRedirect() *Redirect {
 	redirect := &Redirect{
 		FD: 1, // 默认stdout
 	}
+	opToken := p.curToken
 
 	switch p.curToken.Type {
 	case lexer.REDIRECT_OUT:
@@ -640,6 +636,10 @@ func (p *Parser) parseRedirect() *Redirect {
 				redirect.FD = int(p.curToken.Literal[0] - '0')
 			}
 		}
+	case lexer.AND_GREATER:
+		redirect.Type = REDIRECT_OUTPUT
+	case lexer.AND_GREATER_GREATER:
+		redirect.Type = REDIRECT_APPEND
 	case lexer.REDIRECT_HEREDOC:
 		redirect.Type = REDIRECT_HEREDOC
 		redirect.FD = 0
@@ -670,43 +670,174 @@ func (p *Parser) parseRedirect() *Redirect {
 
 	// 读取目标文件或 Here-document 分隔符
 	p.nextToken()
-	
+
+	if !isWordToken(p.curToken.Type) {
+		// 重定向目标缺失
+		if p.curToken.Type == lexer.EOF {
+			p.addError(ErrorTypeMissingToken, i18n.T("重定向缺少目标"), opToken, i18n.T("文件名"))
+		} else {
+			p.unexpectedToken("")
+		}
+		return nil
+	}
+
 	// 对于 Here-document，分隔符可能是带引号的
-	if redirect.Type == REDIRECT_HEREDOC || redirect.Type == REDIRECT_HEREDOC_STRIP {
-		if redirect.HereDoc != nil {
-			// 检查分隔符是否带引号
+	if redirect.HereDoc != nil {
+		// 分隔符可能由多个相邻片段组成（如 \EOF、E"OF"），
+		// 任一片段带引号时不展开正文中的变量
+		// 先登记 here-document：读取分隔符后面的换行时，词法分析器随即给出正文
+		p.pendingHereDocs = append(p.pendingHereDocs, redirect.HereDoc)
+		var delimiter strings.Builder
+		for {
 			if p.curToken.Type == lexer.STRING_SINGLE || p.curToken.Type == lexer.STRING_DOUBLE {
 				redirect.HereDoc.Quoted = true
-				// 提取分隔符（移除引号）
-				expr := p.parseExpression()
-				if str, ok := expr.(*StringLiteral); ok {
-					redirect.HereDoc.Delimiter = str.Value
-				} else if ident, ok := expr.(*Identifier); ok {
-					redirect.HereDoc.Delimiter = ident.Value
-				}
-			} else if p.curToken.Type == lexer.IDENTIFIER {
-				redirect.HereDoc.Quoted = false
-				expr := p.parseExpression()
-				if ident, ok := expr.(*Identifier); ok {
-					redirect.HereDoc.Delimiter = ident.Value
-				}
 			}
-			// Here-document 的内容将在执行时读取
-			redirect.Target = nil
+			if p.curToken.Type == lexer.STRING_DOUBLE {
+				delimiter.WriteString(lexer.UnescapeDoubleQuoted(p.curToken.Literal))
+			} else {
+				delimiter.WriteString(p.curToken.Literal)
+			}
+			adjacent := p.peekIsAdjacent() && isWordToken(p.peekToken.Type)
+			p.nextToken()
+			if !adjacent {
+				break
+			}
 		}
-	} else if p.curToken.Type == lexer.IDENTIFIER || 
-	   p.curToken.Type == lexer.STRING ||
-	   p.curToken.Type == lexer.STRING_SINGLE ||
-	   p.curToken.Type == lexer.STRING_DOUBLE {
-		redirect.Target = p.parseExpression()
-	} else {
-		// 重定向目标缺失
-		return nil
+		redirect.HereDoc.Delimiter = delimiter.String()
+		// Here-document 的正文在下一个换行之后，由 nextToken 填充
+		redirect.Target = nil
+		return redirect
 	}
 
+	redirect.Target = p.parseWord()
 	return redirect
 }
 
+
+// skipNewlines 跳过连续的换行
+func (p *Parser) skipNewlines() {
+	for p.curToken.Type == lexer.NEWLINE {
+		p.nextToken()
+	}
+}
+
+// peekIsAdjacent 检查下一个 token 是否紧跟在当前 token 之后（中间没有空白）
+func (p *Parser) peekIsAdjacent() bool {
+	return p.peekToken.Pos == p.curToken.End
+}
+
+// unexpectedToken 报告当前 token 出现在不合法的位置
+func (p *Parser) unexpectedToken(expected string) {
+	tok := p.curToken
+	switch tok.Type {
+	case lexer.EOF:
+		p.addError(ErrorTypeUnexpectedEOF, i18n.T("意外的文件结束"), tok, expected)
+		return
+	case lexer.NEWLINE:
+		tok.Literal = "newline"
+	}
+	p.addError(ErrorTypeUnexpectedToken, i18n.T("意外的 token"), tok, expected)
+}
+
+// expectClosing 检查当前 token 是否是期望的结束 token（如 fi、done、)），是则跳过
+// 到达文件结束时报告 open 开始的结构未闭合，否则报告意外的 token
+func (p *Parser) expectClosing(tokenType lexer.TokenType, closing string, open lexer.Token, errType ErrorType) bool {
+	if p.curToken.Type == tokenType {
+		p.nextToken()
+		return true
+	}
+	if p.curToken.Type == lexer.EOF {
+		p.addUnclosedError(errType, open, closing)
+	} else {
+		p.unexpectedToken(closing)
+	}
+	return false
+}
+
+// recoverFromStatementError 跳过出错的 token，直到下一个语句分隔符
+func (p *Parser) recoverFromStatementError() {
+	if p.curToken.Type == lexer.EOF {
+		return
+	}
+	bad := p.curToken.Type
+	p.nextToken()
+	if bad == lexer.SEMICOLON || bad == lexer.NEWLINE || bad == lexer.AMPERSAND {
+		return
+	}
+	for p.curToken.Type != lexer.NEWLINE && p.curToken.Type != lexer.SEMICOLON && p.curToken.Type != lexer.EOF {
+		p.nextToken()
+	}
+	if p.curToken.Type != lexer.EOF {
+		p.nextToken()
+	}
+}
+
+// isTokenIn 检查 token 类型是否在列表中
+func isTokenIn(t lexer.TokenType, list []lexer.TokenType) bool {
+	for _, item := range list {
+		if t == item {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordToken 检查 token 是否可以作为单词（命令名、参数、重定向目标）的一部分
+func isWordToken(t lexer.TokenType) bool {
+	switch t {
+	case lexer.IDENTIFIER, lexer.STRING, lexer.STRING_SINGLE, lexer.STRING_DOUBLE, lexer.NUMBER,
+		lexer.VAR, lexer.DOLLAR, lexer.PARAM_EXPAND, lexer.STRING_DOLLAR_SINGLE, lexer.STRING_DOLLAR_DOUBLE,
+		lexer.COMMAND_SUBSTITUTION, lexer.ARITHMETIC_EXPANSION,
+		lexer.PROCESS_SUBSTITUTION_IN, lexer.PROCESS_SUBSTITUTION_OUT,
+		lexer.ILLEGAL, lexer.ESCAPE,
+		lexer.IF, lexer.THEN, lexer.ELSE, lexer.ELIF, lexer.FI, lexer.FOR, lexer.WHILE, lexer.UNTIL, lexer.DO, lexer.DONE,
+		lexer.CASE, lexer.ESAC, lexer.FUNCTION, lexer.BREAK, lexer.CONTINUE, lexer.IN, lexer.SELECT, lexer.TIME,
+		lexer.LBRACE, lexer.RBRACE, lexer.LBRACKET, lexer.RBRACKET, lexer.DBL_LBRACKET, lexer.DBL_RBRACKET:
+		return true
+	}
+	return false
+}
+
+// isRedirectToken 检查 token 是否是重定向操作符
+func isRedirectToken(t lexer.TokenType) bool {
+	switch t {
+	case lexer.REDIRECT_OUT, lexer.REDIRECT_IN, lexer.REDIRECT_APPEND,
+		lexer.REDIRECT_HEREDOC, lexer.REDIRECT_HEREDOC_STRIP, lexer.REDIRECT_HEREDOC_TABS,
+		lexer.REDIRECT_DUP_IN, lexer.REDIRECT_DUP_OUT, lexer.REDIRECT_CLOBBER, lexer.REDIRECT_RW,
+		lexer.AND_GREATER, lexer.AND_GREATER_GREATER:
+		return true
+	}
+	return false
+}
+
+// isValidName 检查字符串是否是合法的变量名
+func isValidName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, ch := range s {
+		if ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || i > 0 && ch >= '0' && ch <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// Errors 返回解析错误（字符串列表，保持向后兼容）
+func (p *Parser) Errors() []string {
+	return p.errors
+}
+
+// ParseErrors 返回结构化解析错误列表
+func (p *Parser) ParseErrors() []*ParseError {
+	return p.parseErrors
+}
+
+// HasErrors 检查是否有解析错误
+func (p *Parser) HasErrors() bool {
+	return len(p.errors) > 0 || len(p.parseErrors) > 0
+}
 // parseExpression 解析表达式
 func (p *Parser) parseExpression() Expression {
 	switch p.curToken.Type {
@@ -714,7 +845,7 @@ func (p *Parser) parseExpression() Expression {
 		return &Identifier{Value: p.curToken.Literal}
 	// 关键字在表达式上下文中应该被当作普通标识符处理
 	case lexer.CASE, lexer.IF, lexer.THEN, lexer.ELSE, lexer.ELIF, lexer.FI,
-		 lexer.FOR, lexer.WHILE, lexer.DO, lexer.DONE, lexer.ESAC,
+		 lexer.FOR, lexer.WHILE, lexer.UNTIL, lexer.DO, lexer.DONE, lexer.ESAC,
 		 lexer.FUNCTION, lexer.IN, lexer.SELECT, lexer.TIME:
 		return &Identifier{Value: p.curToken.Literal}
 	case lexer.STRING, lexer.STRING_SINGLE, lexer.STRING_DOUBLE:
@@ -756,6 +887,36 @@ func (p *Parser) parseExpression() Expression {
 	}
 }
 
+// ParseParamExpand 解析 ${...} 中的内容（不包含 ${ 和 }），执行器展开双引号字符串中的参数展开时使用
+func ParseParamExpand(expr string) *ParamExpandExpression {
+	return (&Parser{}).parseParamExpand(expr)
+}
+
+// paramNameEnd 返回参数展开表达式开头的参数名（可以带间接引用的 !）的结束位置：
+// 变量名、位置参数的数字或一个特殊参数字符，没有参数名时返回 0
+func paramNameEnd(expr string) int {
+	start := 0
+	if start < len(expr) && expr[start] == '!' {
+		start++
+	}
+	end := start
+	for end < len(expr) && isValidName(expr[start:end+1]) {
+		end++
+	}
+	if end == start {
+		for end < len(expr) && expr[end] >= '0' && expr[end] <= '9' {
+			end++
+		}
+	}
+	if end == start && end < len(expr) && strings.IndexByte("@*#?-$!", expr[end]) >= 0 {
+		end++
+	}
+	if end == start {
+		return 0
+	}
+	return end
+}
+
 // parseParamExpand 解析参数展开表达式
 // 例如：${VAR:-default}, ${VAR#pattern}, ${VAR:offset:length} 等
 func (p *Parser) parseParamExpand(expr string) *ParamExpandExpression {
@@ -769,7 +930,7 @@ func (p *Parser) parseParamExpand(expr string) *ParamExpandExpression {
 	// 以及数组访问 [index]
 	
 	// 先检查是否是数组访问
-	if idx := strings.Index(expr, "["); idx != -1 {
+	if idx := strings.Index(expr, "["); idx != -1 && isValidName(strings.TrimLeft(expr[:idx], "#!")) {
 		// 数组访问，如 arr[0] 或 arr[key]
 		pe.VarName = expr[:idx]
 		// 数组索引部分将在变量展开时处理
@@ -777,24 +938,30 @@ func (p *Parser) parseParamExpand(expr string) *ParamExpandExpression {
 		return pe
 	}
 	
-	// 检查操作符
+	// ${#VAR} 是字符串长度，变量名保留 # 前缀（与 ${#arr[@]} 的变量名 #arr 一致），由执行器处理
+	if len(expr) > 1 && expr[0] == '#' {
+		pe.VarName = expr
+		return pe
+	}
+
+	// 检查操作符：操作符紧跟在变量名之后，模式中的 #、%、/ 不是操作符（${#} 是参数个数，开头的 # 不是操作符）
 	ops := []string{"##", "#", "%%", "%", ":=", ":-", ":?", ":+", "::", ":", "//", "/", "^^", "^", ",,", ","}
-	for _, op := range ops {
-		if idx := strings.Index(expr, op); idx != -1 {
-			pe.VarName = expr[:idx]
-			pe.Op = op
-			pe.Word = expr[idx+len(op):]
-			return pe
+	if end := paramNameEnd(expr); end > 0 {
+		for _, op := range ops {
+			if strings.HasPrefix(expr[end:], op) {
+				pe.VarName = expr[:end]
+				pe.Op = op
+				pe.Word = expr[end+len(op):]
+				// ${VAR/#pattern/string} 和 ${VAR/%pattern/string} 的模式锚定在开头和结尾
+				if op == "/" && pe.Word != "" && (pe.Word[0] == '#' || pe.Word[0] == '%') {
+					pe.Op = "/" + pe.Word[:1]
+					pe.Word = pe.Word[1:]
+				}
+				return pe
+			}
 		}
 	}
 	
-	// 检查是否是 ${#VAR} 格式（字符串长度）
-	if len(expr) > 0 && expr[0] == '#' {
-		pe.VarName = expr[1:]
-		pe.Op = "#"
-		return pe
-	}
-	
 	// 检查是否是 ${!VAR} 格式（间接引用）
 	if len(expr) > 0 && expr[0] == '!' {
 		pe.VarName = expr[1:]
@@ -807,443 +974,3 @@ func (p *Parser) parseParamExpand(expr string) *ParamExpandExpression {
 	return pe
 }
 
-// parseIfStatement 解析if语句
-func (p *Parser) parseIfStatement() *IfStatement {
-	stmt := &IfStatement{}
-
-	p.nextToken() // 跳过 if
-
-	// 解析条件（跳过可能的分号）
-	if p.curToken.Type == lexer.SEMICOLON {
-		p.nextToken()
-	}
-	
-	// 解析条件
-	stmt.Condition = p.parseCommandStatement()
-	
-	// 如果条件后面有分号，跳过它
-	if p.curToken.Type == lexer.SEMICOLON {
-		p.nextToken()
-	}
-
-	if p.peekToken.Type == lexer.THEN {
-		p.nextToken() // 跳过 then
-	}
-
-	p.nextToken()
-	// consequence 块应该在遇到 elif 或 else 时停止（但需要考虑嵌套的 if）
-	// 使用专门的函数来解析，能够正确处理嵌套的 if 语句
-	stmt.Consequence = p.parseIfConsequence()
-
-	// 解析elif
-	for p.peekToken.Type == lexer.ELIF {
-		p.nextToken() // 跳过 elif
-		condition := p.parseCommandStatement()
-		if p.peekToken.Type == lexer.THEN {
-			p.nextToken()
-		}
-		p.nextToken()
-		// 对于 elif 的 consequence，需要在遇到 else 或下一个 elif 时停止
-		// 使用专门的函数来解析，能够正确处理嵌套的 if 语句
-		consequence := p.parseIfConsequence()
-		stmt.Elif = append(stmt.Elif, &ElifClause{
-			Condition:   condition,
-			Consequence: consequence,
-		})
-	}
-
-	// 解析else
-	if p.peekToken.Type == lexer.ELSE {
-		p.nextToken() // 跳过 else
-		p.nextToken()
-		// else 块应该在 FI 时停止，使用专门的函数来解析，能够正确处理嵌套的 if 语句
-		stmt.Alternative = p.parseIfAlternative()
-	}
-
-	// 检查并跳过 fi
-	// 注意：parseBlockStatement 在遇到 FI 时会停止，所以 curToken 应该在 FI 上
-	// 跳过空白字符
-	for p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-		p.nextToken()
-	}
-	if p.curToken.Type == lexer.FI {
-		p.nextToken() // 跳过 fi
-	} else if p.peekToken.Type == lexer.FI {
-		p.nextToken() // 跳过 fi
-	} else if p.curToken.Type != lexer.EOF && p.curToken.Type != lexer.RBRACE {
-		// 未闭合的 if 语句（但如果在 RBRACE 上，可能是函数体结束）
-		p.addError(ErrorTypeUnclosedControlFlow, "if 语句未闭合，缺少 fi", p.curToken, "fi")
-	}
-
-	return stmt
-}
-
-// parseForStatement 解析for循环
-func (p *Parser) parseForStatement() *ForStatement {
-	stmt := &ForStatement{}
-
-	p.nextToken() // 跳过 for
-
-	if p.peekToken.Type == lexer.IDENTIFIER {
-		p.nextToken()
-		stmt.Variable = p.curToken.Literal
-	}
-
-	if p.peekToken.Type == lexer.IN {
-		p.nextToken() // 跳过 in
-		// 解析列表
-		for p.peekToken.Type != lexer.DO && p.peekToken.Type != lexer.SEMICOLON {
-			p.nextToken()
-			if p.curToken.Type == lexer.IDENTIFIER || p.curToken.Type == lexer.STRING {
-				stmt.In = append(stmt.In, p.parseExpression())
-			}
-		}
-	}
-
-	if p.peekToken.Type == lexer.DO {
-		p.nextToken() // 跳过 do
-	}
-
-	p.nextToken()
-	stmt.Body = p.parseBlockStatement()
-
-	if p.peekToken.Type == lexer.DONE {
-		p.nextToken() // 跳过 done
-	} else if p.curToken.Type != lexer.EOF {
-		// 未闭合的 for 循环
-		p.addError(ErrorTypeUnclosedControlFlow, "for 循环未闭合，缺少 done", p.curToken, "done")
-	}
-
-	return stmt
-}
-
-// parseWhileStatement 解析while循环
-func (p *Parser) parseWhileStatement() *WhileStatement {
-	stmt := &WhileStatement{}
-
-	p.nextToken() // 跳过 while
-
-	stmt.Condition = p.parseCommandStatement()
-	
-	// 如果parseCommandStatement在遇到]]后break，curToken仍然停留在]]上
-	// 需要移动到下一个token（可能是分号或换行符）
-	if p.curToken.Type == lexer.DBL_RBRACKET {
-		p.nextToken()
-	}
-
-	// 跳过可能的分号和换行
-	// 注意：如果curToken是EOF，说明字符串已经结束，无法继续解析
-	if p.curToken.Type == lexer.EOF {
-		// 如果已经是EOF，说明传递给解析器的字符串不完整
-		// 这种情况下，循环体将为空，但我们应该继续解析（让调用者处理）
-	} else {
-		for p.curToken.Type == lexer.SEMICOLON || p.curToken.Type == lexer.NEWLINE || p.curToken.Type == lexer.WHITESPACE {
-			p.nextToken()
-			if p.curToken.Type == lexer.EOF {
-				break
-			}
-		}
-	}
-
-	// 检查是否有 do 关键字
-	if p.curToken.Type == lexer.DO {
-		p.nextToken() // 跳过 do
-	} else if p.peekToken.Type == lexer.DO {
-		p.nextToken() // 跳过 do
-	}
-
-	// 跳过可能的分号和换行
-	for p.curToken.Type == lexer.SEMICOLON || p.curToken.Type == lexer.NEWLINE || p.curToken.Type == lexer.WHITESPACE {
-		p.nextToken()
-	}
-
-	stmt.Body = p.parseBlockStatement()
-
-	// 跳过可能的分号和换行
-	for p.curToken.Type == lexer.SEMICOLON || p.curToken.Type == lexer.NEWLINE || p.curToken.Type == lexer.WHITESPACE {
-		p.nextToken()
-	}
-
-	// 检查并跳过 done
-	if p.curToken.Type == lexer.DONE {
-		p.nextToken() // 跳过 done
-	} else if p.peekToken.Type == lexer.DONE {
-		p.nextToken() // 跳过 done
-	} else if p.curToken.Type != lexer.EOF {
-		// 未闭合的 while 循环
-		p.addError(ErrorTypeUnclosedControlFlow, "while 循环未闭合，缺少 done", p.curToken, "done")
-	}
-
-	return stmt
-}
-
-// parseFunctionStatement 解析函数定义
-func (p *Parser) parseFunctionStatement() *FunctionStatement {
-	stmt := &FunctionStatement{}
-
-	p.nextToken() // 跳过 function
-
-	// 函数名可能是 function name 或 name()
-	if p.peekToken.Type == lexer.IDENTIFIER {
-		p.nextToken()
-		stmt.Name = p.curToken.Literal
-		// 检查是否有括号
-		if p.peekToken.Type == lexer.LPAREN {
-			p.nextToken() // 跳过 (
-			if p.peekToken.Type == lexer.RPAREN {
-				p.nextToken() // 跳过 )
-			}
-		}
-	} else if p.curToken.Type == lexer.IDENTIFIER && p.peekToken.Type == lexer.LPAREN {
-		// name() 格式
-		stmt.Name = p.curToken.Literal
-		p.nextToken() // 跳过 (
-		if p.peekToken.Type == lexer.RPAREN {
-			p.nextToken() // 跳过 )
-		}
-	}
-
-	p.nextToken()
-	// 函数体不应该在 FI 时停止，因为函数体中可能有 if 语句
-	stmt.Body = p.parseBlockStatementWithStopOnFI(false)
-
-	return stmt
-}
-
-// parseBlockStatement 解析代码块
-// stopOnFI: 如果为 true，在遇到 FI 时停止（用于 if/then 块）
-// 如果为 false，在遇到 FI 时不停止（用于函数体）
-func (p *Parser) parseBlockStatement() *BlockStatement {
-	return p.parseBlockStatementWithStopOnFI(true)
-}
-
-// parseBlockStatementWithStopOnFI 解析代码块，支持控制是否在 FI 时停止
-func (p *Parser) parseBlockStatementWithStopOnFI(stopOnFI bool) *BlockStatement {
-	block := &BlockStatement{}
-	block.Statements = []Statement{}
-
-	stmtCount := 0
-	ifNestingLevel := 0 // 跟踪嵌套的 if 语句层级
-	var shouldStop bool
-	for p.curToken.Type != lexer.EOF &&
-		p.curToken.Type != lexer.RBRACE &&
-		p.curToken.Type != lexer.DONE &&
-		p.curToken.Type != lexer.ESAC {
-		// 如果遇到结束标记，停止解析（但需要考虑嵌套的 if）
-		// FI: 只有在 stopOnFI 为 true 且 ifNestingLevel == 0 时才停止
-		// ELSE/ELIF: 只有在 ifNestingLevel == 0 且 stopOnFI 为 false 时才停止
-		//   (如果 stopOnFI 为 true，说明这是 else 块，不应该在遇到 else/elif 时停止)
-		shouldStop = false
-		if p.curToken.Type == lexer.RBRACE {
-			shouldStop = true
-		} else if stopOnFI && p.curToken.Type == lexer.FI && ifNestingLevel == 0 {
-			shouldStop = true
-		} else if p.curToken.Type == lexer.DONE {
-			shouldStop = true
-		} else if !stopOnFI && ifNestingLevel == 0 && (p.curToken.Type == lexer.ELSE || p.curToken.Type == lexer.ELIF) {
-			// 只有在 stopOnFI 为 false 时（即解析 consequence 块时），才在遇到 else/elif 时停止
-			shouldStop = true
-		} else if p.curToken.Type == lexer.ESAC {
-			shouldStop = true
-		}
-		if shouldStop {
-			break
-		}
-		// 跳过空白字符和换行（它们是语句分隔符）
-		if p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-			p.nextToken()
-			continue
-		}
-		
-		// 检查是否是 if 语句的开始
-		wasIf := false
-		if p.curToken.Type == lexer.IF {
-			ifNestingLevel++
-			wasIf = true
-		}
-		
-		stmtCount++
-		stmt := p.parseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
-		}
-		
-		// 如果刚才解析的是 if 语句，parseIfStatement 会完全解析整个 if 语句（包括 fi），
-		// 所以 curToken 应该在 fi 之后的 token 上，嵌套层级应该减少
-		if wasIf {
-			// parseIfStatement 已经解析完整个 if 语句（包括 fi），
-			// 所以 curToken 现在应该在 fi 之后的 token 上
-			// 嵌套层级应该减少
-			if ifNestingLevel > 0 {
-				ifNestingLevel--
-			}
-		}
-		
-		// 如果解析后遇到结束标记，停止解析（但需要考虑嵌套的 if）
-		// FI: 只有在 stopOnFI 为 true 且 ifNestingLevel == 0 时才停止
-		// ELSE/ELIF: 只有在 ifNestingLevel == 0 且 stopOnFI 为 false 时才停止
-		shouldStop = false
-		if p.curToken.Type == lexer.RBRACE {
-			shouldStop = true
-		} else if stopOnFI && p.curToken.Type == lexer.FI && ifNestingLevel == 0 {
-			shouldStop = true
-		} else if p.curToken.Type == lexer.DONE {
-			shouldStop = true
-		} else if !stopOnFI && ifNestingLevel == 0 && (p.curToken.Type == lexer.ELSE || p.curToken.Type == lexer.ELIF) {
-			// 只有在 stopOnFI 为 false 时（即解析 consequence 块时），才在遇到 else/elif 时停止
-			shouldStop = true
-		} else if p.curToken.Type == lexer.ESAC {
-			shouldStop = true
-		}
-		if shouldStop {
-			break
-		}
-		// 跳过空白字符和换行，准备解析下一个语句
-		// 注意：parseCommandStatement 在遇到 NEWLINE 时会停止，但 curToken 仍然是 NEWLINE
-		// 所以我们需要跳过它
-		if p.curToken.Type == lexer.WHITESPACE || p.curToken.Type == lexer.NEWLINE {
-			p.nextToken()
-			// 跳过 NEWLINE 后，检查是否是结束标记（但需要考虑嵌套的 if）
-			// FI: 只有在 stopOnFI 为 true 且 ifNestingLevel == 0 时才停止
-			// ELSE/ELIF: 只有在 ifNestingLevel == 0 且 stopOnFI 为 false 时才停止
-			shouldStop = false
-			if p.curToken.Type == lexer.RBRACE {
-				shouldStop = true
-			} else if stopOnFI && p.curToken.Type == lexer.FI && ifNestingLevel == 0 {
-				shouldStop = true
-			} else if p.curToken.Type == lexer.DONE {
-				shouldStop = true
-			} else if !stopOnFI && ifNestingLevel == 0 && (p.curToken.Type == lexer.ELSE || p.curToken.Type == lexer.ELIF) {
-				// 只有在 stopOnFI 为 false 时（即解析 consequence 块时），才在遇到 else/elif 时停止
-				shouldStop = true
-			} else if p.curToken.Type == lexer.ESAC {
-				shouldStop = true
-			}
-			if shouldStop {
-				break
-			}
-			continue
-		}
-		// 如果 curToken 是下一个语句的开始（如 BREAK、CONTINUE 等），继续循环解析
-		// 不要调用 p.nextToken()，让下一次循环处理
-		if p.curToken.Type == lexer.BREAK || 
-		   p.curToken.Type == lexer.CONTINUE ||
-		   p.curToken.Type == lexer.IDENTIFIER ||
-		   p.curToken.Type == lexer.IF ||
-		   p.curToken.Type == lexer.FOR ||
-		   p.curToken.Type == lexer.WHILE ||
-		   p.curToken.Type == lexer.CASE {
-			// 这是下一个语句的开始，继续循环
-			continue
-		}
-		// 如果 curToken 不是 NEWLINE 或 WHITESPACE，也不是下一个语句的开始，移动到下一个 token
-		p.nextToken()
-	}
-
-	return block
-}
-
-// parseBreakStatement 解析break语句
-func (p *Parser) parseBreakStatement() *BreakStatement {
-	stmt := &BreakStatement{Level: 1}
-	
-	p.nextToken() // 跳过 break
-	
-	// 检查是否有数字参数（break n）
-	if p.curToken.Type == lexer.NUMBER {
-		// 解析数字
-		if level, err := strconv.Atoi(p.curToken.Literal); err == nil && level > 0 {
-			stmt.Level = level
-		}
-		p.nextToken() // 跳过数字
-	} else if p.curToken.Type == lexer.IDENTIFIER {
-		// 也可能是标识符形式的数字（虽然不常见）
-		if level, err := strconv.Atoi(p.curToken.Literal); err == nil && level > 0 {
-			stmt.Level = level
-			p.nextToken() // 跳过标识符
-		}
-	}
-	
-	return stmt
-}
-
-// parseContinueStatement 解析continue语句
-func (p *Parser) parseContinueStatement() *ContinueStatement {
-	stmt := &ContinueStatement{Level: 1}
-	
-	p.nextToken() // 跳过 continue
-	
-	// 检查是否有数字参数（continue n）
-	if p.curToken.Type == lexer.NUMBER {
-		// 解析数字
-		if level, err := strconv.Atoi(p.curToken.Literal); err == nil && level > 0 {
-			stmt.Level = level
-		}
-		p.nextToken() // 跳过数字
-	} else if p.curToken.Type == lexer.IDENTIFIER {
-		// 也可能是标识符形式的数字（虽然不常见）
-		if level, err := strconv.Atoi(p.curToken.Literal); err == nil && level > 0 {
-			stmt.Level = level
-			p.nextToken() // 跳过标识符
-		}
-	}
-	
-	return stmt
-}
-
-// parseSubshell 解析子shell命令 (command)
-func (p *Parser) parseSubshell() *SubshellCommand {
-	stmt := &SubshellCommand{}
-	
-	p.nextToken() // 跳过 (
-	
-	// 解析命令列表
-	stmt.Body = p.parseBlockStatement()
-	
-	// 检查并跳过 )
-	if p.curToken.Type == lexer.RPAREN {
-		p.nextToken()
-	} else if p.curToken.Type != lexer.EOF {
-		// 未闭合的括号
-		p.addError(ErrorTypeUnclosedParen, "未闭合的括号", p.curToken, ")")
-	}
-	
-	return stmt
-}
-
-// parseGroupCommand 解析命令组 { command; }
-func (p *Parser) parseGroupCommand() *GroupCommand {
-	stmt := &GroupCommand{}
-	
-	p.nextToken() // 跳过 {
-	
-	// 解析命令列表
-	stmt.Body = p.parseBlockStatement()
-	
-	// 检查并跳过 }
-	if p.curToken.Type == lexer.RBRACE {
-		p.nextToken()
-	} else if p.curToken.Type != lexer.EOF {
-		// 未闭合的大括号
-		p.addError(ErrorTypeUnclosedBrace, "未闭合的大括号", p.curToken, "}")
-	}
-	
-	return stmt
-}
-
-// Errors 返回解析错误（字符串列表，保持向后兼容）
-func (p *Parser) Errors() []string {
-	return p.errors
-}
-
-// ParseErrors 返回结构化解析错误列表
-func (p *Parser) ParseErrors() []*ParseError {
-	return p.parseErrors
-}
-
-// HasErrors 检查是否有解析错误
-func (p *Parser) HasErrors() bool {
-	return len(p.errors) > 0 || len(p.parseErrors) > 0
-}
-
-
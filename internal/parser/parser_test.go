@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 	"gobash/internal/lexer"
 )
@@ -120,6 +123,9 @@ func TestParseFunctionStatement(t *testing.T) {
 	if stmt.Body == nil {
 		t.Error("函数体为空")
 	}
+	if stmt.Source != "{ echo hello; }" {
+		t.Errorf("函数体源代码错误，得到 %q", stmt.Source)
+	}
 }
 
 func TestParsePipe(t *testing.T) {
@@ -132,13 +138,259 @@ func TestParsePipe(t *testing.T) {
 		t.Fatal("解析失败：没有语句")
 	}
 
-	stmt, ok := program.Statements[0].(*CommandStatement)
+	stmt, ok := program.Statements[0].(*PipelineStatement)
 	if !ok {
-		t.Fatal("解析失败：不是命令语句")
+		t.Fatal("解析失败：不是管道语句")
+	}
+
+	if len(stmt.Commands) != 2 {
+		t.Errorf("管道语句解析失败：期望 2 个命令，得到 %d", len(stmt.Commands))
+	}
+}
+
+func TestParseCompoundPipe(t *testing.T) {
+	tests := []struct {
+		input string
+		count int
+	}{
+		{"for i in 3 1 2; do echo $i; done | sort", 2},
+		{"if true; then echo x; fi | wc -l", 2},
+		{"{ echo b; echo a; } | sort | uniq", 3},
+		{"echo a |& cat | (cat)", 3},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Errorf("%q 解析错误: %v", tt.input, p.Errors())
+			continue
+		}
+		if len(program.Statements) != 1 {
+			t.Errorf("%q 期望 1 条语句，得到 %d", tt.input, len(program.Statements))
+			continue
+		}
+		stmt, ok := program.Statements[0].(*PipelineStatement)
+		if !ok {
+			t.Errorf("%q 不是管道语句: %T", tt.input, program.Statements[0])
+			continue
+		}
+		if len(stmt.Commands) != tt.count {
+			t.Errorf("%q 期望 %d 个命令，得到 %d", tt.input, tt.count, len(stmt.Commands))
+		}
+	}
+}
+
+// TestParseBackground 测试后台执行的语句：简单命令设置 Background，其他语句整个放到后台
+func TestParseBackground(t *testing.T) {
+	tests := []struct {
+		input string
+		inner string // 后台语句中的语句类型，为空表示后台执行的简单命令
+	}{
+		{"sleep 1 &", ""},
+		{"echo a | tr a A &", "*parser.PipelineStatement"},
+		{"true && echo ok &", "*parser.CommandChain"},
+		{"for i in 1 2; do echo $i; done &", "*parser.ForStatement"},
+		{"( cd /tmp; ls ) &", "*parser.SubshellCommand"},
+		{"{ echo a; } >out &", "*parser.GroupCommand"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 || len(program.Statements) != 1 {
+			t.Errorf("%q 解析错误: %v", tt.input, p.Errors())
+			continue
+		}
+		stmt := program.Statements[0]
+		if tt.inner == "" {
+			if cmd, ok := stmt.(*CommandStatement); !ok || !cmd.Background {
+				t.Errorf("%q 应该是后台执行的简单命令，得到 %#v", tt.input, stmt)
+			}
+			continue
+		}
+		bg, ok := stmt.(*BackgroundStatement)
+		if !ok {
+			t.Errorf("%q 不是后台语句: %T", tt.input, stmt)
+			continue
+		}
+		if got := fmt.Sprintf("%T", bg.Statement); got != tt.inner {
+			t.Errorf("%q 后台执行的是 %s，期望 %s", tt.input, got, tt.inner)
+		}
+	}
+}
+
+// TestParseCoproc 测试协进程：只有复合命令前面的单词是协进程的名称
+func TestParseCoproc(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		inner string
+	}{
+		{"coproc cat", "", "*parser.CommandStatement"},
+		{"coproc bc -l", "", "*parser.CommandStatement"},
+		{"coproc { while read x; do echo $x; done; }", "", "*parser.GroupCommand"},
+		{"coproc WORKER { read x; echo $x; }", "WORKER", "*parser.GroupCommand"},
+		{"coproc P ( cat )", "P", "*parser.SubshellCommand"},
+		{"coproc LOOP while read x; do echo $x; done", "LOOP", "*parser.WhileStatement"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 || len(program.Statements) != 1 {
+			t.Errorf("%q 解析错误: %v", tt.input, p.Errors())
+			continue
+		}
+		stmt, ok := program.Statements[0].(*CoprocStatement)
+		if !ok {
+			t.Errorf("%q 不是协进程: %T", tt.input, program.Statements[0])
+			continue
+		}
+		if stmt.Name != tt.name {
+			t.Errorf("%q 的名称是 %q，期望 %q", tt.input, stmt.Name, tt.name)
+		}
+		if got := fmt.Sprintf("%T", stmt.Statement); got != tt.inner {
+			t.Errorf("%q 的命令是 %s，期望 %s", tt.input, got, tt.inner)
+		}
+	}
+
+	p := New(lexer.New("coproc\n"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Error("coproc 后面没有命令应该报告语法错误")
+	}
+}
+
+func TestParseArithmeticCommand(t *testing.T) {
+	p := New(lexer.New("((i += (2 * 3))) > /dev/null; ((echo a); echo b)"))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 || len(program.Statements) != 2 {
+		t.Fatalf("解析失败: %v", p.Errors())
+	}
+	stmt, ok := program.Statements[0].(*ArithmeticCommand)
+	if !ok || stmt.Expression != "i += (2 * 3)" || len(stmt.Redirects) != 1 {
+		t.Errorf("算术命令解析为 %#v", program.Statements[0])
+	}
+	// (( 没有对应的 )) 时是嵌套的子shell
+	if _, ok := program.Statements[1].(*SubshellCommand); !ok {
+		t.Errorf("((echo a); echo b) 解析为 %T", program.Statements[1])
+	}
+}
+
+func TestParseParamExpand(t *testing.T) {
+	tests := map[string]ParamExpandExpression{
+		"#x":      {VarName: "#x"},
+		"#arr[@]": {VarName: "#arr", Word: "[@]"},
+		"#":       {VarName: "#"},
+		"x#pre":   {VarName: "x", Op: "#", Word: "pre"},
+		"x^^[ab]": {VarName: "x", Op: "^^", Word: "[ab]"},
+		"x,":      {VarName: "x", Op: ","},
+		"x:1:2":   {VarName: "x", Op: ":", Word: "1:2"},
+		"arr[1]":  {VarName: "arr", Word: "[1]"},
+	}
+	for input, want := range tests {
+		if got := ParseParamExpand(input); *got != want {
+			t.Errorf("ParseParamExpand(%q) = %+v，期望 %+v", input, *got, want)
+		}
+	}
+}
+
+func TestParseAssignmentWord(t *testing.T) {
+	p := New(lexer.New(`VAR="a 'b' c"'$x'd y= z=1 cmd arg`))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 || len(program.Statements) != 1 {
+		t.Fatalf("解析失败: %v", p.Errors())
+	}
+	stmt := program.Statements[0].(*CommandStatement)
+	// 命令之前的赋值（包括紧跟的赋值）只作用于这条命令
+	if len(stmt.Assignments) != 3 || stmt.Command.String() != "cmd" || len(stmt.Args) != 1 {
+		t.Fatalf("解析为 %#v", stmt)
+	}
+	assign := stmt.Assignments[0]
+	if assign.Name != "VAR" {
+		t.Fatalf("赋值解析为 %#v", assign)
+	}
+	value, ok := assign.Value.(*ConcatExpression)
+	if !ok || len(value.Parts) != 3 {
+		t.Fatalf("值解析为 %#v", assign.Value)
+	}
+	if part, ok := value.Parts[0].(*StringLiteral); !ok || !part.IsQuote || part.Value != "a 'b' c" {
+		t.Errorf("双引号片段解析为 %#v", value.Parts[0])
+	}
+	if part, ok := value.Parts[1].(*StringLiteral); !ok || part.IsQuote || part.Value != "$x" {
+		t.Errorf("单引号片段解析为 %#v", value.Parts[1])
+	}
+	if y := stmt.Assignments[1]; y.Name != "y" || y.Value != nil {
+		t.Errorf("y= 解析为 %#v", y)
+	}
+	if z := stmt.Assignments[2]; z.Name != "z" || z.Value == nil || z.Append {
+		t.Errorf("z=1 解析为 %#v", z)
+	}
+	stmt = New(lexer.New("PATH+=:/opt/bin cmd")).ParseProgram().Statements[0].(*CommandStatement)
+	if len(stmt.Assignments) != 1 || stmt.Assignments[0].Name != "PATH" || !stmt.Assignments[0].Append {
+		t.Errorf("PATH+=:/opt/bin 解析为 %#v", stmt)
 	}
 
-	if stmt.Pipe == nil {
-		t.Error("管道语句解析失败：Pipe为空")
+	// 只有赋值的命令没有命令名，值可以是命令替换；重定向之后的单词是命令名
+	stmt = New(lexer.New(`A=1 B=2 C="$(date)" > f`)).ParseProgram().Statements[0].(*CommandStatement)
+	if len(stmt.Assignments) != 3 || stmt.Command != nil || len(stmt.Args) != 0 || len(stmt.Redirects) != 1 {
+		t.Errorf("只有赋值的命令解析为 %#v", stmt)
+	} else if c := stmt.Assignments[2]; c.Name != "C" || c.Value.String() != `"$(date)"` {
+		t.Errorf("C=\"$(date)\" 解析为 %#v", c)
+	}
+	stmt = New(lexer.New("x=1 2>/dev/null cmd arg")).ParseProgram().Statements[0].(*CommandStatement)
+	if len(stmt.Assignments) != 1 || stmt.Command == nil || stmt.Command.String() != "cmd" || len(stmt.Args) != 1 {
+		t.Errorf("赋值和重定向之后的命令解析为 %#v", stmt)
+	}
+}
+
+func TestParseTimedStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		posix    bool
+		timed    string
+		commands int
+	}{
+		{"time make all", false, "make all", 0},
+		{"time -p ls | wc -l", true, "ls | wc -l", 2},
+		{"time", false, "", 0},
+		{"echo time", false, "", -1},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 || len(program.Statements) != 1 {
+			t.Errorf("%q 解析失败: %v", tt.input, p.Errors())
+			continue
+		}
+		stmt, ok := program.Statements[0].(*TimedStatement)
+		if tt.commands < 0 {
+			if ok {
+				t.Errorf("%q 中的 time 不是关键字", tt.input)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%q 不是 time 语句: %T", tt.input, program.Statements[0])
+			continue
+		}
+		if stmt.Posix != tt.posix {
+			t.Errorf("%q 的 Posix 为 %v", tt.input, stmt.Posix)
+		}
+		if tt.timed == "" {
+			if stmt.Statement != nil {
+				t.Errorf("%q 不应该有命令", tt.input)
+			}
+			continue
+		}
+		if stmt.Statement == nil || stmt.Statement.String() != tt.timed {
+			t.Errorf("%q 测量的命令为 %v，期望 %q", tt.input, stmt.Statement, tt.timed)
+		}
+		if pipeline, ok := stmt.Statement.(*PipelineStatement); tt.commands > 0 && (!ok || len(pipeline.Commands) != tt.commands) {
+			t.Errorf("%q 应该测量整个管道", tt.input)
+		}
 	}
 }
 
@@ -298,3 +550,296 @@ func TestParseHereDocument(t *testing.T) {
 	}
 }
 
+
+func TestIncompleteInput(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+		closer     string
+	}{
+		{"echo hello\n", false, ""},
+		{"if true; then\n  echo hello\n", true, "fi"},
+		{"for i in 1 2; do\n", true, "done"},
+		{"case $x in\n  a) echo a ;;\n", true, "esac"},
+		{"f() {\n", true, "}"},
+		{"echo \"hello\n", true, "\""},
+		{"echo $(date\n", true, ")"},
+		{"cat <<EOF\nhello\n", true, "EOF"},
+		{"echo hello \\\n", true, ""},
+		{"echo a &&\n", true, ""},
+		{"echo a ||  # 注释\n\n", true, ""},
+		{"echo a |\n", true, ""},
+		{"echo a && \\\n", true, ""},
+		{"echo a &&\n  echo b\n", false, ""},
+		{"fi\n", false, ""},
+		{"if true; then fi\n", false, ""},
+		{"if a; then\n  while b; do\n", true, "done"},
+		{"if a; then echo 'fi'\n", true, "fi"},
+		{"if a; then echo 'fi\n", true, "'"},
+		{"( echo a\n", true, ")"},
+		{"[[ -n $x\n", true, "]]"},
+		{"[[ -n $x &&\n", true, ""},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if got := p.Incomplete(); got != tt.incomplete {
+			t.Errorf("Incomplete(%q) = %v, 期望 %v (错误: %v)", tt.input, got, tt.incomplete, p.AllErrors())
+		}
+		if closer, _ := p.NeedsMore(); closer != tt.closer {
+			t.Errorf("NeedsMore(%q) 的结束符为 %q, 期望 %q", tt.input, closer, tt.closer)
+		}
+	}
+}
+
+// TestParseListAcrossLines 测试以 &&、|| 或 | 结尾的行在下一行继续，中间可以有空行和注释
+func TestParseListAcrossLines(t *testing.T) {
+	input := "echo a &&\n\n  # 注释\n  echo b ||\n  echo c\necho d |\n  tr d D\necho e \\\n  && echo f\n"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.AllErrors(); len(errs) > 0 {
+		t.Fatalf("解析错误: %v", errs)
+	}
+
+	expected := []string{"echo a && echo b || echo c", "echo d | tr d D", "echo e && echo f"}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("期望 %d 条语句，得到 %d", len(expected), len(program.Statements))
+	}
+	for i, want := range expected {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("第 %d 条语句为 %q，期望 %q", i, got, want)
+		}
+	}
+	if lines := program.Lines; lines[0] != 1 || lines[1] != 6 || lines[2] != 8 {
+		t.Errorf("语句的行号为 %v，期望 [1 6 8]", lines)
+	}
+}
+
+func TestProgramLines(t *testing.T) {
+	input := "echo a\n\nif true; then\n  echo b\nfi\necho c; echo d\n"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+
+	expected := []int{1, 3, 6, 6}
+	if len(program.Lines) != len(program.Statements) {
+		t.Fatalf("Lines 数量 %d 与语句数量 %d 不一致", len(program.Lines), len(program.Statements))
+	}
+	for i, line := range expected {
+		if program.Lines[i] != line {
+			t.Errorf("第 %d 条语句的行号为 %d，期望 %d", i, program.Lines[i], line)
+		}
+	}
+}
+
+func TestParseCompoundRedirect(t *testing.T) {
+	tests := []struct {
+		input string
+		count int
+	}{
+		{"while read l; do echo $l; done < file", 1},
+		{"{ echo a; echo b; } > out 2>&1", 2},
+		{"if true; then echo x; fi 2> err.log", 1},
+		{"for i in 1 2; do echo $i; done >> out", 1},
+		{"(echo sub) > out", 1},
+		{"case x in x) echo y;; esac > out", 1},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Errorf("%q 解析错误: %v", tt.input, p.Errors())
+			continue
+		}
+		if len(program.Statements) != 1 {
+			t.Errorf("%q 期望 1 条语句，得到 %d", tt.input, len(program.Statements))
+			continue
+		}
+
+		var redirects []*Redirect
+		switch stmt := program.Statements[0].(type) {
+		case *WhileStatement:
+			redirects = stmt.Redirects
+		case *GroupCommand:
+			redirects = stmt.Redirects
+		case *IfStatement:
+			redirects = stmt.Redirects
+		case *ForStatement:
+			redirects = stmt.Redirects
+		case *SubshellCommand:
+			redirects = stmt.Redirects
+		case *CaseStatement:
+			redirects = stmt.Redirects
+		default:
+			t.Errorf("%q 解析为意外的语句类型 %T", tt.input, stmt)
+			continue
+		}
+		if len(redirects) != tt.count {
+			t.Errorf("%q 期望 %d 个重定向，得到 %d", tt.input, tt.count, len(redirects))
+		}
+	}
+}
+
+// condTree 把条件表达式输出为带括号的前缀形式，用于检查表达式树的结构
+func condTree(cond CondExpression) string {
+	switch c := cond.(type) {
+	case *CondBinary:
+		return "(" + c.Op + " " + condTree(c.Left) + " " + condTree(c.Right) + ")"
+	case *CondNot:
+		return "(! " + condTree(c.Expr) + ")"
+	case *CondParen:
+		return "[" + condTree(c.Expr) + "]"
+	case *CondUnary:
+		return "(" + c.Op + " " + c.Operand.String() + ")"
+	case *CondCompare:
+		return "(" + c.Op + " " + c.Left.String() + " " + c.Right.String() + ")"
+	case *CondWord:
+		return c.Word.String()
+	}
+	return fmt.Sprintf("%T", cond)
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[[ -f file ]]", "(-f file)"},
+		{"[[ $x ]]", "$x"},
+		{"[[ a || b && c ]]", "(|| a (&& b c))"},
+		{"[[ ( a || b ) && ! -z $c ]]", "(&& [(|| a b)] (! (-z $c)))"},
+		{"[[ $x == a* && $y != \"b\" ]]", "(&& (== $x a*) (!= $y \"b\"))"},
+		{"[[ a < b || a > b ]]", "(|| (< a b) (> a b))"},
+		{"[[ $n -lt 10 ]]", "(-lt $n 10)"},
+		// 带引号的运算符是普通的字符串
+		{"[[ \"-f\" ]]", "\"-f\""},
+		{"[[ a '==' b ]]", "ERROR"},
+		// =~ 右边的括号、| 和括号中的空白属于正则表达式
+		{"[[ $v =~ ^(a|b c)+$ ]]", "(=~ $v ^(a|b c)+$)"},
+		{"[[ $v =~ x && y ]]", "(&& (=~ $v x) y)"},
+		// 条件中可以换行
+		{"[[ a &&\n b ]]", "(&& a b)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if tt.expected == "ERROR" {
+			if len(p.AllErrors()) == 0 {
+				t.Errorf("%q 应该报告语法错误", tt.input)
+			}
+			continue
+		}
+		if errs := p.AllErrors(); len(errs) > 0 {
+			t.Errorf("%q 解析失败: %v", tt.input, errs)
+			continue
+		}
+		stmt, ok := program.Statements[0].(*CommandStatement)
+		if !ok || stmt.Cond == nil || len(stmt.Args) != 0 {
+			t.Errorf("%q 没有解析为条件表达式: %#v", tt.input, program.Statements[0])
+			continue
+		}
+		if got := condTree(stmt.Cond); got != tt.expected {
+			t.Errorf("%q 解析为 %s，期望 %s", tt.input, got, tt.expected)
+		}
+	}
+
+	errors := []struct {
+		input  string
+		column int
+	}{
+		{"[[ a b ]]", 6},
+		{"[[ -f ]]", 7},
+		{"[[ a == ]]", 9},
+		{"[[ a && ]]", 9},
+		{"[[ ( a ]]", 8},
+		{"[[ ]]", 4},
+		{"[[ a ]] b", 9},
+	}
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.ParseErrors()
+		if len(errs) != 1 || errs[0].Token.Column != tt.column {
+			t.Errorf("%q 期望在第 %d 列报告一个错误，得到 %v", tt.input, tt.column, errs)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"echo  hi   >out 2>&1", "echo hi > out 2>&1\n"},
+		{"if a; then b; elif c; then d; else e; fi", "if a; then\n    b\nelif c; then\n    d\nelse\n    e\nfi\n"},
+		{"for i in 1 'a b'; do echo $i | cat; done > f", "for i in 1 'a b'; do\n    echo $i | cat\ndone > f\n"},
+		{"case $x in a|b) echo ab;; *) ;; esac", "case $x in\n    a | b)\n        echo ab\n        ;;\n    *)\n        ;;\nesac\n"},
+		{"f() { cat <<'EOF'\n$x\nEOF\n}", "f() {\n    cat <<'EOF'\n$x\nEOF\n}\n"},
+		{"{ a; b & } 2>/dev/null || ( c )", "{\n    a\n    b &\n} 2> /dev/null || (\n    c\n)\n"},
+		{"echo \"it's\" 'it'\\''s'", "echo \"it's\" it\"'\"s\n"},
+		{"if a; b & then c; elif x | y; z; then :; fi", "if a; b & then\n    c\nelif x | y; z; then\n    :\nfi\n"},
+		{"until a; b; do c; done", "until a; b; do\n    c\ndone\n"},
+		{"LC_ALL=C  sort x=1 >out", "LC_ALL=C sort x=1 > out\n"},
+		{"for x in; do a; done; for y\ndo b; done", "for x in; do\n    a\ndone\nfor y; do\n    b\ndone\n"},
+		{"a+=1  b=2 cmd; arr+=(x  \"y z\"); arr[i+1]+=q", "a+=1 b=2 cmd\narr+=(x \"y z\")\narr[i+1]+=q\n"},
+		{"[[ ( -f a||$x == \"b\"* ) && ! $y =~ ^(c|d)$ ]]", "[[ ( -f a || $x == \"b\"* ) && ! $y =~ ^(c|d)$ ]]\n"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if errs := p.AllErrors(); len(errs) > 0 {
+			t.Errorf("%q 解析失败: %v", tt.input, errs)
+			continue
+		}
+		got := Format(program)
+		if got != tt.expected {
+			t.Errorf("Format(%q) = %q，期望 %q", tt.input, got, tt.expected)
+			continue
+		}
+		// 格式化的结果再次格式化不变
+		if again := Format(New(lexer.New(got)).ParseProgram()); again != got {
+			t.Errorf("再次格式化 %q 得到 %q", got, again)
+		}
+	}
+}
+
+func TestStatementString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if a; then b; c & fi", "if a; then b; c & fi"},
+		{"while read l; do echo $l; done < in", "while read l; do echo $l; done < in"},
+		{"(a; b)", "( a; b )"},
+		{"{ a; }", "{ a; }"},
+		{"arr=([2]=b [10]=c)", "arr=([2]=b [10]=c)"},
+	}
+	for _, tt := range tests {
+		program := New(lexer.New(tt.input)).ParseProgram()
+		if len(program.Statements) != 1 {
+			t.Errorf("%q 期望 1 条语句，得到 %d", tt.input, len(program.Statements))
+			continue
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("%q.String() = %q，期望 %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	program := New(lexer.New("echo hi >> log")).ParseProgram()
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(program); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.String()
+	for _, want := range []string{`{"Node":"Program","Statements":[{"Node":"CommandStatement"`, `"Command":{"Node":"Identifier","Value":"echo"}`, `{"Node":"Redirect","Type":">>","FD":1`} {
+		if !strings.Contains(data, want) {
+			t.Errorf("JSON %s 中没有 %s", data, want)
+		}
+	}
+}
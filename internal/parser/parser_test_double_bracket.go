@@ -26,13 +26,8 @@ func TestParseDoubleBracket(t *testing.T) {
 				if ident.Value != "[[" {
 					t.Errorf("命令名错误，期望 '[[', 得到 '%s'", ident.Value)
 				}
-				if len(stmt.Args) == 0 {
-					t.Fatal("没有参数")
-				}
-				// 检查最后一个参数是否是 ]]
-				lastArg, ok := stmt.Args[len(stmt.Args)-1].(*Identifier)
-				if !ok || lastArg.Value != "]]" {
-					t.Errorf("最后一个参数应该是 ']]', 得到 '%v'", stmt.Args[len(stmt.Args)-1])
+				if _, ok := stmt.Cond.(*CondUnary); !ok {
+					t.Errorf("条件应该是一元测试，得到 %T", stmt.Cond)
 				}
 			},
 		},
@@ -43,16 +38,9 @@ func TestParseDoubleBracket(t *testing.T) {
 				if stmt.Command == nil {
 					t.Fatal("命令为空")
 				}
-				// 检查参数中是否包含 &&
-				hasAnd := false
-				for _, arg := range stmt.Args {
-					if ident, ok := arg.(*Identifier); ok && ident.Value == "&&" {
-						hasAnd = true
-						break
-					}
-				}
-				if !hasAnd {
-					t.Error("参数中应该包含 '&&'")
+				// 检查条件是否是 && 连接的两个测试
+				if cond, ok := stmt.Cond.(*CondBinary); !ok || cond.Op != "&&" {
+					t.Errorf("条件应该是 '&&'，得到 %v", stmt.Cond)
 				}
 			},
 		},
@@ -63,16 +51,9 @@ func TestParseDoubleBracket(t *testing.T) {
 				if stmt.Command == nil {
 					t.Fatal("命令为空")
 				}
-				// 检查参数中是否包含 ||
-				hasOr := false
-				for _, arg := range stmt.Args {
-					if ident, ok := arg.(*Identifier); ok && ident.Value == "||" {
-						hasOr = true
-						break
-					}
-				}
-				if !hasOr {
-					t.Error("参数中应该包含 '||'")
+				// 检查条件是否是 || 连接的两个测试
+				if cond, ok := stmt.Cond.(*CondBinary); !ok || cond.Op != "||" {
+					t.Errorf("条件应该是 '||'，得到 %v", stmt.Cond)
 				}
 			},
 		},
@@ -83,16 +64,9 @@ func TestParseDoubleBracket(t *testing.T) {
 				if stmt.Command == nil {
 					t.Fatal("命令为空")
 				}
-				// 检查参数中是否包含括号
-				hasParen := false
-				for _, arg := range stmt.Args {
-					if ident, ok := arg.(*Identifier); ok && (ident.Value == "(" || ident.Value == ")") {
-						hasParen = true
-						break
-					}
-				}
-				if !hasParen {
-					t.Error("参数中应该包含括号")
+				// 检查条件是否是括号
+				if _, ok := stmt.Cond.(*CondParen); !ok {
+					t.Errorf("条件应该是括号，得到 %T", stmt.Cond)
 				}
 			},
 		},
@@ -103,16 +77,9 @@ func TestParseDoubleBracket(t *testing.T) {
 				if stmt.Command == nil {
 					t.Fatal("命令为空")
 				}
-				// 检查参数中是否包含 !
-				hasNot := false
-				for _, arg := range stmt.Args {
-					if ident, ok := arg.(*Identifier); ok && ident.Value == "!" {
-						hasNot = true
-						break
-					}
-				}
-				if !hasNot {
-					t.Error("参数中应该包含 '!'")
+				// 检查条件是否取反
+				if _, ok := stmt.Cond.(*CondNot); !ok {
+					t.Errorf("条件应该取反，得到 %T", stmt.Cond)
 				}
 			},
 		},
@@ -0,0 +1,456 @@
+package parser
+
+import (
+	"gobash/internal/lexer"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 语法树输出为 shell 源代码
+//
+// Format 输出规范化的多行源代码（gobash -fmt）：复合命令的关键字各占一行，命令体缩进 4 个空格，
+// here-document 的正文紧跟在引用它的命令所在的行之后。各节点的 String() 输出同样的源代码，但写成一行
+// （命令之间用 ; 分隔，不输出 here-document 的正文），用于作业列表、$BASH_COMMAND 和调试输出。
+// 注释和空行不在语法树中：FormatComments 按行号把词法分析器记录的注释放回输出，空行不保留
+
+// indentUnit 每一层缩进
+const indentUnit = "    "
+
+// Format 把程序输出为规范化的 shell 源代码，每条顶层语句占一行（复合命令占多行），以换行结尾
+func Format(program *Program) string {
+	return FormatComments(program, nil)
+}
+
+// FormatComments 与 Format 相同，同时输出源代码中的注释 comments（lexer.Lexer.Comments）：
+// 语句之前的注释各占一行，与语句同一行的注释写在这条语句的行尾
+func FormatComments(program *Program, comments []lexer.Comment) string {
+	p := &printer{comments: comments}
+	for _, stmt := range program.Statements {
+		if p.commentsBefore(stmt) {
+			p.newline()
+		}
+		p.statement(stmt)
+		p.trailingComment(stmt)
+		p.newline()
+	}
+	for _, comment := range p.comments {
+		p.out.WriteString(comment.Text)
+		p.newline()
+	}
+	return p.out.String()
+}
+
+// oneLine 把语句输出为一行源代码
+func oneLine(stmt Statement) string {
+	p := &printer{oneLine: true}
+	p.statement(stmt)
+	return p.out.String()
+}
+
+// printer 输出语法树的状态
+type printer struct {
+	out      strings.Builder
+	oneLine  bool            // 写成一行
+	indent   int             // 当前缩进层数
+	heredocs []*HereDocument // 当前行中还没有输出正文的 here-document
+	comments []lexer.Comment // 还没有输出的注释（按行号排列）
+}
+
+// commentsBefore 输出语句 stmt 所在的行之前的注释，注释之间换行，返回是否输出了注释
+func (p *printer) commentsBefore(stmt Statement) bool {
+	line := StatementPos(stmt).Line
+	printed := false
+	for len(p.comments) > 0 && p.comments[0].Line < line {
+		if printed {
+			p.newline()
+		}
+		p.out.WriteString(p.comments[0].Text)
+		p.comments = p.comments[1:]
+		printed = true
+	}
+	return printed
+}
+
+// trailingComment 把与语句 stmt 开头同一行的注释输出在行尾
+func (p *printer) trailingComment(stmt Statement) {
+	if len(p.comments) > 0 && p.comments[0].Line == StatementPos(stmt).Line {
+		p.out.WriteString(" " + p.comments[0].Text)
+		p.comments = p.comments[1:]
+	}
+}
+
+// newline 换行并缩进，先输出当前行中的 here-document 正文；写成一行时输出空格
+func (p *printer) newline() {
+	if p.oneLine {
+		p.out.WriteByte(' ')
+		return
+	}
+	p.out.WriteByte('\n')
+	for _, doc := range p.heredocs {
+		p.out.WriteString(doc.Content)
+		if doc.Content != "" && !strings.HasSuffix(doc.Content, "\n") {
+			p.out.WriteByte('\n')
+		}
+		p.out.WriteString(doc.Delimiter + "\n")
+	}
+	p.heredocs = nil
+	p.out.WriteString(strings.Repeat(indentUnit, p.indent))
+}
+
+// body 输出复合命令的命令体，之后换行到复合命令所在的缩进，准备输出结束的关键字
+func (p *printer) body(block *BlockStatement) {
+	p.indent++
+	if block != nil {
+		for _, stmt := range block.Statements {
+			p.newline()
+			if p.commentsBefore(stmt) {
+				p.newline()
+			}
+			p.statement(stmt)
+			p.terminate()
+			p.trailingComment(stmt)
+		}
+	}
+	p.indent--
+	p.newline()
+}
+
+// terminate 写成一行时在命令之后加上分号（后台命令的 & 已经结束了命令）
+func (p *printer) terminate() {
+	if p.oneLine && !strings.HasSuffix(p.out.String(), "&") {
+		p.out.WriteByte(';')
+	}
+}
+
+// redirects 输出复合命令的重定向，每个重定向之前有一个空格
+func (p *printer) redirects(redirects []*Redirect) {
+	for _, redirect := range redirects {
+		p.redirect(redirect, true)
+	}
+}
+
+// redirect 输出一个重定向，here-document 的正文在换行时输出
+func (p *printer) redirect(redirect *Redirect, space bool) {
+	if space {
+		p.out.WriteByte(' ')
+	}
+	p.out.WriteString(redirect.String())
+	if redirect.HereDoc != nil && !p.oneLine {
+		p.heredocs = append(p.heredocs, redirect.HereDoc)
+	}
+}
+
+// statement 输出一条语句
+func (p *printer) statement(stmt Statement) {
+	switch s := stmt.(type) {
+	case *CommandStatement:
+		p.command(s)
+	case *IfStatement:
+		p.out.WriteString("if ")
+		p.conditionList(s.Condition)
+		p.out.WriteString(" then")
+		p.body(s.Consequence)
+		for _, elif := range s.Elif {
+			p.out.WriteString("elif ")
+			p.conditionList(elif.Condition)
+			p.out.WriteString(" then")
+			p.body(elif.Consequence)
+		}
+		if s.Alternative != nil {
+			p.out.WriteString("else")
+			p.body(s.Alternative)
+		}
+		p.out.WriteString("fi")
+		p.redirects(s.Redirects)
+	case *ForStatement:
+		p.out.WriteString("for " + s.Variable)
+		if s.In != nil {
+			p.out.WriteString(" in")
+			for _, word := range s.In {
+				p.out.WriteString(" " + word.String())
+			}
+		}
+		p.out.WriteString("; do")
+		p.body(s.Body)
+		p.out.WriteString("done")
+		p.redirects(s.Redirects)
+	case *WhileStatement:
+		if s.Until {
+			p.out.WriteString("until ")
+		} else {
+			p.out.WriteString("while ")
+		}
+		p.conditionList(s.Condition)
+		p.out.WriteString(" do")
+		p.body(s.Body)
+		p.out.WriteString("done")
+		p.redirects(s.Redirects)
+	case *CaseStatement:
+		p.caseStatement(s)
+	case *FunctionStatement:
+		p.out.WriteString(s.Name + "() {")
+		p.body(s.Body)
+		p.out.WriteString("}")
+	case *BlockStatement:
+		for i, inner := range s.Statements {
+			if i > 0 {
+				p.terminate()
+				p.newline()
+			}
+			p.statement(inner)
+		}
+	case *GroupCommand:
+		p.out.WriteString("{")
+		p.body(s.Body)
+		p.out.WriteString("}")
+		p.redirects(s.Redirects)
+	case *SubshellCommand:
+		if p.oneLine {
+			// 写成一行时与 bash 相同，最后一个命令之后没有分号：( cmd1; cmd2 )
+			p.out.WriteString("( ")
+			if s.Body != nil {
+				p.statement(s.Body)
+			}
+			p.out.WriteString(" )")
+		} else {
+			p.out.WriteString("(")
+			p.body(s.Body)
+			p.out.WriteString(")")
+		}
+		p.redirects(s.Redirects)
+	case *ArithmeticCommand:
+		p.out.WriteString("((" + s.Expression + "))")
+		p.redirects(s.Redirects)
+	case *ArrayAssignmentStatement:
+		p.out.WriteString(arrayAssignment(s))
+	case *PipelineStatement:
+		for i, cmd := range s.Commands {
+			if i > 0 {
+				if i-1 < len(s.Stderr) && s.Stderr[i-1] {
+					p.out.WriteString(" |& ")
+				} else {
+					p.out.WriteString(" | ")
+				}
+			}
+			p.statement(cmd)
+		}
+	case *CommandChain:
+		p.statement(s.Left)
+		p.out.WriteString(" " + s.Operator + " ")
+		p.statement(s.Right)
+	case *NegatedStatement:
+		p.out.WriteString("!")
+		if s.Statement != nil {
+			p.out.WriteByte(' ')
+			p.statement(s.Statement)
+		}
+	case *TimedStatement:
+		p.out.WriteString("time")
+		if s.Posix {
+			p.out.WriteString(" -p")
+		}
+		if s.Statement != nil {
+			p.out.WriteByte(' ')
+			p.statement(s.Statement)
+		}
+	case *BackgroundStatement:
+		p.statement(s.Statement)
+		p.out.WriteString(" &")
+	case *CoprocStatement:
+		p.out.WriteString("coproc ")
+		if s.Name != "" {
+			p.out.WriteString(s.Name + " ")
+		}
+		p.statement(s.Statement)
+	case nil:
+	default:
+		p.out.WriteString(stmt.String())
+	}
+}
+
+// command 输出简单命令：命令名、参数（[[ 命令是条件表达式和 ]]）、重定向，后台执行时加上 &
+func (p *printer) command(cmd *CommandStatement) {
+	var words []string
+	for _, assign := range cmd.Assignments {
+		words = append(words, assign.String())
+	}
+	if cmd.Command != nil {
+		words = append(words, cmd.Command.String())
+	}
+	if cmd.Cond != nil {
+		words = append(words, cmd.Cond.String(), "]]")
+	}
+	for i, arg := range cmd.Args {
+		if ident, ok := cmd.Command.(*Identifier); ok && i == 0 && strings.HasSuffix(ident.Value, "=") {
+			// 数组元素赋值 arr[i]=value 的值紧跟在 = 之后
+			words[len(words)-1] += arg.String()
+			continue
+		}
+		words = append(words, arg.String())
+	}
+	p.out.WriteString(strings.Join(words, " "))
+	for i, redirect := range cmd.Redirects {
+		// 只有重定向的命令（如 > file）开头没有空格
+		p.redirect(redirect, i > 0 || len(words) > 0)
+	}
+	if cmd.Background {
+		p.out.WriteString(" &")
+	}
+}
+
+// conditionList 输出 if/elif/while 的条件命令列表，写在一行，每条命令之后有分号（后台命令的 & 已经结束了命令）
+func (p *printer) conditionList(cond *BlockStatement) {
+	if cond == nil {
+		return
+	}
+	for i, stmt := range cond.Statements {
+		if i > 0 {
+			p.out.WriteByte(' ')
+		}
+		p.statement(stmt)
+		if !strings.HasSuffix(p.out.String(), "&") {
+			p.out.WriteByte(';')
+		}
+	}
+}
+
+// caseStatement 输出 case 语句，每个模式占一行，命令体再缩进一层，;; 与命令体对齐
+func (p *printer) caseStatement(s *CaseStatement) {
+	p.out.WriteString("case ")
+	if s.Value != nil {
+		p.out.WriteString(s.Value.String())
+	}
+	p.out.WriteString(" in")
+	p.indent++
+	for _, clause := range s.Cases {
+		p.newline()
+		p.out.WriteString(strings.Join(clause.Patterns, " | ") + ")")
+		p.indent++
+		if clause.Body != nil {
+			for i, stmt := range clause.Body.Statements {
+				if i > 0 {
+					p.terminate()
+				}
+				p.newline()
+				if p.commentsBefore(stmt) {
+					p.newline()
+				}
+				p.statement(stmt)
+				p.trailingComment(stmt)
+			}
+		}
+		p.newline()
+		p.out.WriteString(";;")
+		p.indent--
+	}
+	p.indent--
+	p.newline()
+	p.out.WriteString("esac")
+	p.redirects(s.Redirects)
+}
+
+// arrayAssignment 输出数组赋值 name=(...)，带索引的元素按索引排序（数字索引按数值）
+func arrayAssignment(s *ArrayAssignmentStatement) string {
+	var values []string
+	if len(s.IndexedValues) > 0 {
+		keys := make([]string, 0, len(s.IndexedValues))
+		for key := range s.IndexedValues {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, errA := strconv.Atoi(keys[i])
+			b, errB := strconv.Atoi(keys[j])
+			if errA == nil && errB == nil {
+				return a < b
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			values = append(values, "["+key+"]="+s.IndexedValues[key].String())
+		}
+	} else {
+		for _, value := range s.Values {
+			values = append(values, value.String())
+		}
+	}
+	op := "=("
+	if s.Append {
+		op = "+=("
+	}
+	return s.Name + op + strings.Join(values, " ") + ")"
+}
+
+// String 输出重定向的源代码，如 > out、2>&1、<<'EOF'（不含 here-document 的正文）
+func (r *Redirect) String() string {
+	fd := ""
+	switch r.Type {
+	case REDIRECT_OUTPUT, REDIRECT_APPEND, REDIRECT_CLOBBER, REDIRECT_DUP_OUT:
+		if r.FD != 1 {
+			fd = strconv.Itoa(r.FD)
+		}
+	case REDIRECT_INPUT, REDIRECT_DUP_IN, REDIRECT_RW, REDIRECT_HEREDOC, REDIRECT_HEREDOC_STRIP, REDIRECT_HERESTRING:
+		if r.FD != 0 {
+			fd = strconv.Itoa(r.FD)
+		}
+	}
+	if r.HereDoc != nil {
+		delimiter := r.HereDoc.Delimiter
+		if r.HereDoc.Quoted {
+			delimiter = "'" + delimiter + "'"
+		}
+		return fd + r.Type.String() + delimiter
+	}
+	target := ""
+	if r.Target != nil {
+		target = r.Target.String()
+	}
+	if r.Type == REDIRECT_DUP_OUT || r.Type == REDIRECT_DUP_IN {
+		return fd + r.Type.String() + target
+	}
+	return fd + r.Type.String() + " " + target
+}
+
+// String 返回重定向的运算符
+func (t RedirectType) String() string {
+	switch t {
+	case REDIRECT_INPUT:
+		return "<"
+	case REDIRECT_OUTPUT:
+		return ">"
+	case REDIRECT_APPEND:
+		return ">>"
+	case REDIRECT_HEREDOC:
+		return "<<"
+	case REDIRECT_HEREDOC_STRIP:
+		return "<<-"
+	case REDIRECT_HERESTRING:
+		return "<<<"
+	case REDIRECT_DUP_IN:
+		return "<&"
+	case REDIRECT_DUP_OUT:
+		return ">&"
+	case REDIRECT_CLOBBER:
+		return ">|"
+	case REDIRECT_RW:
+		return "<>"
+	}
+	return "?"
+}
+
+// String 输出 case 子句的源代码，如 a | b) echo ab ;;
+func (c *CaseClause) String() string {
+	p := &printer{oneLine: true}
+	p.out.WriteString(strings.Join(c.Patterns, " | ") + ")")
+	if c.Body != nil {
+		for i, stmt := range c.Body.Statements {
+			if i > 0 {
+				p.terminate()
+			}
+			p.newline()
+			p.statement(stmt)
+		}
+	}
+	p.out.WriteString(" ;;")
+	return p.out.String()
+}
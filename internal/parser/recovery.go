@@ -15,6 +15,7 @@ var syncPointTokens = []lexer.TokenType{
 	lexer.IF,           // if
 	lexer.FOR,          // for
 	lexer.WHILE,        // while
+	lexer.UNTIL,        // until
 	lexer.CASE,         // case
 	lexer.FUNCTION,     // function
 	lexer.DO,           // do
@@ -129,6 +130,39 @@ func (p *Parser) recoverToControlFlowEnd() bool {
 	return false
 }
 
+// compoundClosers 复合命令的开始 token 对应的结束 token
+var compoundClosers = map[lexer.TokenType]lexer.TokenType{
+	lexer.IF:     lexer.FI,
+	lexer.FOR:    lexer.DONE,
+	lexer.WHILE:  lexer.DONE,
+	lexer.UNTIL:  lexer.DONE,
+	lexer.SELECT: lexer.DONE,
+	lexer.CASE:   lexer.ESAC,
+	lexer.LBRACE: lexer.RBRACE,
+	lexer.LPAREN: lexer.RPAREN,
+}
+
+// skipCompound 复合命令的开头（for 的变量名和单词列表、if 和 while 的条件）有错误时，
+// 跳过复合命令余下的部分直到与它匹配的结束 token（嵌套的复合命令整个跳过），
+// 这样其中的 do、done、fi 等不会再作为新的语句报告错误，一个错误只报告一次
+func (p *Parser) skipCompound(closer lexer.TokenType) {
+	var pending []lexer.TokenType // 嵌套的复合命令的结束 token
+	for p.curToken.Type != lexer.EOF {
+		tok := p.curToken.Type
+		p.nextToken()
+		switch {
+		case len(pending) == 0 && tok == closer:
+			return
+		case len(pending) > 0 && tok == pending[len(pending)-1]:
+			pending = pending[:len(pending)-1]
+		default:
+			if end, ok := compoundClosers[tok]; ok {
+				pending = append(pending, end)
+			}
+		}
+	}
+}
+
 // shouldContinueAfterError 判断是否应该在错误后继续解析
 // 根据错误类型决定是否继续
 func (p *Parser) shouldContinueAfterError(errType ErrorType) bool {
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"gobash/internal/lexer"
 )
@@ -136,3 +138,39 @@ func TestRecoverFromUnclosedError(t *testing.T) {
 	}
 }
 
+
+// TestMultipleErrors 测试一次解析报告多个语法错误：每个错误只报告一次，位置正确，错误之后的语句继续解析
+func TestMultipleErrors(t *testing.T) {
+	input := `echo start
+for 1 in a b; do
+  if x; then echo y; fi
+done
+while true do
+  echo y
+done
+if true; fi
+for ((i = 0; i < 3; i++)); do echo $i; done
+echo a )
+echo end
+`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+
+	var got []string
+	for _, err := range p.AllErrors() {
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("意外的错误类型: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%d:%d %s", e.Token.Line, e.Token.Column, e.Token.Literal))
+	}
+	want := []string{"2:5 1", "7:1 done", "8:10 fi", "9:1 for", "10:8 )"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("错误 %v，期望 %v", got, want)
+	}
+
+	last := program.Statements[len(program.Statements)-1]
+	if last.String() != "echo end" {
+		t.Errorf("最后一条语句为 %q，期望 echo end", last.String())
+	}
+}
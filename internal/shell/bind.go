@@ -0,0 +1,255 @@
+package shell
+
+import (
+	"fmt"
+	"gobash/internal/i18n"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chzyer/readline"
+)
+
+// editingFunctions bind 支持的行编辑函数（名称与 GNU readline 相同）及 readline 库中执行该函数的按键
+var editingFunctions = map[string]rune{
+	"abort":                  readline.CharBell,
+	"accept-line":            readline.CharEnter,
+	"backward-char":          readline.CharBackward,
+	"backward-delete-char":   readline.CharBackspace,
+	"backward-kill-word":     readline.MetaBackspace,
+	"backward-word":          readline.MetaBackward,
+	"beginning-of-line":      readline.CharLineStart,
+	"clear-screen":           readline.CharCtrlL,
+	"complete":               readline.CharTab,
+	"delete-char":            readline.CharDelete,
+	"end-of-line":            readline.CharLineEnd,
+	"forward-char":           readline.CharForward,
+	"forward-search-history": readline.CharFwdSearch,
+	"forward-word":           readline.MetaForward,
+	"kill-line":              readline.CharKill,
+	"kill-word":              readline.MetaDelete,
+	"next-history":           readline.CharNext,
+	"previous-history":       readline.CharPrev,
+	"reverse-search-history": readline.CharBckSearch,
+	"transpose-chars":        readline.CharTranspose,
+	"unix-line-discard":      readline.CharCtrlU,
+	"unix-word-rubout":       readline.CharCtrlW,
+	"yank":                   readline.CharCtrlY,
+}
+
+// metaKeys \e 之后的字符对应的按键（readline 库把这些 ESC 序列解码为单个按键）
+var metaKeys = map[string]rune{
+	"b":     readline.MetaBackward,
+	"f":     readline.MetaForward,
+	"d":     readline.MetaDelete,
+	"\\C-t": readline.MetaTranspose,
+	"\\C-?": readline.MetaBackspace,
+	"\\177": readline.MetaBackspace,
+}
+
+// unbound 表示按键被 bind -r 解除绑定，按下时不做任何事
+const unbound rune = 0
+
+// keyBindings 用户用 bind 修改的按键绑定：按键映射到执行编辑函数的按键
+// 绑定在命令执行时修改、在 readline 读取按键时查询，因此需要加锁
+type keyBindings struct {
+	mu   sync.Mutex
+	keys map[rune]rune
+}
+
+// filter 作为 readline 的 FuncFilterInputRune，把绑定过的按键替换为执行对应编辑函数的按键
+func (b *keyBindings) filter(r rune) (rune, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	target, ok := b.keys[r]
+	if !ok {
+		return r, true
+	}
+	return target, target != unbound
+}
+
+// set 把按键 key 绑定到执行编辑函数的按键 target
+func (b *keyBindings) set(key, target rune) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.keys == nil {
+		b.keys = make(map[rune]rune)
+	}
+	if key == target {
+		delete(b.keys, key)
+		return
+	}
+	b.keys[key] = target
+}
+
+// functionOf 返回按键当前执行的编辑函数，没有绑定时返回空字符串
+func (b *keyBindings) functionOf(key rune) string {
+	b.mu.Lock()
+	target, ok := b.keys[key]
+	b.mu.Unlock()
+	if !ok {
+		target = key
+	}
+	for name, r := range editingFunctions {
+		if r == target && target != unbound {
+			return name
+		}
+	}
+	return ""
+}
+
+// handleBindCommand 处理 bind 命令：查看和修改行编辑的按键绑定
+// 用法：bind [-lp] [-r 按键序列] ["按键序列": 函数名 ...]
+// -l 列出所有函数名，-p 以可以作为 bind 参数的形式列出绑定，-r 解除按键绑定；
+// 按键序列支持 \C-x（Ctrl+x）、\ex 和 \M-x（Alt+x，只支持 b、f、d、\C-t 和 \C-?）以及 \t 和单个字符
+func (s *Shell) handleBindCommand(args []string, out io.Writer) error {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		arg := args[0]
+		args = args[1:]
+		for i := 1; i < len(arg); i++ {
+			switch arg[i] {
+			case 'l':
+				names := make([]string, 0, len(editingFunctions))
+				for name := range editingFunctions {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Fprintln(out, name)
+				}
+			case 'p':
+				s.printBindings(out)
+			case 'r':
+				if len(args) == 0 {
+					return i18n.Errorf("-r: 需要参数\n用法: bind [-lp] [-r 按键序列] [\"按键序列\": 函数名]")
+				}
+				key, err := parseKeySequence(args[0])
+				if err != nil {
+					return err
+				}
+				args = args[1:]
+				s.bindings.set(key, unbound)
+			default:
+				return i18n.Errorf("-%c: 无效选项\n用法: bind [-lp] [-r 按键序列] [\"按键序列\": 函数名]", arg[i])
+			}
+		}
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasSuffix(arg, ":") && i+1 < len(args) {
+			// 没有整体加引号时（bind "\C-x": 函数名），按键序列和函数名是两个参数
+			i++
+			arg += args[i]
+		}
+		colon := strings.LastIndex(arg, ":")
+		if colon < 0 {
+			return i18n.Errorf("%s: 缺少冒号分隔符", arg)
+		}
+		key, err := parseKeySequence(strings.TrimSpace(arg[:colon]))
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSpace(arg[colon+1:])
+		target, ok := editingFunctions[name]
+		if !ok {
+			return i18n.Errorf("%s: 未知的函数名", name)
+		}
+		s.bindings.set(key, target)
+	}
+	return nil
+}
+
+// printBindings 按函数名顺序输出所有按键绑定（bind -p 的格式）
+func (s *Shell) printBindings(out io.Writer) {
+	keys := []rune{readline.CharBackspace}
+	for r := rune(1); r < 32; r++ {
+		keys = append(keys, r)
+	}
+	for _, r := range metaKeys {
+		keys = append(keys, r)
+	}
+	s.bindings.mu.Lock()
+	for r := range s.bindings.keys {
+		keys = append(keys, r)
+	}
+	s.bindings.mu.Unlock()
+
+	type binding struct{ function, key string }
+	var bindings []binding
+	seen := map[rune]bool{}
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if name := s.bindings.functionOf(key); name != "" {
+			bindings = append(bindings, binding{name, formatKeySequence(key)})
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].function != bindings[j].function {
+			return bindings[i].function < bindings[j].function
+		}
+		return bindings[i].key < bindings[j].key
+	})
+	for _, b := range bindings {
+		fmt.Fprintf(out, "\"%s\": %s\n", b.key, b.function)
+	}
+}
+
+// parseKeySequence 解析 bind 的按键序列（可以带双引号），返回对应的按键
+func parseKeySequence(seq string) (rune, error) {
+	text := seq
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	switch {
+	case strings.HasPrefix(text, "\\C-") && len(text) == 4:
+		c := text[3]
+		if c == '?' {
+			return readline.CharBackspace, nil
+		}
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c > '@' && c <= '_' {
+			return rune(c - '@'), nil
+		}
+	case strings.HasPrefix(text, "\\e"), strings.HasPrefix(text, "\\M-"):
+		meta := strings.TrimPrefix(strings.TrimPrefix(text, "\\e"), "\\M-")
+		if r, ok := metaKeys[meta]; ok {
+			return r, nil
+		}
+	case text == "\\t":
+		return readline.CharTab, nil
+	case text == "\\\\":
+		return '\\', nil
+	case len([]rune(text)) == 1:
+		return []rune(text)[0], nil
+	}
+	return 0, i18n.Errorf("%s: 不支持的按键序列", seq)
+}
+
+// formatKeySequence 返回按键的 bind 写法，如 \C-a、\eb
+func formatKeySequence(key rune) string {
+	for seq, r := range metaKeys {
+		if r == key && seq != "\\177" {
+			return "\\e" + seq
+		}
+	}
+	switch {
+	case key == readline.CharBackspace:
+		return "\\C-?"
+	case key == readline.CharEsc:
+		return "\\e"
+	case key <= 26:
+		return "\\C-" + string(key+'a'-1)
+	case key < 32:
+		return "\\C-" + string(key+'@')
+	case key == '\\':
+		return "\\\\"
+	}
+	return string(key)
+}
@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chzyer/readline"
+)
+
+// TestBindCommand 测试 bind 修改按键绑定以及 set -o vi/emacs 切换编辑模式
+func TestBindCommand(t *testing.T) {
+	s := New()
+	var out strings.Builder
+
+	if err := s.handleBindCommand([]string{`"\C-f": backward-char`}, &out); err != nil {
+		t.Fatalf("bind 失败: %v", err)
+	}
+	if r, ok := s.bindings.filter(readline.CharForward); !ok || r != readline.CharBackward {
+		t.Errorf("\\C-f 应该执行 backward-char，得到 %d", r)
+	}
+	if err := s.handleBindCommand([]string{`\eb:`, "forward-word"}, &out); err != nil {
+		t.Fatalf("bind 失败: %v", err)
+	}
+	if r, _ := s.bindings.filter(readline.MetaBackward); r != readline.MetaForward {
+		t.Errorf("\\eb 应该执行 forward-word，得到 %d", r)
+	}
+	if err := s.handleBindCommand([]string{"-r", `\C-a`}, &out); err != nil {
+		t.Fatalf("bind -r 失败: %v", err)
+	}
+	if _, ok := s.bindings.filter(readline.CharLineStart); ok {
+		t.Error("\\C-a 解除绑定后不应该被处理")
+	}
+
+	if err := s.handleBindCommand([]string{"-p"}, &out); err != nil {
+		t.Fatalf("bind -p 失败: %v", err)
+	}
+	for _, want := range []string{`"\C-f": backward-char`, `"\C-b": backward-char`, `"\e\C-?": backward-kill-word`} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("bind -p 的输出中没有 %s:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "beginning-of-line") {
+		t.Error("解除绑定的按键不应该出现在 bind -p 的输出中")
+	}
+
+	for _, args := range [][]string{{`"\C-x": no-such-function`}, {`"\C-x1": yank`}, {"-z"}} {
+		if err := s.handleBindCommand(args, &out); err == nil {
+			t.Errorf("bind %v 应该失败", args)
+		}
+	}
+
+	if err := s.handleSetCommand([]string{"-o", "vi"}, &out); err != nil {
+		t.Fatalf("set -o vi 失败: %v", err)
+	}
+	if !s.options["vi"] || s.options["emacs"] {
+		t.Error("set -o vi 之后应该使用 vi 模式")
+	}
+	if err := s.handleSetCommand([]string{"+o", "vi"}, &out); err != nil {
+		t.Fatalf("set +o vi 失败: %v", err)
+	}
+	if s.options["vi"] || !s.options["emacs"] {
+		t.Error("set +o vi 之后应该使用 emacs 模式")
+	}
+}
+
+// TestHistorySearch 测试 Ctrl+R、Ctrl+S 增量搜索历史记录
+func TestHistorySearch(t *testing.T) {
+	entries := []string{"echo alpha1", "ls", "echo alpha2", "echo alpha2", "pwd"}
+	search := historySearch{index: len(entries), query: []rune("alp")}
+
+	if got, ok := search.find(entries, false); !ok || got != "echo alpha2" || search.index != 3 {
+		t.Errorf("应该找到最近的 echo alpha2，得到 %q (下标 %d)", got, search.index)
+	}
+	// 继续搜索时跳过相同的记录
+	if got, ok := search.find(entries, true); !ok || got != "echo alpha1" {
+		t.Errorf("再按 Ctrl+R 应该找到 echo alpha1，得到 %q", got)
+	}
+	if _, ok := search.find(entries, true); ok || !search.failed || search.index != 0 {
+		t.Error("没有更早的匹配记录时搜索应该失败并停留在当前记录")
+	}
+	// 当前记录仍然匹配时，输入更多内容不移动
+	search.query = []rune("alpha1")
+	if got, ok := search.find(entries, false); !ok || got != "echo alpha1" || search.failed {
+		t.Errorf("echo alpha1 仍然匹配 alpha1，得到 %q", got)
+	}
+
+	search.forward, search.query = true, []rune("alpha")
+	if got, ok := search.find(entries, true); !ok || got != "echo alpha2" || search.index != 2 {
+		t.Errorf("Ctrl+S 应该找到较新的 echo alpha2，得到 %q (下标 %d)", got, search.index)
+	}
+
+	empty := historySearch{query: []rune("x")}
+	if _, ok := empty.find(nil, false); ok || !empty.failed {
+		t.Error("历史记录为空时搜索应该失败")
+	}
+}
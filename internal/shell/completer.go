@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"gobash/internal/builtin"
 	"os"
 	"path/filepath"
 	"strings"
@@ -52,10 +53,12 @@ func (c *Completer) completeCommands(prefix string) ([][]rune, int) {
 	
 	// 1. 内置命令
 	builtins := []string{
-		"cd", "pwd", "echo", "exit", "export", "unset", "env", "set",
-		"ls", "cat", "mkdir", "rmdir", "rm", "touch", "clear",
+		"cd", "pwd", "pushd", "popd", "dirs", "echo", "exit", "export", "unset", "env", "set", "shopt", "bind",
+		"ls", "cat", "mkdir", "rmdir", "rm", "touch", "clear", "du", "df", "basename", "dirname", "realpath", "mktemp", "date", "sleep", "timeout",
 		"alias", "unalias", "history", "which", "type", "true", "false",
-		"test", "[", "head", "tail", "wc", "grep", "sort", "uniq", "cut",
+		"jobs", "fg", "bg", "kill", "disown", "ps", "pgrep", "pkill", "http",
+		"test", "[", "head", "tail", "wc", "grep", "sed", "sort", "uniq", "cut", "tr", "xargs", "seq", "tee",
+		"base64", "md5sum", "sha256sum", "tar", "gzip", "gunzip", "diff", "cmp", "stat",
 	}
 	
 	for _, cmd := range builtins {
@@ -75,12 +78,10 @@ func (c *Completer) completeCommands(prefix string) ([][]rune, int) {
 	}
 	
 	// 3. PATH中的外部命令（简化版，只检查常见命令）
-	pathEnv := os.Getenv("PATH")
+	pathEnv, _ := c.shell.executor.GetEnv("PATH")
 	if pathEnv != "" {
-		paths := strings.Split(pathEnv, ":")
-		if len(paths) == 0 {
-			paths = strings.Split(pathEnv, ";")
-		}
+		paths := filepath.SplitList(pathEnv)
+		env := c.shell.executor.GetEnvMap()
 		
 		seen := make(map[string]bool)
 		for _, path := range paths {
@@ -95,11 +96,8 @@ func (c *Completer) completeCommands(prefix string) ([][]rune, int) {
 				if entry.IsDir() {
 					continue
 				}
-				name := entry.Name()
-				// 移除.exe扩展名（Windows）
-				if strings.HasSuffix(name, ".exe") {
-					name = name[:len(name)-4]
-				}
+				// Windows 上 mytool.exe 补全为 mytool
+				name := builtin.TrimPathExt(entry.Name(), env)
 				if strings.HasPrefix(name, prefix) && !seen[name] {
 					seen[name] = true
 					suffix := name[len(prefix):]
@@ -164,6 +162,10 @@ func (c *Completer) completeFiles(prefix string) ([][]rune, int) {
 	if dir == "" {
 		dir = "."
 	}
+	// 相对路径按 shell 的工作目录（PWD）解析
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(c.shell.executor.Dir(), dir)
+	}
 	
 	// 读取目录
 	entries, err := os.ReadDir(dir)
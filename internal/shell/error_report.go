@@ -2,9 +2,11 @@ package shell
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"gobash/internal/executor"
+	"gobash/internal/i18n"
 	"gobash/internal/lexer"
 	"gobash/internal/parser"
 )
@@ -14,6 +16,7 @@ type ErrorReporter struct {
 	scriptPath string // 脚本文件路径（如果是在执行脚本）
 	lineNum    int    // 当前行号
 	isInteractive bool // 是否是交互式模式
+	output     io.Writer // 错误消息的输出位置，nil 表示标准错误
 }
 
 // NewErrorReporter 创建新的错误报告器
@@ -29,10 +32,16 @@ func (er *ErrorReporter) SetLineNum(lineNum int) {
 	er.lineNum = lineNum
 }
 
+// SetOutput 设置错误消息的输出位置，nil 表示标准错误
+func (er *ErrorReporter) SetOutput(w io.Writer) {
+	er.output = w
+}
+
 // ReportError 报告错误
 // 根据错误类型格式化错误消息，参考 bash 的错误格式
 func (er *ErrorReporter) ReportError(err error) {
-	if err == nil {
+	// 只表示退出状态的错误（如 realpath -q、外部命令或子shell以非零状态结束）与 bash 相同，不输出
+	if executor.StatusOnly(err) {
 		return
 	}
 
@@ -60,7 +69,11 @@ func (er *ErrorReporter) ReportError(err error) {
 	}
 
 	// 输出错误消息到 stderr
-	fmt.Fprintf(os.Stderr, "%s\n", errorMsg)
+	output := er.output
+	if output == nil {
+		output = os.Stderr
+	}
+	fmt.Fprintf(output, "%s\n", errorMsg)
 
 	// 在非交互式模式下，如果设置了 set -e，应该退出
 	// 但这里只负责报告错误，退出逻辑由调用者处理
@@ -73,7 +86,7 @@ func (er *ErrorReporter) formatExecutionError(e *executor.ExecutionError) string
 	var prefix string
 	if er.scriptPath != "" {
 		if er.lineNum > 0 {
-			prefix = fmt.Sprintf("gobash: %s: 第%d行", er.scriptPath, er.lineNum)
+			prefix = i18n.Sprintf("gobash: %s: 第%d行", er.scriptPath, er.lineNum)
 		} else {
 			prefix = fmt.Sprintf("gobash: %s", er.scriptPath)
 		}
@@ -84,7 +97,7 @@ func (er *ErrorReporter) formatExecutionError(e *executor.ExecutionError) string
 		} else {
 			// 非交互式模式：gobash: 行号: 错误消息
 			if er.lineNum > 0 {
-				prefix = fmt.Sprintf("gobash: 第%d行", er.lineNum)
+				prefix = i18n.Sprintf("gobash: 第%d行", er.lineNum)
 			} else {
 				prefix = "gobash"
 			}
@@ -113,6 +126,9 @@ func (er *ErrorReporter) formatParseError(e *parser.ParseError) string {
 		if !containsScriptPath(errorMsg, er.scriptPath) {
 			return fmt.Sprintf("gobash: %s: %s", er.scriptPath, errorMsg)
 		}
+	} else if !containsPrefix(errorMsg, "gobash") {
+		// -c 命令和交互式输入：与其他错误一样添加 gobash 前缀
+		return fmt.Sprintf("gobash: %s", errorMsg)
 	}
 
 	return errorMsg
@@ -145,7 +161,7 @@ func (er *ErrorReporter) formatGenericError(e error) string {
 	var prefix string
 	if er.scriptPath != "" {
 		if er.lineNum > 0 {
-			prefix = fmt.Sprintf("gobash: %s: 第%d行", er.scriptPath, er.lineNum)
+			prefix = i18n.Sprintf("gobash: %s: 第%d行", er.scriptPath, er.lineNum)
 		} else {
 			prefix = fmt.Sprintf("gobash: %s", er.scriptPath)
 		}
@@ -154,7 +170,7 @@ func (er *ErrorReporter) formatGenericError(e error) string {
 			prefix = "gobash"
 		} else {
 			if er.lineNum > 0 {
-				prefix = fmt.Sprintf("gobash: 第%d行", er.lineNum)
+				prefix = i18n.Sprintf("gobash: 第%d行", er.lineNum)
 			} else {
 				prefix = "gobash"
 			}
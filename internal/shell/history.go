@@ -2,6 +2,9 @@ package shell
 
 import (
 	"fmt"
+	"gobash/internal/lexer"
+	"gobash/internal/parser"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,16 +26,16 @@ func NewHistory(maxSize int) *History {
 	}
 }
 
-// Add 添加命令到历史
-func (h *History) Add(cmd string) {
+// Add 添加命令到历史，命令为空或与上一条相同时不添加并返回 false
+func (h *History) Add(cmd string) bool {
 	cmd = strings.TrimSpace(cmd)
 	if cmd == "" {
-		return
+		return false
 	}
 	
 	// 避免重复添加相同的命令
 	if len(h.commands) > 0 && h.commands[len(h.commands)-1] == cmd {
-		return
+		return false
 	}
 
 	h.commands = append(h.commands, cmd)
@@ -40,6 +43,7 @@ func (h *History) Add(cmd string) {
 		h.commands = h.commands[1:]
 	}
 	h.index = len(h.commands)
+	return true
 }
 
 // Get 获取指定索引的历史命令
@@ -119,10 +123,113 @@ func (h *History) SaveToFile(filename string) error {
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
-// Print 打印历史记录
-func (h *History) Print() {
+// Print 将历史记录输出到 w
+func (h *History) Print(w io.Writer) {
 	for i, cmd := range h.commands {
-		fmt.Printf("%5d  %s\n", i+1, cmd)
+		fmt.Fprintf(w, "%5d  %s\n", i+1, cmd)
 	}
 }
 
+
+// historyEntry 把跨多行的语句合并为一条单行的历史记录（与 bash 的 cmdhist 选项一样）
+// 行之间的换行按语法替换为 "; "（如 if true; then）或空格（如 then 或 | 之后），续行符连同换行一起去掉；
+// 含有 here-document 的语句和以注释结尾的行保留换行，合并后无法解析时返回原来的语句
+func historyEntry(statement string) string {
+	statement = strings.TrimSpace(statement)
+	if !strings.Contains(statement, "\n") {
+		return statement
+	}
+
+	// 找出语句之间的换行（不在引号中、前面没有注释）和续行符，here-document 的正文不能合并
+	joins := map[int]bool{}
+	var singleQuoted [][2]int
+	l := lexer.New(statement)
+	for tok := l.NextToken(); tok.Type != lexer.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case lexer.REDIRECT_HEREDOC, lexer.REDIRECT_HEREDOC_STRIP:
+			return statement
+		case lexer.NEWLINE:
+			if tok.Pos < len(statement) && statement[tok.Pos] == '\n' {
+				joins[tok.Pos] = true
+			}
+		case lexer.STRING_SINGLE:
+			singleQuoted = append(singleQuoted, [2]int{tok.Pos, tok.End})
+		}
+	}
+	for i := strings.IndexByte(statement, '\n'); i >= 0; i = nextNewline(statement, i) {
+		if !joins[i] && isLineContinuation(statement, i, singleQuoted) {
+			joins[i] = false
+		}
+	}
+
+	var entry strings.Builder
+	var rest string
+	offset := 0
+	for i := 0; i < len(statement); i++ {
+		separate, ok := joins[i]
+		if !ok {
+			continue
+		}
+		line := statement[offset:i]
+		if !separate {
+			// 续行符：去掉反斜杠和换行
+			entry.WriteString(line[:len(line)-1])
+			offset = i + 1
+			continue
+		}
+		rest = strings.TrimLeft(statement[i+1:], " \t")
+		offset = len(statement) - len(rest)
+		entry.WriteString(line)
+		prefix := strings.TrimRight(entry.String(), " \t")
+		if strings.HasSuffix(prefix, "\\") {
+			// 保留被转义的空白
+			prefix += " "
+		}
+		entry.Reset()
+		if parses(prefix + "; " + rest) {
+			entry.WriteString(prefix + "; ")
+		} else {
+			entry.WriteString(prefix + " ")
+		}
+	}
+	entry.WriteString(statement[offset:])
+
+	joined := entry.String()
+	if !parses(joined) {
+		return statement
+	}
+	return joined
+}
+
+// nextNewline 返回 s 中 i 之后的下一个换行符的位置，没有时返回 -1
+func nextNewline(s string, i int) int {
+	next := strings.IndexByte(s[i+1:], '\n')
+	if next < 0 {
+		return -1
+	}
+	return i + 1 + next
+}
+
+// isLineContinuation 判断位置 i 的换行符是否是续行（前面有奇数个反斜杠，且不在单引号中）
+func isLineContinuation(s string, i int, singleQuoted [][2]int) bool {
+	backslashes := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		backslashes++
+	}
+	if backslashes%2 == 0 {
+		return false
+	}
+	for _, r := range singleQuoted {
+		if i >= r[0] && i < r[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// parses 判断 input 是否是没有词法和语法错误的完整输入
+func parses(input string) bool {
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+	return len(p.AllErrors()) == 0
+}
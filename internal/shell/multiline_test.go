@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -182,11 +184,71 @@ func TestIsStatementComplete(t *testing.T) {
 			statement: "echo hello \\",
 			expected:  false, // 反斜杠结尾表示未完成
 		},
+		{
+			name:     "以 && 结尾",
+			statement: "echo a &&",
+			expected:  false,
+		},
+		{
+			name:     "以 || 结尾，后面有注释",
+			statement: "echo a || # 注释",
+			expected:  false,
+		},
+		{
+			name:     "以管道符结尾",
+			statement: "echo a |\n",
+			expected:  false,
+		},
+		{
+			name:     "&& 之后的命令在下一行",
+			statement: "echo a &&\n  echo b",
+			expected:  true,
+		},
 		{
 			name:     "空语句",
 			statement: "",
 			expected:  true,
 		},
+		{
+			name:     "未结束的here-document",
+			statement: "cat <<EOF\nhello",
+			expected:  false,
+		},
+		{
+			name:     "结束的here-document",
+			statement: "cat <<EOF\nhello\nEOF",
+			expected:  true,
+		},
+		{
+			name:     "只输入了 here-document 重定向",
+			statement: "cat <<EOF",
+			expected:  false,
+		},
+		{
+			name:     "<<- 的结束分隔符前有制表符",
+			statement: "cat <<-EOF\n\thello\n\tEOF",
+			expected:  true,
+		},
+		{
+			name:     "here-document 中的 if 不需要 fi",
+			statement: "cat <<'EOF'\nif true; then\nEOF",
+			expected:  true,
+		},
+		{
+			name:     "引号中的换行",
+			statement: "echo \"hello\nworld",
+			expected:  false,
+		},
+		{
+			name:     "语法错误不是未完成",
+			statement: "fi",
+			expected:  true,
+		},
+		{
+			name:     "未完成的函数定义",
+			statement: "f() {\n  echo hi",
+			expected:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -205,3 +267,99 @@ func TestIsStatementComplete(t *testing.T) {
 
 
 
+
+// TestFinishHereDocs 测试输入结束时补上未结束的 here-document 的分隔符
+func TestFinishHereDocs(t *testing.T) {
+	s := New()
+
+	var statement strings.Builder
+	statement.WriteString("cat <<A; cat <<B\nline")
+	if !s.finishHereDocs(&statement) {
+		t.Fatal("应该补上未结束的 here-document")
+	}
+	if got := statement.String(); got != "cat <<A; cat <<B\nline\nA\nB" {
+		t.Errorf("补全后的语句错误: %q", got)
+	}
+	if !s.isStatementComplete(statement.String()) {
+		t.Error("补全后的语句应该是完整的")
+	}
+
+	statement.Reset()
+	statement.WriteString("if true; then")
+	if s.finishHereDocs(&statement) {
+		t.Error("没有 here-document 时不应该补全")
+	}
+}
+
+// TestHistoryEntry 测试跨多行的语句合并为一条历史记录
+func TestHistoryEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		expected  string
+	}{
+		{"单行命令", "echo hello", "echo hello"},
+		{"if 语句", "if true\nthen\n  echo a\nfi", "if true; then echo a; fi"},
+		{"for 循环", "for i in 1 2\ndo\n  echo $i\ndone", "for i in 1 2; do echo $i; done"},
+		{"管道之后换行", "echo a |\n  tr a b", "echo a | tr a b"},
+		{"&& 之后换行", "true &&\necho b", "true && echo b"},
+		{"函数定义", "f() {\n  echo hi\n}", "f() { echo hi; }"},
+		{"空行", "if true; then\n\n  echo a\nfi", "if true; then echo a; fi"},
+		{"续行符", "echo a \\\n  b", "echo a   b"},
+		{"续行符连接单词", "echo a\\\nb", "echo ab"},
+		{"引号中的换行保留", "echo \"a\nb\"", "echo \"a\nb\""},
+		{"注释之后的换行保留", "echo a # c\necho b", "echo a # c\necho b"},
+		{"here-document 保留", "cat <<EOF\nhi\nEOF", "cat <<EOF\nhi\nEOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := historyEntry(tt.statement); got != tt.expected {
+				t.Errorf("历史记录不匹配。\n语句: %q\n期望: %q\n得到: %q", tt.statement, tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestContinuationPrompt 测试续行提示符使用 PS2
+func TestContinuationPrompt(t *testing.T) {
+	s := New()
+	if got := s.continuationPrompt(); got != "> " {
+		t.Errorf("默认续行提示符应该是 \"> \"，得到 %q", got)
+	}
+	s.executor.SetEnv("NAME", "more")
+	s.executor.SetEnv("PS2", "$NAME> ")
+	if got := s.continuationPrompt(); got != "more> " {
+		t.Errorf("续行提示符应该展开 PS2，得到 %q", got)
+	}
+}
+
+// TestPrimaryPrompt 测试 PS1 的转义序列、颜色和变量展开
+func TestPrimaryPrompt(t *testing.T) {
+	s := New()
+	home := t.TempDir()
+	dir := filepath.Join(home, "a$b")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	s.executor.SetEnv("HOME", home)
+	s.executor.SetEnv("PWD", dir)
+	s.executor.SetEnv("USER", "tester")
+	s.executor.SetEnv("NAME", "more")
+
+	tests := []struct {
+		ps1      string
+		expected string
+	}{
+		{`\u:\w> `, "tester:~/a$b> "},
+		{`[\W]\\ `, `[a$b]\ `},
+		{`\[\e[32m\]$NAME\[\e[0m\] `, "\033[32mmore\033[0m "},
+		{`\033[1m\n> `, "\033[1m\n> "},
+	}
+	for _, tt := range tests {
+		s.executor.SetEnv("PS1", tt.ps1)
+		if got := s.primaryPrompt(); got != tt.expected {
+			t.Errorf("PS1=%q 展开为 %q，期望 %q", tt.ps1, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// historySearch 交互模式下 Ctrl+R（Ctrl+S）增量搜索历史记录的状态
+// 按键由 readline 的 FuncFilterInputRune 交给 shell 处理，搜索的是 shell 的历史记录（与 history 命令相同）
+type historySearch struct {
+	active  bool
+	forward bool   // Ctrl+S 向新的记录搜索，Ctrl+R 向旧的记录搜索
+	failed  bool   // 没有找到匹配的记录
+	query   []rune // 已输入的搜索内容
+	index   int    // 当前匹配的记录在历史记录中的下标
+	line    string // 开始搜索前正在编辑的行，取消搜索时恢复
+}
+
+// filterKey 处理行编辑器读到的按键：先应用 bind 设置的绑定，再处理历史搜索
+// 返回 false 表示按键已被 shell 处理，行编辑器忽略它
+func (s *Shell) filterKey(r rune) (rune, bool) {
+	r, ok := s.bindings.filter(r)
+	if !ok || s.editor == nil {
+		return r, ok
+	}
+	search := &s.search
+	if !search.active {
+		if r == readline.CharBckSearch || r == readline.CharFwdSearch {
+			search.active, search.failed, search.query = true, false, nil
+			search.forward = r == readline.CharFwdSearch
+			search.index = s.history.Size()
+			s.refreshSearch()
+			return r, false
+		}
+		return r, true
+	}
+
+	switch {
+	case r == readline.CharBckSearch || r == readline.CharFwdSearch:
+		// 再按一次时查找下一个匹配的记录
+		search.forward = r == readline.CharFwdSearch
+		s.searchHistory(true)
+	case r == readline.CharBackspace || r == readline.CharCtrlH:
+		if len(search.query) > 0 {
+			search.query = search.query[:len(search.query)-1]
+			search.index = s.history.Size()
+			s.searchHistory(false)
+		}
+	case r == readline.CharBell:
+		// Ctrl+G 取消搜索，恢复原来的行
+		s.endSearch()
+		s.editor.Operation.SetBuffer(search.line)
+	case r >= ' ':
+		search.query = append(search.query, r)
+		s.searchHistory(false)
+	default:
+		// 其他按键（回车、方向键、Ctrl+A 等）结束搜索，在找到的记录上继续编辑
+		s.endSearch()
+		return r, true
+	}
+	return r, false
+}
+
+// trackLine 作为 readline 的 Listener 记录正在编辑的行
+func (s *Shell) trackLine(line []rune, pos int, key rune) ([]rune, int, bool) {
+	if !s.search.active {
+		s.search.line = string(line)
+	}
+	return nil, 0, false
+}
+
+// searchHistory 查找下一个匹配的记录并显示在编辑行中，next 为 true 时跳过当前记录
+func (s *Shell) searchHistory(next bool) {
+	if entry, ok := s.search.find(s.history.GetAll(), next); ok {
+		s.editor.Operation.SetBuffer(entry)
+	}
+	s.refreshSearch()
+}
+
+// find 从当前匹配的记录开始按搜索方向查找包含搜索内容的记录，next 为 true 时跳过当前记录及与它相同的记录
+// 找到时更新当前记录的下标，搜索内容为空时不查找
+func (h *historySearch) find(entries []string, next bool) (string, bool) {
+	step := -1
+	if h.forward {
+		step = 1
+	}
+	current := ""
+	if h.index >= 0 && h.index < len(entries) {
+		current = entries[h.index]
+	}
+	i := h.index
+	if next || current == "" {
+		i += step
+	}
+
+	h.failed = false
+	if len(h.query) == 0 {
+		return "", false
+	}
+	for ; i >= 0 && i < len(entries); i += step {
+		if strings.Contains(entries[i], string(h.query)) && !(next && entries[i] == current) {
+			h.index = i
+			return entries[i], true
+		}
+	}
+	h.failed = true
+	return "", false
+}
+
+// refreshSearch 在提示符中显示搜索内容（与 bash 相同的格式）
+func (s *Shell) refreshSearch() {
+	search := &s.search
+	name := "reverse-i-search"
+	if search.forward {
+		name = "i-search"
+	}
+	if search.failed {
+		name = "failed " + name
+	}
+	s.editor.SetPrompt(fmt.Sprintf("(%s)`%s': ", name, string(search.query)))
+}
+
+// endSearch 结束搜索，恢复原来的提示符
+func (s *Shell) endSearch() {
+	s.search.active = false
+	s.editor.SetPrompt(s.editorPrompt)
+}
+
+// setPrompt 设置行编辑器的提示符，搜索结束后恢复为它
+func (s *Shell) setPrompt(prompt string) {
+	s.editorPrompt = prompt
+	s.editor.SetPrompt(prompt)
+}
@@ -11,9 +11,12 @@ package shell
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"gobash/internal/builtin"
 	"gobash/internal/executor"
+	"gobash/internal/i18n"
 	"gobash/internal/lexer"
 	"gobash/internal/parser"
 	"io"
@@ -33,8 +36,12 @@ type Shell struct {
 	running       bool
 	aliases       map[string]string
 	history       *History
-	options       map[string]bool // shell选项状态
-	errorReporter *ErrorReporter  // 错误报告器
+	options       map[string]bool    // shell选项状态
+	errorReporter *ErrorReporter     // 错误报告器
+	bindings      keyBindings        // bind 设置的按键绑定
+	search        historySearch      // Ctrl+R 增量搜索历史记录的状态
+	editorPrompt  string             // 行编辑器当前的提示符（不含搜索时显示的提示符）
+	editor        *readline.Instance // 交互模式下的行编辑器，set -o vi/emacs 切换它的编辑模式
 }
 
 // New 创建新的Shell实例
@@ -52,36 +59,121 @@ func New() *Shell {
 		history.LoadFromFile(historyFile)
 	}
 
+	return newShell(history, NewErrorReporter("", true)) // 交互式模式
+}
+
+// NewNonInteractive 创建只用于执行脚本的Shell实例
+// 不加载历史记录，错误按非交互模式报告（用于把 gobash 作为库嵌入）
+func NewNonInteractive() *Shell {
+	return newShell(NewHistory(1000), NewErrorReporter("", false))
+}
+
+// newShell 创建Shell实例并注册由 shell 层实现的命令
+func newShell(history *History, errorReporter *ErrorReporter) *Shell {
+	ex := executor.New()
 	sh := &Shell{
-		executor:      executor.New(),
-		prompt:        getPrompt(),
+		executor:      ex,
+		prompt:        getPrompt(ex.Dir()),
 		running:       true,
 		aliases:       make(map[string]string),
 		history:       history,
 		options:       make(map[string]bool),
-		errorReporter: NewErrorReporter("", true), // 交互式模式
+		errorReporter: errorReporter,
 	}
 
 	// 将选项状态传递给执行器
 	sh.executor.SetOptions(sh.options)
+	sh.executor.SetInteractive(errorReporter.isInteractive)
+
+	// 跟踪输出默认写到标准错误，GOBASH_TRACE_FILE 指定写入的文件
+	if traceFile := os.Getenv("GOBASH_TRACE_FILE"); traceFile != "" {
+		if f, err := os.OpenFile(traceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+			sh.executor.SetTraceSink(executor.NewWriterTraceSink(f))
+		}
+	}
+
+	// type 等命令通过执行器查询 shell 的别名
+	sh.executor.SetAliasLookup(func(name string) (string, bool) {
+		value, ok := sh.aliases[name]
+		return value, ok
+	})
+
+	// 需要访问 shell 状态的命令由 shell 层实现
+	sh.executor.RegisterBuiltin("alias", func(args []string, env map[string]string, stdio *builtin.IO) error {
+		return sh.handleAliasCommand(args, stdio.Stdout)
+	})
+	sh.executor.RegisterBuiltin("unalias", func(args []string, env map[string]string, stdio *builtin.IO) error {
+		return sh.handleUnaliasCommand(args)
+	})
+	sh.executor.RegisterBuiltin("history", func(args []string, env map[string]string, stdio *builtin.IO) error {
+		return sh.handleHistoryCommand(args, stdio.Stdout)
+	})
+	sh.executor.RegisterBuiltin("set", func(args []string, env map[string]string, stdio *builtin.IO) error {
+		return sh.handleSetCommand(args, stdio.Stdout)
+	})
+	sh.executor.RegisterBuiltin("shopt", func(args []string, env map[string]string, stdio *builtin.IO) error {
+		return sh.handleShoptCommand(args, stdio.Stdout)
+	})
+	sh.executor.RegisterBuiltin("bind", func(args []string, env map[string]string, stdio *builtin.IO) error {
+		return sh.handleBindCommand(args, stdio.Stdout)
+	})
 
 	return sh
 }
 
+// Executor 返回Shell使用的执行器
+func (s *Shell) Executor() *executor.Executor {
+	return s.executor
+}
+
+// SetScriptPath 设置错误消息中显示的脚本路径（非交互式模式）
+func (s *Shell) SetScriptPath(scriptPath string) {
+	reporter := NewErrorReporter(scriptPath, false)
+	reporter.SetOutput(s.errorReporter.output)
+	s.errorReporter = reporter
+	s.executor.SetScriptName(scriptPath)
+	s.executor.SetInteractive(false)
+}
+
+// SetPositionalArgs 设置 $0 为 name，位置参数（$1, $2, ...）为 args，以及 $#、$@
+func (s *Shell) SetPositionalArgs(name string, args []string) {
+	s.executor.SetEnv("0", name)
+	for i, arg := range args {
+		s.executor.SetEnv(fmt.Sprintf("%d", i+1), arg)
+	}
+	s.executor.SetEnv("#", fmt.Sprintf("%d", len(args)))
+	s.executor.SetEnv("@", strings.Join(args, " "))
+}
+
+// SetOptions 按 set 的参数格式设置 shell 选项（如 -e、+x、-o pipefail），用于命令行中的 bash 风格选项
+func (s *Shell) SetOptions(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	return s.handleSetCommand(args, io.Discard)
+}
+
+// SetStdio 设置脚本的标准输入、输出和错误输出，nil 表示使用进程的标准流
+// 命令的输出和错误消息都写入这里，不修改进程的 os.Stdout 等全局变量
+func (s *Shell) SetStdio(in io.Reader, out, errOut io.Writer) {
+	s.executor.SetStdio(in, out, errOut)
+	s.errorReporter.SetOutput(errOut)
+}
+
 // Run 运行交互式Shell
 // 启动REPL循环，支持readline库的交互功能（历史记录、自动补全等）
 // 如果readline不可用，会自动回退到简单的输入模式
 func (s *Shell) Run() {
-	// 配置readline
-	home := os.Getenv("HOME")
-	if home == "" {
-		home = os.Getenv("USERPROFILE")
+	// 在终端上启用作业控制（set -m），set +m 可以关闭
+	if s.executor.EnableJobControl(os.Stdin) {
+		s.options["m"] = true
+		s.executor.SetOptions(s.options)
 	}
 
-	historyFile := ""
-	if home != "" {
-		historyFile = filepath.Join(home, ".gobash_history")
-	}
+	// 让终端解释提示符和命令输出中的 ANSI 转义序列（颜色等）
+	builtin.EnableVirtualTerminal(os.Stdout)
+	builtin.EnableVirtualTerminal(os.Stderr)
+	s.prompt = s.primaryPrompt()
 
 	// 创建自动补全器
 	completer := NewCompleter(s)
@@ -89,11 +181,16 @@ func (s *Shell) Run() {
 	// 创建readline配置
 	config := &readline.Config{
 		Prompt:          s.prompt,
-		HistoryFile:     historyFile,
 		HistoryLimit:    1000,
 		AutoComplete:    completer,
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
+		// 历史记录由 shell 在语句完成后保存，跨多行的语句作为一条记录
+		DisableAutoSaveHistory: true,
+		// 行编辑默认使用 emacs 模式，set -o vi 切换到 vi 模式
+		VimMode:             s.options["vi"],
+		FuncFilterInputRune: s.filterKey,
+		Listener:            readline.FuncListener(s.trackLine),
 	}
 
 	rl, err := readline.NewEx(config)
@@ -103,12 +200,21 @@ func (s *Shell) Run() {
 		return
 	}
 	defer rl.Close()
+	s.editor = rl
+	if !s.options["vi"] {
+		s.options["emacs"] = true
+	}
 
-	// readline会自动从HistoryFile加载历史记录，无需手动添加
+	// 行编辑器的历史（上下键和 Ctrl+R 搜索）使用 shell 的历史记录，退出时由 shell 保存到文件
+	for _, entry := range s.history.GetAll() {
+		rl.SaveHistory(entry)
+	}
+	defer s.finish(nil)
 
 	for s.running {
-		// 更新提示符
-		rl.SetPrompt(s.prompt)
+		// 报告结束或停止的后台作业，然后更新提示符
+		s.executor.NotifyJobs()
+		s.setPrompt(s.prompt)
 
 		var currentStatement strings.Builder
 		for {
@@ -120,12 +226,17 @@ func (s *Shell) Run() {
 					currentStatement.Reset()
 					break
 				}
+				// 在 here-document 中按 Ctrl+D 时结束正文并执行，否则退出
+				if err == io.EOF && s.finishHereDocs(&currentStatement) {
+					break
+				}
+				if err == io.EOF {
+					s.reportUnfinished(currentStatement.String())
+				}
 				// EOF或其他错误，退出
 				return
 			}
 
-			lineTrimmed := strings.TrimSpace(line)
-
 			// 如果有未完成的语句，追加当前行
 			if currentStatement.Len() > 0 {
 				currentStatement.WriteString("\n")
@@ -134,14 +245,10 @@ func (s *Shell) Run() {
 				currentStatement.WriteString(line)
 			}
 
-			// 检查语句是否完成
-			statement := currentStatement.String()
-			isComplete := s.isStatementComplete(statement)
-
-			// 也检查是否以反斜杠结尾（行继续符）
-			if !isComplete || strings.HasSuffix(lineTrimmed, "\\") {
+			// 由解析器判断输入是否完整（未闭合的 if/for/case、引号、here-document、行尾的反斜杠）
+			if !s.isStatementComplete(currentStatement.String()) {
 				// 语句未完成，继续读取下一行
-				rl.SetPrompt("> ")
+				s.setPrompt(s.continuationPrompt())
 				continue
 			}
 
@@ -153,46 +260,69 @@ func (s *Shell) Run() {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
+		// 跨多行的语句合并为一行保存，上下键调出时作为一个整体编辑
+		s.addHistory(historyEntry(line))
 
-		if err := s.executeLine(line); err != nil {
+		restoreTerminal := saveTerminal()
+		err := s.executeInput(line)
+		restoreTerminal()
+		if err != nil {
 			// 检查是否是 exit 命令
 			if exitErr, ok := err.(*builtin.ExitError); ok {
 				// 在交互式模式下，exit 命令退出整个程序
-				os.Exit(exitErr.Code)
+				os.Exit(s.finish(exitErr))
+			}
+			// set -e 生效时命令失败，报告错误后退出
+			if scriptExitErr, ok := err.(*executor.ScriptExitError); ok {
+				if scriptExitErr.Err != nil {
+					s.errorReporter.ReportError(scriptExitErr.Err)
+				}
+				os.Exit(s.finish(scriptExitErr))
 			}
 			// 使用统一的错误报告器
 			s.errorReporter.ReportError(err)
-		} else {
-			// 成功执行的命令添加到历史记录
-			s.history.Add(line)
-			// 保存到readline历史记录
-			rl.SaveHistory(line)
 		}
 
 		// 更新提示符（工作目录可能已改变）
-		s.prompt = getPrompt()
+		s.prompt = s.primaryPrompt()
 	}
+}
 
-	// 保存历史记录
-	s.saveHistory()
+// saveTerminal 保存标准输入终端的设置，返回恢复设置的函数（标准输入不是终端时不做处理）
+// 交互模式下每条语句执行之后恢复终端：异常退出或被停止的全屏程序留下的原始模式、关闭的回显等不影响之后的行编辑和命令
+func saveTerminal() func() {
+	fd := int(os.Stdin.Fd())
+	if !readline.IsTerminal(fd) {
+		return func() {}
+	}
+	state, err := readline.GetState(fd)
+	if err != nil {
+		return func() {}
+	}
+	return func() { readline.Restore(fd, state) }
 }
 
 // runSimple 简单的运行模式（当readline不可用时回退）
 // 使用bufio.Scanner进行基本的命令行输入，不支持历史记录和自动补全
 func (s *Shell) runSimple() {
 	scanner := bufio.NewScanner(os.Stdin)
+	defer s.finish(nil)
 
 	for s.running {
+		s.executor.NotifyJobs()
 		fmt.Print(s.prompt)
 
 		var currentStatement strings.Builder
 		for {
 			if !scanner.Scan() {
+				if s.finishHereDocs(&currentStatement) {
+					break
+				}
+				s.reportUnfinished(currentStatement.String())
 				return
 			}
 
 			line := scanner.Text()
-			lineTrimmed := strings.TrimSpace(line)
 
 			// 如果有未完成的语句，追加当前行
 			if currentStatement.Len() > 0 {
@@ -202,19 +332,14 @@ func (s *Shell) runSimple() {
 				currentStatement.WriteString(line)
 			}
 
-			// 检查语句是否完成
-			statement := currentStatement.String()
-			isComplete := s.isStatementComplete(statement)
-
-			// 也检查是否以反斜杠结尾（行继续符）
-			if !isComplete || strings.HasSuffix(lineTrimmed, "\\") {
+			// 由解析器判断输入是否完整
+			if !s.isStatementComplete(currentStatement.String()) {
 				// 语句未完成，继续读取下一行
-				fmt.Print("> ")
+				fmt.Print(s.continuationPrompt())
 				continue
 			}
 
 			// 语句完成，执行
-			line = statement
 			break
 		}
 
@@ -222,26 +347,49 @@ func (s *Shell) runSimple() {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
+		s.addHistory(historyEntry(line))
 
-		if err := s.executeLine(line); err != nil {
+		if err := s.executeInput(line); err != nil {
 			// 检查是否是 exit 命令
 			if exitErr, ok := err.(*builtin.ExitError); ok {
 				// 在交互式模式下，exit 命令退出整个程序
-				os.Exit(exitErr.Code)
+				os.Exit(s.finish(exitErr))
+			}
+			// set -e 生效时命令失败，报告错误后退出
+			if scriptExitErr, ok := err.(*executor.ScriptExitError); ok {
+				if scriptExitErr.Err != nil {
+					s.errorReporter.ReportError(scriptExitErr.Err)
+				}
+				os.Exit(s.finish(scriptExitErr))
 			}
 			// 使用统一的错误报告器
 			s.errorReporter.ReportError(err)
-		} else {
-			// 成功执行的命令添加到历史记录
-			s.history.Add(line)
 		}
 
 		// 更新提示符（工作目录可能已改变）
-		s.prompt = getPrompt()
+		s.prompt = s.primaryPrompt()
 	}
+}
+
+// addHistory 把输入的语句加入历史记录（执行失败的语句也会加入），行编辑器的历史与它保持一致
+func (s *Shell) addHistory(entry string) {
+	if s.history.Add(entry) && s.editor != nil {
+		s.editor.SaveHistory(entry)
+	}
+}
 
-	// 保存历史记录
+// finish 交互式 shell 退出之前执行 EXIT 陷阱、保存历史记录，shopt -s huponexit 时向后台作业发送 SIGHUP（disown 的作业除外）
+// err 是导致退出的 exit 或 set -e 错误（输入结束时为 nil），返回 shell 的退出码（EXIT 陷阱中的 exit 可以改变它）
+func (s *Shell) finish(err error) int {
+	err = s.executor.RunExitTrap(err)
 	s.saveHistory()
+	if s.options["huponexit"] {
+		s.executor.GetJobManager().Hangup()
+	}
+	if err == nil {
+		return s.LastStatus()
+	}
+	return executor.ExitStatus(err)
 }
 
 // saveHistory 保存历史记录
@@ -256,550 +404,245 @@ func (s *Shell) saveHistory() {
 	}
 }
 
+// LastStatus 返回最后执行的命令的退出状态（$?），与 bash 相同，脚本和 -c 命令执行完后作为 gobash 的退出码
+func (s *Shell) LastStatus() int {
+	value, _ := s.executor.GetEnv("?")
+	status, _ := strconv.Atoi(value)
+	return status
+}
+
 // ExecuteScript 执行脚本文件
 func (s *Shell) ExecuteScript(scriptPath string, args ...string) error {
-	// 设置位置参数（$1, $2, ...）和 $#、$@
-	for i, arg := range args {
-		s.executor.SetEnv(fmt.Sprintf("%d", i+1), arg)
-	}
-	s.executor.SetEnv("#", fmt.Sprintf("%d", len(args)))
-	s.executor.SetEnv("@", strings.Join(args, " "))
+	return s.ExecuteScriptContext(context.Background(), scriptPath, args...)
+}
+
+// ExecuteScriptContext 在 ctx 下执行脚本文件，ctx 取消或超时后脚本停止执行
+func (s *Shell) ExecuteScriptContext(ctx context.Context, scriptPath string, args ...string) error {
+	// 与 bash 一样，$0 是脚本路径
+	s.SetPositionalArgs(scriptPath, args)
 
 	file, err := os.Open(scriptPath)
 	if err != nil {
-		return fmt.Errorf("无法打开脚本文件: %v", err)
+		return i18n.Errorf("无法打开脚本文件: %w", err)
 	}
 	defer file.Close()
 
 	// 设置错误报告器的脚本路径（非交互式模式）
-	s.errorReporter = NewErrorReporter(scriptPath, false)
-	return s.ExecuteReader(file)
+	s.SetScriptPath(scriptPath)
+	return s.ExecuteReaderContext(ctx, file)
 }
 
 // ExecuteReader 从Reader执行命令
-// 用于执行脚本文件，自动跳过shebang行和注释行
-// 支持多行语句（case、if、for等）
+// 用于执行脚本文件：先一次解析完整的脚本，再逐条执行顶层语句
+// 多行语句（case、if、for等）、here-document 和引号中的换行都由解析器统一处理，
+// shebang 行和注释由词法分析器跳过
+// 脚本有语法错误时报告所有错误，不执行任何命令，返回退出码为 2 的 ExitError
 func (s *Shell) ExecuteReader(reader io.Reader) error {
-	scanner := bufio.NewScanner(reader)
-	firstLine := true
-	lineNum := 0
-	var currentStatement strings.Builder
-
-	for scanner.Scan() {
-		lineNum++
-		originalLine := scanner.Text()
-		line := strings.TrimSpace(originalLine)
-
-		// 跳过空行（但如果当前有未完成的语句，保留空行）
-		if line == "" {
-			if currentStatement.Len() > 0 {
-				currentStatement.WriteString("\n")
-			}
-			continue
-		}
-
-		// 跳过shebang行（#!/bin/bash, #!/usr/bin/env bash等）
-		if firstLine && strings.HasPrefix(line, "#!") {
-			firstLine = false
-			continue
-		}
-		firstLine = false
-
-		// 跳过注释行（以#开头，但不是shebang）
-		if strings.HasPrefix(line, "#") {
-			continue
-		}
+	return s.ExecuteReaderContext(context.Background(), reader)
+}
 
-		// 检查当前行是否包含 heredoc 标记，如果有则立即跳过内容（在添加到 statement 之前）
-		// 这样可以避免 heredoc 内容被添加到 statement
-		if strings.Contains(originalLine, "<<") {
-			// 提取 heredoc 分隔符
-			delim := extractHeredocDelimiterFromLine(originalLine)
-			if delim != "" {
-				// 先将当前行添加到 statement
-				if currentStatement.Len() > 0 {
-					currentStatement.WriteString("\n")
-					currentStatement.WriteString(originalLine)
-				} else {
-					currentStatement.WriteString(originalLine)
-				}
+// ExecuteReaderContext 在 ctx 下从Reader执行命令
+// ctx 取消或超时后，正在运行的外部命令被终止，报告中断错误后停止执行剩余的语句
+func (s *Shell) ExecuteReaderContext(ctx context.Context, reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
 
-				// 跳过 heredoc 内容直到找到分隔符
-				foundDelimiter := false
-				for scanner.Scan() {
-					lineNum++
-					contentLine := scanner.Text()
-					// 检查是否是分隔符（需要精确匹配，包括前导空白）
-					trimmedContentLine := strings.TrimSpace(contentLine)
-					if trimmedContentLine == delim {
-						// 找到分隔符，将其添加到 statement（parser 需要它）
-						currentStatement.WriteString("\n")
-						currentStatement.WriteString(contentLine)
-						foundDelimiter = true
-						break
-					}
-					// heredoc 内容不添加到 statement（被跳过）
-				}
-				if !foundDelimiter {
-					// 没有找到分隔符，可能有问题，但继续处理
-				}
-				// 继续到下一轮循环，检查语句是否完成
-				// 注意：这里不需要立即检查语句是否完成，因为 heredoc 可能只是语句的一部分
-				// 让正常的循环流程处理语句完成检查
-				continue
-			}
+	program, errs := s.parseInput(string(data))
+	if len(errs) > 0 {
+		for _, e := range errs {
+			s.errorReporter.ReportError(e)
 		}
+		return &builtin.ExitError{Code: 2}
+	}
+	s.executor.CoverProgram(program)
 
-		// 在添加当前行之前，先检查当前 statement 中是否有未完成的 heredoc
-		// 如果有，需要先处理 heredoc，避免 heredoc 内容被添加到 statement
-		preStatement := currentStatement.String()
-		if strings.Contains(preStatement, "<<") {
-			// 检查是否有未完成的 heredoc（有 << 但没有对应的分隔符）
-			lines := strings.Split(preStatement, "\n")
-			for _, line := range lines {
-				if strings.Contains(line, "<<") {
-					delim := extractHeredocDelimiterFromLine(line)
-					if delim != "" {
-						// 检查分隔符是否已经在 statement 中
-						foundDelimiter := false
-						for _, checkLine := range lines {
-							if strings.TrimSpace(checkLine) == delim {
-								foundDelimiter = true
-								break
-							}
-						}
-						if !foundDelimiter {
-							// 有未完成的 heredoc，需要跳过内容
-							// 注意：当前行可能就是分隔符，所以先检查当前行
-							if strings.TrimSpace(originalLine) == delim {
-								// 当前行就是分隔符，添加到 statement
-								currentStatement.WriteString("\n")
-								currentStatement.WriteString(originalLine)
-								// 继续到下一轮循环，检查语句是否完成
-								continue
-							}
-							// 否则，跳过 heredoc 内容直到找到分隔符
-							for scanner.Scan() {
-								lineNum++
-								contentLine := scanner.Text()
-								if strings.TrimSpace(contentLine) == delim {
-									// 找到分隔符，将其添加到 statement
-									currentStatement.WriteString("\n")
-									currentStatement.WriteString(contentLine)
-									break
-								}
-								// heredoc 内容不添加到 statement（被跳过）
-							}
-							// 跳过当前行（它是 heredoc 内容的一部分）
-							continue
-						}
-					}
-				}
+	for i, stmt := range program.Statements {
+		lineNum := program.Lines[i]
+		if err := s.executeStatement(ctx, stmt); err != nil {
+			// 记录出错的语句在脚本中的行号，复合语句中出错时是其中出错的命令所在的行
+			if line := s.executor.LineNumber(); line > 0 {
+				lineNum = line
 			}
-		}
-
-		// 如果有未完成的语句，追加当前行
-		if currentStatement.Len() > 0 {
-			currentStatement.WriteString("\n")
-			currentStatement.WriteString(originalLine)
-		} else {
-			currentStatement.WriteString(originalLine)
-		}
-
-		// 检查语句是否完成
-		statement := currentStatement.String()
-		isComplete := s.isStatementComplete(statement)
-		if isComplete {
-			// 执行完整的语句
-			if err := s.executeLine(statement); err != nil {
-				// 检查是否是 exit 命令或脚本退出错误
-				if exitErr, ok := err.(*builtin.ExitError); ok {
-					// 返回 ExitError，让调用者决定如何处理（不输出错误信息）
-					return exitErr
-				}
-				if scriptExitErr, ok := err.(*executor.ScriptExitError); ok {
-					// 返回 ScriptExitError，让调用者决定如何处理（不输出错误信息）
-					return scriptExitErr
-				}
-				// 使用统一的错误报告器
-				s.errorReporter.SetLineNum(lineNum)
-				s.errorReporter.ReportError(err)
-				// 输出语句内容（用于调试）
-				fmt.Fprintf(os.Stderr, "  %s\n", statement)
-				// 如果设置了set -e，遇到错误应该退出
-				// 但是 ScriptExitError 和 ExitError 已经表示脚本退出，不需要再次包装
-				if s.options["e"] {
-					// 检查是否已经是退出错误
-					if _, ok := err.(*builtin.ExitError); ok {
-						return err
-					}
-					if _, ok := err.(*executor.ScriptExitError); ok {
-						return err
-					}
-					return fmt.Errorf("脚本执行失败（第%d行）: %v", lineNum, err)
-				}
+			var execErr *executor.ExecutionError
+			if errors.As(err, &execErr) && !execErr.Pos.IsValid() {
+				execErr.Pos = lexer.Position{Line: lineNum}
 			}
-			// 重置当前语句
-			currentStatement.Reset()
-		}
-	}
-
-	// 如果还有未完成的语句，尝试执行
-	if currentStatement.Len() > 0 {
-		statement := currentStatement.String()
-		if err := s.executeLine(statement); err != nil {
 			// 检查是否是 exit 命令或脚本退出错误
 			if exitErr, ok := err.(*builtin.ExitError); ok {
 				// 返回 ExitError，让调用者决定如何处理（不输出错误信息）
-				return exitErr
+				return s.executor.RunExitTrap(exitErr)
 			}
+			s.errorReporter.SetLineNum(lineNum)
 			if scriptExitErr, ok := err.(*executor.ScriptExitError); ok {
-				// 返回 ScriptExitError，让调用者决定如何处理（不输出错误信息）
-				return scriptExitErr
+				// set -e 导致的退出：报告失败的命令，返回 ScriptExitError 让调用者决定如何退出
+				if scriptExitErr.Err != nil {
+					s.errorReporter.ReportError(scriptExitErr.Err)
+				}
+				return s.executor.RunExitTrap(scriptExitErr)
 			}
 			// 使用统一的错误报告器
-			s.errorReporter.SetLineNum(lineNum)
 			s.errorReporter.ReportError(err)
-			// 输出语句内容（用于调试）
-			fmt.Fprintf(os.Stderr, "  %s\n", statement)
-			if s.options["e"] {
-				// 检查是否已经是退出错误
-				if _, ok := err.(*builtin.ExitError); ok {
-					return err
-				}
-				if _, ok := err.(*executor.ScriptExitError); ok {
-					return err
-				}
-				return fmt.Errorf("脚本执行失败（第%d行）: %v", lineNum, err)
+			if ctx.Err() != nil {
+				// 执行被取消，不再执行剩余的语句
+				return s.executor.RunExitTrap(err)
 			}
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return err
-	}
-
-	return nil
+	return s.executor.RunExitTrap(nil)
 }
 
-// isStatementComplete 检查语句是否完成
-// 检查是否有关键字未闭合（case需要esac，if需要fi，for/while需要done等）
-// 也检查是否以反斜杠结尾（行继续符）
-func (s *Shell) isStatementComplete(statement string) bool {
-	statement = strings.TrimSpace(statement)
-	if statement == "" {
-		return true
-	}
-
-	// 检查是否以反斜杠结尾（行继续符）
-	// 注意：需要检查去除尾部空白后的最后一个字符
-	trimmed := strings.TrimRight(statement, " \t")
-	if strings.HasSuffix(trimmed, "\\") {
-		return false
-	}
-
-	// 使用更精确的匹配来统计关键字（必须是独立的单词）
-	words := strings.Fields(statement)
-
-	caseCount := 0
-	for _, word := range words {
-		if word == "case" {
-			caseCount++
-		}
-	}
-	esacCount := strings.Count(statement, "esac")
-
-	ifCount := 0
-	for _, word := range words {
-		if word == "if" {
-			ifCount++
-		}
-	}
-	fiCount := strings.Count(statement, "fi")
-
-	forCount := 0
-	for _, word := range words {
-		if word == "for" {
-			forCount++
-		}
-	}
-
-	whileCount := 0
-	for _, word := range words {
-		if word == "while" {
-			whileCount++
-		}
-	}
-
-	doneCount := 0
-	for _, word := range words {
-		if word == "done" {
-			doneCount++
-		}
-	}
-
-	// 先检查函数定义的大括号（最外层结构）
-	// 函数定义格式：name() { ... } 或 function name() { ... }
-	// 需要检查是否有未闭合的大括号
-	braceCount := 0
-	inQuotes := false
-	quoteChar := byte(0)
-	for i := 0; i < len(statement); i++ {
-		ch := statement[i]
-
-		// 处理转义字符
-		if ch == '\\' && i+1 < len(statement) {
-			if !inQuotes {
-				// 在引号外，转义字符用于转义下一个字符
-				i++ // 跳过转义字符和下一个字符
-				continue
-			} else {
-				// 在引号内，转义字符应该保留
-				if i+1 < len(statement) && statement[i+1] == quoteChar {
-					// 转义的引号，不改变引号状态
-					i++
-					continue
-				}
-				// 其他转义字符，保留
-				continue
-			}
-		}
-
-		// 处理引号
-		if (ch == '"' || ch == '\'') && !inQuotes {
-			inQuotes = true
-			quoteChar = ch
-		} else if ch == quoteChar && inQuotes {
-			inQuotes = false
-			quoteChar = 0
-		}
-
-		// 只统计引号外的大括号
-		if !inQuotes {
-			if ch == '{' {
-				braceCount++
-			} else if ch == '}' {
-				braceCount--
-			}
-		}
-	}
-
-	// 如果有未闭合的大括号，语句未完成（函数定义未完成）
-	if braceCount > 0 {
-		return false
+// reportUnfinished 在输入结束时报告没有完成的语句，与 bash 一样指出期望的结束符（如 fi、引号）
+func (s *Shell) reportUnfinished(statement string) {
+	if strings.TrimSpace(statement) == "" {
+		return
 	}
-
-	// 如果大括号已闭合，检查内部的语句是否完整
-	// 检查case语句
-	if caseCount > esacCount {
-		return false
+	p := parser.New(lexer.New(statement + "\n"))
+	p.ParseProgram()
+	closer, more := p.NeedsMore()
+	if !more {
+		return
 	}
-
-	// 检查if语句
-	if ifCount > fiCount {
-		return false
+	// 按 Ctrl+D 时光标还在续行提示符之后
+	fmt.Fprintln(os.Stderr)
+	if closer != "" {
+		i18n.Fprintf(os.Stderr, "gobash: 寻找匹配的 `%s' 时遇到了文件结束符\n", closer)
+	} else {
+		i18n.Fprintf(os.Stderr, "gobash: 语法错误: 意外的文件结束\n")
 	}
+}
 
-	// 检查for/while语句
-	// 注意：while循环需要do关键字，所以如果whileCount > 0但没有done，且没有do，语句未完成
-	if (forCount + whileCount) > doneCount {
-		// 检查是否有do关键字（while循环需要do）
-		// 使用更精确的匹配：do必须是独立的单词
-		doCount := 0
-		// 检查 " do "、" do\n"、";do "、";do\n"、"\ndo "、"\ndo\n" 等
-		doPatterns := []string{" do ", " do\n", ";do ", ";do\n", "\ndo ", "\ndo\n", " do;", "\ndo;"}
-		for _, pattern := range doPatterns {
-			doCount += strings.Count(statement, pattern)
-		}
-		// 也检查行首的do（do在行首）
-		if strings.HasPrefix(strings.TrimSpace(statement), "do ") ||
-			strings.HasPrefix(strings.TrimSpace(statement), "do\n") {
-			doCount++
-		}
-		// 如果while循环没有do关键字，语句未完成
-		if whileCount > 0 && doCount == 0 && doneCount == 0 {
-			return false
-		}
-		// 如果while循环有do但没有done，语句未完成
-		if whileCount > 0 && doCount > 0 && doneCount == 0 {
-			return false
-		}
-		// 如果for循环没有done，语句未完成
-		if forCount > 0 && doneCount == 0 {
-			return false
-		}
-	}
+// isStatementComplete 检查输入是否已经是完整的语句
+// 由解析器判断：缺少 fi/done/esac、引号或 here-document 未结束、以反斜杠结尾时输入不完整，
+// 需要继续读取下一行；语法错误不算不完整，应该立即报告
+func (s *Shell) isStatementComplete(statement string) bool {
+	p := parser.New(lexer.New(statement + "\n"))
+	p.ParseProgram()
+	return !p.Incomplete()
+}
 
-	return true
+// continuationPrompt 返回语句未完成时的续行提示符：展开后的 PS2，未设置时为 "> "
+func (s *Shell) continuationPrompt() string {
+	return s.executor.ExpandPrompt("PS2", "> ")
 }
 
-// extractHeredocDelimiterFromLine 从一行中提取 heredoc 分隔符
-func extractHeredocDelimiterFromLine(line string) string {
-	// 查找 << 或 <<-
-	idx := strings.Index(line, "<<")
-	if idx < 0 {
-		return ""
-	}
-
-	// 检查是否是 <<- 或 <<<
-	afterHeredoc := line[idx:]
-	var suffix string
-	if strings.HasPrefix(afterHeredoc, "<<-") {
-		suffix = line[idx+3:]
-	} else if strings.HasPrefix(afterHeredoc, "<<<") {
-		// <<< here-string，不需要处理
-		return ""
-	} else if strings.HasPrefix(afterHeredoc, "<<") {
-		suffix = line[idx+2:]
-	} else {
-		return ""
-	}
+// primaryPrompt 返回主提示符：设置了 PS1 时展开 PS1，否则为 用户名@主机名:目录$
+func (s *Shell) primaryPrompt() string {
+	return s.executor.ExpandPrompt("PS1", getPrompt(s.executor.Dir()))
+}
 
-	// 跳过空白字符
-	suffix = strings.TrimLeft(suffix, " \t")
-	if len(suffix) == 0 {
-		return ""
+// finishHereDocs 在输入结束时补上未结束的 here-document 的结束分隔符，使已经输入的正文可以执行
+// 与 bash 一样给出警告；没有未结束的 here-document 时返回 false
+func (s *Shell) finishHereDocs(statement *strings.Builder) bool {
+	if statement.Len() == 0 {
+		return false
 	}
-
-	// 检查分隔符是否带引号
-	if suffix[0] == '\'' || suffix[0] == '"' {
-		// 带引号的分隔符
-		quoteChar := suffix[0]
-		endQuote := strings.IndexByte(suffix[1:], quoteChar)
-		if endQuote >= 0 {
-			return suffix[1 : endQuote+1]
-		}
-		// 引号未闭合，尝试提取第一个字段
-		parts := strings.Fields(suffix)
-		if len(parts) > 0 {
-			delim := parts[0]
-			if len(delim) > 1 && (delim[0] == '\'' || delim[0] == '"') {
-				return delim[1 : len(delim)-1]
+	p := parser.New(lexer.New(statement.String() + "\n"))
+	p.ParseProgram()
+	finished := false
+	for _, err := range p.AllErrors() {
+		if e, ok := err.(*lexer.LexerError); ok && e.Type == lexer.LexerErrorTypeUnclosedHereDoc {
+			if !finished {
+				// 按 Ctrl+D 时光标还在续行提示符之后
+				fmt.Fprintln(os.Stderr)
 			}
-			return delim
-		}
-	} else {
-		// 不带引号的分隔符
-		parts := strings.Fields(suffix)
-		if len(parts) > 0 {
-			return parts[0]
+			i18n.Fprintf(os.Stderr, "gobash: 警告: 从第 %d 行开始的 here-document 被文件结束符分隔（需要 `%s'）\n", e.Line, e.Char)
+			statement.WriteString("\n")
+			statement.WriteString(e.Char)
+			finished = true
 		}
 	}
-
-	return ""
+	return finished
 }
 
-// executeLine 执行一行命令
-// 支持分号分隔的多个命令
-func (s *Shell) executeLine(line string) error {
-	line = strings.TrimSpace(line)
-	if line == "" {
-		return nil
+// parseInput 解析一段完整的输入，返回程序和所有词法、语法错误
+func (s *Shell) parseInput(input string) (*parser.Program, []error) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.AllErrors(); len(errs) > 0 {
+		return nil, errs
 	}
+	return program, nil
+}
 
-	// 分割多个命令（分号分隔）
-	commands := splitCommands(line)
-	for _, cmd := range commands {
-		if err := s.executeCommand(cmd); err != nil {
-			// 检查是否是 exit 命令或脚本退出错误，如果是，直接返回
-			if _, ok := err.(*builtin.ExitError); ok {
-				return err
-			}
-			if _, ok := err.(*executor.ScriptExitError); ok {
-				return err
+// executeStatement 执行一条顶层语句
+// 别名在执行前才展开，这样前面的语句定义的别名对后面的语句生效
+func (s *Shell) executeStatement(ctx context.Context, stmt parser.Statement) error {
+	aliased := stmt
+	if bg, ok := stmt.(*parser.BackgroundStatement); ok {
+		// 后台执行的管道同样展开别名
+		aliased = bg.Statement
+	}
+	switch st := aliased.(type) {
+	case *parser.CommandStatement:
+		s.expandAlias(st)
+	case *parser.PipelineStatement:
+		// 管道中的每个简单命令都会展开
+		for _, c := range st.Commands {
+			if cmd, ok := c.(*parser.CommandStatement); ok {
+				s.expandAlias(cmd)
 			}
-			return err
 		}
 	}
-
-	return nil
+	return s.executor.ExecuteContext(ctx, &parser.Program{Statements: []parser.Statement{stmt}})
 }
 
-// executeCommand 执行单个命令
-// 处理别名展开、词法分析、语法分析和命令执行
-func (s *Shell) executeCommand(input string) error {
+// executeInput 解析并执行交互模式下输入的一条（可能跨多行的）语句
+// 语法错误时返回第一个错误，不执行任何命令
+func (s *Shell) executeInput(input string) error {
 	if strings.TrimSpace(input) == "" {
 		return nil
 	}
 
-	// 检查是否为特殊命令，需要特殊处理
-	parts := strings.Fields(input)
-	if len(parts) > 0 {
-		cmd := parts[0]
-		if cmd == "alias" {
-			return s.handleAliasCommand(parts[1:])
-		} else if cmd == "unalias" {
-			return s.handleUnaliasCommand(parts[1:])
-		} else if cmd == "history" {
-			return s.handleHistoryCommand(parts[1:])
-		} else if cmd == "set" {
-			return s.handleSetCommand(parts[1:])
-		}
-	}
-
-	// 展开别名
-	input = s.expandAlias(input)
-
-	// 词法分析
-	l := lexer.New(input)
-
-	// 语法分析
-	p := parser.New(l)
-	program := p.ParseProgram()
-
-	// 检查解析错误
-	if len(p.Errors()) > 0 {
-		// 返回第一个解析错误（ParseError）
-		if len(p.ParseErrors()) > 0 {
-			return p.ParseErrors()[0]
-		}
-		// 如果没有 ParseError，返回通用错误
-		return fmt.Errorf("语法错误: %v", p.Errors())
+	program, errs := s.parseInput(input)
+	if len(errs) > 0 {
+		return errs[0]
 	}
-
-	// 执行
-	if err := s.executor.Execute(program); err != nil {
-		// 检查是否是 exit 命令或脚本退出错误，如果是，直接返回，不包装
-		if _, ok := err.(*builtin.ExitError); ok {
-			return err
-		}
-		if _, ok := err.(*executor.ScriptExitError); ok {
+	for _, stmt := range program.Statements {
+		if err := s.executeStatement(context.Background(), stmt); err != nil {
 			return err
 		}
-		// 直接返回错误，让调用者使用错误报告器格式化
-		return err
 	}
-
 	return nil
 }
 
 // expandAlias 展开别名
-// 如果命令名是已定义的别名，则替换为别名值
-func (s *Shell) expandAlias(input string) string {
-	parts := strings.Fields(input)
-	if len(parts) == 0 {
-		return input
-	}
-
-	cmdName := parts[0]
-	if alias, ok := s.aliases[cmdName]; ok {
-		// 替换命令名，保留参数
-		if len(parts) > 1 {
-			return alias + " " + strings.Join(parts[1:], " ")
-		}
-		return alias
+// 如果命令名是已定义的别名，则替换为别名值，保留原来的参数和重定向
+// 别名值必须是一个简单命令
+func (s *Shell) expandAlias(cmd *parser.CommandStatement) {
+	name, ok := cmd.Command.(*parser.Identifier)
+	if !ok {
+		return
+	}
+	alias, ok := s.aliases[name.Value]
+	if !ok {
+		return
 	}
 
-	return input
+	p := parser.New(lexer.New(alias))
+	program := p.ParseProgram()
+	if len(p.AllErrors()) > 0 || len(program.Statements) != 1 {
+		return
+	}
+	aliasCmd, ok := program.Statements[0].(*parser.CommandStatement)
+	if !ok {
+		return
+	}
+	cmd.Assignments = append(cmd.Assignments, aliasCmd.Assignments...)
+	cmd.Command = aliasCmd.Command
+	cmd.Args = append(aliasCmd.Args, cmd.Args...)
+	cmd.Redirects = append(aliasCmd.Redirects, cmd.Redirects...)
 }
 
 // handleAliasCommand 处理alias命令
 // 支持设置别名、显示所有别名或显示特定别名
-func (s *Shell) handleAliasCommand(args []string) error {
+func (s *Shell) handleAliasCommand(args []string, out io.Writer) error {
 	if len(args) == 0 {
 		// 显示所有别名
 		for name, value := range s.aliases {
-			fmt.Printf("alias %s='%s'\n", name, value)
+			fmt.Fprintf(out, "alias %s='%s'\n", name, value)
 		}
 		return nil
 	}
@@ -814,7 +657,7 @@ func (s *Shell) handleAliasCommand(args []string) error {
 		} else {
 			// 显示特定别名
 			if value, ok := s.aliases[arg]; ok {
-				fmt.Printf("alias %s='%s'\n", arg, value)
+				fmt.Fprintf(out, "alias %s='%s'\n", arg, value)
 			}
 		}
 	}
@@ -824,20 +667,10 @@ func (s *Shell) handleAliasCommand(args []string) error {
 
 // handleSetCommand 处理set命令
 // 支持设置/取消Shell选项（-x, -e, -u等）和设置变量
-func (s *Shell) handleSetCommand(args []string) error {
+func (s *Shell) handleSetCommand(args []string, out io.Writer) error {
 	if len(args) == 0 {
-		// 显示所有变量
-		env := s.executor.GetOptions()
-		_ = env // 暂时不使用，显示变量需要访问executor的env
-		// 显示当前选项状态
-		fmt.Println("--- Shell Options ---")
-		for opt, enabled := range s.options {
-			if enabled {
-				fmt.Printf("set -%s\n", opt)
-			} else {
-				fmt.Printf("set +%s\n", opt)
-			}
-		}
+		// 显示所有变量和函数（选项用 set -o 查看）
+		builtin.WriteVariables(out, s.executor.GetEnvMap(), s.executor.Variables())
 		return nil
 	}
 
@@ -879,9 +712,34 @@ func (s *Shell) handleSetCommand(args []string) error {
 	}
 
 	// 处理选项（跳过已经处理过的 -- 和位置参数）
-	for _, arg := range args {
-
-		if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+") {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "-o" || arg == "+o" {
+			// 长选项，如 set -o functrace、set +o functrace
+			if i+1 >= len(args) {
+				// 只有 -o 时显示所有长选项的状态
+				for _, name := range longOptions {
+					state := "off"
+					if s.options[optionKey(name)] {
+						state = "on"
+					}
+					fmt.Fprintf(out, "%-15s\t%s\n", name, state)
+				}
+				continue
+			}
+			i++
+			name := args[i]
+			if !isLongOption(name) {
+				return i18n.Errorf("%s: 无效的选项名", name)
+			}
+			s.options[optionKey(name)] = arg[0] == '-'
+			if name == "vi" || name == "emacs" {
+				// 两种编辑模式互斥，关闭其中一种时使用另一种
+				s.setEditingMode(s.options[name] == (name == "vi"))
+			}
+			s.executor.SetOptions(s.options)
+		} else if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+") {
 			// 解析选项，如 -x, -e, +x, +e
 			enable := arg[0] == '-'
 			optionStr := arg[1:]
@@ -906,138 +764,173 @@ func (s *Shell) handleSetCommand(args []string) error {
 	return nil
 }
 
-// handleUnaliasCommand 处理unalias命令
-// 支持删除特定别名或清除所有别名（-a选项）
-func (s *Shell) handleUnaliasCommand(args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("unalias: 缺少操作数")
+// longOptions 支持的 set -o 长选项
+var longOptions = []string{"emacs", "functrace", "noclobber", "notify", "pipefail", "vi"}
+
+// longOptionLetters 有对应单字母选项的长选项，如 set -o noclobber 与 set -C 相同
+var longOptionLetters = map[string]string{"noclobber": "C", "notify": "b"}
+
+// optionKey 返回长选项在 options 中的名称（有对应的单字母选项时使用单字母）
+func optionKey(name string) string {
+	if letter, ok := longOptionLetters[name]; ok {
+		return letter
 	}
+	return name
+}
 
-	for _, name := range args {
-		if name == "-a" {
-			// 清除所有别名
-			s.aliases = make(map[string]string)
-		} else {
-			delete(s.aliases, name)
-		}
+// setEditingMode 切换行编辑模式：vi 为 true 时使用 vi 模式，否则使用 emacs 模式
+func (s *Shell) setEditingMode(vi bool) {
+	s.options["vi"] = vi
+	s.options["emacs"] = !vi
+	if s.editor != nil {
+		s.editor.SetVimMode(vi)
 	}
+}
 
-	return nil
+// isLongOption 检查是否是支持的 set -o 长选项
+func isLongOption(name string) bool {
+	for _, opt := range longOptions {
+		if opt == name {
+			return true
+		}
+	}
+	return false
 }
 
-// splitCommands 分割命令（按分号）
-func splitCommands(line string) []string {
-	var commands []string
-	var current strings.Builder
-	inQuotes := false
-	quoteChar := byte(0)
-	braceDepth := 0 // 大括号深度，用于跟踪函数定义和代码块
-
-	for i := 0; i < len(line); i++ {
-		ch := line[i]
-
-		// 处理转义字符
-		if ch == '\\' && i+1 < len(line) {
-			if !inQuotes {
-				// 在引号外，转义字符用于转义分号等，需要处理
-				current.WriteByte(line[i+1])
-				i++
-				continue
-			} else {
-				// 在引号内，转义字符应该保留（由 lexer 处理）
-				// 但需要检查是否是转义的引号（如 \"），如果是，跳过不当作引号结束
-				if line[i+1] == quoteChar {
-					// 转义的引号，保留 \ 和引号，继续
-					current.WriteByte(ch)
-					current.WriteByte(line[i+1])
-					i++
-					continue
-				}
-				// 其他转义字符，保留
-				current.WriteByte(ch)
-				continue
+// shoptOptions 支持的 shopt 选项
+// selfexec：没有安装 bash 时由 gobash 执行 bash、sh 命令和 #! 行指定 bash、sh 的脚本（见 builtin.SelfExec）
+// huponexit：交互式 shell 退出时向后台作业发送 SIGHUP（默认后台作业在 shell 退出后继续运行）
+// inherit_errexit：命令替换继承 set -e（默认命令替换中的命令失败不终止命令替换）
+var shoptOptions = []string{"huponexit", "inherit_errexit", "selfexec", "xpg_echo"}
+
+// handleShoptCommand 处理shopt命令
+// 用法：shopt [-s|-u] [-pq] [选项名...]
+// -s 启用选项，-u 关闭选项，-q 不输出、只通过退出状态表示选项是否都已启用，-p 以 shopt -s/-u 的形式输出
+// 已启用的选项同时保存在 BASHOPTS 变量中（以冒号分隔），内置命令通过它读取选项状态
+func (s *Shell) handleShoptCommand(args []string, out io.Writer) error {
+	set, unset, quiet, printable := false, false, false, false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 's':
+				set = true
+			case 'u':
+				unset = true
+			case 'q':
+				quiet = true
+			case 'p':
+				printable = true
+			default:
+				return i18n.Errorf("-%c: 无效选项\n用法: shopt [-pqsu] [选项名...]", flag)
 			}
 		}
+		args = args[1:]
+	}
+	if set && unset {
+		return i18n.Errorf("不能同时使用 -s 和 -u")
+	}
 
-		if (ch == '"' || ch == '\'') && !inQuotes {
-			inQuotes = true
-			quoteChar = ch
-			current.WriteByte(ch)
-		} else if ch == quoteChar && inQuotes {
-			inQuotes = false
-			quoteChar = 0
-			current.WriteByte(ch)
-		} else if !inQuotes {
-			// 跟踪大括号深度（只在引号外）
-			if ch == '{' {
-				braceDepth++
-				current.WriteByte(ch)
-			} else if ch == '}' {
-				braceDepth--
-				current.WriteByte(ch)
-			} else if ch == ';' && braceDepth == 0 {
-				// 检查是否是双分号 ;;（case语句的结束符）
-				if i+1 < len(line) && line[i+1] == ';' {
-					// 双分号，不分割命令，将 ;; 作为当前命令的一部分
-					current.WriteByte(ch)
-					current.WriteByte(line[i+1])
-					i++ // 跳过第二个分号
-					continue
-				}
+	names := args
+	for _, name := range names {
+		if !isShoptOption(name) {
+			return i18n.Errorf("%s: 无效的 shell 选项名", name)
+		}
+	}
 
-				// 检查分号后的单词是否是控制流关键字（do、then等）
-				// 如果是，这个分号不应该分割命令
-				remaining := line[i+1:]
-				remaining = strings.TrimSpace(remaining)
-				// 获取分号后的第一个单词
-				nextWord := ""
-				if len(remaining) > 0 {
-					parts := strings.Fields(remaining)
-					if len(parts) > 0 {
-						nextWord = strings.ToLower(parts[0])
-					}
+	if set || unset {
+		if len(names) == 0 {
+			// 没有选项名时列出已启用（-s）或已关闭（-u）的选项
+			for _, name := range shoptOptions {
+				if s.options[name] == set {
+					printShoptOption(out, name, set, printable)
 				}
+			}
+			return nil
+		}
+		for _, name := range names {
+			s.options[name] = set
+		}
+		s.executor.SetOptions(s.options)
+		s.updateBashOpts()
+		return nil
+	}
 
-				// 控制流关键字列表（分号后可能出现的）
-				controlFlowAfterSemicolon := []string{"do", "then", "else", "elif"}
-				shouldSplit := true
-				for _, keyword := range controlFlowAfterSemicolon {
-					if nextWord == keyword {
-						shouldSplit = false
-						break
-					}
-				}
+	if len(names) == 0 {
+		names = shoptOptions
+	}
+	allSet := true
+	for _, name := range names {
+		enabled := s.options[name]
+		allSet = allSet && enabled
+		if !quiet {
+			printShoptOption(out, name, enabled, printable)
+		}
+	}
+	if !allSet && len(args) > 0 {
+		return i18n.Errorf("选项未启用")
+	}
+	return nil
+}
 
-				if shouldSplit {
-					// 分号且不在引号内，分割命令
-					cmd := strings.TrimSpace(current.String())
-					if cmd != "" {
-						commands = append(commands, cmd)
-					}
-					current.Reset()
-				} else {
-					// 分号后是控制流关键字，不分割，将分号作为当前命令的一部分
-					current.WriteByte(ch)
-				}
-			} else {
-				current.WriteByte(ch)
-			}
-		} else {
-			current.WriteByte(ch)
+// printShoptOption 输出 shopt 选项的状态
+func printShoptOption(out io.Writer, name string, enabled, printable bool) {
+	if printable {
+		flag := "-u"
+		if enabled {
+			flag = "-s"
 		}
+		fmt.Fprintf(out, "shopt %s %s\n", flag, name)
+		return
+	}
+	state := "off"
+	if enabled {
+		state = "on"
 	}
+	fmt.Fprintf(out, "%-15s\t%s\n", name, state)
+}
 
-	// 添加最后一个命令
-	cmd := strings.TrimSpace(current.String())
-	if cmd != "" {
-		commands = append(commands, cmd)
+// isShoptOption 检查是否是支持的 shopt 选项
+func isShoptOption(name string) bool {
+	for _, opt := range shoptOptions {
+		if opt == name {
+			return true
+		}
 	}
+	return false
+}
 
-	return commands
+// updateBashOpts 把已启用的 shopt 选项写入 BASHOPTS
+func (s *Shell) updateBashOpts() {
+	var enabled []string
+	for _, name := range shoptOptions {
+		if s.options[name] {
+			enabled = append(enabled, name)
+		}
+	}
+	s.executor.SetEnv("BASHOPTS", strings.Join(enabled, ":"))
 }
 
-// getPrompt 获取提示符
-func getPrompt() string {
+// handleUnaliasCommand 处理unalias命令
+// 支持删除特定别名或清除所有别名（-a选项）
+func (s *Shell) handleUnaliasCommand(args []string) error {
+	if len(args) == 0 {
+		return i18n.Errorf("unalias: 缺少操作数")
+	}
+
+	for _, name := range args {
+		if name == "-a" {
+			// 清除所有别名
+			s.aliases = make(map[string]string)
+		} else {
+			delete(s.aliases, name)
+		}
+	}
+
+	return nil
+}
+
+// getPrompt 获取提示符，wd 是 shell 的工作目录
+func getPrompt(wd string) string {
 	// 尝试获取用户名和主机名
 	username := os.Getenv("USER")
 	if username == "" {
@@ -1052,7 +945,6 @@ func getPrompt() string {
 		hostname = "host"
 	}
 
-	wd, _ := os.Getwd()
 	if wd == "" {
 		wd = "~"
 	}
@@ -1075,21 +967,24 @@ func getPrompt() string {
 }
 
 // handleHistoryCommand 处理history命令
-func (s *Shell) handleHistoryCommand(args []string) error {
+func (s *Shell) handleHistoryCommand(args []string, out io.Writer) error {
 	if len(args) == 0 {
 		// 显示所有历史
-		s.history.Print()
+		s.history.Print(out)
 		return nil
 	}
 
 	// 处理参数，如 history -c (清除历史)
 	if len(args) > 0 && args[0] == "-c" {
 		s.history = NewHistory(1000)
+		if s.editor != nil {
+			s.editor.ResetHistory()
+		}
 		return nil
 	}
 
 	// 显示最后N条历史
 	// 简化实现，只显示所有历史
-	s.history.Print()
+	s.history.Print(out)
 	return nil
 }
@@ -0,0 +1,146 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"gobash/internal/i18n"
+	"gobash/internal/lexer"
+	"gobash/internal/lint"
+	"gobash/internal/parser"
+)
+
+// CheckSyntax 检查输入的语法（-n 模式），只做词法和语法分析，不执行任何命令
+// 一次解析完整的输入，返回发现的所有词法错误和语法错误（按行列排序），没有错误时返回 nil
+func CheckSyntax(input string) []error {
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+
+	errs := p.AllErrors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// CheckReader 检查 Reader 中脚本的语法，将错误输出到 stderr
+// 返回发现的错误数量
+func (s *Shell) CheckReader(reader io.Reader) (int, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, i18n.Errorf("读取脚本失败: %v", err)
+	}
+
+	errs := CheckSyntax(string(data))
+	for _, e := range errs {
+		s.errorReporter.ReportError(e)
+	}
+	return len(errs), nil
+}
+
+// CheckScript 检查脚本文件的语法（gobash -n script.sh），不执行脚本
+// 返回发现的错误数量
+func (s *Shell) CheckScript(scriptPath string) (int, error) {
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return 0, i18n.Errorf("无法打开脚本文件: %v", err)
+	}
+	defer file.Close()
+
+	// 设置错误报告器的脚本路径（非交互式模式）
+	s.SetScriptPath(scriptPath)
+	return s.CheckReader(file)
+}
+
+// FormatMode 输出语法树的格式
+type FormatMode int
+
+const (
+	FormatSource FormatMode = iota // 规范化的 shell 源代码（gobash -fmt）
+	FormatAST                      // JSON 格式的语法树（gobash -ast）
+)
+
+// FormatReader 解析 Reader 中的脚本，没有语法错误时按 mode 把语法树输出到 w，不执行脚本
+// 有语法错误时与 CheckReader 一样把错误输出到 stderr，不输出语法树；返回发现的错误数量
+// 输出源代码时按行号保留注释（包括开头的 shebang 行），空行不保留
+func (s *Shell) FormatReader(reader io.Reader, w io.Writer, mode FormatMode) (int, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, i18n.Errorf("读取脚本失败: %v", err)
+	}
+
+	input := string(data)
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if errs := p.AllErrors(); len(errs) > 0 {
+		for _, e := range errs {
+			s.errorReporter.ReportError(e)
+		}
+		return len(errs), nil
+	}
+
+	if mode == FormatAST {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return 0, enc.Encode(program)
+	}
+	_, err = io.WriteString(w, parser.FormatComments(program, l.Comments()))
+	return 0, err
+}
+
+// FormatScript 按 mode 输出脚本文件的语法树（gobash -fmt script.sh、gobash -ast script.sh），不执行脚本
+func (s *Shell) FormatScript(scriptPath string, w io.Writer, mode FormatMode) (int, error) {
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return 0, i18n.Errorf("无法打开脚本文件: %v", err)
+	}
+	defer file.Close()
+
+	s.SetScriptPath(scriptPath)
+	return s.FormatReader(file, w, mode)
+}
+
+// LintReader 解析 Reader 中的脚本并做静态检查（gobash -lint），不执行脚本
+// 警告以 "脚本名:行号: 规则: 消息" 的格式输出到 w（name 为空时脚本名是 -），语法错误与 CheckReader 一样输出到 stderr，
+// 有语法错误时不做检查；返回语法错误和警告的数量
+func (s *Shell) LintReader(reader io.Reader, w io.Writer, name string) (int, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, i18n.Errorf("读取脚本失败: %v", err)
+	}
+
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if errs := p.AllErrors(); len(errs) > 0 {
+		for _, e := range errs {
+			s.errorReporter.ReportError(e)
+		}
+		return len(errs), nil
+	}
+
+	if name == "" {
+		name = "-"
+	}
+	warnings := lint.Check(program)
+	for _, warning := range warnings {
+		if _, err := fmt.Fprintf(w, "%s:%s\n", name, warning); err != nil {
+			return len(warnings), err
+		}
+	}
+	return len(warnings), nil
+}
+
+// LintScript 对脚本文件做静态检查（gobash -lint script.sh），不执行脚本
+func (s *Shell) LintScript(scriptPath string, w io.Writer) (int, error) {
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return 0, i18n.Errorf("无法打开脚本文件: %v", err)
+	}
+	defer file.Close()
+
+	s.SetScriptPath(scriptPath)
+	return s.LintReader(file, w, scriptPath)
+}
@@ -0,0 +1,241 @@
+package shell
+
+import (
+	"errors"
+	"gobash/internal/executor"
+	"gobash/internal/i18n"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestMain 使用中文消息运行测试（测试检查的是源代码中的中文错误消息）
+func TestMain(m *testing.M) {
+	i18n.SetLanguage(i18n.Chinese)
+	os.Exit(m.Run())
+}
+
+// TestCheckSyntax 测试 -n 模式的语法检查
+func TestCheckSyntax(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string // 期望的错误消息片段（按顺序），为空表示没有错误
+	}{
+		{
+			name:  "正确的脚本",
+			input: "#!/bin/bash\necho hello\nif [ -f x ]; then\n  echo yes\nelse\n  echo no\nfi\nfor i in 1 2 3; do echo $i; done\n",
+		},
+		{
+			name:  "here-document 和函数",
+			input: "f() {\n  cat <<EOF\n$1 )\nEOF\n}\nf a\n",
+		},
+		{
+			name:     "未闭合的 if",
+			input:    "echo start\nif true; then\n  echo x\n",
+			expected: []string{"第4行第1列", "fi"},
+		},
+		{
+			name:     "行尾的换行是意外的 token",
+			input:    "echo a\necho b\necho (\n",
+			expected: []string{"第3行第7列", "意外的 token `newline'"},
+		},
+		{
+			name:     "意外的 token",
+			input:    "echo a\necho b )\n",
+			expected: []string{"第2行第8列", "意外的 token `)'"},
+		},
+		{
+			name:     "未闭合的引号",
+			input:    "echo \"abc\n",
+			expected: []string{"第1行第6列", "未闭合的引号"},
+		},
+		{
+			name:     "多个错误全部报告",
+			input:    "fi\necho ok\ndone\n",
+			expected: []string{"第1行第1列", "第3行第1列"},
+		},
+		{
+			name:     "未闭合的 here-document",
+			input:    "cat <<EOF\nabc\n",
+			expected: []string{"EOF"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := CheckSyntax(tt.input)
+			if len(tt.expected) == 0 {
+				if len(errs) != 0 {
+					t.Fatalf("期望没有错误，得到: %v", errs)
+				}
+				return
+			}
+			if len(errs) == 0 {
+				t.Fatalf("期望有语法错误，但没有检测到")
+			}
+			var all []string
+			for _, err := range errs {
+				all = append(all, err.Error())
+			}
+			msg := strings.Join(all, "\n")
+			for _, want := range tt.expected {
+				if !strings.Contains(msg, want) {
+					t.Errorf("错误消息 %q 中没有 %q", msg, want)
+				}
+			}
+		})
+	}
+}
+
+// TestFormatReader 测试 -fmt 和 -ast 的输出
+func TestFormatReader(t *testing.T) {
+	s := New()
+	var out, stderr strings.Builder
+	s.SetStdio(nil, io.Discard, &stderr)
+	count, err := s.FormatReader(strings.NewReader("#!/bin/bash\nif true; then echo yes; fi\n"), &out, FormatSource)
+	if err != nil || count != 0 {
+		t.Fatalf("格式化失败: %d %v", count, err)
+	}
+	if want := "#!/bin/bash\nif true; then\n    echo yes\nfi\n"; out.String() != want {
+		t.Errorf("输出 %q，期望 %q", out.String(), want)
+	}
+
+	out.Reset()
+	if _, err := s.FormatReader(strings.NewReader("a && b\n"), &out, FormatAST); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"Operator": "&&"`) {
+		t.Errorf("语法树 %s 中没有 &&", out.String())
+	}
+
+	// 注释按行号保留：单独一行的注释和行尾注释，词中间的 # 不是注释
+	out.Reset()
+	count, err = s.FormatReader(strings.NewReader("#!/bin/bash\n# 开头\nf() {\n  # 函数体\n  echo \"#\" $# a#b # 行尾\n}\n# 结尾\n"), &out, FormatSource)
+	if want := "#!/bin/bash\n# 开头\nf() {\n    # 函数体\n    echo \"#\" $# a#b # 行尾\n}\n# 结尾\n"; err != nil || count != 0 || out.String() != want {
+		t.Errorf("含有注释时 count=%d err=%v 输出 %q，期望 %q", count, err, out.String(), want)
+	}
+
+	out.Reset()
+	stderr.Reset()
+	count, err = s.FormatReader(strings.NewReader("if true; then\n"), &out, FormatSource)
+	if err != nil || count != 1 || out.Len() != 0 || !strings.Contains(stderr.String(), "fi") {
+		t.Errorf("语法错误时 count=%d err=%v 输出 %q 错误输出 %q", count, err, out.String(), stderr.String())
+	}
+}
+
+// TestEnglishMessages 测试选择英文时语法错误和命令错误的消息
+func TestEnglishMessages(t *testing.T) {
+	i18n.SetLanguage(i18n.English)
+	defer i18n.SetLanguage(i18n.Chinese)
+
+	errs := CheckSyntax("echo start\nif true; then\n  echo x\n")
+	if len(errs) != 1 || errs[0].Error() != "line 4, column 1: syntax error: unexpected end of file (expected `fi')" {
+		t.Errorf("语法错误消息 = %v", errs)
+	}
+
+	s := New()
+	var stderr strings.Builder
+	s.SetStdio(nil, io.Discard, &stderr)
+	if err := s.ExecuteReader(strings.NewReader("shift abc\n")); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "shift: abc: numeric argument required") {
+		t.Errorf("命令错误消息 = %q", stderr.String())
+	}
+
+	// 与 bash 相同只输出命令名（或文件名）和原因，不输出 Go 的错误链；语法错误带有 gobash 前缀
+	stderr.Reset()
+	s.ExecuteReader(strings.NewReader("gobash_no_such_cmd arg\ncd /gobash_nonexist\necho > /gobash_nonexist/x\ngobash_no_such_cmd2 | cat\n./gobash_nonexist\n"))
+	want := "gobash: gobash_no_such_cmd: command not found\n" +
+		"gobash: cd: /gobash_nonexist: No such file or directory\n" +
+		"gobash: /gobash_nonexist/x: No such file or directory\n" +
+		"gobash: gobash_no_such_cmd2: command not found\n" +
+		"gobash: ./gobash_nonexist: No such file or directory\n"
+	if stderr.String() != want {
+		t.Errorf("错误消息 = %q，期望 %q", stderr.String(), want)
+	}
+	stderr.Reset()
+	s.ExecuteReader(strings.NewReader("fi\n"))
+	if want := "gobash: line 1, column 1: syntax error near unexpected token `fi'\n"; stderr.String() != want {
+		t.Errorf("语法错误消息 = %q，期望 %q", stderr.String(), want)
+	}
+}
+
+// TestScriptExitStatus 测试脚本的退出状态和出错位置
+func TestScriptExitStatus(t *testing.T) {
+	s := New()
+	s.SetStdio(nil, io.Discard, io.Discard)
+	if err := s.ExecuteReader(strings.NewReader("true\ngobash_no_such_cmd\n")); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if got := s.LastStatus(); got != 127 {
+		t.Errorf("最后一个命令未找到时退出状态应该是 127，得到 %d", got)
+	}
+
+	err := s.ExecuteReader(strings.NewReader("set -e\necho a\ngobash_no_such_cmd\necho b\n"))
+	var shellErr executor.ShellError
+	if !errors.As(err, &shellErr) || shellErr.ExitCode() != 127 || shellErr.Position().Line != 3 {
+		t.Errorf("set -e 退出的错误应该是第 3 行、退出码为 127 的 ShellError，得到 %v", err)
+	}
+}
+
+// TestExitTrap 测试脚本结束、exit 和 set -e 退出之前执行 EXIT 陷阱，陷阱中的 exit 改变退出码
+func TestExitTrap(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		code  int
+	}{
+		{"trap 'echo bye $?' EXIT\necho hi\n", "hi\nbye 0\n", 0},
+		{"trap 'echo bye $?' 0\nexit 3\necho no\n", "bye 3\n", 3},
+		{"set -e\ntrap 'echo cleanup; exit 7' EXIT\nfalse\n", "cleanup\n", 7},
+		{"trap 'echo bye' EXIT\ntrap - EXIT\n", "", 0},
+	}
+	for _, tt := range tests {
+		s := New()
+		var stdout strings.Builder
+		s.SetStdio(nil, &stdout, io.Discard)
+		err := s.ExecuteReader(strings.NewReader(tt.input))
+		code := s.LastStatus()
+		if err != nil {
+			code = executor.ExitStatus(err)
+		}
+		if stdout.String() != tt.want || code != tt.code {
+			t.Errorf("%q 输出 %q（退出码 %d），期望 %q（%d）", tt.input, stdout.String(), code, tt.want, tt.code)
+		}
+	}
+}
+
+// TestRuntimeErrorLocation 测试脚本执行中的错误消息带有脚本名和出错的命令所在的行
+func TestRuntimeErrorLocation(t *testing.T) {
+	script := t.TempDir() + "/t.sh"
+	if err := os.WriteFile(script, []byte("echo a\nif true; then\n  gobash_no_such_cmd\n  true\nfi\n{\n  echo b\n  gobash_no_such_cmd2\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := New()
+	var stderr strings.Builder
+	s.SetStdio(nil, io.Discard, &stderr)
+	if err := s.ExecuteScript(script); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "gobash: "+script+": 第3行: ") || !strings.HasPrefix(lines[1], "gobash: "+script+": 第8行: ") {
+		t.Errorf("错误消息 = %q", stderr.String())
+	}
+}
+
+// TestStatusOnlyFailures 测试外部命令、子shell、取反和命令替换以非零状态结束时与 bash 相同，只设置退出状态，不输出错误消息
+func TestStatusOnlyFailures(t *testing.T) {
+	s := New()
+	var stdout, stderr strings.Builder
+	s.SetStdio(nil, &stdout, &stderr)
+	input := "sh -c 'exit 2'\necho $?\n(exit 4)\necho $?\n! true\necho $?\ny=$(false)\necho $?\n{ (exit 3); true; }\n"
+	if err := s.ExecuteReader(strings.NewReader(input)); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if stdout.String() != "2\n4\n1\n1\n" || stderr.String() != "" {
+		t.Errorf("输出 %q，标准错误 %q", stdout.String(), stderr.String())
+	}
+}
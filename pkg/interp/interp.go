@@ -0,0 +1,358 @@
+// Package interp 提供把 gobash 作为 Go 库嵌入使用的接口
+//
+// 基本用法：
+//
+//	r, err := interp.New(interp.StdIO(nil, &out, os.Stderr))
+//	if err != nil {
+//		return err
+//	}
+//	err = r.Run(context.Background(), strings.NewReader("echo hello"))
+//
+// 同一个 Runner 可以多次调用 Run，变量、函数和选项在多次调用之间保留。
+// 不同的 Runner 之间不共享状态（变量、工作目录、作业等），可以在多个 goroutine 中同时使用；
+// 同一个 Runner 不能并发调用 Run。
+package interp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gobash/internal/builtin"
+	"gobash/internal/executor"
+	"gobash/internal/i18n"
+	"gobash/internal/shell"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ExitStatus 脚本以非零状态退出（exit N 或 set -e）时 Run 返回的错误
+type ExitStatus int
+
+func (s ExitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// ExitCode 返回退出码
+func (s ExitStatus) ExitCode() int {
+	return int(s)
+}
+
+// Call 调用 Go 实现的内置命令时传入的信息
+// Stdin、Stdout、Stderr 已经应用了命令上的重定向
+type Call struct {
+	Args   []string // 命令参数（不含命令名）
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// BuiltinFunc Go 实现的内置命令
+// 返回 ExitStatus 表示命令以指定状态失败，返回其他错误时退出状态为 1
+type BuiltinFunc func(ctx context.Context, call *Call) error
+
+// AccessKind 访问策略检查的操作类型
+type AccessKind string
+
+const (
+	AccessExec    AccessKind = "exec"    // 执行外部命令，Target 为命令名，Args 为参数
+	AccessRead    AccessKind = "read"    // 读取文件或列出目录，Target 为绝对路径
+	AccessWrite   AccessKind = "write"   // 创建、修改或删除文件和目录，Target 为绝对路径
+	AccessNetwork AccessKind = "network" // 访问网络（http 命令），Target 为 URL
+)
+
+// Access 需要访问策略检查的操作
+type Access struct {
+	Kind   AccessKind
+	Target string
+	Args   []string
+}
+
+// PolicyFunc 访问策略，返回错误时拒绝操作，错误信息作为拒绝的原因显示在脚本的错误输出中
+// 管道中的命令同时执行，策略可能被并发调用
+type PolicyFunc func(ctx context.Context, access Access) error
+
+// Runner 脚本解释器
+type Runner struct {
+	sh     *shell.Shell
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	ctx    context.Context         // 正在执行的 Run 的 context
+	cancel context.CancelCauseFunc // 终止正在执行的 Run（超出资源限制时）
+
+	policy PolicyFunc   // Policy 设置的访问策略
+	limits limits       // 每次 Run 的资源限制
+	procs  atomic.Int64 // 本次 Run 启动的外部命令数
+}
+
+// Option 创建 Runner 的选项
+type Option func(r *Runner) error
+
+// StdIO 设置脚本的标准输入、输出和错误输出，nil 表示使用进程的标准流
+func StdIO(in io.Reader, out, err io.Writer) Option {
+	return func(r *Runner) error {
+		r.stdin = in
+		r.stdout = out
+		r.stderr = err
+		r.sh.SetStdio(in, out, err)
+		return nil
+	}
+}
+
+// Params 设置位置参数（$1, $2, ...）
+func Params(args ...string) Option {
+	return func(r *Runner) error {
+		r.SetParams(args...)
+		return nil
+	}
+}
+
+// Env 设置初始的环境变量（会覆盖从进程环境继承的同名变量），它们与继承的变量一样导出给外部命令
+func Env(vars map[string]string) Option {
+	return func(r *Runner) error {
+		for name, value := range vars {
+			r.SetVar(name, value)
+			r.sh.Executor().Export(name)
+		}
+		return nil
+	}
+}
+
+// Builtin 注册 Go 实现的内置命令
+func Builtin(name string, fn BuiltinFunc) Option {
+	return func(r *Runner) error {
+		if name == "" || strings.ContainsAny(name, " \t\n=/") {
+			return i18n.Errorf("无效的内置命令名: %q", name)
+		}
+		r.RegisterBuiltin(name, fn)
+		return nil
+	}
+}
+
+// Dir 设置脚本的工作目录，默认是进程的当前目录
+// 工作目录只属于这个 Runner，脚本中的 cd 不会改变进程或其他 Runner 的工作目录
+func Dir(path string) Option {
+	return func(r *Runner) error {
+		dir, err := filepath.Abs(path)
+		if err != nil {
+			return i18n.Errorf("无效的工作目录: %v", err)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return i18n.Errorf("无效的工作目录: %v", err)
+		}
+		if !info.IsDir() {
+			return i18n.Errorf("无效的工作目录: %s 不是目录", path)
+		}
+		r.SetVar("PWD", dir)
+		return nil
+	}
+}
+
+// Name 设置错误消息中显示的脚本名
+func Name(name string) Option {
+	return func(r *Runner) error {
+		r.sh.SetScriptPath(name)
+		return nil
+	}
+}
+
+// CommandTimeout 设置单个外部命令的超时时间，超时的命令被终止，退出状态为 124
+func CommandTimeout(timeout time.Duration) Option {
+	return func(r *Runner) error {
+		r.sh.Executor().SetCommandTimeout(timeout)
+		return nil
+	}
+}
+
+// SubstitutionLimit 设置命令替换输出的最大字节数，超过时结果被截断，命令替换的退出状态为 1
+func SubstitutionLimit(limit int) Option {
+	return func(r *Runner) error {
+		r.sh.Executor().SetSubstitutionLimit(limit)
+		return nil
+	}
+}
+
+// Policy 设置访问策略，用于运行不受信任的脚本
+// 执行外部命令、打开重定向的文件、内置命令（cat、rm、tee、http 等）读写文件和访问网络之前调用 fn，
+// fn 返回错误时不执行该操作：外部命令的退出状态为 126，其他操作与没有权限时一样失败
+func Policy(fn PolicyFunc) Option {
+	return func(r *Runner) error {
+		r.policy = fn
+		return nil
+	}
+}
+
+// DryRun 设置试运行模式，用于预览脚本会执行的操作
+// 外部命令和修改文件、访问网络的内置命令（rm、mkdir、tee、http 等）不执行，展开后的命令和重定向输出到标准错误，
+// 命令被视为执行成功；其他内置命令、变量赋值、函数和控制结构正常执行。写入文件的重定向不会创建文件
+func DryRun() Option {
+	return func(r *Runner) error {
+		r.sh.Executor().SetDryRun(true)
+		return nil
+	}
+}
+
+// New 创建 Runner
+func New(opts ...Option) (*Runner, error) {
+	r := &Runner{sh: shell.NewNonInteractive()}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if r.policy != nil || r.limits.processes > 0 {
+		r.sh.Executor().SetPolicy(r.checkAccess)
+	}
+	return r, nil
+}
+
+// checkAccess 按 Policy 设置的策略和 MaxProcesses 检查操作
+func (r *Runner) checkAccess(access builtin.Access) error {
+	if r.policy != nil {
+		if err := r.policy(r.context(), Access{Kind: AccessKind(access.Kind), Target: access.Target, Args: access.Args}); err != nil {
+			return err
+		}
+	}
+	if access.Kind == builtin.AccessExec && r.limits.processes > 0 && r.procs.Add(1) > r.limits.processes {
+		err := &LimitError{Limit: LimitProcesses}
+		r.exceed(err)
+		return err
+	}
+	return nil
+}
+
+// exceed 脚本超出资源限制时终止正在执行的 Run
+func (r *Runner) exceed(err *LimitError) {
+	if r.cancel != nil {
+		r.cancel(err)
+	}
+}
+
+// Run 解析并执行 src 中的脚本
+// 脚本正常结束或以 exit 0 退出时返回 nil；以非零状态退出时返回 ExitStatus；
+// 有语法错误时返回 ExitStatus(2)，错误信息写到标准错误输出；
+// ctx 取消或超时后正在运行的命令被终止，返回的错误满足 errors.Is(err, ctx.Err())；
+// 超出 Timeout、MaxProcesses 或 MaxOutput 设置的资源限制时脚本被终止，返回 *LimitError
+func (r *Runner) Run(ctx context.Context, src io.Reader) error {
+	return r.run(ctx, src, r.stdout, r.stderr)
+}
+
+// run 以 stdout、stderr 作为输出执行 src 中的脚本，结束后恢复 StdIO 设置的输出
+func (r *Runner) run(ctx context.Context, src io.Reader, stdout, stderr io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if r.limits.timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, r.limits.timeout, &LimitError{Limit: LimitTimeout})
+		defer stop()
+	}
+	r.ctx, r.cancel = ctx, cancel
+	r.procs.Store(0)
+	defer func() { r.ctx, r.cancel = nil, nil }()
+
+	if r.limits.output > 0 {
+		limit := &outputLimit{remaining: r.limits.output, exceed: r.exceed}
+		stdout, stderr = limit.writer(stdout, os.Stdout), limit.writer(stderr, os.Stderr)
+	}
+	r.sh.SetStdio(r.stdin, stdout, stderr)
+	defer r.sh.SetStdio(r.stdin, r.stdout, r.stderr)
+
+	// 超出资源限制时同时终止后台作业
+	stopKill := context.AfterFunc(ctx, func() {
+		if limitErr := r.limitError(ctx); limitErr != nil {
+			r.sh.Executor().GetJobManager().KillAll()
+		}
+	})
+	defer stopKill()
+
+	err := r.sh.ExecuteReaderContext(ctx, src)
+	if limitErr := r.limitError(ctx); limitErr != nil {
+		return limitErr
+	}
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *builtin.ExitError:
+		if e.Code == 0 {
+			return nil
+		}
+		return ExitStatus(e.Code)
+	case *executor.ScriptExitError:
+		return ExitStatus(e.Code)
+	}
+	return err
+}
+
+// limitError 返回终止 ctx 的资源限制错误，ctx 没有因为超出资源限制而终止时返回 nil
+func (r *Runner) limitError(ctx context.Context) *LimitError {
+	limitErr, _ := context.Cause(ctx).(*LimitError)
+	return limitErr
+}
+
+// Capture 执行 src 中的脚本，返回捕获的标准输出和错误输出
+// 捕获只在本次调用中生效，之后恢复 StdIO 设置的输出；返回的错误与 Run 相同
+func (r *Runner) Capture(ctx context.Context, src io.Reader) (stdout, stderr string, err error) {
+	var outBuf, errBuf bytes.Buffer
+	err = r.run(ctx, src, &outBuf, &errBuf)
+	return outBuf.String(), errBuf.String(), err
+}
+
+// Vars 返回所有变量的副本
+func (r *Runner) Vars() map[string]string {
+	env := r.sh.Executor().GetEnvMap()
+	vars := make(map[string]string, len(env))
+	for name, value := range env {
+		vars[name] = value
+	}
+	return vars
+}
+
+// Var 返回变量的值
+func (r *Runner) Var(name string) (string, bool) {
+	return r.sh.Executor().GetEnv(name)
+}
+
+// SetVar 设置变量，不影响宿主进程的环境变量
+func (r *Runner) SetVar(name, value string) {
+	r.sh.Executor().GetEnvMap()[name] = value
+}
+
+// SetParams 设置位置参数（$1, $2, ...）以及 $# 和 $@
+func (r *Runner) SetParams(args ...string) {
+	env := r.sh.Executor().GetEnvMap()
+	if count, err := strconv.Atoi(env["#"]); err == nil {
+		for i := 1; i <= count; i++ {
+			delete(env, strconv.Itoa(i))
+		}
+	}
+	for i, arg := range args {
+		env[strconv.Itoa(i+1)] = arg
+	}
+	env["#"] = strconv.Itoa(len(args))
+	env["@"] = strings.Join(args, " ")
+}
+
+// RegisterBuiltin 注册 Go 实现的内置命令，同名的内置命令会被覆盖
+func (r *Runner) RegisterBuiltin(name string, fn BuiltinFunc) {
+	r.sh.Executor().RegisterBuiltin(name, func(args []string, env map[string]string, stdio *builtin.IO) error {
+		return fn(r.context(), &Call{Args: args, Stdin: stdio.Stdin, Stdout: stdio.Stdout, Stderr: stdio.Stderr})
+	})
+}
+
+// context 返回正在执行的 Run 的 context，不在 Run 中时返回 context.Background()
+func (r *Runner) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
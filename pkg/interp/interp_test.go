@@ -0,0 +1,381 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunOutputAndVars(t *testing.T) {
+	var out bytes.Buffer
+	r, err := New(StdIO(nil, &out, nil), Env(map[string]string{"GREETING": "hello"}), Params("a", "b"))
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+
+	if err := r.Run(context.Background(), strings.NewReader("echo $GREETING $2\nRESULT=done\n")); err != nil {
+		t.Fatalf("Run 失败: %v", err)
+	}
+	if got := out.String(); got != "hello b\n" {
+		t.Errorf("输出 %q，期望 %q", got, "hello b\n")
+	}
+	if value, ok := r.Var("RESULT"); !ok || value != "done" {
+		t.Errorf("RESULT = %q，期望 done", value)
+	}
+	if vars := r.Vars(); vars["GREETING"] != "hello" {
+		t.Errorf("Vars()[GREETING] = %q，期望 hello", vars["GREETING"])
+	}
+
+	// 变量在多次 Run 之间保留
+	out.Reset()
+	r.SetVar("NAME", "gobash")
+	if err := r.Run(context.Background(), strings.NewReader("echo $RESULT $NAME")); err != nil {
+		t.Fatalf("Run 失败: %v", err)
+	}
+	if got := out.String(); got != "done gobash\n" {
+		t.Errorf("输出 %q，期望 %q", got, "done gobash\n")
+	}
+}
+
+func TestRunExitStatus(t *testing.T) {
+	r, err := New(StdIO(nil, &bytes.Buffer{}, &bytes.Buffer{}))
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+
+	tests := []struct {
+		script string
+		status int // 0 表示期望返回 nil
+	}{
+		{"exit 0", 0},
+		{"exit 3", 3},
+		{"set -e\nfalse\necho unreachable", 1},
+		{"if true; then", 2},
+	}
+	for _, tt := range tests {
+		err := r.Run(context.Background(), strings.NewReader(tt.script))
+		if tt.status == 0 {
+			if err != nil {
+				t.Errorf("%q: 期望成功，得到 %v", tt.script, err)
+			}
+			continue
+		}
+		var status ExitStatus
+		if !errors.As(err, &status) || int(status) != tt.status {
+			t.Errorf("%q: 期望 ExitStatus(%d)，得到 %v", tt.script, tt.status, err)
+		}
+		r.Run(context.Background(), strings.NewReader("set +e"))
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	var out bytes.Buffer
+	var gotArgs []string
+	r, err := New(
+		StdIO(strings.NewReader("from stdin"), &out, nil),
+		Builtin("greet", func(ctx context.Context, call *Call) error {
+			gotArgs = call.Args
+			_, err := call.Stdout.Write([]byte("hi " + strings.Join(call.Args, ",") + "\n"))
+			return err
+		}),
+		Builtin("fail", func(ctx context.Context, call *Call) error {
+			return ExitStatus(5)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+
+	if err := r.Run(context.Background(), strings.NewReader("greet a b\nfail || echo status=$?")); err != nil {
+		t.Fatalf("Run 失败: %v", err)
+	}
+	if len(gotArgs) != 2 || gotArgs[0] != "a" || gotArgs[1] != "b" {
+		t.Errorf("参数 %v，期望 [a b]", gotArgs)
+	}
+	if got := out.String(); got != "hi a,b\nstatus=5\n" {
+		t.Errorf("输出 %q，期望 %q", got, "hi a,b\nstatus=5\n")
+	}
+
+	if _, err := New(Builtin("bad name", nil)); err == nil {
+		t.Error("无效的内置命令名应该返回错误")
+	}
+}
+
+func TestRunCanceledContext(t *testing.T) {
+	r, err := New()
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Run(ctx, strings.NewReader("echo hi")); !errors.Is(err, context.Canceled) {
+		t.Errorf("期望 context.Canceled，得到 %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	var out, errOut bytes.Buffer
+	r, err := New(StdIO(nil, &out, &errOut))
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = r.Run(ctx, strings.NewReader("echo start\nsleep 5\necho end\n"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 context.DeadlineExceeded，得到 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("超时后应该立即终止 sleep，实际耗时 %s", elapsed)
+	}
+	if got := out.String(); got != "start\n" {
+		t.Errorf("输出 %q，期望 %q", got, "start\n")
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	var out bytes.Buffer
+	r, err := New(StdIO(nil, &out, &bytes.Buffer{}), CommandTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	if err := r.Run(context.Background(), strings.NewReader("sleep 5\necho $?\n")); err != nil {
+		t.Fatalf("Run 失败: %v", err)
+	}
+	if got := out.String(); got != "124\n" {
+		t.Errorf("输出 %q，期望 %q", got, "124\n")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	var out bytes.Buffer
+	r, err := New(StdIO(nil, &out, nil))
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+
+	stdout, stderr, err := r.Capture(context.Background(), strings.NewReader("echo captured\nsh -c 'echo warn >&2; exit 3'\n"))
+	if err != nil {
+		t.Fatalf("Capture 失败: %v", err)
+	}
+	if stdout != "captured\n" {
+		t.Errorf("标准输出 %q，期望 %q", stdout, "captured\n")
+	}
+	if !strings.HasPrefix(stderr, "warn\n") {
+		t.Errorf("错误输出 %q，期望以 %q 开头", stderr, "warn\n")
+	}
+
+	// 捕获结束后恢复 StdIO 设置的输出
+	if err := r.Run(context.Background(), strings.NewReader("echo after")); err != nil {
+		t.Fatalf("Run 失败: %v", err)
+	}
+	if got := out.String(); got != "after\n" {
+		t.Errorf("输出 %q，期望 %q", got, "after\n")
+	}
+}
+
+// TestPolicy 测试访问策略拒绝外部命令、重定向和内置命令的文件操作
+func TestPolicy(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var checked []Access
+	policy := func(ctx context.Context, access Access) error {
+		mu.Lock()
+		checked = append(checked, access)
+		mu.Unlock()
+		switch {
+		case access.Kind == AccessExec:
+			return errors.New("external commands are disabled")
+		case access.Kind == AccessNetwork:
+			return errors.New("network is disabled")
+		case filepath.Base(access.Target) == "secret":
+			return errors.New("forbidden")
+		}
+		return nil
+	}
+	r, err := New(Dir(dir), Policy(policy))
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{"sh -c 'echo run'; echo $?", "126\n"},
+		{"echo a | sh -c cat; echo $?", "126\n"},
+		{"command sh -c 'echo run'; echo $?", "126\n"},
+		{"echo ok > file; cat file", "ok\n"},
+		{"echo x > secret; echo $?", "1\n"},
+		{"cat < secret; echo $?", "1\n"},
+		{"(echo x >> secret); echo $?", "1\n"},
+		{"touch secret || echo denied", "denied\n"},
+		{"echo x | tee secret >/dev/null || echo denied", "denied\n"},
+		{"http http://127.0.0.1:1/; echo $?", "7\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, err := r.Capture(context.Background(), strings.NewReader(tt.script))
+		if err != nil {
+			t.Fatalf("%q: Run 失败: %v", tt.script, err)
+		}
+		if stdout != tt.want {
+			t.Errorf("%q: 输出 %q，期望 %q（错误输出 %q）", tt.script, stdout, tt.want, stderr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret")); !os.IsNotExist(err) {
+		t.Errorf("被拒绝的文件不应该被创建: %v", err)
+	}
+
+	// 策略收到命令参数和绝对路径
+	found := map[AccessKind]bool{}
+	for _, access := range checked {
+		switch {
+		case access.Kind == AccessExec && access.Target == "sh" && len(access.Args) == 2 && access.Args[1] == "echo run":
+			found[AccessExec] = true
+		case access.Kind == AccessWrite && access.Target == filepath.Join(dir, "file"):
+			found[AccessWrite] = true
+		case access.Kind == AccessRead && access.Target == filepath.Join(dir, "file"):
+			found[AccessRead] = true
+		case access.Kind == AccessNetwork && access.Target == "http://127.0.0.1:1/":
+			found[AccessNetwork] = true
+		}
+	}
+	for _, kind := range []AccessKind{AccessExec, AccessRead, AccessWrite, AccessNetwork} {
+		if !found[kind] {
+			t.Errorf("策略没有收到 %s 检查: %v", kind, checked)
+		}
+	}
+}
+
+// TestLimits 测试超出资源限制时脚本被终止
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		opt    Option
+		script string
+		want   string
+		limit  Limit
+	}{
+		{"timeout", Timeout(200 * time.Millisecond), "echo start; sleep 5; echo end", "start\n", LimitTimeout},
+		{"background", Timeout(200 * time.Millisecond), "sleep 5 & wait; echo end", "", LimitTimeout},
+		{"loop", Timeout(200 * time.Millisecond), "while true; do true; done; echo end", "", LimitTimeout},
+		{"processes", MaxProcesses(2), "sh -c 'echo 1'; sh -c 'echo 2'; sh -c 'echo 3'; echo end", "1\n2\n", LimitProcesses},
+		{"output", MaxOutput(8), "echo 12345; echo 67890; echo end", "12345\n67", LimitOutput},
+		{"output loop", MaxOutput(4), "while true; do echo x; done", "x\nx\n", LimitOutput},
+	}
+	for _, tt := range tests {
+		r, err := New(tt.opt)
+		if err != nil {
+			t.Fatalf("%s: New 失败: %v", tt.name, err)
+		}
+		start := time.Now()
+		stdout, _, err := r.Capture(context.Background(), strings.NewReader(tt.script))
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit {
+			t.Errorf("%s: 期望 %s 限制错误，得到 %v", tt.name, tt.limit, err)
+		}
+		if stdout != tt.want {
+			t.Errorf("%s: 输出 %q，期望 %q", tt.name, stdout, tt.want)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("%s: 超出限制后应该立即终止，实际耗时 %s", tt.name, elapsed)
+		}
+	}
+
+	// 超时满足 errors.Is(err, context.DeadlineExceeded)，限制对每次 Run 分别计算
+	r, err := New(Timeout(200*time.Millisecond), MaxProcesses(1))
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	if err := r.Run(context.Background(), strings.NewReader("sleep 5")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 context.DeadlineExceeded，得到 %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if stdout, _, err := r.Capture(context.Background(), strings.NewReader("sh -c 'echo ok'")); err != nil || stdout != "ok\n" {
+			t.Errorf("第 %d 次 Run: 输出 %q，错误 %v", i+1, stdout, err)
+		}
+	}
+
+	if _, err := New(MaxOutput(-1)); err == nil {
+		t.Error("负数的资源限制应该返回错误")
+	}
+}
+
+// TestShopt 测试 shopt 设置的选项对内置命令生效
+func TestShopt(t *testing.T) {
+	r, err := New()
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	script := "echo 'a\\tb'\nshopt -s xpg_echo\necho 'a\\tb'\nshopt -p xpg_echo\necho $BASHOPTS\nshopt -u xpg_echo\nshopt -q xpg_echo || echo off"
+	stdout, _, err := r.Capture(context.Background(), strings.NewReader(script))
+	if err != nil {
+		t.Fatalf("Run 失败: %v", err)
+	}
+	want := "a\\tb\na\tb\nshopt -s xpg_echo\nxpg_echo\noff\n"
+	if stdout != want {
+		t.Errorf("输出 %q，期望 %q", stdout, want)
+	}
+}
+
+func TestConcurrentRunners(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd 失败: %v", err)
+	}
+
+	const n = 8
+	dirs := make([]string, n)
+	for i := range dirs {
+		dirs[i] = t.TempDir()
+		if err := os.Mkdir(filepath.Join(dirs[i], "sub"), 0755); err != nil {
+			t.Fatalf("Mkdir 失败: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	outputs := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var out bytes.Buffer
+			r, err := New(StdIO(nil, &out, nil), Dir(dirs[i]), Env(map[string]string{"ID": strconv.Itoa(i)}))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = r.Run(context.Background(), strings.NewReader("cd sub\necho runner $ID > out.txt\npwd\nsh -c pwd\ncat out.txt\n"))
+			outputs[i] = out.String()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Errorf("Runner %d 失败: %v", i, errs[i])
+			continue
+		}
+		sub := filepath.Join(dirs[i], "sub")
+		want := fmt.Sprintf("%s\n%s\nrunner %d\n", sub, sub, i)
+		if outputs[i] != want {
+			t.Errorf("Runner %d 输出 %q，期望 %q", i, outputs[i], want)
+		}
+	}
+
+	// cd 不改变进程的工作目录
+	if got, _ := os.Getwd(); got != wd {
+		t.Errorf("进程工作目录变为 %q，期望 %q", got, wd)
+	}
+}
@@ -0,0 +1,120 @@
+package interp
+
+import (
+	"context"
+	"gobash/internal/i18n"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limit 资源限制的种类
+type Limit string
+
+const (
+	LimitTimeout   Limit = "timeout"   // 执行时间（Timeout）
+	LimitProcesses Limit = "processes" // 启动的外部命令数（MaxProcesses）
+	LimitOutput    Limit = "output"    // 输出的字节数（MaxOutput）
+)
+
+// LimitError 脚本超出资源限制被终止时 Run 返回的错误
+// 超时的 LimitError 满足 errors.Is(err, context.DeadlineExceeded)
+type LimitError struct {
+	Limit Limit
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitTimeout:
+		return i18n.T("脚本执行超时")
+	case LimitProcesses:
+		return i18n.T("启动的外部命令数超过限制")
+	}
+	return i18n.T("输出超过限制")
+}
+
+func (e *LimitError) Unwrap() error {
+	if e.Limit == LimitTimeout {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// limits 每次 Run 的资源限制，0 表示不限制
+type limits struct {
+	timeout   time.Duration
+	processes int64
+	output    int64
+}
+
+// Timeout 限制每次 Run 的执行时间
+// 超时后终止正在运行的命令和后台作业，Run 返回 *LimitError；0 表示不限制
+func Timeout(d time.Duration) Option {
+	return func(r *Runner) error {
+		if d < 0 {
+			return i18n.Errorf("无效的资源限制: %v", d)
+		}
+		r.limits.timeout = d
+		return nil
+	}
+}
+
+// MaxProcesses 限制每次 Run 启动的外部命令数（包括管道、子shell、xargs 和 timeout 中的外部命令）
+// 超出时该命令不执行，脚本被终止，Run 返回 *LimitError；0 表示不限制
+func MaxProcesses(n int) Option {
+	return func(r *Runner) error {
+		if n < 0 {
+			return i18n.Errorf("无效的资源限制: %v", n)
+		}
+		r.limits.processes = int64(n)
+		return nil
+	}
+}
+
+// MaxOutput 限制每次 Run 写到标准输出和错误输出的总字节数
+// 超出的部分被丢弃，脚本被终止，Run 返回 *LimitError；0 表示不限制
+func MaxOutput(n int64) Option {
+	return func(r *Runner) error {
+		if n < 0 {
+			return i18n.Errorf("无效的资源限制: %v", n)
+		}
+		r.limits.output = n
+		return nil
+	}
+}
+
+// outputLimit 标准输出和错误输出共享的剩余字节数，用完时调用 exceed
+type outputLimit struct {
+	mu        sync.Mutex
+	remaining int64
+	exceed    func(err *LimitError)
+}
+
+// writer 返回写到 w 并计入限制的 Writer，w 为 nil 时写到 def
+func (l *outputLimit) writer(w, def io.Writer) io.Writer {
+	if w == nil {
+		w = def
+	}
+	return &limitedWriter{w: w, limit: l}
+}
+
+// limitedWriter 超出输出限制后丢弃数据并返回 *LimitError
+type limitedWriter struct {
+	w     io.Writer
+	limit *outputLimit
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	lw.limit.mu.Lock()
+	n := min(int64(len(p)), lw.limit.remaining)
+	lw.limit.remaining -= n
+	lw.limit.mu.Unlock()
+
+	if n < int64(len(p)) {
+		written, _ := lw.w.Write(p[:n])
+		err := &LimitError{Limit: LimitOutput}
+		lw.limit.exceed(err)
+		return written, err
+	}
+	return lw.w.Write(p)
+}